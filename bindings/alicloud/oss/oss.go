@@ -8,13 +8,20 @@ package oss
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"time"
 
 	"github.com/aliyun/aliyun-oss-go-sdk/oss"
 	"github.com/dapr/components-contrib/bindings"
+	contrib_objectstorage "github.com/dapr/components-contrib/internal/component/objectstorage"
 	"github.com/dapr/dapr/pkg/logger"
 	"github.com/google/uuid"
 )
 
+const metadataKey = "key"
+
 // AliCloudOSS is a binding for an AliCloud OSS storage bucket
 type AliCloudOSS struct {
 	metadata *ossMetadata
@@ -23,10 +30,11 @@ type AliCloudOSS struct {
 }
 
 type ossMetadata struct {
-	Endpoint    string `json:"endpoint"`
-	AccessKeyID string `json:"accessKeyID"`
-	AccessKey   string `json:"accessKey"`
-	Bucket      string `json:"bucket"`
+	Endpoint     string `json:"endpoint"`
+	AccessKeyID  string `json:"accessKeyID"`
+	AccessKey    string `json:"accessKey"`
+	Bucket       string `json:"bucket"`
+	DecodeBase64 bool   `json:"decodeBase64,string"`
 }
 
 // NewAliCloudOSS returns a new  instance
@@ -51,30 +59,172 @@ func (s *AliCloudOSS) Init(metadata bindings.Metadata) error {
 }
 
 func (s *AliCloudOSS) Operations() []bindings.OperationKind {
-	return []bindings.OperationKind{bindings.CreateOperation}
+	return contrib_objectstorage.Operations()
 }
 
 func (s *AliCloudOSS) Invoke(req *bindings.InvokeRequest) (*bindings.InvokeResponse, error) {
+	bucket, err := s.client.Bucket(s.metadata.Bucket)
+	if err != nil {
+		return nil, err
+	}
+
+	switch req.Operation {
+	case bindings.CreateOperation:
+		return s.create(bucket, req)
+	case bindings.GetOperation:
+		return s.get(bucket, req)
+	case bindings.DeleteOperation:
+		return s.delete(bucket, req)
+	case bindings.ListOperation:
+		return s.list(bucket, req)
+	case contrib_objectstorage.PresignOperation:
+		return s.presign(bucket, req)
+	default:
+		return nil, fmt.Errorf("oss binding error: unsupported operation %s", req.Operation)
+	}
+}
+
+func (s *AliCloudOSS) create(bucket *oss.Bucket, req *bindings.InvokeRequest) (*bindings.InvokeResponse, error) {
 	key := ""
-	if val, ok := req.Metadata["key"]; ok && val != "" {
+	if val, ok := req.Metadata[metadataKey]; ok && val != "" {
 		key = val
 	} else {
 		key = uuid.New().String()
 		s.logger.Debugf("key not found. generating key %s", key)
 	}
 
-	bucket, err := s.client.Bucket(s.metadata.Bucket)
+	data, err := contrib_objectstorage.DecodeData(req.Data, s.metadata.DecodeBase64, req.Metadata)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("oss binding error: %s", err)
+	}
+
+	var options []oss.Option
+	if val, ok := req.Metadata[contrib_objectstorage.ContentTypeKey]; ok && val != "" {
+		options = append(options, oss.ContentType(val))
+	}
+	for k, v := range contrib_objectstorage.UserMetadata(req.Metadata, metadataKey) {
+		options = append(options, oss.Meta(k, v))
 	}
 
 	// Upload a byte array.
-	err = bucket.PutObject(key, bytes.NewReader(req.Data))
+	if err = bucket.PutObject(key, bytes.NewReader(data), options...); err != nil {
+		return nil, fmt.Errorf("oss binding error: uploading %s failed: %s", key, err)
+	}
+
+	return &bindings.InvokeResponse{
+		Metadata: map[string]string{metadataKey: key},
+	}, nil
+}
+
+func (s *AliCloudOSS) get(bucket *oss.Bucket, req *bindings.InvokeRequest) (*bindings.InvokeResponse, error) {
+	key, err := requiredKey(req)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := bucket.DoGetObject(&oss.GetObjectRequest{ObjectKey: key}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("oss binding error: getting %s failed: %s", key, err)
+	}
+	defer result.Response.Close()
+
+	data, err := ioutil.ReadAll(result.Response)
+	if err != nil {
+		return nil, fmt.Errorf("oss binding error: reading %s failed: %s", key, err)
+	}
+
+	metadata := map[string]string{}
+	for k := range result.Response.Headers {
+		if strings.HasPrefix(k, oss.HTTPHeaderOssMetaPrefix) {
+			metadata[strings.TrimPrefix(k, oss.HTTPHeaderOssMetaPrefix)] = result.Response.Headers.Get(k)
+		}
+	}
+	if val := result.Response.Headers.Get(oss.HTTPHeaderContentType); val != "" {
+		metadata[contrib_objectstorage.ContentTypeKey] = val
+	}
+
+	return &bindings.InvokeResponse{
+		Data:     data,
+		Metadata: metadata,
+	}, nil
+}
+
+func (s *AliCloudOSS) delete(bucket *oss.Bucket, req *bindings.InvokeRequest) (*bindings.InvokeResponse, error) {
+	key, err := requiredKey(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = bucket.DeleteObject(key); err != nil {
+		return nil, fmt.Errorf("oss binding error: deleting %s failed: %s", key, err)
+	}
+
+	return nil, nil
+}
+
+func (s *AliCloudOSS) list(bucket *oss.Bucket, req *bindings.InvokeRequest) (*bindings.InvokeResponse, error) {
+	r, err := contrib_objectstorage.ParseListRequest(req.Metadata)
+	if err != nil {
+		return nil, fmt.Errorf("oss binding error: %s", err)
+	}
+
+	result, err := bucket.ListObjects(oss.Prefix(r.Prefix), oss.Marker(r.Marker), oss.MaxKeys(r.MaxResults))
+	if err != nil {
+		return nil, fmt.Errorf("oss binding error: listing objects failed: %s", err)
+	}
+
+	resp := contrib_objectstorage.ListResponse{
+		Objects: make([]contrib_objectstorage.Object, 0, len(result.Objects)),
+	}
+	for _, o := range result.Objects {
+		resp.Objects = append(resp.Objects, contrib_objectstorage.Object{
+			Key:          o.Key,
+			Size:         o.Size,
+			LastModified: o.LastModified,
+			ETag:         o.ETag,
+		})
+	}
+	if result.IsTruncated {
+		resp.NextMarker = result.NextMarker
+	}
+
+	b, err := json.Marshal(resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return &bindings.InvokeResponse{Data: b}, nil
+}
+
+func (s *AliCloudOSS) presign(bucket *oss.Bucket, req *bindings.InvokeRequest) (*bindings.InvokeResponse, error) {
+	key, err := requiredKey(req)
+	if err != nil {
+		return nil, err
+	}
+
+	ttl, err := contrib_objectstorage.ParsePresignTTL(req.Metadata)
+	if err != nil {
+		return nil, fmt.Errorf("oss binding error: %s", err)
+	}
+
+	// OSS signs URLs for whole seconds
+	if ttl < time.Second {
+		ttl = time.Second
+	}
+	url, err := bucket.SignURL(key, oss.HTTPGet, int64(ttl/time.Second))
+	if err != nil {
+		return nil, fmt.Errorf("oss binding error: presigning %s failed: %s", key, err)
+	}
+
+	b, err := json.Marshal(contrib_objectstorage.PresignResponse{
+		PresignedURL: url,
+		ExpiresAt:    time.Now().UTC().Add(ttl),
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	return nil, err
+	return &bindings.InvokeResponse{Data: b}, nil
 }
 
 func (s *AliCloudOSS) parseMetadata(metadata bindings.Metadata) (*ossMetadata, error) {
@@ -100,3 +250,11 @@ func (s *AliCloudOSS) getClient(metadata *ossMetadata) (*oss.Client, error) {
 
 	return client, nil
 }
+
+func requiredKey(req *bindings.InvokeRequest) (string, error) {
+	if val, ok := req.Metadata[metadataKey]; ok && val != "" {
+		return val, nil
+	}
+
+	return "", fmt.Errorf("oss binding error: required metadata %s missing", metadataKey)
+}
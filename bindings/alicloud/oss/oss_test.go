@@ -6,15 +6,28 @@
 package oss
 
 import (
+	"encoding/base64"
+	"encoding/json"
+	"encoding/xml"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/dapr/components-contrib/bindings"
+	contrib_objectstorage "github.com/dapr/components-contrib/internal/component/objectstorage"
+	"github.com/dapr/dapr/pkg/logger"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestParseMetadata(t *testing.T) {
 	m := bindings.Metadata{}
-	m.Properties = map[string]string{"AccessKey": "key", "Endpoint": "endpoint", "AccessKeyID": "accessKeyID", "Bucket": "test"}
+	m.Properties = map[string]string{"AccessKey": "key", "Endpoint": "endpoint", "AccessKeyID": "accessKeyID", "Bucket": "test", "decodeBase64": "true"}
 	aliCloudOSS := AliCloudOSS{}
 	meta, err := aliCloudOSS.parseMetadata(m)
 	assert.Nil(t, err)
@@ -22,4 +35,172 @@ func TestParseMetadata(t *testing.T) {
 	assert.Equal(t, "endpoint", meta.Endpoint)
 	assert.Equal(t, "accessKeyID", meta.AccessKeyID)
 	assert.Equal(t, "test", meta.Bucket)
+	assert.True(t, meta.DecodeBase64)
+}
+
+// fakeOSS serves the object requests of a single bucket, which the SDK
+// addresses in the path when the endpoint is an IP address.
+type fakeOSS struct {
+	lock    sync.Mutex
+	objects map[string]fakeObject
+}
+
+type fakeObject struct {
+	data   []byte
+	header http.Header
+}
+
+type listBucketResult struct {
+	XMLName     xml.Name `xml:"ListBucketResult"`
+	IsTruncated bool
+	NextMarker  string
+	Contents    []fakeContents
+}
+
+type fakeContents struct {
+	Key  string
+	Size int64
+}
+
+func (f *fakeOSS) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	key := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, "/bucket"), "/")
+	switch {
+	case r.Method == http.MethodGet && key == "":
+		var res listBucketResult
+		keys := make([]string, 0, len(f.objects))
+		for k := range f.objects {
+			if strings.HasPrefix(k, r.URL.Query().Get("prefix")) && k > r.URL.Query().Get("marker") {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		maxKeys, _ := strconv.Atoi(r.URL.Query().Get("max-keys"))
+		if len(keys) > maxKeys {
+			keys = keys[:maxKeys]
+			res.IsTruncated = true
+			res.NextMarker = keys[len(keys)-1]
+		}
+		for _, k := range keys {
+			res.Contents = append(res.Contents, fakeContents{k, int64(len(f.objects[k].data))})
+		}
+		_ = xml.NewEncoder(w).Encode(res)
+	case r.Method == http.MethodPut:
+		data, _ := ioutil.ReadAll(r.Body)
+		f.objects[key] = fakeObject{data: data, header: r.Header.Clone()}
+	case r.Method == http.MethodGet:
+		o, ok := f.objects[key]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+
+			return
+		}
+		for k, v := range o.header {
+			if strings.HasPrefix(k, "X-Oss-Meta-") || k == "Content-Type" {
+				w.Header()[k] = v
+			}
+		}
+		_, _ = w.Write(o.data)
+	case r.Method == http.MethodDelete:
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func TestOperations(t *testing.T) {
+	server := httptest.NewServer(&fakeOSS{objects: map[string]fakeObject{}})
+	defer server.Close()
+
+	s := NewAliCloudOSS(logger.NewLogger("test"))
+	err := s.Init(bindings.Metadata{Properties: map[string]string{
+		"endpoint": server.URL, "accessKeyID": "id", "accessKey": "key", "bucket": "bucket",
+	}})
+	require.NoError(t, err)
+
+	t.Run("create and get with metadata", func(t *testing.T) {
+		resp, err := s.Invoke(&bindings.InvokeRequest{
+			Operation: bindings.CreateOperation,
+			Data:      []byte(base64.StdEncoding.EncodeToString([]byte("hello"))),
+			Metadata:  map[string]string{"key": "docs/a.txt", "contentType": "text/plain", "decodeBase64": "true", "owner": "dapr"},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "docs/a.txt", resp.Metadata["key"])
+
+		resp, err = s.Invoke(&bindings.InvokeRequest{
+			Operation: bindings.GetOperation,
+			Metadata:  map[string]string{"key": "docs/a.txt"},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "hello", string(resp.Data))
+		assert.Equal(t, "text/plain", resp.Metadata["contentType"])
+		assert.Equal(t, "dapr", resp.Metadata["Owner"])
+	})
+
+	t.Run("list pages", func(t *testing.T) {
+		for _, key := range []string{"docs/b.txt", "docs/c.txt", "other.txt"} {
+			_, err := s.Invoke(&bindings.InvokeRequest{
+				Operation: bindings.CreateOperation,
+				Data:      []byte(key),
+				Metadata:  map[string]string{"key": key},
+			})
+			require.NoError(t, err)
+		}
+
+		resp, err := s.Invoke(&bindings.InvokeRequest{
+			Operation: bindings.ListOperation,
+			Metadata:  map[string]string{"prefix": "docs/", "maxResults": "2"},
+		})
+		require.NoError(t, err)
+		var page contrib_objectstorage.ListResponse
+		require.NoError(t, json.Unmarshal(resp.Data, &page))
+		require.Len(t, page.Objects, 2)
+		assert.Equal(t, "docs/a.txt", page.Objects[0].Key)
+		assert.Equal(t, "docs/b.txt", page.NextMarker)
+
+		resp, err = s.Invoke(&bindings.InvokeRequest{
+			Operation: bindings.ListOperation,
+			Metadata:  map[string]string{"prefix": "docs/", "marker": page.NextMarker},
+		})
+		require.NoError(t, err)
+		page = contrib_objectstorage.ListResponse{}
+		require.NoError(t, json.Unmarshal(resp.Data, &page))
+		require.Len(t, page.Objects, 1)
+		assert.Equal(t, "docs/c.txt", page.Objects[0].Key)
+		assert.Empty(t, page.NextMarker)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		_, err := s.Invoke(&bindings.InvokeRequest{
+			Operation: bindings.DeleteOperation,
+			Metadata:  map[string]string{"key": "other.txt"},
+		})
+		require.NoError(t, err)
+
+		_, err = s.Invoke(&bindings.InvokeRequest{
+			Operation: bindings.GetOperation,
+			Metadata:  map[string]string{"key": "other.txt"},
+		})
+		assert.Error(t, err)
+	})
+
+	t.Run("presign", func(t *testing.T) {
+		resp, err := s.Invoke(&bindings.InvokeRequest{
+			Operation: contrib_objectstorage.PresignOperation,
+			Metadata:  map[string]string{"key": "docs/a.txt", "presignTTL": "1m"},
+		})
+		require.NoError(t, err)
+		var presigned contrib_objectstorage.PresignResponse
+		require.NoError(t, json.Unmarshal(resp.Data, &presigned))
+		assert.Contains(t, presigned.PresignedURL, "/bucket/docs%2Fa.txt?")
+		assert.Contains(t, presigned.PresignedURL, "Signature=")
+	})
+
+	t.Run("missing key", func(t *testing.T) {
+		for _, op := range []bindings.OperationKind{bindings.GetOperation, bindings.DeleteOperation, contrib_objectstorage.PresignOperation} {
+			_, err := s.Invoke(&bindings.InvokeRequest{Operation: op, Metadata: map[string]string{}})
+			assert.Error(t, err, op)
+		}
+	})
 }
@@ -0,0 +1,162 @@
+// ------------------------------------------------------------
+// Copyright (c) Microsoft Corporation and Dapr Contributors.
+// Licensed under the MIT License.
+// ------------------------------------------------------------
+
+package s3
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/dapr/components-contrib/bindings"
+	contrib_objectstorage "github.com/dapr/components-contrib/internal/component/objectstorage"
+)
+
+const receiveErrorBackoff = 5 * time.Second
+
+// s3Notification is an event notification of S3, see
+// https://docs.aws.amazon.com/AmazonS3/latest/dev/notification-content-structure.html
+type s3Notification struct {
+	Records []struct {
+		EventName string    `json:"eventName"`
+		EventTime time.Time `json:"eventTime"`
+		S3        struct {
+			Bucket struct {
+				Name string `json:"name"`
+			} `json:"bucket"`
+			Object struct {
+				Key  string `json:"key"`
+				Size int64  `json:"size"`
+				ETag string `json:"eTag"`
+			} `json:"object"`
+		} `json:"s3"`
+	} `json:"Records"`
+}
+
+// snsNotification is the envelope of the notifications delivered to the queue through an SNS topic.
+type snsNotification struct {
+	Type    string `json:"Type"`
+	Message string `json:"Message"`
+}
+
+// Read triggers handler with the events of the objects of the bucket, read from the
+// notifications of the SQS queue or, when there is none, by polling the bucket.
+func (s *AWSS3) Read(handler func(*bindings.ReadResponse) error) error {
+	if s.metadata.QueueURL == "" {
+		s.logger.Warnf("queueURL not set, polling bucket %s every %s: each replica emits the events", s.metadata.Bucket, s.pollInterval)
+
+		return contrib_objectstorage.NewPoller(s.metadata.Bucket, s.pollInterval, s.listObjects, s.pollCheckpoint, s.logger).Run(s.ctx, handler)
+	}
+
+	for {
+		result, err := s.sqsClient.ReceiveMessageWithContext(s.ctx, &sqs.ReceiveMessageInput{
+			QueueUrl:            aws.String(s.metadata.QueueURL),
+			MaxNumberOfMessages: aws.Int64(10),
+			WaitTimeSeconds:     aws.Int64(20),
+		})
+		if s.ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			s.logger.Errorf("s3 binding error: receiving notifications from %s failed: %s", s.metadata.QueueURL, err)
+			select {
+			case <-s.ctx.Done():
+				return nil
+			case <-time.After(receiveErrorBackoff):
+			}
+
+			continue
+		}
+
+		for _, m := range result.Messages {
+			if err = s.handleNotification(aws.StringValue(m.Body), handler); err != nil {
+				s.logger.Errorf("s3 binding error: %s", err)
+
+				continue
+			}
+
+			_, err = s.sqsClient.DeleteMessageWithContext(s.ctx, &sqs.DeleteMessageInput{
+				QueueUrl:      aws.String(s.metadata.QueueURL),
+				ReceiptHandle: m.ReceiptHandle,
+			})
+			if err != nil {
+				s.logger.Errorf("s3 binding error: deleting notification from %s failed: %s", s.metadata.QueueURL, err)
+			}
+		}
+	}
+}
+
+// handleNotification triggers handler with the events of a notification. The
+// notification is left in the queue to be retried when one of them fails.
+func (s *AWSS3) handleNotification(body string, handler func(*bindings.ReadResponse) error) error {
+	events, err := parseNotification(body)
+	if err != nil {
+		return err
+	}
+
+	for _, e := range events {
+		if err = e.Handle(handler); err != nil {
+			return fmt.Errorf("handling %s event of %s failed: %s", e.EventType, e.Key, err)
+		}
+	}
+
+	return nil
+}
+
+// parseNotification returns the events of the created and deleted objects of an S3
+// notification, sent to the queue directly or through an SNS topic. Test events and
+// other events are ignored.
+func parseNotification(body string) ([]contrib_objectstorage.Event, error) {
+	var envelope snsNotification
+	if err := json.Unmarshal([]byte(body), &envelope); err == nil && envelope.Type == "Notification" {
+		body = envelope.Message
+	}
+
+	var n s3Notification
+	if err := json.Unmarshal([]byte(body), &n); err != nil {
+		return nil, fmt.Errorf("invalid notification: %s", err)
+	}
+
+	events := make([]contrib_objectstorage.Event, 0, len(n.Records))
+	for _, r := range n.Records {
+		var eventType string
+		switch {
+		case strings.HasPrefix(r.EventName, "ObjectCreated:"):
+			eventType = contrib_objectstorage.EventCreated
+		case strings.HasPrefix(r.EventName, "ObjectRemoved:"):
+			eventType = contrib_objectstorage.EventDeleted
+		default:
+			continue
+		}
+
+		// Keys are URL encoded in notifications
+		key, err := url.QueryUnescape(r.S3.Object.Key)
+		if err != nil {
+			return nil, fmt.Errorf("invalid key %s in notification: %s", r.S3.Object.Key, err)
+		}
+
+		events = append(events, contrib_objectstorage.Event{
+			EventType: eventType,
+			Bucket:    r.S3.Bucket.Name,
+			Key:       key,
+			Size:      r.S3.Object.Size,
+			ETag:      r.S3.Object.ETag,
+			EventTime: r.EventTime,
+		})
+	}
+
+	return events, nil
+}
+
+// Close stops reading events
+func (s *AWSS3) Close() error {
+	s.cancel()
+
+	return nil
+}
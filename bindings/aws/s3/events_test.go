@@ -0,0 +1,142 @@
+// ------------------------------------------------------------
+// Copyright (c) Microsoft Corporation and Dapr Contributors.
+// Licensed under the MIT License.
+// ------------------------------------------------------------
+
+package s3
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/dapr/components-contrib/bindings"
+	contrib_objectstorage "github.com/dapr/components-contrib/internal/component/objectstorage"
+	"github.com/dapr/dapr/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testNotification = `{"Records": [
+	{"eventName": "ObjectCreated:Put", "eventTime": "2021-01-22T10:30:00.000Z",
+	 "s3": {"bucket": {"name": "bucket"}, "object": {"key": "docs/my+file%3D1.txt", "size": 5, "eTag": "etag"}}},
+	{"eventName": "ObjectRemoved:Delete", "eventTime": "2021-01-22T10:31:00.000Z",
+	 "s3": {"bucket": {"name": "bucket"}, "object": {"key": "docs/b.txt"}}},
+	{"eventName": "ObjectRestore:Post", "s3": {"bucket": {"name": "bucket"}, "object": {"key": "docs/c.txt"}}}
+]}`
+
+func TestParseNotification(t *testing.T) {
+	expected := []contrib_objectstorage.Event{
+		{
+			EventType: contrib_objectstorage.EventCreated,
+			Bucket:    "bucket",
+			Key:       "docs/my file=1.txt",
+			Size:      5,
+			ETag:      "etag",
+			EventTime: time.Date(2021, 1, 22, 10, 30, 0, 0, time.UTC),
+		},
+		{
+			EventType: contrib_objectstorage.EventDeleted,
+			Bucket:    "bucket",
+			Key:       "docs/b.txt",
+			EventTime: time.Date(2021, 1, 22, 10, 31, 0, 0, time.UTC),
+		},
+	}
+
+	t.Run("sent to the queue", func(t *testing.T) {
+		events, err := parseNotification(testNotification)
+		require.NoError(t, err)
+		assert.Equal(t, expected, events)
+	})
+
+	t.Run("sent through an SNS topic", func(t *testing.T) {
+		body, err := json.Marshal(snsNotification{Type: "Notification", Message: testNotification})
+		require.NoError(t, err)
+		events, err := parseNotification(string(body))
+		require.NoError(t, err)
+		assert.Equal(t, expected, events)
+	})
+
+	t.Run("test event", func(t *testing.T) {
+		events, err := parseNotification(`{"Service": "Amazon S3", "Event": "s3:TestEvent", "Bucket": "bucket"}`)
+		require.NoError(t, err)
+		assert.Empty(t, events)
+	})
+
+	t.Run("invalid notification", func(t *testing.T) {
+		_, err := parseNotification("not json")
+		assert.Error(t, err)
+	})
+}
+
+func TestReadPollsWithoutQueue(t *testing.T) {
+	s := newTestS3(t)
+	s.pollInterval = 10 * time.Millisecond
+
+	events := make(chan map[string]string, 100)
+	done := make(chan error)
+	go func() {
+		done <- s.Read(func(resp *bindings.ReadResponse) error {
+			events <- resp.Metadata
+
+			return nil
+		})
+	}()
+	defer func() {
+		require.NoError(t, s.Close())
+		select {
+		case err := <-done:
+			assert.NoError(t, err)
+		case <-time.After(5 * time.Second):
+			assert.Fail(t, "timeout waiting for Read to return")
+		}
+	}()
+
+	// The objects created before the first listing are not emitted, so new ones
+	// are created until one is
+	var created map[string]string
+	for i := 0; created == nil; i++ {
+		require.Less(t, i, 50, "timeout waiting for created event")
+		_, err := s.Invoke(&bindings.InvokeRequest{
+			Operation: bindings.CreateOperation,
+			Data:      []byte("hello"),
+			Metadata:  map[string]string{"key": fmt.Sprintf("polled/%02d.txt", i)},
+		})
+		require.NoError(t, err)
+		select {
+		case created = <-events:
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+	assert.Equal(t, "created", created["eventType"])
+	assert.Equal(t, "bucket", created["bucket"])
+	assert.Contains(t, created["key"], "polled/")
+
+	_, err := s.Invoke(&bindings.InvokeRequest{
+		Operation: bindings.DeleteOperation,
+		Metadata:  map[string]string{"key": created["key"]},
+	})
+	require.NoError(t, err)
+	for {
+		select {
+		case e := <-events:
+			if e["eventType"] == "deleted" {
+				assert.Equal(t, created["key"], e["key"])
+
+				return
+			}
+		case <-time.After(5 * time.Second):
+			require.Fail(t, "timeout waiting for deleted event")
+		}
+	}
+}
+
+func TestParseQueueURL(t *testing.T) {
+	s := NewAWSS3(logger.NewLogger("test"))
+	meta, err := s.parseMetadata(bindings.Metadata{Properties: map[string]string{
+		"bucket": "bucket", "queueURL": "https://sqs.us-east-1.amazonaws.com/123456789012/events",
+	}})
+	require.NoError(t, err)
+	assert.Equal(t, "https://sqs.us-east-1.amazonaws.com/123456789012/events", meta.QueueURL)
+}
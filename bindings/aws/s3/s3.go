@@ -7,21 +7,38 @@ package s3
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"github.com/aws/aws-sdk-go/service/sqs"
 	aws_auth "github.com/dapr/components-contrib/authentication/aws"
 	"github.com/dapr/components-contrib/bindings"
+	contrib_objectstorage "github.com/dapr/components-contrib/internal/component/objectstorage"
 	"github.com/dapr/dapr/pkg/logger"
 	"github.com/google/uuid"
 )
 
+const metadataKey = "key"
+
 // AWSS3 is a binding for an AWS S3 storage bucket
 type AWSS3 struct {
-	metadata *s3Metadata
-	uploader *s3manager.Uploader
-	logger   logger.Logger
+	metadata     *s3Metadata
+	s3Client     *s3.S3
+	sqsClient    *sqs.SQS
+	uploader     *s3manager.Uploader
+	pollInterval time.Duration
+	ctx          context.Context
+	cancel       context.CancelFunc
+	logger       logger.Logger
+
+	// pollCheckpoint persists the checkpoint of the poller, nil to keep it in memory
+	pollCheckpoint *contrib_objectstorage.PollCheckpoint
 }
 
 type s3Metadata struct {
@@ -31,11 +48,23 @@ type s3Metadata struct {
 	SecretKey    string `json:"secretKey"`
 	SessionToken string `json:"sessionToken"`
 	Bucket       string `json:"bucket"`
+	DecodeBase64 bool   `json:"decodeBase64,string"`
+	// ForcePathStyle addresses buckets in the path of URLs, as required by most S3-compatible services
+	ForcePathStyle bool `json:"forcePathStyle,string"`
+	// QueueURL is the SQS queue receiving the event notifications of the bucket, which the
+	// input binding reads. The input binding polls the bucket when it is not set.
+	QueueURL string `json:"queueURL"`
 }
 
 // NewAWSS3 returns a new AWSS3 instance
 func NewAWSS3(logger logger.Logger) *AWSS3 {
-	return &AWSS3{logger: logger}
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &AWSS3{
+		ctx:    ctx,
+		cancel: cancel,
+		logger: logger,
+	}
 }
 
 // Init does metadata parsing and connection creation
@@ -44,37 +73,218 @@ func (s *AWSS3) Init(metadata bindings.Metadata) error {
 	if err != nil {
 		return err
 	}
-	uploader, err := s.getClient(m)
+	pollInterval, err := contrib_objectstorage.ParsePollInterval(metadata.Properties)
+	if err != nil {
+		return fmt.Errorf("s3 binding error: %s", err)
+	}
+	pollCheckpoint, err := contrib_objectstorage.NewPollCheckpoint(metadata, s.logger)
+	if err != nil {
+		return fmt.Errorf("s3 binding error: %s", err)
+	}
+	sess, err := aws_auth.GetClient(m.AccessKey, m.SecretKey, m.SessionToken, m.Region, m.Endpoint)
 	if err != nil {
 		return err
 	}
 	s.metadata = m
-	s.uploader = uploader
+	s.s3Client = s3.New(sess, aws.NewConfig().WithS3ForcePathStyle(m.ForcePathStyle))
+	s.sqsClient = sqs.New(sess)
+	s.uploader = s3manager.NewUploaderWithClient(s.s3Client)
+	s.pollInterval = pollInterval
+	s.pollCheckpoint = pollCheckpoint
 
 	return nil
 }
 
 func (s *AWSS3) Operations() []bindings.OperationKind {
-	return []bindings.OperationKind{bindings.CreateOperation}
+	return contrib_objectstorage.Operations()
 }
 
 func (s *AWSS3) Invoke(req *bindings.InvokeRequest) (*bindings.InvokeResponse, error) {
+	switch req.Operation {
+	case bindings.CreateOperation:
+		return s.create(req)
+	case bindings.GetOperation:
+		return s.get(req)
+	case bindings.DeleteOperation:
+		return s.delete(req)
+	case bindings.ListOperation:
+		return s.list(req)
+	case contrib_objectstorage.PresignOperation:
+		return s.presign(req)
+	default:
+		return nil, fmt.Errorf("s3 binding error: unsupported operation %s", req.Operation)
+	}
+}
+
+func (s *AWSS3) create(req *bindings.InvokeRequest) (*bindings.InvokeResponse, error) {
 	key := ""
-	if val, ok := req.Metadata["key"]; ok && val != "" {
+	if val, ok := req.Metadata[metadataKey]; ok && val != "" {
 		key = val
 	} else {
 		key = uuid.New().String()
 		s.logger.Debugf("key not found. generating key %s", key)
 	}
 
-	r := bytes.NewReader(req.Data)
-	_, err := s.uploader.Upload(&s3manager.UploadInput{
+	data, err := contrib_objectstorage.DecodeData(req.Data, s.metadata.DecodeBase64, req.Metadata)
+	if err != nil {
+		return nil, fmt.Errorf("s3 binding error: %s", err)
+	}
+
+	input := &s3manager.UploadInput{
+		Bucket:   aws.String(s.metadata.Bucket),
+		Key:      aws.String(key),
+		Body:     bytes.NewReader(data),
+		Metadata: aws.StringMap(contrib_objectstorage.UserMetadata(req.Metadata, metadataKey)),
+	}
+	if val, ok := req.Metadata[contrib_objectstorage.ContentTypeKey]; ok && val != "" {
+		input.ContentType = aws.String(val)
+	}
+
+	if _, err = s.uploader.Upload(input); err != nil {
+		return nil, fmt.Errorf("s3 binding error: uploading %s failed: %s", key, err)
+	}
+
+	return &bindings.InvokeResponse{
+		Metadata: map[string]string{metadataKey: key},
+	}, nil
+}
+
+func (s *AWSS3) get(req *bindings.InvokeRequest) (*bindings.InvokeResponse, error) {
+	key, err := requiredKey(req)
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := s.s3Client.GetObject(&s3.GetObjectInput{
 		Bucket: aws.String(s.metadata.Bucket),
 		Key:    aws.String(key),
-		Body:   r,
 	})
+	if err != nil {
+		return nil, fmt.Errorf("s3 binding error: getting %s failed: %s", key, err)
+	}
+	defer out.Body.Close()
+
+	data, err := ioutil.ReadAll(out.Body)
+	if err != nil {
+		return nil, fmt.Errorf("s3 binding error: reading %s failed: %s", key, err)
+	}
 
-	return nil, err
+	metadata := aws.StringValueMap(out.Metadata)
+	if out.ContentType != nil {
+		metadata[contrib_objectstorage.ContentTypeKey] = *out.ContentType
+	}
+
+	return &bindings.InvokeResponse{
+		Data:     data,
+		Metadata: metadata,
+	}, nil
+}
+
+func (s *AWSS3) delete(req *bindings.InvokeRequest) (*bindings.InvokeResponse, error) {
+	key, err := requiredKey(req)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = s.s3Client.DeleteObject(&s3.DeleteObjectInput{
+		Bucket: aws.String(s.metadata.Bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("s3 binding error: deleting %s failed: %s", key, err)
+	}
+
+	return nil, nil
+}
+
+func (s *AWSS3) list(req *bindings.InvokeRequest) (*bindings.InvokeResponse, error) {
+	r, err := contrib_objectstorage.ParseListRequest(req.Metadata)
+	if err != nil {
+		return nil, fmt.Errorf("s3 binding error: %s", err)
+	}
+
+	resp, err := s.listObjects(context.Background(), r)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := json.Marshal(resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return &bindings.InvokeResponse{Data: b}, nil
+}
+
+// listObjects returns a page of objects, whose next marker is the last key of
+// the page when it is truncated.
+func (s *AWSS3) listObjects(ctx context.Context, r contrib_objectstorage.ListRequest) (contrib_objectstorage.ListResponse, error) {
+	input := &s3.ListObjectsInput{
+		Bucket:  aws.String(s.metadata.Bucket),
+		MaxKeys: aws.Int64(int64(r.MaxResults)),
+	}
+	if r.Prefix != "" {
+		input.Prefix = aws.String(r.Prefix)
+	}
+	if r.Marker != "" {
+		input.Marker = aws.String(r.Marker)
+	}
+
+	out, err := s.s3Client.ListObjectsWithContext(ctx, input)
+	if err != nil {
+		return contrib_objectstorage.ListResponse{}, fmt.Errorf("s3 binding error: listing objects failed: %s", err)
+	}
+
+	resp := contrib_objectstorage.ListResponse{
+		Objects: make([]contrib_objectstorage.Object, 0, len(out.Contents)),
+	}
+	for _, o := range out.Contents {
+		resp.Objects = append(resp.Objects, contrib_objectstorage.Object{
+			Key:          aws.StringValue(o.Key),
+			Size:         aws.Int64Value(o.Size),
+			LastModified: aws.TimeValue(o.LastModified),
+			ETag:         aws.StringValue(o.ETag),
+		})
+	}
+	if aws.BoolValue(out.IsTruncated) {
+		resp.NextMarker = aws.StringValue(out.NextMarker)
+		if resp.NextMarker == "" && len(resp.Objects) > 0 {
+			resp.NextMarker = resp.Objects[len(resp.Objects)-1].Key
+		}
+	}
+
+	return resp, nil
+}
+
+func (s *AWSS3) presign(req *bindings.InvokeRequest) (*bindings.InvokeResponse, error) {
+	key, err := requiredKey(req)
+	if err != nil {
+		return nil, err
+	}
+
+	ttl, err := contrib_objectstorage.ParsePresignTTL(req.Metadata)
+	if err != nil {
+		return nil, fmt.Errorf("s3 binding error: %s", err)
+	}
+
+	objReq, _ := s.s3Client.GetObjectRequest(&s3.GetObjectInput{
+		Bucket: aws.String(s.metadata.Bucket),
+		Key:    aws.String(key),
+	})
+	url, err := objReq.Presign(ttl)
+	if err != nil {
+		return nil, fmt.Errorf("s3 binding error: presigning %s failed: %s", key, err)
+	}
+
+	b, err := json.Marshal(contrib_objectstorage.PresignResponse{
+		PresignedURL: url,
+		ExpiresAt:    time.Now().UTC().Add(ttl),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &bindings.InvokeResponse{Data: b}, nil
 }
 
 func (s *AWSS3) parseMetadata(metadata bindings.Metadata) (*s3Metadata, error) {
@@ -92,13 +302,10 @@ func (s *AWSS3) parseMetadata(metadata bindings.Metadata) (*s3Metadata, error) {
 	return &m, nil
 }
 
-func (s *AWSS3) getClient(metadata *s3Metadata) (*s3manager.Uploader, error) {
-	sess, err := aws_auth.GetClient(metadata.AccessKey, metadata.SecretKey, metadata.SessionToken, metadata.Region, metadata.Endpoint)
-	if err != nil {
-		return nil, err
+func requiredKey(req *bindings.InvokeRequest) (string, error) {
+	if val, ok := req.Metadata[metadataKey]; ok && val != "" {
+		return val, nil
 	}
 
-	uploader := s3manager.NewUploader(sess)
-
-	return uploader, nil
+	return "", fmt.Errorf("s3 binding error: required metadata %s missing", metadataKey)
 }
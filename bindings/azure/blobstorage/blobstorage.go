@@ -13,9 +13,12 @@ import (
 	"fmt"
 	"net/url"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/Azure/azure-storage-blob-go/azblob"
 	"github.com/dapr/components-contrib/bindings"
+	contrib_objectstorage "github.com/dapr/components-contrib/internal/component/objectstorage"
 	"github.com/dapr/dapr/pkg/logger"
 	"github.com/google/uuid"
 )
@@ -35,9 +38,16 @@ const (
 // AzureBlobStorage allows saving blobs to an Azure Blob Storage account
 type AzureBlobStorage struct {
 	metadata     *blobStorageMetadata
+	credential   *azblob.SharedKeyCredential
 	containerURL azblob.ContainerURL
+	pollInterval time.Duration
+	ctx          context.Context
+	cancel       context.CancelFunc
 
 	logger logger.Logger
+
+	// pollCheckpoint persists the checkpoint of the poller, nil to keep it in memory
+	pollCheckpoint *contrib_objectstorage.PollCheckpoint
 }
 
 type blobStorageMetadata struct {
@@ -47,6 +57,13 @@ type blobStorageMetadata struct {
 	DecodeBase64      string `json:"decodeBase64"`
 	GetBlobRetryCount int    `json:"getBlobRetryCount"`
 	PublicAccessLevel string `json:"publicAccessLevel"`
+	// Endpoint is the blob service URL of the account, for example the one of an emulator
+	Endpoint string `json:"endpoint"`
+	// QueueName is the storage queue receiving the Event Grid events of the blobs, which the
+	// input binding reads. The input binding polls the container when it is not set.
+	QueueName string `json:"queueName"`
+	// QueueEndpoint is the queue service URL of the account, for example the one of an emulator
+	QueueEndpoint string `json:"queueEndpoint"`
 }
 
 type createResponse struct {
@@ -55,7 +72,13 @@ type createResponse struct {
 
 // NewAzureBlobStorage returns a new Azure Blob Storage instance
 func NewAzureBlobStorage(logger logger.Logger) *AzureBlobStorage {
-	return &AzureBlobStorage{logger: logger}
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &AzureBlobStorage{
+		ctx:    ctx,
+		cancel: cancel,
+		logger: logger,
+	}
 }
 
 // Init performs metadata parsing
@@ -72,14 +95,21 @@ func (a *AzureBlobStorage) Init(metadata bindings.Metadata) error {
 	p := azblob.NewPipeline(credential, azblob.PipelineOptions{})
 
 	containerName := a.metadata.Container
-	URL, _ := url.Parse(
-		fmt.Sprintf("https://%s.blob.core.windows.net/%s", m.StorageAccount, containerName))
+	endpoint := fmt.Sprintf("https://%s.blob.core.windows.net", m.StorageAccount)
+	if m.Endpoint != "" {
+		endpoint = strings.TrimSuffix(m.Endpoint, "/")
+	}
+	URL, err := url.Parse(fmt.Sprintf("%s/%s", endpoint, containerName))
+	if err != nil {
+		return fmt.Errorf("invalid endpoint %s: %s", endpoint, err)
+	}
 	containerURL := azblob.NewContainerURL(*URL, p)
 
 	ctx := context.Background()
 	_, err = containerURL.Create(ctx, azblob.Metadata{}, azblob.PublicAccessType(m.PublicAccessLevel))
 	// Don't return error, container might already exist
 	a.logger.Debugf("error creating container: %s", err)
+	a.credential = credential
 	a.containerURL = containerURL
 
 	return nil
@@ -102,11 +132,21 @@ func (a *AzureBlobStorage) parseMetadata(metadata bindings.Metadata) (*blobStora
 		m.GetBlobRetryCount = defaultGetBlobRetryCount
 	}
 
+	a.pollInterval, err = contrib_objectstorage.ParsePollInterval(metadata.Properties)
+	if err != nil {
+		return nil, err
+	}
+
+	a.pollCheckpoint, err = contrib_objectstorage.NewPollCheckpoint(metadata, a.logger)
+	if err != nil {
+		return nil, err
+	}
+
 	return &m, nil
 }
 
 func (a *AzureBlobStorage) Operations() []bindings.OperationKind {
-	return []bindings.OperationKind{bindings.CreateOperation, bindings.GetOperation, bindings.DeleteOperation}
+	return contrib_objectstorage.Operations()
 }
 
 func (a *AzureBlobStorage) create(blobURL azblob.BlockBlobURL, req *bindings.InvokeRequest) (*bindings.InvokeResponse, error) {
@@ -114,6 +154,8 @@ func (a *AzureBlobStorage) create(blobURL azblob.BlockBlobURL, req *bindings.Inv
 	if val, ok := req.Metadata[contentType]; ok && val != "" {
 		blobHTTPHeaders.ContentType = val
 		delete(req.Metadata, contentType)
+	} else if val, ok := req.Metadata[contrib_objectstorage.ContentTypeKey]; ok && val != "" {
+		blobHTTPHeaders.ContentType = val
 	}
 	if val, ok := req.Metadata[contentMD5]; ok && val != "" {
 		sDec, err := b64.StdEncoding.DecodeString(val)
@@ -146,17 +188,14 @@ func (a *AzureBlobStorage) create(blobURL azblob.BlockBlobURL, req *bindings.Inv
 	}
 
 	// The "true" is the only allowed positive value. Other positive variations like "True" not acceptable.
-	if a.metadata.DecodeBase64 == "true" {
-		decoded, decodeError := b64.StdEncoding.DecodeString(string(req.Data))
-		if decodeError != nil {
-			return nil, decodeError
-		}
-		req.Data = decoded
+	req.Data, err = contrib_objectstorage.DecodeData(req.Data, a.metadata.DecodeBase64 == "true", req.Metadata)
+	if err != nil {
+		return nil, err
 	}
 
 	_, err = azblob.UploadBufferToBlockBlob(context.Background(), req.Data, blobURL, azblob.UploadToBlockBlobOptions{
 		Parallelism:     16,
-		Metadata:        req.Metadata,
+		Metadata:        contrib_objectstorage.UserMetadata(req.Metadata),
 		BlobHTTPHeaders: blobHTTPHeaders,
 	})
 	if err != nil {
@@ -190,8 +229,17 @@ func (a *AzureBlobStorage) get(blobURL azblob.BlockBlobURL, req *bindings.Invoke
 		return nil, fmt.Errorf("error reading az blob body: %s", err)
 	}
 
+	metadata := map[string]string(resp.NewMetadata())
+	if metadata == nil {
+		metadata = map[string]string{}
+	}
+	if val := resp.ContentType(); val != "" {
+		metadata[contrib_objectstorage.ContentTypeKey] = val
+	}
+
 	return &bindings.InvokeResponse{
-		Data: b.Bytes(),
+		Data:     b.Bytes(),
+		Metadata: metadata,
 	}, nil
 }
 
@@ -201,13 +249,113 @@ func (a *AzureBlobStorage) delete(blobURL azblob.BlockBlobURL, req *bindings.Inv
 	return nil, err
 }
 
+func (a *AzureBlobStorage) list(req *bindings.InvokeRequest) (*bindings.InvokeResponse, error) {
+	r, err := contrib_objectstorage.ParseListRequest(req.Metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := a.listObjects(context.Background(), r)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := json.Marshal(resp)
+	if err != nil {
+		return nil, fmt.Errorf("error marshalling list response for azure blob: %s", err)
+	}
+
+	return &bindings.InvokeResponse{
+		Data: b,
+	}, nil
+}
+
+func (a *AzureBlobStorage) listObjects(ctx context.Context, r contrib_objectstorage.ListRequest) (contrib_objectstorage.ListResponse, error) {
+	marker := azblob.Marker{}
+	if r.Marker != "" {
+		marker.Val = &r.Marker
+	}
+	segment, err := a.containerURL.ListBlobsFlatSegment(ctx, marker, azblob.ListBlobsSegmentOptions{
+		Prefix:     r.Prefix,
+		MaxResults: int32(r.MaxResults),
+	})
+	if err != nil {
+		return contrib_objectstorage.ListResponse{}, fmt.Errorf("error listing az blobs: %s", err)
+	}
+
+	resp := contrib_objectstorage.ListResponse{
+		Objects: make([]contrib_objectstorage.Object, 0, len(segment.Segment.BlobItems)),
+	}
+	for _, item := range segment.Segment.BlobItems {
+		o := contrib_objectstorage.Object{
+			Key:          item.Name,
+			LastModified: item.Properties.LastModified,
+			ETag:         string(item.Properties.Etag),
+		}
+		if item.Properties.ContentLength != nil {
+			o.Size = *item.Properties.ContentLength
+		}
+		resp.Objects = append(resp.Objects, o)
+	}
+	if segment.NextMarker.Val != nil {
+		resp.NextMarker = *segment.NextMarker.Val
+	}
+
+	return resp, nil
+}
+
+// presign returns a blob URL with a SAS token granting read access, signed with the account key.
+func (a *AzureBlobStorage) presign(blobURL azblob.BlockBlobURL, req *bindings.InvokeRequest) (*bindings.InvokeResponse, error) {
+	ttl, err := contrib_objectstorage.ParsePresignTTL(req.Metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	parts := azblob.NewBlobURLParts(blobURL.URL())
+	// The token is only valid over HTTPS, unless the endpoint is an emulator served over HTTP
+	protocol := azblob.SASProtocolHTTPS
+	if parts.Scheme == "http" {
+		protocol = azblob.SASProtocolHTTPSandHTTP
+	}
+	expiresAt := time.Now().UTC().Add(ttl)
+	parts.SAS, err = azblob.BlobSASSignatureValues{
+		Protocol:      protocol,
+		ExpiryTime:    expiresAt,
+		ContainerName: parts.ContainerName,
+		BlobName:      parts.BlobName,
+		Permissions:   azblob.BlobSASPermissions{Read: true}.String(),
+	}.NewSASQueryParameters(a.credential)
+	if err != nil {
+		return nil, fmt.Errorf("error presigning az blob: %s", err)
+	}
+
+	presignedURL := parts.URL()
+	b, err := json.Marshal(contrib_objectstorage.PresignResponse{
+		PresignedURL: presignedURL.String(),
+		ExpiresAt:    expiresAt,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error marshalling presign response for azure blob: %s", err)
+	}
+
+	return &bindings.InvokeResponse{
+		Data: b,
+	}, nil
+}
+
 func (a *AzureBlobStorage) Invoke(req *bindings.InvokeRequest) (*bindings.InvokeResponse, error) {
+	if req.Operation == bindings.ListOperation {
+		return a.list(req)
+	}
+
 	name := ""
 	if val, ok := req.Metadata[blobName]; ok && val != "" {
 		name = val
 		delete(req.Metadata, blobName)
-	} else {
+	} else if req.Operation == bindings.CreateOperation {
 		name = uuid.New().String()
+	} else {
+		return nil, fmt.Errorf("required metadata %s missing", blobName)
 	}
 
 	blobURL := a.containerURL.NewBlockBlobURL(name)
@@ -218,8 +366,8 @@ func (a *AzureBlobStorage) Invoke(req *bindings.InvokeRequest) (*bindings.Invoke
 		return a.get(blobURL, req)
 	case bindings.DeleteOperation:
 		return a.delete(blobURL, req)
-	case bindings.ListOperation:
-		fallthrough
+	case contrib_objectstorage.PresignOperation:
+		return a.presign(blobURL, req)
 	default:
 		return nil, fmt.Errorf("unsupported operation %s", req.Operation)
 	}
@@ -6,11 +6,24 @@
 package blobstorage
 
 import (
+	"encoding/json"
+	"net/url"
+	"os"
 	"testing"
+	"time"
 
+	"github.com/Azure/azure-storage-blob-go/azblob"
 	"github.com/dapr/components-contrib/bindings"
+	contrib_objectstorage "github.com/dapr/components-contrib/internal/component/objectstorage"
 	"github.com/dapr/dapr/pkg/logger"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// The well-known account of the Azurite emulator.
+const (
+	azuriteAccount = "devstoreaccount1"
+	azuriteKey     = "Eby8vdM02xNOcqFlqUwJPLlmEtlCDXJ1OUzFT50uSRZ6IFsuFq2UVErCz4I6tq/K1SZFPTOtr/KBHBeksoGMGw=="
 )
 
 func TestParseMetadata(t *testing.T) {
@@ -24,3 +37,116 @@ func TestParseMetadata(t *testing.T) {
 	assert.Equal(t, "key", meta.StorageAccessKey)
 	assert.Equal(t, "true", meta.DecodeBase64)
 }
+
+func TestOperations(t *testing.T) {
+	blobStorage := NewAzureBlobStorage(logger.NewLogger("test"))
+	l := blobStorage.Operations()
+	assert.Contains(t, l, bindings.ListOperation)
+	assert.Contains(t, l, contrib_objectstorage.PresignOperation)
+}
+
+func TestPresign(t *testing.T) {
+	credential, err := azblob.NewSharedKeyCredential(azuriteAccount, azuriteKey)
+	require.NoError(t, err)
+	containerURL, err := url.Parse("http://127.0.0.1:10000/" + azuriteAccount + "/test")
+	require.NoError(t, err)
+	blobStorage := NewAzureBlobStorage(logger.NewLogger("test"))
+	blobStorage.credential = credential
+	blobStorage.containerURL = azblob.NewContainerURL(*containerURL, azblob.NewPipeline(credential, azblob.PipelineOptions{}))
+
+	t.Run("returns a read-only SAS URL", func(t *testing.T) {
+		resp, err := blobStorage.Invoke(&bindings.InvokeRequest{
+			Operation: contrib_objectstorage.PresignOperation,
+			Metadata:  map[string]string{"blobName": "docs/a.txt", "presignTTL": "1m"},
+		})
+		require.NoError(t, err)
+
+		var presigned contrib_objectstorage.PresignResponse
+		require.NoError(t, json.Unmarshal(resp.Data, &presigned))
+		assert.WithinDuration(t, time.Now().Add(time.Minute), presigned.ExpiresAt, 5*time.Second)
+
+		u, err := url.Parse(presigned.PresignedURL)
+		require.NoError(t, err)
+		assert.Equal(t, "/"+azuriteAccount+"/test/docs/a.txt", u.Path)
+		assert.Equal(t, "r", u.Query().Get("sp"))
+		assert.Equal(t, "b", u.Query().Get("sr"))
+		assert.Equal(t, "https,http", u.Query().Get("spr"))
+		assert.NotEmpty(t, u.Query().Get("sig"))
+	})
+
+	t.Run("requires HTTPS for an HTTPS endpoint", func(t *testing.T) {
+		accountURL, err := url.Parse("https://" + azuriteAccount + ".blob.core.windows.net/test")
+		require.NoError(t, err)
+		blobStorage := NewAzureBlobStorage(logger.NewLogger("test"))
+		blobStorage.credential = credential
+		blobStorage.containerURL = azblob.NewContainerURL(*accountURL, azblob.NewPipeline(credential, azblob.PipelineOptions{}))
+
+		resp, err := blobStorage.Invoke(&bindings.InvokeRequest{
+			Operation: contrib_objectstorage.PresignOperation,
+			Metadata:  map[string]string{"blobName": "docs/a.txt"},
+		})
+		require.NoError(t, err)
+
+		var presigned contrib_objectstorage.PresignResponse
+		require.NoError(t, json.Unmarshal(resp.Data, &presigned))
+		u, err := url.Parse(presigned.PresignedURL)
+		require.NoError(t, err)
+		assert.Equal(t, "https", u.Query().Get("spr"))
+	})
+
+	t.Run("requires the blob name", func(t *testing.T) {
+		_, err := blobStorage.Invoke(&bindings.InvokeRequest{
+			Operation: contrib_objectstorage.PresignOperation,
+			Metadata:  map[string]string{},
+		})
+		assert.Error(t, err)
+	})
+}
+
+// TestAzuriteIntegration runs against the Azurite emulator, for example
+// AZURITE_BLOB_ENDPOINT=http://127.0.0.1:10000/devstoreaccount1
+func TestAzuriteIntegration(t *testing.T) {
+	endpoint := os.Getenv("AZURITE_BLOB_ENDPOINT")
+	if endpoint == "" {
+		t.Skip("AZURITE_BLOB_ENDPOINT not set, skipping")
+	}
+
+	blobStorage := NewAzureBlobStorage(logger.NewLogger("test"))
+	err := blobStorage.Init(bindings.Metadata{Properties: map[string]string{
+		"storageAccount": azuriteAccount, "storageAccessKey": azuriteKey, "container": "integration",
+		"endpoint": endpoint,
+	}})
+	require.NoError(t, err)
+
+	_, err = blobStorage.Invoke(&bindings.InvokeRequest{
+		Operation: bindings.CreateOperation,
+		Data:      []byte("hello"),
+		Metadata:  map[string]string{"blobName": "docs/a.txt", "contentType": "text/plain", "owner": "dapr"},
+	})
+	require.NoError(t, err)
+
+	resp, err := blobStorage.Invoke(&bindings.InvokeRequest{
+		Operation: bindings.GetOperation,
+		Metadata:  map[string]string{"blobName": "docs/a.txt"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(resp.Data))
+	assert.Equal(t, "text/plain", resp.Metadata["contentType"])
+	assert.Equal(t, "dapr", resp.Metadata["owner"])
+
+	resp, err = blobStorage.Invoke(&bindings.InvokeRequest{
+		Operation: bindings.ListOperation,
+		Metadata:  map[string]string{"prefix": "docs/"},
+	})
+	require.NoError(t, err)
+	var page contrib_objectstorage.ListResponse
+	require.NoError(t, json.Unmarshal(resp.Data, &page))
+	require.Len(t, page.Objects, 1)
+	assert.Equal(t, int64(5), page.Objects[0].Size)
+
+	_, err = blobStorage.Invoke(&bindings.InvokeRequest{
+		Operation: bindings.DeleteOperation,
+		Metadata:  map[string]string{"blobName": "docs/a.txt"},
+	})
+	require.NoError(t, err)
+}
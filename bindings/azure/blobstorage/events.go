@@ -0,0 +1,189 @@
+// ------------------------------------------------------------
+// Copyright (c) Microsoft Corporation and Dapr Contributors.
+// Licensed under the MIT License.
+// ------------------------------------------------------------
+
+package blobstorage
+
+import (
+	b64 "encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-storage-queue-go/azqueue"
+	"github.com/dapr/components-contrib/bindings"
+	contrib_objectstorage "github.com/dapr/components-contrib/internal/component/objectstorage"
+)
+
+const (
+	blobCreatedEvent = "Microsoft.Storage.BlobCreated"
+	blobDeletedEvent = "Microsoft.Storage.BlobDeleted"
+
+	// the number of messages dequeued at once, and how long they are invisible to other consumers
+	dequeueMaxMessages = 32
+	visibilityTimeout  = 30 * time.Second
+)
+
+// queueBackoff is how long the input binding waits when the queue is empty or cannot be read
+var queueBackoff = 5 * time.Second
+
+// blobEvent is a blob event of Event Grid, in the Event Grid or the CloudEvents schema, see
+// https://docs.microsoft.com/azure/event-grid/event-schema-blob-storage
+type blobEvent struct {
+	EventType string    `json:"eventType"`
+	Type      string    `json:"type"`
+	Subject   string    `json:"subject"`
+	EventTime time.Time `json:"eventTime"`
+	Time      time.Time `json:"time"`
+	Data      struct {
+		ETag          string `json:"eTag"`
+		ContentLength int64  `json:"contentLength"`
+	} `json:"data"`
+}
+
+// Read triggers handler with the events of the blobs of the container, read from the
+// storage queue receiving their Event Grid events or, when there is none, by polling
+// the container.
+func (a *AzureBlobStorage) Read(handler func(*bindings.ReadResponse) error) error {
+	if a.metadata.QueueName == "" {
+		a.logger.Warnf("queueName not set, polling container %s every %s: each replica emits the events", a.metadata.Container, a.pollInterval)
+
+		return contrib_objectstorage.NewPoller(a.metadata.Container, a.pollInterval, a.listObjects, a.pollCheckpoint, a.logger).Run(a.ctx, handler)
+	}
+
+	credential, err := azqueue.NewSharedKeyCredential(a.metadata.StorageAccount, a.metadata.StorageAccessKey)
+	if err != nil {
+		return fmt.Errorf("invalid credentials with error: %s", err.Error())
+	}
+	endpoint := fmt.Sprintf("https://%s.queue.core.windows.net", a.metadata.StorageAccount)
+	if a.metadata.QueueEndpoint != "" {
+		endpoint = strings.TrimSuffix(a.metadata.QueueEndpoint, "/")
+	}
+	u, err := url.Parse(fmt.Sprintf("%s/%s", endpoint, a.metadata.QueueName))
+	if err != nil {
+		return fmt.Errorf("invalid queue endpoint %s: %s", endpoint, err)
+	}
+	messagesURL := azqueue.NewQueueURL(*u, azqueue.NewPipeline(credential, azqueue.PipelineOptions{})).NewMessagesURL()
+
+	for {
+		res, err := messagesURL.Dequeue(a.ctx, dequeueMaxMessages, visibilityTimeout)
+		if a.ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			a.logger.Errorf("error receiving events from queue %s: %s", a.metadata.QueueName, err)
+		}
+		if err != nil || res.NumMessages() == 0 {
+			select {
+			case <-a.ctx.Done():
+				return nil
+			case <-time.After(queueBackoff):
+			}
+
+			continue
+		}
+
+		for i := int32(0); i < res.NumMessages(); i++ {
+			m := res.Message(i)
+			if err = a.handleMessage(m.Text, handler); err != nil {
+				a.logger.Errorf("error handling message %s of queue %s: %s", m.ID, a.metadata.QueueName, err)
+
+				continue
+			}
+
+			if _, err = messagesURL.NewMessageIDURL(m.ID).Delete(a.ctx, m.PopReceipt); err != nil {
+				a.logger.Errorf("error deleting message %s of queue %s: %s", m.ID, a.metadata.QueueName, err)
+			}
+		}
+	}
+}
+
+// handleMessage triggers handler with the events of a message. The message is
+// left in the queue to be retried when one of them fails.
+func (a *AzureBlobStorage) handleMessage(text string, handler func(*bindings.ReadResponse) error) error {
+	events, err := parseMessage(text, a.metadata.Container)
+	if err != nil {
+		return err
+	}
+
+	for _, e := range events {
+		if err = e.Handle(handler); err != nil {
+			return fmt.Errorf("error handling %s event of %s: %s", e.EventType, e.Key, err)
+		}
+	}
+
+	return nil
+}
+
+// parseMessage returns the events of the created and deleted blobs of container
+// in a queue message, which has an event or an array of events encoded in base64
+// by Event Grid. The events of the other containers of the account are skipped,
+// as Event Grid subscriptions of storage accounts aren't scoped to a container.
+func parseMessage(text string, container string) ([]contrib_objectstorage.Event, error) {
+	data := []byte(strings.TrimSpace(text))
+	if len(data) > 0 && data[0] != '{' && data[0] != '[' {
+		decoded, err := b64.StdEncoding.DecodeString(string(data))
+		if err != nil {
+			return nil, fmt.Errorf("invalid message: %s", err)
+		}
+		data = decoded
+	}
+
+	var blobEvents []blobEvent
+	if len(data) > 0 && data[0] == '{' {
+		data = append(append([]byte{'['}, data...), ']')
+	}
+	if err := json.Unmarshal(data, &blobEvents); err != nil {
+		return nil, fmt.Errorf("invalid message: %s", err)
+	}
+
+	events := make([]contrib_objectstorage.Event, 0, len(blobEvents))
+	for _, be := range blobEvents {
+		e := contrib_objectstorage.Event{
+			Size:      be.Data.ContentLength,
+			ETag:      be.Data.ETag,
+			EventTime: be.EventTime,
+		}
+		if e.EventTime.IsZero() {
+			e.EventTime = be.Time
+		}
+
+		eventType := be.EventType
+		if eventType == "" {
+			eventType = be.Type
+		}
+		switch eventType {
+		case blobCreatedEvent:
+			e.EventType = contrib_objectstorage.EventCreated
+		case blobDeletedEvent:
+			e.EventType = contrib_objectstorage.EventDeleted
+		default:
+			continue
+		}
+
+		// The subject is /blobServices/default/containers/<container>/blobs/<blob>
+		parts := strings.SplitN(be.Subject, "/", 7)
+		if len(parts) != 7 || parts[3] != "containers" || parts[5] != "blobs" {
+			return nil, fmt.Errorf("invalid subject %s in event", be.Subject)
+		}
+		if parts[4] != container {
+			continue
+		}
+		e.Bucket = parts[4]
+		e.Key = parts[6]
+
+		events = append(events, e)
+	}
+
+	return events, nil
+}
+
+// Close stops reading events
+func (a *AzureBlobStorage) Close() error {
+	a.cancel()
+
+	return nil
+}
@@ -0,0 +1,172 @@
+// ------------------------------------------------------------
+// Copyright (c) Microsoft Corporation and Dapr Contributors.
+// Licensed under the MIT License.
+// ------------------------------------------------------------
+
+package blobstorage
+
+import (
+	b64 "encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dapr/components-contrib/bindings"
+	contrib_objectstorage "github.com/dapr/components-contrib/internal/component/objectstorage"
+	"github.com/dapr/dapr/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testBlobCreatedEvent = `{
+	"topic": "/subscriptions/id/resourceGroups/group/providers/Microsoft.Storage/storageAccounts/account",
+	"subject": "/blobServices/default/containers/test/blobs/docs/a.txt",
+	"eventType": "Microsoft.Storage.BlobCreated",
+	"eventTime": "2021-01-22T10:30:00Z",
+	"data": {"api": "PutBlob", "eTag": "0x8D8BEBB1B4F8C6A", "contentLength": 5, "blobType": "BlockBlob"}
+}`
+
+func TestParseMessage(t *testing.T) {
+	created := contrib_objectstorage.Event{
+		EventType: contrib_objectstorage.EventCreated,
+		Bucket:    "test",
+		Key:       "docs/a.txt",
+		Size:      5,
+		ETag:      "0x8D8BEBB1B4F8C6A",
+		EventTime: time.Date(2021, 1, 22, 10, 30, 0, 0, time.UTC),
+	}
+
+	t.Run("base64 encoded event", func(t *testing.T) {
+		events, err := parseMessage(b64.StdEncoding.EncodeToString([]byte(testBlobCreatedEvent)), "test")
+		require.NoError(t, err)
+		assert.Equal(t, []contrib_objectstorage.Event{created}, events)
+	})
+
+	t.Run("array of events", func(t *testing.T) {
+		events, err := parseMessage(`[`+testBlobCreatedEvent+`, {
+			"subject": "/blobServices/default/containers/test/blobs/docs/b.txt",
+			"eventType": "Microsoft.Storage.BlobDeleted",
+			"eventTime": "2021-01-22T10:31:00Z"
+		}, {
+			"subject": "/blobServices/default/containers/test/blobs/docs/c.txt",
+			"eventType": "Microsoft.Storage.BlobTierChanged"
+		}, {
+			"subject": "/blobServices/default/containers/other/blobs/docs/d.txt",
+			"eventType": "Microsoft.Storage.BlobCreated"
+		}]`, "test")
+		require.NoError(t, err)
+		require.Len(t, events, 2)
+		assert.Equal(t, created, events[0])
+		assert.Equal(t, contrib_objectstorage.EventDeleted, events[1].EventType)
+		assert.Equal(t, "docs/b.txt", events[1].Key)
+	})
+
+	t.Run("cloud event", func(t *testing.T) {
+		events, err := parseMessage(`{
+			"specversion": "1.0",
+			"type": "Microsoft.Storage.BlobCreated",
+			"subject": "/blobServices/default/containers/test/blobs/docs/a.txt",
+			"time": "2021-01-22T10:30:00Z",
+			"data": {"eTag": "0x8D8BEBB1B4F8C6A", "contentLength": 5}
+		}`, "test")
+		require.NoError(t, err)
+		assert.Equal(t, []contrib_objectstorage.Event{created}, events)
+	})
+
+	t.Run("events of other containers are skipped", func(t *testing.T) {
+		events, err := parseMessage(testBlobCreatedEvent, "other")
+		require.NoError(t, err)
+		assert.Empty(t, events)
+	})
+
+	t.Run("invalid messages", func(t *testing.T) {
+		for _, text := range []string{"not base64!", `{"eventType": "Microsoft.Storage.BlobCreated", "subject": "/blobServices/default"}`} {
+			_, err := parseMessage(text, "test")
+			assert.Error(t, err, text)
+		}
+	})
+}
+
+// fakeQueue serves the messages of a queue once, and records the deleted messages.
+type fakeQueue struct {
+	lock     sync.Mutex
+	messages []string
+	deleted  []string
+}
+
+func (f *fakeQueue) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	switch r.Method {
+	case http.MethodGet:
+		now := time.Now().UTC().Format(http.TimeFormat)
+		var b strings.Builder
+		b.WriteString("<QueueMessagesList>")
+		for i, text := range f.messages {
+			fmt.Fprintf(&b, "<QueueMessage><MessageId>%d</MessageId><InsertionTime>%s</InsertionTime>"+
+				"<ExpirationTime>%s</ExpirationTime><PopReceipt>receipt</PopReceipt><TimeNextVisible>%s</TimeNextVisible>"+
+				"<DequeueCount>1</DequeueCount><MessageText>%s</MessageText></QueueMessage>", i, now, now, now, text)
+		}
+		b.WriteString("</QueueMessagesList>")
+		f.messages = nil
+		w.Header().Set("Content-Type", "application/xml")
+		_, _ = w.Write([]byte(b.String()))
+	case http.MethodDelete:
+		f.deleted = append(f.deleted, r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:])
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func TestReadQueue(t *testing.T) {
+	queueBackoff = 10 * time.Millisecond
+	queue := &fakeQueue{messages: []string{
+		b64.StdEncoding.EncodeToString([]byte(testBlobCreatedEvent)),
+		b64.StdEncoding.EncodeToString([]byte("invalid")),
+	}}
+	server := httptest.NewServer(queue)
+	defer server.Close()
+
+	a := NewAzureBlobStorage(logger.NewLogger("test"))
+	a.metadata = &blobStorageMetadata{
+		StorageAccount: azuriteAccount, StorageAccessKey: azuriteKey, Container: "test",
+		QueueName: "events", QueueEndpoint: server.URL + "/" + azuriteAccount,
+	}
+
+	events := make(chan *bindings.ReadResponse, 10)
+	done := make(chan error)
+	go func() {
+		done <- a.Read(func(resp *bindings.ReadResponse) error {
+			events <- resp
+
+			return nil
+		})
+	}()
+
+	select {
+	case resp := <-events:
+		assert.Equal(t, map[string]string{"eventType": "created", "bucket": "test", "key": "docs/a.txt"}, resp.Metadata)
+	case <-time.After(5 * time.Second):
+		assert.Fail(t, "timeout waiting for event")
+	}
+
+	// The invalid message is left in the queue
+	assert.Eventually(t, func() bool {
+		queue.lock.Lock()
+		defer queue.lock.Unlock()
+
+		return len(queue.deleted) == 1 && queue.deleted[0] == "0"
+	}, 5*time.Second, 10*time.Millisecond)
+
+	require.NoError(t, a.Close())
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		assert.Fail(t, "timeout waiting for Read to return")
+	}
+}
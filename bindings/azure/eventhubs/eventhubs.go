@@ -19,6 +19,7 @@ import (
 	"github.com/Azure/azure-storage-blob-go/azblob"
 	"github.com/Azure/go-autorest/autorest/azure"
 	"github.com/dapr/components-contrib/bindings"
+	contrib_eventhubs "github.com/dapr/components-contrib/internal/component/azure/eventhubs"
 	"github.com/dapr/dapr/pkg/logger"
 )
 
@@ -27,7 +28,9 @@ const (
 	connectionString = "connectionString"
 
 	// required by subscriber
-	consumerGroup        = "consumerGroup"
+	consumerGroup = "consumerGroup"
+
+	// required by subscriber unless checkpointStore is set
 	storageAccountName   = "storageAccountName"
 	storageAccountKey    = "storageAccountKey"
 	storageContainerName = "storageContainerName"
@@ -58,8 +61,10 @@ type azureEventHubsMetadata struct {
 	storageAccountName   string
 	storageAccountKey    string
 	storageContainerName string
-	partitionID          string
-	partitionKey         string
+	// the metadata of the state store keeping checkpoints, nil when checkpoints are kept in Azure Blob Storage
+	checkpointStoreProperties map[string]string
+	partitionID               string
+	partitionKey              string
 }
 
 func (m azureEventHubsMetadata) partitioned() bool {
@@ -104,22 +109,26 @@ func parseMetadata(meta bindings.Metadata) (*azureEventHubsMetadata, error) {
 		return m, errors.New(missingConnectionStringErrorMsg)
 	}
 
-	if val, ok := meta.Properties[storageAccountName]; ok && val != "" {
-		m.storageAccountName = val
+	if contrib_eventhubs.UsesCheckpointStore(meta.Properties) {
+		m.checkpointStoreProperties = meta.Properties
 	} else {
-		return m, errors.New(missingStorageAccountNameErrorMsg)
-	}
+		if val, ok := meta.Properties[storageAccountName]; ok && val != "" {
+			m.storageAccountName = val
+		} else {
+			return m, errors.New(missingStorageAccountNameErrorMsg)
+		}
 
-	if val, ok := meta.Properties[storageAccountKey]; ok && val != "" {
-		m.storageAccountKey = val
-	} else {
-		return m, errors.New(missingStorageAccountKeyErrorMsg)
-	}
+		if val, ok := meta.Properties[storageAccountKey]; ok && val != "" {
+			m.storageAccountKey = val
+		} else {
+			return m, errors.New(missingStorageAccountKeyErrorMsg)
+		}
 
-	if val, ok := meta.Properties[storageContainerName]; ok && val != "" {
-		m.storageContainerName = val
-	} else {
-		return m, errors.New(missingStorageContainerNameErrorMsg)
+		if val, ok := meta.Properties[storageContainerName]; ok && val != "" {
+			m.storageContainerName = val
+		} else {
+			return m, errors.New(missingStorageContainerNameErrorMsg)
+		}
 	}
 
 	if val, ok := meta.Properties[consumerGroup]; ok && val != "" {
@@ -242,12 +251,7 @@ func contains(arr []string, str string) bool {
 // RegisterEventProcessor - receive eventhub messages by eventprocessor
 // host by balancing partitions
 func (a *AzureEventHubs) RegisterEventProcessor(handler func(*bindings.ReadResponse) error) error {
-	cred, err := azblob.NewSharedKeyCredential(a.metadata.storageAccountName, a.metadata.storageAccountKey)
-	if err != nil {
-		return err
-	}
-
-	leaserCheckpointer, err := storage.NewStorageLeaserCheckpointer(cred, a.metadata.storageAccountName, a.metadata.storageContainerName, azure.PublicCloud)
+	leaserCheckpointer, err := a.leaserCheckpointer()
 	if err != nil {
 		return err
 	}
@@ -272,3 +276,18 @@ func (a *AzureEventHubs) RegisterEventProcessor(handler func(*bindings.ReadRespo
 
 	return nil
 }
+
+// leaserCheckpointer returns the leaser and checkpointer of the event processor host,
+// which keeps checkpoints in the configured checkpoint store or in Azure Blob Storage.
+func (a *AzureEventHubs) leaserCheckpointer() (contrib_eventhubs.LeaserCheckpointer, error) {
+	if a.metadata.checkpointStoreProperties != nil {
+		return contrib_eventhubs.NewCheckpointStore(a.metadata.checkpointStoreProperties, a.metadata.connectionString, a.metadata.consumerGroup, a.logger)
+	}
+
+	cred, err := azblob.NewSharedKeyCredential(a.metadata.storageAccountName, a.metadata.storageAccountKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return storage.NewStorageLeaserCheckpointer(cred, a.metadata.storageAccountName, a.metadata.storageContainerName, azure.PublicCloud)
+}
@@ -28,6 +28,18 @@ func TestParseMetadata(t *testing.T) {
 		assert.Equal(t, m.consumerGroup, "mygroup")
 	})
 
+	t.Run("test checkpoint store configuration", func(t *testing.T) {
+		props := map[string]string{connectionString: "fake", consumerGroup: "mygroup", "checkpointStore": "memory"}
+
+		bindingsMetadata := bindings.Metadata{Properties: props}
+
+		m, err := parseMetadata(bindingsMetadata)
+
+		assert.NoError(t, err)
+		assert.Equal(t, props, m.checkpointStoreProperties)
+		assert.Empty(t, m.storageAccountName)
+	})
+
 	type invalidConfigTestCase struct {
 		name   string
 		config map[string]string
@@ -8,10 +8,13 @@ package servicebusqueues
 import (
 	"context"
 	"encoding/json"
+	"fmt"
+	"strconv"
 	"time"
 
 	servicebus "github.com/Azure/azure-service-bus-go"
 	"github.com/dapr/components-contrib/bindings"
+	contrib_servicebus "github.com/dapr/components-contrib/internal/component/azure/servicebus"
 	contrib_metadata "github.com/dapr/components-contrib/metadata"
 	"github.com/dapr/dapr/pkg/logger"
 )
@@ -21,6 +24,14 @@ const (
 	label         = "label"
 	id            = "id"
 
+	requireSessions         = "requireSessions"
+	maxConcurrentHandlers   = "maxConcurrentHandlers"
+	lockRenewalInSec        = "lockRenewalInSec"
+	sessionIdleTimeoutInSec = "sessionIdleTimeoutInSec"
+
+	defaultLockRenewalInSec = 20
+	defaultTimeoutInSec     = 60
+
 	// AzureServiceBusDefaultMessageTimeToLive defines the default time to live for queues, which is 14 days. The same way Azure Portal does.
 	AzureServiceBusDefaultMessageTimeToLive = time.Hour * 24 * 14
 )
@@ -37,6 +48,12 @@ type serviceBusQueuesMetadata struct {
 	ConnectionString string `json:"connectionString"`
 	QueueName        string `json:"queueName"`
 	ttl              time.Duration
+
+	// Sessions
+	requireSessions         bool
+	maxConcurrentHandlers   int
+	lockRenewalInSec        int
+	sessionIdleTimeoutInSec int
 }
 
 // NewAzureServiceBusQueues returns a new AzureServiceBusQueues instance
@@ -88,7 +105,12 @@ func (a *AzureServiceBusQueues) Init(metadata bindings.Metadata) error {
 			ttl = a.metadata.ttl
 		}
 
-		entity, err = qm.Put(ctx, a.metadata.QueueName, servicebus.QueueEntityWithMessageTimeToLive(&ttl))
+		opts := []servicebus.QueueManagementOption{servicebus.QueueEntityWithMessageTimeToLive(&ttl)}
+		if a.metadata.requireSessions {
+			opts = append(opts, servicebus.QueueEntityWithRequiredSessions())
+		}
+
+		entity, err = qm.Put(ctx, a.metadata.QueueName, opts...)
 		if err != nil {
 			return err
 		}
@@ -127,6 +149,33 @@ func (a *AzureServiceBusQueues) parseMetadata(metadata bindings.Metadata) (*serv
 
 	m.ttl = ttl
 
+	m.maxConcurrentHandlers = contrib_servicebus.DefaultMaxConcurrentSessions
+	m.lockRenewalInSec = defaultLockRenewalInSec
+	m.sessionIdleTimeoutInSec = contrib_servicebus.DefaultSessionIdleTimeoutInSec
+
+	if val, ok := metadata.Properties[requireSessions]; ok && val != "" {
+		m.requireSessions, err = strconv.ParseBool(val)
+		if err != nil {
+			return nil, fmt.Errorf("invalid requireSessions %s, %s", val, err)
+		}
+	}
+
+	for key, value := range map[string]*int{
+		maxConcurrentHandlers:   &m.maxConcurrentHandlers,
+		lockRenewalInSec:        &m.lockRenewalInSec,
+		sessionIdleTimeoutInSec: &m.sessionIdleTimeoutInSec,
+	} {
+		if val, ok := metadata.Properties[key]; ok && val != "" {
+			*value, err = strconv.Atoi(val)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s %s, %s", key, val, err)
+			}
+			if *value <= 0 {
+				return nil, fmt.Errorf("invalid %s %s, must be greater than 0", key, val)
+			}
+		}
+	}
+
 	return &m, nil
 }
 
@@ -145,6 +194,9 @@ func (a *AzureServiceBusQueues) Invoke(req *bindings.InvokeRequest) (*bindings.I
 	if val, ok := req.Metadata[correlationID]; ok && val != "" {
 		msg.CorrelationID = val
 	}
+	if val, ok := req.Metadata[contrib_servicebus.SessionIDMetadataKey]; ok && val != "" {
+		msg.SessionID = &val
+	}
 
 	ttl, ok, err := contrib_metadata.TryGetTTL(req.Metadata)
 	if err != nil {
@@ -160,9 +212,13 @@ func (a *AzureServiceBusQueues) Invoke(req *bindings.InvokeRequest) (*bindings.I
 
 func (a *AzureServiceBusQueues) Read(handler func(*bindings.ReadResponse) error) error {
 	var sbHandler servicebus.HandlerFunc = func(ctx context.Context, msg *servicebus.Message) error {
+		metadata := map[string]string{id: msg.ID, correlationID: msg.CorrelationID, label: msg.Label}
+		if msg.SessionID != nil {
+			metadata[contrib_servicebus.SessionIDMetadataKey] = *msg.SessionID
+		}
 		err := handler(&bindings.ReadResponse{
 			Data:     msg.Data,
-			Metadata: map[string]string{id: msg.ID, correlationID: msg.CorrelationID, label: msg.Label},
+			Metadata: metadata,
 		})
 		if err == nil {
 			return msg.Complete(ctx)
@@ -171,6 +227,19 @@ func (a *AzureServiceBusQueues) Read(handler func(*bindings.ReadResponse) error)
 		return msg.Abandon(ctx)
 	}
 
+	if a.metadata.requireSessions {
+		newSession := func() contrib_servicebus.Session {
+			return a.client.NewSession(nil)
+		}
+
+		return contrib_servicebus.ReceiveSessions(context.Background(), newSession, sbHandler, contrib_servicebus.SessionOptions{
+			MaxConcurrentSessions: a.metadata.maxConcurrentHandlers,
+			LockRenewalInterval:   time.Second * time.Duration(a.metadata.lockRenewalInSec),
+			IdleTimeout:           time.Second * time.Duration(a.metadata.sessionIdleTimeoutInSec),
+			CloseTimeout:          time.Second * defaultTimeoutInSec,
+		}, a.logger)
+	}
+
 	if err := a.client.Receive(context.Background(), sbHandler); err != nil {
 		return err
 	}
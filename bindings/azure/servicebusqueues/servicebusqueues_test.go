@@ -92,3 +92,48 @@ func TestParseMetadataWithInvalidTTL(t *testing.T) {
 		})
 	}
 }
+
+func TestParseSessionMetadata(t *testing.T) {
+	a := NewAzureServiceBusQueues(logger.NewLogger("test"))
+
+	t.Run("defaults", func(t *testing.T) {
+		meta, err := a.parseMetadata(bindings.Metadata{Properties: map[string]string{"connectionString": "connString", "queueName": "queue1"}})
+		assert.Nil(t, err)
+		assert.False(t, meta.requireSessions)
+		assert.Equal(t, 8, meta.maxConcurrentHandlers)
+		assert.Equal(t, 20, meta.lockRenewalInSec)
+		assert.Equal(t, 60, meta.sessionIdleTimeoutInSec)
+	})
+
+	t.Run("sessions", func(t *testing.T) {
+		meta, err := a.parseMetadata(bindings.Metadata{Properties: map[string]string{
+			"connectionString":        "connString",
+			"queueName":               "queue1",
+			"requireSessions":         "true",
+			"maxConcurrentHandlers":   "2",
+			"lockRenewalInSec":        "10",
+			"sessionIdleTimeoutInSec": "5",
+		}})
+		assert.Nil(t, err)
+		assert.True(t, meta.requireSessions)
+		assert.Equal(t, 2, meta.maxConcurrentHandlers)
+		assert.Equal(t, 10, meta.lockRenewalInSec)
+		assert.Equal(t, 5, meta.sessionIdleTimeoutInSec)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		for _, key := range []string{"requireSessions", "maxConcurrentHandlers", "lockRenewalInSec", "sessionIdleTimeoutInSec"} {
+			_, err := a.parseMetadata(bindings.Metadata{Properties: map[string]string{"connectionString": "connString", "queueName": "queue1", key: "abc"}})
+			assert.NotNil(t, err, key)
+		}
+	})
+
+	t.Run("not positive", func(t *testing.T) {
+		for _, key := range []string{"maxConcurrentHandlers", "lockRenewalInSec", "sessionIdleTimeoutInSec"} {
+			for _, val := range []string{"0", "-1"} {
+				_, err := a.parseMetadata(bindings.Metadata{Properties: map[string]string{"connectionString": "connString", "queueName": "queue1", key: val}})
+				assert.NotNil(t, err, key+"="+val)
+			}
+		}
+	})
+}
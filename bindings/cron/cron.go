@@ -6,33 +6,87 @@
 package cron
 
 import (
+	"context"
 	"fmt"
+	"math/rand"
+	"strconv"
+	"sync"
 	"time"
 
 	"github.com/dapr/components-contrib/bindings"
+	"github.com/dapr/components-contrib/internal/component/statestore"
+	"github.com/dapr/components-contrib/state"
 	"github.com/dapr/dapr/pkg/logger"
 	"github.com/pkg/errors"
 	"github.com/robfig/cron/v3"
 )
 
+const (
+	// keys from component's metadata
+	scheduleMetadata = "schedule"
+	// timeZoneMetadata is the IANA name of the time zone of the schedule, local by default
+	timeZoneMetadata = "timeZone"
+	// payloadMetadata is the data of the runs
+	payloadMetadata = "payload"
+	// jitterMetadata delays each run by a random duration up to jitter
+	jitterMetadata = "jitter"
+	// lockStoreMetadata selects the state store that ensures that only one
+	// replica fires each run, see lock.go
+	lockStoreMetadata = "lockStore"
+	// lockKeyMetadata is the key of the record of the last run in the lock store,
+	// the name of the binding by default
+	lockKeyMetadata = "lockKey"
+	// catchUpMetadata fires the runs missed since the last run of the lock store when reading starts
+	catchUpMetadata = "catchUp"
+	// maxCatchUpMetadata is the maximum number of missed runs fired, the most recent ones
+	maxCatchUpMetadata = "maxCatchUp"
+
+	defaultMaxCatchUp = 10
+
+	// list of operations.
+	stopOperation  bindings.OperationKind = "stop"
+	pauseOperation bindings.OperationKind = "pause"
+	startOperation bindings.OperationKind = "start"
+)
+
 // Binding represents Cron input binding
 type Binding struct {
-	logger   logger.Logger
-	schedule string
-	stopCh   chan bool
-	parser   cron.Parser
+	logger     logger.Logger
+	schedule   string
+	parser     cron.Parser
+	sched      cron.Schedule
+	location   *time.Location
+	payload    []byte
+	jitter     time.Duration
+	lock       *tickLock
+	catchUp    bool
+	maxCatchUp int
+	// newStore creates the lock store, it is replaced in tests
+	newStore func(properties map[string]string, key string, logger logger.Logger) (state.Store, error)
+
+	paused bool
+	lck    sync.Mutex
+	wg     sync.WaitGroup
+	ctx    context.Context
+	cancel context.CancelFunc
 }
 
 var _ = bindings.InputBinding(&Binding{})
 
 // NewCron returns a new Cron event input binding
 func NewCron(logger logger.Logger) *Binding {
+	ctx, cancel := context.WithCancel(context.Background())
+
 	return &Binding{
 		logger: logger,
-		stopCh: make(chan bool),
 		parser: cron.NewParser(
 			cron.SecondOptional | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor,
 		),
+		location:   time.Local,
+		maxCatchUp: defaultMaxCatchUp,
+		newStore:   statestore.New,
+		ctx:        ctx,
+		cancel:     cancel,
 	}
 }
 
@@ -41,63 +95,241 @@ func NewCron(logger logger.Logger) *Binding {
 //   "15 * * * * *" - Every 15 sec
 //   "0 30 * * * *" - Every 30 min
 func (b *Binding) Init(metadata bindings.Metadata) error {
-	s, f := metadata.Properties["schedule"]
+	props := metadata.Properties
+	s, f := props[scheduleMetadata]
 	if !f || s == "" {
 		return fmt.Errorf("schedule not set")
 	}
-	_, err := b.parser.Parse(s)
+	sched, err := b.parser.Parse(s)
 	if err != nil {
 		return errors.Wrapf(err, "invalid schedule format: %s", s)
 	}
 	b.schedule = s
+	b.sched = sched
+
+	if val, ok := props[timeZoneMetadata]; ok && val != "" {
+		if b.location, err = time.LoadLocation(val); err != nil {
+			return errors.Wrapf(err, "invalid time zone: %s", val)
+		}
+	}
+	if val, ok := props[payloadMetadata]; ok && val != "" {
+		b.payload = []byte(val)
+	}
+	if val, ok := props[jitterMetadata]; ok && val != "" {
+		if b.jitter, err = time.ParseDuration(val); err != nil || b.jitter < 0 {
+			return fmt.Errorf("invalid jitter: %s", val)
+		}
+	}
+	if val, ok := props[catchUpMetadata]; ok && val != "" {
+		if b.catchUp, err = strconv.ParseBool(val); err != nil {
+			return fmt.Errorf("invalid catchUp: %s", val)
+		}
+	}
+	if val, ok := props[maxCatchUpMetadata]; ok && val != "" {
+		if b.maxCatchUp, err = strconv.Atoi(val); err != nil || b.maxCatchUp < 0 {
+			return fmt.Errorf("invalid maxCatchUp: %s", val)
+		}
+	}
+
+	if val, ok := props[lockStoreMetadata]; ok && val != "" {
+		// The memory store is not shared by the replicas, so it can't lock the runs
+		if val == statestore.MemoryStore {
+			return fmt.Errorf("%s %s is not supported, as it is not shared by the replicas", lockStoreMetadata, val)
+		}
+		key := metadata.Name
+		if val, ok := props[lockKeyMetadata]; ok && val != "" {
+			key = val
+		}
+		if key == "" {
+			return fmt.Errorf("%s not set, which is required without the binding name", lockKeyMetadata)
+		}
+		store, err := b.newStore(props, lockStoreMetadata, b.logger)
+		if err != nil {
+			return err
+		}
+		if b.lock, err = newTickLock(store, key); err != nil {
+			return err
+		}
+	} else if b.catchUp {
+		return fmt.Errorf("%s requires %s to persist the last run", catchUpMetadata, lockStoreMetadata)
+	}
 
 	return nil
 }
 
-// Read triggers the Cron scheduler
+// Read triggers the Cron scheduler. With a lock store, only the replica that
+// records a run in the store first fires it, and the runs missed since the
+// last recorded run are fired first when catchUp is enabled.
 func (b *Binding) Read(handler func(*bindings.ReadResponse) error) error {
-	c := cron.New(cron.WithParser(b.parser))
-	id, err := c.AddFunc(b.schedule, func() {
-		b.logger.Debugf("schedule fired: %v", time.Now())
-		handler(&bindings.ReadResponse{
-			Metadata: map[string]string{
-				"timeZone":    c.Location().String(),
-				"readTimeUTC": time.Now().UTC().String(),
-			},
-		})
-	})
+	if b.lock != nil {
+		if err := b.lock.init(time.Now()); err != nil {
+			return errors.Wrap(err, "error initializing lock store")
+		}
+		if b.catchUp {
+			b.fireMissed(handler)
+		}
+	}
+
+	next := b.sched.Next(time.Now().In(b.location))
+	b.logger.Debugf("next run: %v", time.Until(next))
+	for {
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-b.ctx.Done():
+			timer.Stop()
+			b.logger.Debugf("stopping schedule: %s", b.schedule)
+			b.wg.Wait()
+
+			return nil
+		case <-timer.C:
+		}
+
+		// Runs are fired concurrently, as a run may take longer than the schedule
+		tick := next
+		b.wg.Add(1)
+		go func() {
+			defer b.wg.Done()
+			b.fire(tick, false, handler)
+		}()
+		next = b.sched.Next(time.Now().In(b.location))
+	}
+}
+
+// fireMissed fires the runs scheduled between the last recorded run and now, up to maxCatchUp.
+func (b *Binding) fireMissed(handler func(*bindings.ReadResponse) error) {
+	last, err := b.lock.lastTick()
 	if err != nil {
-		return errors.Wrapf(err, "error scheduling %s", b.schedule)
+		b.logger.Errorf("error reading the last run of %s: %s", b.schedule, err)
+
+		return
 	}
-	c.Start()
-	b.logger.Debugf("next run: %v", time.Until(c.Entry(id).Next))
-	<-b.stopCh
-	b.logger.Debugf("stopping schedule: %s", b.schedule)
-	c.Stop()
 
-	return nil
+	now := time.Now()
+	var missed []time.Time
+	skipped := 0
+	for t := b.sched.Next(last.In(b.location)); !t.IsZero() && !t.After(now); t = b.sched.Next(t) {
+		missed = append(missed, t)
+		if len(missed) > b.maxCatchUp {
+			missed = missed[1:]
+			skipped++
+		}
+	}
+	if skipped > 0 {
+		b.logger.Warnf("skipping %d missed runs of %s, more than maxCatchUp", skipped, b.schedule)
+	}
+
+	for _, t := range missed {
+		if b.ctx.Err() != nil {
+			return
+		}
+		b.fire(t, true, handler)
+	}
 }
 
-// Invoke exposes way to stop previously started cron
+// fire triggers handler with the run scheduled at tick, unless the schedule is
+// paused or another replica fired it. The runs skipped while paused are still
+// recorded in the lock store, so that they are not caught up after a restart.
+func (b *Binding) fire(tick time.Time, catchUp bool, handler func(*bindings.ReadResponse) error) {
+	if b.isPaused() {
+		b.logger.Debugf("schedule paused, skipping run: %v", tick)
+		if b.lock != nil {
+			if _, err := b.lock.claim(tick); err != nil {
+				b.logger.Errorf("error recording skipped run %v of %s: %s", tick, b.schedule, err)
+			}
+		}
+
+		return
+	}
+
+	if b.lock != nil {
+		claimed, err := b.lock.claim(tick)
+		if err != nil {
+			b.logger.Errorf("error recording run %v of %s: %s", tick, b.schedule, err)
+
+			return
+		}
+		if !claimed {
+			b.logger.Debugf("run fired by another replica: %v", tick)
+
+			return
+		}
+	}
+
+	if b.jitter > 0 && !catchUp {
+		// nolint: gosec
+		delay := time.Duration(rand.Int63n(int64(b.jitter)))
+		select {
+		case <-b.ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+	}
+
+	b.logger.Debugf("schedule fired: %v", tick)
+	metadata := map[string]string{
+		"timeZone":         b.location.String(),
+		"readTimeUTC":      time.Now().UTC().String(),
+		"scheduledTimeUTC": tick.UTC().Format(time.RFC3339),
+	}
+	if catchUp {
+		metadata["catchUp"] = "true"
+	}
+	if err := handler(&bindings.ReadResponse{Data: b.payload, Metadata: metadata}); err != nil {
+		b.logger.Errorf("error handling run %v of %s: %s", tick, b.schedule, err)
+	}
+}
+
+func (b *Binding) isPaused() bool {
+	b.lck.Lock()
+	defer b.lck.Unlock()
+
+	return b.paused
+}
+
+// Invoke exposes way to stop, pause and start previously started cron.
+// Stopping, with the delete or stop operation, ends Read and cannot be undone,
+// while a paused schedule skips its runs until it is started again.
 func (b *Binding) Invoke(req *bindings.InvokeRequest) (*bindings.InvokeResponse, error) {
 	b.logger.Debugf("operation: %v", req.Operation)
-	if req.Operation != bindings.DeleteOperation {
-		return nil, fmt.Errorf("invalid operation: '%v', only '%v' supported",
-			req.Operation, bindings.DeleteOperation)
-	}
-	b.stopCh <- true
-
-	return &bindings.InvokeResponse{
-		Metadata: map[string]string{
-			"schedule":    b.schedule,
-			"stopTimeUTC": time.Now().UTC().String(),
-		},
-	}, nil
+	now := time.Now().UTC().String()
+	metadata := map[string]string{
+		"schedule": b.schedule,
+	}
+
+	switch req.Operation {
+	case bindings.DeleteOperation, stopOperation:
+		b.cancel()
+		metadata["stopTimeUTC"] = now
+	case pauseOperation:
+		b.setPaused(true)
+		metadata["pauseTimeUTC"] = now
+	case startOperation:
+		if b.ctx.Err() != nil {
+			return nil, fmt.Errorf("schedule %s is stopped", b.schedule)
+		}
+		b.setPaused(false)
+		metadata["startTimeUTC"] = now
+	default:
+		return nil, fmt.Errorf("invalid operation: '%v', only '%v', '%v', '%v' and '%v' supported",
+			req.Operation, bindings.DeleteOperation, stopOperation, pauseOperation, startOperation)
+	}
+
+	return &bindings.InvokeResponse{Metadata: metadata}, nil
+}
+
+func (b *Binding) setPaused(paused bool) {
+	b.lck.Lock()
+	defer b.lck.Unlock()
+
+	b.paused = paused
 }
 
 // Operations method returns the supported operations by this binding
 func (b *Binding) Operations() []bindings.OperationKind {
 	return []bindings.OperationKind{
 		bindings.DeleteOperation,
+		stopOperation,
+		pauseOperation,
+		startOperation,
 	}
 }
@@ -7,11 +7,16 @@ package cron
 
 import (
 	"os"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/dapr/components-contrib/bindings"
+	"github.com/dapr/components-contrib/internal/component/statestore"
+	"github.com/dapr/components-contrib/state"
 	"github.com/dapr/dapr/pkg/logger"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func getTestMetadata(schedule string) bindings.Metadata {
@@ -87,3 +92,292 @@ func TestCronInvokeInvalidOperation(t *testing.T) {
 	})
 	assert.Error(t, err)
 }
+
+func TestCronInitOptions(t *testing.T) {
+	for name, properties := range map[string]map[string]string{
+		"time zone":                 {"timeZone": "Mars/Olympus_Mons"},
+		"jitter":                    {"jitter": "a second"},
+		"negative jitter":           {"jitter": "-1s"},
+		"catch up":                  {"catchUp": "maybe"},
+		"max catch up":              {"maxCatchUp": "-1", "catchUp": "true", "lockStore": "redis", "lockKey": "key"},
+		"catch up without store":    {"catchUp": "true"},
+		"unsupported store":         {"lockStore": "cassandra", "lockKey": "key"},
+		"memory store":              {"lockStore": "memory", "lockKey": "key"},
+		"lock store without a key":  {"lockStore": "redis"},
+		"lock store metadata error": {"lockStore": "redis", "lockKey": "key", "lockStore.redisHost": ""},
+	} {
+		t.Run(name, func(t *testing.T) {
+			properties["schedule"] = "@every 1s"
+			assert.Error(t, getNewCron().Init(bindings.Metadata{Properties: properties}))
+		})
+	}
+
+	// The lock writes with strong consistency, which Redis Cluster can't provide
+	err := getNewCron().Init(bindings.Metadata{Properties: map[string]string{
+		"schedule": "@every 1s", "lockStore": "redis", "lockKey": "key",
+		"lockStore.redisHost": "localhost:6379", "lockStore.redisType": "cluster",
+	}})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "cluster")
+
+	c := getNewCron()
+	c.newStore = func(properties map[string]string, key string, logger logger.Logger) (state.Store, error) {
+		assert.Equal(t, "redis", properties[key])
+
+		return statestore.NewMemoryStore(), nil
+	}
+	require.NoError(t, c.Init(bindings.Metadata{Name: "cron", Properties: map[string]string{
+		"schedule": "@every 1s", "timeZone": "America/New_York", "payload": "{}", "jitter": "100ms",
+		"lockStore": "redis", "catchUp": "true", "maxCatchUp": "5",
+	}}))
+	assert.Equal(t, "America/New_York", c.location.String())
+	assert.Equal(t, []byte("{}"), c.payload)
+	assert.Equal(t, 100*time.Millisecond, c.jitter)
+	assert.Equal(t, "cron", c.lock.key)
+	assert.True(t, c.catchUp)
+	assert.Equal(t, 5, c.maxCatchUp)
+}
+
+// readRuns reads the runs of c until it is stopped.
+func readRuns(c *Binding) (chan *bindings.ReadResponse, chan error) {
+	runs := make(chan *bindings.ReadResponse, 100)
+	done := make(chan error)
+	go func() {
+		done <- c.Read(func(res *bindings.ReadResponse) error {
+			runs <- res
+
+			return nil
+		})
+	}()
+
+	return runs, done
+}
+
+func waitRead(t *testing.T, done chan error) {
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		assert.Fail(t, "timeout waiting for Read to return")
+	}
+}
+
+func TestCronReadWithOptions(t *testing.T) {
+	c := getNewCron()
+	require.NoError(t, c.Init(bindings.Metadata{Properties: map[string]string{
+		"schedule": "* * * * * *", "timeZone": "Asia/Tokyo", "payload": `{"job": "cleanup"}`, "jitter": "10ms",
+	}}))
+	runs, done := readRuns(c)
+
+	select {
+	case res := <-runs:
+		assert.Equal(t, `{"job": "cleanup"}`, string(res.Data))
+		assert.Equal(t, "Asia/Tokyo", res.Metadata["timeZone"])
+		scheduled, err := time.Parse(time.RFC3339, res.Metadata["scheduledTimeUTC"])
+		require.NoError(t, err)
+		assert.WithinDuration(t, time.Now(), scheduled, 2*time.Second)
+	case <-time.After(3 * time.Second):
+		assert.Fail(t, "timeout waiting for run")
+	}
+
+	t.Run("pause and start", func(t *testing.T) {
+		_, err := c.Invoke(&bindings.InvokeRequest{Operation: pauseOperation})
+		require.NoError(t, err)
+		// Drain the run that may have started before the pause
+		time.Sleep(100 * time.Millisecond)
+		for len(runs) > 0 {
+			<-runs
+		}
+		select {
+		case <-runs:
+			assert.Fail(t, "paused schedule fired")
+		case <-time.After(1500 * time.Millisecond):
+		}
+
+		resp, err := c.Invoke(&bindings.InvokeRequest{Operation: startOperation})
+		require.NoError(t, err)
+		assert.NotEmpty(t, resp.Metadata["startTimeUTC"])
+		select {
+		case <-runs:
+		case <-time.After(3 * time.Second):
+			assert.Fail(t, "timeout waiting for run")
+		}
+	})
+
+	t.Run("stop", func(t *testing.T) {
+		resp, err := c.Invoke(&bindings.InvokeRequest{Operation: stopOperation})
+		require.NoError(t, err)
+		assert.NotEmpty(t, resp.Metadata["stopTimeUTC"])
+		waitRead(t, done)
+
+		_, err = c.Invoke(&bindings.InvokeRequest{Operation: startOperation})
+		assert.Error(t, err)
+	})
+}
+
+func TestCronStopWithoutRead(t *testing.T) {
+	c := getNewCron()
+	require.NoError(t, c.Init(getTestMetadata("@every 1s")))
+
+	// Stopping does not block without a reader
+	_, err := c.Invoke(&bindings.InvokeRequest{Operation: bindings.DeleteOperation})
+	require.NoError(t, err)
+	_, err = c.Invoke(&bindings.InvokeRequest{Operation: bindings.DeleteOperation})
+	require.NoError(t, err)
+	assert.NoError(t, c.Read(func(*bindings.ReadResponse) error { return nil }))
+}
+
+func TestCronLock(t *testing.T) {
+	store := statestore.NewMemoryStore()
+	replicas := make([]*Binding, 3)
+	runs := make(chan *bindings.ReadResponse, 100)
+	var wg sync.WaitGroup
+	for i := range replicas {
+		c := getNewCron()
+		require.NoError(t, c.Init(getTestMetadata("* * * * * *")))
+		lock, err := newTickLock(store, "cron")
+		require.NoError(t, err)
+		c.lock = lock
+		replicas[i] = c
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, c.Read(func(res *bindings.ReadResponse) error {
+				runs <- res
+
+				return nil
+			}))
+		}()
+	}
+
+	time.Sleep(3500 * time.Millisecond)
+	for _, c := range replicas {
+		_, err := c.Invoke(&bindings.InvokeRequest{Operation: stopOperation})
+		require.NoError(t, err)
+	}
+	wg.Wait()
+	close(runs)
+
+	// Each run is fired by one replica
+	scheduled := map[string]bool{}
+	for res := range runs {
+		assert.False(t, scheduled[res.Metadata["scheduledTimeUTC"]], "run fired twice")
+		scheduled[res.Metadata["scheduledTimeUTC"]] = true
+	}
+	assert.GreaterOrEqual(t, len(scheduled), 2)
+
+	last, err := replicas[0].lock.lastTick()
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now(), last, 2*time.Second)
+}
+
+func TestCronCatchUp(t *testing.T) {
+	store := statestore.NewMemoryStore()
+	lock, err := newTickLock(store, "cron")
+	require.NoError(t, err)
+
+	// The last run was five and a half minutes ago
+	last := time.Now().Add(-330 * time.Second).Truncate(time.Second)
+	require.NoError(t, lock.set(&tickRecord{LastTick: last}, nil))
+
+	c := getNewCron()
+	require.NoError(t, c.Init(bindings.Metadata{Properties: map[string]string{"schedule": "@every 1m", "maxCatchUp": "3"}}))
+	c.lock = lock
+	c.catchUp = true
+
+	runs, done := readRuns(c)
+	for i := 3; i <= 5; i++ {
+		select {
+		case res := <-runs:
+			assert.Equal(t, "true", res.Metadata["catchUp"])
+			assert.Equal(t, last.Add(time.Duration(i)*time.Minute).UTC().Format(time.RFC3339), res.Metadata["scheduledTimeUTC"])
+		case <-time.After(3 * time.Second):
+			assert.Fail(t, "timeout waiting for missed run")
+		}
+	}
+
+	_, err = c.Invoke(&bindings.InvokeRequest{Operation: stopOperation})
+	require.NoError(t, err)
+	waitRead(t, done)
+	assert.Empty(t, runs)
+
+	recorded, err := lock.lastTick()
+	require.NoError(t, err)
+	assert.True(t, recorded.Equal(last.Add(5*time.Minute)))
+}
+
+func TestCronCatchUpAfterPause(t *testing.T) {
+	store := statestore.NewMemoryStore()
+	newCron := func() *Binding {
+		c := getNewCron()
+		c.newStore = func(properties map[string]string, key string, logger logger.Logger) (state.Store, error) {
+			return store, nil
+		}
+		require.NoError(t, c.Init(bindings.Metadata{Name: "cron", Properties: map[string]string{
+			"schedule": "@every 1m", "lockStore": "redis", "catchUp": "true",
+		}}))
+
+		return c
+	}
+
+	// The last run was three and a half minutes ago
+	last := time.Now().Add(-210 * time.Second).Truncate(time.Second)
+	c := newCron()
+	require.NoError(t, c.lock.set(&tickRecord{LastTick: last}, nil))
+
+	// The schedule is paused while the runs are due, then restarted
+	_, err := c.Invoke(&bindings.InvokeRequest{Operation: pauseOperation})
+	require.NoError(t, err)
+	fired := 0
+	for i := 1; i <= 2; i++ {
+		c.fire(last.Add(time.Duration(i)*time.Minute), false, func(*bindings.ReadResponse) error {
+			fired++
+
+			return nil
+		})
+	}
+	assert.Equal(t, 0, fired)
+	_, err = c.Invoke(&bindings.InvokeRequest{Operation: stopOperation})
+	require.NoError(t, err)
+
+	// Only the run due after the pause is caught up
+	restarted := newCron()
+	runs, done := readRuns(restarted)
+	select {
+	case res := <-runs:
+		assert.Equal(t, "true", res.Metadata["catchUp"])
+		assert.Equal(t, last.Add(3*time.Minute).UTC().Format(time.RFC3339), res.Metadata["scheduledTimeUTC"])
+	case <-time.After(3 * time.Second):
+		assert.Fail(t, "timeout waiting for missed run")
+	}
+
+	_, err = restarted.Invoke(&bindings.InvokeRequest{Operation: stopOperation})
+	require.NoError(t, err)
+	waitRead(t, done)
+	assert.Empty(t, runs)
+}
+
+func TestCronLockInit(t *testing.T) {
+	lock, err := newTickLock(statestore.NewMemoryStore(), "cron")
+	require.NoError(t, err)
+
+	now := time.Now().Truncate(time.Second)
+	require.NoError(t, lock.init(now))
+	require.NoError(t, lock.init(now.Add(time.Hour)))
+	last, err := lock.lastTick()
+	require.NoError(t, err)
+	assert.True(t, last.Equal(now))
+
+	// Another replica inserting the record concurrently keeps the first one
+	inserted, err := lock.compareAndSet(&tickRecord{LastTick: now.Add(time.Hour)}, nil)
+	require.NoError(t, err)
+	assert.False(t, inserted)
+
+	claimed, err := lock.claim(now)
+	require.NoError(t, err)
+	assert.False(t, claimed)
+	claimed, err = lock.claim(now.Add(time.Second))
+	require.NoError(t, err)
+	assert.True(t, claimed)
+}
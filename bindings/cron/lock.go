@@ -0,0 +1,127 @@
+// ------------------------------------------------------------
+// Copyright (c) Microsoft Corporation and Dapr Contributors.
+// Licensed under the MIT License.
+// ------------------------------------------------------------
+
+package cron
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/dapr/components-contrib/state"
+)
+
+// tickRecord is the record of the last run of a schedule in the lock store.
+type tickRecord struct {
+	LastTick time.Time `json:"lastTick"`
+}
+
+// tickLock records the runs of a schedule in a state store with ETags, so that
+// the replicas sharing the store fire each run once.
+type tickLock struct {
+	store state.Store
+	key   string
+}
+
+func newTickLock(store state.Store, key string) (*tickLock, error) {
+	if !state.FeatureETag.IsPresent(store.Features()) {
+		return nil, errors.New("the lock store must support ETags")
+	}
+
+	return &tickLock{store: store, key: key}, nil
+}
+
+// init records now as the last run when there is no record yet, so that the
+// runs before the first start are not caught up.
+func (l *tickLock) init(now time.Time) error {
+	record, _, err := l.get()
+	if err != nil || record != nil {
+		return err
+	}
+
+	// The insert fails when another replica recorded a run meanwhile, which is kept
+	_, err = l.compareAndSet(&tickRecord{LastTick: now}, nil)
+
+	return err
+}
+
+// lastTick returns the last recorded run.
+func (l *tickLock) lastTick() (time.Time, error) {
+	record, _, err := l.get()
+	if err != nil || record == nil {
+		return time.Time{}, err
+	}
+
+	return record.LastTick, nil
+}
+
+// claim records tick as the last run, and returns false when it, or a later
+// run, was recorded by another replica.
+func (l *tickLock) claim(tick time.Time) (bool, error) {
+	record, etag, err := l.get()
+	if err != nil {
+		return false, err
+	}
+	if record != nil && !record.LastTick.Before(tick) {
+		return false, nil
+	}
+
+	return l.compareAndSet(&tickRecord{LastTick: tick}, etag)
+}
+
+// compareAndSet stores a record if it has not been changed since it was read
+// with etag, or inserts it if etag is nil. It returns false on an ETag mismatch.
+func (l *tickLock) compareAndSet(record *tickRecord, etag *string) (bool, error) {
+	err := l.set(record, etag)
+	if err != nil {
+		var etagErr *state.ETagError
+		if errors.As(err, &etagErr) && etagErr.Kind() == state.ETagMismatch {
+			return false, nil
+		}
+
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (l *tickLock) get() (*tickRecord, *string, error) {
+	res, err := l.store.Get(&state.GetRequest{
+		Key:     l.key,
+		Options: state.GetStateOption{Consistency: state.Strong},
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if res == nil || len(res.Data) == 0 {
+		return nil, nil, nil
+	}
+
+	var record tickRecord
+	if err = json.Unmarshal(res.Data, &record); err != nil {
+		return nil, nil, fmt.Errorf("invalid record of %s: %s", l.key, err)
+	}
+
+	return &record, res.ETag, nil
+}
+
+func (l *tickLock) set(record *tickRecord, etag *string) error {
+	b, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	return l.store.Set(&state.SetRequest{
+		Key:   l.key,
+		Value: b,
+		ETag:  etag,
+		Options: state.SetStateOption{
+			Concurrency: state.FirstWrite,
+			Consistency: state.Strong,
+		},
+	})
+}
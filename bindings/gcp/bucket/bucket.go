@@ -8,19 +8,42 @@ package bucket
 import (
 	"context"
 	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"time"
 
 	"cloud.google.com/go/storage"
 	"github.com/dapr/components-contrib/bindings"
+	contrib_objectstorage "github.com/dapr/components-contrib/internal/component/objectstorage"
 	"github.com/dapr/dapr/pkg/logger"
 	"github.com/google/uuid"
+	"google.golang.org/api/iterator"
 	"google.golang.org/api/option"
 )
 
+const (
+	metadataName = "name"
+
+	// emulatorHostEnv is the address of a storage emulator, which doesn't need credentials
+	emulatorHostEnv = "STORAGE_EMULATOR_HOST"
+	// pubsubEmulatorHostEnv is the address of a Pub/Sub emulator, which doesn't need credentials
+	pubsubEmulatorHostEnv = "PUBSUB_EMULATOR_HOST"
+)
+
 // GCPStorage allows saving data to GCP bucket storage
 type GCPStorage struct {
-	metadata gcpMetadata
-	client   *storage.Client
-	logger   logger.Logger
+	metadata     gcpMetadata
+	credentials  []byte
+	client       *storage.Client
+	pollInterval time.Duration
+	ctx          context.Context
+	cancel       context.CancelFunc
+	logger       logger.Logger
+
+	// pollCheckpoint persists the checkpoint of the poller, nil to keep it in memory
+	pollCheckpoint *contrib_objectstorage.PollCheckpoint
 }
 
 type gcpMetadata struct {
@@ -35,11 +58,21 @@ type gcpMetadata struct {
 	TokenURI            string `json:"token_uri"`
 	AuthProviderCertURL string `json:"auth_provider_x509_cert_url"`
 	ClientCertURL       string `json:"client_x509_cert_url"`
+	DecodeBase64        bool   `json:"decodeBase64,string"`
+	// Subscription is the Pub/Sub subscription to the notifications of the bucket, which the
+	// input binding reads. The input binding polls the bucket when it is not set.
+	Subscription string `json:"subscription"`
 }
 
 // NewGCPStorage returns a new GCP storage instance
 func NewGCPStorage(logger logger.Logger) *GCPStorage {
-	return &GCPStorage{logger: logger}
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &GCPStorage{
+		ctx:    ctx,
+		cancel: cancel,
+		logger: logger,
+	}
 }
 
 // Init performs connection parsing
@@ -54,15 +87,29 @@ func (g *GCPStorage) Init(metadata bindings.Metadata) error {
 	if err != nil {
 		return err
 	}
-	clientOptions := option.WithCredentialsJSON(b)
+	pollInterval, err := contrib_objectstorage.ParsePollInterval(metadata.Properties)
+	if err != nil {
+		return fmt.Errorf("gcp bucket binding error: %s", err)
+	}
+	pollCheckpoint, err := contrib_objectstorage.NewPollCheckpoint(metadata, g.logger)
+	if err != nil {
+		return fmt.Errorf("gcp bucket binding error: %s", err)
+	}
+	var clientOptions []option.ClientOption
+	if os.Getenv(emulatorHostEnv) == "" {
+		clientOptions = append(clientOptions, option.WithCredentialsJSON(b))
+	}
 	ctx := context.Background()
-	client, err := storage.NewClient(ctx, clientOptions)
+	client, err := storage.NewClient(ctx, clientOptions...)
 	if err != nil {
 		return err
 	}
 
 	g.metadata = gm
+	g.credentials = b
 	g.client = client
+	g.pollInterval = pollInterval
+	g.pollCheckpoint = pollCheckpoint
 
 	return nil
 }
@@ -77,12 +124,29 @@ func (g *GCPStorage) parseMetadata(metadata bindings.Metadata) ([]byte, error) {
 }
 
 func (g *GCPStorage) Operations() []bindings.OperationKind {
-	return []bindings.OperationKind{bindings.CreateOperation}
+	return contrib_objectstorage.Operations()
 }
 
 func (g *GCPStorage) Invoke(req *bindings.InvokeRequest) (*bindings.InvokeResponse, error) {
+	switch req.Operation {
+	case bindings.CreateOperation:
+		return g.create(req)
+	case bindings.GetOperation:
+		return g.get(req)
+	case bindings.DeleteOperation:
+		return g.delete(req)
+	case bindings.ListOperation:
+		return g.list(req)
+	case contrib_objectstorage.PresignOperation:
+		return g.presign(req)
+	default:
+		return nil, fmt.Errorf("gcp bucket binding error: unsupported operation %s", req.Operation)
+	}
+}
+
+func (g *GCPStorage) create(req *bindings.InvokeRequest) (*bindings.InvokeResponse, error) {
 	var name string
-	if val, ok := req.Metadata["name"]; ok && val != "" {
+	if val, ok := req.Metadata[metadataName]; ok && val != "" {
 		name = val
 	} else {
 		id, err := uuid.NewRandom()
@@ -91,11 +155,164 @@ func (g *GCPStorage) Invoke(req *bindings.InvokeRequest) (*bindings.InvokeRespon
 		}
 		name = id.String()
 	}
+
+	data, err := contrib_objectstorage.DecodeData(req.Data, g.metadata.DecodeBase64, req.Metadata)
+	if err != nil {
+		return nil, fmt.Errorf("gcp bucket binding error: %s", err)
+	}
+
 	h := g.client.Bucket(g.metadata.Bucket).Object(name).NewWriter(context.Background())
-	defer h.Close()
-	if _, err := h.Write(req.Data); err != nil {
+	h.ContentType = req.Metadata[contrib_objectstorage.ContentTypeKey]
+	h.Metadata = contrib_objectstorage.UserMetadata(req.Metadata, metadataName)
+	if _, err = h.Write(data); err != nil {
+		_ = h.Close()
+
+		return nil, fmt.Errorf("gcp bucket binding error: uploading %s failed: %s", name, err)
+	}
+	// The object is only created once the writer is closed
+	if err = h.Close(); err != nil {
+		return nil, fmt.Errorf("gcp bucket binding error: uploading %s failed: %s", name, err)
+	}
+
+	return &bindings.InvokeResponse{
+		Metadata: map[string]string{metadataName: name},
+	}, nil
+}
+
+func (g *GCPStorage) get(req *bindings.InvokeRequest) (*bindings.InvokeResponse, error) {
+	name, err := requiredName(req)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	object := g.client.Bucket(g.metadata.Bucket).Object(name)
+	attrs, err := object.Attrs(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("gcp bucket binding error: getting %s failed: %s", name, err)
+	}
+
+	r, err := object.Generation(attrs.Generation).NewReader(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("gcp bucket binding error: getting %s failed: %s", name, err)
+	}
+	defer r.Close()
+
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("gcp bucket binding error: reading %s failed: %s", name, err)
+	}
+
+	metadata := make(map[string]string, len(attrs.Metadata)+1)
+	for k, v := range attrs.Metadata {
+		metadata[k] = v
+	}
+	if attrs.ContentType != "" {
+		metadata[contrib_objectstorage.ContentTypeKey] = attrs.ContentType
+	}
+
+	return &bindings.InvokeResponse{
+		Data:     data,
+		Metadata: metadata,
+	}, nil
+}
+
+func (g *GCPStorage) delete(req *bindings.InvokeRequest) (*bindings.InvokeResponse, error) {
+	name, err := requiredName(req)
+	if err != nil {
 		return nil, err
 	}
 
+	if err = g.client.Bucket(g.metadata.Bucket).Object(name).Delete(context.Background()); err != nil {
+		return nil, fmt.Errorf("gcp bucket binding error: deleting %s failed: %s", name, err)
+	}
+
 	return nil, nil
 }
+
+func (g *GCPStorage) list(req *bindings.InvokeRequest) (*bindings.InvokeResponse, error) {
+	r, err := contrib_objectstorage.ParseListRequest(req.Metadata)
+	if err != nil {
+		return nil, fmt.Errorf("gcp bucket binding error: %s", err)
+	}
+
+	resp, err := g.listObjects(context.Background(), r)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := json.Marshal(resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return &bindings.InvokeResponse{Data: b}, nil
+}
+
+// listObjects returns a page of objects, whose marker is the page token of the next page.
+func (g *GCPStorage) listObjects(ctx context.Context, r contrib_objectstorage.ListRequest) (contrib_objectstorage.ListResponse, error) {
+	it := g.client.Bucket(g.metadata.Bucket).Objects(ctx, &storage.Query{Prefix: r.Prefix})
+	var objects []*storage.ObjectAttrs
+	nextMarker, err := iterator.NewPager(it, r.MaxResults, r.Marker).NextPage(&objects)
+	if err != nil {
+		return contrib_objectstorage.ListResponse{}, fmt.Errorf("gcp bucket binding error: listing objects failed: %s", err)
+	}
+
+	resp := contrib_objectstorage.ListResponse{
+		Objects:    make([]contrib_objectstorage.Object, 0, len(objects)),
+		NextMarker: nextMarker,
+	}
+	for _, o := range objects {
+		resp.Objects = append(resp.Objects, contrib_objectstorage.Object{
+			Key:          o.Name,
+			Size:         o.Size,
+			LastModified: o.Updated,
+			ETag:         o.Etag,
+		})
+	}
+
+	return resp, nil
+}
+
+// presign returns a signed URL, signed with the private key of the service account.
+func (g *GCPStorage) presign(req *bindings.InvokeRequest) (*bindings.InvokeResponse, error) {
+	name, err := requiredName(req)
+	if err != nil {
+		return nil, err
+	}
+
+	ttl, err := contrib_objectstorage.ParsePresignTTL(req.Metadata)
+	if err != nil {
+		return nil, fmt.Errorf("gcp bucket binding error: %s", err)
+	}
+
+	expiresAt := time.Now().UTC().Add(ttl)
+	url, err := storage.SignedURL(g.metadata.Bucket, name, &storage.SignedURLOptions{
+		GoogleAccessID: g.metadata.ClientEmail,
+		PrivateKey:     []byte(g.metadata.PrivateKey),
+		Method:         http.MethodGet,
+		Expires:        expiresAt,
+		Scheme:         storage.SigningSchemeV4,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("gcp bucket binding error: presigning %s failed: %s", name, err)
+	}
+
+	b, err := json.Marshal(contrib_objectstorage.PresignResponse{
+		PresignedURL: url,
+		ExpiresAt:    expiresAt,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &bindings.InvokeResponse{Data: b}, nil
+}
+
+func requiredName(req *bindings.InvokeRequest) (string, error) {
+	if val, ok := req.Metadata[metadataName]; ok && val != "" {
+		return val, nil
+	}
+
+	return "", fmt.Errorf("gcp bucket binding error: required metadata %s missing", metadataName)
+}
@@ -6,12 +6,22 @@
 package bucket
 
 import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
 	"encoding/json"
+	"encoding/pem"
+	"os"
 	"testing"
+	"time"
 
 	"github.com/dapr/components-contrib/bindings"
+	contrib_objectstorage "github.com/dapr/components-contrib/internal/component/objectstorage"
 	"github.com/dapr/dapr/pkg/logger"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestInit(t *testing.T) {
@@ -40,3 +50,71 @@ func TestInit(t *testing.T) {
 	assert.Equal(t, "a", gm.TokenURI)
 	assert.Equal(t, "a", gm.Type)
 }
+
+func TestPresign(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	privateKey := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+
+	gs := GCPStorage{
+		logger:   logger.NewLogger("test"),
+		metadata: gcpMetadata{Bucket: "bucket", ClientEmail: "test@dapr.iam.gserviceaccount.com", PrivateKey: string(privateKey)},
+	}
+
+	resp, err := gs.Invoke(&bindings.InvokeRequest{
+		Operation: contrib_objectstorage.PresignOperation,
+		Metadata:  map[string]string{"name": "docs/a.txt", "presignTTL": "1m"},
+	})
+	require.NoError(t, err)
+	var presigned contrib_objectstorage.PresignResponse
+	require.NoError(t, json.Unmarshal(resp.Data, &presigned))
+	assert.Contains(t, presigned.PresignedURL, "https://storage.googleapis.com/bucket/docs/a.txt?")
+	assert.Contains(t, presigned.PresignedURL, "X-Goog-Expires=")
+	assert.WithinDuration(t, time.Now().Add(time.Minute), presigned.ExpiresAt, 5*time.Second)
+
+	_, err = gs.Invoke(&bindings.InvokeRequest{Operation: contrib_objectstorage.PresignOperation, Metadata: map[string]string{}})
+	assert.Error(t, err)
+}
+
+// SETUP TESTS
+// 1. `docker run -d -p 4443:4443 fsouza/fake-gcs-server -scheme http -public-host localhost:4443`
+// 2. `export STORAGE_EMULATOR_HOST=localhost:4443`
+// 3. `go test -v -count=1 ./bindings/gcp/bucket -run ^TestEmulatorIntegration`
+
+func TestEmulatorIntegration(t *testing.T) {
+	if os.Getenv(emulatorHostEnv) == "" {
+		t.SkipNow()
+	}
+
+	gs := NewGCPStorage(logger.NewLogger("test"))
+	require.NoError(t, gs.Init(bindings.Metadata{Properties: map[string]string{"bucket": "dapr-test"}}))
+	err := gs.client.Bucket("dapr-test").Create(context.Background(), "test", nil)
+	if err != nil {
+		t.Logf("error creating bucket: %s", err)
+	}
+
+	_, err = gs.Invoke(&bindings.InvokeRequest{
+		Operation: bindings.CreateOperation,
+		Data:      []byte(base64.StdEncoding.EncodeToString([]byte("hello"))),
+		Metadata:  map[string]string{"name": "docs/a.txt", "contentType": "text/plain", "decodeBase64": "true", "owner": "dapr"},
+	})
+	require.NoError(t, err)
+
+	resp, err := gs.Invoke(&bindings.InvokeRequest{Operation: bindings.GetOperation, Metadata: map[string]string{"name": "docs/a.txt"}})
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(resp.Data))
+	assert.Equal(t, "text/plain", resp.Metadata["contentType"])
+	assert.Equal(t, "dapr", resp.Metadata["owner"])
+
+	resp, err = gs.Invoke(&bindings.InvokeRequest{Operation: bindings.ListOperation, Metadata: map[string]string{"prefix": "docs/"}})
+	require.NoError(t, err)
+	var page contrib_objectstorage.ListResponse
+	require.NoError(t, json.Unmarshal(resp.Data, &page))
+	require.Len(t, page.Objects, 1)
+	assert.Equal(t, "docs/a.txt", page.Objects[0].Key)
+
+	_, err = gs.Invoke(&bindings.InvokeRequest{Operation: bindings.DeleteOperation, Metadata: map[string]string{"name": "docs/a.txt"}})
+	require.NoError(t, err)
+	_, err = gs.Invoke(&bindings.InvokeRequest{Operation: bindings.GetOperation, Metadata: map[string]string{"name": "docs/a.txt"}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,141 @@
+// ------------------------------------------------------------
+// Copyright (c) Microsoft Corporation and Dapr Contributors.
+// Licensed under the MIT License.
+// ------------------------------------------------------------
+
+package bucket
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+	"github.com/dapr/components-contrib/bindings"
+	contrib_objectstorage "github.com/dapr/components-contrib/internal/component/objectstorage"
+	"google.golang.org/api/option"
+)
+
+// attributes of the Pub/Sub notifications of Cloud Storage, see
+// https://cloud.google.com/storage/docs/pubsub-notifications
+const (
+	eventTypeAttribute               = "eventType"
+	bucketIDAttribute                = "bucketId"
+	objectIDAttribute                = "objectId"
+	eventTimeAttribute               = "eventTime"
+	overwrittenByGenerationAttribute = "overwrittenByGeneration"
+
+	objectFinalizeEvent = "OBJECT_FINALIZE"
+	objectDeleteEvent   = "OBJECT_DELETE"
+)
+
+// notificationPayload is the object resource sent with notifications in the JSON_API_V1 format.
+type notificationPayload struct {
+	Size string `json:"size"`
+	ETag string `json:"etag"`
+}
+
+// Read triggers handler with the events of the objects of the bucket, read from the
+// Pub/Sub subscription to its notifications or, when there is none, by polling the bucket.
+func (g *GCPStorage) Read(handler func(*bindings.ReadResponse) error) error {
+	if g.metadata.Subscription == "" {
+		g.logger.Warnf("subscription not set, polling bucket %s every %s: each replica emits the events", g.metadata.Bucket, g.pollInterval)
+
+		return contrib_objectstorage.NewPoller(g.metadata.Bucket, g.pollInterval, g.listObjects, g.pollCheckpoint, g.logger).Run(g.ctx, handler)
+	}
+
+	var clientOptions []option.ClientOption
+	if os.Getenv(pubsubEmulatorHostEnv) == "" {
+		clientOptions = append(clientOptions, option.WithCredentialsJSON(g.credentials))
+	}
+	client, err := pubsub.NewClient(g.ctx, g.metadata.ProjectID, clientOptions...)
+	if err != nil {
+		return fmt.Errorf("gcp bucket binding error: creating pubsub client failed: %s", err)
+	}
+	defer client.Close()
+
+	err = client.Subscription(g.metadata.Subscription).Receive(g.ctx, func(ctx context.Context, m *pubsub.Message) {
+		e, ok, err := parseNotification(m.Attributes, m.Data)
+		if err != nil {
+			g.logger.Errorf("gcp bucket binding error: %s", err)
+			m.Nack()
+
+			return
+		}
+		if !ok {
+			m.Ack()
+
+			return
+		}
+
+		if err = e.Handle(handler); err != nil {
+			g.logger.Errorf("gcp bucket binding error: handling %s event of %s failed: %s", e.EventType, e.Key, err)
+			m.Nack()
+
+			return
+		}
+		m.Ack()
+	})
+	if err != nil && g.ctx.Err() == nil {
+		return fmt.Errorf("gcp bucket binding error: receiving notifications from %s failed: %s", g.metadata.Subscription, err)
+	}
+
+	return nil
+}
+
+// parseNotification returns the event of the notification of a created or deleted
+// object, or false for other notifications. The deletion of the versions that are
+// overwritten is ignored, as the objects still exist.
+func parseNotification(attributes map[string]string, data []byte) (contrib_objectstorage.Event, bool, error) {
+	e := contrib_objectstorage.Event{
+		Bucket: attributes[bucketIDAttribute],
+		Key:    attributes[objectIDAttribute],
+	}
+	switch attributes[eventTypeAttribute] {
+	case objectFinalizeEvent:
+		e.EventType = contrib_objectstorage.EventCreated
+	case objectDeleteEvent:
+		if attributes[overwrittenByGenerationAttribute] != "" {
+			return e, false, nil
+		}
+		e.EventType = contrib_objectstorage.EventDeleted
+	default:
+		return e, false, nil
+	}
+
+	if val, ok := attributes[eventTimeAttribute]; ok && val != "" {
+		eventTime, err := time.Parse(time.RFC3339Nano, val)
+		if err != nil {
+			return e, false, fmt.Errorf("invalid event time %s in notification: %s", val, err)
+		}
+		e.EventTime = eventTime
+	}
+
+	// The payload is empty when the notification has no payload format
+	if len(data) > 0 {
+		var payload notificationPayload
+		if err := json.Unmarshal(data, &payload); err != nil {
+			return e, false, fmt.Errorf("invalid notification payload: %s", err)
+		}
+		if payload.Size != "" {
+			size, err := strconv.ParseInt(payload.Size, 10, 64)
+			if err != nil {
+				return e, false, fmt.Errorf("invalid size %s in notification: %s", payload.Size, err)
+			}
+			e.Size = size
+		}
+		e.ETag = payload.ETag
+	}
+
+	return e, true, nil
+}
+
+// Close stops reading events
+func (g *GCPStorage) Close() error {
+	g.cancel()
+
+	return nil
+}
@@ -0,0 +1,130 @@
+// ------------------------------------------------------------
+// Copyright (c) Microsoft Corporation and Dapr Contributors.
+// Licensed under the MIT License.
+// ------------------------------------------------------------
+
+package bucket
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+	"cloud.google.com/go/pubsub/pstest"
+	"github.com/dapr/components-contrib/bindings"
+	contrib_objectstorage "github.com/dapr/components-contrib/internal/component/objectstorage"
+	"github.com/dapr/dapr/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseNotification(t *testing.T) {
+	attributes := func(eventType string) map[string]string {
+		return map[string]string{
+			"eventType":     eventType,
+			"bucketId":      "bucket",
+			"objectId":      "docs/a.txt",
+			"eventTime":     "2021-01-22T10:30:00.123Z",
+			"payloadFormat": "JSON_API_V1",
+		}
+	}
+	payload := []byte(`{"kind": "storage#object", "name": "docs/a.txt", "size": "5", "etag": "CJ6Kqq7j2u4CEAE="}`)
+
+	t.Run("finalize", func(t *testing.T) {
+		e, ok, err := parseNotification(attributes("OBJECT_FINALIZE"), payload)
+		require.NoError(t, err)
+		require.True(t, ok)
+		assert.Equal(t, contrib_objectstorage.Event{
+			EventType: contrib_objectstorage.EventCreated,
+			Bucket:    "bucket",
+			Key:       "docs/a.txt",
+			Size:      5,
+			ETag:      "CJ6Kqq7j2u4CEAE=",
+			EventTime: time.Date(2021, 1, 22, 10, 30, 0, 123000000, time.UTC),
+		}, e)
+	})
+
+	t.Run("delete without payload", func(t *testing.T) {
+		e, ok, err := parseNotification(attributes("OBJECT_DELETE"), nil)
+		require.NoError(t, err)
+		require.True(t, ok)
+		assert.Equal(t, contrib_objectstorage.EventDeleted, e.EventType)
+		assert.Equal(t, int64(0), e.Size)
+	})
+
+	t.Run("overwritten generation is ignored", func(t *testing.T) {
+		a := attributes("OBJECT_DELETE")
+		a["overwrittenByGeneration"] = "2"
+		_, ok, err := parseNotification(a, payload)
+		require.NoError(t, err)
+		assert.False(t, ok)
+	})
+
+	t.Run("other events are ignored", func(t *testing.T) {
+		_, ok, err := parseNotification(attributes("OBJECT_METADATA_UPDATE"), payload)
+		require.NoError(t, err)
+		assert.False(t, ok)
+	})
+
+	t.Run("invalid payload", func(t *testing.T) {
+		_, _, err := parseNotification(attributes("OBJECT_FINALIZE"), []byte(`{"size": "five"}`))
+		assert.Error(t, err)
+	})
+}
+
+func TestReadSubscription(t *testing.T) {
+	srv := pstest.NewServer()
+	defer srv.Close()
+	os.Setenv(pubsubEmulatorHostEnv, srv.Addr)
+	defer os.Unsetenv(pubsubEmulatorHostEnv)
+
+	ctx := context.Background()
+	client, err := pubsub.NewClient(ctx, "project")
+	require.NoError(t, err)
+	defer client.Close()
+	topic, err := client.CreateTopic(ctx, "notifications")
+	require.NoError(t, err)
+	defer topic.Stop()
+	_, err = client.CreateSubscription(ctx, "notifications", pubsub.SubscriptionConfig{Topic: topic})
+	require.NoError(t, err)
+
+	g := NewGCPStorage(logger.NewLogger("test"))
+	g.metadata = gcpMetadata{Bucket: "bucket", ProjectID: "project", Subscription: "notifications"}
+
+	events := make(chan *bindings.ReadResponse, 10)
+	done := make(chan error)
+	go func() {
+		done <- g.Read(func(resp *bindings.ReadResponse) error {
+			events <- resp
+
+			return nil
+		})
+	}()
+
+	for _, eventType := range []string{"OBJECT_METADATA_UPDATE", "OBJECT_FINALIZE"} {
+		_, err = topic.Publish(ctx, &pubsub.Message{
+			Data:       []byte(`{"size": "5", "etag": "etag"}`),
+			Attributes: map[string]string{"eventType": eventType, "bucketId": "bucket", "objectId": "docs/a.txt"},
+		}).Get(ctx)
+		require.NoError(t, err)
+	}
+
+	select {
+	case resp := <-events:
+		assert.Equal(t, map[string]string{"eventType": "created", "bucket": "bucket", "key": "docs/a.txt"}, resp.Metadata)
+		assert.JSONEq(t, `{"eventType": "created", "bucket": "bucket", "key": "docs/a.txt", "size": 5, "etag": "etag", "eventTime": "0001-01-01T00:00:00Z"}`, string(resp.Data))
+	case <-time.After(10 * time.Second):
+		assert.Fail(t, "timeout waiting for event")
+	}
+
+	require.NoError(t, g.Close())
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(10 * time.Second):
+		assert.Fail(t, "timeout waiting for Read to return")
+	}
+	assert.Empty(t, events)
+}
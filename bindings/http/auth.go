@@ -0,0 +1,118 @@
+// ------------------------------------------------------------
+// Copyright (c) Microsoft Corporation and Dapr Contributors.
+// Licensed under the MIT License.
+// ------------------------------------------------------------
+
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/patrickmn/go-cache"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
+)
+
+const (
+	// AuthTypeNone sends requests without authorization
+	AuthTypeNone = "none"
+	// AuthTypeBasic authorizes requests with username and password
+	AuthTypeBasic = "basic"
+	// AuthTypeBearer authorizes requests with bearerToken
+	AuthTypeBearer = "bearer"
+	// AuthTypeOAuth2 authorizes requests with a token of the OAuth2 client credentials flow
+	AuthTypeOAuth2 = "oauth2"
+
+	tokenCacheKey = "token"
+)
+
+func (m *httpMetadata) validateAuth() error {
+	switch strings.ToLower(m.AuthType) {
+	case "", AuthTypeNone:
+	case AuthTypeBasic:
+		if m.Username == "" {
+			return errors.New("http binding error: missing username for basic authentication")
+		}
+	case AuthTypeBearer:
+		if m.BearerToken == "" {
+			return errors.New("http binding error: missing bearerToken for bearer authentication")
+		}
+	case AuthTypeOAuth2:
+		if m.ClientID == "" || m.ClientSecret == "" || m.TokenURL == "" {
+			return errors.New("http binding error: clientID, clientSecret and tokenURL are required for oauth2 authentication")
+		}
+		if _, err := url.ParseQuery(m.EndpointParamsQuery); err != nil {
+			return fmt.Errorf("http binding error: invalid endpointParamsQuery: %s", err)
+		}
+		if m.AuthStyle < 0 || m.AuthStyle > 2 {
+			return fmt.Errorf("http binding error: authStyle can only have the values 0,1,2. Received: '%d'", m.AuthStyle)
+		}
+	default:
+		return fmt.Errorf("http binding error: invalid authType %s", m.AuthType)
+	}
+
+	return nil
+}
+
+// authorize sets the Authorization header of a request for the configured authType.
+func (h *HTTPSource) authorize(request *http.Request) error {
+	switch strings.ToLower(h.metadata.AuthType) {
+	case AuthTypeBasic:
+		request.SetBasicAuth(h.metadata.Username, h.metadata.Password)
+	case AuthTypeBearer:
+		request.Header.Set("Authorization", "Bearer "+h.metadata.BearerToken)
+	case AuthTypeOAuth2:
+		headerValue, err := h.oauth2Token()
+		if err != nil {
+			return err
+		}
+		request.Header.Set("Authorization", headerValue)
+	}
+
+	return nil
+}
+
+// oauth2Token returns the Authorization header of the cached token, or of a new
+// token fetched from tokenURL, which is cached until it expires.
+func (h *HTTPSource) oauth2Token() (string, error) {
+	if cachedToken, found := h.tokenCache.Get(tokenCacheKey); found {
+		return cachedToken.(string), nil
+	}
+
+	// The validation of Init ensures the query is valid
+	endpointParams, _ := url.ParseQuery(h.metadata.EndpointParamsQuery)
+	conf := &clientcredentials.Config{
+		ClientID:       h.metadata.ClientID,
+		ClientSecret:   h.metadata.ClientSecret,
+		TokenURL:       h.metadata.TokenURL,
+		EndpointParams: endpointParams,
+		AuthStyle:      oauth2.AuthStyle(h.metadata.AuthStyle),
+	}
+	if h.metadata.Scopes != "" {
+		conf.Scopes = strings.Split(h.metadata.Scopes, ",")
+	}
+
+	// The token is requested with the client of the binding and its TLS configuration
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, h.client)
+	token, err := conf.Token(ctx)
+	if err != nil {
+		return "", fmt.Errorf("http binding error: error acquiring token: %s", err)
+	}
+
+	headerValue := token.Type() + " " + token.AccessToken
+	expiration := time.Until(token.Expiry)
+	if token.Expiry.IsZero() {
+		expiration = cache.DefaultExpiration
+	}
+	if expiration > 0 {
+		h.tokenCache.Set(tokenCacheKey, headerValue, expiration)
+	}
+
+	return headerValue, nil
+}
@@ -7,63 +7,220 @@ package http
 
 import (
 	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"net"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
 	"time"
 	"unicode"
 
-	"github.com/mitchellh/mapstructure"
+	"github.com/cenkalti/backoff/v4"
+	"github.com/patrickmn/go-cache"
 
 	"github.com/dapr/components-contrib/bindings"
+	"github.com/dapr/components-contrib/internal/config"
 	"github.com/dapr/dapr/pkg/logger"
 )
 
+const (
+	// keys from request's metadata
+
+	// queryMetadata is a query string added to the query of the URL
+	queryMetadata = "query"
+	pathMetadata  = "path"
+)
+
 // HTTPSource is a binding for an http url endpoint invocation
 // nolint:golint
 type HTTPSource struct {
-	metadata httpMetadata
-	client   *http.Client
+	metadata         httpMetadata
+	client           *http.Client
+	retryStatusCodes map[int]bool
+	tokenCache       *cache.Cache
+	verifier         *webhookVerifier
+	ctx              context.Context
+	cancel           context.CancelFunc
 
 	logger logger.Logger
 }
 
 type httpMetadata struct {
 	URL string `mapstructure:"url"`
+
+	// Timeout is the timeout of each attempt of a request, including reading the response
+	Timeout             time.Duration `mapstructure:"timeout"`
+	DialTimeout         time.Duration `mapstructure:"dialTimeout"`
+	TLSHandshakeTimeout time.Duration `mapstructure:"tlsHandshakeTimeout"`
+
+	// PEM encoded certificates of the CAs of the server and of the client certificate
+	CACert     string `mapstructure:"caCert"`
+	ClientCert string `mapstructure:"clientCert"`
+	ClientKey  string `mapstructure:"clientKey"`
+	SkipVerify bool   `mapstructure:"skipVerify"`
+
+	// AuthType is none, basic, bearer or oauth2, see auth.go
+	AuthType            string `mapstructure:"authType"`
+	Username            string `mapstructure:"username"`
+	Password            string `mapstructure:"password"`
+	BearerToken         string `mapstructure:"bearerToken"`
+	ClientID            string `mapstructure:"clientID"`
+	ClientSecret        string `mapstructure:"clientSecret"`
+	Scopes              string `mapstructure:"scopes"`
+	TokenURL            string `mapstructure:"tokenURL"`
+	EndpointParamsQuery string `mapstructure:"endpointParamsQuery"`
+	AuthStyle           int    `mapstructure:"authStyle"`
+
+	// MaxRetries is the number of retries of requests failing with a network error
+	// or one of RetryStatusCodes, with an exponential backoff from RetryBackoff to MaxRetryBackoff
+	MaxRetries       int           `mapstructure:"maxRetries"`
+	RetryBackoff     time.Duration `mapstructure:"retryBackoff"`
+	MaxRetryBackoff  time.Duration `mapstructure:"maxRetryBackoff"`
+	RetryStatusCodes string        `mapstructure:"retryStatusCodes"`
+
+	// ErrorIfNot2XX returns an error along with the response of non-2xx status codes
+	ErrorIfNot2XX bool `mapstructure:"errorIfNot2XX"`
+
+	// Port and Path are where the input binding listens for webhooks, see webhook.go
+	Port int    `mapstructure:"port"`
+	Path string `mapstructure:"path"`
+
+	// SignatureType is none, github, stripe, slack or hmac
+	SignatureType      string        `mapstructure:"signatureType"`
+	SignatureSecret    string        `mapstructure:"signatureSecret"`
+	SignatureHeader    string        `mapstructure:"signatureHeader"`
+	SignatureAlgorithm string        `mapstructure:"signatureAlgorithm"`
+	TimestampHeader    string        `mapstructure:"timestampHeader"`
+	TimestampTolerance time.Duration `mapstructure:"timestampTolerance"`
 }
 
 // NewHTTP returns a new HTTPSource
 func NewHTTP(logger logger.Logger) *HTTPSource {
-	return &HTTPSource{logger: logger}
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &HTTPSource{
+		logger:     logger,
+		tokenCache: cache.New(1*time.Hour, 10*time.Minute),
+		ctx:        ctx,
+		cancel:     cancel,
+	}
 }
 
 // Init performs metadata parsing
 func (h *HTTPSource) Init(metadata bindings.Metadata) error {
-	if err := mapstructure.Decode(metadata.Properties, &h.metadata); err != nil {
+	m, err := parseMetadata(metadata.Properties)
+	if err != nil {
+		return err
+	}
+	h.metadata = m
+
+	h.retryStatusCodes = map[int]bool{}
+	for _, val := range strings.Split(m.RetryStatusCodes, ",") {
+		if val = strings.TrimSpace(val); val == "" {
+			continue
+		}
+		code, err := strconv.Atoi(val)
+		if err != nil {
+			return fmt.Errorf("http binding error: invalid status code %s in retryStatusCodes", val)
+		}
+		h.retryStatusCodes[code] = true
+	}
+
+	if err = m.validateAuth(); err != nil {
+		return err
+	}
+
+	if h.verifier, err = m.webhookVerifier(); err != nil {
+		return err
+	}
+
+	tlsConfig, err := m.tlsConfig()
+	if err != nil {
 		return err
 	}
 
 	// See guidance on proper HTTP client settings here:
 	// https://medium.com/@nate510/don-t-use-go-s-default-http-client-4804cb19f779
 	dialer := &net.Dialer{
-		Timeout: 5 * time.Second,
+		Timeout: m.DialTimeout,
 	}
 	var netTransport = &http.Transport{
 		Dial:                dialer.Dial,
-		TLSHandshakeTimeout: 5 * time.Second,
+		TLSHandshakeTimeout: m.TLSHandshakeTimeout,
+		TLSClientConfig:     tlsConfig,
 	}
 	h.client = &http.Client{
-		Timeout:   time.Second * 10,
+		Timeout:   m.Timeout,
 		Transport: netTransport,
 	}
 
 	return nil
 }
 
+func parseMetadata(properties map[string]string) (httpMetadata, error) {
+	m := httpMetadata{
+		Timeout:             10 * time.Second,
+		DialTimeout:         5 * time.Second,
+		TLSHandshakeTimeout: 5 * time.Second,
+		RetryBackoff:        time.Second,
+		MaxRetryBackoff:     30 * time.Second,
+		RetryStatusCodes:    "429,502,503,504",
+		ErrorIfNot2XX:       true,
+		TimestampTolerance:  5 * time.Minute,
+	}
+
+	// Empty values keep the defaults
+	props := make(map[string]string, len(properties))
+	for k, v := range properties {
+		if v != "" {
+			props[k] = v
+		}
+	}
+	if err := config.Decode(props, &m); err != nil {
+		return m, fmt.Errorf("http binding error: %s", err)
+	}
+	if m.MaxRetries < 0 {
+		return m, errors.New("http binding error: maxRetries must not be negative")
+	}
+
+	return m, nil
+}
+
+func (m *httpMetadata) tlsConfig() (*tls.Config, error) {
+	if m.CACert == "" && m.ClientCert == "" && m.ClientKey == "" && !m.SkipVerify {
+		return nil, nil
+	}
+
+	// nolint: gosec
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: m.SkipVerify,
+	}
+
+	if m.CACert != "" {
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if ok := tlsConfig.RootCAs.AppendCertsFromPEM([]byte(m.CACert)); !ok {
+			return nil, errors.New("http binding error: unable to load ca certificate")
+		}
+	}
+
+	if m.ClientCert != "" || m.ClientKey != "" {
+		cert, err := tls.X509KeyPair([]byte(m.ClientCert), []byte(m.ClientKey))
+		if err != nil {
+			return nil, fmt.Errorf("http binding error: unable to load client certificate and key pair: %s", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
+
 // Operations returns the supported operations for this binding.
 func (h *HTTPSource) Operations() []bindings.OperationKind {
 	return []bindings.OperationKind{
@@ -83,59 +240,123 @@ func (h *HTTPSource) Operations() []bindings.OperationKind {
 func (h *HTTPSource) Invoke(req *bindings.InvokeRequest) (*bindings.InvokeResponse, error) {
 	u := h.metadata.URL
 	if req.Metadata != nil {
-		if path, ok := req.Metadata["path"]; ok {
+		if path, ok := req.Metadata[pathMetadata]; ok {
 			// Simplicity and no "../../.." type exploits.
 			u = fmt.Sprintf("%s/%s", strings.TrimRight(u, "/"), strings.TrimLeft(path, "/"))
 			if strings.Contains(u, "..") {
 				return nil, fmt.Errorf("invalid path: %s", path)
 			}
 		}
+		if query, ok := req.Metadata[queryMetadata]; ok && query != "" {
+			if _, err := url.ParseQuery(query); err != nil {
+				return nil, fmt.Errorf("invalid query: %s", query)
+			}
+			if strings.Contains(u, "?") {
+				u = fmt.Sprintf("%s&%s", u, strings.TrimLeft(query, "?&"))
+			} else {
+				u = fmt.Sprintf("%s?%s", u, strings.TrimLeft(query, "?&"))
+			}
+		}
 	}
 
-	var body io.Reader
 	method := strings.ToUpper(string(req.Operation))
 	// For backward compatibility
 	if method == "CREATE" {
 		method = "POST"
 	}
+	hasBody := false
 	switch method {
 	case "PUT", "POST", "PATCH":
-		body = bytes.NewBuffer(req.Data)
+		hasBody = true
 	case "GET", "HEAD", "DELETE", "OPTIONS", "TRACE":
 	default:
 		return nil, fmt.Errorf("invalid operation: %s", req.Operation)
 	}
 
-	// nolint: noctx
-	request, err := http.NewRequest(method, u, body)
-	if err != nil {
+	var resp *bindings.InvokeResponse
+	var statusCode int
+	b := backoff.NewExponentialBackOff()
+	b.InitialInterval = h.metadata.RetryBackoff
+	b.MaxInterval = h.metadata.MaxRetryBackoff
+	b.MaxElapsedTime = 0
+	err := backoff.RetryNotify(func() error {
+		resp = nil
+		var body io.Reader
+		if hasBody {
+			body = bytes.NewReader(req.Data)
+		}
+
+		// nolint: noctx
+		request, err := http.NewRequest(method, u, body)
+		if err != nil {
+			return backoff.Permanent(err)
+		}
+		if err = h.setHeaders(request, req.Metadata, hasBody); err != nil {
+			return err
+		}
+
+		// Send the question
+		r, err := h.client.Do(request)
+		if err != nil {
+			return err
+		}
+		resp, err = readResponse(r)
+		if err != nil {
+			return err
+		}
+
+		statusCode = r.StatusCode
+		if h.retryStatusCodes[statusCode] {
+			return fmt.Errorf("received status code %d", statusCode)
+		}
+
+		return nil
+	}, backoff.WithMaxRetries(b, uint64(h.metadata.MaxRetries)), func(err error, d time.Duration) {
+		h.logger.Warnf("http binding: %s %s failed, retrying in %s: %s", method, u, d, err)
+	})
+	if resp == nil {
 		return nil, err
 	}
 
+	// Create an error for non-200 status codes.
+	if statusCode/100 != 2 && h.metadata.ErrorIfNot2XX {
+		return resp, fmt.Errorf("received status code %d", statusCode)
+	}
+
+	return resp, nil
+}
+
+// setHeaders sets the authorization header and, with defaults for Content-Type
+// and Accept, the headers of the request's metadata.
+func (h *HTTPSource) setHeaders(request *http.Request, metadata map[string]string, hasBody bool) error {
+	if err := h.authorize(request); err != nil {
+		return err
+	}
+
 	// Set default values for Content-Type and Accept headers.
-	if body != nil {
-		if _, ok := req.Metadata["Content-Type"]; !ok {
+	if hasBody {
+		if _, ok := metadata["Content-Type"]; !ok {
 			request.Header.Set("Content-Type", "application/json; charset=utf-8")
 		}
 	}
-	if _, ok := req.Metadata["Accept"]; !ok {
+	if _, ok := metadata["Accept"]; !ok {
 		request.Header.Set("Accept", "application/json; charset=utf-8")
 	}
 
 	// Any metadata keys that start with a capital letter
 	// are treated as request headers
-	for mdKey, mdValue := range req.Metadata {
+	for mdKey, mdValue := range metadata {
 		keyAsRunes := []rune(mdKey)
 		if len(keyAsRunes) > 0 && unicode.IsUpper(keyAsRunes[0]) {
 			request.Header.Set(mdKey, mdValue)
 		}
 	}
 
-	// Send the question
-	resp, err := h.client.Do(request)
-	if err != nil {
-		return nil, err
-	}
+	return nil
+}
+
+// readResponse returns the body of a response with its status and headers as metadata.
+func readResponse(resp *http.Response) (*bindings.InvokeResponse, error) {
 	defer resp.Body.Close()
 
 	// Read the response body. For empty responses (e.g. 204 No Content)
@@ -156,13 +377,8 @@ func (h *HTTPSource) Invoke(req *bindings.InvokeRequest) (*bindings.InvokeRespon
 		metadata[key] = strings.Join(values, ", ")
 	}
 
-	// Create an error for non-200 status codes.
-	if resp.StatusCode/100 != 2 {
-		err = fmt.Errorf("received status code %d", resp.StatusCode)
-	}
-
 	return &bindings.InvokeResponse{
 		Data:     b,
 		Metadata: metadata,
-	}, err
+	}, nil
 }
@@ -6,11 +6,22 @@
 package http_test
 
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
 	"io/ioutil"
+	"math/big"
 	"net/http"
 	"net/http/httptest"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/dapr/dapr/pkg/logger"
 	"github.com/stretchr/testify/assert"
@@ -181,3 +192,231 @@ func TestInit(t *testing.T) {
 		})
 	}
 }
+
+func initHTTP(t *testing.T, properties map[string]string) bindings.OutputBinding {
+	hs := binding_http.NewHTTP(logger.NewLogger("test"))
+	require.NoError(t, hs.Init(bindings.Metadata{Properties: properties}))
+
+	return hs
+}
+
+func TestInvalidMetadata(t *testing.T) {
+	for name, properties := range map[string]map[string]string{
+		"timeout":            {"timeout": "ten seconds"},
+		"max retries":        {"maxRetries": "-1"},
+		"retry status codes": {"retryStatusCodes": "503,unavailable"},
+		"auth type":          {"authType": "digest"},
+		"basic":              {"authType": "basic"},
+		"bearer":             {"authType": "bearer"},
+		"oauth2":             {"authType": "oauth2", "clientID": "id"},
+		"auth style":         {"authType": "oauth2", "clientID": "id", "clientSecret": "secret", "tokenURL": "http://localhost", "authStyle": "3"},
+		"ca certificate":     {"caCert": "invalid"},
+		"client certificate": {"clientCert": "invalid", "clientKey": "invalid"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			properties["url"] = "http://localhost"
+			hs := binding_http.NewHTTP(logger.NewLogger("test"))
+			assert.Error(t, hs.Init(bindings.Metadata{Properties: properties}))
+		})
+	}
+}
+
+func TestQuery(t *testing.T) {
+	var query string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		query = req.URL.RawQuery
+	}))
+	defer s.Close()
+
+	hs := initHTTP(t, map[string]string{"url": s.URL})
+	_, err := hs.Invoke(&bindings.InvokeRequest{Operation: "get", Metadata: map[string]string{"path": "/items", "query": "a=1&b=two"}})
+	require.NoError(t, err)
+	assert.Equal(t, "a=1&b=two", query)
+
+	hs = initHTTP(t, map[string]string{"url": s.URL + "/items?version=2"})
+	_, err = hs.Invoke(&bindings.InvokeRequest{Operation: "get", Metadata: map[string]string{"query": "a=1"}})
+	require.NoError(t, err)
+	assert.Equal(t, "version=2&a=1", query)
+
+	_, err = hs.Invoke(&bindings.InvokeRequest{Operation: "get", Metadata: map[string]string{"query": "a=%zz"}})
+	assert.Error(t, err)
+}
+
+func TestRetries(t *testing.T) {
+	var lock sync.Mutex
+	var bodies []string
+	statusCodes := []int{}
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+
+		b, _ := ioutil.ReadAll(req.Body)
+		bodies = append(bodies, string(b))
+		statusCode := http.StatusOK
+		if len(statusCodes) > 0 {
+			statusCode = statusCodes[0]
+			statusCodes = statusCodes[1:]
+		}
+		w.WriteHeader(statusCode)
+		fmt.Fprintf(w, "attempt %d", len(bodies))
+	}))
+	defer s.Close()
+
+	reset := func(codes ...int) {
+		lock.Lock()
+		defer lock.Unlock()
+		bodies = nil
+		statusCodes = codes
+	}
+
+	hs := initHTTP(t, map[string]string{"url": s.URL, "maxRetries": "2", "retryBackoff": "1ms", "retryStatusCodes": "500,503"})
+
+	t.Run("retried until success", func(t *testing.T) {
+		reset(503, 500)
+		resp, err := hs.Invoke(&bindings.InvokeRequest{Operation: "post", Data: []byte("data")})
+		require.NoError(t, err)
+		assert.Equal(t, "attempt 3", string(resp.Data))
+		assert.Equal(t, []string{"data", "data", "data"}, bodies)
+	})
+
+	t.Run("retries exhausted", func(t *testing.T) {
+		reset(503, 503, 503, 503)
+		resp, err := hs.Invoke(&bindings.InvokeRequest{Operation: "post", Data: []byte("data")})
+		require.Error(t, err)
+		assert.Equal(t, "received status code 503", err.Error())
+		assert.Equal(t, "503", resp.Metadata["statusCode"])
+		assert.Equal(t, "attempt 3", string(resp.Data))
+		assert.Len(t, bodies, 3)
+	})
+
+	t.Run("other status codes are not retried", func(t *testing.T) {
+		reset(404)
+		_, err := hs.Invoke(&bindings.InvokeRequest{Operation: "get"})
+		require.Error(t, err)
+		assert.Len(t, bodies, 1)
+	})
+}
+
+func TestErrorIfNot2XX(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("not found"))
+	}))
+	defer s.Close()
+
+	hs := initHTTP(t, map[string]string{"url": s.URL, "errorIfNot2XX": "false"})
+	resp, err := hs.Invoke(&bindings.InvokeRequest{Operation: "get"})
+	require.NoError(t, err)
+	assert.Equal(t, "not found", string(resp.Data))
+	assert.Equal(t, "404", resp.Metadata["statusCode"])
+	assert.Equal(t, "404 Not Found", resp.Metadata["status"])
+}
+
+func TestTimeout(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer s.Close()
+
+	hs := initHTTP(t, map[string]string{"url": s.URL, "timeout": "50ms"})
+	_, err := hs.Invoke(&bindings.InvokeRequest{Operation: "get"})
+	assert.Error(t, err)
+}
+
+func TestAuth(t *testing.T) {
+	var authorization string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		authorization = req.Header.Get("Authorization")
+	}))
+	defer s.Close()
+
+	t.Run("basic", func(t *testing.T) {
+		hs := initHTTP(t, map[string]string{"url": s.URL, "authType": "basic", "username": "user", "password": "pass"})
+		_, err := hs.Invoke(&bindings.InvokeRequest{Operation: "get"})
+		require.NoError(t, err)
+		assert.Equal(t, "Basic dXNlcjpwYXNz", authorization)
+	})
+
+	t.Run("bearer", func(t *testing.T) {
+		hs := initHTTP(t, map[string]string{"url": s.URL, "authType": "bearer", "bearerToken": "token"})
+		_, err := hs.Invoke(&bindings.InvokeRequest{Operation: "get"})
+		require.NoError(t, err)
+		assert.Equal(t, "Bearer token", authorization)
+	})
+
+	t.Run("oauth2", func(t *testing.T) {
+		var tokenRequests int
+		tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			tokenRequests++
+			require.NoError(t, req.ParseForm())
+			assert.Equal(t, "client_credentials", req.Form.Get("grant_type"))
+			assert.Equal(t, "read write", req.Form.Get("scope"))
+			assert.Equal(t, "api", req.Form.Get("audience"))
+			id, secret, _ := req.BasicAuth()
+			assert.Equal(t, "id", id)
+			assert.Equal(t, "secret", secret)
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprintf(w, `{"access_token": "token%d", "token_type": "Bearer", "expires_in": 3600}`, tokenRequests)
+		}))
+		defer tokenServer.Close()
+
+		hs := initHTTP(t, map[string]string{
+			"url": s.URL, "authType": "oauth2", "clientID": "id", "clientSecret": "secret", "scopes": "read,write",
+			"tokenURL": tokenServer.URL, "endpointParamsQuery": "audience=api", "authStyle": "2",
+		})
+		for i := 0; i < 2; i++ {
+			_, err := hs.Invoke(&bindings.InvokeRequest{Operation: "get"})
+			require.NoError(t, err)
+			assert.Equal(t, "Bearer token1", authorization)
+		}
+		assert.Equal(t, 1, tokenRequests)
+	})
+}
+
+func TestTLS(t *testing.T) {
+	s := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte(req.TLS.PeerCertificates[0].Subject.CommonName))
+	}))
+	s.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	s.StartTLS()
+	defer s.Close()
+
+	caCert := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: s.Certificate().Raw}))
+	clientCert, clientKey := generateCertificate(t, "client")
+
+	hs := initHTTP(t, map[string]string{"url": s.URL, "caCert": caCert, "clientCert": clientCert, "clientKey": clientKey})
+	resp, err := hs.Invoke(&bindings.InvokeRequest{Operation: "get"})
+	require.NoError(t, err)
+	assert.Equal(t, "client", string(resp.Data))
+
+	// Without the client certificate
+	hs = initHTTP(t, map[string]string{"url": s.URL, "caCert": caCert})
+	_, err = hs.Invoke(&bindings.InvokeRequest{Operation: "get"})
+	assert.Error(t, err)
+
+	// Without the ca certificate
+	hs = initHTTP(t, map[string]string{"url": s.URL, "clientCert": clientCert, "clientKey": clientKey})
+	_, err = hs.Invoke(&bindings.InvokeRequest{Operation: "get"})
+	assert.Error(t, err)
+}
+
+// generateCertificate returns a PEM encoded self-signed certificate and its key.
+func generateCertificate(t *testing.T, commonName string) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+		string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}))
+}
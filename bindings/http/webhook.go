@@ -0,0 +1,313 @@
+// ------------------------------------------------------------
+// Copyright (c) Microsoft Corporation and Dapr Contributors.
+// Licensed under the MIT License.
+// ------------------------------------------------------------
+
+package http
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha1" // nolint: gosec
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dapr/components-contrib/bindings"
+)
+
+const (
+	// SignatureTypeNone accepts webhooks without verifying their signature
+	SignatureTypeNone = "none"
+	// SignatureTypeGitHub verifies the X-Hub-Signature-256 header of GitHub webhooks
+	SignatureTypeGitHub = "github"
+	// SignatureTypeStripe verifies the Stripe-Signature header of Stripe webhooks
+	SignatureTypeStripe = "stripe"
+	// SignatureTypeSlack verifies the X-Slack-Signature and X-Slack-Request-Timestamp headers of Slack requests
+	SignatureTypeSlack = "slack"
+	// SignatureTypeHMAC verifies the hex encoded HMAC of the body, prefixed with the timestamp
+	// of timestampHeader and a dot if set, in signatureHeader
+	SignatureTypeHMAC = "hmac"
+
+	// keys from read response's metadata, the headers of the request are added as they are
+	methodResponseMetadata = "method"
+	pathResponseMetadata   = "path"
+	queryResponseMetadata  = "query"
+
+	// the maximum size of the body of webhooks
+	maxWebhookBodySize = 4 << 20
+	// the time to read the headers and the whole request of webhooks, so that slow
+	// clients can't hold connections open
+	webhookReadHeaderTimeout = 10 * time.Second
+	webhookReadTimeout       = 30 * time.Second
+)
+
+// webhookVerifier verifies the HMAC signature and the timestamp of webhooks.
+type webhookVerifier struct {
+	signatureType   string
+	secret          []byte
+	header          string
+	algorithm       string
+	newHash         func() hash.Hash
+	timestampHeader string
+	tolerance       time.Duration
+	now             func() time.Time
+}
+
+func (m *httpMetadata) webhookVerifier() (*webhookVerifier, error) {
+	v := webhookVerifier{
+		signatureType:   strings.ToLower(m.SignatureType),
+		secret:          []byte(m.SignatureSecret),
+		header:          m.SignatureHeader,
+		algorithm:       "sha256",
+		timestampHeader: m.TimestampHeader,
+		tolerance:       m.TimestampTolerance,
+		now:             time.Now,
+	}
+	if m.SignatureAlgorithm != "" {
+		v.algorithm = strings.ToLower(m.SignatureAlgorithm)
+	}
+
+	switch v.signatureType {
+	case "", SignatureTypeNone:
+		return nil, nil
+	case SignatureTypeGitHub:
+		if v.header == "" {
+			v.header = "X-Hub-Signature-256"
+			if v.algorithm == "sha1" {
+				v.header = "X-Hub-Signature"
+			}
+		}
+	case SignatureTypeStripe:
+		if v.header == "" {
+			v.header = "Stripe-Signature"
+		}
+	case SignatureTypeSlack:
+		if v.header == "" {
+			v.header = "X-Slack-Signature"
+		}
+		if v.timestampHeader == "" {
+			v.timestampHeader = "X-Slack-Request-Timestamp"
+		}
+	case SignatureTypeHMAC:
+		if v.header == "" {
+			return nil, errors.New("http binding error: missing signatureHeader for hmac signatures")
+		}
+	default:
+		return nil, fmt.Errorf("http binding error: invalid signatureType %s", m.SignatureType)
+	}
+
+	if len(v.secret) == 0 {
+		return nil, fmt.Errorf("http binding error: missing signatureSecret for %s signatures", v.signatureType)
+	}
+	switch v.algorithm {
+	case "sha1":
+		v.newHash = sha1.New
+	case "sha256":
+		v.newHash = sha256.New
+	case "sha512":
+		v.newHash = sha512.New
+	default:
+		return nil, fmt.Errorf("http binding error: invalid signatureAlgorithm %s", m.SignatureAlgorithm)
+	}
+
+	return &v, nil
+}
+
+// verify returns an error when the signature of a webhook does not match its
+// body, or when its timestamp is out of the tolerance.
+func (v *webhookVerifier) verify(header http.Header, body []byte) error {
+	signature := header.Get(v.header)
+	if signature == "" {
+		return fmt.Errorf("missing %s header", v.header)
+	}
+
+	switch v.signatureType {
+	case SignatureTypeGitHub:
+		// The signature is sha256=<hmac of the body>
+		return v.compare(body, strings.TrimPrefix(signature, v.algorithm+"="))
+	case SignatureTypeStripe:
+		// The signature is t=<timestamp>,v1=<hmac of timestamp.body>,v1=..., with
+		// several v1 signatures while the secret is rolled
+		var timestamp string
+		var signatures []string
+		for _, part := range strings.Split(signature, ",") {
+			kv := strings.SplitN(strings.TrimSpace(part), "=", 2)
+			if len(kv) != 2 {
+				continue
+			}
+			switch kv[0] {
+			case "t":
+				timestamp = kv[1]
+			case "v1":
+				signatures = append(signatures, kv[1])
+			}
+		}
+		if err := v.checkTimestamp(timestamp); err != nil {
+			return err
+		}
+		payload := []byte(timestamp + "." + string(body))
+		for _, s := range signatures {
+			if v.compare(payload, s) == nil {
+				return nil
+			}
+		}
+
+		return errors.New("invalid signature")
+	case SignatureTypeSlack:
+		// The signature is v0=<hmac of v0:timestamp:body>
+		timestamp := header.Get(v.timestampHeader)
+		if err := v.checkTimestamp(timestamp); err != nil {
+			return err
+		}
+
+		return v.compare([]byte("v0:"+timestamp+":"+string(body)), strings.TrimPrefix(signature, "v0="))
+	default:
+		payload := body
+		if v.timestampHeader != "" {
+			timestamp := header.Get(v.timestampHeader)
+			if err := v.checkTimestamp(timestamp); err != nil {
+				return err
+			}
+			payload = []byte(timestamp + "." + string(body))
+		}
+
+		return v.compare(payload, strings.TrimPrefix(signature, v.algorithm+"="))
+	}
+}
+
+// compare compares the hex encoded signature with the HMAC of payload.
+func (v *webhookVerifier) compare(payload []byte, signature string) error {
+	actual, err := hex.DecodeString(signature)
+	if err != nil {
+		return errors.New("invalid signature")
+	}
+
+	mac := hmac.New(v.newHash, v.secret)
+	mac.Write(payload)
+	if !hmac.Equal(mac.Sum(nil), actual) {
+		return errors.New("invalid signature")
+	}
+
+	return nil
+}
+
+// checkTimestamp rejects the webhooks that are replayed after the tolerance,
+// the timestamp is in seconds since the epoch.
+func (v *webhookVerifier) checkTimestamp(timestamp string) error {
+	if timestamp == "" {
+		return errors.New("missing timestamp")
+	}
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid timestamp %s", timestamp)
+	}
+	if v.tolerance <= 0 {
+		return nil
+	}
+
+	diff := v.now().Sub(time.Unix(seconds, 0))
+	if diff > v.tolerance || diff < -v.tolerance {
+		return fmt.Errorf("timestamp %s is out of the tolerance of %s", timestamp, v.tolerance)
+	}
+
+	return nil
+}
+
+// Read listens for webhooks on port and path, and triggers handler with their
+// body. The headers of the request are the metadata of the response, along with
+// its method, path and query. Webhooks are answered with a 500 status code when
+// handler fails, so that senders retry them.
+func (h *HTTPSource) Read(handler func(*bindings.ReadResponse) error) error {
+	if h.metadata.Port == 0 {
+		return errors.New("http binding error: missing port to listen for webhooks")
+	}
+
+	path := "/" + strings.TrimLeft(h.metadata.Path, "/")
+	mux := http.NewServeMux()
+	mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+		h.serveWebhook(w, r, handler)
+	})
+	server := &http.Server{
+		Addr:              fmt.Sprintf(":%d", h.metadata.Port),
+		Handler:           mux,
+		ReadHeaderTimeout: webhookReadHeaderTimeout,
+		ReadTimeout:       webhookReadTimeout,
+	}
+
+	go func() {
+		<-h.ctx.Done()
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := server.Shutdown(ctx); err != nil {
+			h.logger.Errorf("http binding: error shutting down webhook server: %s", err)
+		}
+	}()
+
+	h.logger.Infof("http binding: listening for webhooks at http://localhost:%d%s", h.metadata.Port, path)
+	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		return fmt.Errorf("http binding error: %s", err)
+	}
+
+	return nil
+}
+
+func (h *HTTPSource) serveWebhook(w http.ResponseWriter, r *http.Request, handler func(*bindings.ReadResponse) error) {
+	if r.Method != http.MethodPost && r.Method != http.MethodPut {
+		w.Header().Set("Allow", "POST, PUT")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxWebhookBodySize))
+	if err != nil {
+		http.Error(w, "invalid body", http.StatusBadRequest)
+
+		return
+	}
+
+	if h.verifier != nil {
+		if err = h.verifier.verify(r.Header, body); err != nil {
+			h.logger.Warnf("http binding: rejected webhook from %s: %s", r.RemoteAddr, err)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+
+			return
+		}
+	}
+
+	metadata := make(map[string]string, len(r.Header)+3)
+	for key, values := range r.Header {
+		metadata[key] = strings.Join(values, ", ")
+	}
+	metadata[methodResponseMetadata] = r.Method
+	metadata[pathResponseMetadata] = r.URL.Path
+	if r.URL.RawQuery != "" {
+		metadata[queryResponseMetadata] = r.URL.RawQuery
+	}
+
+	if err = handler(&bindings.ReadResponse{Data: body, Metadata: metadata}); err != nil {
+		// The error is only logged as it may reveal the internals of the application
+		h.logger.Errorf("http binding: error handling webhook: %s", err)
+		http.Error(w, "error handling webhook", http.StatusInternalServerError)
+
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// Close stops listening for webhooks
+func (h *HTTPSource) Close() error {
+	h.cancel()
+
+	return nil
+}
@@ -0,0 +1,235 @@
+// ------------------------------------------------------------
+// Copyright (c) Microsoft Corporation and Dapr Contributors.
+// Licensed under the MIT License.
+// ------------------------------------------------------------
+
+package http_test
+
+import (
+	"crypto/hmac"
+	"crypto/sha1" // nolint: gosec
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dapr/dapr/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/components-contrib/bindings"
+	binding_http "github.com/dapr/components-contrib/bindings/http"
+)
+
+const webhookSecret = "secret"
+
+func sign(newHash func() hash.Hash, payload string) string {
+	mac := hmac.New(newHash, []byte(webhookSecret))
+	mac.Write([]byte(payload))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// startWebhook starts reading webhooks with handler, and returns their URL.
+func startWebhook(t *testing.T, properties map[string]string, handler func(*bindings.ReadResponse) error) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	port := l.Addr().(*net.TCPAddr).Port
+	require.NoError(t, l.Close())
+
+	properties["port"] = strconv.Itoa(port)
+	hs := binding_http.NewHTTP(logger.NewLogger("test"))
+	require.NoError(t, hs.Init(bindings.Metadata{Properties: properties}))
+
+	done := make(chan error)
+	go func() {
+		done <- hs.Read(handler)
+	}()
+	t.Cleanup(func() {
+		require.NoError(t, hs.Close())
+		select {
+		case err := <-done:
+			assert.NoError(t, err)
+		case <-time.After(5 * time.Second):
+			assert.Fail(t, "timeout waiting for Read to return")
+		}
+	})
+
+	u := fmt.Sprintf("http://127.0.0.1:%d/%s", port, strings.TrimLeft(properties["path"], "/"))
+	require.Eventually(t, func() bool {
+		resp, err := http.Get(u) // nolint: noctx
+		if err != nil {
+			return false
+		}
+		resp.Body.Close()
+
+		return true
+	}, 5*time.Second, 10*time.Millisecond)
+
+	return u
+}
+
+func postWebhook(t *testing.T, u, body string, headers map[string]string) int {
+	req, err := http.NewRequest(http.MethodPost, u, strings.NewReader(body)) // nolint: noctx
+	require.NoError(t, err)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	return resp.StatusCode
+}
+
+func TestWebhook(t *testing.T) {
+	var received *bindings.ReadResponse
+	var handlerErr error
+	u := startWebhook(t, map[string]string{"path": "/hooks", "signatureType": "github", "signatureSecret": webhookSecret},
+		func(resp *bindings.ReadResponse) error {
+			received = resp
+
+			return handlerErr
+		})
+	body := `{"action": "opened"}`
+	headers := map[string]string{
+		"X-Hub-Signature-256": "sha256=" + sign(sha256.New, body),
+		"X-GitHub-Event":      "issues",
+	}
+
+	t.Run("valid webhook", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, postWebhook(t, u+"?id=1", body, headers))
+		require.NotNil(t, received)
+		assert.Equal(t, body, string(received.Data))
+		assert.Equal(t, "issues", received.Metadata["X-Github-Event"])
+		assert.Equal(t, "POST", received.Metadata["method"])
+		assert.Equal(t, "/hooks", received.Metadata["path"])
+		assert.Equal(t, "id=1", received.Metadata["query"])
+	})
+
+	t.Run("invalid signature", func(t *testing.T) {
+		received = nil
+		assert.Equal(t, http.StatusUnauthorized, postWebhook(t, u, body+" ", headers))
+		assert.Equal(t, http.StatusUnauthorized, postWebhook(t, u, body, nil))
+		assert.Nil(t, received)
+	})
+
+	t.Run("handler error", func(t *testing.T) {
+		handlerErr = errors.New("handler failed: connection to db-internal refused")
+		defer func() { handlerErr = nil }()
+
+		req, err := http.NewRequest(http.MethodPost, u, strings.NewReader(body)) // nolint: noctx
+		require.NoError(t, err)
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		respBody, err := ioutil.ReadAll(resp.Body)
+		require.NoError(t, err)
+
+		assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+		// The error of the handler is not sent to the client
+		assert.NotContains(t, string(respBody), "db-internal")
+	})
+
+	t.Run("other paths and methods", func(t *testing.T) {
+		assert.Equal(t, http.StatusNotFound, postWebhook(t, strings.TrimSuffix(u, "hooks")+"other", body, headers))
+		resp, err := http.Get(u) // nolint: noctx
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+	})
+}
+
+func TestWebhookSignatures(t *testing.T) {
+	body := `{"type": "event"}`
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	expired := strconv.FormatInt(time.Now().Add(-10*time.Minute).Unix(), 10)
+
+	tests := map[string]struct {
+		properties map[string]string
+		valid      map[string]string
+		invalid    []map[string]string
+	}{
+		"github sha1": {
+			properties: map[string]string{"signatureType": "github", "signatureAlgorithm": "sha1"},
+			valid:      map[string]string{"X-Hub-Signature": "sha1=" + sign(sha1.New, body)},
+			invalid: []map[string]string{
+				{"X-Hub-Signature": "sha1=" + sign(sha256.New, body)},
+			},
+		},
+		"stripe": {
+			properties: map[string]string{"signatureType": "stripe"},
+			valid:      map[string]string{"Stripe-Signature": fmt.Sprintf("t=%s,v1=%s,v1=%s", now, sign(sha256.New, "old"), sign(sha256.New, now+"."+body))},
+			invalid: []map[string]string{
+				{"Stripe-Signature": fmt.Sprintf("t=%s,v1=%s", expired, sign(sha256.New, expired+"."+body))},
+				{"Stripe-Signature": fmt.Sprintf("v1=%s", sign(sha256.New, "."+body))},
+				{"Stripe-Signature": fmt.Sprintf("t=%s,v0=%s", now, sign(sha256.New, now+"."+body))},
+			},
+		},
+		"slack": {
+			properties: map[string]string{"signatureType": "slack"},
+			valid:      map[string]string{"X-Slack-Request-Timestamp": now, "X-Slack-Signature": "v0=" + sign(sha256.New, "v0:"+now+":"+body)},
+			invalid: []map[string]string{
+				{"X-Slack-Request-Timestamp": expired, "X-Slack-Signature": "v0=" + sign(sha256.New, "v0:"+expired+":"+body)},
+				{"X-Slack-Signature": "v0=" + sign(sha256.New, "v0::"+body)},
+			},
+		},
+		"hmac": {
+			properties: map[string]string{"signatureType": "hmac", "signatureHeader": "X-Signature", "signatureAlgorithm": "sha512"},
+			valid:      map[string]string{"X-Signature": sign(sha512.New, body)},
+			invalid: []map[string]string{
+				{"X-Signature": "not hex"},
+				{"X-Other-Signature": sign(sha512.New, body)},
+			},
+		},
+		"hmac with timestamp": {
+			properties: map[string]string{"signatureType": "hmac", "signatureHeader": "X-Signature", "timestampHeader": "X-Timestamp", "timestampTolerance": "1m"},
+			valid:      map[string]string{"X-Timestamp": now, "X-Signature": "sha256=" + sign(sha256.New, now+"."+body)},
+			invalid: []map[string]string{
+				{"X-Timestamp": expired, "X-Signature": sign(sha256.New, expired+"."+body)},
+				{"X-Timestamp": "yesterday", "X-Signature": sign(sha256.New, "yesterday."+body)},
+			},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			tc.properties["signatureSecret"] = webhookSecret
+			u := startWebhook(t, tc.properties, func(*bindings.ReadResponse) error { return nil })
+			assert.Equal(t, http.StatusOK, postWebhook(t, u, body, tc.valid))
+			for _, headers := range tc.invalid {
+				assert.Equal(t, http.StatusUnauthorized, postWebhook(t, u, body, headers), headers)
+			}
+		})
+	}
+}
+
+func TestInvalidWebhookMetadata(t *testing.T) {
+	for name, properties := range map[string]map[string]string{
+		"signature type":      {"signatureType": "gitlab", "signatureSecret": webhookSecret},
+		"missing secret":      {"signatureType": "github"},
+		"missing header":      {"signatureType": "hmac", "signatureSecret": webhookSecret},
+		"signature algorithm": {"signatureType": "github", "signatureSecret": webhookSecret, "signatureAlgorithm": "md5"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			hs := binding_http.NewHTTP(logger.NewLogger("test"))
+			assert.Error(t, hs.Init(bindings.Metadata{Properties: properties}))
+		})
+	}
+
+	hs := binding_http.NewHTTP(logger.NewLogger("test"))
+	require.NoError(t, hs.Init(bindings.Metadata{Properties: map[string]string{}}))
+	assert.Error(t, hs.Read(func(*bindings.ReadResponse) error { return nil }))
+}
@@ -7,18 +7,18 @@ package kafka
 
 import (
 	"context"
-	"crypto/tls"
-	"errors"
-	"fmt"
 	"os"
 	"os/signal"
-	"strconv"
 	"strings"
 	"sync"
 	"syscall"
+	"time"
 
 	"github.com/Shopify/sarama"
+	"github.com/cenkalti/backoff/v4"
 	"github.com/dapr/components-contrib/bindings"
+	contrib_kafka "github.com/dapr/components-contrib/internal/component/kafka"
+	"github.com/dapr/components-contrib/internal/schemaregistry"
 	"github.com/dapr/dapr/pkg/logger"
 )
 
@@ -33,33 +33,48 @@ type Kafka struct {
 	consumerGroup string
 	brokers       []string
 	publishTopic  string
-	authRequired  bool
-	saslUsername  string
-	saslPassword  string
+	clientMeta    *contrib_kafka.ClientMetadata
+	serializer    *schemaregistry.Serializer
 	logger        logger.Logger
 }
 
 type kafkaMetadata struct {
-	Brokers         []string `json:"brokers"`
-	Topics          []string `json:"topics"`
-	PublishTopic    string   `json:"publishTopic"`
-	ConsumerGroup   string   `json:"consumerGroup"`
-	AuthRequired    bool     `json:"authRequired"`
-	SaslUsername    string   `json:"saslUsername"`
-	SaslPassword    string   `json:"saslPassword"`
-	MaxMessageBytes int
+	contrib_kafka.ClientMetadata
+	Topics        []string `json:"topics"`
+	PublishTopic  string   `json:"publishTopic"`
+	ConsumerGroup string   `json:"consumerGroup"`
 }
 
 type consumer struct {
-	ready    chan bool
-	callback func(*bindings.ReadResponse) error
+	ready      chan bool
+	callback   func(*bindings.ReadResponse) error
+	serializer *schemaregistry.Serializer
+	backOff    backoff.BackOff
+	logger     logger.Logger
 }
 
 func (consumer *consumer) ConsumeClaim(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+	bo := backoff.WithContext(consumer.backOff, session.Context())
 	for message := range claim.Messages() {
 		if consumer.callback != nil {
+			data := message.Value
+			if consumer.serializer != nil && schemaregistry.IsWireFormat(data) {
+				var err error
+				if data, err = consumer.deserialize(message, bo); err != nil {
+					if schemaregistry.IsDecodeError(err) && consumer.serializer.SkipUndecodable() {
+						consumer.logger.Errorf("skipping message %s/%d/%d that can't be decoded: %s", message.Topic, message.Partition, message.Offset, err)
+						session.MarkMessage(message, "")
+
+						continue
+					}
+
+					// The message isn't marked, so the claim is consumed again from it
+					return err
+				}
+			}
 			err := consumer.callback(&bindings.ReadResponse{
-				Data: message.Value,
+				Data:     data,
+				Metadata: contrib_kafka.HeadersToMetadata(message.Headers),
 			})
 			if err == nil {
 				session.MarkMessage(message, "")
@@ -70,6 +85,28 @@ func (consumer *consumer) ConsumeClaim(session sarama.ConsumerGroupSession, clai
 	return nil
 }
 
+// deserialize decodes a message with the schema registry, retrying the errors
+// reaching the registry. Messages that can't be decoded are not retried.
+func (consumer *consumer) deserialize(message *sarama.ConsumerMessage, bo backoff.BackOff) ([]byte, error) {
+	var data []byte
+	err := backoff.RetryNotify(func() error {
+		var err error
+		data, err = consumer.serializer.Deserialize(message.Value)
+		if schemaregistry.IsDecodeError(err) {
+			return backoff.Permanent(err)
+		}
+
+		return err
+	}, bo, func(err error, d time.Duration) {
+		consumer.logger.Errorf("error decoding message %s/%d/%d, retrying: %s", message.Topic, message.Partition, message.Offset, err)
+	})
+	if err != nil {
+		consumer.logger.Errorf("error decoding message %s/%d/%d: %s", message.Topic, message.Partition, message.Offset, err)
+	}
+
+	return data, err
+}
+
 func (consumer *consumer) Setup(sarama.ConsumerGroupSession) error {
 	close(consumer.ready)
 
@@ -88,6 +125,11 @@ func (k *Kafka) Init(metadata bindings.Metadata) error {
 		return err
 	}
 
+	k.serializer, err = schemaregistry.NewSerializerFromMetadata(metadata.Properties)
+	if err != nil {
+		return err
+	}
+
 	p, err := k.getSyncProducer(meta)
 	if err != nil {
 		return err
@@ -98,13 +140,7 @@ func (k *Kafka) Init(metadata bindings.Metadata) error {
 	k.topics = meta.Topics
 	k.publishTopic = meta.PublishTopic
 	k.consumerGroup = meta.ConsumerGroup
-	k.authRequired = meta.AuthRequired
-
-	// ignore SASL properties if authRequired is false
-	if meta.AuthRequired {
-		k.saslUsername = meta.SaslUsername
-		k.saslPassword = meta.SaslPassword
-	}
+	k.clientMeta = &meta.ClientMetadata
 
 	return nil
 }
@@ -114,9 +150,17 @@ func (k *Kafka) Operations() []bindings.OperationKind {
 }
 
 func (k *Kafka) Invoke(req *bindings.InvokeRequest) (*bindings.InvokeResponse, error) {
+	data := req.Data
+	if k.serializer != nil {
+		var err error
+		if data, err = k.serializer.Serialize(k.publishTopic, data); err != nil {
+			return nil, err
+		}
+	}
+
 	msg := &sarama.ProducerMessage{
 		Topic: k.publishTopic,
-		Value: sarama.ByteEncoder(req.Data),
+		Value: sarama.ByteEncoder(data),
 	}
 	if val, ok := req.Metadata[key]; ok && val != "" {
 		msg.Key = sarama.StringEncoder(val)
@@ -136,49 +180,15 @@ func (k *Kafka) getKafkaMetadata(metadata bindings.Metadata) (*kafkaMetadata, er
 	meta.ConsumerGroup = metadata.Properties["consumerGroup"]
 	meta.PublishTopic = metadata.Properties["publishTopic"]
 
-	if val, ok := metadata.Properties["brokers"]; ok && val != "" {
-		meta.Brokers = strings.Split(val, ",")
-	}
 	if val, ok := metadata.Properties["topics"]; ok && val != "" {
 		meta.Topics = strings.Split(val, ",")
 	}
 
-	val, ok := metadata.Properties["authRequired"]
-	if !ok {
-		return nil, errors.New("kafka error: missing 'authRequired' attribute")
-	}
-	if val == "" {
-		return nil, errors.New("kafka error: 'authRequired' attribute was empty")
-	}
-	validAuthRequired, err := strconv.ParseBool(val)
+	clientMeta, err := contrib_kafka.ParseClientMetadata(metadata.Properties, sarama.V1_0_0_0)
 	if err != nil {
-		return nil, errors.New("kafka error: invalid value for 'authRequired' attribute")
-	}
-	meta.AuthRequired = validAuthRequired
-
-	// ignore SASL properties if authRequired is false
-	if meta.AuthRequired {
-		if val, ok := metadata.Properties["saslUsername"]; ok && val != "" {
-			meta.SaslUsername = val
-		} else {
-			return nil, errors.New("kafka error: missing SASL Username")
-		}
-
-		if val, ok := metadata.Properties["saslPassword"]; ok && val != "" {
-			meta.SaslPassword = val
-		} else {
-			return nil, errors.New("kafka error: missing SASL Password")
-		}
-	}
-
-	if val, ok := metadata.Properties["maxMessageBytes"]; ok && val != "" {
-		maxBytes, err := strconv.Atoi(val)
-		if err != nil {
-			return nil, fmt.Errorf("kafka error: cannot parse maxMessageBytes: %s", err)
-		}
-
-		meta.MaxMessageBytes = maxBytes
+		return nil, err
 	}
+	meta.ClientMetadata = *clientMeta
 
 	return &meta, nil
 }
@@ -188,15 +198,9 @@ func (k *Kafka) getSyncProducer(meta *kafkaMetadata) (sarama.SyncProducer, error
 	config.Producer.RequiredAcks = sarama.WaitForAll
 	config.Producer.Retry.Max = 5
 	config.Producer.Return.Successes = true
-	config.Version = sarama.V1_0_0_0
 
-	// ignore SASL properties if authRequired is false
-	if meta.AuthRequired {
-		updateAuthInfo(config, meta.SaslUsername, meta.SaslPassword)
-	}
-
-	if meta.MaxMessageBytes > 0 {
-		config.Producer.MaxMessageBytes = meta.MaxMessageBytes
+	if err := meta.UpdateConfig(config); err != nil {
+		return nil, err
 	}
 
 	producer, err := sarama.NewSyncProducer(meta.Brokers, config)
@@ -209,14 +213,15 @@ func (k *Kafka) getSyncProducer(meta *kafkaMetadata) (sarama.SyncProducer, error
 
 func (k *Kafka) Read(handler func(*bindings.ReadResponse) error) error {
 	config := sarama.NewConfig()
-	config.Version = sarama.V1_0_0_0
-	// ignore SASL properties if authRequired is false
-	if k.authRequired {
-		updateAuthInfo(config, k.saslUsername, k.saslPassword)
+	if err := k.clientMeta.UpdateConfig(config); err != nil {
+		return err
 	}
 	c := consumer{
-		callback: handler,
-		ready:    make(chan bool),
+		callback:   handler,
+		ready:      make(chan bool),
+		serializer: k.serializer,
+		backOff:    backoff.NewConstantBackOff(5 * time.Second),
+		logger:     k.logger,
 	}
 
 	client, err := sarama.NewConsumerGroup(k.brokers, k.consumerGroup, config)
@@ -259,17 +264,3 @@ func (k *Kafka) Read(handler func(*bindings.ReadResponse) error) error {
 func (consumer *consumer) Cleanup(sarama.ConsumerGroupSession) error {
 	return nil
 }
-
-func updateAuthInfo(config *sarama.Config, saslUsername, saslPassword string) {
-	config.Net.SASL.Enable = true
-	config.Net.SASL.User = saslUsername
-	config.Net.SASL.Password = saslPassword
-	config.Net.SASL.Mechanism = sarama.SASLTypePlaintext
-
-	config.Net.TLS.Enable = true
-	// nolint: gosec
-	config.Net.TLS.Config = &tls.Config{
-		// InsecureSkipVerify: true,
-		ClientAuth: 0,
-	}
-}
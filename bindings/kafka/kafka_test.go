@@ -6,12 +6,22 @@
 package kafka
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/Shopify/sarama"
+	"github.com/cenkalti/backoff/v4"
 	"github.com/dapr/components-contrib/bindings"
+	"github.com/dapr/components-contrib/internal/schemaregistry"
 	"github.com/dapr/dapr/pkg/logger"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestParseMetadata(t *testing.T) {
@@ -274,3 +284,117 @@ func TestParseMetadata(t *testing.T) {
 		assert.Nil(t, meta)
 	})
 }
+
+func TestParseClientOptions(t *testing.T) {
+	m := bindings.Metadata{}
+	m.Properties = map[string]string{
+		"consumerGroup": "a", "publishTopic": "a", "brokers": "a", "topics": "a",
+		"authType": "scram-sha-512", "saslUsername": "foo", "saslPassword": "bar",
+		"initialOffset": "oldest", "version": "2.1.0", "balanceStrategy": "roundrobin",
+	}
+	k := Kafka{logger: logger.NewLogger("test")}
+	meta, err := k.getKafkaMetadata(m)
+	assert.Nil(t, err)
+	assert.Equal(t, "scram-sha-512", meta.AuthType)
+	assert.Equal(t, "foo", meta.SaslUsername)
+	assert.Equal(t, sarama.OffsetOldest, meta.InitialOffset)
+	assert.Equal(t, sarama.V2_1_0_0, meta.Version)
+	assert.Equal(t, sarama.BalanceStrategyRoundRobin, meta.BalanceStrategy)
+}
+
+type fakeSession struct {
+	sarama.ConsumerGroupSession
+	marked []int64
+}
+
+func (s *fakeSession) Context() context.Context {
+	return context.Background()
+}
+
+func (s *fakeSession) MarkMessage(msg *sarama.ConsumerMessage, metadata string) {
+	s.marked = append(s.marked, msg.Offset)
+}
+
+type fakeClaim struct {
+	sarama.ConsumerGroupClaim
+	messages chan *sarama.ConsumerMessage
+}
+
+func (c *fakeClaim) Messages() <-chan *sarama.ConsumerMessage {
+	return c.messages
+}
+
+func newFakeClaim(values ...[]byte) *fakeClaim {
+	c := &fakeClaim{messages: make(chan *sarama.ConsumerMessage, len(values))}
+	for i, value := range values {
+		c.messages <- &sarama.ConsumerMessage{Topic: "orders", Offset: int64(i), Value: value}
+	}
+	close(c.messages)
+
+	return c
+}
+
+func TestConsumeClaimWithSchemaRegistry(t *testing.T) {
+	// {"id": "a1"} encoded with the schema of id 1
+	order := []byte{0, 0, 0, 0, 1, 4, 'a', '1'}
+	// a payload that doesn't match the schema of id 1
+	invalid := []byte{0, 0, 0, 0, 1, 0xff}
+
+	newConsumer := func(t *testing.T, properties map[string]string) (*consumer, *[]string) {
+		var unavailable int32 = 1
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if atomic.CompareAndSwapInt32(&unavailable, 1, 0) {
+				w.WriteHeader(http.StatusServiceUnavailable)
+
+				return
+			}
+			json.NewEncoder(w).Encode(schemaregistry.Schema{Schema: `{"type":"record","name":"Order","fields":[{"name":"id","type":"string"}]}`})
+		}))
+		t.Cleanup(server.Close)
+
+		properties["schemaRegistryURL"] = server.URL
+		serializer, err := schemaregistry.NewSerializerFromMetadata(properties)
+		require.NoError(t, err)
+
+		var received []string
+		c := &consumer{
+			serializer: serializer,
+			backOff:    backoff.NewConstantBackOff(time.Millisecond),
+			logger:     logger.NewLogger("test"),
+			callback: func(res *bindings.ReadResponse) error {
+				received = append(received, string(res.Data))
+
+				return nil
+			},
+		}
+
+		return c, &received
+	}
+
+	t.Run("registry errors are retried", func(t *testing.T) {
+		c, received := newConsumer(t, map[string]string{})
+		session := &fakeSession{}
+
+		require.NoError(t, c.ConsumeClaim(session, newFakeClaim(order)))
+		assert.Equal(t, []string{`{"id":"a1"}`}, *received)
+		assert.Equal(t, []int64{0}, session.marked)
+	})
+
+	t.Run("undecodable messages stop the claim", func(t *testing.T) {
+		c, received := newConsumer(t, map[string]string{})
+		session := &fakeSession{}
+
+		assert.Error(t, c.ConsumeClaim(session, newFakeClaim(order, invalid, order)))
+		assert.Equal(t, []string{`{"id":"a1"}`}, *received)
+		assert.Equal(t, []int64{0}, session.marked)
+	})
+
+	t.Run("undecodable messages are skipped when enabled", func(t *testing.T) {
+		c, received := newConsumer(t, map[string]string{"skipUndecodableMessages": "true"})
+		session := &fakeSession{}
+
+		require.NoError(t, c.ConsumeClaim(session, newFakeClaim(order, invalid, order)))
+		assert.Equal(t, []string{`{"id":"a1"}`, `{"id":"a1"}`}, *received)
+		assert.Equal(t, []int64{0, 1, 2}, session.marked)
+	})
+}
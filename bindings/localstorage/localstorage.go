@@ -0,0 +1,429 @@
+// ------------------------------------------------------------
+// Copyright (c) Microsoft Corporation and Dapr Contributors.
+// Licensed under the MIT License.
+// ------------------------------------------------------------
+
+package localstorage
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/dapr/components-contrib/bindings"
+	contrib_objectstorage "github.com/dapr/components-contrib/internal/component/objectstorage"
+	"github.com/dapr/dapr/pkg/logger"
+	"github.com/fsnotify/fsnotify"
+	"github.com/google/uuid"
+)
+
+const (
+	// rootPathKey is the directory that holds the files of the binding
+	rootPathKey = "rootPath"
+	// decodeBase64Key decodes the data of the create operation from base64
+	decodeBase64Key = "decodeBase64"
+
+	// keys from request's metadata
+	fileNameKey = "fileName"
+
+	// keys from read response's metadata
+	eventKey = "event"
+
+	createEvent = "create"
+	modifyEvent = "modify"
+	deleteEvent = "delete"
+
+	dirPerm  = 0755
+	filePerm = 0644
+)
+
+// LocalStorage is a binding for the files of a local directory
+type LocalStorage struct {
+	metadata *localStorageMetadata
+	stopCh   chan struct{}
+	stopOnce sync.Once
+	logger   logger.Logger
+}
+
+type localStorageMetadata struct {
+	rootPath     string
+	decodeBase64 bool
+}
+
+var (
+	_ = bindings.InputBinding(&LocalStorage{})
+	_ = bindings.OutputBinding(&LocalStorage{})
+)
+
+// NewLocalStorage returns a new LocalStorage instance
+func NewLocalStorage(logger logger.Logger) *LocalStorage {
+	return &LocalStorage{
+		stopCh: make(chan struct{}),
+		logger: logger,
+	}
+}
+
+// Init creates the root directory when it does not exist
+func (ls *LocalStorage) Init(metadata bindings.Metadata) error {
+	m, err := parseMetadata(metadata.Properties)
+	if err != nil {
+		return err
+	}
+
+	if err = os.MkdirAll(m.rootPath, dirPerm); err != nil {
+		return fmt.Errorf("error creating root path %s: %s", m.rootPath, err)
+	}
+	// The root path is resolved so that the files can be checked to be in it
+	root, err := filepath.Abs(m.rootPath)
+	if err != nil {
+		return fmt.Errorf("invalid root path %s: %s", m.rootPath, err)
+	}
+	if m.rootPath, err = filepath.EvalSymlinks(root); err != nil {
+		return fmt.Errorf("invalid root path %s: %s", root, err)
+	}
+	ls.metadata = m
+
+	return nil
+}
+
+func parseMetadata(props map[string]string) (*localStorageMetadata, error) {
+	m := localStorageMetadata{}
+
+	if val, ok := props[rootPathKey]; ok && val != "" {
+		m.rootPath = val
+	} else {
+		return nil, fmt.Errorf("missing local storage metadata: %s", rootPathKey)
+	}
+
+	if val, ok := props[decodeBase64Key]; ok && val != "" {
+		decodeBase64, err := strconv.ParseBool(val)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s %s: %s", decodeBase64Key, val, err)
+		}
+		m.decodeBase64 = decodeBase64
+	}
+
+	return &m, nil
+}
+
+// Operations returns the operations of the output binding
+func (ls *LocalStorage) Operations() []bindings.OperationKind {
+	return []bindings.OperationKind{
+		bindings.CreateOperation,
+		bindings.GetOperation,
+		bindings.DeleteOperation,
+		bindings.ListOperation,
+	}
+}
+
+// Invoke runs an operation on the files of the root directory
+func (ls *LocalStorage) Invoke(req *bindings.InvokeRequest) (*bindings.InvokeResponse, error) {
+	switch req.Operation {
+	case bindings.CreateOperation:
+		return ls.create(req)
+	case bindings.GetOperation:
+		return ls.get(req)
+	case bindings.DeleteOperation:
+		return ls.delete(req)
+	case bindings.ListOperation:
+		return ls.list(req)
+	default:
+		return nil, fmt.Errorf("unsupported operation %s", req.Operation)
+	}
+}
+
+func (ls *LocalStorage) create(req *bindings.InvokeRequest) (*bindings.InvokeResponse, error) {
+	name := ""
+	if val, ok := req.Metadata[fileNameKey]; ok && val != "" {
+		name = val
+	} else {
+		name = uuid.New().String()
+		ls.logger.Debugf("%s not found. generating name %s", fileNameKey, name)
+	}
+
+	p, err := ls.resolvePath(name)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := contrib_objectstorage.DecodeData(req.Data, ls.metadata.decodeBase64, req.Metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = os.MkdirAll(filepath.Dir(p), dirPerm); err != nil {
+		return nil, fmt.Errorf("error creating directory of file %s: %s", name, err)
+	}
+	if err = ioutil.WriteFile(p, data, filePerm); err != nil {
+		return nil, fmt.Errorf("error writing file %s: %s", name, err)
+	}
+
+	return &bindings.InvokeResponse{
+		Metadata: map[string]string{fileNameKey: name},
+	}, nil
+}
+
+func (ls *LocalStorage) get(req *bindings.InvokeRequest) (*bindings.InvokeResponse, error) {
+	name, p, err := ls.requiredPath(req)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := ioutil.ReadFile(p)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("file %s not found", name)
+		}
+
+		return nil, fmt.Errorf("error reading file %s: %s", name, err)
+	}
+
+	return &bindings.InvokeResponse{
+		Data: data,
+	}, nil
+}
+
+func (ls *LocalStorage) delete(req *bindings.InvokeRequest) (*bindings.InvokeResponse, error) {
+	name, p, err := ls.requiredPath(req)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := os.Lstat(p)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("file %s not found", name)
+		}
+
+		return nil, fmt.Errorf("error deleting file %s: %s", name, err)
+	}
+	if info.IsDir() {
+		return nil, fmt.Errorf("error deleting file %s: is a directory", name)
+	}
+
+	if err = os.Remove(p); err != nil {
+		return nil, fmt.Errorf("error deleting file %s: %s", name, err)
+	}
+
+	return nil, nil
+}
+
+// list returns the regular files of the root directory and its subdirectories
+// sorted by name, which are slash separated paths relative to the root.
+func (ls *LocalStorage) list(req *bindings.InvokeRequest) (*bindings.InvokeResponse, error) {
+	r, err := contrib_objectstorage.ParseListRequest(req.Metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	var objects []contrib_objectstorage.Object
+	err = filepath.Walk(ls.metadata.rootPath, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(ls.metadata.rootPath, p)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+		if strings.HasPrefix(key, r.Prefix) && key > r.Marker {
+			objects = append(objects, contrib_objectstorage.Object{
+				Key:          key,
+				Size:         info.Size(),
+				LastModified: info.ModTime().UTC(),
+			})
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error listing files: %s", err)
+	}
+
+	sort.Slice(objects, func(i, j int) bool {
+		return objects[i].Key < objects[j].Key
+	})
+	resp := contrib_objectstorage.ListResponse{
+		Objects: []contrib_objectstorage.Object{},
+	}
+	if len(objects) > r.MaxResults {
+		objects = objects[:r.MaxResults]
+		resp.NextMarker = objects[len(objects)-1].Key
+	}
+	resp.Objects = append(resp.Objects, objects...)
+
+	b, err := json.Marshal(resp)
+	if err != nil {
+		return nil, fmt.Errorf("error marshalling list response: %s", err)
+	}
+
+	return &bindings.InvokeResponse{
+		Data: b,
+	}, nil
+}
+
+func (ls *LocalStorage) requiredPath(req *bindings.InvokeRequest) (string, string, error) {
+	name, ok := req.Metadata[fileNameKey]
+	if !ok || name == "" {
+		return "", "", fmt.Errorf("required metadata %s missing", fileNameKey)
+	}
+
+	p, err := ls.resolvePath(name)
+
+	return name, p, err
+}
+
+// resolvePath returns the path of a file name in the root directory. Names
+// that are absolute, contain a parent directory element or resolve through
+// symbolic links to a path outside of the root directory are rejected.
+func (ls *LocalStorage) resolvePath(name string) (string, error) {
+	if strings.ContainsRune(name, 0) || filepath.IsAbs(name) || filepath.VolumeName(name) != "" ||
+		strings.HasPrefix(name, "/") || strings.HasPrefix(name, `\`) {
+		return "", fmt.Errorf("invalid file name %s: must be a relative path", name)
+	}
+	for _, element := range strings.FieldsFunc(name, func(r rune) bool { return r == '/' || r == '\\' }) {
+		if element == ".." {
+			return "", fmt.Errorf("invalid file name %s: must not refer to a parent directory", name)
+		}
+	}
+
+	p := filepath.Join(ls.metadata.rootPath, filepath.FromSlash(name))
+	if p == ls.metadata.rootPath {
+		return "", fmt.Errorf("invalid file name %s: must not be the root path", name)
+	}
+
+	// The deepest existing element of the path is resolved, the others are
+	// created by the create operation and cannot be links.
+	existing := p
+	for {
+		if _, err := os.Lstat(existing); err == nil || existing == ls.metadata.rootPath {
+			break
+		}
+		existing = filepath.Dir(existing)
+	}
+	resolved, err := filepath.EvalSymlinks(existing)
+	if err != nil {
+		return "", fmt.Errorf("invalid file name %s: %s", name, err)
+	}
+	if !isWithin(ls.metadata.rootPath, resolved) {
+		return "", fmt.Errorf("invalid file name %s: must be in the root path", name)
+	}
+
+	return p, nil
+}
+
+func isWithin(root, p string) bool {
+	rel, err := filepath.Rel(root, p)
+
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// Read watches the root directory and its subdirectories, and triggers the
+// handler when a file is created, modified or deleted until Close is called.
+func (ls *LocalStorage) Read(handler func(*bindings.ReadResponse) error) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("error creating watcher: %s", err)
+	}
+	defer watcher.Close()
+
+	if err = ls.watch(watcher, ls.metadata.rootPath, nil); err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-ls.stopCh:
+			return nil
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			ls.handleEvent(watcher, event, handler)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			ls.logger.Errorf("error watching %s: %s", ls.metadata.rootPath, err)
+		}
+	}
+}
+
+// watch adds a directory and its subdirectories to the watcher. The files
+// already in them are passed to found, if any, as they may have been created
+// before the directory was watched.
+func (ls *LocalStorage) watch(watcher *fsnotify.Watcher, dir string, found func(p string)) error {
+	return filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if err = watcher.Add(p); err != nil {
+				return fmt.Errorf("error watching %s: %s", p, err)
+			}
+		} else if found != nil && info.Mode().IsRegular() {
+			found(p)
+		}
+
+		return nil
+	})
+}
+
+func (ls *LocalStorage) handleEvent(watcher *fsnotify.Watcher, event fsnotify.Event, handler func(*bindings.ReadResponse) error) {
+	trigger := func(kind, p string) {
+		rel, err := filepath.Rel(ls.metadata.rootPath, p)
+		if err != nil {
+			return
+		}
+		err = handler(&bindings.ReadResponse{
+			Metadata: map[string]string{
+				eventKey:    kind,
+				fileNameKey: filepath.ToSlash(rel),
+			},
+		})
+		if err != nil {
+			ls.logger.Errorf("error handling %s event of %s: %s", kind, rel, err)
+		}
+	}
+
+	switch {
+	case event.Op&fsnotify.Create == fsnotify.Create:
+		info, err := os.Lstat(event.Name)
+		if err != nil {
+			return
+		}
+		if info.IsDir() {
+			err = ls.watch(watcher, event.Name, func(p string) {
+				trigger(createEvent, p)
+			})
+			if err != nil {
+				ls.logger.Errorf("%s", err)
+			}
+		} else if info.Mode().IsRegular() {
+			trigger(createEvent, event.Name)
+		}
+	case event.Op&fsnotify.Write == fsnotify.Write:
+		trigger(modifyEvent, event.Name)
+	case event.Op&(fsnotify.Remove|fsnotify.Rename) != 0:
+		trigger(deleteEvent, event.Name)
+	}
+}
+
+// Close stops watching the root directory
+func (ls *LocalStorage) Close() error {
+	ls.stopOnce.Do(func() {
+		close(ls.stopCh)
+	})
+
+	return nil
+}
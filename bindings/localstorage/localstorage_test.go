@@ -0,0 +1,251 @@
+// ------------------------------------------------------------
+// Copyright (c) Microsoft Corporation and Dapr Contributors.
+// Licensed under the MIT License.
+// ------------------------------------------------------------
+
+package localstorage
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dapr/components-contrib/bindings"
+	contrib_objectstorage "github.com/dapr/components-contrib/internal/component/objectstorage"
+	"github.com/dapr/dapr/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestLocalStorage(t *testing.T) (*LocalStorage, string) {
+	dir, err := ioutil.TempDir("", "localstorage")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		os.RemoveAll(dir)
+	})
+
+	root := filepath.Join(dir, "root")
+	ls := NewLocalStorage(logger.NewLogger("test"))
+	require.NoError(t, ls.Init(bindings.Metadata{Properties: map[string]string{rootPathKey: root}}))
+
+	return ls, dir
+}
+
+func TestParseMetadata(t *testing.T) {
+	m, err := parseMetadata(map[string]string{rootPathKey: "/tmp/files", decodeBase64Key: "true"})
+	require.NoError(t, err)
+	assert.Equal(t, "/tmp/files", m.rootPath)
+	assert.True(t, m.decodeBase64)
+
+	_, err = parseMetadata(map[string]string{})
+	assert.Error(t, err)
+	_, err = parseMetadata(map[string]string{rootPathKey: "/tmp/files", decodeBase64Key: "maybe"})
+	assert.Error(t, err)
+}
+
+func TestOperations(t *testing.T) {
+	ls, _ := newTestLocalStorage(t)
+
+	t.Run("create and get", func(t *testing.T) {
+		resp, err := ls.Invoke(&bindings.InvokeRequest{
+			Operation: bindings.CreateOperation,
+			Data:      []byte(base64.StdEncoding.EncodeToString([]byte("hello"))),
+			Metadata:  map[string]string{fileNameKey: "docs/a.txt", "decodeBase64": "true"},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "docs/a.txt", resp.Metadata[fileNameKey])
+
+		resp, err = ls.Invoke(&bindings.InvokeRequest{
+			Operation: bindings.GetOperation,
+			Metadata:  map[string]string{fileNameKey: "docs/a.txt"},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "hello", string(resp.Data))
+	})
+
+	t.Run("create generates a file name", func(t *testing.T) {
+		resp, err := ls.Invoke(&bindings.InvokeRequest{
+			Operation: bindings.CreateOperation,
+			Data:      []byte("generated"),
+		})
+		require.NoError(t, err)
+		name := resp.Metadata[fileNameKey]
+		assert.NotEmpty(t, name)
+
+		_, err = ls.Invoke(&bindings.InvokeRequest{
+			Operation: bindings.DeleteOperation,
+			Metadata:  map[string]string{fileNameKey: name},
+		})
+		require.NoError(t, err)
+	})
+
+	t.Run("list pages", func(t *testing.T) {
+		for _, name := range []string{"docs/b.txt", "docs/c/d.txt", "other.txt"} {
+			_, err := ls.Invoke(&bindings.InvokeRequest{
+				Operation: bindings.CreateOperation,
+				Data:      []byte(name),
+				Metadata:  map[string]string{fileNameKey: name},
+			})
+			require.NoError(t, err)
+		}
+
+		resp, err := ls.Invoke(&bindings.InvokeRequest{
+			Operation: bindings.ListOperation,
+			Metadata:  map[string]string{"prefix": "docs/", "maxResults": "2"},
+		})
+		require.NoError(t, err)
+		var page contrib_objectstorage.ListResponse
+		require.NoError(t, json.Unmarshal(resp.Data, &page))
+		require.Len(t, page.Objects, 2)
+		assert.Equal(t, "docs/a.txt", page.Objects[0].Key)
+		assert.Equal(t, int64(5), page.Objects[0].Size)
+		assert.Equal(t, "docs/b.txt", page.NextMarker)
+
+		resp, err = ls.Invoke(&bindings.InvokeRequest{
+			Operation: bindings.ListOperation,
+			Metadata:  map[string]string{"prefix": "docs/", "marker": page.NextMarker},
+		})
+		require.NoError(t, err)
+		page = contrib_objectstorage.ListResponse{}
+		require.NoError(t, json.Unmarshal(resp.Data, &page))
+		require.Len(t, page.Objects, 1)
+		assert.Equal(t, "docs/c/d.txt", page.Objects[0].Key)
+		assert.Empty(t, page.NextMarker)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		_, err := ls.Invoke(&bindings.InvokeRequest{
+			Operation: bindings.DeleteOperation,
+			Metadata:  map[string]string{fileNameKey: "other.txt"},
+		})
+		require.NoError(t, err)
+
+		_, err = ls.Invoke(&bindings.InvokeRequest{
+			Operation: bindings.GetOperation,
+			Metadata:  map[string]string{fileNameKey: "other.txt"},
+		})
+		assert.Error(t, err)
+
+		_, err = ls.Invoke(&bindings.InvokeRequest{
+			Operation: bindings.DeleteOperation,
+			Metadata:  map[string]string{fileNameKey: "docs/c"},
+		})
+		assert.Error(t, err)
+	})
+
+	t.Run("missing file name", func(t *testing.T) {
+		for _, op := range []bindings.OperationKind{bindings.GetOperation, bindings.DeleteOperation} {
+			_, err := ls.Invoke(&bindings.InvokeRequest{Operation: op, Metadata: map[string]string{}})
+			assert.Error(t, err, op)
+		}
+	})
+
+	t.Run("unsupported operation", func(t *testing.T) {
+		_, err := ls.Invoke(&bindings.InvokeRequest{Operation: "unsupported"})
+		assert.Error(t, err)
+	})
+}
+
+func TestPathTraversal(t *testing.T) {
+	ls, dir := newTestLocalStorage(t)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "secret.txt"), []byte("secret"), 0600))
+	require.NoError(t, os.Symlink(dir, filepath.Join(ls.metadata.rootPath, "link")))
+
+	for _, name := range []string{
+		"../secret.txt",
+		"docs/../../secret.txt",
+		"docs/..",
+		`..\secret.txt`,
+		"/etc/passwd",
+		`\secret.txt`,
+		".",
+		"link/secret.txt",
+		"link/new.txt",
+		"a\x00b",
+	} {
+		_, err := ls.Invoke(&bindings.InvokeRequest{
+			Operation: bindings.GetOperation,
+			Metadata:  map[string]string{fileNameKey: name},
+		})
+		assert.Error(t, err, name)
+
+		_, err = ls.Invoke(&bindings.InvokeRequest{
+			Operation: bindings.CreateOperation,
+			Data:      []byte("overwritten"),
+			Metadata:  map[string]string{fileNameKey: name},
+		})
+		assert.Error(t, err, name)
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join(dir, "secret.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "secret", string(data))
+	_, err = os.Stat(filepath.Join(dir, "new.txt"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestRead(t *testing.T) {
+	ls, _ := newTestLocalStorage(t)
+
+	events := make(chan map[string]string, 10)
+	done := make(chan error)
+	go func() {
+		done <- ls.Read(func(resp *bindings.ReadResponse) error {
+			events <- resp.Metadata
+
+			return nil
+		})
+	}()
+
+	// next skips the modify events, whose number depends on how the files are written
+	next := func() map[string]string {
+		for {
+			select {
+			case e := <-events:
+				if e[eventKey] != modifyEvent {
+					return e
+				}
+			case <-time.After(5 * time.Second):
+				require.Fail(t, "timeout waiting for event")
+
+				return nil
+			}
+		}
+	}
+
+	// The watcher is added asynchronously, new files are created until it is
+	var created map[string]string
+	for i := 0; created == nil; i++ {
+		require.Less(t, i, 50, "timeout waiting for create event")
+		name := fmt.Sprintf("a%d.txt", i)
+		require.NoError(t, ioutil.WriteFile(filepath.Join(ls.metadata.rootPath, name), []byte("a"), 0600))
+		select {
+		case e := <-events:
+			if e[eventKey] == createEvent {
+				created = e
+			}
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+	require.Equal(t, createEvent, created[eventKey])
+
+	require.NoError(t, os.Remove(filepath.Join(ls.metadata.rootPath, created[fileNameKey])))
+	assert.Equal(t, map[string]string{eventKey: deleteEvent, fileNameKey: created[fileNameKey]}, next())
+
+	require.NoError(t, os.MkdirAll(filepath.Join(ls.metadata.rootPath, "docs"), 0700))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(ls.metadata.rootPath, "docs", "b.txt"), []byte("b"), 0600))
+	assert.Equal(t, map[string]string{eventKey: createEvent, fileNameKey: "docs/b.txt"}, next())
+
+	require.NoError(t, ls.Close())
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		assert.Fail(t, "timeout waiting for Read to return")
+	}
+}
@@ -6,6 +6,7 @@
 package mqtt
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -16,7 +17,10 @@ import (
 	"time"
 
 	"github.com/dapr/components-contrib/bindings"
+	contrib_mqtt "github.com/dapr/components-contrib/internal/component/mqtt"
+	contrib_metadata "github.com/dapr/components-contrib/metadata"
 	"github.com/dapr/dapr/pkg/logger"
+	"github.com/eclipse/paho.golang/paho"
 	mqtt "github.com/eclipse/paho.mqtt.golang"
 	"github.com/google/uuid"
 )
@@ -25,14 +29,18 @@ import (
 type MQTT struct {
 	metadata *mqttMetadata
 	client   mqtt.Client
+	clientV5 *contrib_mqtt.V5Client
 
 	logger logger.Logger
 }
 
 // Metadata is the MQTT config
 type mqttMetadata struct {
-	URL   string `json:"url"`
-	Topic string `json:"topic"`
+	URL             string `json:"url"`
+	Topic           string `json:"topic"`
+	ProtocolVersion string `json:"protocolVersion"`
+	// ConsumerID makes the input bindings sharing it competing consumers with MQTT 5
+	ConsumerID string `json:"consumerID"`
 }
 
 // NewMQTT returns a new MQTT instance
@@ -56,10 +64,27 @@ func (m *MQTT) Init(metadata bindings.Metadata) error {
 		return errors.New("MQTT error: topic required")
 	}
 
+	protocolVersion, err := contrib_mqtt.ParseProtocolVersion(m.metadata.ProtocolVersion)
+	if err != nil {
+		return fmt.Errorf("MQTT error: %s", err)
+	}
+	m.metadata.ProtocolVersion = protocolVersion
+
 	uri, err := url.Parse(m.metadata.URL)
 	if err != nil {
 		return err
 	}
+
+	if protocolVersion == contrib_mqtt.ProtocolV5 {
+		m.clientV5, err = contrib_mqtt.ConnectV5(context.Background(), contrib_mqtt.V5Options{
+			URL:        uri,
+			ClientID:   uuid.New().String(),
+			CleanStart: true,
+		}, m.logger)
+
+		return err
+	}
+
 	client, err := m.connect(uuid.New().String(), uri)
 	if err != nil {
 		return err
@@ -89,6 +114,10 @@ func (m *MQTT) Operations() []bindings.OperationKind {
 }
 
 func (m *MQTT) Invoke(req *bindings.InvokeRequest) (*bindings.InvokeResponse, error) {
+	if m.clientV5 != nil {
+		return nil, m.invokeV5(req)
+	}
+
 	m.client.Publish(m.metadata.Topic, 0, false, string(req.Data))
 	m.client.Disconnect(0)
 
@@ -99,6 +128,15 @@ func (m *MQTT) Read(handler func(*bindings.ReadResponse) error) error {
 	c := make(chan os.Signal, 1)
 	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
 
+	if m.clientV5 != nil {
+		if err := m.readV5(handler); err != nil {
+			return err
+		}
+		<-c
+
+		return m.clientV5.Close()
+	}
+
 	m.client.Subscribe(m.metadata.Topic, 0, func(client mqtt.Client, msg mqtt.Message) {
 		handler(&bindings.ReadResponse{
 			Data: msg.Payload(),
@@ -132,3 +170,36 @@ func (m *MQTT) createClientOptions(clientID string, uri *url.URL) *mqtt.ClientOp
 
 	return opts
 }
+
+func (m *MQTT) invokeV5(req *bindings.InvokeRequest) error {
+	ttl, _, err := contrib_metadata.TryGetTTL(req.Metadata)
+	if err != nil {
+		return fmt.Errorf("MQTT error: %s", err)
+	}
+
+	properties := make(map[string]string, len(req.Metadata))
+	for k, v := range req.Metadata {
+		if k != contrib_metadata.TTLMetadataKey {
+			properties[k] = v
+		}
+	}
+
+	return m.clientV5.Publish(context.Background(), m.metadata.Topic, 0, false, req.Data, properties, ttl)
+}
+
+func (m *MQTT) readV5(handler func(*bindings.ReadResponse) error) error {
+	filter := m.metadata.Topic
+	if m.metadata.ConsumerID != "" {
+		filter = contrib_mqtt.SharedSubscription(m.metadata.ConsumerID, m.metadata.Topic)
+	}
+
+	return m.clientV5.Subscribe(context.Background(), filter, m.metadata.Topic, 0, func(p *paho.Publish) {
+		res := &bindings.ReadResponse{Data: p.Payload}
+		if p.Properties != nil {
+			res.Metadata = contrib_mqtt.MetadataFromUserProperties(p.Properties.User)
+		}
+		if err := handler(res); err != nil {
+			m.logger.Errorf("MQTT error handling message on %s: %s", p.Topic, err)
+		}
+	})
+}
@@ -22,3 +22,13 @@ func TestParseMetadata(t *testing.T) {
 	assert.Equal(t, "a", mm.URL)
 	assert.Equal(t, "a", mm.Topic)
 }
+
+func TestParseMetadataV5(t *testing.T) {
+	m := bindings.Metadata{}
+	m.Properties = map[string]string{"url": "tcp://localhost:1883", "topic": "orders", "protocolVersion": "5", "consumerID": "orders-service"}
+	mq := MQTT{logger: logger.NewLogger("test")}
+	mm, err := mq.getMQTTMetadata(m)
+	assert.Nil(t, err)
+	assert.Equal(t, "5", mm.ProtocolVersion)
+	assert.Equal(t, "orders-service", mm.ConsumerID)
+}
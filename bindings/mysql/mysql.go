@@ -6,16 +6,17 @@
 package mysql
 
 import (
+	"context"
 	"crypto/tls"
 	"crypto/x509"
 	"database/sql"
-	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"strconv"
 	"time"
 
 	"github.com/dapr/components-contrib/bindings"
+	contrib_sql "github.com/dapr/components-contrib/internal/component/sql"
 	"github.com/dapr/dapr/pkg/logger"
 	"github.com/go-sql-driver/mysql"
 	"github.com/pkg/errors"
@@ -23,9 +24,10 @@ import (
 
 const (
 	// list of operations.
-	execOperation  bindings.OperationKind = "exec"
-	queryOperation bindings.OperationKind = "query"
-	closeOperation bindings.OperationKind = "close"
+	execOperation        = contrib_sql.ExecOperation
+	queryOperation       = contrib_sql.QueryOperation
+	transactionOperation = contrib_sql.TransactionOperation
+	closeOperation       = contrib_sql.CloseOperation
 
 	// configurations to connect to Mysql, either a data source name represent by URL
 	connectionURLKey = "url"
@@ -46,15 +48,11 @@ const (
 	connMaxIdleTimeKey = "connMaxIdleTime"
 
 	// keys from request's metadata
-	commandSQLKey = "sql"
+	commandSQLKey = contrib_sql.CommandSQLKey
+	paramsKey     = contrib_sql.ParamsKey
 
 	// keys from response's metadata
-	respOpKey           = "operation"
-	respSQLKey          = "sql"
-	respStartTimeKey    = "start-time"
-	respRowsAffectedKey = "rows-affected"
-	respEndTimeKey      = "end-time"
-	respDurationKey     = "duration"
+	respRowsAffectedKey = contrib_sql.RespRowsAffectedKey
 )
 
 // Mysql represents MySQL output bindings
@@ -125,60 +123,12 @@ func (m *Mysql) Invoke(req *bindings.InvokeRequest) (*bindings.InvokeResponse, e
 		return nil, m.db.Close()
 	}
 
-	if req.Metadata == nil {
-		return nil, errors.Errorf("metadata required")
-	}
-	m.logger.Debugf("operation: %v", req.Operation)
-
-	s, ok := req.Metadata[commandSQLKey]
-	if !ok || s == "" {
-		return nil, errors.Errorf("required metadata not set: %s", commandSQLKey)
-	}
-
-	startTime := time.Now().UTC()
-
-	resp := &bindings.InvokeResponse{
-		Metadata: map[string]string{
-			respOpKey:        string(req.Operation),
-			respSQLKey:       s,
-			respStartTimeKey: startTime.Format(time.RFC3339Nano),
-		},
-	}
-
-	switch req.Operation { // nolint: exhaustive
-	case execOperation:
-		r, err := m.exec(s)
-		if err != nil {
-			return nil, errors.Wrapf(err, "error executing %s with %v", s, err)
-		}
-		resp.Metadata[respRowsAffectedKey] = strconv.FormatInt(r, 10)
-
-	case queryOperation:
-		d, err := m.query(s)
-		if err != nil {
-			return nil, errors.Wrapf(err, "error executing %s with %v", s, err)
-		}
-		resp.Data = d
-
-	default:
-		return nil, errors.Errorf("invalid operation type: %s. Expected %s, %s, or %s",
-			req.Operation, execOperation, queryOperation, closeOperation)
-	}
-
-	endTime := time.Now().UTC()
-	resp.Metadata[respEndTimeKey] = endTime.Format(time.RFC3339Nano)
-	resp.Metadata[respDurationKey] = endTime.Sub(startTime).String()
-
-	return resp, nil
+	return contrib_sql.Invoke(context.Background(), m.db, req, m.logger)
 }
 
 // Operations returns list of operations supported by Mysql binding
 func (m *Mysql) Operations() []bindings.OperationKind {
-	return []bindings.OperationKind{
-		execOperation,
-		queryOperation,
-		closeOperation,
-	}
+	return contrib_sql.Operations()
 }
 
 // Close will close the DB
@@ -190,38 +140,6 @@ func (m *Mysql) Close() error {
 	return nil
 }
 
-func (m *Mysql) query(s string) ([]byte, error) {
-	m.logger.Debugf("query: %s", s)
-
-	rows, err := m.db.Query(s)
-	if err != nil {
-		return nil, errors.Wrapf(err, "error executing %s", s)
-	}
-
-	defer func() {
-		_ = rows.Close()
-		_ = rows.Err()
-	}()
-
-	result, err := jsonify(rows)
-	if err != nil {
-		return nil, errors.Wrapf(err, "error marshalling query result for %s", s)
-	}
-
-	return result, nil
-}
-
-func (m *Mysql) exec(sql string) (int64, error) {
-	m.logger.Debugf("exec: %s", sql)
-
-	res, err := m.db.Exec(sql)
-	if err != nil {
-		return 0, errors.Wrapf(err, "error executing %s", sql)
-	}
-
-	return res.RowsAffected()
-}
-
 func propertyToInt(props map[string]string, key string, setter func(int)) error {
 	if v, ok := props[key]; ok {
 		if i, err := strconv.Atoi(v); err == nil {
@@ -277,93 +195,3 @@ func initDB(url, pemPath string) (*sql.DB, error) {
 
 	return db, nil
 }
-
-func jsonify(rows *sql.Rows) ([]byte, error) {
-	columnTypes, err := rows.ColumnTypes()
-	if err != nil {
-		return nil, err
-	}
-
-	var ret []interface{}
-	for rows.Next() {
-		scanArgs := prepareScanArgs(columnTypes)
-		err := rows.Scan(scanArgs...)
-		if err != nil {
-			return nil, err
-		}
-
-		r := convertScanArgs(columnTypes, scanArgs)
-		ret = append(ret, r)
-	}
-
-	return json.Marshal(ret)
-}
-
-func convertScanArgs(columnTypes []*sql.ColumnType, scanArgs []interface{}) map[string]interface{} {
-	r := map[string]interface{}{}
-
-	for i, v := range columnTypes {
-		if s, ok := (scanArgs[i]).(*sql.NullString); ok {
-			r[v.Name()] = s.String
-
-			continue
-		}
-
-		if s, ok := (scanArgs[i]).(*sql.NullBool); ok {
-			r[v.Name()] = s.Bool
-
-			continue
-		}
-
-		if s, ok := (scanArgs[i]).(*sql.NullInt32); ok {
-			r[v.Name()] = s.Int32
-
-			continue
-		}
-
-		if s, ok := (scanArgs[i]).(*sql.NullInt64); ok {
-			r[v.Name()] = s.Int64
-
-			continue
-		}
-
-		if s, ok := (scanArgs[i]).(*sql.NullFloat64); ok {
-			r[v.Name()] = s.Float64
-
-			continue
-		}
-
-		if s, ok := (scanArgs[i]).(*sql.NullTime); ok {
-			r[v.Name()] = s.Time
-
-			continue
-		}
-
-		// this won't happen since the default switch is sql.NullString
-		r[v.Name()] = scanArgs[i]
-	}
-
-	return r
-}
-
-func prepareScanArgs(columnTypes []*sql.ColumnType) []interface{} {
-	scanArgs := make([]interface{}, len(columnTypes))
-	for i, v := range columnTypes {
-		switch v.DatabaseTypeName() {
-		case "BOOL":
-			scanArgs[i] = new(sql.NullBool)
-		case "INT", "MEDIUMINT", "SMALLINT", "CHAR", "TINYINT":
-			scanArgs[i] = new(sql.NullInt32)
-		case "BIGINT":
-			scanArgs[i] = new(sql.NullInt64)
-		case "DOUBLE", "FLOAT", "DECIMAL":
-			scanArgs[i] = new(sql.NullFloat64)
-		case "DATE", "TIME", "YEAR":
-			scanArgs[i] = new(sql.NullTime)
-		default:
-			scanArgs[i] = new(sql.NullString)
-		}
-	}
-
-	return scanArgs
-}
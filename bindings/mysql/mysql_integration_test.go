@@ -37,8 +37,9 @@ func TestOperations(t *testing.T) {
 		b := NewMysql(nil)
 		assert.NotNil(t, b)
 		l := b.Operations()
-		assert.Equal(t, 3, len(l))
+		assert.Equal(t, 4, len(l))
 		assert.Contains(t, l, execOperation)
+		assert.Contains(t, l, transactionOperation)
 		assert.Contains(t, l, closeOperation)
 		assert.Contains(t, l, queryOperation)
 	})
@@ -119,9 +120,8 @@ func TestMysqlIntegration(t *testing.T) {
 		// verify timestamp
 		ts, ok := result[0].(map[string]interface{})["ts"].(string)
 		assert.True(t, ok)
-		// have to use custom layout to parse timestamp, see this: https://github.com/dapr/components-contrib/pull/615
 		var tt time.Time
-		tt, err = time.Parse("2006-01-02 15:04:05", ts)
+		tt, err = time.Parse(time.RFC3339, ts)
 		assert.Nil(t, err)
 		t.Logf("time stamp is: %v", tt)
 	})
@@ -23,12 +23,15 @@ func TestQuery(t *testing.T) {
 			AddRow(3, "value-3", time.Now().Add(2000))
 
 		mock.ExpectQuery("SELECT \\* FROM foo WHERE id < 4").WillReturnRows(rows)
-		ret, err := m.query(`SELECT * FROM foo WHERE id < 4`)
+		ret, err := m.Invoke(&bindings.InvokeRequest{
+			Operation: queryOperation,
+			Metadata:  map[string]string{commandSQLKey: "SELECT * FROM foo WHERE id < 4"},
+		})
 		assert.Nil(t, err)
-		t.Logf("query result: %s", ret)
-		assert.Contains(t, string(ret), "\"id\":\"1\"")
+		t.Logf("query result: %s", ret.Data)
+		assert.Contains(t, string(ret.Data), "\"id\":1")
 		var result []interface{}
-		err = json.Unmarshal(ret, &result)
+		err = json.Unmarshal(ret.Data, &result)
 		assert.Nil(t, err)
 		assert.Equal(t, 3, len(result))
 	})
@@ -41,17 +44,20 @@ func TestQuery(t *testing.T) {
 			AddRow(1, 1.1, time.Now()).
 			AddRow(2, 2.2, time.Now().Add(1000)).
 			AddRow(3, 3.3, time.Now().Add(2000))
-		mock.ExpectQuery("SELECT \\* FROM foo WHERE id < 4").WillReturnRows(rows)
-		ret, err := m.query("SELECT * FROM foo WHERE id < 4")
+		mock.ExpectQuery("SELECT \\* FROM foo WHERE id < \\?").WithArgs(int64(4)).WillReturnRows(rows)
+		ret, err := m.Invoke(&bindings.InvokeRequest{
+			Operation: queryOperation,
+			Metadata:  map[string]string{commandSQLKey: "SELECT * FROM foo WHERE id < ?", paramsKey: "[4]"},
+		})
 		assert.Nil(t, err)
-		t.Logf("query result: %s", ret)
+		t.Logf("query result: %s", ret.Data)
 
 		// verify number
-		assert.Contains(t, string(ret), "\"id\":1")
-		assert.Contains(t, string(ret), "\"value\":2.2")
+		assert.Contains(t, string(ret.Data), "\"id\":1")
+		assert.Contains(t, string(ret.Data), "\"value\":2.2")
 
 		var result []interface{}
-		err = json.Unmarshal(ret, &result)
+		err = json.Unmarshal(ret.Data, &result)
 		assert.Nil(t, err)
 		assert.Equal(t, 3, len(result))
 
@@ -68,10 +74,38 @@ func TestQuery(t *testing.T) {
 func TestExec(t *testing.T) {
 	m, mock, _ := mockDatabase(t)
 	defer m.Close()
-	mock.ExpectExec("INSERT INTO foo \\(id, v1, ts\\) VALUES \\(.*\\)").WillReturnResult(sqlmock.NewResult(1, 1))
-	i, err := m.exec("INSERT INTO foo (id, v1, ts) VALUES (1, 'test-1', '2021-01-22')")
-	assert.Equal(t, int64(1), i)
+	mock.ExpectExec("INSERT INTO foo \\(id, v1, ts\\) VALUES \\(\\?, \\?, \\?\\)").
+		WithArgs(int64(1), "test-1", "2021-01-22").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	resp, err := m.Invoke(&bindings.InvokeRequest{
+		Operation: execOperation,
+		Metadata: map[string]string{
+			commandSQLKey: "INSERT INTO foo (id, v1, ts) VALUES (?, ?, ?)",
+			paramsKey:     `[1, "test-1", "2021-01-22"]`,
+		},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "1", resp.Metadata[respRowsAffectedKey])
+}
+
+func TestTransaction(t *testing.T) {
+	m, mock, _ := mockDatabase(t)
+	defer m.Close()
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM foo WHERE id = \\?").WithArgs(int64(1)).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO foo \\(id, v1\\) VALUES \\(\\?, \\?\\)").WithArgs(int64(1), "test-1").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	resp, err := m.Invoke(&bindings.InvokeRequest{
+		Operation: transactionOperation,
+		Data: []byte(`[
+			{"sql": "DELETE FROM foo WHERE id = ?", "params": [1]},
+			{"sql": "INSERT INTO foo (id, v1) VALUES (?, ?)", "params": [1, "test-1"]}
+		]`),
+	})
 	assert.Nil(t, err)
+	assert.Equal(t, "2", resp.Metadata[respRowsAffectedKey])
+	assert.Nil(t, mock.ExpectationsWereMet())
 }
 
 func TestInvoke(t *testing.T) {
@@ -7,30 +7,32 @@ package postgres
 
 import (
 	"context"
-	"encoding/json"
-	"strconv"
-	"time"
+	"database/sql"
 
 	"github.com/dapr/components-contrib/bindings"
+	contrib_sql "github.com/dapr/components-contrib/internal/component/sql"
 	"github.com/dapr/dapr/pkg/logger"
 	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/jackc/pgx/v4/stdlib"
 	"github.com/pkg/errors"
 )
 
 // List of operations.
 const (
-	execOperation  bindings.OperationKind = "exec"
-	queryOperation bindings.OperationKind = "query"
-	closeOperation bindings.OperationKind = "close"
+	execOperation        = contrib_sql.ExecOperation
+	queryOperation       = contrib_sql.QueryOperation
+	transactionOperation = contrib_sql.TransactionOperation
+	closeOperation       = contrib_sql.CloseOperation
 
 	connectionURLKey = "url"
-	commandSQLKey    = "sql"
+	commandSQLKey    = contrib_sql.CommandSQLKey
+	paramsKey        = contrib_sql.ParamsKey
 )
 
 // Postgres represents PostgreSQL output binding
 type Postgres struct {
 	logger logger.Logger
-	db     *pgxpool.Pool
+	db     *sql.DB
 }
 
 var _ = bindings.OutputBinding(&Postgres{})
@@ -47,26 +49,31 @@ func (p *Postgres) Init(metadata bindings.Metadata) error {
 		return errors.Errorf("required metadata not set: %s", connectionURLKey)
 	}
 
+	// The pool settings of the URL, such as pool_max_conns, are applied to the database/sql pool
 	poolConfig, err := pgxpool.ParseConfig(url)
 	if err != nil {
 		return errors.Wrap(err, "error opening DB connection")
 	}
 
-	p.db, err = pgxpool.ConnectConfig(context.Background(), poolConfig)
-	if err != nil {
+	db := stdlib.OpenDB(*poolConfig.ConnConfig)
+	db.SetMaxOpenConns(int(poolConfig.MaxConns))
+	db.SetConnMaxLifetime(poolConfig.MaxConnLifetime)
+	db.SetConnMaxIdleTime(poolConfig.MaxConnIdleTime)
+
+	if err = db.PingContext(context.Background()); err != nil {
+		_ = db.Close()
+
 		return errors.Wrap(err, "unable to ping the DB")
 	}
 
+	p.db = db
+
 	return nil
 }
 
 // Operations returns list of operations supported by PostgreSql binding
 func (p *Postgres) Operations() []bindings.OperationKind {
-	return []bindings.OperationKind{
-		execOperation,
-		queryOperation,
-		closeOperation,
-	}
+	return contrib_sql.Operations()
 }
 
 // Invoke handles all invoke operations
@@ -76,92 +83,8 @@ func (p *Postgres) Invoke(req *bindings.InvokeRequest) (resp *bindings.InvokeRes
 	}
 
 	if req.Operation == closeOperation {
-		p.db.Close()
-
-		return nil, nil
-	}
-
-	if req.Metadata == nil {
-		return nil, errors.Errorf("metadata required")
-	}
-	p.logger.Debugf("operation: %v", req.Operation)
-
-	sql, ok := req.Metadata[commandSQLKey]
-	if !ok || sql == "" {
-		return nil, errors.Errorf("required metadata not set: %s", commandSQLKey)
-	}
-
-	startTime := time.Now().UTC()
-	resp = &bindings.InvokeResponse{
-		Metadata: map[string]string{
-			"operation":  string(req.Operation),
-			"sql":        sql,
-			"start-time": startTime.Format(time.RFC3339Nano),
-		},
-	}
-
-	switch req.Operation { // nolint: exhaustive
-	case execOperation:
-		r, err := p.exec(sql)
-		if err != nil {
-			return nil, errors.Wrapf(err, "error executing %s with %v", sql, err)
-		}
-		resp.Metadata["rows-affected"] = strconv.FormatInt(r, 10) // 0 if error
-
-	case queryOperation:
-		d, err := p.query(sql)
-		if err != nil {
-			return nil, errors.Wrapf(err, "error executing %s with %v", sql, err)
-		}
-		resp.Data = d
-
-	default:
-		return nil, errors.Errorf(
-			"invalid operation type: %s. Expected %s, %s, or %s",
-			req.Operation, execOperation, queryOperation, closeOperation,
-		)
-	}
-
-	endTime := time.Now().UTC()
-	resp.Metadata["end-time"] = endTime.Format(time.RFC3339Nano)
-	resp.Metadata["duration"] = endTime.Sub(startTime).String()
-
-	return resp, nil
-}
-
-func (p *Postgres) query(sql string) (result []byte, err error) {
-	p.logger.Debugf("query: %s", sql)
-
-	rows, err := p.db.Query(context.Background(), sql)
-	if err != nil {
-		return nil, errors.Wrapf(err, "error executing %s", sql)
-	}
-
-	rs := make([]interface{}, 0)
-	for rows.Next() {
-		val, rowErr := rows.Values()
-		if rowErr != nil {
-			return nil, errors.Wrapf(rowErr, "error parsing result: %v", rows.Err())
-		}
-		rs = append(rs, val)
-	}
-
-	if result, err = json.Marshal(rs); err != nil {
-		err = errors.Wrap(err, "error serializing results")
-	}
-
-	return
-}
-
-func (p *Postgres) exec(sql string) (result int64, err error) {
-	p.logger.Debugf("exec: %s", sql)
-
-	res, err := p.db.Exec(context.Background(), sql)
-	if err != nil {
-		return 0, errors.Wrapf(err, "error executing %s", sql)
+		return nil, p.db.Close()
 	}
 
-	result = res.RowsAffected()
-
-	return
+	return contrib_sql.Invoke(context.Background(), p.db, req, p.logger)
 }
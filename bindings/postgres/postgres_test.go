@@ -24,7 +24,7 @@ const (
 	testInsert = "INSERT INTO foo (id, v1, ts) VALUES (%d, 'test-%d', '%v')"
 	testDelete = "DELETE FROM foo"
 	testUpdate = "UPDATE foo SET ts = '%v' WHERE id = %d"
-	testSelect = "SELECT * FROM foo WHERE id < 3"
+	testSelect = "SELECT * FROM foo WHERE id < $1"
 )
 
 func TestOperations(t *testing.T) {
@@ -33,7 +33,7 @@ func TestOperations(t *testing.T) {
 		b := NewPostgres(nil)
 		assert.NotNil(t, b)
 		l := b.Operations()
-		assert.Equal(t, 3, len(l))
+		assert.Equal(t, 4, len(l))
 	})
 }
 
@@ -93,13 +93,26 @@ func TestPostgresIntegration(t *testing.T) {
 	t.Run("Invoke select", func(t *testing.T) {
 		req.Operation = queryOperation
 		req.Metadata[commandSQLKey] = testSelect
+		req.Metadata[paramsKey] = "[3]"
 		res, err := b.Invoke(req)
 		assertResponse(t, res, err)
 	})
 
+	t.Run("Invoke transaction", func(t *testing.T) {
+		res, err := b.Invoke(&bindings.InvokeRequest{
+			Operation: transactionOperation,
+			Data: []byte(`[
+				{"sql": "UPDATE foo SET v1 = $1 WHERE id = $2", "params": ["updated", 1]},
+				{"operation": "query", "sql": "SELECT v1 FROM foo WHERE id = $1", "params": [1]}
+			]`),
+		})
+		assertResponse(t, res, err)
+		assert.JSONEq(t, `[{"rowsAffected":1},{"rows":[{"v1":"updated"}]}]`, string(res.Data))
+	})
+
 	t.Run("Invoke delete", func(t *testing.T) {
 		req.Operation = execOperation
-		req.Metadata[commandSQLKey] = testDelete
+		req.Metadata = map[string]string{commandSQLKey: testDelete}
 		req.Data = nil
 		res, err := b.Invoke(req)
 		assertResponse(t, res, err)
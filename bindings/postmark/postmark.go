@@ -6,11 +6,12 @@
 package postmark
 
 import (
+	b64 "encoding/base64"
 	"errors"
 	"fmt"
-	"strconv"
 
 	"github.com/dapr/components-contrib/bindings"
+	contrib_email "github.com/dapr/components-contrib/internal/component/email"
 	"github.com/dapr/dapr/pkg/logger"
 	"github.com/keighl/postmark"
 )
@@ -18,6 +19,7 @@ import (
 // Postmark allows sending of emails using the 3rd party Postmark service
 type Postmark struct {
 	metadata postmarkMetadata
+	builder  *contrib_email.Builder
 	logger   logger.Logger
 }
 
@@ -71,9 +73,14 @@ func (p *Postmark) Init(metadata bindings.Metadata) error {
 		return err
 	}
 
-	// Um, yeah that's about it!
 	p.metadata = meta
 
+	// The builder renders the templates and decodes the attachments of requests
+	p.builder, err = contrib_email.NewBuilder(metadata.Properties, p.logger)
+	if err != nil {
+		return fmt.Errorf("Postmark binding error: %s", err)
+	}
+
 	return nil
 }
 
@@ -84,6 +91,25 @@ func (p *Postmark) Operations() []bindings.OperationKind {
 
 // Invoke does the work of sending message to Postmark API
 func (p *Postmark) Invoke(req *bindings.InvokeRequest) (*bindings.InvokeResponse, error) {
+	email, err := p.buildEmail(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Send the email
+	client := postmark.NewClient(p.metadata.ServerToken, p.metadata.AccountToken)
+	_, err = client.SendEmail(email)
+	if err != nil {
+		return nil, fmt.Errorf("error from Postmark, sending email failed: %+v", err)
+	}
+
+	p.logger.Info("sent email with Postmark")
+
+	return nil, nil
+}
+
+// buildEmail builds the email of a request, with its bodies, attachments and headers
+func (p *Postmark) buildEmail(req *bindings.InvokeRequest) (postmark.Email, error) {
 	// We allow two possible sources of the properties we need,
 	// the component metadata or request metadata, request takes priority if present
 
@@ -97,7 +123,7 @@ func (p *Postmark) Invoke(req *bindings.InvokeRequest) (*bindings.InvokeResponse
 		email.From = req.Metadata["emailFrom"]
 	}
 	if len(email.From) == 0 {
-		return nil, fmt.Errorf("error Postmark from email not supplied")
+		return email, fmt.Errorf("error Postmark from email not supplied")
 	}
 
 	// Build email to address, this is required
@@ -108,7 +134,7 @@ func (p *Postmark) Invoke(req *bindings.InvokeRequest) (*bindings.InvokeResponse
 		email.To = req.Metadata["emailTo"]
 	}
 	if len(email.To) == 0 {
-		return nil, fmt.Errorf("error Postmark to email not supplied")
+		return email, fmt.Errorf("error Postmark to email not supplied")
 	}
 
 	// Build email subject, this is required
@@ -119,7 +145,7 @@ func (p *Postmark) Invoke(req *bindings.InvokeRequest) (*bindings.InvokeResponse
 		email.Subject = req.Metadata["subject"]
 	}
 	if len(email.Subject) == 0 {
-		return nil, fmt.Errorf("error Postmark subject not supplied")
+		return email, fmt.Errorf("error Postmark subject not supplied")
 	}
 
 	// Build email cc address, this is optional
@@ -138,18 +164,24 @@ func (p *Postmark) Invoke(req *bindings.InvokeRequest) (*bindings.InvokeResponse
 		email.Bcc = req.Metadata["emailBcc"]
 	}
 
-	// Email body is held in req.Data, after we tidy it up a bit
-	emailBody, _ := strconv.Unquote(string(req.Data))
-	email.HtmlBody = emailBody
-
-	// Send the email
-	client := postmark.NewClient(p.metadata.ServerToken, p.metadata.AccountToken)
-	_, err := client.SendEmail(email)
+	// Email bodies and attachments are held in req.Data
+	msg, err := p.builder.Build(req)
 	if err != nil {
-		return nil, fmt.Errorf("error from Postmark, sending email failed: %+v", err)
+		return email, fmt.Errorf("error Postmark email: %s", err)
+	}
+	email.HtmlBody = msg.HTMLBody
+	email.TextBody = msg.TextBody
+	email.ReplyTo = msg.ReplyTo
+	for _, a := range msg.Attachments {
+		email.Attachments = append(email.Attachments, postmark.Attachment{
+			Name:        a.Filename,
+			Content:     b64.StdEncoding.EncodeToString(a.Content),
+			ContentType: a.ContentType,
+		})
+	}
+	for k, v := range contrib_email.PriorityHeaders(msg.Priority) {
+		email.Headers = append(email.Headers, postmark.Header{Name: k, Value: v})
 	}
 
-	p.logger.Info("sent email with Postmark")
-
-	return nil, nil
+	return email, nil
 }
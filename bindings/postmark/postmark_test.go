@@ -10,7 +10,9 @@ import (
 
 	"github.com/dapr/components-contrib/bindings"
 	"github.com/dapr/dapr/pkg/logger"
+	"github.com/keighl/postmark"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestParseMetadata(t *testing.T) {
@@ -29,3 +31,36 @@ func TestParseMetadata(t *testing.T) {
 		assert.Equal(t, "hello", pMeta.Subject)
 	})
 }
+
+func TestBuildEmail(t *testing.T) {
+	p := NewPostmark(logger.NewLogger("test"))
+	require.NoError(t, p.Init(bindings.Metadata{Properties: map[string]string{
+		"serverToken": "abc", "accountToken": "123", "emailFrom": "test1@example.net", "emailTo": "test2@example.net", "subject": "hello", "replyTo": "reply@example.net",
+	}}))
+
+	t.Run("Has html body", func(t *testing.T) {
+		email, err := p.buildEmail(&bindings.InvokeRequest{Data: []byte(`"<b>Hello</b>"`)})
+		require.NoError(t, err)
+		assert.Equal(t, "<b>Hello</b>", email.HtmlBody)
+		assert.Empty(t, email.TextBody)
+		assert.Equal(t, "reply@example.net", email.ReplyTo)
+		assert.Equal(t, "hello", email.Subject)
+	})
+
+	t.Run("Has alternatives, attachments and priority", func(t *testing.T) {
+		email, err := p.buildEmail(&bindings.InvokeRequest{
+			Data:     []byte(`{"html": "<b>Hello</b>", "text": "Hello", "attachments": [{"filename": "a.txt", "contentType": "text/plain", "content": "aGVsbG8="}]}`),
+			Metadata: map[string]string{"priority": "high"},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "<b>Hello</b>", email.HtmlBody)
+		assert.Equal(t, "Hello", email.TextBody)
+		assert.Equal(t, []postmark.Attachment{{Name: "a.txt", Content: "aGVsbG8=", ContentType: "text/plain"}}, email.Attachments)
+		assert.ElementsMatch(t, []postmark.Header{{Name: "X-Priority", Value: "1"}, {Name: "Importance", Value: "high"}}, email.Headers)
+	})
+
+	t.Run("Has invalid payload", func(t *testing.T) {
+		_, err := p.buildEmail(&bindings.InvokeRequest{Data: []byte(`{"attachments": [{}]}`)})
+		assert.Error(t, err)
+	})
+}
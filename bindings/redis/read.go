@@ -0,0 +1,199 @@
+// ------------------------------------------------------------
+// Copyright (c) Microsoft Corporation and Dapr Contributors.
+// Licensed under the MIT License.
+// ------------------------------------------------------------
+
+package redis
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/dapr/components-contrib/bindings"
+	redis "github.com/go-redis/redis/v7"
+)
+
+const (
+	// keys from component's metadata of the input binding
+
+	// channelsMetadata is a comma separated list of channels to subscribe to,
+	// the ones with glob-style wildcards are patterns
+	channelsMetadata = "channels"
+	// keyspacePatternMetadata subscribes to the keyspace notifications of the matching keys
+	keyspacePatternMetadata = "keyspacePattern"
+	// keyspaceEventsMetadata sets the notify-keyspace-events configuration of the server,
+	// for example "K$gx", as notifications are disabled by default
+	keyspaceEventsMetadata = "keyspaceEvents"
+
+	// keys from read response's metadata
+	channelResponseMetadata = "channel"
+	patternResponseMetadata = "pattern"
+	keyResponseMetadata     = "key"
+	eventResponseMetadata   = "event"
+
+	keyspaceChannelPrefix = "__keyspace@"
+)
+
+type readMetadata struct {
+	channels        []string
+	patterns        []string
+	keyspacePattern string
+	keyspaceEvents  string
+}
+
+func parseReadMetadata(props map[string]string) *readMetadata {
+	m := readMetadata{}
+
+	if val, ok := props[channelsMetadata]; ok && val != "" {
+		for _, channel := range strings.Split(val, ",") {
+			channel = strings.TrimSpace(channel)
+			if channel == "" {
+				continue
+			}
+			if strings.ContainsAny(channel, "*?[") {
+				m.patterns = append(m.patterns, channel)
+			} else {
+				m.channels = append(m.channels, channel)
+			}
+		}
+	}
+	if val, ok := props[keyspacePatternMetadata]; ok && val != "" {
+		m.keyspacePattern = val
+	}
+	if val, ok := props[keyspaceEventsMetadata]; ok && val != "" {
+		m.keyspaceEvents = val
+	}
+
+	return &m
+}
+
+// Read subscribes to the channels and the keyspace notifications, and triggers
+// handler with their messages until Close is called. The messages of channels have
+// their payload as data, and keyspace notifications have the key and the event,
+// such as set or expired, as metadata.
+//
+// Keyspace notifications are only published by the node holding the key, so with
+// Redis Cluster every master is subscribed to. The masters are the ones of the
+// cluster when Read is called, the binding must be restarted when they change.
+func (r *Redis) Read(handler func(*bindings.ReadResponse) error) error {
+	if len(r.read.channels) == 0 && len(r.read.patterns) == 0 && r.read.keyspacePattern == "" {
+		return errors.New("redis binding: missing channels or keyspacePattern to read")
+	}
+
+	if r.read.keyspaceEvents != "" {
+		err := r.forEachMaster(func(client redis.UniversalClient) error {
+			return client.ConfigSet("notify-keyspace-events", r.read.keyspaceEvents).Err()
+		})
+		if err != nil {
+			return fmt.Errorf("redis binding: error enabling keyspace notifications: %s", err)
+		}
+	}
+
+	subscriptions, err := r.subscribe()
+	for _, s := range subscriptions {
+		defer s.Close()
+	}
+	if err != nil {
+		return err
+	}
+
+	messages := make(chan *redis.Message)
+	for _, s := range subscriptions {
+		go func(ch <-chan *redis.Message) {
+			for m := range ch {
+				select {
+				case messages <- m:
+				case <-r.ctx.Done():
+					return
+				}
+			}
+		}(s.Channel())
+	}
+
+	for {
+		select {
+		case <-r.ctx.Done():
+			return nil
+		case m := <-messages:
+			if err := handler(readResponse(m)); err != nil {
+				r.logger.Errorf("redis binding: error handling message of channel %s: %s", m.Channel, err)
+			}
+		}
+	}
+}
+
+// subscribe subscribes to the channels and the patterns, and to the keyspace
+// notifications on each master. The subscriptions are returned even on error
+// so that they are closed.
+func (r *Redis) subscribe() ([]*redis.PubSub, error) {
+	var subscriptions []*redis.PubSub
+
+	if len(r.read.channels) > 0 || len(r.read.patterns) > 0 {
+		pubsub := r.client.Subscribe()
+		subscriptions = append(subscriptions, pubsub)
+		if len(r.read.channels) > 0 {
+			if err := pubsub.Subscribe(r.read.channels...); err != nil {
+				return subscriptions, fmt.Errorf("redis binding: error subscribing to %v: %s", r.read.channels, err)
+			}
+		}
+		if len(r.read.patterns) > 0 {
+			if err := pubsub.PSubscribe(r.read.patterns...); err != nil {
+				return subscriptions, fmt.Errorf("redis binding: error subscribing to %v: %s", r.read.patterns, err)
+			}
+		}
+	}
+
+	if r.read.keyspacePattern != "" {
+		pattern := fmt.Sprintf("%s%d__:%s", keyspaceChannelPrefix, r.settings.DB, r.read.keyspacePattern)
+		var lock sync.Mutex
+		err := r.forEachMaster(func(client redis.UniversalClient) error {
+			pubsub := client.Subscribe()
+			lock.Lock()
+			subscriptions = append(subscriptions, pubsub)
+			lock.Unlock()
+
+			return pubsub.PSubscribe(pattern)
+		})
+		if err != nil {
+			return subscriptions, fmt.Errorf("redis binding: error subscribing to %s: %s", pattern, err)
+		}
+	}
+
+	return subscriptions, nil
+}
+
+// forEachMaster calls fn with the client of each master of a cluster, which
+// may be concurrent, or with the client when Redis is not a cluster.
+func (r *Redis) forEachMaster(fn func(client redis.UniversalClient) error) error {
+	if cluster, ok := r.client.(*redis.ClusterClient); ok {
+		return cluster.ForEachMaster(func(master *redis.Client) error {
+			return fn(master)
+		})
+	}
+
+	return fn(r.client)
+}
+
+func readResponse(m *redis.Message) *bindings.ReadResponse {
+	metadata := map[string]string{channelResponseMetadata: m.Channel}
+	if m.Pattern != "" {
+		metadata[patternResponseMetadata] = m.Pattern
+	}
+
+	// The channel of keyspace notifications is __keyspace@<db>__:<key>
+	if strings.HasPrefix(m.Channel, keyspaceChannelPrefix) {
+		if i := strings.Index(m.Channel, "__:"); i >= 0 {
+			metadata[keyResponseMetadata] = m.Channel[i+len("__:"):]
+			metadata[eventResponseMetadata] = m.Payload
+
+			return &bindings.ReadResponse{Metadata: metadata}
+		}
+	}
+
+	return &bindings.ReadResponse{
+		Data:     []byte(m.Payload),
+		Metadata: metadata,
+	}
+}
@@ -7,40 +7,60 @@ package redis
 
 import (
 	"context"
-	"crypto/tls"
-	"errors"
+	"encoding/json"
 	"fmt"
 	"strconv"
-	"time"
 
 	"github.com/dapr/components-contrib/bindings"
+	contrib_redis "github.com/dapr/components-contrib/internal/component/redis"
+	contrib_metadata "github.com/dapr/components-contrib/metadata"
 	"github.com/dapr/dapr/pkg/logger"
 	redis "github.com/go-redis/redis/v7"
 )
 
 const (
-	host                   = "redisHost"
-	password               = "redisPassword"
-	enableTLS              = "enableTLS"
-	maxRetries             = "maxRetries"
-	maxRetryBackoff        = "maxRetryBackoff"
-	defaultBase            = 10
-	defaultBitSize         = 0
-	defaultDB              = 0
-	defaultMaxRetries      = 3
-	defaultMaxRetryBackoff = time.Second * 2
-	defaultEnableTLS       = false
+	// list of operations.
+	existsOperation  bindings.OperationKind = "exists"
+	incrOperation    bindings.OperationKind = "incr"
+	decrOperation    bindings.OperationKind = "decr"
+	expireOperation  bindings.OperationKind = "expire"
+	hsetOperation    bindings.OperationKind = "hset"
+	hgetOperation    bindings.OperationKind = "hget"
+	lpushOperation   bindings.OperationKind = "lpush"
+	rpushOperation   bindings.OperationKind = "rpush"
+	lpopOperation    bindings.OperationKind = "lpop"
+	rpopOperation    bindings.OperationKind = "rpop"
+	publishOperation bindings.OperationKind = "publish"
+
+	// keys from request's metadata
+	keyMetadata = "key"
+	// fieldMetadata is the field of a hash, hset sets all the fields of a JSON object without it
+	// and hget gets all the fields without it
+	fieldMetadata = "field"
+	// deltaMetadata is the amount of incr and decr, 1 by default
+	deltaMetadata   = "delta"
+	channelMetadata = "channel"
 )
 
-// Redis is a redis output binding
+// Redis is a redis input and output binding
 type Redis struct {
-	client *redis.Client
-	logger logger.Logger
+	client   redis.UniversalClient
+	settings *contrib_redis.Settings
+	read     *readMetadata
+	ctx      context.Context
+	cancel   context.CancelFunc
+	logger   logger.Logger
 }
 
 // NewRedis returns a new redis bindings instance
 func NewRedis(logger logger.Logger) *Redis {
-	return &Redis{logger: logger}
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &Redis{
+		ctx:    ctx,
+		cancel: cancel,
+		logger: logger,
+	}
 }
 
 // Init performs metadata parsing and connection creation
@@ -49,88 +69,202 @@ func (r *Redis) Init(meta bindings.Metadata) error {
 	if err != nil {
 		return err
 	}
+	r.settings = m
+	r.read = parseReadMetadata(meta.Properties)
 
-	opts := &redis.Options{
-		Addr:            m.host,
-		Password:        m.password,
-		DB:              defaultDB,
-		MaxRetries:      m.maxRetries,
-		MaxRetryBackoff: m.maxRetryBackoff,
-	}
-
-	/* #nosec */
-	if m.enableTLS {
-		opts.TLSConfig = &tls.Config{
-			InsecureSkipVerify: m.enableTLS,
-		}
-	}
-
-	r.client = redis.NewClient(opts)
+	r.client = contrib_redis.NewClient(m)
 	_, err = r.client.Ping().Result()
 	if err != nil {
-		return fmt.Errorf("redis binding: error connecting to redis at %s: %s", m.host, err)
+		return fmt.Errorf("redis binding: error connecting to redis at %s: %s", m.Host, err)
 	}
 
 	return err
 }
 
-func (r *Redis) parseMetadata(meta bindings.Metadata) (metadata, error) {
-	m := metadata{}
+func (r *Redis) parseMetadata(meta bindings.Metadata) (*contrib_redis.Settings, error) {
+	return contrib_redis.ParseSettings(meta.Properties, "redis binding error")
+}
 
-	if val, ok := meta.Properties[host]; ok && val != "" {
-		m.host = val
-	} else {
-		return m, errors.New("redis binding error: missing host address")
+func (r *Redis) Operations() []bindings.OperationKind {
+	return []bindings.OperationKind{
+		bindings.CreateOperation,
+		bindings.GetOperation,
+		bindings.DeleteOperation,
+		existsOperation,
+		incrOperation,
+		decrOperation,
+		expireOperation,
+		hsetOperation,
+		hgetOperation,
+		lpushOperation,
+		rpushOperation,
+		lpopOperation,
+		rpopOperation,
+		publishOperation,
 	}
+}
 
-	if val, ok := meta.Properties[password]; ok && val != "" {
-		m.password = val
+func (r *Redis) Invoke(req *bindings.InvokeRequest) (*bindings.InvokeResponse, error) {
+	if req.Operation == publishOperation {
+		return r.publish(req)
 	}
 
-	m.enableTLS = defaultEnableTLS
-	if val, ok := meta.Properties[enableTLS]; ok && val != "" {
-		tls, err := strconv.ParseBool(val)
-		if err != nil {
-			return m, fmt.Errorf("redis binding error: can't parse enableTLS field: %s", err)
-		}
-		m.enableTLS = tls
+	key, ok := req.Metadata[keyMetadata]
+	if !ok || key == "" {
+		return nil, fmt.Errorf("redis binding: missing %s on %s request metadata", keyMetadata, req.Operation)
 	}
 
-	m.maxRetries = defaultMaxRetries
-	if val, ok := meta.Properties[maxRetries]; ok && val != "" {
-		parsedVal, err := strconv.ParseInt(val, defaultBase, defaultBitSize)
-		if err != nil {
-			return m, fmt.Errorf("redis binding error: can't parse maxRetries field: %s", err)
+	switch req.Operation {
+	case bindings.CreateOperation:
+		return r.set(key, req)
+	case bindings.GetOperation:
+		return bytesResponse(r.client.Get(key).Bytes())
+	case bindings.DeleteOperation:
+		return jsonResponse(r.client.Del(key).Result())
+	case existsOperation:
+		n, err := r.client.Exists(key).Result()
+
+		return jsonResponse(n > 0, err)
+	case incrOperation, decrOperation:
+		return r.incr(key, req)
+	case expireOperation:
+		return r.expire(key, req)
+	case hsetOperation:
+		return r.hset(key, req)
+	case hgetOperation:
+		if field, ok := req.Metadata[fieldMetadata]; ok && field != "" {
+			return bytesResponse(r.client.HGet(key, field).Bytes())
 		}
-		m.maxRetries = int(parsedVal)
+
+		return jsonResponse(r.client.HGetAll(key).Result())
+	case lpushOperation:
+		return jsonResponse(r.client.LPush(key, req.Data).Result())
+	case rpushOperation:
+		return jsonResponse(r.client.RPush(key, req.Data).Result())
+	case lpopOperation:
+		return bytesResponse(r.client.LPop(key).Bytes())
+	case rpopOperation:
+		return bytesResponse(r.client.RPop(key).Bytes())
+	default:
+		return nil, fmt.Errorf("redis binding: unsupported operation %s", req.Operation)
+	}
+}
+
+// set sets the value of key, which expires after ttlInSeconds if present.
+func (r *Redis) set(key string, req *bindings.InvokeRequest) (*bindings.InvokeResponse, error) {
+	ttl, _, err := contrib_metadata.TryGetTTL(req.Metadata)
+	if err != nil {
+		return nil, fmt.Errorf("redis binding: %s", err)
+	}
+
+	if err = r.client.Set(key, req.Data, ttl).Err(); err != nil {
+		return nil, err
 	}
 
-	m.maxRetryBackoff = defaultMaxRetryBackoff
-	if val, ok := meta.Properties[maxRetryBackoff]; ok && val != "" {
-		parsedVal, err := strconv.ParseInt(val, defaultBase, defaultBitSize)
+	return nil, nil
+}
+
+// incr increments or decrements key by delta and returns the new value.
+func (r *Redis) incr(key string, req *bindings.InvokeRequest) (*bindings.InvokeResponse, error) {
+	delta := int64(1)
+	if val, ok := req.Metadata[deltaMetadata]; ok && val != "" {
+		d, err := strconv.ParseInt(val, 10, 64)
 		if err != nil {
-			return m, fmt.Errorf("redis binding error: can't parse maxRetries field: %s", err)
+			return nil, fmt.Errorf("redis binding: %s must be an integer: %s", deltaMetadata, val)
 		}
-		m.maxRetryBackoff = time.Duration(parsedVal)
+		delta = d
+	}
+	if req.Operation == decrOperation {
+		delta = -delta
 	}
 
-	return m, nil
+	return jsonResponse(r.client.IncrBy(key, delta).Result())
 }
 
-func (r *Redis) Operations() []bindings.OperationKind {
-	return []bindings.OperationKind{bindings.CreateOperation}
+// expire sets the ttlInSeconds of key and returns whether the key exists.
+func (r *Redis) expire(key string, req *bindings.InvokeRequest) (*bindings.InvokeResponse, error) {
+	ttl, ok, err := contrib_metadata.TryGetTTL(req.Metadata)
+	if err != nil {
+		return nil, fmt.Errorf("redis binding: %s", err)
+	}
+	if !ok {
+		return nil, fmt.Errorf("redis binding: missing %s on expire request metadata", contrib_metadata.TTLMetadataKey)
+	}
+
+	return jsonResponse(r.client.Expire(key, ttl).Result())
 }
 
-func (r *Redis) Invoke(req *bindings.InvokeRequest) (*bindings.InvokeResponse, error) {
-	if val, ok := req.Metadata["key"]; ok && val != "" {
-		key := val
-		_, err := r.client.DoContext(context.Background(), "SET", key, req.Data).Result()
-		if err != nil {
-			return nil, err
+// hset sets the field of a hash to the data or, without field, the fields of
+// the JSON object of the data. It returns the number of fields added.
+func (r *Redis) hset(key string, req *bindings.InvokeRequest) (*bindings.InvokeResponse, error) {
+	if field, ok := req.Metadata[fieldMetadata]; ok && field != "" {
+		return jsonResponse(r.client.HMSet(key, field, req.Data).Result())
+	}
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal(req.Data, &fields); err != nil || len(fields) == 0 {
+		return nil, fmt.Errorf("redis binding: hset requires %s metadata or a JSON object with fields", fieldMetadata)
+	}
+	values := make(map[string]interface{}, len(fields))
+	for k, v := range fields {
+		// Strings are set as they are, other values as JSON
+		if s, ok := v.(string); ok {
+			values[k] = s
+		} else {
+			b, err := json.Marshal(v)
+			if err != nil {
+				return nil, err
+			}
+			values[k] = b
 		}
+	}
+
+	return jsonResponse(r.client.HMSet(key, values).Result())
+}
+
+// publish posts the data to channel and returns the number of subscribers that received it.
+func (r *Redis) publish(req *bindings.InvokeRequest) (*bindings.InvokeResponse, error) {
+	channel, ok := req.Metadata[channelMetadata]
+	if !ok || channel == "" {
+		return nil, fmt.Errorf("redis binding: missing %s on publish request metadata", channelMetadata)
+	}
+
+	return jsonResponse(r.client.Publish(channel, req.Data).Result())
+}
+
+// bytesResponse returns a value as the data of the response, which has no data
+// when the value does not exist.
+func bytesResponse(data []byte, err error) (*bindings.InvokeResponse, error) {
+	if err == redis.Nil {
+		return &bindings.InvokeResponse{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &bindings.InvokeResponse{Data: data}, nil
+}
+
+// jsonResponse returns the result of a command, such as a number, as JSON data.
+func jsonResponse(v interface{}, err error) (*bindings.InvokeResponse, error) {
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	return &bindings.InvokeResponse{Data: b}, nil
+}
 
-		return nil, nil
+// Close stops reading and closes the connection
+func (r *Redis) Close() error {
+	r.cancel()
+	if r.client == nil {
+		return nil
 	}
 
-	return nil, errors.New("redis binding: missing key on write request metadata")
+	return r.client.Close()
 }
@@ -6,12 +6,16 @@
 package redis
 
 import (
+	"encoding/json"
 	"testing"
 	"time"
 
+	miniredis "github.com/alicebob/miniredis/v2"
 	"github.com/dapr/components-contrib/bindings"
 	"github.com/dapr/dapr/pkg/logger"
+	redis "github.com/go-redis/redis/v7"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestParseMetadata(t *testing.T) {
@@ -20,9 +24,210 @@ func TestParseMetadata(t *testing.T) {
 	r := Redis{logger: logger.NewLogger("test")}
 	redisM, err := r.parseMetadata(m)
 	assert.Nil(t, err)
-	assert.Equal(t, "host", redisM.host)
-	assert.Equal(t, "password", redisM.password)
-	assert.Equal(t, true, redisM.enableTLS)
-	assert.Equal(t, 3, redisM.maxRetries)
-	assert.Equal(t, time.Duration(10000), redisM.maxRetryBackoff)
+	assert.Equal(t, "host", redisM.Host)
+	assert.Equal(t, "password", redisM.Password)
+	assert.Equal(t, true, redisM.EnableTLS)
+	assert.Equal(t, 3, redisM.MaxRetries)
+	assert.Equal(t, time.Duration(10000), redisM.MaxRetryBackoff)
+}
+
+func setupRedis(t *testing.T, properties map[string]string) (*miniredis.Miniredis, *Redis) {
+	s, err := miniredis.Run()
+	require.NoError(t, err)
+
+	props := map[string]string{"redisHost": s.Addr(), "redisPassword": ""}
+	for k, v := range properties {
+		props[k] = v
+	}
+	r := NewRedis(logger.NewLogger("test"))
+	require.NoError(t, r.Init(bindings.Metadata{Properties: props}))
+
+	return s, r
+}
+
+func TestOperations(t *testing.T) {
+	s, r := setupRedis(t, nil)
+	defer s.Close()
+	defer r.Close()
+
+	assert.Len(t, r.Operations(), 14)
+
+	invoke := func(operation bindings.OperationKind, data string, metadata map[string]string) string {
+		resp, err := r.Invoke(&bindings.InvokeRequest{Operation: operation, Data: []byte(data), Metadata: metadata})
+		require.NoError(t, err, operation)
+		if resp == nil {
+			return ""
+		}
+
+		return string(resp.Data)
+	}
+
+	t.Run("create, get, exists, expire and delete", func(t *testing.T) {
+		key := map[string]string{"key": "k"}
+		invoke(bindings.CreateOperation, "value", map[string]string{"key": "k", "ttlInSeconds": "100"})
+		assert.Equal(t, 100*time.Second, s.TTL("k"))
+		assert.Equal(t, "value", invoke(bindings.GetOperation, "", key))
+		assert.Equal(t, "true", invoke(existsOperation, "", key))
+		assert.Equal(t, "true", invoke(expireOperation, "", map[string]string{"key": "k", "ttlInSeconds": "10"}))
+		assert.Equal(t, 10*time.Second, s.TTL("k"))
+		assert.Equal(t, "1", invoke(bindings.DeleteOperation, "", key))
+		assert.Equal(t, "false", invoke(existsOperation, "", key))
+		assert.Equal(t, "", invoke(bindings.GetOperation, "", key))
+		assert.Equal(t, "false", invoke(expireOperation, "", map[string]string{"key": "k", "ttlInSeconds": "10"}))
+	})
+
+	t.Run("incr and decr", func(t *testing.T) {
+		assert.Equal(t, "1", invoke(incrOperation, "", map[string]string{"key": "counter"}))
+		assert.Equal(t, "11", invoke(incrOperation, "", map[string]string{"key": "counter", "delta": "10"}))
+		assert.Equal(t, "8", invoke(decrOperation, "", map[string]string{"key": "counter", "delta": "3"}))
+	})
+
+	t.Run("hset and hget", func(t *testing.T) {
+		assert.Equal(t, "1", invoke(hsetOperation, "v1", map[string]string{"key": "h", "field": "f1"}))
+		assert.Equal(t, "2", invoke(hsetOperation, `{"f2": "v2", "f3": {"a": 1}}`, map[string]string{"key": "h"}))
+		assert.Equal(t, "v1", invoke(hgetOperation, "", map[string]string{"key": "h", "field": "f1"}))
+		assert.Equal(t, "", invoke(hgetOperation, "", map[string]string{"key": "h", "field": "missing"}))
+
+		var fields map[string]string
+		require.NoError(t, json.Unmarshal([]byte(invoke(hgetOperation, "", map[string]string{"key": "h"})), &fields))
+		assert.Equal(t, map[string]string{"f1": "v1", "f2": "v2", "f3": `{"a":1}`}, fields)
+	})
+
+	t.Run("push and pop", func(t *testing.T) {
+		key := map[string]string{"key": "l"}
+		assert.Equal(t, "1", invoke(lpushOperation, "b", key))
+		assert.Equal(t, "2", invoke(lpushOperation, "a", key))
+		assert.Equal(t, "3", invoke(rpushOperation, "c", key))
+		assert.Equal(t, "a", invoke(lpopOperation, "", key))
+		assert.Equal(t, "c", invoke(rpopOperation, "", key))
+		assert.Equal(t, "b", invoke(rpopOperation, "", key))
+		assert.Equal(t, "", invoke(lpopOperation, "", key))
+	})
+
+	t.Run("publish", func(t *testing.T) {
+		assert.Equal(t, "0", invoke(publishOperation, "message", map[string]string{"channel": "c"}))
+	})
+
+	t.Run("invalid requests", func(t *testing.T) {
+		for _, req := range []*bindings.InvokeRequest{
+			{Operation: bindings.GetOperation},
+			{Operation: publishOperation, Data: []byte("message")},
+			{Operation: expireOperation, Metadata: map[string]string{"key": "k"}},
+			{Operation: incrOperation, Metadata: map[string]string{"key": "k", "delta": "one"}},
+			{Operation: hsetOperation, Data: []byte("value"), Metadata: map[string]string{"key": "k"}},
+			{Operation: "unknown", Metadata: map[string]string{"key": "k"}},
+		} {
+			_, err := r.Invoke(req)
+			assert.Error(t, err, req.Operation)
+		}
+	})
+}
+
+func TestParseReadMetadata(t *testing.T) {
+	m := parseReadMetadata(map[string]string{"channels": "orders, events.*,,news[12]", "keyspacePattern": "user:*", "keyspaceEvents": "K$"})
+	assert.Equal(t, []string{"orders"}, m.channels)
+	assert.Equal(t, []string{"events.*", "news[12]"}, m.patterns)
+	assert.Equal(t, "user:*", m.keyspacePattern)
+	assert.Equal(t, "K$", m.keyspaceEvents)
+}
+
+func TestRead(t *testing.T) {
+	s, r := setupRedis(t, map[string]string{"channels": "orders,events.*"})
+	defer s.Close()
+
+	events := make(chan *bindings.ReadResponse, 10)
+	done := make(chan error)
+	go func() {
+		done <- r.Read(func(resp *bindings.ReadResponse) error {
+			events <- resp
+
+			return nil
+		})
+	}()
+
+	// Wait for the subscriptions before publishing
+	assert.Eventually(t, func() bool {
+		return s.PubSubNumPat() == 1 && s.PubSubNumSub("orders")["orders"] == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	s.Publish("orders", "order 1")
+	s.Publish("events.created", "event 1")
+	// The messages of the channel and the pattern are not ordered
+	received := map[string]bindings.ReadResponse{}
+	for len(received) < 2 {
+		select {
+		case resp := <-events:
+			received[resp.Metadata["channel"]] = *resp
+		case <-time.After(5 * time.Second):
+			require.Fail(t, "timeout waiting for messages")
+		}
+	}
+	assert.Equal(t, map[string]bindings.ReadResponse{
+		"orders":         {Data: []byte("order 1"), Metadata: map[string]string{"channel": "orders"}},
+		"events.created": {Data: []byte("event 1"), Metadata: map[string]string{"channel": "events.created", "pattern": "events.*"}},
+	}, received)
+
+	require.NoError(t, r.Close())
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		assert.Fail(t, "timeout waiting for Read to return")
+	}
+}
+
+func TestReadKeyspaceNotifications(t *testing.T) {
+	s, r := setupRedis(t, map[string]string{"channels": "events.*", "keyspacePattern": "user:*"})
+	defer s.Close()
+
+	events := make(chan *bindings.ReadResponse, 10)
+	done := make(chan error)
+	go func() {
+		done <- r.Read(func(resp *bindings.ReadResponse) error {
+			events <- resp
+
+			return nil
+		})
+	}()
+
+	// The keyspace notifications are subscribed to on their own connection
+	assert.Eventually(t, func() bool {
+		return s.PubSubNumPat() == 2
+	}, 5*time.Second, 10*time.Millisecond)
+
+	s.Publish("__keyspace@0__:user:1", "set")
+	select {
+	case resp := <-events:
+		assert.Equal(t, "user:1", resp.Metadata["key"])
+		assert.Equal(t, "set", resp.Metadata["event"])
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "timeout waiting for the notification")
+	}
+
+	require.NoError(t, r.Close())
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		assert.Fail(t, "timeout waiting for Read to return")
+	}
+}
+
+func TestReadWithoutChannels(t *testing.T) {
+	s, r := setupRedis(t, nil)
+	defer s.Close()
+	defer r.Close()
+
+	assert.Error(t, r.Read(func(*bindings.ReadResponse) error { return nil }))
+}
+
+func TestReadResponse(t *testing.T) {
+	resp := readResponse(&redis.Message{Channel: "__keyspace@0__:user:1", Pattern: "__keyspace@0__:user:*", Payload: "expired"})
+	assert.Nil(t, resp.Data)
+	assert.Equal(t, map[string]string{
+		"channel": "__keyspace@0__:user:1",
+		"pattern": "__keyspace@0__:user:*",
+		"key":     "user:1",
+		"event":   "expired",
+	}, resp.Metadata)
 }
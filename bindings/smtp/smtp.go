@@ -9,9 +9,11 @@ import (
 	"crypto/tls"
 	"errors"
 	"fmt"
+	"io"
 	"strconv"
 
 	"github.com/dapr/components-contrib/bindings"
+	contrib_email "github.com/dapr/components-contrib/internal/component/email"
 	"github.com/dapr/dapr/pkg/logger"
 	"gopkg.in/gomail.v2"
 )
@@ -19,6 +21,7 @@ import (
 // Mailer allows sending of emails using the Simple Mail Transfer Protocol
 type Mailer struct {
 	metadata Metadata
+	builder  *contrib_email.Builder
 	logger   logger.Logger
 }
 
@@ -50,6 +53,11 @@ func (s *Mailer) Init(metadata bindings.Metadata) error {
 	}
 	s.metadata = meta
 
+	s.builder, err = contrib_email.NewBuilder(metadata.Properties, s.logger)
+	if err != nil {
+		return fmt.Errorf("SMTP binding error: %s", err)
+	}
+
 	return nil
 }
 
@@ -60,11 +68,8 @@ func (s *Mailer) Operations() []bindings.OperationKind {
 
 // Invoke sends an email message
 func (s *Mailer) Invoke(req *bindings.InvokeRequest) (*bindings.InvokeResponse, error) {
-	// Determine host and port
-	port, err := strconv.Atoi(s.metadata.Port)
-	if err != nil {
-		s.logger.Fatal("SMTP binding error: Unable to parse specified port to integer value")
-	}
+	// Determine host and port, which is validated by Init
+	port, _ := strconv.Atoi(s.metadata.Port)
 	s.logger.Debugf("SMTP binding: using server %v:%v", s.metadata.Host, port)
 
 	// Merge config metadata with request metadata
@@ -80,14 +85,11 @@ func (s *Mailer) Invoke(req *bindings.InvokeRequest) (*bindings.InvokeResponse,
 	}
 
 	// Compose message
-	msg := gomail.NewMessage()
-	msg.SetHeader("From", metadata.EmailFrom)
-	msg.SetHeader("To", metadata.EmailTo)
-	msg.SetHeader("CC", metadata.EmailCC)
-	msg.SetHeader("BCC", metadata.EmailBCC)
-	msg.SetHeader("Subject", metadata.Subject)
-	body, _ := strconv.Unquote(string(req.Data))
-	msg.SetBody("text/html", body)
+	email, err := s.builder.Build(req)
+	if err != nil {
+		return nil, fmt.Errorf("SMTP binding error: %s", err)
+	}
+	msg := composeMessage(metadata, email)
 
 	// Send message
 	dialer := gomail.NewDialer(metadata.Host, port, metadata.User, metadata.Password)
@@ -96,7 +98,7 @@ func (s *Mailer) Invoke(req *bindings.InvokeRequest) (*bindings.InvokeResponse,
 		dialer.TLSConfig = &tls.Config{InsecureSkipVerify: true}
 	}
 	if err := dialer.DialAndSend(msg); err != nil {
-		s.logger.Fatal(err)
+		return nil, fmt.Errorf("SMTP binding error: failed to send email: %s", err)
 	}
 
 	// Log success
@@ -105,6 +107,45 @@ func (s *Mailer) Invoke(req *bindings.InvokeRequest) (*bindings.InvokeResponse,
 	return nil, nil
 }
 
+// Helper to compose the message with the HTML and plain text alternatives, and the attachments
+func composeMessage(metadata Metadata, email *contrib_email.Message) *gomail.Message {
+	msg := gomail.NewMessage()
+	msg.SetHeader("From", metadata.EmailFrom)
+	msg.SetHeader("To", metadata.EmailTo)
+	msg.SetHeader("CC", metadata.EmailCC)
+	msg.SetHeader("BCC", metadata.EmailBCC)
+	msg.SetHeader("Subject", metadata.Subject)
+	if email.ReplyTo != "" {
+		msg.SetHeader("Reply-To", email.ReplyTo)
+	}
+	for k, v := range contrib_email.PriorityHeaders(email.Priority) {
+		msg.SetHeader(k, v)
+	}
+
+	switch {
+	case email.TextBody != "" && email.HTMLBody != "":
+		msg.SetBody("text/plain", email.TextBody)
+		msg.AddAlternative("text/html", email.HTMLBody)
+	case email.TextBody != "":
+		msg.SetBody("text/plain", email.TextBody)
+	default:
+		msg.SetBody("text/html", email.HTMLBody)
+	}
+
+	for _, a := range email.Attachments {
+		content := a.Content
+		msg.Attach(a.Filename,
+			gomail.SetHeader(map[string][]string{"Content-Type": {a.ContentType}}),
+			gomail.SetCopyFunc(func(w io.Writer) error {
+				_, err := w.Write(content)
+
+				return err
+			}))
+	}
+
+	return msg
+}
+
 // Helper to parse metadata
 func (s *Mailer) parseMetadata(meta bindings.Metadata) (Metadata, error) {
 	smtpMeta := Metadata{}
@@ -114,6 +155,9 @@ func (s *Mailer) parseMetadata(meta bindings.Metadata) (Metadata, error) {
 		meta.Properties["user"] == "" || meta.Properties["password"] == "" {
 		return smtpMeta, errors.New("SMTP binding error: host, port, user and password fields are required in metadata")
 	}
+	if _, err := strconv.Atoi(meta.Properties["port"]); err != nil {
+		return smtpMeta, errors.New("SMTP binding error: Unable to parse specified port to integer value")
+	}
 	smtpMeta.Host = meta.Properties["host"]
 	smtpMeta.Port = meta.Properties["port"]
 	smtpMeta.User = meta.Properties["user"]
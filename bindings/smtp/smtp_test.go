@@ -6,11 +6,14 @@
 package smtp
 
 import (
+	"bytes"
 	"testing"
 
 	"github.com/dapr/components-contrib/bindings"
+	contrib_email "github.com/dapr/components-contrib/internal/component/email"
 	"github.com/dapr/dapr/pkg/logger"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestParseMetadata(t *testing.T) {
@@ -43,6 +46,18 @@ func TestParseMetadata(t *testing.T) {
 		assert.Equal(t, "bcc@dapr.io", smtpMeta.EmailBCC)
 		assert.Equal(t, "Test email", smtpMeta.Subject)
 	})
+
+	t.Run("Has invalid port", func(t *testing.T) {
+		m := bindings.Metadata{}
+		m.Properties = map[string]string{
+			"host":     "mailserver.dapr.io",
+			"port":     "smtp",
+			"user":     "user@dapr.io",
+			"password": "P@$$w0rd!"}
+		r := Mailer{logger: logger}
+		_, err := r.parseMetadata(m)
+		assert.Error(t, err)
+	})
 }
 
 func TestMergeWithRequestMetadata(t *testing.T) {
@@ -81,3 +96,41 @@ func TestMergeWithRequestMetadata(t *testing.T) {
 		assert.Equal(t, "req-Test email", mergedMeta.Subject)
 	})
 }
+
+func TestComposeMessage(t *testing.T) {
+	meta := Metadata{
+		EmailFrom: "from@dapr.io",
+		EmailTo:   "to@dapr.io",
+		Subject:   "Test email"}
+
+	t.Run("Has html body", func(t *testing.T) {
+		var buf bytes.Buffer
+		_, err := composeMessage(meta, &contrib_email.Message{HTMLBody: "<b>Hello</b>"}).WriteTo(&buf)
+		require.NoError(t, err)
+		assert.Contains(t, buf.String(), "Content-Type: text/html; charset=UTF-8")
+		assert.Contains(t, buf.String(), "<b>Hello</b>")
+		assert.NotContains(t, buf.String(), "X-Priority")
+	})
+
+	t.Run("Has alternatives, attachments, reply-to and priority", func(t *testing.T) {
+		var buf bytes.Buffer
+		_, err := composeMessage(meta, &contrib_email.Message{
+			HTMLBody:    "<b>Hello</b>",
+			TextBody:    "Hello",
+			Attachments: []*contrib_email.Attachment{{Filename: "a.txt", ContentType: "text/plain", Content: []byte("attached")}},
+			ReplyTo:     "reply@dapr.io",
+			Priority:    contrib_email.PriorityHigh,
+		}).WriteTo(&buf)
+		require.NoError(t, err)
+		email := buf.String()
+		assert.Contains(t, email, "Reply-To: reply@dapr.io")
+		assert.Contains(t, email, "X-Priority: 1")
+		assert.Contains(t, email, "Importance: high")
+		assert.Contains(t, email, "Content-Type: multipart/mixed")
+		assert.Contains(t, email, "Content-Type: multipart/alternative")
+		assert.Contains(t, email, "Content-Type: text/plain; charset=UTF-8")
+		assert.Contains(t, email, "Content-Disposition: attachment; filename=\"a.txt\"")
+		// The attachment is base64 encoded
+		assert.Contains(t, email, "YXR0YWNoZWQ=")
+	})
+}
@@ -0,0 +1,197 @@
+// ------------------------------------------------------------
+// Copyright (c) Microsoft Corporation and Dapr Contributors.
+// Licensed under the MIT License.
+// ------------------------------------------------------------
+
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dapr/components-contrib/bindings"
+	contrib_sql "github.com/dapr/components-contrib/internal/component/sql"
+	"github.com/dapr/dapr/pkg/logger"
+	"github.com/pkg/errors"
+	"modernc.org/sqlite"
+)
+
+const (
+	// list of operations.
+	execOperation        = contrib_sql.ExecOperation
+	queryOperation       = contrib_sql.QueryOperation
+	transactionOperation = contrib_sql.TransactionOperation
+	closeOperation       = contrib_sql.CloseOperation
+
+	// connectionURLKey is the path of the database file, a `file:` URI or
+	// `:memory:` for an in-memory database
+	connectionURLKey = "url"
+	// readOnlyKey rejects the statements that modify the database
+	readOnlyKey = "readOnly"
+	// busyTimeoutKey is how long a statement waits for the lock of the
+	// database held by another connection or process
+	busyTimeoutKey = "busyTimeout"
+
+	// keys from request's metadata
+	commandSQLKey = contrib_sql.CommandSQLKey
+	paramsKey     = contrib_sql.ParamsKey
+
+	// keys from response's metadata
+	respRowsAffectedKey = contrib_sql.RespRowsAffectedKey
+
+	defaultBusyTimeout = 5 * time.Second
+	memoryDatabase     = ":memory:"
+)
+
+// SQLite represents SQLite output bindings
+type SQLite struct {
+	db     *sql.DB
+	logger logger.Logger
+}
+
+type sqliteMetadata struct {
+	url         string
+	readOnly    bool
+	busyTimeout time.Duration
+}
+
+var _ = bindings.OutputBinding(&SQLite{})
+
+// NewSQLite returns a new SQLite output binding
+func NewSQLite(logger logger.Logger) *SQLite {
+	return &SQLite{logger: logger}
+}
+
+// Init initializes the SQLite binding
+func (s *SQLite) Init(metadata bindings.Metadata) error {
+	m, err := parseMetadata(metadata.Properties)
+	if err != nil {
+		return err
+	}
+
+	pragmas := []string{fmt.Sprintf("PRAGMA busy_timeout = %d", m.busyTimeout.Milliseconds())}
+	if m.readOnly {
+		pragmas = append(pragmas, "PRAGMA query_only = ON")
+	}
+
+	db := sql.OpenDB(&connector{dsn: m.url, pragmas: pragmas})
+	if isInMemory(m.url) {
+		// Each connection has its own in-memory database, so a single
+		// connection is kept open for the lifetime of the binding
+		db.SetMaxOpenConns(1)
+		db.SetMaxIdleConns(1)
+		db.SetConnMaxLifetime(0)
+	}
+
+	if err = db.Ping(); err != nil {
+		_ = db.Close()
+
+		return errors.Wrap(err, "unable to open the DB")
+	}
+
+	s.db = db
+
+	return nil
+}
+
+// Invoke handles all invoke operations
+func (s *SQLite) Invoke(req *bindings.InvokeRequest) (*bindings.InvokeResponse, error) {
+	if req == nil {
+		return nil, errors.Errorf("invoke request required")
+	}
+
+	if req.Operation == closeOperation {
+		return nil, s.db.Close()
+	}
+
+	return contrib_sql.Invoke(context.Background(), s.db, req, s.logger)
+}
+
+// Operations returns list of operations supported by SQLite binding
+func (s *SQLite) Operations() []bindings.OperationKind {
+	return contrib_sql.Operations()
+}
+
+// Close will close the DB
+func (s *SQLite) Close() error {
+	if s.db != nil {
+		return s.db.Close()
+	}
+
+	return nil
+}
+
+func parseMetadata(props map[string]string) (sqliteMetadata, error) {
+	m := sqliteMetadata{busyTimeout: defaultBusyTimeout}
+
+	m.url = props[connectionURLKey]
+	if m.url == "" {
+		return m, errors.Errorf("required metadata not set: %s", connectionURLKey)
+	}
+
+	if val, ok := props[readOnlyKey]; ok && val != "" {
+		readOnly, err := strconv.ParseBool(val)
+		if err != nil {
+			return m, errors.Wrapf(err, "error converting %s:%s to bool", readOnlyKey, val)
+		}
+		m.readOnly = readOnly
+	}
+
+	if val, ok := props[busyTimeoutKey]; ok && val != "" {
+		busyTimeout, err := time.ParseDuration(val)
+		if err != nil {
+			return m, errors.Wrapf(err, "error converting %s:%s to time duration", busyTimeoutKey, val)
+		}
+		m.busyTimeout = busyTimeout
+	}
+
+	return m, nil
+}
+
+// isInMemory returns true for the names of in-memory databases, which are
+// `:memory:` or URIs such as `file::memory:` and `file:name?mode=memory`.
+func isInMemory(url string) bool {
+	return url == memoryDatabase ||
+		strings.HasPrefix(url, "file:"+memoryDatabase) ||
+		strings.Contains(url, "mode=memory")
+}
+
+// connector opens connections to the database and applies the settings of
+// the binding to each of them, as SQLite pragmas are set per connection.
+type connector struct {
+	dsn     string
+	pragmas []string
+}
+
+func (c *connector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Driver().Open(c.dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	execer, ok := conn.(driver.ExecerContext)
+	if !ok {
+		_ = conn.Close()
+
+		return nil, errors.New("sqlite driver does not support exec")
+	}
+
+	for _, pragma := range c.pragmas {
+		if _, err = execer.ExecContext(ctx, pragma, nil); err != nil {
+			_ = conn.Close()
+
+			return nil, errors.Wrapf(err, "error executing %s", pragma)
+		}
+	}
+
+	return conn, nil
+}
+
+func (c *connector) Driver() driver.Driver {
+	return &sqlite.Driver{}
+}
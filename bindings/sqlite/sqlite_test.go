@@ -0,0 +1,177 @@
+// ------------------------------------------------------------
+// Copyright (c) Microsoft Corporation and Dapr Contributors.
+// Licensed under the MIT License.
+// ------------------------------------------------------------
+
+package sqlite
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dapr/components-contrib/bindings"
+	"github.com/dapr/dapr/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testCreateTable = `CREATE TABLE foo (
+		id INTEGER NOT NULL PRIMARY KEY,
+		v1 TEXT NOT NULL,
+		b  BOOLEAN,
+		ts DATETIME)`
+	testInsert = "INSERT INTO foo (id, v1, b, ts) VALUES (?, ?, ?, ?)"
+	testSelect = "SELECT * FROM foo WHERE id < ? ORDER BY id"
+)
+
+func TestParseMetadata(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		m, err := parseMetadata(map[string]string{connectionURLKey: "test.db"})
+		require.NoError(t, err)
+		assert.Equal(t, "test.db", m.url)
+		assert.False(t, m.readOnly)
+		assert.Equal(t, defaultBusyTimeout, m.busyTimeout)
+	})
+
+	t.Run("all values", func(t *testing.T) {
+		m, err := parseMetadata(map[string]string{connectionURLKey: memoryDatabase, readOnlyKey: "true", busyTimeoutKey: "1s"})
+		require.NoError(t, err)
+		assert.True(t, m.readOnly)
+		assert.Equal(t, time.Second, m.busyTimeout)
+	})
+
+	t.Run("invalid values", func(t *testing.T) {
+		_, err := parseMetadata(map[string]string{})
+		assert.Error(t, err)
+		_, err = parseMetadata(map[string]string{connectionURLKey: "test.db", readOnlyKey: "maybe"})
+		assert.Error(t, err)
+		_, err = parseMetadata(map[string]string{connectionURLKey: "test.db", busyTimeoutKey: "5"})
+		assert.Error(t, err)
+	})
+}
+
+func TestIsInMemory(t *testing.T) {
+	assert.True(t, isInMemory(":memory:"))
+	assert.True(t, isInMemory("file::memory:?cache=shared"))
+	assert.True(t, isInMemory("file:test?mode=memory&cache=shared"))
+	assert.False(t, isInMemory("test.db"))
+	assert.False(t, isInMemory("file:test.db?cache=shared"))
+}
+
+func TestOperations(t *testing.T) {
+	b := NewSQLite(nil)
+	l := b.Operations()
+	assert.Equal(t, 4, len(l))
+	assert.Contains(t, l, execOperation)
+	assert.Contains(t, l, queryOperation)
+	assert.Contains(t, l, transactionOperation)
+	assert.Contains(t, l, closeOperation)
+}
+
+func TestInMemoryDatabase(t *testing.T) {
+	b := NewSQLite(logger.NewLogger("test"))
+	require.NoError(t, b.Init(bindings.Metadata{Properties: map[string]string{connectionURLKey: memoryDatabase}}))
+	defer b.Close()
+
+	t.Run("exec", func(t *testing.T) {
+		res, err := b.Invoke(&bindings.InvokeRequest{
+			Operation: execOperation,
+			Metadata:  map[string]string{commandSQLKey: testCreateTable},
+		})
+		require.NoError(t, err)
+		assert.NotEmpty(t, res.Metadata["duration"])
+		assert.NotEmpty(t, res.Metadata["start-time"])
+
+		for i := 0; i < 3; i++ {
+			res, err = b.Invoke(&bindings.InvokeRequest{
+				Operation: execOperation,
+				Metadata: map[string]string{
+					commandSQLKey: testInsert,
+					paramsKey:     fmt.Sprintf(`[%d, "it's", true, "2021-01-22 10:30:00"]`, i),
+				},
+			})
+			require.NoError(t, err)
+			assert.Equal(t, "1", res.Metadata[respRowsAffectedKey])
+		}
+	})
+
+	t.Run("query", func(t *testing.T) {
+		res, err := b.Invoke(&bindings.InvokeRequest{
+			Operation: queryOperation,
+			Metadata:  map[string]string{commandSQLKey: testSelect, paramsKey: "[2]"},
+		})
+		require.NoError(t, err)
+
+		var rows []map[string]interface{}
+		require.NoError(t, json.Unmarshal(res.Data, &rows))
+		require.Equal(t, 2, len(rows))
+		assert.Equal(t, float64(1), rows[1]["id"])
+		assert.Equal(t, "it's", rows[1]["v1"])
+		assert.Equal(t, true, rows[1]["b"])
+		ts, err := time.Parse(time.RFC3339, rows[1]["ts"].(string))
+		require.NoError(t, err)
+		assert.Equal(t, 2021, ts.Year())
+	})
+
+	t.Run("transaction is rolled back", func(t *testing.T) {
+		_, err := b.Invoke(&bindings.InvokeRequest{
+			Operation: transactionOperation,
+			Data: []byte(`[
+				{"sql": "DELETE FROM foo WHERE id = ?", "params": [0]},
+				{"sql": "INSERT INTO foo (id, v1) VALUES (?, ?)", "params": [1, "duplicate"]}
+			]`),
+		})
+		assert.Error(t, err)
+
+		res, err := b.Invoke(&bindings.InvokeRequest{
+			Operation: queryOperation,
+			Metadata:  map[string]string{commandSQLKey: "SELECT count(*) AS n FROM foo"},
+		})
+		require.NoError(t, err)
+		assert.JSONEq(t, `[{"n":3}]`, string(res.Data))
+	})
+
+	t.Run("close", func(t *testing.T) {
+		_, err := b.Invoke(&bindings.InvokeRequest{Operation: closeOperation})
+		assert.NoError(t, err)
+	})
+}
+
+func TestReadOnly(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sqlite")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	url := filepath.Join(dir, "test.db")
+
+	rw := NewSQLite(logger.NewLogger("test"))
+	require.NoError(t, rw.Init(bindings.Metadata{Properties: map[string]string{connectionURLKey: url}}))
+	defer rw.Close()
+	_, err = rw.Invoke(&bindings.InvokeRequest{
+		Operation: execOperation,
+		Metadata:  map[string]string{commandSQLKey: testCreateTable},
+	})
+	require.NoError(t, err)
+
+	ro := NewSQLite(logger.NewLogger("test"))
+	require.NoError(t, ro.Init(bindings.Metadata{Properties: map[string]string{connectionURLKey: url, readOnlyKey: "true"}}))
+	defer ro.Close()
+
+	_, err = ro.Invoke(&bindings.InvokeRequest{
+		Operation: execOperation,
+		Metadata:  map[string]string{commandSQLKey: testInsert, paramsKey: `[1, "test", false, null]`},
+	})
+	assert.Error(t, err)
+
+	res, err := ro.Invoke(&bindings.InvokeRequest{
+		Operation: queryOperation,
+		Metadata:  map[string]string{commandSQLKey: testSelect, paramsKey: "[10]"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "[]", string(res.Data))
+}
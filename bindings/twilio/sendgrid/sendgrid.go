@@ -6,13 +6,14 @@
 package sendgrid
 
 import (
+	b64 "encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"strconv"
 	"strings"
 
 	"github.com/dapr/components-contrib/bindings"
+	contrib_email "github.com/dapr/components-contrib/internal/component/email"
 	"github.com/dapr/dapr/pkg/logger"
 	"github.com/sendgrid/sendgrid-go"
 	"github.com/sendgrid/sendgrid-go/helpers/mail"
@@ -21,6 +22,7 @@ import (
 // SendGrid allows sending of emails using the 3rd party SendGrid service
 type SendGrid struct {
 	metadata sendGridMetadata
+	builder  *contrib_email.Builder
 	logger   logger.Logger
 }
 
@@ -77,9 +79,14 @@ func (sg *SendGrid) Init(metadata bindings.Metadata) error {
 		return err
 	}
 
-	// Um, yeah that's about it!
 	sg.metadata = meta
 
+	// The builder renders the templates and decodes the attachments of requests
+	sg.builder, err = contrib_email.NewBuilder(metadata.Properties, sg.logger)
+	if err != nil {
+		return fmt.Errorf("SendGrid binding error: %s", err)
+	}
+
 	return nil
 }
 
@@ -89,6 +96,34 @@ func (sg *SendGrid) Operations() []bindings.OperationKind {
 
 // Write does the work of sending message to SendGrid API
 func (sg *SendGrid) Invoke(req *bindings.InvokeRequest) (*bindings.InvokeResponse, error) {
+	email, err := sg.buildEmail(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Send the email
+	client := sendgrid.NewSendClient(sg.metadata.APIKey)
+	resp, err := client.Send(email)
+	if err != nil {
+		return nil, fmt.Errorf("error from SendGrid, sending email failed: %+v", err)
+	}
+
+	// Check SendGrid response is OK
+	if !(resp.StatusCode >= 200 && resp.StatusCode < 300) {
+		// Extract the underlying error message(s) returned from SendGrid REST API
+		sendGridError := sendGridRestError{}
+		json.NewDecoder(strings.NewReader(resp.Body)).Decode(&sendGridError)
+		// Pass it back to the caller, so they have some idea what went wrong
+		return nil, fmt.Errorf("error from SendGrid, sending email failed: %d %+v", resp.StatusCode, sendGridError)
+	}
+
+	sg.logger.Info("sent email with SendGrid")
+
+	return nil, nil
+}
+
+// buildEmail builds the email of a request, with its bodies, attachments and headers
+func (sg *SendGrid) buildEmail(req *bindings.InvokeRequest) (*mail.SGMailV3, error) {
 	// We allow two possible sources of the properties we need,
 	// the component metadata or request metadata, request takes priority if present
 
@@ -146,13 +181,35 @@ func (sg *SendGrid) Invoke(req *bindings.InvokeRequest) (*bindings.InvokeRespons
 		bccAddress = mail.NewEmail("", req.Metadata["emailBcc"])
 	}
 
-	// Email body is held in req.Data, after we tidy it up a bit
-	emailBody, _ := strconv.Unquote(string(req.Data))
+	// Email bodies and attachments are held in req.Data
+	msg, err := sg.builder.Build(req)
+	if err != nil {
+		return nil, fmt.Errorf("error SendGrid email: %s", err)
+	}
 
-	// Construct email message
+	// Construct email message, the plain text content must come first
 	email := mail.NewV3Mail()
 	email.SetFrom(fromAddress)
-	email.AddContent(mail.NewContent("text/html", emailBody))
+	if msg.TextBody != "" {
+		email.AddContent(mail.NewContent("text/plain", msg.TextBody))
+	}
+	if msg.HTMLBody != "" || msg.TextBody == "" {
+		email.AddContent(mail.NewContent("text/html", msg.HTMLBody))
+	}
+	for _, a := range msg.Attachments {
+		attachment := mail.NewAttachment()
+		attachment.SetContent(b64.StdEncoding.EncodeToString(a.Content))
+		attachment.SetType(a.ContentType)
+		attachment.SetFilename(a.Filename)
+		attachment.SetDisposition("attachment")
+		email.AddAttachment(attachment)
+	}
+	if msg.ReplyTo != "" {
+		email.SetReplyTo(mail.NewEmail("", msg.ReplyTo))
+	}
+	for k, v := range contrib_email.PriorityHeaders(msg.Priority) {
+		email.SetHeader(k, v)
+	}
 
 	// Add other fields to email
 	personalization := mail.NewPersonalization()
@@ -166,23 +223,5 @@ func (sg *SendGrid) Invoke(req *bindings.InvokeRequest) (*bindings.InvokeRespons
 	}
 	email.AddPersonalizations(personalization)
 
-	// Send the email
-	client := sendgrid.NewSendClient(sg.metadata.APIKey)
-	resp, err := client.Send(email)
-	if err != nil {
-		return nil, fmt.Errorf("error from SendGrid, sending email failed: %+v", err)
-	}
-
-	// Check SendGrid response is OK
-	if !(resp.StatusCode >= 200 && resp.StatusCode < 300) {
-		// Extract the underlying error message(s) returned from SendGrid REST API
-		sendGridError := sendGridRestError{}
-		json.NewDecoder(strings.NewReader(resp.Body)).Decode(&sendGridError)
-		// Pass it back to the caller, so they have some idea what went wrong
-		return nil, fmt.Errorf("error from SendGrid, sending email failed: %d %+v", resp.StatusCode, sendGridError)
-	}
-
-	sg.logger.Info("sent email with SendGrid")
-
-	return nil, nil
+	return email, nil
 }
@@ -11,6 +11,7 @@ import (
 	"github.com/dapr/components-contrib/bindings"
 	"github.com/dapr/dapr/pkg/logger"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestParseMetadata(t *testing.T) {
@@ -28,3 +29,41 @@ func TestParseMetadata(t *testing.T) {
 		assert.Equal(t, "hello", sgMeta.Subject)
 	})
 }
+
+func TestBuildEmail(t *testing.T) {
+	sg := NewSendGrid(logger.NewLogger("test"))
+	require.NoError(t, sg.Init(bindings.Metadata{Properties: map[string]string{
+		"apiKey": "123", "emailFrom": "test1@example.net", "emailTo": "test2@example.net", "subject": "hello", "replyTo": "reply@example.net",
+	}}))
+
+	t.Run("Has html body", func(t *testing.T) {
+		email, err := sg.buildEmail(&bindings.InvokeRequest{Data: []byte(`"<b>Hello</b>"`)})
+		require.NoError(t, err)
+		require.Len(t, email.Content, 1)
+		assert.Equal(t, "text/html", email.Content[0].Type)
+		assert.Equal(t, "<b>Hello</b>", email.Content[0].Value)
+		assert.Equal(t, "reply@example.net", email.ReplyTo.Address)
+		assert.Equal(t, "hello", email.Personalizations[0].Subject)
+	})
+
+	t.Run("Has alternatives, attachments and priority", func(t *testing.T) {
+		email, err := sg.buildEmail(&bindings.InvokeRequest{
+			Data:     []byte(`{"html": "<b>Hello</b>", "text": "Hello", "attachments": [{"filename": "a.txt", "contentType": "text/plain", "content": "aGVsbG8="}]}`),
+			Metadata: map[string]string{"priority": "high"},
+		})
+		require.NoError(t, err)
+		require.Len(t, email.Content, 2)
+		assert.Equal(t, "text/plain", email.Content[0].Type)
+		assert.Equal(t, "text/html", email.Content[1].Type)
+		require.Len(t, email.Attachments, 1)
+		assert.Equal(t, "a.txt", email.Attachments[0].Filename)
+		assert.Equal(t, "text/plain", email.Attachments[0].Type)
+		assert.Equal(t, "aGVsbG8=", email.Attachments[0].Content)
+		assert.Equal(t, "1", email.Headers["X-Priority"])
+	})
+
+	t.Run("Has invalid payload", func(t *testing.T) {
+		_, err := sg.buildEmail(&bindings.InvokeRequest{Data: []byte(`{"attachments": [{}]}`)})
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,381 @@
+// ------------------------------------------------------------
+// Copyright (c) Microsoft Corporation and Dapr Contributors.
+// Licensed under the MIT License.
+// ------------------------------------------------------------
+
+package eventhubs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-amqp-common-go/persist"
+	"github.com/Azure/azure-event-hubs-go/eph"
+	"github.com/google/uuid"
+
+	"github.com/dapr/components-contrib/state"
+)
+
+// partitionLease is the record kept in the state store for each partition.
+// It holds both the lease used to balance partitions across hosts and the
+// checkpoint of the partition.
+type partitionLease struct {
+	eph.Lease
+	Token      string              `json:"token"`
+	Expiration time.Time           `json:"expiration"`
+	Checkpoint *persist.Checkpoint `json:"checkpoint,omitempty"`
+}
+
+// IsExpired indicates that the lease is not owned by any host or has not been
+// renewed in time by its owner.
+func (l *partitionLease) IsExpired(_ context.Context) bool {
+	return l.Owner == "" || time.Now().After(l.Expiration)
+}
+
+// StateLeaserCheckpointer implements eph.Leaser and eph.Checkpointer on top of
+// a state store. Leases are coordinated across hosts with ETags, so the store
+// must support them. Leases are created with first-write inserts, so the store
+// must also fail a first-write Set without an ETag when the key exists.
+type StateLeaserCheckpointer struct {
+	store         state.Store
+	keyPrefix     string
+	leaseDuration time.Duration
+
+	owner        string
+	partitionIDs []string
+	// tokens holds the lease tokens of the partitions owned by this host.
+	tokens map[string]string
+	lock   sync.Mutex
+
+	closeOnce sync.Once
+}
+
+// NewStateLeaserCheckpointer returns a leaser and checkpointer that keeps the
+// records of each partition in store under keys starting with keyPrefix.
+func NewStateLeaserCheckpointer(store state.Store, keyPrefix string, leaseDuration time.Duration) (*StateLeaserCheckpointer, error) {
+	if !state.FeatureETag.IsPresent(store.Features()) {
+		return nil, errors.New("the checkpoint store must support ETags")
+	}
+
+	return &StateLeaserCheckpointer{
+		store:         store,
+		keyPrefix:     keyPrefix,
+		leaseDuration: leaseDuration,
+		tokens:        map[string]string{},
+	}, nil
+}
+
+// SetEventHostProcessor sets the host that owns the leases.
+func (s *StateLeaserCheckpointer) SetEventHostProcessor(processor *eph.EventProcessorHost) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.owner = processor.GetName()
+	s.partitionIDs = processor.GetPartitionIDs()
+}
+
+// StoreExists always returns true as a state store needs no provisioning.
+func (s *StateLeaserCheckpointer) StoreExists(ctx context.Context) (bool, error) {
+	return true, nil
+}
+
+// EnsureStore is a no-op as a state store needs no provisioning.
+func (s *StateLeaserCheckpointer) EnsureStore(ctx context.Context) error {
+	return nil
+}
+
+// DeleteStore deletes the records of all partitions.
+func (s *StateLeaserCheckpointer) DeleteStore(ctx context.Context) error {
+	for _, partitionID := range s.getPartitionIDs() {
+		if err := s.DeleteLease(ctx, partitionID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetLeases returns the current leases of all partitions.
+func (s *StateLeaserCheckpointer) GetLeases(ctx context.Context) ([]eph.LeaseMarker, error) {
+	partitionIDs := s.getPartitionIDs()
+	leases := make([]eph.LeaseMarker, len(partitionIDs))
+	for i, partitionID := range partitionIDs {
+		lease, _, err := s.get(partitionID)
+		if err != nil {
+			return nil, err
+		}
+		if lease == nil {
+			lease = newPartitionLease(partitionID)
+		}
+		leases[i] = lease
+	}
+
+	return leases, nil
+}
+
+// EnsureLease creates the lease of a partition if it does not exist yet.
+func (s *StateLeaserCheckpointer) EnsureLease(ctx context.Context, partitionID string) (eph.LeaseMarker, error) {
+	lease, _, err := s.get(partitionID)
+	if err != nil || lease != nil {
+		return lease, err
+	}
+
+	// The first-write insert fails if another host created the lease meanwhile,
+	// which must not be overwritten as it may already be acquired
+	lease = newPartitionLease(partitionID)
+	ok, err := s.compareAndSet(lease, nil)
+	if ok || err != nil {
+		return lease, err
+	}
+
+	existing, _, err := s.get(partitionID)
+	if err != nil {
+		return nil, err
+	}
+	if existing == nil {
+		return nil, fmt.Errorf("lease for partition %s was deleted while it was created", partitionID)
+	}
+
+	return existing, nil
+}
+
+// DeleteLease deletes the record of a partition.
+func (s *StateLeaserCheckpointer) DeleteLease(ctx context.Context, partitionID string) error {
+	s.lock.Lock()
+	delete(s.tokens, partitionID)
+	s.lock.Unlock()
+
+	return s.store.Delete(&state.DeleteRequest{Key: s.key(partitionID)})
+}
+
+// AcquireLease takes ownership of the lease of a partition. It returns false
+// when another host changed the lease concurrently.
+func (s *StateLeaserCheckpointer) AcquireLease(ctx context.Context, partitionID string) (eph.LeaseMarker, bool, error) {
+	lease, etag, err := s.get(partitionID)
+	if err != nil {
+		return nil, false, err
+	}
+	if lease == nil {
+		// Create the lease first so that it is acquired with an ETag like an existing one
+		if _, err = s.EnsureLease(ctx, partitionID); err != nil {
+			return nil, false, err
+		}
+		if lease, etag, err = s.get(partitionID); err != nil || lease == nil {
+			return nil, false, err
+		}
+	}
+
+	token := uuid.New().String()
+	lease.Owner = s.getOwner()
+	lease.Token = token
+	lease.Expiration = time.Now().Add(s.leaseDuration)
+	lease.IncrementEpoch()
+
+	ok, err := s.compareAndSet(lease, etag)
+	if !ok || err != nil {
+		return nil, false, err
+	}
+
+	s.lock.Lock()
+	s.tokens[partitionID] = token
+	s.lock.Unlock()
+
+	return lease, true, nil
+}
+
+// RenewLease extends the lease of a partition owned by this host.
+func (s *StateLeaserCheckpointer) RenewLease(ctx context.Context, partitionID string) (eph.LeaseMarker, bool, error) {
+	var lease *partitionLease
+	ok, err := s.update(partitionID, func(l *partitionLease) {
+		l.Expiration = time.Now().Add(s.leaseDuration)
+		lease = l
+	})
+	if !ok || err != nil {
+		return nil, false, err
+	}
+
+	return lease, true, nil
+}
+
+// ReleaseLease gives up the lease of a partition owned by this host.
+func (s *StateLeaserCheckpointer) ReleaseLease(ctx context.Context, partitionID string) (bool, error) {
+	ok, err := s.update(partitionID, func(l *partitionLease) {
+		l.Owner = ""
+		l.Token = ""
+		l.Expiration = time.Time{}
+	})
+
+	s.lock.Lock()
+	delete(s.tokens, partitionID)
+	s.lock.Unlock()
+
+	return ok, err
+}
+
+// UpdateLease renews the lease of a partition owned by this host.
+func (s *StateLeaserCheckpointer) UpdateLease(ctx context.Context, partitionID string) (eph.LeaseMarker, bool, error) {
+	return s.RenewLease(ctx, partitionID)
+}
+
+// GetCheckpoint returns the checkpoint of a partition and whether it exists.
+func (s *StateLeaserCheckpointer) GetCheckpoint(ctx context.Context, partitionID string) (persist.Checkpoint, bool) {
+	lease, _, err := s.get(partitionID)
+	if err != nil || lease == nil || lease.Checkpoint == nil {
+		return persist.NewCheckpointFromStartOfStream(), false
+	}
+
+	return *lease.Checkpoint, true
+}
+
+// EnsureCheckpoint returns the checkpoint of a partition, or the start of the
+// stream when the partition has no checkpoint yet.
+func (s *StateLeaserCheckpointer) EnsureCheckpoint(ctx context.Context, partitionID string) (persist.Checkpoint, error) {
+	lease, _, err := s.get(partitionID)
+	if err != nil {
+		return persist.Checkpoint{}, err
+	}
+
+	if lease == nil || lease.Checkpoint == nil {
+		return persist.NewCheckpointFromStartOfStream(), nil
+	}
+
+	return *lease.Checkpoint, nil
+}
+
+// UpdateCheckpoint stores the checkpoint of a partition owned by this host.
+func (s *StateLeaserCheckpointer) UpdateCheckpoint(ctx context.Context, partitionID string, checkpoint persist.Checkpoint) error {
+	ok, err := s.update(partitionID, func(l *partitionLease) {
+		l.Checkpoint = &checkpoint
+	})
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("lease for partition %s isn't owned by this host", partitionID)
+	}
+
+	return nil
+}
+
+// DeleteCheckpoint resets the checkpoint of a partition owned by this host to
+// the start of the stream.
+func (s *StateLeaserCheckpointer) DeleteCheckpoint(ctx context.Context, partitionID string) error {
+	return s.UpdateCheckpoint(ctx, partitionID, persist.NewCheckpointFromStartOfStream())
+}
+
+// Close closes the state store. It is safe to call more than once, as the
+// processor closes both its leaser and its checkpointer.
+func (s *StateLeaserCheckpointer) Close() error {
+	var err error
+	s.closeOnce.Do(func() {
+		if closer, ok := s.store.(io.Closer); ok {
+			err = closer.Close()
+		}
+	})
+
+	return err
+}
+
+// update applies fn to the lease of a partition if it is still owned by this
+// host. It returns false if the lease was lost to another host.
+func (s *StateLeaserCheckpointer) update(partitionID string, fn func(l *partitionLease)) (bool, error) {
+	s.lock.Lock()
+	token, owned := s.tokens[partitionID]
+	s.lock.Unlock()
+	if !owned {
+		return false, nil
+	}
+
+	lease, etag, err := s.get(partitionID)
+	if err != nil {
+		return false, err
+	}
+	if lease == nil || lease.Token != token {
+		return false, nil
+	}
+
+	fn(lease)
+
+	return s.compareAndSet(lease, etag)
+}
+
+// compareAndSet stores a lease if it has not been changed since it was read
+// with etag. It returns false on an ETag mismatch.
+func (s *StateLeaserCheckpointer) compareAndSet(lease *partitionLease, etag *string) (bool, error) {
+	err := s.set(lease, etag)
+	if err != nil {
+		var etagErr *state.ETagError
+		if errors.As(err, &etagErr) && etagErr.Kind() == state.ETagMismatch {
+			return false, nil
+		}
+
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (s *StateLeaserCheckpointer) get(partitionID string) (*partitionLease, *string, error) {
+	res, err := s.store.Get(&state.GetRequest{
+		Key:     s.key(partitionID),
+		Options: state.GetStateOption{Consistency: state.Strong},
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if res == nil || len(res.Data) == 0 {
+		return nil, nil, nil
+	}
+
+	var lease partitionLease
+	if err = json.Unmarshal(res.Data, &lease); err != nil {
+		return nil, nil, fmt.Errorf("invalid lease for partition %s: %s", partitionID, err)
+	}
+
+	return &lease, res.ETag, nil
+}
+
+func (s *StateLeaserCheckpointer) set(lease *partitionLease, etag *string) error {
+	b, err := json.Marshal(lease)
+	if err != nil {
+		return err
+	}
+
+	return s.store.Set(&state.SetRequest{
+		Key:   s.key(lease.PartitionID),
+		Value: b,
+		ETag:  etag,
+		Options: state.SetStateOption{
+			Concurrency: state.FirstWrite,
+			Consistency: state.Strong,
+		},
+	})
+}
+
+func (s *StateLeaserCheckpointer) key(partitionID string) string {
+	return s.keyPrefix + partitionID
+}
+
+func (s *StateLeaserCheckpointer) getOwner() string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.owner
+}
+
+func (s *StateLeaserCheckpointer) getPartitionIDs() []string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.partitionIDs
+}
+
+func newPartitionLease(partitionID string) *partitionLease {
+	return &partitionLease{Lease: eph.Lease{PartitionID: partitionID}}
+}
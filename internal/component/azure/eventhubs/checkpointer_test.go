@@ -0,0 +1,261 @@
+// ------------------------------------------------------------
+// Copyright (c) Microsoft Corporation and Dapr Contributors.
+// Licensed under the MIT License.
+// ------------------------------------------------------------
+
+package eventhubs
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-amqp-common-go/persist"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/components-contrib/internal/component/statestore"
+	"github.com/dapr/components-contrib/state"
+	"github.com/dapr/dapr/pkg/logger"
+)
+
+// newTestHosts returns leaser checkpointers for two hosts sharing the same store.
+func newTestHosts(t *testing.T, leaseDuration time.Duration) (*StateLeaserCheckpointer, *StateLeaserCheckpointer) {
+	store := statestore.NewMemoryStore()
+	partitionIDs := []string{"0", "1", "2", "3"}
+
+	hosts := make([]*StateLeaserCheckpointer, 2)
+	for i, owner := range []string{"host1", "host2"} {
+		h, err := NewStateLeaserCheckpointer(store, "ns/hub/group/", leaseDuration)
+		require.NoError(t, err)
+		h.owner = owner
+		h.partitionIDs = partitionIDs
+		hosts[i] = h
+	}
+
+	return hosts[0], hosts[1]
+}
+
+func TestStateLeaserCheckpointer(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("leases start unowned and expired", func(t *testing.T) {
+		host1, _ := newTestHosts(t, time.Minute)
+
+		lease, err := host1.EnsureLease(ctx, "0")
+		require.NoError(t, err)
+		assert.Equal(t, "0", lease.GetPartitionID())
+		assert.True(t, lease.IsExpired(ctx))
+
+		leases, err := host1.GetLeases(ctx)
+		require.NoError(t, err)
+		require.Len(t, leases, 4)
+		for _, l := range leases {
+			assert.Empty(t, l.GetOwner())
+			assert.True(t, l.IsExpired(ctx))
+		}
+	})
+
+	t.Run("acquire, renew and release", func(t *testing.T) {
+		host1, host2 := newTestHosts(t, time.Minute)
+
+		lease, ok, err := host1.AcquireLease(ctx, "0")
+		require.NoError(t, err)
+		require.True(t, ok)
+		assert.Equal(t, "host1", lease.GetOwner())
+		assert.Equal(t, int64(1), lease.GetEpoch())
+		assert.False(t, lease.IsExpired(ctx))
+
+		// The other host sees the lease as owned
+		leases, err := host2.GetLeases(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, "host1", leases[0].GetOwner())
+		assert.False(t, leases[0].IsExpired(ctx))
+
+		_, ok, err = host1.RenewLease(ctx, "0")
+		require.NoError(t, err)
+		assert.True(t, ok)
+
+		// A host can't renew a lease it doesn't own
+		_, ok, err = host2.RenewLease(ctx, "0")
+		require.NoError(t, err)
+		assert.False(t, ok)
+
+		ok, err = host1.ReleaseLease(ctx, "0")
+		require.NoError(t, err)
+		assert.True(t, ok)
+
+		leases, err = host2.GetLeases(ctx)
+		require.NoError(t, err)
+		assert.True(t, leases[0].IsExpired(ctx))
+	})
+
+	t.Run("stolen lease can't be renewed or checkpointed", func(t *testing.T) {
+		host1, host2 := newTestHosts(t, time.Minute)
+
+		_, ok, err := host1.AcquireLease(ctx, "1")
+		require.NoError(t, err)
+		require.True(t, ok)
+
+		lease, ok, err := host2.AcquireLease(ctx, "1")
+		require.NoError(t, err)
+		require.True(t, ok)
+		assert.Equal(t, "host2", lease.GetOwner())
+		assert.Equal(t, int64(2), lease.GetEpoch())
+
+		_, ok, err = host1.RenewLease(ctx, "1")
+		require.NoError(t, err)
+		assert.False(t, ok)
+
+		err = host1.UpdateCheckpoint(ctx, "1", persist.NewCheckpoint("10", 10, time.Now()))
+		assert.Error(t, err)
+	})
+
+	t.Run("expired lease", func(t *testing.T) {
+		host1, _ := newTestHosts(t, time.Millisecond)
+
+		lease, ok, err := host1.AcquireLease(ctx, "2")
+		require.NoError(t, err)
+		require.True(t, ok)
+
+		time.Sleep(5 * time.Millisecond)
+		leases, err := host1.GetLeases(ctx)
+		require.NoError(t, err)
+		assert.True(t, leases[2].IsExpired(ctx))
+		assert.Equal(t, lease.GetOwner(), leases[2].GetOwner())
+	})
+
+	t.Run("checkpoints", func(t *testing.T) {
+		host1, host2 := newTestHosts(t, time.Minute)
+
+		checkpoint, ok := host1.GetCheckpoint(ctx, "3")
+		assert.False(t, ok)
+		assert.Equal(t, persist.StartOfStream, checkpoint.Offset)
+
+		_, ok, err := host1.AcquireLease(ctx, "3")
+		require.NoError(t, err)
+		require.True(t, ok)
+
+		enqueueTime := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+		err = host1.UpdateCheckpoint(ctx, "3", persist.NewCheckpoint("42", 7, enqueueTime))
+		require.NoError(t, err)
+
+		// The checkpoint survives a change of ownership
+		ok, err = host1.ReleaseLease(ctx, "3")
+		require.NoError(t, err)
+		require.True(t, ok)
+		_, ok, err = host2.AcquireLease(ctx, "3")
+		require.NoError(t, err)
+		require.True(t, ok)
+
+		checkpoint, err = host2.EnsureCheckpoint(ctx, "3")
+		require.NoError(t, err)
+		assert.Equal(t, "42", checkpoint.Offset)
+		assert.Equal(t, int64(7), checkpoint.SequenceNumber)
+		assert.True(t, enqueueTime.Equal(checkpoint.EnqueueTime))
+
+		err = host2.DeleteCheckpoint(ctx, "3")
+		require.NoError(t, err)
+		checkpoint, _ = host2.GetCheckpoint(ctx, "3")
+		assert.Equal(t, persist.StartOfStream, checkpoint.Offset)
+	})
+
+	t.Run("delete store", func(t *testing.T) {
+		host1, _ := newTestHosts(t, time.Minute)
+
+		_, ok, err := host1.AcquireLease(ctx, "0")
+		require.NoError(t, err)
+		require.True(t, ok)
+
+		require.NoError(t, host1.DeleteStore(ctx))
+		leases, err := host1.GetLeases(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, leases[0].GetOwner())
+	})
+}
+
+func TestAcquireLeaseRace(t *testing.T) {
+	host1, host2 := newTestHosts(t, time.Minute)
+
+	_, err := host1.EnsureLease(context.Background(), "0")
+	require.NoError(t, err)
+
+	// host2 changes the lease after host1 read it
+	_, etag, err := host1.get("0")
+	require.NoError(t, err)
+	_, ok, err := host2.AcquireLease(context.Background(), "0")
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	lease := newPartitionLease("0")
+	lease.Owner = "host1"
+	ok, err = host1.compareAndSet(lease, etag)
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestEnsureLeaseRace(t *testing.T) {
+	host1, host2 := newTestHosts(t, time.Minute)
+
+	_, ok, err := host1.AcquireLease(context.Background(), "0")
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	// host2 tries to create the lease after host1 created and acquired it
+	ok, err = host2.compareAndSet(newPartitionLease("0"), nil)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	lease, err := host2.EnsureLease(context.Background(), "0")
+	require.NoError(t, err)
+	assert.Equal(t, "host1", lease.GetOwner())
+
+	_, ok, err = host1.RenewLease(context.Background(), "0")
+	require.NoError(t, err)
+	assert.True(t, ok)
+}
+
+type noETagStore struct {
+	state.Store
+}
+
+func (s noETagStore) Features() []state.Feature {
+	return nil
+}
+
+func TestNewStateLeaserCheckpointerRequiresETags(t *testing.T) {
+	_, err := NewStateLeaserCheckpointer(noETagStore{statestore.NewMemoryStore()}, "", time.Minute)
+	assert.Error(t, err)
+}
+
+func TestNewCheckpointStore(t *testing.T) {
+	connStr := "Endpoint=sb://myns.servicebus.windows.net/;SharedAccessKeyName=key;SharedAccessKey=secret;EntityPath=myhub"
+
+	t.Run("memory store", func(t *testing.T) {
+		lc, err := NewCheckpointStore(map[string]string{CheckpointStoreMetadataKey: MemoryCheckpointStore}, connStr, "group", logger.NewLogger("test"))
+		require.NoError(t, err)
+		assert.Equal(t, "myns/myhub/group/", lc.keyPrefix)
+		assert.NoError(t, lc.Close())
+		assert.NoError(t, lc.Close())
+	})
+
+	t.Run("unsupported store", func(t *testing.T) {
+		_, err := NewCheckpointStore(map[string]string{CheckpointStoreMetadataKey: "cassandra"}, connStr, "group", logger.NewLogger("test"))
+		assert.Error(t, err)
+	})
+
+	t.Run("store metadata is passed without prefix", func(t *testing.T) {
+		_, err := NewCheckpointStore(map[string]string{
+			CheckpointStoreMetadataKey:                      "redis",
+			CheckpointStoreMetadataPrefix + "redisHost":     "",
+			CheckpointStoreMetadataPrefix + "redisPassword": "secret",
+		}, connStr, "group", logger.NewLogger("test"))
+		assert.Error(t, err)
+	})
+
+	t.Run("uses checkpoint store", func(t *testing.T) {
+		assert.True(t, UsesCheckpointStore(map[string]string{CheckpointStoreMetadataKey: "redis"}))
+		assert.False(t, UsesCheckpointStore(map[string]string{CheckpointStoreMetadataKey: ""}))
+		assert.False(t, UsesCheckpointStore(map[string]string{}))
+	})
+}
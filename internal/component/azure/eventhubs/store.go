@@ -0,0 +1,76 @@
+// ------------------------------------------------------------
+// Copyright (c) Microsoft Corporation and Dapr Contributors.
+// Licensed under the MIT License.
+// ------------------------------------------------------------
+
+package eventhubs
+
+import (
+	"fmt"
+
+	"github.com/Azure/azure-amqp-common-go/conn"
+	"github.com/Azure/azure-event-hubs-go/eph"
+
+	"github.com/dapr/components-contrib/internal/component/statestore"
+	"github.com/dapr/dapr/pkg/logger"
+)
+
+const (
+	// CheckpointStoreMetadataKey selects the state store used for checkpoints and leases.
+	// When it is not set, checkpoints are kept in Azure Blob Storage.
+	CheckpointStoreMetadataKey = "checkpointStore"
+	// CheckpointStoreMetadataPrefix is the prefix of the metadata passed to the checkpoint store,
+	// for example `checkpointStore.redisHost`.
+	CheckpointStoreMetadataPrefix = "checkpointStore."
+
+	// MemoryCheckpointStore keeps checkpoints in memory, which is useful for tests and emulators.
+	MemoryCheckpointStore = statestore.MemoryStore
+)
+
+// LeaserCheckpointer coordinates partition leases and persists checkpoints for an event processor host.
+type LeaserCheckpointer interface {
+	eph.Leaser
+	eph.Checkpointer
+}
+
+// NewCheckpointStore creates the leaser and checkpointer configured by the
+// `checkpointStore` metadata of an Event Hubs component. The checkpoint store
+// is initialized with the metadata prefixed with `checkpointStore.`, and the
+// records are scoped to the hub of the connection string and the consumer group.
+//
+// Only the redis, postgresql and mysql state stores and the memory store can be
+// selected, as leases rely on ETags and on first-write inserts that fail when the
+// key exists, which the other state stores don't implement. Redis must be a
+// single node, as leases are written with strong consistency.
+func NewCheckpointStore(properties map[string]string, connectionString, consumerGroup string, logger logger.Logger) (*StateLeaserCheckpointer, error) {
+	store, err := statestore.New(properties, CheckpointStoreMetadataKey, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	keyPrefix, err := checkpointKeyPrefix(connectionString, consumerGroup)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewStateLeaserCheckpointer(store, keyPrefix, eph.DefaultLeaseDuration)
+}
+
+// checkpointKeyPrefix returns the prefix of the checkpoint keys of a consumer group,
+// for example `namespace/hub/$Default/`.
+func checkpointKeyPrefix(connectionString, consumerGroup string) (string, error) {
+	parsed, err := conn.ParsedConnectionFromStr(connectionString)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%s/%s/%s/", parsed.Namespace, parsed.HubName, consumerGroup), nil
+}
+
+// UsesCheckpointStore returns whether the component metadata selects a state
+// store for checkpoints instead of Azure Blob Storage.
+func UsesCheckpointStore(properties map[string]string) bool {
+	val, ok := properties[CheckpointStoreMetadataKey]
+
+	return ok && val != ""
+}
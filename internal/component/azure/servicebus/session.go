@@ -0,0 +1,212 @@
+// ------------------------------------------------------------
+// Copyright (c) Microsoft Corporation and Dapr Contributors.
+// Licensed under the MIT License.
+// ------------------------------------------------------------
+
+// Package servicebus holds the session receiver shared by the Azure Service Bus binding and pub/sub components.
+package servicebus
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	azservicebus "github.com/Azure/azure-service-bus-go"
+	"github.com/dapr/dapr/pkg/logger"
+)
+
+const (
+	// SessionIDMetadataKey is the publish metadata holding the session of a message.
+	SessionIDMetadataKey = "sessionID"
+
+	// DefaultMaxConcurrentSessions is the number of sessions handled at the same time when not configured.
+	DefaultMaxConcurrentSessions = 8
+	// DefaultSessionIdleTimeoutInSec is the time after which a session without new messages is released.
+	DefaultSessionIdleTimeoutInSec = 60
+
+	sessionRetryInterval = 2 * time.Second
+)
+
+// Session receives the messages of the next available session of a queue or subscription.
+type Session interface {
+	ReceiveOne(ctx context.Context, handler azservicebus.SessionHandler) error
+	Close(ctx context.Context) error
+}
+
+// SessionOptions configures how sessions are received.
+type SessionOptions struct {
+	// MaxConcurrentSessions is the number of sessions handled at the same time.
+	MaxConcurrentSessions int
+	// LockRenewalInterval is the interval at which session locks are renewed, 0 disables renewal.
+	LockRenewalInterval time.Duration
+	// IdleTimeout releases a session when no message was received for this long.
+	IdleTimeout time.Duration
+	// CloseTimeout bounds the time spent closing a session.
+	CloseTimeout time.Duration
+}
+
+// ReceiveSessions accepts up to MaxConcurrentSessions sessions at a time from newSession and passes
+// their messages to handler. Messages of a session are handled one at a time, in order. It blocks
+// until ctx is done.
+func ReceiveSessions(ctx context.Context, newSession func() Session, handler azservicebus.HandlerFunc, opts SessionOptions, logger logger.Logger) error {
+	concurrency := opts.MaxConcurrentSessions
+	if concurrency <= 0 {
+		concurrency = DefaultMaxConcurrentSessions
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			receiveSessions(ctx, newSession, handler, opts, logger)
+		}()
+	}
+	wg.Wait()
+
+	return ctx.Err()
+}
+
+func receiveSessions(ctx context.Context, newSession func() Session, handler azservicebus.HandlerFunc, opts SessionOptions, logger logger.Logger) {
+	for ctx.Err() == nil {
+		session := newSession()
+		h := newSessionHandler(ctx, handler, opts, logger)
+
+		// Waits for a session to become available and returns once it is released
+		err := session.ReceiveOne(ctx, h)
+
+		closeCtx, closeCancel := context.WithTimeout(context.Background(), opts.CloseTimeout)
+		if closeErr := session.Close(closeCtx); closeErr != nil {
+			logger.Debugf("Error closing session: %s", closeErr)
+		}
+		closeCancel()
+
+		if err != nil && ctx.Err() == nil {
+			// Service Bus also returns an error when no session became available in time
+			logger.Debugf("No session received, retrying: %s", err)
+			select {
+			case <-ctx.Done():
+			case <-time.After(sessionRetryInterval):
+			}
+		}
+	}
+}
+
+// messageSession is the part of azservicebus.MessageSession used by the session handler.
+type messageSession interface {
+	Close()
+	RenewLock(ctx context.Context) error
+	SessionID() *string
+}
+
+// sessionHandler handles the messages of one session. It renews the session lock and
+// releases the session once it has been idle for the configured timeout.
+type sessionHandler struct {
+	ctx     context.Context
+	handler azservicebus.HandlerFunc
+	opts    SessionOptions
+	logger  logger.Logger
+
+	session  messageSession
+	handling int32
+	activity chan struct{}
+	done     chan struct{}
+	doneOnce sync.Once
+}
+
+func newSessionHandler(ctx context.Context, handler azservicebus.HandlerFunc, opts SessionOptions, logger logger.Logger) *sessionHandler {
+	return &sessionHandler{
+		ctx:      ctx,
+		handler:  handler,
+		opts:     opts,
+		logger:   logger,
+		activity: make(chan struct{}, 1),
+		done:     make(chan struct{}),
+	}
+}
+
+func (h *sessionHandler) Start(ms *azservicebus.MessageSession) error {
+	return h.start(ms)
+}
+
+func (h *sessionHandler) start(ms messageSession) error {
+	h.session = ms
+	h.logger.Debugf("Accepted session %s", h.sessionID())
+	go h.monitor()
+
+	return nil
+}
+
+func (h *sessionHandler) Handle(ctx context.Context, msg *azservicebus.Message) error {
+	atomic.AddInt32(&h.handling, 1)
+	defer atomic.AddInt32(&h.handling, -1)
+	h.touch()
+	defer h.touch()
+
+	return h.handler(ctx, msg)
+}
+
+func (h *sessionHandler) End() {
+	h.doneOnce.Do(func() {
+		close(h.done)
+	})
+}
+
+func (h *sessionHandler) touch() {
+	select {
+	case h.activity <- struct{}{}:
+	default:
+	}
+}
+
+func (h *sessionHandler) sessionID() string {
+	if id := h.session.SessionID(); id != nil {
+		return *id
+	}
+
+	return ""
+}
+
+func (h *sessionHandler) monitor() {
+	idle := time.NewTimer(h.opts.IdleTimeout)
+	defer idle.Stop()
+
+	var renew <-chan time.Time
+	if h.opts.LockRenewalInterval > 0 {
+		ticker := time.NewTicker(h.opts.LockRenewalInterval)
+		defer ticker.Stop()
+		renew = ticker.C
+	}
+
+	for {
+		select {
+		case <-h.ctx.Done():
+			h.session.Close()
+
+			return
+		case <-h.done:
+			return
+		case <-h.activity:
+			if !idle.Stop() {
+				<-idle.C
+			}
+			idle.Reset(h.opts.IdleTimeout)
+		case <-idle.C:
+			if atomic.LoadInt32(&h.handling) > 0 {
+				idle.Reset(h.opts.IdleTimeout)
+
+				continue
+			}
+			h.logger.Debugf("Releasing idle session %s", h.sessionID())
+			h.session.Close()
+
+			return
+		case <-renew:
+			// Lock renewal is best effort, the session is received again if the lock is lost
+			if err := h.session.RenewLock(h.ctx); err != nil {
+				h.logger.Warnf("Couldn't renew lock of session %s: %s", h.sessionID(), err)
+			}
+		}
+	}
+}
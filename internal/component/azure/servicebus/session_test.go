@@ -0,0 +1,194 @@
+// ------------------------------------------------------------
+// Copyright (c) Microsoft Corporation and Dapr Contributors.
+// Licensed under the MIT License.
+// ------------------------------------------------------------
+
+package servicebus
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	azservicebus "github.com/Azure/azure-service-bus-go"
+	"github.com/dapr/dapr/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeMessageSession struct {
+	id       string
+	renewals int32
+	closed   chan struct{}
+	once     sync.Once
+}
+
+func newFakeMessageSession(id string) *fakeMessageSession {
+	return &fakeMessageSession{id: id, closed: make(chan struct{})}
+}
+
+func (s *fakeMessageSession) Close() {
+	s.once.Do(func() { close(s.closed) })
+}
+
+func (s *fakeMessageSession) RenewLock(ctx context.Context) error {
+	atomic.AddInt32(&s.renewals, 1)
+
+	return nil
+}
+
+func (s *fakeMessageSession) SessionID() *string {
+	return &s.id
+}
+
+// fakeSession delivers the messages of one session and returns once the session is released.
+type fakeSession struct {
+	id       string
+	messages []string
+	closed   int32
+}
+
+func (s *fakeSession) ReceiveOne(ctx context.Context, handler azservicebus.SessionHandler) error {
+	if s.id == "" {
+		return errors.New("no session available")
+	}
+
+	ms := newFakeMessageSession(s.id)
+	h := handler.(*sessionHandler)
+	if err := h.start(ms); err != nil {
+		return err
+	}
+	defer h.End()
+
+	for _, m := range s.messages {
+		if err := h.Handle(ctx, &azservicebus.Message{Data: []byte(m)}); err != nil {
+			return err
+		}
+	}
+	<-ms.closed
+
+	return nil
+}
+
+func (s *fakeSession) Close(ctx context.Context) error {
+	atomic.AddInt32(&s.closed, 1)
+
+	return nil
+}
+
+func TestReceiveSessions(t *testing.T) {
+	sessions := make(chan *fakeSession, 3)
+	sessions <- &fakeSession{id: "a", messages: []string{"a1", "a2", "a3"}}
+	sessions <- &fakeSession{id: "b", messages: []string{"b1", "b2"}}
+	sessions <- &fakeSession{id: "c", messages: []string{"c1"}}
+
+	var accepted []*fakeSession
+	var lock sync.Mutex
+	newSession := func() Session {
+		lock.Lock()
+		defer lock.Unlock()
+		select {
+		case s := <-sessions:
+			accepted = append(accepted, s)
+
+			return s
+		default:
+			return &fakeSession{}
+		}
+	}
+
+	received := map[string][]string{}
+	concurrent, maxConcurrent := int32(0), int32(0)
+	handler := func(ctx context.Context, msg *azservicebus.Message) error {
+		n := atomic.AddInt32(&concurrent, 1)
+		defer atomic.AddInt32(&concurrent, -1)
+		lock.Lock()
+		if n > maxConcurrent {
+			maxConcurrent = n
+		}
+		received[string(msg.Data[:1])] = append(received[string(msg.Data[:1])], string(msg.Data))
+		lock.Unlock()
+		time.Sleep(5 * time.Millisecond)
+
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	err := ReceiveSessions(ctx, newSession, handler, SessionOptions{
+		MaxConcurrentSessions: 2,
+		IdleTimeout:           20 * time.Millisecond,
+		CloseTimeout:          time.Second,
+	}, logger.NewLogger("test"))
+
+	assert.Equal(t, context.DeadlineExceeded, err)
+	assert.Equal(t, map[string][]string{"a": {"a1", "a2", "a3"}, "b": {"b1", "b2"}, "c": {"c1"}}, received)
+	assert.LessOrEqual(t, maxConcurrent, int32(2))
+	require.Len(t, accepted, 3)
+	for _, s := range accepted {
+		assert.Equal(t, int32(1), atomic.LoadInt32(&s.closed))
+	}
+}
+
+func TestSessionHandler(t *testing.T) {
+	t.Run("renews lock until idle", func(t *testing.T) {
+		h := newSessionHandler(context.Background(), nil, SessionOptions{
+			LockRenewalInterval: 5 * time.Millisecond,
+			IdleTimeout:         50 * time.Millisecond,
+		}, logger.NewLogger("test"))
+		ms := newFakeMessageSession("a")
+		require.NoError(t, h.start(ms))
+
+		select {
+		case <-ms.closed:
+		case <-time.After(time.Second):
+			require.FailNow(t, "session was not released")
+		}
+		assert.Greater(t, atomic.LoadInt32(&ms.renewals), int32(1))
+	})
+
+	t.Run("is not released while handling", func(t *testing.T) {
+		handling := make(chan struct{})
+		release := make(chan struct{})
+		h := newSessionHandler(context.Background(), func(ctx context.Context, msg *azservicebus.Message) error {
+			close(handling)
+			<-release
+
+			return nil
+		}, SessionOptions{IdleTimeout: 10 * time.Millisecond}, logger.NewLogger("test"))
+		ms := newFakeMessageSession("a")
+		require.NoError(t, h.start(ms))
+
+		go h.Handle(context.Background(), &azservicebus.Message{})
+		<-handling
+		select {
+		case <-ms.closed:
+			require.FailNow(t, "session released while handling a message")
+		case <-time.After(50 * time.Millisecond):
+		}
+
+		close(release)
+		select {
+		case <-ms.closed:
+		case <-time.After(time.Second):
+			require.FailNow(t, "session was not released")
+		}
+	})
+
+	t.Run("is released when the context is done", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		h := newSessionHandler(ctx, nil, SessionOptions{IdleTimeout: time.Minute}, logger.NewLogger("test"))
+		ms := newFakeMessageSession("a")
+		require.NoError(t, h.start(ms))
+
+		cancel()
+		select {
+		case <-ms.closed:
+		case <-time.After(time.Second):
+			require.FailNow(t, "session was not released")
+		}
+	})
+}
@@ -0,0 +1,275 @@
+// ------------------------------------------------------------
+// Copyright (c) Microsoft Corporation and Dapr Contributors.
+// Licensed under the MIT License.
+// ------------------------------------------------------------
+
+package email
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+	texttemplate "text/template"
+
+	"github.com/dapr/components-contrib/bindings"
+	"github.com/dapr/components-contrib/internal/component/statestore"
+	"github.com/dapr/components-contrib/state"
+	"github.com/dapr/dapr/pkg/logger"
+)
+
+const (
+	// keys from component's metadata
+
+	// TemplateDirKey is the directory of the template files
+	TemplateDirKey = "templateDir"
+	// TemplateStoreKey selects the state store keeping the templates, which is
+	// initialized with the metadata prefixed with `templateStore.`
+	TemplateStoreKey = "templateStore"
+
+	// keys from component's or request's metadata, the request's take priority
+
+	// HTMLTemplateKey is the name of the html/template of the HTML body
+	HTMLTemplateKey = "htmlTemplate"
+	// TextTemplateKey is the name of the text/template of the plain text body
+	TextTemplateKey = "textTemplate"
+	// PriorityKey is the priority of the email, high, normal or low
+	PriorityKey = "priority"
+	// ReplyToKey is the address replies are sent to
+	ReplyToKey = "replyTo"
+
+	// PriorityHigh, PriorityNormal and PriorityLow are the priorities of emails
+	PriorityHigh   = "high"
+	PriorityNormal = "normal"
+	PriorityLow    = "low"
+)
+
+// Attachment is a file attached to an email, its content is base64 encoded in the payload.
+type Attachment struct {
+	Filename    string `json:"filename"`
+	ContentType string `json:"contentType"`
+	Content     []byte `json:"content"`
+}
+
+// payload is the JSON object of a request with the bodies of the email or the
+// data of its templates, and its attachments.
+type payload struct {
+	HTML        *string       `json:"html"`
+	Text        *string       `json:"text"`
+	Data        interface{}   `json:"data"`
+	Attachments []*Attachment `json:"attachments"`
+}
+
+// Message is the content of an email, shared by the email bindings.
+type Message struct {
+	HTMLBody    string
+	TextBody    string
+	Attachments []*Attachment
+	Priority    string
+	ReplyTo     string
+}
+
+// Builder builds the messages of requests, rendering their templates.
+type Builder struct {
+	templateDir  string
+	store        state.Store
+	htmlTemplate string
+	textTemplate string
+	priority     string
+	replyTo      string
+}
+
+// NewBuilder returns a builder with the templates and the defaults of the component metadata.
+func NewBuilder(properties map[string]string, logger logger.Logger) (*Builder, error) {
+	b := Builder{
+		htmlTemplate: properties[HTMLTemplateKey],
+		textTemplate: properties[TextTemplateKey],
+		priority:     strings.ToLower(properties[PriorityKey]),
+		replyTo:      properties[ReplyToKey],
+	}
+	if err := checkPriority(b.priority); err != nil {
+		return nil, err
+	}
+
+	if val, ok := properties[TemplateDirKey]; ok && val != "" {
+		b.templateDir = val
+	}
+	if val, ok := properties[TemplateStoreKey]; ok && val != "" {
+		if b.templateDir != "" {
+			return nil, fmt.Errorf("only one of %s and %s can be set", TemplateDirKey, TemplateStoreKey)
+		}
+		store, err := statestore.New(properties, TemplateStoreKey, logger)
+		if err != nil {
+			return nil, err
+		}
+		b.store = store
+	}
+
+	return &b, nil
+}
+
+// Build returns the message of a request. The data of the request is either
+// the HTML body, as a string which may be quoted, or a JSON object with the
+// html and text bodies, the data of the templates and the attachments:
+//
+//	{"data": {"name": "Dapr"}, "attachments": [{"filename": "a.txt", "contentType": "text/plain", "content": "aGk="}]}
+func (b *Builder) Build(req *bindings.InvokeRequest) (*Message, error) {
+	msg := Message{
+		Priority: b.priority,
+		ReplyTo:  b.replyTo,
+	}
+	if val := req.Metadata[PriorityKey]; val != "" {
+		msg.Priority = strings.ToLower(val)
+	}
+	if val := req.Metadata[ReplyToKey]; val != "" {
+		msg.ReplyTo = val
+	}
+	if err := checkPriority(msg.Priority); err != nil {
+		return nil, err
+	}
+
+	var p payload
+	if data := bytes.TrimSpace(req.Data); len(data) > 0 && data[0] == '{' {
+		if err := json.Unmarshal(data, &p); err != nil {
+			return nil, fmt.Errorf("invalid email payload: %s", err)
+		}
+	} else {
+		// For backward compatibility the data is the HTML body
+		body, err := strconv.Unquote(string(req.Data))
+		if err != nil {
+			body = string(req.Data)
+		}
+		p.HTML = &body
+	}
+	if p.HTML != nil {
+		msg.HTMLBody = *p.HTML
+	}
+	if p.Text != nil {
+		msg.TextBody = *p.Text
+	}
+	for _, a := range p.Attachments {
+		if a.Filename == "" {
+			return nil, errors.New("invalid email payload: missing filename of attachment")
+		}
+		if a.ContentType == "" {
+			a.ContentType = "application/octet-stream"
+		}
+	}
+	msg.Attachments = p.Attachments
+
+	htmlTemplate := b.htmlTemplate
+	if val := req.Metadata[HTMLTemplateKey]; val != "" {
+		htmlTemplate = val
+	}
+	if htmlTemplate != "" {
+		body, err := b.render(htmlTemplate, p.Data, func(text string) (template, error) {
+			return htmltemplate.New(htmlTemplate).Parse(text)
+		})
+		if err != nil {
+			return nil, err
+		}
+		msg.HTMLBody = body
+	}
+
+	textTemplate := b.textTemplate
+	if val := req.Metadata[TextTemplateKey]; val != "" {
+		textTemplate = val
+	}
+	if textTemplate != "" {
+		body, err := b.render(textTemplate, p.Data, func(text string) (template, error) {
+			return texttemplate.New(textTemplate).Parse(text)
+		})
+		if err != nil {
+			return nil, err
+		}
+		msg.TextBody = body
+	}
+
+	return &msg, nil
+}
+
+// template is an html/template or a text/template.
+type template interface {
+	Execute(w io.Writer, data interface{}) error
+}
+
+// render loads the template name, parses it and executes it with data.
+func (b *Builder) render(name string, data interface{}, parse func(text string) (template, error)) (string, error) {
+	text, err := b.loadTemplate(name)
+	if err != nil {
+		return "", err
+	}
+	t, err := parse(text)
+	if err != nil {
+		return "", fmt.Errorf("invalid template %s: %s", name, err)
+	}
+
+	var buf bytes.Buffer
+	if err = t.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("error rendering template %s: %s", name, err)
+	}
+
+	return buf.String(), nil
+}
+
+// loadTemplate returns a template from the state store, or from the template
+// directory, which the name cannot escape.
+func (b *Builder) loadTemplate(name string) (string, error) {
+	switch {
+	case b.store != nil:
+		res, err := b.store.Get(&state.GetRequest{Key: name})
+		if err != nil {
+			return "", fmt.Errorf("error getting template %s: %s", name, err)
+		}
+		if res == nil || len(res.Data) == 0 {
+			return "", fmt.Errorf("template %s not found", name)
+		}
+
+		// Templates set through the state API are JSON strings
+		var text string
+		if err = json.Unmarshal(res.Data, &text); err != nil {
+			text = string(res.Data)
+		}
+
+		return text, nil
+	case b.templateDir != "":
+		path := filepath.Join(b.templateDir, filepath.Clean("/"+name))
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("error reading template %s: %s", name, err)
+		}
+
+		return string(data), nil
+	default:
+		return "", fmt.Errorf("template %s requires %s or %s", name, TemplateDirKey, TemplateStoreKey)
+	}
+}
+
+// PriorityHeaders returns the headers of a priority, which are understood by most email clients.
+func PriorityHeaders(priority string) map[string]string {
+	switch priority {
+	case PriorityHigh:
+		return map[string]string{"X-Priority": "1", "Importance": "high"}
+	case PriorityLow:
+		return map[string]string{"X-Priority": "5", "Importance": "low"}
+	case PriorityNormal:
+		return map[string]string{"X-Priority": "3", "Importance": "normal"}
+	default:
+		return nil
+	}
+}
+
+func checkPriority(priority string) error {
+	switch priority {
+	case "", PriorityHigh, PriorityNormal, PriorityLow:
+		return nil
+	default:
+		return fmt.Errorf("invalid %s %s, must be high, normal or low", PriorityKey, priority)
+	}
+}
@@ -0,0 +1,151 @@
+// ------------------------------------------------------------
+// Copyright (c) Microsoft Corporation and Dapr Contributors.
+// Licensed under the MIT License.
+// ------------------------------------------------------------
+
+package email
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dapr/components-contrib/bindings"
+	"github.com/dapr/components-contrib/internal/component/statestore"
+	"github.com/dapr/components-contrib/state"
+	"github.com/dapr/dapr/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewBuilder(t *testing.T) {
+	for name, properties := range map[string]map[string]string{
+		"priority":            {PriorityKey: "urgent"},
+		"directory and store": {TemplateDirKey: "/tmp", TemplateStoreKey: statestore.MemoryStore},
+		"unsupported store":   {TemplateStoreKey: "cassandra"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := NewBuilder(properties, logger.NewLogger("test"))
+			assert.Error(t, err)
+		})
+	}
+
+	b, err := NewBuilder(map[string]string{TemplateStoreKey: statestore.MemoryStore, PriorityKey: "High"}, logger.NewLogger("test"))
+	require.NoError(t, err)
+	assert.NotNil(t, b.store)
+	assert.Equal(t, PriorityHigh, b.priority)
+}
+
+func TestBuild(t *testing.T) {
+	b, err := NewBuilder(map[string]string{ReplyToKey: "support@dapr.io", PriorityKey: "low"}, logger.NewLogger("test"))
+	require.NoError(t, err)
+
+	t.Run("quoted body", func(t *testing.T) {
+		msg, err := b.Build(&bindings.InvokeRequest{Data: []byte(`"<b>Hello</b>"`)})
+		require.NoError(t, err)
+		assert.Equal(t, &Message{HTMLBody: "<b>Hello</b>", ReplyTo: "support@dapr.io", Priority: PriorityLow}, msg)
+	})
+
+	t.Run("raw body", func(t *testing.T) {
+		msg, err := b.Build(&bindings.InvokeRequest{Data: []byte(`<b>Hello</b>`)})
+		require.NoError(t, err)
+		assert.Equal(t, "<b>Hello</b>", msg.HTMLBody)
+	})
+
+	t.Run("payload with attachments", func(t *testing.T) {
+		msg, err := b.Build(&bindings.InvokeRequest{
+			Data: []byte(`{"html": "<b>Hello</b>", "text": "Hello", "attachments": [
+				{"filename": "a.txt", "contentType": "text/plain", "content": "aGVsbG8="},
+				{"filename": "b.bin", "content": "AAE="}
+			]}`),
+			Metadata: map[string]string{ReplyToKey: "team@dapr.io", PriorityKey: "HIGH"},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, &Message{
+			HTMLBody: "<b>Hello</b>",
+			TextBody: "Hello",
+			Attachments: []*Attachment{
+				{Filename: "a.txt", ContentType: "text/plain", Content: []byte("hello")},
+				{Filename: "b.bin", ContentType: "application/octet-stream", Content: []byte{0, 1}},
+			},
+			ReplyTo:  "team@dapr.io",
+			Priority: PriorityHigh,
+		}, msg)
+	})
+
+	t.Run("invalid requests", func(t *testing.T) {
+		for _, req := range []*bindings.InvokeRequest{
+			{Data: []byte(`{"html": 1}`)},
+			{Data: []byte(`{"attachments": [{"content": "aGVsbG8="}]}`)},
+			{Data: []byte(`{"attachments": [{"filename": "a.txt", "content": "not base64"}]}`)},
+			{Data: []byte(`body`), Metadata: map[string]string{PriorityKey: "urgent"}},
+			{Data: []byte(`{}`), Metadata: map[string]string{HTMLTemplateKey: "welcome.html"}},
+		} {
+			_, err := b.Build(req)
+			assert.Error(t, err, string(req.Data))
+		}
+	})
+}
+
+func TestBuildWithTemplateDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "templates")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "welcome.html"), []byte(`<p>Welcome {{.name}}</p>`), 0600))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "welcome.txt"), []byte(`Welcome {{.name}}`), 0600))
+
+	b, err := NewBuilder(map[string]string{TemplateDirKey: dir, HTMLTemplateKey: "welcome.html"}, logger.NewLogger("test"))
+	require.NoError(t, err)
+
+	t.Run("html and text templates", func(t *testing.T) {
+		msg, err := b.Build(&bindings.InvokeRequest{
+			Data:     []byte(`{"data": {"name": "<Dapr>"}}`),
+			Metadata: map[string]string{TextTemplateKey: "welcome.txt"},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "<p>Welcome &lt;Dapr&gt;</p>", msg.HTMLBody)
+		assert.Equal(t, "Welcome <Dapr>", msg.TextBody)
+	})
+
+	t.Run("names cannot escape the directory", func(t *testing.T) {
+		outside := filepath.Join(filepath.Dir(dir), "outside.html")
+		require.NoError(t, ioutil.WriteFile(outside, []byte(`outside`), 0600))
+		defer os.Remove(outside)
+
+		_, err := b.Build(&bindings.InvokeRequest{
+			Data:     []byte(`{}`),
+			Metadata: map[string]string{HTMLTemplateKey: "../outside.html"},
+		})
+		assert.Error(t, err)
+	})
+
+	t.Run("missing template", func(t *testing.T) {
+		_, err := b.Build(&bindings.InvokeRequest{Data: []byte(`{}`), Metadata: map[string]string{HTMLTemplateKey: "missing.html"}})
+		assert.Error(t, err)
+	})
+}
+
+func TestBuildWithTemplateStore(t *testing.T) {
+	b, err := NewBuilder(map[string]string{TextTemplateKey: "reminder"}, logger.NewLogger("test"))
+	require.NoError(t, err)
+	b.store = statestore.NewMemoryStore()
+	require.NoError(t, b.store.Set(&state.SetRequest{Key: "reminder", Value: "Your order {{.order}} ships {{.date}}"}))
+	require.NoError(t, b.store.Set(&state.SetRequest{Key: "invalid", Value: "{{.order"}))
+
+	msg, err := b.Build(&bindings.InvokeRequest{Data: []byte(`{"data": {"order": 42, "date": "today"}}`)})
+	require.NoError(t, err)
+	assert.Equal(t, "Your order 42 ships today", msg.TextBody)
+
+	for _, name := range []string{"invalid", "missing"} {
+		_, err = b.Build(&bindings.InvokeRequest{Data: []byte(`{}`), Metadata: map[string]string{TextTemplateKey: name}})
+		assert.Error(t, err, name)
+	}
+}
+
+func TestPriorityHeaders(t *testing.T) {
+	assert.Equal(t, map[string]string{"X-Priority": "1", "Importance": "high"}, PriorityHeaders(PriorityHigh))
+	assert.Equal(t, map[string]string{"X-Priority": "3", "Importance": "normal"}, PriorityHeaders(PriorityNormal))
+	assert.Equal(t, map[string]string{"X-Priority": "5", "Importance": "low"}, PriorityHeaders(PriorityLow))
+	assert.Nil(t, PriorityHeaders(""))
+}
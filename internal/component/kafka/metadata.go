@@ -0,0 +1,366 @@
+// ------------------------------------------------------------
+// Copyright (c) Microsoft Corporation and Dapr Contributors.
+// Licensed under the MIT License.
+// ------------------------------------------------------------
+
+// Package kafka holds the client configuration shared by the Kafka binding and pub/sub components.
+package kafka
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/Shopify/sarama"
+)
+
+const (
+	brokersKey          = "brokers"
+	authRequiredKey     = "authRequired"
+	authTypeKey         = "authType"
+	saslUsernameKey     = "saslUsername"
+	saslPasswordKey     = "saslPassword"
+	oidcTokenURLKey     = "oidcTokenEndpoint"
+	oidcClientIDKey     = "oidcClientID"
+	oidcClientSecretKey = "oidcClientSecret"
+	oidcScopesKey       = "oidcScopes"
+	enableTLSKey        = "enableTLS"
+	skipVerifyKey       = "skipVerify"
+	caCertKey           = "caCert"
+	clientCertKey       = "clientCert"
+	clientKeyKey        = "clientKey"
+	initialOffsetKey    = "initialOffset"
+	versionKey          = "version"
+	balanceStrategyKey  = "balanceStrategy"
+	maxMessageBytesKey  = "maxMessageBytes"
+	idempotenceKey      = "enableIdempotence"
+
+	// AuthTypeNone disables authentication
+	AuthTypeNone = "none"
+	// AuthTypePlain authenticates with SASL/PLAIN
+	AuthTypePlain = "plain"
+	// AuthTypeSCRAMSHA256 authenticates with SASL/SCRAM-SHA-256
+	AuthTypeSCRAMSHA256 = "scram-sha-256"
+	// AuthTypeSCRAMSHA512 authenticates with SASL/SCRAM-SHA-512
+	AuthTypeSCRAMSHA512 = "scram-sha-512"
+	// AuthTypeOAuth authenticates with SASL/OAUTHBEARER using OAuth2 client credentials
+	AuthTypeOAuth = "oauth"
+	// AuthTypeMTLS authenticates with a TLS client certificate
+	AuthTypeMTLS = "mtls"
+
+	// InitialOffsetNewest starts consuming new partitions from the newest offset
+	InitialOffsetNewest = "newest"
+	// InitialOffsetOldest starts consuming new partitions from the oldest offset
+	InitialOffsetOldest = "oldest"
+
+	// BalanceStrategyRange is the range consumer group rebalance strategy
+	BalanceStrategyRange = "range"
+	// BalanceStrategyRoundRobin is the round robin consumer group rebalance strategy
+	BalanceStrategyRoundRobin = "roundrobin"
+)
+
+// ClientMetadata is the connection, authentication and consumer configuration
+// shared by the Kafka components.
+type ClientMetadata struct {
+	Brokers []string `json:"brokers"`
+	// AuthRequired is the legacy switch for SASL/PLAIN over TLS
+	AuthRequired     bool     `json:"authRequired"`
+	AuthType         string   `json:"authType"`
+	SaslUsername     string   `json:"saslUsername"`
+	SaslPassword     string   `json:"saslPassword"`
+	OIDCTokenURL     string   `json:"oidcTokenEndpoint"`
+	OIDCClientID     string   `json:"oidcClientID"`
+	OIDCClientSecret string   `json:"oidcClientSecret"`
+	OIDCScopes       []string `json:"oidcScopes"`
+	EnableTLS        bool     `json:"enableTLS"`
+	SkipVerify       bool     `json:"skipVerify"`
+	CACert           string   `json:"caCert"`
+	ClientCert       string   `json:"clientCert"`
+	ClientKey        string   `json:"clientKey"`
+	InitialOffset    int64    `json:"initialOffset"`
+	Version          sarama.KafkaVersion
+	BalanceStrategy  sarama.BalanceStrategy
+	MaxMessageBytes  int `json:"maxMessageBytes"`
+	// EnableIdempotence makes the producer write each message exactly once per partition
+	EnableIdempotence bool `json:"enableIdempotence"`
+}
+
+// ParseClientMetadata parses the shared Kafka metadata. defaultVersion is used
+// when the component metadata doesn't set a Kafka version.
+func ParseClientMetadata(properties map[string]string, defaultVersion sarama.KafkaVersion) (*ClientMetadata, error) {
+	meta := ClientMetadata{
+		InitialOffset:   sarama.OffsetNewest,
+		Version:         defaultVersion,
+		BalanceStrategy: sarama.BalanceStrategyRange,
+	}
+
+	if val, ok := properties[brokersKey]; ok && val != "" {
+		meta.Brokers = strings.Split(val, ",")
+	} else {
+		return nil, errors.New("kafka error: missing 'brokers' attribute")
+	}
+
+	if err := parseAuth(properties, &meta); err != nil {
+		return nil, err
+	}
+
+	if err := parseTLS(properties, &meta); err != nil {
+		return nil, err
+	}
+
+	if val, ok := properties[initialOffsetKey]; ok && val != "" {
+		switch strings.ToLower(val) {
+		case InitialOffsetNewest:
+			meta.InitialOffset = sarama.OffsetNewest
+		case InitialOffsetOldest:
+			meta.InitialOffset = sarama.OffsetOldest
+		default:
+			return nil, fmt.Errorf("kafka error: invalid initialOffset '%s', use '%s' or '%s'", val, InitialOffsetNewest, InitialOffsetOldest)
+		}
+	}
+
+	if val, ok := properties[versionKey]; ok && val != "" {
+		version, err := sarama.ParseKafkaVersion(val)
+		if err != nil {
+			return nil, fmt.Errorf("kafka error: invalid version '%s': %s", val, err)
+		}
+		meta.Version = version
+	}
+
+	if val, ok := properties[balanceStrategyKey]; ok && val != "" {
+		switch strings.ToLower(val) {
+		case BalanceStrategyRange:
+			meta.BalanceStrategy = sarama.BalanceStrategyRange
+		case BalanceStrategyRoundRobin:
+			meta.BalanceStrategy = sarama.BalanceStrategyRoundRobin
+		default:
+			return nil, fmt.Errorf("kafka error: invalid balanceStrategy '%s', use '%s' or '%s'", val, BalanceStrategyRange, BalanceStrategyRoundRobin)
+		}
+	}
+
+	if val, ok := properties[maxMessageBytesKey]; ok && val != "" {
+		maxBytes, err := strconv.Atoi(val)
+		if err != nil {
+			return nil, fmt.Errorf("kafka error: cannot parse maxMessageBytes: %s", err)
+		}
+
+		meta.MaxMessageBytes = maxBytes
+	}
+
+	if val, ok := properties[idempotenceKey]; ok && val != "" {
+		idempotence, err := strconv.ParseBool(val)
+		if err != nil {
+			return nil, fmt.Errorf("kafka error: cannot parse enableIdempotence: %s", err)
+		}
+		if idempotence && !meta.Version.IsAtLeast(sarama.V0_11_0_0) {
+			return nil, fmt.Errorf("kafka error: enableIdempotence requires version %s or later", sarama.V0_11_0_0)
+		}
+
+		meta.EnableIdempotence = idempotence
+	}
+
+	return &meta, nil
+}
+
+func parseAuth(properties map[string]string, meta *ClientMetadata) error {
+	if val, ok := properties[authTypeKey]; ok && val != "" {
+		meta.AuthType = strings.ToLower(val)
+	} else {
+		// Fall back to the legacy authRequired switch, which enables SASL/PLAIN over TLS
+		val, ok := properties[authRequiredKey]
+		if !ok {
+			return errors.New("kafka error: missing 'authRequired' attribute")
+		}
+		if val == "" {
+			return errors.New("kafka error: 'authRequired' attribute was empty")
+		}
+		authRequired, err := strconv.ParseBool(val)
+		if err != nil {
+			return errors.New("kafka error: invalid value for 'authRequired' attribute")
+		}
+		meta.AuthRequired = authRequired
+		meta.AuthType = AuthTypeNone
+		if authRequired {
+			meta.AuthType = AuthTypePlain
+			meta.EnableTLS = true
+		}
+	}
+
+	switch meta.AuthType {
+	case AuthTypeNone, AuthTypeMTLS:
+	case AuthTypePlain, AuthTypeSCRAMSHA256, AuthTypeSCRAMSHA512:
+		meta.AuthRequired = true
+		if val, ok := properties[saslUsernameKey]; ok && val != "" {
+			meta.SaslUsername = val
+		} else {
+			return errors.New("kafka error: missing SASL Username")
+		}
+
+		if val, ok := properties[saslPasswordKey]; ok && val != "" {
+			meta.SaslPassword = val
+		} else {
+			return errors.New("kafka error: missing SASL Password")
+		}
+	case AuthTypeOAuth:
+		meta.AuthRequired = true
+		meta.OIDCTokenURL = properties[oidcTokenURLKey]
+		meta.OIDCClientID = properties[oidcClientIDKey]
+		meta.OIDCClientSecret = properties[oidcClientSecretKey]
+		if meta.OIDCTokenURL == "" || meta.OIDCClientID == "" || meta.OIDCClientSecret == "" {
+			return fmt.Errorf("kafka error: '%s', '%s' and '%s' are required for oauth authentication", oidcTokenURLKey, oidcClientIDKey, oidcClientSecretKey)
+		}
+		if val, ok := properties[oidcScopesKey]; ok && val != "" {
+			meta.OIDCScopes = strings.Split(val, ",")
+		}
+	default:
+		return fmt.Errorf("kafka error: invalid authType '%s'", meta.AuthType)
+	}
+
+	return nil
+}
+
+func parseTLS(properties map[string]string, meta *ClientMetadata) error {
+	if val, ok := properties[enableTLSKey]; ok && val != "" {
+		enableTLS, err := strconv.ParseBool(val)
+		if err != nil {
+			return fmt.Errorf("kafka error: invalid value for 'enableTLS' attribute: %s", err)
+		}
+		meta.EnableTLS = enableTLS
+	}
+
+	if val, ok := properties[skipVerifyKey]; ok && val != "" {
+		skipVerify, err := strconv.ParseBool(val)
+		if err != nil {
+			return fmt.Errorf("kafka error: invalid value for 'skipVerify' attribute: %s", err)
+		}
+		meta.SkipVerify = skipVerify
+	}
+
+	for _, cert := range []struct {
+		key    string
+		target *string
+	}{
+		{caCertKey, &meta.CACert},
+		{clientCertKey, &meta.ClientCert},
+		{clientKeyKey, &meta.ClientKey},
+	} {
+		if val, ok := properties[cert.key]; ok && val != "" {
+			if block, _ := pem.Decode([]byte(val)); block == nil {
+				return fmt.Errorf("kafka error: invalid PEM in '%s' attribute", cert.key)
+			}
+			*cert.target = val
+			meta.EnableTLS = true
+		}
+	}
+
+	if (meta.ClientCert == "") != (meta.ClientKey == "") {
+		return errors.New("kafka error: 'clientCert' and 'clientKey' must be set together")
+	}
+	if meta.AuthType == AuthTypeMTLS && meta.ClientCert == "" {
+		return errors.New("kafka error: 'clientCert' and 'clientKey' are required for mtls authentication")
+	}
+
+	return nil
+}
+
+// UpdateConfig applies the metadata to a sarama client configuration.
+func (m *ClientMetadata) UpdateConfig(config *sarama.Config) error {
+	config.Version = m.Version
+	config.Consumer.Offsets.Initial = m.InitialOffset
+	config.Consumer.Group.Rebalance.Strategy = m.BalanceStrategy
+
+	if m.MaxMessageBytes > 0 {
+		config.Producer.MaxMessageBytes = m.MaxMessageBytes
+	}
+
+	if m.EnableIdempotence {
+		// The idempotent producer needs acks from all replicas and a single in-flight request
+		config.Producer.Idempotent = true
+		config.Producer.RequiredAcks = sarama.WaitForAll
+		config.Net.MaxOpenRequests = 1
+		if config.Producer.Retry.Max == 0 {
+			config.Producer.Retry.Max = 5
+		}
+	}
+
+	switch m.AuthType {
+	case AuthTypePlain:
+		updatePasswordAuthInfo(config, m.SaslUsername, m.SaslPassword, sarama.SASLTypePlaintext)
+	case AuthTypeSCRAMSHA256:
+		updatePasswordAuthInfo(config, m.SaslUsername, m.SaslPassword, sarama.SASLTypeSCRAMSHA256)
+		config.Net.SASL.SCRAMClientGeneratorFunc = func() sarama.SCRAMClient {
+			return &scramClient{HashGeneratorFcn: sha256HashGenerator}
+		}
+	case AuthTypeSCRAMSHA512:
+		updatePasswordAuthInfo(config, m.SaslUsername, m.SaslPassword, sarama.SASLTypeSCRAMSHA512)
+		config.Net.SASL.SCRAMClientGeneratorFunc = func() sarama.SCRAMClient {
+			return &scramClient{HashGeneratorFcn: sha512HashGenerator}
+		}
+	case AuthTypeOAuth:
+		config.Net.SASL.Enable = true
+		config.Net.SASL.Mechanism = sarama.SASLTypeOAuth
+		config.Net.SASL.TokenProvider = newOAuthTokenProvider(m.OIDCTokenURL, m.OIDCClientID, m.OIDCClientSecret, m.OIDCScopes)
+	}
+
+	if m.EnableTLS {
+		tlsConfig, err := m.tlsConfig()
+		if err != nil {
+			return err
+		}
+		config.Net.TLS.Enable = true
+		config.Net.TLS.Config = tlsConfig
+	}
+
+	return nil
+}
+
+func (m *ClientMetadata) tlsConfig() (*tls.Config, error) {
+	// nolint: gosec
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: m.SkipVerify,
+	}
+
+	if m.CACert != "" {
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if ok := tlsConfig.RootCAs.AppendCertsFromPEM([]byte(m.CACert)); !ok {
+			return nil, errors.New("kafka error: unable to load ca certificate")
+		}
+	}
+
+	if m.ClientCert != "" && m.ClientKey != "" {
+		cert, err := tls.X509KeyPair([]byte(m.ClientCert), []byte(m.ClientKey))
+		if err != nil {
+			return nil, fmt.Errorf("kafka error: unable to load client certificate and key pair: %s", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
+
+func updatePasswordAuthInfo(config *sarama.Config, saslUsername, saslPassword string, mechanism sarama.SASLMechanism) {
+	config.Net.SASL.Enable = true
+	config.Net.SASL.User = saslUsername
+	config.Net.SASL.Password = saslPassword
+	config.Net.SASL.Mechanism = mechanism
+}
+
+// HeadersToMetadata maps Kafka record headers to message metadata.
+func HeadersToMetadata(headers []*sarama.RecordHeader) map[string]string {
+	if len(headers) == 0 {
+		return nil
+	}
+
+	metadata := make(map[string]string, len(headers))
+	for _, header := range headers {
+		if header != nil {
+			metadata[string(header.Key)] = string(header.Value)
+		}
+	}
+
+	return metadata
+}
@@ -0,0 +1,198 @@
+// ------------------------------------------------------------
+// Copyright (c) Microsoft Corporation and Dapr Contributors.
+// Licensed under the MIT License.
+// ------------------------------------------------------------
+
+package kafka
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func generateCertificate(t *testing.T) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "kafka-test"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	cert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPem := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer})
+
+	return string(cert), string(keyPem)
+}
+
+func TestParseClientMetadata(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		meta, err := ParseClientMetadata(map[string]string{"brokers": "a:9092,b:9092", "authRequired": "false"}, sarama.V1_0_0_0)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"a:9092", "b:9092"}, meta.Brokers)
+		assert.Equal(t, AuthTypeNone, meta.AuthType)
+		assert.False(t, meta.EnableTLS)
+		assert.Equal(t, sarama.OffsetNewest, meta.InitialOffset)
+		assert.Equal(t, sarama.V1_0_0_0, meta.Version)
+		assert.Equal(t, sarama.BalanceStrategyRange, meta.BalanceStrategy)
+	})
+
+	t.Run("legacy authRequired enables SASL/PLAIN over TLS", func(t *testing.T) {
+		meta, err := ParseClientMetadata(map[string]string{
+			"brokers":      "a",
+			"authRequired": "true",
+			"saslUsername": "user",
+			"saslPassword": "pass",
+		}, sarama.V2_0_0_0)
+		require.NoError(t, err)
+		assert.Equal(t, AuthTypePlain, meta.AuthType)
+		assert.True(t, meta.AuthRequired)
+		assert.True(t, meta.EnableTLS)
+	})
+
+	t.Run("consumer settings", func(t *testing.T) {
+		meta, err := ParseClientMetadata(map[string]string{
+			"brokers":         "a",
+			"authType":        "none",
+			"initialOffset":   "oldest",
+			"version":         "2.3.0",
+			"balanceStrategy": "roundrobin",
+			"maxMessageBytes": "2048",
+		}, sarama.V2_0_0_0)
+		require.NoError(t, err)
+		assert.Equal(t, sarama.OffsetOldest, meta.InitialOffset)
+		assert.Equal(t, sarama.V2_3_0_0, meta.Version)
+		assert.Equal(t, sarama.BalanceStrategyRoundRobin, meta.BalanceStrategy)
+		assert.Equal(t, 2048, meta.MaxMessageBytes)
+	})
+
+	t.Run("invalid values", func(t *testing.T) {
+		for name, props := range map[string]map[string]string{
+			"missing brokers":  {"authType": "none"},
+			"missing auth":     {"brokers": "a"},
+			"unknown authType": {"brokers": "a", "authType": "kerberos"},
+			"scram without password": {
+				"brokers": "a", "authType": "scram-sha-512", "saslUsername": "user",
+			},
+			"oauth without client": {
+				"brokers": "a", "authType": "oauth", "oidcTokenEndpoint": "https://idp/token",
+			},
+			"mtls without certificate":  {"brokers": "a", "authType": "mtls"},
+			"invalid caCert":            {"brokers": "a", "authType": "none", "caCert": "not a pem"},
+			"invalid initialOffset":     {"brokers": "a", "authType": "none", "initialOffset": "latest"},
+			"invalid version":           {"brokers": "a", "authType": "none", "version": "x.y"},
+			"invalid balanceStrategy":   {"brokers": "a", "authType": "none", "balanceStrategy": "random"},
+			"invalid enableIdempotence": {"brokers": "a", "authType": "none", "enableIdempotence": "maybe"},
+			"idempotence on old version": {
+				"brokers": "a", "authType": "none", "enableIdempotence": "true", "version": "0.10.2.0",
+			},
+		} {
+			_, err := ParseClientMetadata(props, sarama.V2_0_0_0)
+			assert.Error(t, err, name)
+		}
+	})
+}
+
+func TestUpdateConfig(t *testing.T) {
+	t.Run("scram", func(t *testing.T) {
+		meta, err := ParseClientMetadata(map[string]string{
+			"brokers":      "a",
+			"authType":     "scram-sha-256",
+			"saslUsername": "user",
+			"saslPassword": "pass",
+		}, sarama.V2_0_0_0)
+		require.NoError(t, err)
+
+		config := sarama.NewConfig()
+		require.NoError(t, meta.UpdateConfig(config))
+		assert.True(t, config.Net.SASL.Enable)
+		assert.Equal(t, sarama.SASLMechanism(sarama.SASLTypeSCRAMSHA256), config.Net.SASL.Mechanism)
+		assert.NotNil(t, config.Net.SASL.SCRAMClientGeneratorFunc())
+		assert.False(t, config.Net.TLS.Enable)
+		assert.NoError(t, config.Validate())
+	})
+
+	t.Run("oauth", func(t *testing.T) {
+		meta, err := ParseClientMetadata(map[string]string{
+			"brokers":           "a",
+			"authType":          "oauth",
+			"oidcTokenEndpoint": "https://idp/token",
+			"oidcClientID":      "client",
+			"oidcClientSecret":  "secret",
+			"oidcScopes":        "kafka,openid",
+			"enableTLS":         "true",
+		}, sarama.V2_0_0_0)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"kafka", "openid"}, meta.OIDCScopes)
+
+		config := sarama.NewConfig()
+		require.NoError(t, meta.UpdateConfig(config))
+		assert.Equal(t, sarama.SASLMechanism(sarama.SASLTypeOAuth), config.Net.SASL.Mechanism)
+		assert.NotNil(t, config.Net.SASL.TokenProvider)
+		assert.True(t, config.Net.TLS.Enable)
+		assert.NoError(t, config.Validate())
+	})
+
+	t.Run("idempotent producer", func(t *testing.T) {
+		meta, err := ParseClientMetadata(map[string]string{
+			"brokers":           "a",
+			"authType":          "none",
+			"enableIdempotence": "true",
+		}, sarama.V2_0_0_0)
+		require.NoError(t, err)
+		assert.True(t, meta.EnableIdempotence)
+
+		config := sarama.NewConfig()
+		config.Producer.Return.Successes = true
+		require.NoError(t, meta.UpdateConfig(config))
+		assert.True(t, config.Producer.Idempotent)
+		assert.Equal(t, sarama.WaitForAll, config.Producer.RequiredAcks)
+		assert.Equal(t, 1, config.Net.MaxOpenRequests)
+		assert.NoError(t, config.Validate())
+	})
+
+	t.Run("mtls", func(t *testing.T) {
+		cert, key := generateCertificate(t)
+		meta, err := ParseClientMetadata(map[string]string{
+			"brokers":    "a",
+			"authType":   "mtls",
+			"caCert":     cert,
+			"clientCert": cert,
+			"clientKey":  key,
+		}, sarama.V2_0_0_0)
+		require.NoError(t, err)
+
+		config := sarama.NewConfig()
+		require.NoError(t, meta.UpdateConfig(config))
+		assert.False(t, config.Net.SASL.Enable)
+		assert.True(t, config.Net.TLS.Enable)
+		assert.Len(t, config.Net.TLS.Config.Certificates, 1)
+		assert.NotNil(t, config.Net.TLS.Config.RootCAs)
+	})
+}
+
+func TestHeadersToMetadata(t *testing.T) {
+	assert.Nil(t, HeadersToMetadata(nil))
+	assert.Equal(t, map[string]string{"a": "1", "b": "2"}, HeadersToMetadata([]*sarama.RecordHeader{
+		{Key: []byte("a"), Value: []byte("1")},
+		{Key: []byte("b"), Value: []byte("2")},
+		nil,
+	}))
+}
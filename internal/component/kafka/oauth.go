@@ -0,0 +1,50 @@
+// ------------------------------------------------------------
+// Copyright (c) Microsoft Corporation and Dapr Contributors.
+// Licensed under the MIT License.
+// ------------------------------------------------------------
+
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/Shopify/sarama"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
+)
+
+// oauthTokenProvider implements sarama.AccessTokenProvider with the OAuth2
+// client credentials flow. Tokens are cached until they expire.
+type oauthTokenProvider struct {
+	config *clientcredentials.Config
+	token  *oauth2.Token
+	lock   sync.Mutex
+}
+
+func newOAuthTokenProvider(tokenEndpoint, clientID, clientSecret string, scopes []string) *oauthTokenProvider {
+	return &oauthTokenProvider{
+		config: &clientcredentials.Config{
+			ClientID:     clientID,
+			ClientSecret: clientSecret,
+			TokenURL:     tokenEndpoint,
+			Scopes:       scopes,
+		},
+	}
+}
+
+func (p *oauthTokenProvider) Token() (*sarama.AccessToken, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if !p.token.Valid() {
+		token, err := p.config.Token(context.Background())
+		if err != nil {
+			return nil, fmt.Errorf("kafka error: failed to get oauth token: %s", err)
+		}
+		p.token = token
+	}
+
+	return &sarama.AccessToken{Token: p.token.AccessToken}, nil
+}
@@ -0,0 +1,309 @@
+// ------------------------------------------------------------
+// Copyright (c) Microsoft Corporation and Dapr Contributors.
+// Licensed under the MIT License.
+// ------------------------------------------------------------
+
+package mqtt
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dapr/dapr/pkg/logger"
+	"github.com/eclipse/paho.golang/autopaho"
+	"github.com/eclipse/paho.golang/paho"
+)
+
+const (
+	// ProtocolV3 is MQTT 3.1.1, served by the paho.mqtt.golang client
+	ProtocolV3 = "3.1.1"
+	// ProtocolV5 is MQTT 5, served by the paho.golang client
+	ProtocolV5 = "5"
+
+	sharedSubscriptionPrefix = "$share/"
+	defaultKeepAlive         = 30
+	defaultConnectTimeout    = 10 * time.Second
+	defaultRetryDelay        = 5 * time.Second
+	// sessionExpiryNever keeps the session of a client that disconnected until it connects again
+	sessionExpiryNever = 0xFFFFFFFF
+)
+
+// ParseProtocolVersion parses the protocolVersion metadata, accepting 3, 3.1.1, 4, 5 and 5.0.
+func ParseProtocolVersion(val string) (string, error) {
+	switch strings.TrimSpace(val) {
+	case "", "3", "3.1.1", "4":
+		return ProtocolV3, nil
+	case "5", "5.0":
+		return ProtocolV5, nil
+	default:
+		return "", fmt.Errorf("unsupported protocolVersion %s, use 3.1.1 or 5", val)
+	}
+}
+
+// SharedSubscription returns the shared subscription of group to topic, so
+// that each message is delivered to only one of the clients of the group.
+func SharedSubscription(group, topic string) string {
+	return sharedSubscriptionPrefix + group + "/" + topic
+}
+
+// UserProperties converts metadata to MQTT 5 user properties.
+func UserProperties(metadata map[string]string) paho.UserProperties {
+	if len(metadata) == 0 {
+		return nil
+	}
+
+	keys := make([]string, 0, len(metadata))
+	for k := range metadata {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	props := make(paho.UserProperties, 0, len(keys))
+	for _, k := range keys {
+		props = append(props, paho.UserProperty{Key: k, Value: metadata[k]})
+	}
+
+	return props
+}
+
+// MetadataFromUserProperties converts MQTT 5 user properties to metadata.
+// When a key is repeated the last value wins.
+func MetadataFromUserProperties(props paho.UserProperties) map[string]string {
+	if len(props) == 0 {
+		return nil
+	}
+
+	metadata := make(map[string]string, len(props))
+	for _, p := range props {
+		metadata[p.Key] = p.Value
+	}
+
+	return metadata
+}
+
+// ReasonCodeError is returned when the broker rejects a request with an MQTT 5 reason code.
+type ReasonCodeError struct {
+	Operation string
+	Code      byte
+	Reason    string
+}
+
+func (e *ReasonCodeError) Error() string {
+	if e.Reason == "" {
+		return fmt.Sprintf("mqtt %s failed with reason code 0x%02x", e.Operation, e.Code)
+	}
+
+	return fmt.Sprintf("mqtt %s failed with reason code 0x%02x: %s", e.Operation, e.Code, e.Reason)
+}
+
+// Retriable returns false for reason codes that will not change by retrying
+// the same request, such as not authorized or payload format invalid.
+func (e *ReasonCodeError) Retriable() bool {
+	switch e.Code {
+	case 0x87, 0x8F, 0x90, 0x95, 0x99, 0x9E, 0xA1, 0xA2:
+		// not authorized, topic filter invalid, topic name invalid, packet too large,
+		// payload format invalid, shared subscriptions / subscription ids / wildcards not supported
+		return false
+	default:
+		return true
+	}
+}
+
+// V5Options configures an MQTT 5 client.
+type V5Options struct {
+	URL      *url.URL
+	ClientID string
+	// CleanStart discards the session of the client id when connecting. When it is
+	// false, the session is kept after the client disconnects, as with cleanSession
+	// in MQTT 3.1.1, so the client id must be stable across connections.
+	CleanStart bool
+	TLSConfig  *tls.Config
+}
+
+// V5Client is an MQTT 5 client that reconnects automatically and restores
+// its subscriptions after a reconnection.
+type V5Client struct {
+	conn   *autopaho.ConnectionManager
+	router *paho.StandardRouter
+	logger logger.Logger
+
+	subscriptions map[string]paho.SubscribeOptions
+	lock          sync.Mutex
+}
+
+// ConnectV5 connects to the broker and waits for the connection to be established.
+func ConnectV5(ctx context.Context, opts V5Options, logger logger.Logger) (*V5Client, error) {
+	c := &V5Client{
+		router:        paho.NewStandardRouter(),
+		logger:        logger,
+		subscriptions: map[string]paho.SubscribeOptions{},
+	}
+
+	brokerURL := *opts.URL
+	brokerURL.User = nil
+	cfg := autopaho.ClientConfig{
+		BrokerUrls:        []*url.URL{&brokerURL},
+		TlsCfg:            opts.TLSConfig,
+		KeepAlive:         defaultKeepAlive,
+		ConnectRetryDelay: defaultRetryDelay,
+		ConnectTimeout:    defaultConnectTimeout,
+		OnConnectionUp:    c.onConnectionUp,
+		OnConnectError: func(err error) {
+			logger.Warnf("mqtt connection error: %v", err)
+		},
+		ClientConfig: paho.ClientConfig{
+			ClientID: opts.ClientID,
+			Router:   c.router,
+			OnServerDisconnect: func(d *paho.Disconnect) {
+				reason := ""
+				if d.Properties != nil {
+					reason = d.Properties.ReasonString
+				}
+				logger.Warnf("mqtt broker disconnected client %s with reason code 0x%02x %s", opts.ClientID, d.ReasonCode, reason)
+			},
+			OnClientError: func(err error) {
+				logger.Warnf("mqtt client %s error: %v", opts.ClientID, err)
+			},
+		},
+	}
+	if opts.URL.User != nil {
+		password, _ := opts.URL.User.Password()
+		cfg.SetUsernamePassword(opts.URL.User.Username(), []byte(password))
+	}
+	cfg.SetConnectPacketConfigurator(func(cp *paho.Connect) *paho.Connect {
+		return configureConnect(cp, opts)
+	})
+
+	conn, err := autopaho.NewConnection(context.Background(), cfg)
+	if err != nil {
+		return nil, err
+	}
+	c.conn = conn
+
+	connectCtx, cancel := context.WithTimeout(ctx, defaultConnectTimeout)
+	defer cancel()
+	if err := conn.AwaitConnection(connectCtx); err != nil {
+		_ = conn.Disconnect(context.Background())
+
+		return nil, fmt.Errorf("mqtt error connecting to %s: %v", brokerURL.Host, err)
+	}
+
+	return c, nil
+}
+
+// configureConnect sets the session of the connect packet. MQTT 5 ends a session
+// when the client disconnects unless it has a session expiry interval.
+func configureConnect(cp *paho.Connect, opts V5Options) *paho.Connect {
+	cp.CleanStart = opts.CleanStart
+	if !opts.CleanStart {
+		expiry := uint32(sessionExpiryNever)
+		if cp.Properties == nil {
+			cp.Properties = &paho.ConnectProperties{}
+		}
+		cp.Properties.SessionExpiryInterval = &expiry
+	}
+
+	return cp
+}
+
+func (c *V5Client) onConnectionUp(conn *autopaho.ConnectionManager, _ *paho.Connack) {
+	c.lock.Lock()
+	subscriptions := make(map[string]paho.SubscribeOptions, len(c.subscriptions))
+	for topic, opts := range c.subscriptions {
+		subscriptions[topic] = opts
+	}
+	c.lock.Unlock()
+
+	if len(subscriptions) == 0 {
+		return
+	}
+
+	if err := c.subscribe(context.Background(), subscriptions); err != nil {
+		c.logger.Errorf("mqtt error restoring subscriptions: %v", err)
+	}
+}
+
+// Publish publishes payload to topic, with user properties from metadata.
+// A non-zero expiry sets the message expiry interval.
+func (c *V5Client) Publish(ctx context.Context, topic string, qos byte, retain bool, payload []byte, metadata map[string]string, expiry time.Duration) error {
+	p := &paho.Publish{
+		Topic:   topic,
+		QoS:     qos,
+		Retain:  retain,
+		Payload: payload,
+		Properties: &paho.PublishProperties{
+			User: UserProperties(metadata),
+		},
+	}
+	if expiry > 0 {
+		seconds := uint32((expiry + time.Second - 1) / time.Second)
+		p.Properties.MessageExpiry = &seconds
+	}
+
+	res, err := c.conn.Publish(ctx, p)
+	if res != nil && res.ReasonCode >= 0x80 {
+		return &ReasonCodeError{Operation: "publish", Code: res.ReasonCode, Reason: publishReason(res)}
+	}
+
+	return err
+}
+
+func publishReason(res *paho.PublishResponse) string {
+	if res.Properties != nil {
+		return res.Properties.ReasonString
+	}
+
+	return ""
+}
+
+// Subscribe subscribes to a topic filter, possibly shared, and routes the
+// messages published on the topic to handler. Handlers are called one at a
+// time and a message is acknowledged once its handler returns.
+func (c *V5Client) Subscribe(ctx context.Context, filter, topic string, qos byte, handler func(*paho.Publish)) error {
+	c.router.RegisterHandler(topic, handler)
+
+	subscriptions := map[string]paho.SubscribeOptions{filter: {QoS: qos}}
+	if err := c.subscribe(ctx, subscriptions); err != nil {
+		c.router.UnregisterHandler(topic)
+
+		return err
+	}
+
+	c.lock.Lock()
+	c.subscriptions[filter] = subscriptions[filter]
+	c.lock.Unlock()
+
+	return nil
+}
+
+func (c *V5Client) subscribe(ctx context.Context, subscriptions map[string]paho.SubscribeOptions) error {
+	res, err := c.conn.Subscribe(ctx, &paho.Subscribe{Subscriptions: subscriptions})
+	if res != nil {
+		for _, code := range res.Reasons {
+			if code >= 0x80 {
+				reason := ""
+				if res.Properties != nil {
+					reason = res.Properties.ReasonString
+				}
+
+				return &ReasonCodeError{Operation: "subscribe", Code: code, Reason: reason}
+			}
+		}
+	}
+
+	return err
+}
+
+// Close disconnects from the broker.
+func (c *V5Client) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultConnectTimeout)
+	defer cancel()
+
+	return c.conn.Disconnect(ctx)
+}
@@ -0,0 +1,68 @@
+// ------------------------------------------------------------
+// Copyright (c) Microsoft Corporation and Dapr Contributors.
+// Licensed under the MIT License.
+// ------------------------------------------------------------
+
+package mqtt
+
+import (
+	"testing"
+
+	"github.com/eclipse/paho.golang/paho"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseProtocolVersion(t *testing.T) {
+	for _, val := range []string{"", "3", "3.1.1", "4"} {
+		v, err := ParseProtocolVersion(val)
+		assert.NoError(t, err)
+		assert.Equal(t, ProtocolV3, v)
+	}
+	for _, val := range []string{"5", "5.0"} {
+		v, err := ParseProtocolVersion(val)
+		assert.NoError(t, err)
+		assert.Equal(t, ProtocolV5, v)
+	}
+	_, err := ParseProtocolVersion("3.1")
+	assert.Error(t, err)
+}
+
+func TestUserProperties(t *testing.T) {
+	assert.Nil(t, UserProperties(nil))
+	assert.Nil(t, MetadataFromUserProperties(nil))
+
+	props := UserProperties(map[string]string{"b": "2", "a": "1"})
+	assert.Equal(t, paho.UserProperties{{Key: "a", Value: "1"}, {Key: "b", Value: "2"}}, props)
+	assert.Equal(t, map[string]string{"a": "1", "b": "2"}, MetadataFromUserProperties(props))
+
+	props = append(props, paho.UserProperty{Key: "a", Value: "3"})
+	assert.Equal(t, "3", MetadataFromUserProperties(props)["a"])
+}
+
+func TestSharedSubscription(t *testing.T) {
+	assert.Equal(t, "$share/orders-service/orders/created", SharedSubscription("orders-service", "orders/created"))
+}
+
+func TestConfigureConnect(t *testing.T) {
+	cp := configureConnect(&paho.Connect{ClientID: "orders"}, V5Options{CleanStart: true})
+	assert.True(t, cp.CleanStart)
+	assert.Nil(t, cp.Properties)
+
+	// The session outlives the connection, as with cleanSession false in MQTT 3.1.1
+	cp = configureConnect(&paho.Connect{ClientID: "orders"}, V5Options{CleanStart: false})
+	assert.False(t, cp.CleanStart)
+	require.NotNil(t, cp.Properties)
+	require.NotNil(t, cp.Properties.SessionExpiryInterval)
+	assert.Equal(t, uint32(0xFFFFFFFF), *cp.Properties.SessionExpiryInterval)
+}
+
+func TestReasonCodeError(t *testing.T) {
+	err := &ReasonCodeError{Operation: "publish", Code: 0x87, Reason: "not allowed"}
+	assert.Equal(t, "mqtt publish failed with reason code 0x87: not allowed", err.Error())
+	assert.False(t, err.Retriable())
+
+	err = &ReasonCodeError{Operation: "subscribe", Code: 0x97}
+	assert.Equal(t, "mqtt subscribe failed with reason code 0x97", err.Error())
+	assert.True(t, err.Retriable())
+}
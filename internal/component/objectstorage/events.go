@@ -0,0 +1,313 @@
+// ------------------------------------------------------------
+// Copyright (c) Microsoft Corporation and Dapr Contributors.
+// Licensed under the MIT License.
+// ------------------------------------------------------------
+
+package objectstorage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/dapr/components-contrib/bindings"
+	"github.com/dapr/components-contrib/internal/component/statestore"
+	"github.com/dapr/components-contrib/state"
+	"github.com/dapr/dapr/pkg/logger"
+)
+
+const (
+	// EventCreated is the type of the events of created or overwritten objects.
+	EventCreated = "created"
+	// EventDeleted is the type of the events of deleted objects.
+	EventDeleted = "deleted"
+
+	// keys from component's metadata of input bindings
+	// PollIntervalKey sets how often buckets without notifications are polled, see Poller
+	PollIntervalKey = "pollInterval"
+	// PollCheckpointStoreKey selects the state store that the poller persists its
+	// checkpoint in, configured with the metadata prefixed with `pollCheckpointStore.`
+	PollCheckpointStoreKey = "pollCheckpointStore"
+	// PollCheckpointKeyKey is the key of the checkpoint in the state store, the
+	// name of the binding by default
+	PollCheckpointKeyKey = "pollCheckpointKey"
+
+	// keys from read response's metadata
+	EventTypeKey = "eventType"
+	BucketKey    = "bucket"
+	KeyKey       = "key"
+
+	DefaultPollInterval = 30 * time.Second
+)
+
+// Event is the event of an object emitted by the input bindings of object storages,
+// whichever the notifications of the provider are.
+type Event struct {
+	EventType string    `json:"eventType"`
+	Bucket    string    `json:"bucket"`
+	Key       string    `json:"key"`
+	Size      int64     `json:"size"`
+	ETag      string    `json:"etag,omitempty"`
+	EventTime time.Time `json:"eventTime"`
+}
+
+// ReadResponse returns the response of an input binding for the event. The
+// data is the JSON event and the metadata has its type, bucket and key.
+func (e Event) ReadResponse() (*bindings.ReadResponse, error) {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return nil, err
+	}
+
+	return &bindings.ReadResponse{
+		Data: b,
+		Metadata: map[string]string{
+			EventTypeKey: e.EventType,
+			BucketKey:    e.Bucket,
+			KeyKey:       e.Key,
+		},
+	}, nil
+}
+
+// Handle triggers handler with the response of the event.
+func (e Event) Handle(handler func(*bindings.ReadResponse) error) error {
+	resp, err := e.ReadResponse()
+	if err != nil {
+		return err
+	}
+
+	return handler(resp)
+}
+
+// ParsePollInterval reads how often the bucket is listed when the input
+// binding polls for events.
+func ParsePollInterval(metadata map[string]string) (time.Duration, error) {
+	if val, ok := metadata[PollIntervalKey]; ok && val != "" {
+		interval, err := time.ParseDuration(val)
+		if err != nil || interval <= 0 {
+			return 0, fmt.Errorf("%s must be a positive duration: %s", PollIntervalKey, val)
+		}
+
+		return interval, nil
+	}
+
+	return DefaultPollInterval, nil
+}
+
+// PollCheckpoint persists the checkpoint of a Poller in a state store.
+type PollCheckpoint struct {
+	store state.Store
+	key   string
+}
+
+// NewPollCheckpoint creates the state store selected by pollCheckpointStore,
+// which persists the checkpoint under the pollCheckpointKey or the name of the
+// binding. It returns nil when no store is selected.
+func NewPollCheckpoint(metadata bindings.Metadata, logger logger.Logger) (*PollCheckpoint, error) {
+	val, ok := metadata.Properties[PollCheckpointStoreKey]
+	if !ok || val == "" {
+		return nil, nil
+	}
+	// The memory store would lose the checkpoint with the binding
+	if val == statestore.MemoryStore {
+		return nil, fmt.Errorf("%s %s is not supported, as it is not persisted", PollCheckpointStoreKey, val)
+	}
+
+	key := metadata.Name
+	if val, ok := metadata.Properties[PollCheckpointKeyKey]; ok && val != "" {
+		key = val
+	}
+	if key == "" {
+		return nil, fmt.Errorf("%s not set, which is required without the binding name", PollCheckpointKeyKey)
+	}
+
+	store, err := statestore.New(metadata.Properties, PollCheckpointStoreKey, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PollCheckpoint{store: store, key: key}, nil
+}
+
+// load returns the persisted checkpoint, nil if there is none.
+func (c *PollCheckpoint) load() (map[string]Object, error) {
+	res, err := c.store.Get(&state.GetRequest{Key: c.key})
+	if err != nil {
+		return nil, err
+	}
+	if res == nil || len(res.Data) == 0 {
+		return nil, nil
+	}
+
+	checkpoint := map[string]Object{}
+	if err := json.Unmarshal(res.Data, &checkpoint); err != nil {
+		return nil, err
+	}
+
+	return checkpoint, nil
+}
+
+func (c *PollCheckpoint) save(checkpoint map[string]Object) error {
+	b, err := json.Marshal(checkpoint)
+	if err != nil {
+		return err
+	}
+
+	return c.store.Set(&state.SetRequest{Key: c.key, Value: b})
+}
+
+// ListFunc returns a page of the objects of a bucket.
+type ListFunc func(ctx context.Context, r ListRequest) (ListResponse, error)
+
+// Poller emits the events of a bucket without notifications, by comparing its
+// listings with a checkpoint of the objects found by the previous listing.
+//
+// The first listing only initializes the checkpoint, unless it was persisted
+// with a PollCheckpoint, in which case the objects changed while the binding
+// was not running are emitted too. Polling is meant for a single replica, as
+// each replica polling the same bucket emits every event. Notifications should
+// be used otherwise.
+type Poller struct {
+	bucket      string
+	interval    time.Duration
+	list        ListFunc
+	persistence *PollCheckpoint
+	logger      logger.Logger
+
+	checkpoint map[string]Object
+}
+
+// NewPoller returns a poller listing the objects of bucket with list. The
+// checkpoint is kept in memory only when persistence is nil.
+func NewPoller(bucket string, interval time.Duration, list ListFunc, persistence *PollCheckpoint, logger logger.Logger) *Poller {
+	return &Poller{
+		bucket:      bucket,
+		interval:    interval,
+		list:        list,
+		persistence: persistence,
+		logger:      logger,
+	}
+}
+
+// Run polls the bucket until ctx is done.
+func (p *Poller) Run(ctx context.Context, handler func(*bindings.ReadResponse) error) error {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := p.Poll(ctx, handler); err != nil {
+			p.logger.Errorf("error polling bucket %s: %s", p.bucket, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Poll lists the bucket and triggers handler with the events of the objects
+// created, overwritten or deleted since the previous listing. The objects whose
+// events fail to be handled are left out of the checkpoint so that they are
+// retried by the next listing.
+func (p *Poller) Poll(ctx context.Context, handler func(*bindings.ReadResponse) error) error {
+	if p.checkpoint == nil && p.persistence != nil {
+		checkpoint, err := p.persistence.load()
+		if err != nil {
+			return fmt.Errorf("failed to load the checkpoint: %s", err)
+		}
+		p.checkpoint = checkpoint
+	}
+
+	objects := map[string]Object{}
+	r := ListRequest{MaxResults: DefaultMaxResults}
+	for {
+		resp, err := p.list(ctx, r)
+		if err != nil {
+			return err
+		}
+		for _, o := range resp.Objects {
+			objects[o.Key] = o
+		}
+		if resp.NextMarker == "" {
+			break
+		}
+		r.Marker = resp.NextMarker
+	}
+
+	if p.checkpoint == nil {
+		p.checkpoint = objects
+
+		return p.saveCheckpoint()
+	}
+
+	now := time.Now().UTC()
+	checkpoint := make(map[string]Object, len(objects))
+	for key, o := range objects {
+		previous, found := p.checkpoint[key]
+		if found && !changed(previous, o) {
+			checkpoint[key] = o
+
+			continue
+		}
+
+		err := p.handle(handler, EventCreated, o, o.LastModified)
+		if err == nil {
+			checkpoint[key] = o
+		} else if found {
+			// the previous version is kept so that the new one is still changed
+			checkpoint[key] = previous
+		}
+	}
+	for key, o := range p.checkpoint {
+		if _, found := objects[key]; found {
+			continue
+		}
+
+		if err := p.handle(handler, EventDeleted, o, now); err != nil {
+			checkpoint[key] = o
+		}
+	}
+	p.checkpoint = checkpoint
+
+	return p.saveCheckpoint()
+}
+
+func (p *Poller) saveCheckpoint() error {
+	if p.persistence == nil {
+		return nil
+	}
+	if err := p.persistence.save(p.checkpoint); err != nil {
+		return fmt.Errorf("failed to persist the checkpoint: %s", err)
+	}
+
+	return nil
+}
+
+func (p *Poller) handle(handler func(*bindings.ReadResponse) error, eventType string, o Object, eventTime time.Time) error {
+	err := Event{
+		EventType: eventType,
+		Bucket:    p.bucket,
+		Key:       o.Key,
+		Size:      o.Size,
+		ETag:      o.ETag,
+		EventTime: eventTime,
+	}.Handle(handler)
+	if err != nil {
+		p.logger.Errorf("error handling %s event of %s: %s", eventType, o.Key, err)
+	}
+
+	return err
+}
+
+// changed returns whether an object was overwritten, compared with its ETag
+// or, when there is none, its size and modification time.
+func changed(previous, current Object) bool {
+	if previous.ETag != "" || current.ETag != "" {
+		return previous.ETag != current.ETag
+	}
+
+	return previous.Size != current.Size || !previous.LastModified.Equal(current.LastModified)
+}
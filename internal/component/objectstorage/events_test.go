@@ -0,0 +1,197 @@
+// ------------------------------------------------------------
+// Copyright (c) Microsoft Corporation and Dapr Contributors.
+// Licensed under the MIT License.
+// ------------------------------------------------------------
+
+package objectstorage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/dapr/components-contrib/bindings"
+	"github.com/dapr/components-contrib/internal/component/statestore"
+	"github.com/dapr/dapr/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeBucket lists its objects by pages of two objects.
+type fakeBucket map[string]Object
+
+func (b fakeBucket) list(ctx context.Context, r ListRequest) (ListResponse, error) {
+	keys := make([]string, 0, len(b))
+	for k := range b {
+		if k > r.Marker {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	resp := ListResponse{}
+	for i, k := range keys {
+		if i == 2 {
+			resp.NextMarker = keys[1]
+
+			break
+		}
+		resp.Objects = append(resp.Objects, b[k])
+	}
+
+	return resp, nil
+}
+
+func (b fakeBucket) put(key, etag string) {
+	b[key] = Object{Key: key, Size: int64(len(etag)), ETag: etag}
+}
+
+// recorder records the events it handles, and fails for the keys in failures.
+type recorder struct {
+	events   []string
+	failures map[string]bool
+}
+
+func (r *recorder) handle(resp *bindings.ReadResponse) error {
+	var e Event
+	if err := json.Unmarshal(resp.Data, &e); err != nil {
+		return err
+	}
+	if e.EventType != resp.Metadata[EventTypeKey] || e.Bucket != resp.Metadata[BucketKey] || e.Key != resp.Metadata[KeyKey] {
+		return errors.New("metadata does not match event")
+	}
+	if r.failures[e.Key] {
+		return errors.New("handler failed")
+	}
+	r.events = append(r.events, e.EventType+" "+e.Key)
+
+	return nil
+}
+
+func (r *recorder) take() []string {
+	events := r.events
+	r.events = nil
+	sort.Strings(events)
+
+	return events
+}
+
+func TestPoller(t *testing.T) {
+	bucket := fakeBucket{}
+	bucket.put("a", "1")
+	bucket.put("b", "1")
+	bucket.put("c", "1")
+	p := NewPoller("bucket", time.Second, bucket.list, nil, logger.NewLogger("test"))
+	r := &recorder{failures: map[string]bool{}}
+	ctx := context.Background()
+
+	t.Run("first listing initializes the checkpoint", func(t *testing.T) {
+		require.NoError(t, p.Poll(ctx, r.handle))
+		assert.Empty(t, r.take())
+		assert.Len(t, p.checkpoint, 3)
+	})
+
+	t.Run("changes are emitted", func(t *testing.T) {
+		bucket.put("b", "2")
+		bucket.put("d", "1")
+		delete(bucket, "a")
+		require.NoError(t, p.Poll(ctx, r.handle))
+		assert.Equal(t, []string{"created b", "created d", "deleted a"}, r.take())
+
+		require.NoError(t, p.Poll(ctx, r.handle))
+		assert.Empty(t, r.take())
+	})
+
+	t.Run("failed events are retried", func(t *testing.T) {
+		bucket.put("c", "2")
+		bucket.put("e", "1")
+		delete(bucket, "d")
+		r.failures = map[string]bool{"c": true, "d": true, "e": true}
+		require.NoError(t, p.Poll(ctx, r.handle))
+		assert.Empty(t, r.take())
+
+		r.failures = map[string]bool{}
+		require.NoError(t, p.Poll(ctx, r.handle))
+		assert.Equal(t, []string{"created c", "created e", "deleted d"}, r.take())
+	})
+
+	t.Run("listing errors", func(t *testing.T) {
+		failing := NewPoller("bucket", time.Second, func(context.Context, ListRequest) (ListResponse, error) {
+			return ListResponse{}, errors.New("list failed")
+		}, nil, logger.NewLogger("test"))
+		assert.Error(t, failing.Poll(ctx, r.handle))
+	})
+}
+
+func TestPollerCheckpoint(t *testing.T) {
+	bucket := fakeBucket{}
+	bucket.put("a", "1")
+	bucket.put("b", "1")
+	persistence := &PollCheckpoint{store: statestore.NewMemoryStore(), key: "bucket-events"}
+	r := &recorder{failures: map[string]bool{}}
+	ctx := context.Background()
+
+	p := NewPoller("bucket", time.Second, bucket.list, persistence, logger.NewLogger("test"))
+	require.NoError(t, p.Poll(ctx, r.handle))
+	assert.Empty(t, r.take())
+
+	// The objects changed while the binding is not running are emitted on restart
+	bucket.put("b", "2")
+	bucket.put("c", "1")
+	delete(bucket, "a")
+	restarted := NewPoller("bucket", time.Second, bucket.list, persistence, logger.NewLogger("test"))
+	require.NoError(t, restarted.Poll(ctx, r.handle))
+	assert.Equal(t, []string{"created b", "created c", "deleted a"}, r.take())
+
+	restarted = NewPoller("bucket", time.Second, bucket.list, persistence, logger.NewLogger("test"))
+	require.NoError(t, restarted.Poll(ctx, r.handle))
+	assert.Empty(t, r.take())
+}
+
+func TestNewPollCheckpoint(t *testing.T) {
+	l := logger.NewLogger("test")
+	persistence, err := NewPollCheckpoint(bindings.Metadata{Name: "events", Properties: map[string]string{}}, l)
+	require.NoError(t, err)
+	assert.Nil(t, persistence)
+
+	for name, properties := range map[string]map[string]string{
+		"memory store":         {PollCheckpointStoreKey: statestore.MemoryStore, PollCheckpointKeyKey: "key"},
+		"unsupported store":    {PollCheckpointStoreKey: "cassandra", PollCheckpointKeyKey: "key"},
+		"store without a key":  {PollCheckpointStoreKey: "redis"},
+		"store metadata error": {PollCheckpointStoreKey: "redis", PollCheckpointKeyKey: "key", "pollCheckpointStore.redisHost": ""},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := NewPollCheckpoint(bindings.Metadata{Properties: properties}, l)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestChanged(t *testing.T) {
+	modified := time.Date(2021, 1, 22, 10, 30, 0, 0, time.UTC)
+	o := Object{Key: "a", Size: 1, LastModified: modified}
+
+	assert.False(t, changed(o, o))
+	assert.True(t, changed(o, Object{Key: "a", Size: 2, LastModified: modified}))
+	assert.True(t, changed(o, Object{Key: "a", Size: 1, LastModified: modified.Add(time.Second)}))
+	assert.True(t, changed(Object{Key: "a", ETag: "1"}, Object{Key: "a", ETag: "2"}))
+	assert.False(t, changed(Object{Key: "a", ETag: "1", Size: 1}, Object{Key: "a", ETag: "1", Size: 1}))
+}
+
+func TestParsePollInterval(t *testing.T) {
+	interval, err := ParsePollInterval(map[string]string{})
+	require.NoError(t, err)
+	assert.Equal(t, DefaultPollInterval, interval)
+
+	interval, err = ParsePollInterval(map[string]string{PollIntervalKey: "5s"})
+	require.NoError(t, err)
+	assert.Equal(t, 5*time.Second, interval)
+
+	for _, val := range []string{"5", "-1s", "0s"} {
+		_, err = ParsePollInterval(map[string]string{PollIntervalKey: val})
+		assert.Error(t, err, val)
+	}
+}
@@ -0,0 +1,147 @@
+// ------------------------------------------------------------
+// Copyright (c) Microsoft Corporation and Dapr Contributors.
+// Licensed under the MIT License.
+// ------------------------------------------------------------
+
+package objectstorage
+
+import (
+	b64 "encoding/base64"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/dapr/components-contrib/bindings"
+)
+
+// PresignOperation returns a presigned URL to download an object without credentials.
+const PresignOperation bindings.OperationKind = "presign"
+
+const (
+	// keys from request's metadata
+	ContentTypeKey  = "contentType"
+	DecodeBase64Key = "decodeBase64"
+	PrefixKey       = "prefix"
+	MarkerKey       = "marker"
+	MaxResultsKey   = "maxResults"
+	PresignTTLKey   = "presignTTL"
+
+	DefaultMaxResults = 1000
+	DefaultPresignTTL = 15 * time.Minute
+)
+
+// reservedKeys are the keys of the request metadata that are not user metadata of objects.
+var reservedKeys = []string{ContentTypeKey, DecodeBase64Key, PrefixKey, MarkerKey, MaxResultsKey, PresignTTLKey}
+
+// ListRequest is the paging of the list operation.
+type ListRequest struct {
+	Prefix     string
+	Marker     string
+	MaxResults int
+}
+
+// ListResponse is the response data of the list operation.
+type ListResponse struct {
+	Objects []Object `json:"objects"`
+	// NextMarker is the marker of the next page, empty for the last page
+	NextMarker string `json:"nextMarker,omitempty"`
+}
+
+// Object is an object returned by the list operation.
+type Object struct {
+	Key          string    `json:"key"`
+	Size         int64     `json:"size"`
+	LastModified time.Time `json:"lastModified"`
+	ETag         string    `json:"etag,omitempty"`
+}
+
+// PresignResponse is the response data of the presign operation.
+type PresignResponse struct {
+	PresignedURL string    `json:"presignedURL"`
+	ExpiresAt    time.Time `json:"expiresAt"`
+}
+
+// Operations returns the list of operations supported by object storage bindings.
+func Operations() []bindings.OperationKind {
+	return []bindings.OperationKind{
+		bindings.CreateOperation,
+		bindings.GetOperation,
+		bindings.DeleteOperation,
+		bindings.ListOperation,
+		PresignOperation,
+	}
+}
+
+// ParseListRequest reads the prefix, marker and maxResults of the list operation.
+func ParseListRequest(metadata map[string]string) (ListRequest, error) {
+	r := ListRequest{
+		Prefix:     metadata[PrefixKey],
+		Marker:     metadata[MarkerKey],
+		MaxResults: DefaultMaxResults,
+	}
+
+	if val, ok := metadata[MaxResultsKey]; ok && val != "" {
+		maxResults, err := strconv.Atoi(val)
+		if err != nil || maxResults <= 0 {
+			return r, fmt.Errorf("%s must be a positive integer: %s", MaxResultsKey, val)
+		}
+		r.MaxResults = maxResults
+	}
+
+	return r, nil
+}
+
+// ParsePresignTTL reads how long the URL of the presign operation is valid.
+func ParsePresignTTL(metadata map[string]string) (time.Duration, error) {
+	if val, ok := metadata[PresignTTLKey]; ok && val != "" {
+		ttl, err := time.ParseDuration(val)
+		if err != nil || ttl <= 0 {
+			return 0, fmt.Errorf("%s must be a positive duration: %s", PresignTTLKey, val)
+		}
+
+		return ttl, nil
+	}
+
+	return DefaultPresignTTL, nil
+}
+
+// DecodeData decodes the data of the create operation from base64 when the
+// component is configured to, which a request can override with decodeBase64.
+func DecodeData(data []byte, decodeBase64 bool, metadata map[string]string) ([]byte, error) {
+	if val, ok := metadata[DecodeBase64Key]; ok && val != "" {
+		decode, err := strconv.ParseBool(val)
+		if err != nil {
+			return nil, fmt.Errorf("%s must be a boolean: %s", DecodeBase64Key, val)
+		}
+		decodeBase64 = decode
+	}
+
+	if !decodeBase64 {
+		return data, nil
+	}
+
+	decoded, err := b64.StdEncoding.DecodeString(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("error decoding base64 data: %s", err)
+	}
+
+	return decoded, nil
+}
+
+// UserMetadata returns the request metadata to store with an object, which
+// excludes the keys of the binding, such as the object key, and the keys of
+// this package.
+func UserMetadata(metadata map[string]string, bindingKeys ...string) map[string]string {
+	m := make(map[string]string, len(metadata))
+	for k, v := range metadata {
+		m[k] = v
+	}
+	for _, k := range bindingKeys {
+		delete(m, k)
+	}
+	for _, k := range reservedKeys {
+		delete(m, k)
+	}
+
+	return m
+}
@@ -0,0 +1,285 @@
+// ------------------------------------------------------------
+// Copyright (c) Microsoft Corporation and Dapr Contributors.
+// Licensed under the MIT License.
+// ------------------------------------------------------------
+
+package redis
+
+import (
+	"crypto/tls"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	redis "github.com/go-redis/redis/v7"
+)
+
+const (
+	hostKey               = "redisHost"
+	passwordKey           = "redisPassword"
+	redisTypeKey          = "redisType"
+	dbKey                 = "redisDB"
+	enableTLSKey          = "enableTLS"
+	maxRetriesKey         = "maxRetries"
+	maxRetryBackoffKey    = "maxRetryBackoff"
+	dialTimeoutKey        = "dialTimeout"
+	readTimeoutKey        = "readTimeout"
+	writeTimeoutKey       = "writeTimeout"
+	poolSizeKey           = "poolSize"
+	minIdleConnsKey       = "minIdleConns"
+	idleTimeoutKey        = "idleTimeout"
+	failoverKey           = "failover"
+	sentinelMasterNameKey = "sentinelMasterName"
+
+	// NodeType connects to a single Redis node
+	NodeType = "node"
+	// ClusterType connects to a Redis Cluster through one or more of its nodes
+	ClusterType = "cluster"
+
+	defaultMaxRetries      = 3
+	defaultMaxRetryBackoff = time.Second * 2
+)
+
+// Settings are the connection settings shared by the Redis components.
+type Settings struct {
+	// The Redis host, or a comma separated list of cluster nodes or sentinels
+	Host string
+	// The Redis password
+	Password string
+	// Either node (default) or cluster
+	RedisType string
+	// The database to select, always 0 for clusters
+	DB int
+	// A flag to enables TLS by setting InsecureSkipVerify to true
+	EnableTLS bool
+	// Maximum number of retries before giving up, -1 disables retries
+	MaxRetries int
+	// Maximum backoff between each retry
+	MaxRetryBackoff time.Duration
+	// Connection, read and write timeouts, 0 uses the client defaults
+	DialTimeout  time.Duration
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+	// Maximum number of socket connections, 0 uses the client default
+	PoolSize int
+	// Minimum number of idle connections kept open
+	MinIdleConns int
+	// Amount of time after which idle connections are closed
+	IdleTimeout time.Duration
+	// Use Redis Sentinel with SentinelMasterName
+	Failover           bool
+	SentinelMasterName string
+}
+
+// ParseSettings parses the connection settings from the component metadata.
+// errorPrefix prefixes the returned errors, for example "redis store error".
+func ParseSettings(properties map[string]string, errorPrefix string) (*Settings, error) {
+	s := &Settings{
+		RedisType:       NodeType,
+		MaxRetries:      defaultMaxRetries,
+		MaxRetryBackoff: defaultMaxRetryBackoff,
+	}
+
+	if val, ok := properties[hostKey]; ok && val != "" {
+		s.Host = val
+	} else {
+		return nil, fmt.Errorf("%s: missing host address", errorPrefix)
+	}
+
+	if val, ok := properties[passwordKey]; ok && val != "" {
+		s.Password = val
+	}
+
+	if val, ok := properties[redisTypeKey]; ok && val != "" {
+		switch strings.ToLower(val) {
+		case NodeType, ClusterType:
+			s.RedisType = strings.ToLower(val)
+		default:
+			return nil, fmt.Errorf("%s: invalid redisType %s, use node or cluster", errorPrefix, val)
+		}
+	}
+
+	if val, ok := properties[dbKey]; ok && val != "" {
+		db, err := strconv.Atoi(val)
+		if err != nil {
+			return nil, fmt.Errorf("%s: can't parse redisDB field: %s", errorPrefix, err)
+		}
+		s.DB = db
+	}
+
+	if val, ok := properties[enableTLSKey]; ok && val != "" {
+		tls, err := strconv.ParseBool(val)
+		if err != nil {
+			return nil, fmt.Errorf("%s: can't parse enableTLS field: %s", errorPrefix, err)
+		}
+		s.EnableTLS = tls
+	}
+
+	if val, ok := properties[maxRetriesKey]; ok && val != "" {
+		parsedVal, err := strconv.Atoi(val)
+		if err != nil {
+			return nil, fmt.Errorf("%s: can't parse maxRetries field: %s", errorPrefix, err)
+		}
+		s.MaxRetries = parsedVal
+	}
+
+	if val, ok := properties[maxRetryBackoffKey]; ok && val != "" {
+		// Plain integers are nanoseconds for backwards compatibility
+		if parsedVal, err := strconv.ParseInt(val, 10, 64); err == nil {
+			s.MaxRetryBackoff = time.Duration(parsedVal)
+		} else if d, err := time.ParseDuration(val); err == nil {
+			s.MaxRetryBackoff = d
+		} else {
+			return nil, fmt.Errorf("%s: can't parse maxRetryBackoff field: %s", errorPrefix, err)
+		}
+	}
+
+	durations := map[string]*time.Duration{
+		dialTimeoutKey:  &s.DialTimeout,
+		readTimeoutKey:  &s.ReadTimeout,
+		writeTimeoutKey: &s.WriteTimeout,
+		idleTimeoutKey:  &s.IdleTimeout,
+	}
+	for key, field := range durations {
+		if val, ok := properties[key]; ok && val != "" {
+			d, err := parseDuration(val)
+			if err != nil {
+				return nil, fmt.Errorf("%s: can't parse %s field: %s", errorPrefix, key, err)
+			}
+			*field = d
+		}
+	}
+
+	ints := map[string]*int{
+		poolSizeKey:     &s.PoolSize,
+		minIdleConnsKey: &s.MinIdleConns,
+	}
+	for key, field := range ints {
+		if val, ok := properties[key]; ok && val != "" {
+			parsedVal, err := strconv.Atoi(val)
+			if err != nil || parsedVal < 0 {
+				return nil, fmt.Errorf("%s: can't parse %s field: %s", errorPrefix, key, val)
+			}
+			*field = parsedVal
+		}
+	}
+
+	if val, ok := properties[failoverKey]; ok && val != "" {
+		failover, err := strconv.ParseBool(val)
+		if err != nil {
+			return nil, fmt.Errorf("%s: can't parse failover field: %s", errorPrefix, err)
+		}
+		s.Failover = failover
+	}
+
+	// set the sentinelMasterName only with failover == true.
+	if s.Failover {
+		if val, ok := properties[sentinelMasterNameKey]; ok && val != "" {
+			s.SentinelMasterName = val
+		} else {
+			return nil, fmt.Errorf("%s: missing sentinelMasterName", errorPrefix)
+		}
+	}
+
+	if s.RedisType == ClusterType {
+		if s.Failover {
+			return nil, fmt.Errorf("%s: failover is not supported with redisType cluster", errorPrefix)
+		}
+		if s.DB != 0 {
+			return nil, fmt.Errorf("%s: redisDB must be 0 with redisType cluster", errorPrefix)
+		}
+	} else if !s.Failover && len(s.Addrs()) > 1 {
+		return nil, fmt.Errorf("%s: multiple hosts require redisType cluster or failover", errorPrefix)
+	}
+
+	return s, nil
+}
+
+// parseDuration parses milliseconds or a Go duration string.
+func parseDuration(val string) (time.Duration, error) {
+	if ms, err := strconv.ParseUint(val, 10, 64); err == nil {
+		return time.Duration(ms) * time.Millisecond, nil
+	}
+
+	return time.ParseDuration(val)
+}
+
+// Addrs returns the addresses listed in Host.
+func (s *Settings) Addrs() []string {
+	hosts := strings.Split(s.Host, ",")
+	addrs := make([]string, 0, len(hosts))
+	for _, host := range hosts {
+		if host = strings.TrimSpace(host); host != "" {
+			addrs = append(addrs, host)
+		}
+	}
+
+	return addrs
+}
+
+// IsCluster returns true when connecting to a Redis Cluster.
+func (s *Settings) IsCluster() bool {
+	return s.RedisType == ClusterType
+}
+
+// NewClient returns a client for a single node, a sentinel monitored master
+// or a cluster, depending on the settings.
+func NewClient(s *Settings) redis.UniversalClient {
+	var tlsConfig *tls.Config
+	/* #nosec */
+	if s.EnableTLS {
+		tlsConfig = &tls.Config{
+			InsecureSkipVerify: s.EnableTLS,
+		}
+	}
+
+	if s.IsCluster() {
+		return redis.NewClusterClient(&redis.ClusterOptions{
+			Addrs:           s.Addrs(),
+			Password:        s.Password,
+			MaxRetries:      s.MaxRetries,
+			MaxRetryBackoff: s.MaxRetryBackoff,
+			DialTimeout:     s.DialTimeout,
+			ReadTimeout:     s.ReadTimeout,
+			WriteTimeout:    s.WriteTimeout,
+			PoolSize:        s.PoolSize,
+			MinIdleConns:    s.MinIdleConns,
+			IdleTimeout:     s.IdleTimeout,
+			TLSConfig:       tlsConfig,
+		})
+	}
+
+	if s.Failover {
+		return redis.NewFailoverClient(&redis.FailoverOptions{
+			MasterName:      s.SentinelMasterName,
+			SentinelAddrs:   s.Addrs(),
+			Password:        s.Password,
+			DB:              s.DB,
+			MaxRetries:      s.MaxRetries,
+			MaxRetryBackoff: s.MaxRetryBackoff,
+			DialTimeout:     s.DialTimeout,
+			ReadTimeout:     s.ReadTimeout,
+			WriteTimeout:    s.WriteTimeout,
+			PoolSize:        s.PoolSize,
+			MinIdleConns:    s.MinIdleConns,
+			IdleTimeout:     s.IdleTimeout,
+			TLSConfig:       tlsConfig,
+		})
+	}
+
+	return redis.NewClient(&redis.Options{
+		Addr:            s.Addrs()[0],
+		Password:        s.Password,
+		DB:              s.DB,
+		MaxRetries:      s.MaxRetries,
+		MaxRetryBackoff: s.MaxRetryBackoff,
+		DialTimeout:     s.DialTimeout,
+		ReadTimeout:     s.ReadTimeout,
+		WriteTimeout:    s.WriteTimeout,
+		PoolSize:        s.PoolSize,
+		MinIdleConns:    s.MinIdleConns,
+		IdleTimeout:     s.IdleTimeout,
+		TLSConfig:       tlsConfig,
+	})
+}
@@ -0,0 +1,87 @@
+// ------------------------------------------------------------
+// Copyright (c) Microsoft Corporation and Dapr Contributors.
+// Licensed under the MIT License.
+// ------------------------------------------------------------
+
+package redis
+
+import (
+	"testing"
+	"time"
+
+	redis "github.com/go-redis/redis/v7"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSettings(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		s, err := ParseSettings(map[string]string{"redisHost": "localhost:6379"}, "redis error")
+		require.NoError(t, err)
+		assert.Equal(t, NodeType, s.RedisType)
+		assert.Equal(t, []string{"localhost:6379"}, s.Addrs())
+		assert.Equal(t, 3, s.MaxRetries)
+		assert.Equal(t, 2*time.Second, s.MaxRetryBackoff)
+		assert.False(t, s.IsCluster())
+		assert.IsType(t, &redis.Client{}, NewClient(s))
+	})
+
+	t.Run("cluster", func(t *testing.T) {
+		s, err := ParseSettings(map[string]string{
+			"redisHost":       "node1:6379, node2:6379,node3:6379",
+			"redisType":       "Cluster",
+			"enableTLS":       "true",
+			"maxRetryBackoff": "500ms",
+			"dialTimeout":     "3000",
+			"readTimeout":     "1s",
+			"poolSize":        "20",
+			"minIdleConns":    "2",
+		}, "redis error")
+		require.NoError(t, err)
+		assert.True(t, s.IsCluster())
+		assert.Equal(t, []string{"node1:6379", "node2:6379", "node3:6379"}, s.Addrs())
+		assert.True(t, s.EnableTLS)
+		assert.Equal(t, 500*time.Millisecond, s.MaxRetryBackoff)
+		assert.Equal(t, 3*time.Second, s.DialTimeout)
+		assert.Equal(t, time.Second, s.ReadTimeout)
+		assert.Equal(t, 20, s.PoolSize)
+		assert.Equal(t, 2, s.MinIdleConns)
+		assert.IsType(t, &redis.ClusterClient{}, NewClient(s))
+	})
+
+	t.Run("failover", func(t *testing.T) {
+		s, err := ParseSettings(map[string]string{"redisHost": "s1:26379,s2:26379", "failover": "true", "sentinelMasterName": "master"}, "redis error")
+		require.NoError(t, err)
+		assert.Equal(t, "master", s.SentinelMasterName)
+		assert.Len(t, s.Addrs(), 2)
+	})
+
+	tests := map[string]map[string]string{
+		"redis error: missing host address":                                 {},
+		"redis error: missing sentinelMasterName":                           {"redisHost": "h", "failover": "true"},
+		"redis error: invalid redisType ring, use node or cluster":          {"redisHost": "h", "redisType": "ring"},
+		"redis error: redisDB must be 0 with redisType cluster":             {"redisHost": "h", "redisType": "cluster", "redisDB": "1"},
+		"redis error: multiple hosts require redisType cluster or failover": {"redisHost": "h1,h2"},
+		"redis error: can't parse poolSize field: many":                     {"redisHost": "h", "poolSize": "many"},
+	}
+	for expected, properties := range tests {
+		t.Run(expected, func(t *testing.T) {
+			_, err := ParseSettings(properties, "redis error")
+			assert.EqualError(t, err, expected)
+		})
+	}
+}
+
+func TestSlot(t *testing.T) {
+	// Values from the Redis Cluster specification and CLUSTER KEYSLOT
+	assert.Equal(t, 12739, Slot("123456789"))
+	assert.Equal(t, 12182, Slot("foo"))
+	assert.Equal(t, Slot("user1000"), Slot("{user1000}.following"))
+	assert.Equal(t, Slot("{}.key"), Slot("{}.key"))
+	assert.Equal(t, "{}.key", HashTag("{}.key"))
+	assert.Equal(t, "app", HashTag("{app}||key"))
+
+	assert.True(t, SameSlot("{order-1}.a", "{order-1}.b"))
+	assert.True(t, SameSlot("only"))
+	assert.False(t, SameSlot("a", "b"))
+}
@@ -0,0 +1,61 @@
+// ------------------------------------------------------------
+// Copyright (c) Microsoft Corporation and Dapr Contributors.
+// Licensed under the MIT License.
+// ------------------------------------------------------------
+
+package redis
+
+import (
+	"errors"
+	"strings"
+)
+
+const slotCount = 16384
+
+// ErrCrossSlot is returned when the keys of a cluster transaction hash to different slots.
+var ErrCrossSlot = errors.New("keys of a transaction must hash to the same cluster slot, use a common {hash tag} in the keys")
+
+// HashTag returns the part of key that is hashed to select its cluster slot:
+// the content of the first non-empty {...} section, or the whole key.
+func HashTag(key string) string {
+	if start := strings.IndexByte(key, '{'); start >= 0 {
+		if end := strings.IndexByte(key[start+1:], '}'); end > 0 {
+			return key[start+1 : start+1+end]
+		}
+	}
+
+	return key
+}
+
+// Slot returns the cluster slot of key.
+func Slot(key string) int {
+	return int(crc16(HashTag(key)) % slotCount)
+}
+
+// SameSlot returns true if all keys hash to the same cluster slot.
+func SameSlot(keys ...string) bool {
+	for i := 1; i < len(keys); i++ {
+		if Slot(keys[i]) != Slot(keys[0]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// crc16 implements CRC16-CCITT (XMODEM) as used by Redis Cluster.
+func crc16(s string) uint16 {
+	var crc uint16
+	for i := 0; i < len(s); i++ {
+		crc ^= uint16(s[i]) << 8
+		for j := 0; j < 8; j++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+
+	return crc
+}
@@ -0,0 +1,123 @@
+// ------------------------------------------------------------
+// Copyright (c) Microsoft Corporation and Dapr Contributors.
+// Licensed under the MIT License.
+// ------------------------------------------------------------
+
+package sql
+
+import (
+	"database/sql"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// mysqlDateTimeLayout is the text format of the DATETIME and TIMESTAMP values
+// of MySQL, which are returned as text unless the DSN has parseTime=true.
+const mysqlDateTimeLayout = "2006-01-02 15:04:05.999999999"
+
+// rowsToJSON serializes rows as a JSON array of objects keyed by column name.
+func rowsToJSON(rows *sql.Rows) ([]byte, error) {
+	columnTypes, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make([]map[string]interface{}, 0)
+	for rows.Next() {
+		values := make([]interface{}, len(columnTypes))
+		scanArgs := make([]interface{}, len(columnTypes))
+		for i := range values {
+			scanArgs[i] = &values[i]
+		}
+
+		if err := rows.Scan(scanArgs...); err != nil {
+			return nil, err
+		}
+
+		r := make(map[string]interface{}, len(columnTypes))
+		for i, c := range columnTypes {
+			r[c.Name()] = typedValue(c.DatabaseTypeName(), values[i])
+		}
+		ret = append(ret, r)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(ret)
+}
+
+// typedValue converts a value returned by a driver to the JSON type of its
+// column. NULL is returned as nil, numbers, booleans and times are kept as
+// such, while values returned as text, for example by MySQL queries without
+// parameters, are parsed based on the type of their column.
+func typedValue(dbType string, v interface{}) interface{} {
+	var s string
+	switch t := v.(type) {
+	case []byte:
+		if isBinaryType(dbType) {
+			// serialized as base64
+			return t
+		}
+		s = string(t)
+	case string:
+		s = t
+	case int64:
+		// SQLite stores booleans as integers
+		if dbType == "BOOL" || dbType == "BOOLEAN" {
+			return t != 0
+		}
+
+		return t
+	default:
+		return v
+	}
+
+	switch strings.TrimPrefix(dbType, "UNSIGNED ") {
+	case "TINYINT", "SMALLINT", "MEDIUMINT", "INT", "INTEGER", "BIGINT", "YEAR", "INT2", "INT4", "INT8":
+		if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return i
+		}
+
+		return numberOrString(s)
+	case "DECIMAL", "NUMERIC", "FLOAT", "DOUBLE", "REAL", "FLOAT4", "FLOAT8":
+		// kept as text so that decimals don't lose precision
+		return numberOrString(s)
+	case "BOOL", "BOOLEAN":
+		if b, err := strconv.ParseBool(s); err == nil {
+			return b
+		}
+	case "DATETIME", "TIMESTAMP":
+		if t, err := time.Parse(mysqlDateTimeLayout, s); err == nil {
+			return t
+		}
+	case "JSON", "JSONB":
+		if json.Valid([]byte(s)) {
+			return json.RawMessage(s)
+		}
+	}
+
+	return s
+}
+
+// numberOrString returns s as a JSON number when it is one, which excludes
+// values such as NaN.
+func numberOrString(s string) interface{} {
+	if s != "" && (s[0] == '-' || (s[0] >= '0' && s[0] <= '9')) && json.Valid([]byte(s)) {
+		return json.Number(s)
+	}
+
+	return s
+}
+
+func isBinaryType(dbType string) bool {
+	switch dbType {
+	case "BINARY", "VARBINARY", "BLOB", "TINYBLOB", "MEDIUMBLOB", "LONGBLOB", "BIT", "BYTEA":
+		return true
+	}
+
+	return false
+}
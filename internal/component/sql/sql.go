@@ -0,0 +1,281 @@
+// ------------------------------------------------------------
+// Copyright (c) Microsoft Corporation and Dapr Contributors.
+// Licensed under the MIT License.
+// ------------------------------------------------------------
+
+package sql
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/dapr/components-contrib/bindings"
+	"github.com/dapr/dapr/pkg/logger"
+	"github.com/pkg/errors"
+)
+
+// List of operations.
+const (
+	ExecOperation        bindings.OperationKind = "exec"
+	QueryOperation       bindings.OperationKind = "query"
+	TransactionOperation bindings.OperationKind = "transaction"
+	CloseOperation       bindings.OperationKind = "close"
+)
+
+const (
+	// keys from request's metadata
+	CommandSQLKey = "sql"
+	// ParamsKey is the JSON array of positional parameters bound to the
+	// placeholders of the statement, `?` with MySQL and `$1` with PostgreSQL
+	ParamsKey = "params"
+
+	// keys from response's metadata
+	RespOpKey           = "operation"
+	RespSQLKey          = "sql"
+	RespStartTimeKey    = "start-time"
+	RespRowsAffectedKey = "rows-affected"
+	RespEndTimeKey      = "end-time"
+	RespDurationKey     = "duration"
+)
+
+// Statement is a statement of a transaction operation, whose request data is
+// the JSON array of the statements to run in order.
+type Statement struct {
+	// Operation is either exec, the default, or query
+	Operation bindings.OperationKind `json:"operation,omitempty"`
+	SQL       string                 `json:"sql"`
+	Params    []interface{}          `json:"params,omitempty"`
+}
+
+// StatementResult is the result of a statement of a transaction operation,
+// whose response data is the JSON array of the results of the statements.
+type StatementResult struct {
+	RowsAffected *int64          `json:"rowsAffected,omitempty"`
+	Rows         json.RawMessage `json:"rows,omitempty"`
+}
+
+// execQuerier is implemented by both *sql.DB and *sql.Tx.
+type execQuerier interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// Operations returns the list of operations supported by SQL bindings.
+func Operations() []bindings.OperationKind {
+	return []bindings.OperationKind{
+		ExecOperation,
+		QueryOperation,
+		TransactionOperation,
+		CloseOperation,
+	}
+}
+
+// Invoke handles the exec, query and transaction operations of SQL bindings,
+// the close operation is left to the bindings.
+func Invoke(ctx context.Context, db *sql.DB, req *bindings.InvokeRequest, logger logger.Logger) (*bindings.InvokeResponse, error) {
+	logger.Debugf("operation: %v", req.Operation)
+
+	startTime := time.Now().UTC()
+	resp := &bindings.InvokeResponse{
+		Metadata: map[string]string{
+			RespOpKey:        string(req.Operation),
+			RespStartTimeKey: startTime.Format(time.RFC3339Nano),
+		},
+	}
+
+	switch req.Operation { // nolint: exhaustive
+	case ExecOperation, QueryOperation:
+		s, err := statementFromMetadata(req.Operation, req.Metadata)
+		if err != nil {
+			return nil, err
+		}
+		resp.Metadata[RespSQLKey] = s.SQL
+
+		result, err := run(ctx, db, s, logger)
+		if err != nil {
+			return nil, err
+		}
+		if result.RowsAffected != nil {
+			resp.Metadata[RespRowsAffectedKey] = strconv.FormatInt(*result.RowsAffected, 10)
+		}
+		resp.Data = result.Rows
+
+	case TransactionOperation:
+		var statements []Statement
+		if err := decodeJSON(req.Data, &statements); err != nil {
+			return nil, errors.Wrap(err, "transaction data must be a JSON array of statements")
+		}
+
+		results, err := transaction(ctx, db, statements, logger)
+		if err != nil {
+			return nil, err
+		}
+
+		var rowsAffected int64
+		for _, r := range results {
+			if r.RowsAffected != nil {
+				rowsAffected += *r.RowsAffected
+			}
+		}
+		resp.Metadata[RespRowsAffectedKey] = strconv.FormatInt(rowsAffected, 10)
+
+		if resp.Data, err = json.Marshal(results); err != nil {
+			return nil, errors.Wrap(err, "error serializing results")
+		}
+
+	default:
+		return nil, errors.Errorf(
+			"invalid operation type: %s. Expected %s, %s, %s, or %s",
+			req.Operation, ExecOperation, QueryOperation, TransactionOperation, CloseOperation,
+		)
+	}
+
+	endTime := time.Now().UTC()
+	resp.Metadata[RespEndTimeKey] = endTime.Format(time.RFC3339Nano)
+	resp.Metadata[RespDurationKey] = endTime.Sub(startTime).String()
+
+	return resp, nil
+}
+
+// transaction runs statements in order in a single transaction, which is
+// rolled back when any of them fails.
+func transaction(ctx context.Context, db *sql.DB, statements []Statement, logger logger.Logger) ([]StatementResult, error) {
+	if len(statements) == 0 {
+		return nil, errors.New("transaction has no statements")
+	}
+	for i, s := range statements {
+		if s.SQL == "" {
+			return nil, errors.Errorf("statement %d of transaction has no sql", i)
+		}
+		if s.Operation != "" && s.Operation != ExecOperation && s.Operation != QueryOperation {
+			return nil, errors.Errorf("invalid operation type of statement %d: %s. Expected %s or %s", i, s.Operation, ExecOperation, QueryOperation)
+		}
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "error starting transaction")
+	}
+
+	results := make([]StatementResult, 0, len(statements))
+	for i, s := range statements {
+		result, err := run(ctx, tx, s, logger)
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				logger.Warnf("error rolling back transaction: %s", rbErr)
+			}
+
+			return nil, errors.Wrapf(err, "statement %d of transaction failed", i)
+		}
+		results = append(results, result)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, errors.Wrap(err, "error committing transaction")
+	}
+
+	return results, nil
+}
+
+// run runs a statement, returning the rows affected by exec statements and the
+// rows selected by query statements.
+func run(ctx context.Context, db execQuerier, s Statement, logger logger.Logger) (StatementResult, error) {
+	args, err := queryArgs(s.Params)
+	if err != nil {
+		return StatementResult{}, err
+	}
+
+	if s.Operation == QueryOperation {
+		logger.Debugf("query: %s", s.SQL)
+
+		rows, err := db.QueryContext(ctx, s.SQL, args...)
+		if err != nil {
+			return StatementResult{}, errors.Wrapf(err, "error executing %s", s.SQL)
+		}
+		defer rows.Close()
+
+		data, err := rowsToJSON(rows)
+		if err != nil {
+			return StatementResult{}, errors.Wrapf(err, "error serializing query result for %s", s.SQL)
+		}
+
+		return StatementResult{Rows: data}, nil
+	}
+
+	logger.Debugf("exec: %s", s.SQL)
+
+	res, err := db.ExecContext(ctx, s.SQL, args...)
+	if err != nil {
+		return StatementResult{}, errors.Wrapf(err, "error executing %s", s.SQL)
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return StatementResult{}, errors.Wrapf(err, "error reading rows affected by %s", s.SQL)
+	}
+
+	return StatementResult{RowsAffected: &rowsAffected}, nil
+}
+
+// statementFromMetadata reads the statement and parameters of exec and query operations.
+func statementFromMetadata(op bindings.OperationKind, metadata map[string]string) (Statement, error) {
+	if metadata == nil {
+		return Statement{}, errors.Errorf("metadata required")
+	}
+
+	s := Statement{Operation: op}
+	s.SQL = metadata[CommandSQLKey]
+	if s.SQL == "" {
+		return Statement{}, errors.Errorf("required metadata not set: %s", CommandSQLKey)
+	}
+
+	if val, ok := metadata[ParamsKey]; ok && val != "" {
+		if err := decodeJSON([]byte(val), &s.Params); err != nil {
+			return Statement{}, errors.Wrapf(err, "%s must be a JSON array", ParamsKey)
+		}
+	}
+
+	return s, nil
+}
+
+// queryArgs converts the JSON parameters of a statement to query arguments.
+// Integers are bound as int64 and other numbers as float64, while arrays and
+// objects are bound as their JSON text, for JSON columns.
+func queryArgs(params []interface{}) ([]interface{}, error) {
+	args := make([]interface{}, len(params))
+	for i, p := range params {
+		switch v := p.(type) {
+		case json.Number:
+			if n, err := v.Int64(); err == nil {
+				args[i] = n
+			} else if f, err := v.Float64(); err == nil {
+				args[i] = f
+			} else {
+				return nil, errors.Wrapf(err, "invalid number parameter %d", i)
+			}
+		case map[string]interface{}, []interface{}:
+			b, err := json.Marshal(v)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid parameter %d", i)
+			}
+			args[i] = string(b)
+		default:
+			args[i] = v
+		}
+	}
+
+	return args, nil
+}
+
+// decodeJSON decodes data keeping numbers as json.Number, so that integers
+// are not rounded to float64.
+func decodeJSON(data []byte, v interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	return dec.Decode(v)
+}
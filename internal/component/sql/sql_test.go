@@ -0,0 +1,181 @@
+// ------------------------------------------------------------
+// Copyright (c) Microsoft Corporation and Dapr Contributors.
+// Licensed under the MIT License.
+// ------------------------------------------------------------
+
+package sql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/dapr/components-contrib/bindings"
+	"github.com/dapr/dapr/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInvoke(t *testing.T) {
+	log := logger.NewLogger("test")
+
+	t.Run("exec binds params", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectExec("INSERT INTO foo \\(id, v1, data\\) VALUES \\(\\?, \\?, \\?\\)").
+			WithArgs(int64(12345678901234), "it's", `{"a":1}`).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+
+		resp, err := Invoke(context.Background(), db, &bindings.InvokeRequest{
+			Operation: ExecOperation,
+			Metadata: map[string]string{
+				CommandSQLKey: "INSERT INTO foo (id, v1, data) VALUES (?, ?, ?)",
+				ParamsKey:     `[12345678901234, "it's", {"a": 1}]`,
+			},
+		}, log)
+		require.NoError(t, err)
+		assert.Equal(t, "1", resp.Metadata[RespRowsAffectedKey])
+		assert.Equal(t, "INSERT INTO foo (id, v1, data) VALUES (?, ?, ?)", resp.Metadata[RespSQLKey])
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("query returns typed rows", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		ts := time.Date(2021, 1, 22, 10, 30, 0, 0, time.UTC)
+		rows := sqlmock.NewRowsWithColumnDefinition(
+			sqlmock.NewColumn("id").OfType("BIGINT", int64(1)),
+			sqlmock.NewColumn("price").OfType("DECIMAL", ""),
+			sqlmock.NewColumn("active").OfType("BOOL", false),
+			sqlmock.NewColumn("ts").OfType("DATETIME", ts),
+			sqlmock.NewColumn("note").OfType("VARCHAR", ""),
+		).AddRow([]byte("1"), []byte("10.10"), true, ts, nil)
+		mock.ExpectQuery("SELECT \\* FROM foo WHERE id = \\$1").WithArgs(1.5).WillReturnRows(rows)
+
+		resp, err := Invoke(context.Background(), db, &bindings.InvokeRequest{
+			Operation: QueryOperation,
+			Metadata: map[string]string{
+				CommandSQLKey: "SELECT * FROM foo WHERE id = $1",
+				ParamsKey:     "[1.5]",
+			},
+		}, log)
+		require.NoError(t, err)
+		assert.JSONEq(t, `[{"id":1,"price":10.10,"active":true,"ts":"2021-01-22T10:30:00Z","note":null}]`, string(resp.Data))
+	})
+
+	t.Run("query without rows returns an empty array", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectQuery("SELECT").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+		resp, err := Invoke(context.Background(), db, &bindings.InvokeRequest{
+			Operation: QueryOperation,
+			Metadata:  map[string]string{CommandSQLKey: "SELECT id FROM foo"},
+		}, log)
+		require.NoError(t, err)
+		assert.Equal(t, "[]", string(resp.Data))
+	})
+
+	t.Run("invalid params", func(t *testing.T) {
+		_, err := Invoke(context.Background(), nil, &bindings.InvokeRequest{
+			Operation: ExecOperation,
+			Metadata:  map[string]string{CommandSQLKey: "DELETE FROM foo WHERE id = ?", ParamsKey: `{"id": 1}`},
+		}, log)
+		assert.Error(t, err)
+	})
+
+	t.Run("missing sql", func(t *testing.T) {
+		_, err := Invoke(context.Background(), nil, &bindings.InvokeRequest{Operation: ExecOperation, Metadata: map[string]string{}}, log)
+		assert.Error(t, err)
+	})
+
+	t.Run("unsupported operation", func(t *testing.T) {
+		_, err := Invoke(context.Background(), nil, &bindings.InvokeRequest{Operation: "unsupported"}, log)
+		assert.Error(t, err)
+	})
+}
+
+func TestTransaction(t *testing.T) {
+	log := logger.NewLogger("test")
+	data := []byte(`[
+		{"sql": "UPDATE accounts SET balance = balance - ? WHERE id = ?", "params": [10, "a"]},
+		{"sql": "UPDATE accounts SET balance = balance + ? WHERE id = ?", "params": [10, "b"]},
+		{"operation": "query", "sql": "SELECT balance FROM accounts WHERE id = ?", "params": ["b"]}
+	]`)
+
+	t.Run("commits", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE accounts SET balance = balance -").WithArgs(int64(10), "a").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("UPDATE accounts SET balance = balance \\+").WithArgs(int64(10), "b").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery("SELECT balance").WithArgs("b").WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow(int64(20)))
+		mock.ExpectCommit()
+
+		resp, err := Invoke(context.Background(), db, &bindings.InvokeRequest{Operation: TransactionOperation, Data: data}, log)
+		require.NoError(t, err)
+		assert.Equal(t, "2", resp.Metadata[RespRowsAffectedKey])
+		assert.JSONEq(t, `[{"rowsAffected":1},{"rowsAffected":1},{"rows":[{"balance":20}]}]`, string(resp.Data))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("rolls back when a statement fails", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE accounts SET balance = balance -").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("UPDATE accounts SET balance = balance \\+").WillReturnError(errors.New("update failed"))
+		mock.ExpectRollback()
+
+		resp, err := Invoke(context.Background(), db, &bindings.InvokeRequest{Operation: TransactionOperation, Data: data}, log)
+		assert.Nil(t, resp)
+		assert.Error(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("invalid statements", func(t *testing.T) {
+		for _, data := range []string{``, `[]`, `{"sql": "DELETE FROM foo"}`, `[{"params": [1]}]`, `[{"operation": "close", "sql": "DELETE FROM foo"}]`} {
+			_, err := Invoke(context.Background(), nil, &bindings.InvokeRequest{Operation: TransactionOperation, Data: []byte(data)}, log)
+			assert.Error(t, err, data)
+		}
+	})
+}
+
+func TestTypedValue(t *testing.T) {
+	tests := []struct {
+		dbType   string
+		value    interface{}
+		expected interface{}
+	}{
+		{"INT", []byte("42"), int64(42)},
+		{"UNSIGNED BIGINT", []byte("18446744073709551615"), json.Number("18446744073709551615")},
+		{"NUMERIC", "NaN", "NaN"},
+		{"DOUBLE", []byte("1.5"), json.Number("1.5")},
+		{"BOOLEAN", []byte("1"), true},
+		{"JSONB", `{"a":1}`, json.RawMessage(`{"a":1}`)},
+		{"BLOB", []byte{0, 1}, []byte{0, 1}},
+		{"TIMESTAMP", []byte("2021-01-22 10:30:00.5"), time.Date(2021, 1, 22, 10, 30, 0, 500000000, time.UTC)},
+		{"DATE", []byte("2021-01-22"), "2021-01-22"},
+		{"VARCHAR", []byte("text"), "text"},
+		{"INT8", int64(7), int64(7)},
+		{"BOOLEAN", int64(0), false},
+		{"TEXT", nil, nil},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, typedValue(tt.dbType, tt.value), tt.dbType)
+	}
+}
@@ -0,0 +1,113 @@
+// ------------------------------------------------------------
+// Copyright (c) Microsoft Corporation and Dapr Contributors.
+// Licensed under the MIT License.
+// ------------------------------------------------------------
+
+package statestore
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/dapr/components-contrib/state"
+	"github.com/dapr/components-contrib/state/utils"
+)
+
+type memoryItem struct {
+	data    []byte
+	version uint64
+}
+
+// memoryStore is a minimal in-memory state store with ETag support, used when
+// no persistent store is needed, such as in tests or against a local emulator.
+type memoryStore struct {
+	state.DefaultBulkStore
+	items   map[string]memoryItem
+	version uint64
+	lock    sync.Mutex
+}
+
+// NewMemoryStore returns an in-memory state store with ETag support. The
+// records are only shared by the users of the returned instance.
+//
+// A first-write Set without an ETag only creates a record and fails with an
+// ETag mismatch if the key already exists, like the other supported stores.
+func NewMemoryStore() state.Store {
+	s := &memoryStore{items: map[string]memoryItem{}}
+	s.DefaultBulkStore = state.NewDefaultBulkStore(s)
+
+	return s
+}
+
+func (m *memoryStore) Init(metadata state.Metadata) error {
+	return nil
+}
+
+func (m *memoryStore) Features() []state.Feature {
+	return []state.Feature{state.FeatureETag}
+}
+
+func (m *memoryStore) Get(req *state.GetRequest) (*state.GetResponse, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	item, ok := m.items[req.Key]
+	if !ok {
+		return &state.GetResponse{}, nil
+	}
+
+	etag := strconv.FormatUint(item.version, 10)
+
+	return &state.GetResponse{Data: item.data, ETag: &etag}, nil
+}
+
+func (m *memoryStore) Set(req *state.SetRequest) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if req.ETag == nil && req.Options.Concurrency == state.FirstWrite {
+		if _, ok := m.items[req.Key]; ok {
+			return state.NewETagError(state.ETagMismatch, fmt.Errorf("key %s already exists", req.Key))
+		}
+	} else if err := m.checkETag(req.Key, req.ETag); err != nil {
+		return err
+	}
+
+	bt, err := utils.Marshal(req.Value, json.Marshal)
+	if err != nil {
+		return err
+	}
+
+	m.version++
+	m.items[req.Key] = memoryItem{data: bt, version: m.version}
+
+	return nil
+}
+
+func (m *memoryStore) Delete(req *state.DeleteRequest) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if err := m.checkETag(req.Key, req.ETag); err != nil {
+		return err
+	}
+
+	delete(m.items, req.Key)
+
+	return nil
+}
+
+func (m *memoryStore) checkETag(key string, etag *string) error {
+	if etag == nil {
+		return nil
+	}
+
+	item, ok := m.items[key]
+	if !ok || strconv.FormatUint(item.version, 10) != *etag {
+		return state.NewETagError(state.ETagMismatch, fmt.Errorf("key %s was modified", key))
+	}
+
+	return nil
+}
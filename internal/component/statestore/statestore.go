@@ -0,0 +1,85 @@
+// ------------------------------------------------------------
+// Copyright (c) Microsoft Corporation and Dapr Contributors.
+// Licensed under the MIT License.
+// ------------------------------------------------------------
+
+package statestore
+
+import (
+	"fmt"
+	"strings"
+
+	contrib_redis "github.com/dapr/components-contrib/internal/component/redis"
+	"github.com/dapr/components-contrib/state"
+	"github.com/dapr/components-contrib/state/mysql"
+	"github.com/dapr/components-contrib/state/postgresql"
+	"github.com/dapr/components-contrib/state/redis"
+	"github.com/dapr/dapr/pkg/logger"
+)
+
+// MemoryStore keeps the records in memory, which is useful for tests and emulators.
+const MemoryStore = "memory"
+
+// stores are the state stores that components can keep their records in. They
+// support ETags and treat a first-write Set without an ETag as an insert that
+// fails with an ETag mismatch when the key exists, which components rely on to
+// create records concurrently. Other state stores, such as sqlserver, don't and
+// can't be selected.
+var stores = map[string]func(logger.Logger) state.Store{
+	"redis":      func(l logger.Logger) state.Store { return redis.NewRedisStateStore(l) },
+	"postgresql": func(l logger.Logger) state.Store { return postgresql.NewPostgreSQLStateStore(l) },
+	"mysql":      func(l logger.Logger) state.Store { return mysql.NewMySQLStateStore(l) },
+	MemoryStore: func(logger.Logger) state.Store {
+		return NewMemoryStore()
+	},
+}
+
+// checks reject store configurations that can't give the guarantees components
+// rely on before the store is initialized.
+var checks = map[string]func(properties map[string]string) error{
+	// Components write their records with strong consistency, which the redis
+	// store can't provide with Redis Cluster and rejects on every write.
+	"redis": func(properties map[string]string) error {
+		settings, err := contrib_redis.ParseSettings(properties, "redis store error")
+		if err != nil {
+			return err
+		}
+		if settings.IsCluster() {
+			return fmt.Errorf("redisType %s is not supported, strongly consistent writes require a single node", contrib_redis.ClusterType)
+		}
+
+		return nil
+	},
+}
+
+// New creates the state store selected by the metadata key of a component, for
+// example `checkpointStore: redis`. The store is initialized with the metadata
+// prefixed with the key and a dot, such as `checkpointStore.redisHost`.
+func New(properties map[string]string, key string, logger logger.Logger) (state.Store, error) {
+	storeType := properties[key]
+	newStore, ok := stores[storeType]
+	if !ok {
+		return nil, fmt.Errorf("unsupported %s %s", key, storeType)
+	}
+
+	prefix := key + "."
+	storeMetadata := state.Metadata{Properties: map[string]string{}}
+	for k, v := range properties {
+		if strings.HasPrefix(k, prefix) {
+			storeMetadata.Properties[strings.TrimPrefix(k, prefix)] = v
+		}
+	}
+
+	if check, ok := checks[storeType]; ok {
+		if err := check(storeMetadata.Properties); err != nil {
+			return nil, fmt.Errorf("unsupported %s %s: %s", key, storeType, err)
+		}
+	}
+
+	store := newStore(logger)
+	if err := store.Init(storeMetadata); err != nil {
+		return nil, fmt.Errorf("error initializing %s %s: %s", key, storeType, err)
+	}
+
+	return store, nil
+}
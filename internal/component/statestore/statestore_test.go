@@ -0,0 +1,103 @@
+// ------------------------------------------------------------
+// Copyright (c) Microsoft Corporation and Dapr Contributors.
+// Licensed under the MIT License.
+// ------------------------------------------------------------
+
+package statestore
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/dapr/components-contrib/state"
+	"github.com/dapr/dapr/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	t.Run("memory store", func(t *testing.T) {
+		store, err := New(map[string]string{"checkpointStore": MemoryStore}, "checkpointStore", logger.NewLogger("test"))
+		require.NoError(t, err)
+		assert.True(t, state.FeatureETag.IsPresent(store.Features()))
+	})
+
+	t.Run("unsupported store", func(t *testing.T) {
+		_, err := New(map[string]string{"checkpointStore": "cassandra"}, "checkpointStore", logger.NewLogger("test"))
+		assert.Error(t, err)
+		_, err = New(map[string]string{"checkpointStore": "sqlserver"}, "checkpointStore", logger.NewLogger("test"))
+		assert.Error(t, err)
+		_, err = New(map[string]string{}, "checkpointStore", logger.NewLogger("test"))
+		assert.Error(t, err)
+	})
+
+	t.Run("store metadata is passed without prefix", func(t *testing.T) {
+		_, err := New(map[string]string{
+			"checkpointStore":               "redis",
+			"checkpointStore.redisHost":     "",
+			"checkpointStore.redisPassword": "secret",
+		}, "checkpointStore", logger.NewLogger("test"))
+		assert.Error(t, err)
+	})
+
+	t.Run("redis cluster", func(t *testing.T) {
+		_, err := New(map[string]string{
+			"checkpointStore":           "redis",
+			"checkpointStore.redisHost": "localhost:6379",
+			"checkpointStore.redisType": "cluster",
+		}, "checkpointStore", logger.NewLogger("test"))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "redisType cluster is not supported")
+	})
+}
+
+func TestMemoryStore(t *testing.T) {
+	store := NewMemoryStore()
+
+	res, err := store.Get(&state.GetRequest{Key: "key"})
+	require.NoError(t, err)
+	assert.Nil(t, res.Data)
+
+	require.NoError(t, store.Set(&state.SetRequest{Key: "key", Value: []byte("1")}))
+	res, err = store.Get(&state.GetRequest{Key: "key"})
+	require.NoError(t, err)
+	assert.Equal(t, []byte("1"), res.Data)
+	require.NotNil(t, res.ETag)
+
+	etag := *res.ETag
+	require.NoError(t, store.Set(&state.SetRequest{Key: "key", Value: []byte("2"), ETag: &etag}))
+
+	// The record was changed since etag
+	var etagErr *state.ETagError
+	err = store.Set(&state.SetRequest{Key: "key", Value: []byte("3"), ETag: &etag})
+	require.True(t, errors.As(err, &etagErr))
+	assert.Equal(t, state.ETagMismatch, etagErr.Kind())
+	assert.Error(t, store.Delete(&state.DeleteRequest{Key: "key", ETag: &etag}))
+
+	require.NoError(t, store.Delete(&state.DeleteRequest{Key: "key"}))
+	res, err = store.Get(&state.GetRequest{Key: "key"})
+	require.NoError(t, err)
+	assert.Nil(t, res.Data)
+}
+
+func TestMemoryStoreFirstWriteInsert(t *testing.T) {
+	store := NewMemoryStore()
+	insert := &state.SetRequest{
+		Key:     "key",
+		Value:   []byte("1"),
+		Options: state.SetStateOption{Concurrency: state.FirstWrite},
+	}
+
+	require.NoError(t, store.Set(insert))
+
+	var etagErr *state.ETagError
+	err := store.Set(insert)
+	require.True(t, errors.As(err, &etagErr))
+	assert.Equal(t, state.ETagMismatch, etagErr.Kind())
+
+	// Last-write Sets without an ETag still overwrite the record
+	require.NoError(t, store.Set(&state.SetRequest{Key: "key", Value: []byte("2")}))
+	res, err := store.Get(&state.GetRequest{Key: "key"})
+	require.NoError(t, err)
+	assert.Equal(t, []byte("2"), res.Data)
+}
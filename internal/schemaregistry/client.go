@@ -0,0 +1,242 @@
+// ------------------------------------------------------------
+// Copyright (c) Microsoft Corporation and Dapr Contributors.
+// Licensed under the MIT License.
+// ------------------------------------------------------------
+
+// Package schemaregistry implements a client for the Confluent Schema Registry
+// REST API and a serializer for its wire format.
+package schemaregistry
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+const (
+	contentType = "application/vnd.schemaregistry.v1+json"
+
+	// SchemaTypeAvro is the Avro schema type
+	SchemaTypeAvro = "AVRO"
+	// SchemaTypeProtobuf is the Protobuf schema type
+	SchemaTypeProtobuf = "PROTOBUF"
+	// SchemaTypeJSON is the JSON Schema schema type
+	SchemaTypeJSON = "JSON"
+)
+
+// Schema is a schema registered in the registry.
+type Schema struct {
+	ID         int    `json:"id"`
+	Subject    string `json:"subject,omitempty"`
+	Version    int    `json:"version,omitempty"`
+	SchemaType string `json:"schemaType,omitempty"`
+	Schema     string `json:"schema"`
+}
+
+type cachedSchema struct {
+	schema  *Schema
+	fetched time.Time
+}
+
+// Client talks to a schema registry. Schemas looked up by id are immutable
+// and cached forever, the latest schema of a subject is cached for latestTTL.
+type Client struct {
+	url       string
+	username  string
+	password  string
+	latestTTL time.Duration
+	http      *http.Client
+
+	byID     map[int]*Schema
+	bySubj   map[string]cachedSchema
+	cacheMux sync.RWMutex
+}
+
+// NewClient returns a schema registry client. username and password are
+// used for basic authentication (API key and secret on Confluent Cloud).
+func NewClient(registryURL, username, password string, timeout, latestTTL time.Duration) *Client {
+	return &Client{
+		url:       strings.TrimSuffix(registryURL, "/"),
+		username:  username,
+		password:  password,
+		latestTTL: latestTTL,
+		http:      &http.Client{Timeout: timeout},
+		byID:      map[int]*Schema{},
+		bySubj:    map[string]cachedSchema{},
+	}
+}
+
+// GetSchemaByID returns the schema with the given id.
+func (c *Client) GetSchemaByID(id int) (*Schema, error) {
+	c.cacheMux.RLock()
+	schema, ok := c.byID[id]
+	c.cacheMux.RUnlock()
+	if ok {
+		return schema, nil
+	}
+
+	schema = &Schema{}
+	if err := c.do(http.MethodGet, fmt.Sprintf("/schemas/ids/%d", id), nil, schema); err != nil {
+		return nil, err
+	}
+	schema.ID = id
+	if schema.SchemaType == "" {
+		schema.SchemaType = SchemaTypeAvro
+	}
+
+	c.cacheMux.Lock()
+	c.byID[id] = schema
+	c.cacheMux.Unlock()
+
+	return schema, nil
+}
+
+// GetLatestSchema returns the latest version of the schema registered under subject.
+func (c *Client) GetLatestSchema(subject string) (*Schema, error) {
+	c.cacheMux.RLock()
+	cached, ok := c.bySubj[subject]
+	c.cacheMux.RUnlock()
+	if ok && time.Since(cached.fetched) < c.latestTTL {
+		return cached.schema, nil
+	}
+
+	schema := &Schema{}
+	if err := c.do(http.MethodGet, fmt.Sprintf("/subjects/%s/versions/latest", url.PathEscape(subject)), nil, schema); err != nil {
+		return nil, err
+	}
+	if schema.SchemaType == "" {
+		schema.SchemaType = SchemaTypeAvro
+	}
+
+	c.cacheMux.Lock()
+	c.bySubj[subject] = cachedSchema{schema: schema, fetched: time.Now()}
+	c.byID[schema.ID] = schema
+	c.cacheMux.Unlock()
+
+	return schema, nil
+}
+
+// RegisterSchema registers a schema under subject and returns its id.
+// Registering a schema that already exists returns the existing id.
+func (c *Client) RegisterSchema(subject, schemaType, schema string) (int, error) {
+	req := Schema{Schema: schema}
+	if schemaType != SchemaTypeAvro {
+		req.SchemaType = schemaType
+	}
+
+	var res struct {
+		ID int `json:"id"`
+	}
+	if err := c.do(http.MethodPost, fmt.Sprintf("/subjects/%s/versions", url.PathEscape(subject)), req, &res); err != nil {
+		return 0, err
+	}
+
+	c.cacheMux.Lock()
+	c.byID[res.ID] = &Schema{ID: res.ID, Subject: subject, SchemaType: schemaType, Schema: schema}
+	c.cacheMux.Unlock()
+
+	return res.ID, nil
+}
+
+// IsCompatible checks a schema against the latest version registered under subject.
+func (c *Client) IsCompatible(subject, schemaType, schema string) (bool, error) {
+	req := Schema{Schema: schema}
+	if schemaType != SchemaTypeAvro {
+		req.SchemaType = schemaType
+	}
+
+	var res struct {
+		IsCompatible bool `json:"is_compatible"`
+	}
+	err := c.do(http.MethodPost, fmt.Sprintf("/compatibility/subjects/%s/versions/latest", url.PathEscape(subject)), req, &res)
+	if err != nil {
+		if regErr, ok := err.(*RegistryError); ok && regErr.ErrorCode == errorCodeSubjectNotFound {
+			// Nothing registered yet, any schema is compatible
+			return true, nil
+		}
+
+		return false, err
+	}
+
+	return res.IsCompatible, nil
+}
+
+// SetCompatibility sets the compatibility level of subject, for example BACKWARD or FULL_TRANSITIVE.
+func (c *Client) SetCompatibility(subject, level string) error {
+	req := struct {
+		Compatibility string `json:"compatibility"`
+	}{level}
+
+	return c.do(http.MethodPut, fmt.Sprintf("/config/%s", url.PathEscape(subject)), req, nil)
+}
+
+const errorCodeSubjectNotFound = 40401
+
+// RegistryError is an error returned by the schema registry.
+type RegistryError struct {
+	StatusCode int    `json:"-"`
+	ErrorCode  int    `json:"error_code"`
+	Message    string `json:"message"`
+}
+
+func (e *RegistryError) Error() string {
+	return fmt.Sprintf("schema registry error %d (status %d): %s", e.ErrorCode, e.StatusCode, e.Message)
+}
+
+func (c *Client) do(method, path string, body interface{}, result interface{}) error {
+	var reader *bytes.Reader
+	if body != nil {
+		b, err := jsoniter.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(b)
+	} else {
+		reader = bytes.NewReader(nil)
+	}
+
+	req, err := http.NewRequest(method, c.url+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", contentType)
+	if body != nil {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if c.username != "" {
+		req.SetBasicAuth(c.username, c.password)
+	}
+
+	res, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("schema registry error: %s", err)
+	}
+	defer res.Body.Close()
+
+	b, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		regErr := &RegistryError{StatusCode: res.StatusCode}
+		if jsoniter.Unmarshal(b, regErr) != nil || regErr.Message == "" {
+			regErr.Message = string(b)
+		}
+
+		return regErr
+	}
+
+	if result == nil {
+		return nil
+	}
+
+	return jsoniter.Unmarshal(b, result)
+}
@@ -0,0 +1,191 @@
+// ------------------------------------------------------------
+// Copyright (c) Microsoft Corporation and Dapr Contributors.
+// Licensed under the MIT License.
+// ------------------------------------------------------------
+
+package schemaregistry
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/desc/protoparse"
+	"github.com/jhump/protoreflect/dynamic"
+	"github.com/linkedin/goavro/v2"
+	"github.com/xeipuuv/gojsonschema"
+)
+
+// codec converts between JSON and the binary encoding of a schema.
+type codec interface {
+	// encode validates the JSON document and returns its encoding, without the wire format header
+	encode(data []byte) ([]byte, error)
+	// decode returns the JSON document of an encoded payload, without the wire format header
+	decode(payload []byte) ([]byte, error)
+}
+
+func newCodec(schema *Schema) (codec, error) {
+	switch strings.ToUpper(schema.SchemaType) {
+	case SchemaTypeAvro, "":
+		return newAvroCodec(schema.Schema)
+	case SchemaTypeJSON:
+		return newJSONSchemaCodec(schema.Schema)
+	case SchemaTypeProtobuf:
+		return newProtobufCodec(schema.Schema)
+	default:
+		return nil, fmt.Errorf("schema registry error: unsupported schema type %s", schema.SchemaType)
+	}
+}
+
+type avroCodec struct {
+	codec *goavro.Codec
+}
+
+func newAvroCodec(schema string) (*avroCodec, error) {
+	c, err := goavro.NewCodec(schema)
+	if err != nil {
+		return nil, fmt.Errorf("schema registry error: invalid avro schema: %s", err)
+	}
+
+	return &avroCodec{codec: c}, nil
+}
+
+func (a *avroCodec) encode(data []byte) ([]byte, error) {
+	native, _, err := a.codec.NativeFromTextual(data)
+	if err != nil {
+		return nil, fmt.Errorf("schema registry error: data does not match avro schema: %s", err)
+	}
+
+	return a.codec.BinaryFromNative(nil, native)
+}
+
+func (a *avroCodec) decode(payload []byte) ([]byte, error) {
+	native, _, err := a.codec.NativeFromBinary(payload)
+	if err != nil {
+		return nil, fmt.Errorf("schema registry error: can't decode avro payload: %s", err)
+	}
+
+	return a.codec.TextualFromNative(nil, native)
+}
+
+type jsonSchemaCodec struct {
+	schema *gojsonschema.Schema
+}
+
+func newJSONSchemaCodec(schema string) (*jsonSchemaCodec, error) {
+	s, err := gojsonschema.NewSchema(gojsonschema.NewStringLoader(schema))
+	if err != nil {
+		return nil, fmt.Errorf("schema registry error: invalid json schema: %s", err)
+	}
+
+	return &jsonSchemaCodec{schema: s}, nil
+}
+
+func (j *jsonSchemaCodec) validate(data []byte) error {
+	result, err := j.schema.Validate(gojsonschema.NewBytesLoader(data))
+	if err != nil {
+		return fmt.Errorf("schema registry error: invalid json document: %s", err)
+	}
+	if !result.Valid() {
+		errs := make([]string, 0, len(result.Errors()))
+		for _, e := range result.Errors() {
+			errs = append(errs, e.String())
+		}
+
+		return fmt.Errorf("schema registry error: data does not match json schema: %s", strings.Join(errs, "; "))
+	}
+
+	return nil
+}
+
+func (j *jsonSchemaCodec) encode(data []byte) ([]byte, error) {
+	if err := j.validate(data); err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+func (j *jsonSchemaCodec) decode(payload []byte) ([]byte, error) {
+	return payload, nil
+}
+
+// protobufCodec encodes the first message type of the schema. Payloads are
+// prefixed with the message indexes that locate the message type in the schema.
+type protobufCodec struct {
+	file *desc.FileDescriptor
+}
+
+const protobufSchemaFile = "schema.proto"
+
+func newProtobufCodec(schema string) (*protobufCodec, error) {
+	parser := protoparse.Parser{
+		Accessor: protoparse.FileContentsFromMap(map[string]string{protobufSchemaFile: schema}),
+	}
+	files, err := parser.ParseFiles(protobufSchemaFile)
+	if err != nil {
+		return nil, fmt.Errorf("schema registry error: invalid protobuf schema: %s", err)
+	}
+	if len(files[0].GetMessageTypes()) == 0 {
+		return nil, errors.New("schema registry error: protobuf schema has no message types")
+	}
+
+	return &protobufCodec{file: files[0]}, nil
+}
+
+func (p *protobufCodec) encode(data []byte) ([]byte, error) {
+	msg := dynamic.NewMessage(p.file.GetMessageTypes()[0])
+	if err := msg.UnmarshalJSON(data); err != nil {
+		return nil, fmt.Errorf("schema registry error: data does not match protobuf schema: %s", err)
+	}
+
+	b, err := msg.Marshal()
+	if err != nil {
+		return nil, err
+	}
+
+	// The message indexes [0] of the first message type are encoded as a single zero
+	return append([]byte{0}, b...), nil
+}
+
+func (p *protobufCodec) decode(payload []byte) ([]byte, error) {
+	md, n, err := p.messageDescriptor(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	msg := dynamic.NewMessage(md)
+	if err := msg.Unmarshal(payload[n:]); err != nil {
+		return nil, fmt.Errorf("schema registry error: can't decode protobuf payload: %s", err)
+	}
+
+	return msg.MarshalJSON()
+}
+
+// messageDescriptor resolves the message indexes at the start of payload,
+// a zigzag varint count followed by the indexes, and returns the bytes read.
+func (p *protobufCodec) messageDescriptor(payload []byte) (*desc.MessageDescriptor, int, error) {
+	count, read := binary.Varint(payload)
+	if read <= 0 || count < 0 {
+		return nil, 0, errors.New("schema registry error: invalid protobuf message indexes")
+	}
+	if count == 0 {
+		return p.file.GetMessageTypes()[0], read, nil
+	}
+
+	var md *desc.MessageDescriptor
+	types := p.file.GetMessageTypes()
+	for i := int64(0); i < count; i++ {
+		index, n := binary.Varint(payload[read:])
+		if n <= 0 || index < 0 || int(index) >= len(types) {
+			return nil, 0, errors.New("schema registry error: invalid protobuf message indexes")
+		}
+		read += n
+		md = types[index]
+		types = md.GetNestedMessageTypes()
+	}
+
+	return md, read, nil
+}
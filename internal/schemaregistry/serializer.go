@@ -0,0 +1,299 @@
+// ------------------------------------------------------------
+// Copyright (c) Microsoft Corporation and Dapr Contributors.
+// Licensed under the MIT License.
+// ------------------------------------------------------------
+
+package schemaregistry
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	registryURLKey       = "schemaRegistryURL"
+	registryAPIKeyKey    = "schemaRegistryAPIKey"
+	registryAPISecretKey = "schemaRegistryAPISecret"
+	registryTimeoutKey   = "schemaRegistryTimeoutInSec"
+	schemaTypeKey        = "schemaType"
+	valueSchemaKey       = "valueSchema"
+	autoRegisterKey      = "autoRegisterSchemas"
+	compatibilityKey     = "schemaCompatibility"
+	latestCacheTTLKey    = "schemaLatestVersionCacheTTLInSec"
+	skipUndecodableKey   = "skipUndecodableMessages"
+
+	defaultTimeout        = 10 * time.Second
+	defaultLatestCacheTTL = 5 * time.Minute
+
+	// magicByte starts every payload in the schema registry wire format
+	magicByte  = byte(0)
+	headerSize = 5
+)
+
+// nolint: gochecknoglobals
+var compatibilityLevels = []string{
+	"NONE", "BACKWARD", "BACKWARD_TRANSITIVE", "FORWARD", "FORWARD_TRANSITIVE", "FULL", "FULL_TRANSITIVE",
+}
+
+// Serializer converts JSON documents to and from the schema registry wire
+// format: a zero magic byte, the 4 byte big-endian schema id and the payload
+// encoded with that schema. Value schemas are looked up under the
+// "<topic>-value" subject.
+type Serializer struct {
+	client        *Client
+	schemaType    string
+	valueSchema   string
+	autoRegister  bool
+	compatibility string
+	// skipUndecodable lets consumers skip the messages that can't be decoded
+	skipUndecodable bool
+
+	registered map[string]int
+	codecs     map[int]codec
+	lock       sync.Mutex
+}
+
+// NewSerializerFromMetadata returns a serializer configured by the component
+// metadata, or nil when no schema registry is configured.
+func NewSerializerFromMetadata(properties map[string]string) (*Serializer, error) {
+	registryURL, ok := properties[registryURLKey]
+	if !ok || registryURL == "" {
+		return nil, nil
+	}
+
+	s := &Serializer{
+		schemaType: SchemaTypeAvro,
+		registered: map[string]int{},
+		codecs:     map[int]codec{},
+	}
+
+	if val, ok := properties[schemaTypeKey]; ok && val != "" {
+		switch strings.ToUpper(val) {
+		case SchemaTypeAvro, SchemaTypeProtobuf, SchemaTypeJSON:
+			s.schemaType = strings.ToUpper(val)
+		default:
+			return nil, fmt.Errorf("schema registry error: invalid %s '%s', use avro, protobuf or json", schemaTypeKey, val)
+		}
+	}
+
+	s.valueSchema = properties[valueSchemaKey]
+	if s.valueSchema != "" {
+		// Fail fast on schemas that can't be compiled
+		if _, err := newCodec(&Schema{SchemaType: s.schemaType, Schema: s.valueSchema}); err != nil {
+			return nil, err
+		}
+	}
+
+	if val, ok := properties[autoRegisterKey]; ok && val != "" {
+		autoRegister, err := strconv.ParseBool(val)
+		if err != nil {
+			return nil, fmt.Errorf("schema registry error: can't parse %s: %s", autoRegisterKey, err)
+		}
+		s.autoRegister = autoRegister
+	}
+	if s.autoRegister && s.valueSchema == "" {
+		return nil, fmt.Errorf("schema registry error: %s requires %s", autoRegisterKey, valueSchemaKey)
+	}
+
+	if val, ok := properties[compatibilityKey]; ok && val != "" {
+		s.compatibility = strings.ToUpper(val)
+		valid := false
+		for _, level := range compatibilityLevels {
+			if level == s.compatibility {
+				valid = true
+			}
+		}
+		if !valid {
+			return nil, fmt.Errorf("schema registry error: invalid %s '%s'", compatibilityKey, val)
+		}
+	}
+
+	if val, ok := properties[skipUndecodableKey]; ok && val != "" {
+		skipUndecodable, err := strconv.ParseBool(val)
+		if err != nil {
+			return nil, fmt.Errorf("schema registry error: can't parse %s: %s", skipUndecodableKey, err)
+		}
+		s.skipUndecodable = skipUndecodable
+	}
+
+	timeout := defaultTimeout
+	if val, ok := properties[registryTimeoutKey]; ok && val != "" {
+		seconds, err := strconv.Atoi(val)
+		if err != nil || seconds <= 0 {
+			return nil, fmt.Errorf("schema registry error: invalid %s '%s'", registryTimeoutKey, val)
+		}
+		timeout = time.Duration(seconds) * time.Second
+	}
+
+	latestTTL := defaultLatestCacheTTL
+	if val, ok := properties[latestCacheTTLKey]; ok && val != "" {
+		seconds, err := strconv.Atoi(val)
+		if err != nil || seconds < 0 {
+			return nil, fmt.Errorf("schema registry error: invalid %s '%s'", latestCacheTTLKey, val)
+		}
+		latestTTL = time.Duration(seconds) * time.Second
+	}
+
+	s.client = NewClient(registryURL, properties[registryAPIKeyKey], properties[registryAPISecretKey], timeout, latestTTL)
+
+	return s, nil
+}
+
+// ValueSubject returns the subject of the value schema of a topic.
+func ValueSubject(topic string) string {
+	return topic + "-value"
+}
+
+// Serialize validates a JSON document against the value schema of topic and
+// returns it in the wire format.
+func (s *Serializer) Serialize(topic string, data []byte) ([]byte, error) {
+	id, err := s.valueSchemaID(ValueSubject(topic))
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := s.codec(id)
+	if err != nil {
+		return nil, err
+	}
+
+	payload, err := c.encode(data)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]byte, headerSize, headerSize+len(payload))
+	out[0] = magicByte
+	binary.BigEndian.PutUint32(out[1:headerSize], uint32(id))
+
+	return append(out, payload...), nil
+}
+
+// DecodeError is returned by Deserialize when a payload can't be decoded:
+// it is not in the wire format, its schema doesn't exist or is invalid, or it
+// doesn't match its schema. Other errors, such as the registry being
+// unreachable, are transient and worth retrying.
+type DecodeError struct {
+	Err error
+}
+
+func (e *DecodeError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// IsDecodeError reports whether err is a DecodeError, which retrying won't fix.
+func IsDecodeError(err error) bool {
+	var decodeErr *DecodeError
+
+	return errors.As(err, &decodeErr)
+}
+
+// SkipUndecodable reports whether consumers should skip the messages that
+// can't be decoded, instead of stopping at them.
+func (s *Serializer) SkipUndecodable() bool {
+	return s.skipUndecodable
+}
+
+// Deserialize decodes a payload in the wire format to a JSON document.
+func (s *Serializer) Deserialize(data []byte) ([]byte, error) {
+	if !IsWireFormat(data) {
+		return nil, &DecodeError{errors.New("schema registry error: payload is not in the schema registry wire format")}
+	}
+
+	c, err := s.codec(int(binary.BigEndian.Uint32(data[1:headerSize])))
+	if err != nil {
+		var regErr *RegistryError
+		if errors.As(err, &regErr) && regErr.StatusCode == http.StatusNotFound {
+			return nil, &DecodeError{err}
+		}
+
+		return nil, err
+	}
+
+	decoded, err := c.decode(data[headerSize:])
+	if err != nil {
+		return nil, &DecodeError{err}
+	}
+
+	return decoded, nil
+}
+
+// IsWireFormat reports whether data starts with the schema registry wire format header.
+func IsWireFormat(data []byte) bool {
+	return len(data) > headerSize && data[0] == magicByte
+}
+
+func (s *Serializer) valueSchemaID(subject string) (int, error) {
+	if !s.autoRegister {
+		schema, err := s.client.GetLatestSchema(subject)
+		if err != nil {
+			return 0, err
+		}
+
+		return schema.ID, nil
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if id, ok := s.registered[subject]; ok {
+		return id, nil
+	}
+
+	if s.compatibility != "" {
+		if err := s.client.SetCompatibility(subject, s.compatibility); err != nil {
+			return 0, err
+		}
+
+		compatible, err := s.client.IsCompatible(subject, s.schemaType, s.valueSchema)
+		if err != nil {
+			return 0, err
+		}
+		if !compatible {
+			return 0, fmt.Errorf("schema registry error: schema is not %s compatible with the latest version of %s", s.compatibility, subject)
+		}
+	}
+
+	id, err := s.client.RegisterSchema(subject, s.schemaType, s.valueSchema)
+	if err != nil {
+		return 0, err
+	}
+	s.registered[subject] = id
+
+	return id, nil
+}
+
+func (s *Serializer) codec(id int) (codec, error) {
+	s.lock.Lock()
+	c, ok := s.codecs[id]
+	s.lock.Unlock()
+	if ok {
+		return c, nil
+	}
+
+	schema, err := s.client.GetSchemaByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	c, err = newCodec(schema)
+	if err != nil {
+		return nil, &DecodeError{err}
+	}
+
+	s.lock.Lock()
+	s.codecs[id] = c
+	s.lock.Unlock()
+
+	return c, nil
+}
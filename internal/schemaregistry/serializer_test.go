@@ -0,0 +1,280 @@
+// ------------------------------------------------------------
+// Copyright (c) Microsoft Corporation and Dapr Contributors.
+// Licensed under the MIT License.
+// ------------------------------------------------------------
+
+package schemaregistry
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	avroSchema  = `{"type":"record","name":"Order","fields":[{"name":"id","type":"string"},{"name":"amount","type":"int"}]}`
+	jsonSchema  = `{"type":"object","properties":{"id":{"type":"string"},"amount":{"type":"integer"}},"required":["id","amount"]}`
+	protoSchema = `syntax = "proto3";
+message Order {
+  string id = 1;
+  int32 amount = 2;
+}`
+)
+
+// fakeRegistry is a minimal in-memory stand-in for the schema registry REST API.
+type fakeRegistry struct {
+	schemas       map[int]Schema
+	subjects      map[string][]int
+	compatibility map[string]string
+	incompatible  bool
+	requests      int
+	lock          sync.Mutex
+}
+
+func newFakeRegistry() *fakeRegistry {
+	return &fakeRegistry{
+		schemas:       map[int]Schema{},
+		subjects:      map[string][]int{},
+		compatibility: map[string]string{},
+	}
+}
+
+func (f *fakeRegistry) register(subject string, schema Schema) int {
+	for _, id := range f.subjects[subject] {
+		if f.schemas[id].Schema == schema.Schema {
+			return id
+		}
+	}
+	schema.ID = len(f.schemas) + 1
+	schema.Subject = subject
+	schema.Version = len(f.subjects[subject]) + 1
+	f.schemas[schema.ID] = schema
+	f.subjects[subject] = append(f.subjects[subject], schema.ID)
+
+	return schema.ID
+}
+
+func (f *fakeRegistry) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.requests++
+
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	reply := func(status int, body interface{}) {
+		w.Header().Set("Content-Type", contentType)
+		w.WriteHeader(status)
+		b, _ := json.Marshal(body)
+		w.Write(b)
+	}
+	notFound := func() {
+		reply(http.StatusNotFound, RegistryError{ErrorCode: errorCodeSubjectNotFound, Message: "Subject not found"})
+	}
+
+	switch {
+	case r.Method == http.MethodGet && len(parts) == 3 && parts[0] == "schemas":
+		id, _ := strconv.Atoi(parts[2])
+		schema, ok := f.schemas[id]
+		if !ok {
+			reply(http.StatusNotFound, RegistryError{ErrorCode: 40403, Message: "Schema not found"})
+
+			return
+		}
+		reply(http.StatusOK, Schema{SchemaType: schema.SchemaType, Schema: schema.Schema})
+	case r.Method == http.MethodGet && len(parts) == 4 && parts[0] == "subjects":
+		ids := f.subjects[parts[1]]
+		if len(ids) == 0 {
+			notFound()
+
+			return
+		}
+		reply(http.StatusOK, f.schemas[ids[len(ids)-1]])
+	case r.Method == http.MethodPost && len(parts) == 3 && parts[0] == "subjects":
+		var schema Schema
+		json.NewDecoder(r.Body).Decode(&schema)
+		reply(http.StatusOK, map[string]int{"id": f.register(parts[1], schema)})
+	case r.Method == http.MethodPost && parts[0] == "compatibility":
+		if len(f.subjects[parts[2]]) == 0 {
+			notFound()
+
+			return
+		}
+		reply(http.StatusOK, map[string]bool{"is_compatible": !f.incompatible})
+	case r.Method == http.MethodPut && parts[0] == "config":
+		var req map[string]string
+		json.NewDecoder(r.Body).Decode(&req)
+		f.compatibility[parts[1]] = req["compatibility"]
+		reply(http.StatusOK, req)
+	default:
+		reply(http.StatusNotFound, RegistryError{ErrorCode: 404, Message: fmt.Sprintf("unexpected %s %s", r.Method, r.URL.Path)})
+	}
+}
+
+func newTestSerializer(t *testing.T, registry *fakeRegistry, properties map[string]string) *Serializer {
+	server := httptest.NewServer(registry)
+	t.Cleanup(server.Close)
+
+	properties[registryURLKey] = server.URL
+	s, err := NewSerializerFromMetadata(properties)
+	require.NoError(t, err)
+	require.NotNil(t, s)
+
+	return s
+}
+
+func TestNewSerializerFromMetadata(t *testing.T) {
+	t.Run("not configured", func(t *testing.T) {
+		s, err := NewSerializerFromMetadata(map[string]string{"brokers": "localhost:9092"})
+		assert.NoError(t, err)
+		assert.Nil(t, s)
+	})
+
+	t.Run("invalid schema type", func(t *testing.T) {
+		_, err := NewSerializerFromMetadata(map[string]string{registryURLKey: "http://localhost", schemaTypeKey: "xml"})
+		assert.Error(t, err)
+	})
+
+	t.Run("invalid local schema", func(t *testing.T) {
+		_, err := NewSerializerFromMetadata(map[string]string{registryURLKey: "http://localhost", valueSchemaKey: "{"})
+		assert.Error(t, err)
+	})
+
+	t.Run("auto register without schema", func(t *testing.T) {
+		_, err := NewSerializerFromMetadata(map[string]string{registryURLKey: "http://localhost", autoRegisterKey: "true"})
+		assert.Error(t, err)
+	})
+
+	t.Run("invalid compatibility", func(t *testing.T) {
+		_, err := NewSerializerFromMetadata(map[string]string{registryURLKey: "http://localhost", compatibilityKey: "sideways"})
+		assert.Error(t, err)
+	})
+}
+
+func TestSerializeRoundTrip(t *testing.T) {
+	tests := []struct {
+		schemaType string
+		schema     string
+	}{
+		{"avro", avroSchema},
+		{"json", jsonSchema},
+		{"protobuf", protoSchema},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.schemaType, func(t *testing.T) {
+			registry := newFakeRegistry()
+			s := newTestSerializer(t, registry, map[string]string{
+				schemaTypeKey:   tt.schemaType,
+				valueSchemaKey:  tt.schema,
+				autoRegisterKey: "true",
+			})
+
+			encoded, err := s.Serialize("orders", []byte(`{"id":"a1","amount":42}`))
+			require.NoError(t, err)
+			assert.True(t, IsWireFormat(encoded))
+			assert.Equal(t, []byte{0, 0, 0, 0, 1}, encoded[:headerSize])
+			assert.Len(t, registry.subjects["orders-value"], 1)
+			if tt.schemaType != "avro" {
+				assert.Equal(t, strings.ToUpper(tt.schemaType), registry.schemas[1].SchemaType)
+			}
+
+			// A fresh serializer only knows the id from the payload
+			reader := newTestSerializer(t, registry, map[string]string{})
+			decoded, err := reader.Deserialize(encoded)
+			require.NoError(t, err)
+			assert.JSONEq(t, `{"id":"a1","amount":42}`, string(decoded))
+
+			_, err = s.Serialize("orders", []byte(`{"id":"a1","amount":"lots"}`))
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestSerializeLatestSchema(t *testing.T) {
+	registry := newFakeRegistry()
+	registry.register("orders-value", Schema{Schema: avroSchema})
+	s := newTestSerializer(t, registry, map[string]string{})
+
+	encoded, err := s.Serialize("orders", []byte(`{"id":"a1","amount":42}`))
+	require.NoError(t, err)
+	_, err = s.Serialize("orders", []byte(`{"id":"a2","amount":7}`))
+	require.NoError(t, err)
+	// the latest schema is cached and also resolves the codec by id
+	assert.Equal(t, 1, registry.requests)
+
+	decoded, err := s.Deserialize(encoded)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"id":"a1","amount":42}`, string(decoded))
+
+	_, err = s.Serialize("payments", []byte(`{"id":"a1","amount":42}`))
+	var regErr *RegistryError
+	require.ErrorAs(t, err, &regErr)
+	assert.Equal(t, errorCodeSubjectNotFound, regErr.ErrorCode)
+}
+
+func TestSerializeCompatibility(t *testing.T) {
+	registry := newFakeRegistry()
+	registry.register("orders-value", Schema{Schema: `{"type":"record","name":"Order","fields":[{"name":"id","type":"string"}]}`})
+	properties := map[string]string{
+		valueSchemaKey:   avroSchema,
+		autoRegisterKey:  "true",
+		compatibilityKey: "full_transitive",
+	}
+
+	registry.incompatible = true
+	s := newTestSerializer(t, registry, properties)
+	_, err := s.Serialize("orders", []byte(`{"id":"a1","amount":42}`))
+	assert.Error(t, err)
+	assert.Equal(t, "FULL_TRANSITIVE", registry.compatibility["orders-value"])
+	assert.Len(t, registry.subjects["orders-value"], 1)
+
+	registry.incompatible = false
+	encoded, err := s.Serialize("orders", []byte(`{"id":"a1","amount":42}`))
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0, 0, 0, 0, 2}, encoded[:headerSize])
+}
+
+func TestDeserializeInvalid(t *testing.T) {
+	registry := newFakeRegistry()
+	id := registry.register("orders-value", Schema{SchemaType: SchemaTypeAvro, Schema: avroSchema})
+	s := newTestSerializer(t, registry, map[string]string{})
+	assert.False(t, s.SkipUndecodable())
+
+	_, err := s.Deserialize([]byte(`{"id":"a1"}`))
+	assert.True(t, IsDecodeError(err))
+
+	// unknown schema id
+	_, err = s.Deserialize([]byte{0, 0, 0, 0, 9, 1})
+	assert.True(t, IsDecodeError(err))
+
+	// payload not matching its schema
+	_, err = s.Deserialize([]byte{0, 0, 0, 0, byte(id), 0xff})
+	assert.True(t, IsDecodeError(err))
+}
+
+func TestDeserializeRegistryUnavailable(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	s, err := NewSerializerFromMetadata(map[string]string{registryURLKey: server.URL, skipUndecodableKey: "true"})
+	require.NoError(t, err)
+	assert.True(t, s.SkipUndecodable())
+
+	_, err = s.Deserialize([]byte{0, 0, 0, 0, 1, 2})
+	assert.Error(t, err)
+	assert.False(t, IsDecodeError(err), "registry errors are transient")
+
+	server.Close()
+	_, err = s.Deserialize([]byte{0, 0, 0, 0, 1, 2})
+	assert.Error(t, err)
+	assert.False(t, IsDecodeError(err), "network errors are transient")
+}
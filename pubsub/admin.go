@@ -0,0 +1,24 @@
+// ------------------------------------------------------------
+// Copyright (c) Microsoft Corporation and Dapr Contributors.
+// Licensed under the MIT License.
+// ------------------------------------------------------------
+
+package pubsub
+
+import "errors"
+
+// ErrNotSupported is returned by the TopicAdmin operations that the broker
+// of a component can't perform.
+var ErrNotSupported = errors.New("operation not supported by the pubsub component")
+
+// TopicAdmin is implemented by PubSub components that can manage the topics
+// and subscriptions of their broker. A subscription is the entity that holds
+// the messages of a topic for one consumer group, such as a Kafka consumer
+// group or a RabbitMQ queue.
+type TopicAdmin interface {
+	CreateTopic(req CreateTopicRequest) error
+	DeleteTopic(req DeleteTopicRequest) error
+	ListTopics() ([]string, error)
+	ListSubscriptions(topic string) ([]string, error)
+	GetSubscriptionLag(req SubscriptionLagRequest) (*SubscriptionLagResponse, error)
+}
@@ -0,0 +1,185 @@
+package snssqs
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/aws/aws-sdk-go/aws"
+	sns "github.com/aws/aws-sdk-go/service/sns"
+	sqs "github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/dapr/components-contrib/pubsub"
+)
+
+// CreateTopic creates the SNS topic of a topic. SNS topics don't keep
+// messages, so partitions and retention are ignored.
+func (s *snsSqs) CreateTopic(req pubsub.CreateTopicRequest) error {
+	_, err := s.getOrCreateTopic(req.Topic)
+
+	return err
+}
+
+// DeleteTopic deletes the SNS topic of a topic and its subscriptions.
+func (s *snsSqs) DeleteTopic(req pubsub.DeleteTopicRequest) error {
+	topicArn, err := s.findTopicArn(req.Topic)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.snsClient.DeleteTopic(&sns.DeleteTopicInput{TopicArn: aws.String(topicArn)})
+	if err != nil {
+		return err
+	}
+
+	delete(s.topics, req.Topic)
+	delete(s.topicHash, s.resourceName(req.Topic))
+
+	return nil
+}
+
+// ListTopics returns the topics whose SNS topics were created by this component,
+// which are recognized by their dapr-topic-name tag.
+func (s *snsSqs) ListTopics() ([]string, error) {
+	var arns []string
+	err := s.snsClient.ListTopicsPages(&sns.ListTopicsInput{}, func(page *sns.ListTopicsOutput, lastPage bool) bool {
+		for _, topic := range page.Topics {
+			arns = append(arns, aws.StringValue(topic.TopicArn))
+		}
+
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	topics := make([]string, 0, len(arns))
+	for _, arn := range arns {
+		if topic, ok := s.topicHash[parseTopicArn(arn)]; ok {
+			topics = append(topics, topic)
+
+			continue
+		}
+
+		tags, err := s.snsClient.ListTagsForResource(&sns.ListTagsForResourceInput{ResourceArn: aws.String(arn)})
+		if err != nil {
+			return nil, err
+		}
+		for _, tag := range tags.Tags {
+			if aws.StringValue(tag.Key) == awsSnsTopicNameKey {
+				topics = append(topics, aws.StringValue(tag.Value))
+			}
+		}
+	}
+	sort.Strings(topics)
+
+	return topics, nil
+}
+
+// ListSubscriptions returns the names of the SQS queues subscribed to a topic,
+// which are the consumer IDs of the subscribers.
+func (s *snsSqs) ListSubscriptions(topic string) ([]string, error) {
+	topicArn, err := s.findTopicArn(topic)
+	if err != nil {
+		return nil, err
+	}
+
+	var queueArns []string
+	err = s.snsClient.ListSubscriptionsByTopicPages(&sns.ListSubscriptionsByTopicInput{TopicArn: aws.String(topicArn)}, func(page *sns.ListSubscriptionsByTopicOutput, lastPage bool) bool {
+		for _, subscription := range page.Subscriptions {
+			if aws.StringValue(subscription.Protocol) == "sqs" {
+				queueArns = append(queueArns, aws.StringValue(subscription.Endpoint))
+			}
+		}
+
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	subscriptions := make([]string, 0, len(queueArns))
+	for _, queueArn := range queueArns {
+		queueURL, err := s.sqsClient.GetQueueUrl(&sqs.GetQueueUrlInput{QueueName: aws.String(parseTopicArn(queueArn))})
+		if err != nil {
+			return nil, err
+		}
+
+		tags, err := s.sqsClient.ListQueueTags(&sqs.ListQueueTagsInput{QueueUrl: queueURL.QueueUrl})
+		if err != nil {
+			return nil, err
+		}
+		if name, ok := tags.Tags[awsSqsQueueNameKey]; ok {
+			subscriptions = append(subscriptions, aws.StringValue(name))
+		}
+	}
+	sort.Strings(subscriptions)
+
+	return subscriptions, nil
+}
+
+// GetSubscriptionLag returns the number of messages in the SQS queue of a
+// subscriber, including the messages being handled. A queue receives the
+// messages of all the topics its consumer subscribed to.
+func (s *snsSqs) GetSubscriptionLag(req pubsub.SubscriptionLagRequest) (*pubsub.SubscriptionLagResponse, error) {
+	queueURL, err := s.sqsClient.GetQueueUrl(&sqs.GetQueueUrlInput{QueueName: aws.String(s.resourceName(req.Subscription))})
+	if err != nil {
+		return nil, err
+	}
+
+	attributes, err := s.sqsClient.GetQueueAttributes(&sqs.GetQueueAttributesInput{
+		AttributeNames: []*string{
+			aws.String(sqs.QueueAttributeNameApproximateNumberOfMessages),
+			aws.String(sqs.QueueAttributeNameApproximateNumberOfMessagesNotVisible),
+		},
+		QueueUrl: queueURL.QueueUrl,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var lag int64
+	for _, name := range []string{sqs.QueueAttributeNameApproximateNumberOfMessages, sqs.QueueAttributeNameApproximateNumberOfMessagesNotVisible} {
+		if val, ok := attributes.Attributes[name]; ok {
+			count, err := parseInt64(aws.StringValue(val), name)
+			if err != nil {
+				return nil, err
+			}
+			lag += count
+		}
+	}
+
+	return &pubsub.SubscriptionLagResponse{
+		Topic:        req.Topic,
+		Subscription: req.Subscription,
+		Lag:          lag,
+	}, nil
+}
+
+// findTopicArn returns the ARN of an existing topic without creating it.
+func (s *snsSqs) findTopicArn(topic string) (string, error) {
+	if topicArn, ok := s.topics[topic]; ok {
+		return topicArn, nil
+	}
+
+	name := s.resourceName(topic)
+	var topicArn string
+	err := s.snsClient.ListTopicsPages(&sns.ListTopicsInput{}, func(page *sns.ListTopicsOutput, lastPage bool) bool {
+		for _, t := range page.Topics {
+			if parseTopicArn(aws.StringValue(t.TopicArn)) == name {
+				topicArn = aws.StringValue(t.TopicArn)
+
+				return false
+			}
+		}
+
+		return true
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if topicArn == "" {
+		return "", fmt.Errorf("topic %s not found", topic)
+	}
+
+	return topicArn, nil
+}
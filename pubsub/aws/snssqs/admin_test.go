@@ -0,0 +1,104 @@
+package snssqs
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	sns "github.com/aws/aws-sdk-go/service/sns"
+	sqs "github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/dapr/components-contrib/pubsub"
+	"github.com/dapr/dapr/pkg/logger"
+	"github.com/stretchr/testify/require"
+)
+
+func (s *fakeSNS) ListTopicsPages(input *sns.ListTopicsInput, fn func(*sns.ListTopicsOutput, bool) bool) error {
+	var topics []*sns.Topic
+	if s.f.topicName != "" {
+		topics = append(topics, &sns.Topic{TopicArn: aws.String("arn:aws:sns:us-east-1:000000000000:" + s.f.topicName)})
+	}
+	topics = append(topics, &sns.Topic{TopicArn: aws.String("arn:aws:sns:us-east-1:000000000000:unmanaged")})
+	fn(&sns.ListTopicsOutput{Topics: topics}, true)
+
+	return nil
+}
+
+func (s *fakeSNS) ListTagsForResource(input *sns.ListTagsForResourceInput) (*sns.ListTagsForResourceOutput, error) {
+	if parseTopicArn(*input.ResourceArn) != s.f.topicName {
+		return &sns.ListTagsForResourceOutput{}, nil
+	}
+
+	return &sns.ListTagsForResourceOutput{Tags: s.f.topicTags}, nil
+}
+
+func (s *fakeSNS) DeleteTopic(input *sns.DeleteTopicInput) (*sns.DeleteTopicOutput, error) {
+	s.f.topicName = ""
+
+	return &sns.DeleteTopicOutput{}, nil
+}
+
+func (s *fakeSNS) ListSubscriptionsByTopicPages(input *sns.ListSubscriptionsByTopicInput, fn func(*sns.ListSubscriptionsByTopicOutput, bool) bool) error {
+	subscriptions := make([]*sns.Subscription, 0, len(s.f.subscribedQueues))
+	for _, queueArn := range s.f.subscribedQueues {
+		subscriptions = append(subscriptions, &sns.Subscription{Protocol: aws.String("sqs"), Endpoint: aws.String(queueArn)})
+	}
+	fn(&sns.ListSubscriptionsByTopicOutput{Subscriptions: subscriptions}, true)
+
+	return nil
+}
+
+func (s *fakeSQS) GetQueueUrl(input *sqs.GetQueueUrlInput) (*sqs.GetQueueUrlOutput, error) {
+	return &sqs.GetQueueUrlOutput{QueueUrl: aws.String("https://sqs.us-east-1.amazonaws.com/000000000000/" + *input.QueueName)}, nil
+}
+
+func (s *fakeSQS) ListQueueTags(input *sqs.ListQueueTagsInput) (*sqs.ListQueueTagsOutput, error) {
+	return &sqs.ListQueueTagsOutput{Tags: s.f.queueTags}, nil
+}
+
+func Test_topicAdmin(t *testing.T) {
+	r := require.New(t)
+	fake := newFakeAWS()
+	fake.queueCounts = map[string]*string{
+		sqs.QueueAttributeNameApproximateNumberOfMessages:           aws.String("5"),
+		sqs.QueueAttributeNameApproximateNumberOfMessagesNotVisible: aws.String("2"),
+	}
+	ps := &snsSqs{
+		topics:    map[string]string{},
+		topicHash: map[string]string{},
+		queues:    map[string]*sqsQueueInfo{},
+		snsClient: fake.sns(),
+		sqsClient: fake.sqs(),
+		metadata:  &snsSqsMetadata{sqsQueueName: "consumer", messageMaxNumber: 10},
+		logger:    logger.NewLogger("snssqs-test"),
+	}
+	var admin pubsub.TopicAdmin = ps
+
+	r.NoError(admin.CreateTopic(pubsub.CreateTopicRequest{Topic: "payments"}))
+	r.Equal(nameToHash("payments"), fake.topicName)
+
+	// topics are resolved from their tags when they were not created by this instance
+	ps.topics = map[string]string{}
+	ps.topicHash = map[string]string{}
+	topics, err := admin.ListTopics()
+	r.NoError(err)
+	r.Equal([]string{"payments"}, topics)
+
+	_, err = ps.getOrCreateQueue("consumer")
+	r.NoError(err)
+	_, err = ps.snsClient.Subscribe(&sns.SubscribeInput{
+		Endpoint: aws.String("arn:aws:sqs:us-east-1:000000000000:" + fake.queueName),
+		TopicArn: aws.String("arn:aws:sns:us-east-1:000000000000:" + fake.topicName),
+	})
+	r.NoError(err)
+
+	subscriptions, err := admin.ListSubscriptions("payments")
+	r.NoError(err)
+	r.Equal([]string{"consumer"}, subscriptions)
+
+	lag, err := admin.GetSubscriptionLag(pubsub.SubscriptionLagRequest{Topic: "payments", Subscription: "consumer"})
+	r.NoError(err)
+	r.Equal(int64(7), lag.Lag)
+
+	r.NoError(admin.DeleteTopic(pubsub.DeleteTopicRequest{Topic: "payments"}))
+	r.Empty(fake.topicName)
+	r.Error(admin.DeleteTopic(pubsub.DeleteTopicRequest{Topic: "payments"}))
+}
@@ -7,10 +7,13 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/aws/aws-sdk-go/aws"
 	sns "github.com/aws/aws-sdk-go/service/sns"
+	"github.com/aws/aws-sdk-go/service/sns/snsiface"
 	sqs "github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/aws/aws-sdk-go/service/sqs/sqsiface"
 	aws_auth "github.com/dapr/components-contrib/authentication/aws"
 	"github.com/dapr/components-contrib/pubsub"
 	"github.com/dapr/dapr/pkg/logger"
@@ -23,8 +26,8 @@ type snsSqs struct {
 	topicHash map[string]string
 	// key is the topic name, value holds the ARN of the queue and its url
 	queues        map[string]*sqsQueueInfo
-	snsClient     *sns.SNS
-	sqsClient     *sqs.SQS
+	snsClient     snsiface.SNSAPI
+	sqsClient     sqsiface.SQSAPI
 	metadata      *snsSqsMetadata
 	logger        logger.Logger
 	subscriptions []*string
@@ -58,11 +61,19 @@ type snsSqsMetadata struct {
 	messageWaitTimeSeconds int64
 	// maximum number of messages to receive from the queue at a time. Default: 10, Maximum: 10
 	messageMaxNumber int64
+	// use FIFO topics and queues. Messages are ordered per message group, taken from the partitionKey publish metadata. Default: false
+	fifo bool
 }
 
 const (
 	awsSqsQueueNameKey = "dapr-queue-name"
 	awsSnsTopicNameKey = "dapr-topic-name"
+
+	fifoSuffix = ".fifo"
+	// publish metadata holding the message group of FIFO topics
+	partitionKeyMetadataKey = "partitionKey"
+	// maximum length of a FIFO message deduplication id
+	maxDeduplicationIDLength = 128
 )
 
 func NewSnsSqs(l logger.Logger) pubsub.PubSub {
@@ -192,6 +203,15 @@ func (s *snsSqs) getSnsSqsMetatdata(metadata pubsub.Metadata) (*snsSqsMetadata,
 		md.messageMaxNumber = maxNumber
 	}
 
+	if val, ok := props["fifo"]; ok && val != "" {
+		fifo, err := strconv.ParseBool(val)
+		if err != nil {
+			return nil, fmt.Errorf("parsing fifo failed with: %v", err)
+		}
+
+		md.fifo = fifo
+	}
+
 	return &md, nil
 }
 
@@ -218,12 +238,25 @@ func (s *snsSqs) Init(metadata pubsub.Metadata) error {
 	return nil
 }
 
+// resourceName returns the AWS name of a topic or queue. FIFO topics and queues must end with .fifo.
+func (s *snsSqs) resourceName(name string) string {
+	if s.metadata.fifo {
+		return nameToHash(name) + fifoSuffix
+	}
+
+	return nameToHash(name)
+}
+
 func (s *snsSqs) createTopic(topic string) (string, string, error) {
-	hashedName := nameToHash(topic)
-	createTopicResponse, err := s.snsClient.CreateTopic(&sns.CreateTopicInput{
+	hashedName := s.resourceName(topic)
+	input := &sns.CreateTopicInput{
 		Name: aws.String(hashedName),
 		Tags: []*sns.Tag{{Key: aws.String(awsSnsTopicNameKey), Value: aws.String(topic)}},
-	})
+	}
+	if s.metadata.fifo {
+		input.Attributes = map[string]*string{"FifoTopic": aws.String("true")}
+	}
+	createTopicResponse, err := s.snsClient.CreateTopic(input)
 	if err != nil {
 		return "", "", err
 	}
@@ -259,10 +292,14 @@ func (s *snsSqs) getOrCreateTopic(topic string) (string, error) {
 }
 
 func (s *snsSqs) createQueue(queueName string) (*sqsQueueInfo, error) {
-	createQueueResponse, err := s.sqsClient.CreateQueue(&sqs.CreateQueueInput{
-		QueueName: aws.String(nameToHash(queueName)),
+	input := &sqs.CreateQueueInput{
+		QueueName: aws.String(s.resourceName(queueName)),
 		Tags:      map[string]*string{awsSqsQueueNameKey: aws.String(queueName)},
-	})
+	}
+	if s.metadata.fifo {
+		input.Attributes = map[string]*string{sqs.QueueAttributeNameFifoQueue: aws.String("true")}
+	}
+	createQueueResponse, err := s.sqsClient.CreateQueue(input)
 	if err != nil {
 		return nil, err
 	}
@@ -330,10 +367,16 @@ func (s *snsSqs) Publish(req *pubsub.PublishRequest) error {
 	}
 
 	message := string(req.Data)
-	_, err = s.snsClient.Publish(&sns.PublishInput{
-		Message:  &message,
-		TopicArn: &topicArn,
-	})
+	input := &sns.PublishInput{
+		Message:           &message,
+		MessageAttributes: filterMessageAttributes(req.Data),
+		TopicArn:          &topicArn,
+	}
+	if s.metadata.fifo {
+		input.MessageGroupId = aws.String(messageGroupID(req))
+		input.MessageDeduplicationId = aws.String(deduplicationID(req))
+	}
+	_, err = s.snsClient.Publish(input)
 
 	if err != nil {
 		s.logger.Errorf("error publishing topic %s with topic ARN %s: %v", req.Topic, topicArn, err)
@@ -344,6 +387,43 @@ func (s *snsSqs) Publish(req *pubsub.PublishRequest) error {
 	return nil
 }
 
+// messageGroupID returns the FIFO message group of a published message.
+// Messages without partition key share a single group per topic.
+func messageGroupID(req *pubsub.PublishRequest) string {
+	if val, ok := req.Metadata[partitionKeyMetadataKey]; ok && val != "" {
+		return val
+	}
+
+	return req.Topic
+}
+
+// deduplicationID returns the FIFO deduplication id of a message: the message id
+// from the request metadata, the CloudEvent id when the message is a CloudEvent
+// and a hash of the message otherwise.
+func deduplicationID(req *pubsub.PublishRequest) string {
+	id := req.Metadata[pubsub.MessageIDMetadataKey]
+	if id == "" {
+		var event struct {
+			ID string `json:"id"`
+		}
+		if err := json.Unmarshal(req.Data, &event); err == nil {
+			id = event.ID
+		}
+	}
+
+	if id != "" {
+		if len(id) <= maxDeduplicationIDLength {
+			return id
+		}
+
+		return nameToHash(id)
+	}
+
+	h := sha256.Sum256(req.Data)
+
+	return fmt.Sprintf("%x", h)
+}
+
 type snsMessage struct {
 	Message  string
 	TopicArn string
@@ -417,6 +497,7 @@ func (s *snsSqs) consumeSubscription(queueInfo *sqsQueueInfo, handler func(msg *
 				// use this property to decide when a message should be discarded
 				AttributeNames: []*string{
 					aws.String(sqs.MessageSystemAttributeNameApproximateReceiveCount),
+					aws.String(sqs.MessageSystemAttributeNameMessageGroupId),
 				},
 				MaxNumberOfMessages: aws.Int64(s.metadata.messageMaxNumber),
 				QueueUrl:            &queueInfo.url,
@@ -438,6 +519,12 @@ func (s *snsSqs) consumeSubscription(queueInfo *sqsQueueInfo, handler func(msg *
 
 			s.logger.Debugf("%v message(s) received", len(messageResponse.Messages))
 
+			if s.metadata.fifo {
+				s.handleMessageGroups(messageResponse.Messages, queueInfo, handler)
+
+				continue
+			}
+
 			for _, m := range messageResponse.Messages {
 				if err := s.handleMessage(m, queueInfo, handler); err != nil {
 					s.logger.Error(err)
@@ -447,7 +534,44 @@ func (s *snsSqs) consumeSubscription(queueInfo *sqsQueueInfo, handler func(msg *
 	}()
 }
 
+// handleMessageGroups handles the messages of each FIFO message group sequentially, groups
+// are handled concurrently. When a message fails the remaining messages of its group are
+// left in the queue so that they are redelivered in order after the visibility timeout.
+func (s *snsSqs) handleMessageGroups(messages []*sqs.Message, queueInfo *sqsQueueInfo, handler func(msg *pubsub.NewMessage) error) {
+	var groupIDs []string
+	groups := map[string][]*sqs.Message{}
+	for _, m := range messages {
+		groupID := aws.StringValue(m.Attributes[sqs.MessageSystemAttributeNameMessageGroupId])
+		if _, ok := groups[groupID]; !ok {
+			groupIDs = append(groupIDs, groupID)
+		}
+		groups[groupID] = append(groups[groupID], m)
+	}
+
+	var wg sync.WaitGroup
+	for _, groupID := range groupIDs {
+		wg.Add(1)
+		go func(groupID string, messages []*sqs.Message) {
+			defer wg.Done()
+
+			for i, m := range messages {
+				if err := s.handleMessage(m, queueInfo, handler); err != nil {
+					s.logger.Errorf("%v, skipping %d remaining message(s) of message group %s", err, len(messages)-i-1, groupID)
+
+					return
+				}
+			}
+		}(groupID, groups[groupID])
+	}
+	wg.Wait()
+}
+
 func (s *snsSqs) Subscribe(req pubsub.SubscribeRequest, handler func(msg *pubsub.NewMessage) error) error {
+	handler, err := pubsub.FilterHandler(req, handler)
+	if err != nil {
+		return err
+	}
+
 	// subscribers declare a topic ARN
 	// and declare a SQS queue to use
 	// these should be idempotent
@@ -467,9 +591,13 @@ func (s *snsSqs) Subscribe(req pubsub.SubscribeRequest, handler func(msg *pubsub
 		return err
 	}
 
+	filter, err := pubsub.FilterFromMetadata(req.Metadata)
+	if err != nil {
+		return err
+	}
+
 	// subscription creation is idempotent. Subscriptions are unique by topic/queue
 	subscribeOutput, err := s.snsClient.Subscribe(&sns.SubscribeInput{
-		Attributes:            nil,
 		Endpoint:              &queueInfo.arn, // create SQS queue per subscription
 		Protocol:              aws.String("sqs"),
 		ReturnSubscriptionArn: nil,
@@ -484,11 +612,81 @@ func (s *snsSqs) Subscribe(req pubsub.SubscribeRequest, handler func(msg *pubsub
 	s.subscriptions = append(s.subscriptions, subscribeOutput.SubscriptionArn)
 	s.logger.Debugf("Subscribed to topic %s: %v", req.Topic, subscribeOutput)
 
+	// the filter policy is set on existing subscriptions too, so that changing
+	// or removing the filter of a subscription takes effect
+	err = s.setFilterPolicy(subscribeOutput.SubscriptionArn, filter)
+	if err != nil {
+		s.logger.Errorf("error setting filter policy of subscription to topic %s: %v", req.Topic, err)
+
+		return err
+	}
+
 	s.consumeSubscription(queueInfo, handler)
 
 	return nil
 }
 
+// filterMessageAttributes attaches the CloudEvent attributes used by filter policies to a published message.
+func filterMessageAttributes(data []byte) map[string]*sns.MessageAttributeValue {
+	properties := pubsub.FilterProperties(data)
+	if len(properties) == 0 {
+		return nil
+	}
+
+	attributes := make(map[string]*sns.MessageAttributeValue, len(properties))
+	for k, v := range properties {
+		attributes[k] = &sns.MessageAttributeValue{
+			DataType:    aws.String("String"),
+			StringValue: aws.String(v),
+		}
+	}
+
+	return attributes
+}
+
+// setFilterPolicy sets the filter policy of a subscription to the translation of
+// filter. Filters that can't be expressed as a policy are only evaluated when
+// messages are received, and the subscription accepts all messages.
+func (s *snsSqs) setFilterPolicy(subscriptionArn *string, filter *pubsub.Filter) error {
+	policy, err := filterPolicy(filter)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.snsClient.SetSubscriptionAttributes(&sns.SetSubscriptionAttributesInput{
+		SubscriptionArn: subscriptionArn,
+		AttributeName:   aws.String("FilterPolicy"),
+		AttributeValue:  aws.String(policy),
+	})
+
+	return err
+}
+
+// filterPolicy translates a subscription filter into an SNS filter policy when
+// possible, otherwise it returns the empty policy that accepts all messages.
+func filterPolicy(filter *pubsub.Filter) (string, error) {
+	if filter == nil {
+		return "{}", nil
+	}
+
+	equalities, ok := filter.AttributeEqualities()
+	if !ok {
+		return "{}", nil
+	}
+
+	policy := make(map[string][]string, len(equalities))
+	for attribute, value := range equalities {
+		policy[pubsub.FilterAttributePrefix+attribute] = []string{value}
+	}
+
+	b, err := json.Marshal(policy)
+	if err != nil {
+		return "", err
+	}
+
+	return string(b), nil
+}
+
 func (s *snsSqs) Close() error {
 	for _, sub := range s.subscriptions {
 		s.snsClient.Unsubscribe(&sns.UnsubscribeInput{
@@ -1,10 +1,20 @@
 package snssqs
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"sort"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sns"
+	"github.com/aws/aws-sdk-go/service/sns/snsiface"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/aws/aws-sdk-go/service/sqs/sqsiface"
 	"github.com/dapr/components-contrib/pubsub"
 	"github.com/dapr/dapr/pkg/logger"
 	"github.com/stretchr/testify/require"
@@ -255,3 +265,297 @@ func Test_nameToHash(t *testing.T) {
 			fmt.Sprintf("Invalid character %s in hashed name", string(c)))
 	}
 }
+
+func Test_filterPolicy(t *testing.T) {
+	r := require.New(t)
+
+	policy, err := filterPolicy(nil)
+	r.NoError(err)
+	r.Equal("{}", policy)
+
+	filter, err := pubsub.ParseFilter("type = 'order.created' AND source = 'checkout'")
+	r.NoError(err)
+	policy, err = filterPolicy(filter)
+	r.NoError(err)
+	r.JSONEq(`{"ce_type":["order.created"],"ce_source":["checkout"]}`, policy)
+
+	filter, err = pubsub.ParseFilter("data.amount > 100")
+	r.NoError(err)
+	policy, err = filterPolicy(filter)
+	r.NoError(err)
+	r.Equal("{}", policy)
+}
+
+func Test_setFilterPolicy(t *testing.T) {
+	r := require.New(t)
+	fake := newFakeAWS()
+	ps := &snsSqs{snsClient: fake.sns()}
+	arn := aws.String("arn:aws:sns:us-east-1:000000000000:orders:subscription")
+
+	filter, err := pubsub.ParseFilter("type = 'order.created'")
+	r.NoError(err)
+	r.NoError(ps.setFilterPolicy(arn, filter))
+	r.JSONEq(`{"ce_type":["order.created"]}`, fake.filterPolicies[*arn])
+
+	// removing the filter of an existing subscription resets its policy
+	r.NoError(ps.setFilterPolicy(arn, nil))
+	r.Equal("{}", fake.filterPolicies[*arn])
+}
+
+func Test_filterMessageAttributes(t *testing.T) {
+	r := require.New(t)
+
+	attributes := filterMessageAttributes([]byte(`{"type":"order.created","data":{}}`))
+	r.Len(attributes, 1)
+	r.Equal("String", *attributes["ce_type"].DataType)
+	r.Equal("order.created", *attributes["ce_type"].StringValue)
+
+	r.Nil(filterMessageAttributes([]byte("raw")))
+}
+
+func Test_getSnsSqsMetatdata_fifo(t *testing.T) {
+	r := require.New(t)
+	ps := snsSqs{
+		logger: logger.NewLogger("SnsSqs unit test"),
+	}
+
+	md, err := ps.getSnsSqsMetatdata(pubsub.Metadata{Properties: map[string]string{"fifo": "true"}})
+	r.NoError(err)
+	r.True(md.fifo)
+
+	_, err = ps.getSnsSqsMetatdata(pubsub.Metadata{Properties: map[string]string{"fifo": "sometimes"}})
+	r.Error(err)
+}
+
+func Test_deduplicationID(t *testing.T) {
+	r := require.New(t)
+
+	publish := func(data string, metadata map[string]string) *pubsub.PublishRequest {
+		return &pubsub.PublishRequest{Data: []byte(data), Metadata: metadata}
+	}
+
+	r.Equal("5e1a6c1e", deduplicationID(publish(`{"id":"5e1a6c1e","type":"payment.created"}`, nil)))
+	r.Equal(nameToHash(strings.Repeat("a", 200)), deduplicationID(publish(`{"id":"`+strings.Repeat("a", 200)+`"}`, nil)))
+	r.Equal(deduplicationID(publish("raw", nil)), deduplicationID(publish("raw", nil)))
+	r.Len(deduplicationID(publish("raw", nil)), 64)
+	r.Equal("msg-1", deduplicationID(publish(`{"id":"5e1a6c1e"}`, map[string]string{pubsub.MessageIDMetadataKey: "msg-1"})))
+}
+
+func Test_fifoPublishAndSubscribe(t *testing.T) {
+	r := require.New(t)
+	fake := newFakeAWS()
+	ps := &snsSqs{
+		topics:        map[string]string{},
+		topicHash:     map[string]string{},
+		queues:        map[string]*sqsQueueInfo{},
+		snsClient:     fake.sns(),
+		sqsClient:     fake.sqs(),
+		metadata:      &snsSqsMetadata{sqsQueueName: "consumer", fifo: true, messageRetryLimit: 10, messageMaxNumber: 10},
+		logger:        logger.NewLogger("SnsSqs unit test"),
+		subscriptions: []*string{},
+	}
+
+	received := make(chan string, 10)
+	err := ps.Subscribe(pubsub.SubscribeRequest{Topic: "payments"}, func(msg *pubsub.NewMessage) error {
+		r.Equal("payments", msg.Topic)
+		received <- string(msg.Data)
+
+		return nil
+	})
+	r.NoError(err)
+
+	r.Equal(nameToHash("payments")+".fifo", fake.topicName)
+	r.Equal("true", *fake.topicAttributes["FifoTopic"])
+	r.Equal(nameToHash("consumer")+".fifo", fake.queueName)
+	r.Equal("true", *fake.queueAttributes["FifoQueue"])
+
+	for i, account := range []string{"account-1", "account-1", ""} {
+		err = ps.Publish(&pubsub.PublishRequest{
+			Topic:    "payments",
+			Data:     []byte(fmt.Sprintf(`{"id":"event-%d"}`, i)),
+			Metadata: map[string]string{"partitionKey": account},
+		})
+		r.NoError(err)
+	}
+
+	r.Equal([]string{"account-1", "account-1", "payments"}, fake.groupIDs)
+	r.Equal([]string{"event-0", "event-1", "event-2"}, fake.deduplicationIDs)
+
+	for i := 0; i < 3; i++ {
+		select {
+		case <-received:
+		case <-time.After(5 * time.Second):
+			r.FailNow("timed out waiting for messages")
+		}
+	}
+}
+
+func Test_handleMessageGroups(t *testing.T) {
+	r := require.New(t)
+	fake := newFakeAWS()
+	ps := &snsSqs{
+		topicHash: map[string]string{},
+		sqsClient: fake.sqs(),
+		metadata:  &snsSqsMetadata{fifo: true, messageRetryLimit: 10},
+		logger:    logger.NewLogger("SnsSqs unit test"),
+	}
+
+	var messages []*sqs.Message
+	for _, m := range []struct{ group, body string }{
+		{"a", "a1"}, {"b", "b1"}, {"a", "a2"}, {"a", "a3"}, {"b", "b2"},
+	} {
+		messages = append(messages, fake.message(m.group, m.body))
+	}
+
+	var lock sync.Mutex
+	handled := map[string][]string{}
+	ps.handleMessageGroups(messages, &sqsQueueInfo{url: "queue"}, func(msg *pubsub.NewMessage) error {
+		body := string(msg.Data)
+		lock.Lock()
+		handled[body[:1]] = append(handled[body[:1]], body)
+		lock.Unlock()
+		if body == "a2" {
+			return errors.New("failed")
+		}
+
+		return nil
+	})
+
+	// a3 is not handled after a2 failed, it stays in the queue
+	r.Equal(map[string][]string{"a": {"a1", "a2"}, "b": {"b1", "b2"}}, handled)
+	sort.Strings(fake.deleted)
+	r.Equal([]string{"a1", "b1", "b2"}, fake.deleted)
+}
+
+// fakeAWS is an in-memory stand-in for the SNS and SQS APIs used by the component.
+type fakeAWS struct {
+	lock sync.Mutex
+
+	topicName        string
+	topicAttributes  map[string]*string
+	topicTags        []*sns.Tag
+	queueName        string
+	queueAttributes  map[string]*string
+	queueTags        map[string]*string
+	queueCounts      map[string]*string
+	subscribedQueues []string
+	filterPolicies   map[string]string
+	groupIDs         []string
+	deduplicationIDs []string
+	pending          []*sqs.Message
+	deleted          []string
+}
+
+func newFakeAWS() *fakeAWS {
+	return &fakeAWS{}
+}
+
+func (f *fakeAWS) sns() snsiface.SNSAPI {
+	return &fakeSNS{f: f}
+}
+
+func (f *fakeAWS) sqs() sqsiface.SQSAPI {
+	return &fakeSQS{f: f}
+}
+
+func (f *fakeAWS) message(groupID, body string) *sqs.Message {
+	b, _ := json.Marshal(snsMessage{Message: body, TopicArn: "arn:aws:sns:us-east-1:000000000000:" + f.topicName})
+
+	return &sqs.Message{
+		Body:          aws.String(string(b)),
+		ReceiptHandle: aws.String(body),
+		Attributes: map[string]*string{
+			sqs.MessageSystemAttributeNameApproximateReceiveCount: aws.String("1"),
+			sqs.MessageSystemAttributeNameMessageGroupId:          aws.String(groupID),
+		},
+	}
+}
+
+type fakeSNS struct {
+	snsiface.SNSAPI
+	f *fakeAWS
+}
+
+func (s *fakeSNS) CreateTopic(input *sns.CreateTopicInput) (*sns.CreateTopicOutput, error) {
+	s.f.topicName = *input.Name
+	s.f.topicAttributes = input.Attributes
+	s.f.topicTags = input.Tags
+
+	return &sns.CreateTopicOutput{TopicArn: aws.String("arn:aws:sns:us-east-1:000000000000:" + *input.Name)}, nil
+}
+
+func (s *fakeSNS) Subscribe(input *sns.SubscribeInput) (*sns.SubscribeOutput, error) {
+	s.f.subscribedQueues = append(s.f.subscribedQueues, *input.Endpoint)
+
+	return &sns.SubscribeOutput{SubscriptionArn: aws.String(*input.TopicArn + ":subscription")}, nil
+}
+
+func (s *fakeSNS) SetSubscriptionAttributes(input *sns.SetSubscriptionAttributesInput) (*sns.SetSubscriptionAttributesOutput, error) {
+	if s.f.filterPolicies == nil {
+		s.f.filterPolicies = map[string]string{}
+	}
+	s.f.filterPolicies[*input.SubscriptionArn] = *input.AttributeValue
+
+	return &sns.SetSubscriptionAttributesOutput{}, nil
+}
+
+func (s *fakeSNS) Publish(input *sns.PublishInput) (*sns.PublishOutput, error) {
+	s.f.lock.Lock()
+	defer s.f.lock.Unlock()
+
+	s.f.groupIDs = append(s.f.groupIDs, aws.StringValue(input.MessageGroupId))
+	s.f.deduplicationIDs = append(s.f.deduplicationIDs, aws.StringValue(input.MessageDeduplicationId))
+	s.f.pending = append(s.f.pending, s.f.message(aws.StringValue(input.MessageGroupId), *input.Message))
+
+	return &sns.PublishOutput{}, nil
+}
+
+type fakeSQS struct {
+	sqsiface.SQSAPI
+	f *fakeAWS
+}
+
+func (s *fakeSQS) CreateQueue(input *sqs.CreateQueueInput) (*sqs.CreateQueueOutput, error) {
+	s.f.queueName = *input.QueueName
+	s.f.queueAttributes = input.Attributes
+	s.f.queueTags = input.Tags
+
+	return &sqs.CreateQueueOutput{QueueUrl: aws.String("https://sqs.us-east-1.amazonaws.com/000000000000/" + *input.QueueName)}, nil
+}
+
+func (s *fakeSQS) GetQueueAttributes(input *sqs.GetQueueAttributesInput) (*sqs.GetQueueAttributesOutput, error) {
+	attributes := map[string]*string{
+		"QueueArn": aws.String("arn:aws:sqs:us-east-1:000000000000:" + s.f.queueName),
+	}
+	for k, v := range s.f.queueCounts {
+		attributes[k] = v
+	}
+
+	return &sqs.GetQueueAttributesOutput{Attributes: attributes}, nil
+}
+
+func (s *fakeSQS) SetQueueAttributes(input *sqs.SetQueueAttributesInput) (*sqs.SetQueueAttributesOutput, error) {
+	return &sqs.SetQueueAttributesOutput{}, nil
+}
+
+func (s *fakeSQS) ReceiveMessage(input *sqs.ReceiveMessageInput) (*sqs.ReceiveMessageOutput, error) {
+	s.f.lock.Lock()
+	messages := s.f.pending
+	s.f.pending = nil
+	s.f.lock.Unlock()
+
+	if len(messages) == 0 {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	return &sqs.ReceiveMessageOutput{Messages: messages}, nil
+}
+
+func (s *fakeSQS) DeleteMessage(input *sqs.DeleteMessageInput) (*sqs.DeleteMessageOutput, error) {
+	s.f.lock.Lock()
+	defer s.f.lock.Unlock()
+
+	s.f.deleted = append(s.f.deleted, *input.ReceiptHandle)
+
+	return &sqs.DeleteMessageOutput{}, nil
+}
@@ -15,6 +15,7 @@ import (
 	"github.com/Azure/azure-event-hubs-go/storage"
 	"github.com/Azure/azure-storage-blob-go/azblob"
 	"github.com/Azure/go-autorest/autorest/azure"
+	contrib_eventhubs "github.com/dapr/components-contrib/internal/component/azure/eventhubs"
 	"github.com/dapr/components-contrib/pubsub"
 	"github.com/dapr/dapr/pkg/logger"
 )
@@ -24,7 +25,7 @@ const (
 
 	connectionString = "connectionString"
 	consumerID       = "consumerID" // passed by dapr runtime
-	// required by subscriber
+	// required by subscriber unless checkpointStore is set
 	storageAccountName   = "storageAccountName"
 	storageAccountKey    = "storageAccountKey"
 	storageContainerName = "storageContainerName"
@@ -52,6 +53,8 @@ type azureEventHubsMetadata struct {
 	storageAccountName   string
 	storageAccountKey    string
 	storageContainerName string
+	// the metadata of the state store keeping checkpoints, nil when checkpoints are kept in Azure Blob Storage
+	checkpointStoreProperties map[string]string
 }
 
 // NewAzureEventHubs returns a new Azure Event hubs instance
@@ -68,22 +71,26 @@ func parseEventHubsMetadata(meta pubsub.Metadata) (azureEventHubsMetadata, error
 		return m, errors.New(missingConnectionStringErrorMsg)
 	}
 
-	if val, ok := meta.Properties[storageAccountName]; ok && val != "" {
-		m.storageAccountName = val
+	if contrib_eventhubs.UsesCheckpointStore(meta.Properties) {
+		m.checkpointStoreProperties = meta.Properties
 	} else {
-		return m, errors.New(missingStorageAccountNameErrorMsg)
-	}
-
-	if val, ok := meta.Properties[storageAccountKey]; ok && val != "" {
-		m.storageAccountKey = val
-	} else {
-		return m, errors.New(missingStorageAccountKeyErrorMsg)
-	}
-
-	if val, ok := meta.Properties[storageContainerName]; ok && val != "" {
-		m.storageContainerName = val
-	} else {
-		return m, errors.New(missingStorageContainerNameErrorMsg)
+		if val, ok := meta.Properties[storageAccountName]; ok && val != "" {
+			m.storageAccountName = val
+		} else {
+			return m, errors.New(missingStorageAccountNameErrorMsg)
+		}
+
+		if val, ok := meta.Properties[storageAccountKey]; ok && val != "" {
+			m.storageAccountKey = val
+		} else {
+			return m, errors.New(missingStorageAccountKeyErrorMsg)
+		}
+
+		if val, ok := meta.Properties[storageContainerName]; ok && val != "" {
+			m.storageContainerName = val
+		} else {
+			return m, errors.New(missingStorageContainerNameErrorMsg)
+		}
 	}
 
 	if val, ok := meta.Properties[consumerID]; ok && val != "" {
@@ -124,12 +131,12 @@ func (aeh *AzureEventHubs) Publish(req *pubsub.PublishRequest) error {
 
 // Subscribe receives data from Azure Event Hubs
 func (aeh *AzureEventHubs) Subscribe(req pubsub.SubscribeRequest, handler func(msg *pubsub.NewMessage) error) error {
-	cred, err := azblob.NewSharedKeyCredential(aeh.metadata.storageAccountName, aeh.metadata.storageAccountKey)
+	handler, err := pubsub.FilterHandler(req, handler)
 	if err != nil {
 		return err
 	}
 
-	leaserCheckpointer, err := storage.NewStorageLeaserCheckpointer(cred, aeh.metadata.storageAccountName, aeh.metadata.storageContainerName, azure.PublicCloud)
+	leaserCheckpointer, err := aeh.leaserCheckpointer()
 	if err != nil {
 		return err
 	}
@@ -162,3 +169,18 @@ func (aeh *AzureEventHubs) Close() error {
 func (aeh *AzureEventHubs) Features() []pubsub.Feature {
 	return nil
 }
+
+// leaserCheckpointer returns the leaser and checkpointer of the event processor host,
+// which keeps checkpoints in the configured checkpoint store or in Azure Blob Storage.
+func (aeh *AzureEventHubs) leaserCheckpointer() (contrib_eventhubs.LeaserCheckpointer, error) {
+	if aeh.metadata.checkpointStoreProperties != nil {
+		return contrib_eventhubs.NewCheckpointStore(aeh.metadata.checkpointStoreProperties, aeh.metadata.connectionString, aeh.metadata.consumerGroup, aeh.logger)
+	}
+
+	cred, err := azblob.NewSharedKeyCredential(aeh.metadata.storageAccountName, aeh.metadata.storageAccountKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return storage.NewStorageLeaserCheckpointer(cred, aeh.metadata.storageAccountName, aeh.metadata.storageContainerName, azure.PublicCloud)
+}
@@ -27,6 +27,17 @@ func TestParseEventHubsMetadata(t *testing.T) {
 		assert.Equal(t, m.consumerGroup, "mygroup")
 	})
 
+	t.Run("test checkpoint store configuration", func(t *testing.T) {
+		props := map[string]string{"connectionString": "fake", "consumerID": "mygroup", "checkpointStore": "redis", "checkpointStore.redisHost": "localhost:6379"}
+
+		metadata := pubsub.Metadata{Properties: props}
+		m, err := parseEventHubsMetadata(metadata)
+
+		assert.NoError(t, err)
+		assert.Equal(t, props, m.checkpointStoreProperties)
+		assert.Empty(t, m.storageAccountName)
+	})
+
 	type invalidConfigTestCase struct {
 		name   string
 		config map[string]string
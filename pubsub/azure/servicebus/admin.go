@@ -0,0 +1,143 @@
+// ------------------------------------------------------------
+// Copyright (c) Microsoft Corporation and Dapr Contributors.
+// Licensed under the MIT License.
+// ------------------------------------------------------------
+
+package servicebus
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	azservicebus "github.com/Azure/azure-service-bus-go"
+	"github.com/dapr/components-contrib/pubsub"
+)
+
+// CreateTopic creates a topic. Service Bus topics have a fixed number of
+// partitions, so any partition count above 1 creates a partitioned topic.
+func (a *azureServiceBus) CreateTopic(req pubsub.CreateTopicRequest) error {
+	if a.topicManager == nil {
+		return fmt.Errorf("%s init() has not been called", errorMessagePrefix)
+	}
+
+	return a.createTopicEntity(req.Topic, topicManagementOptions(req)...)
+}
+
+// DeleteTopic deletes a topic with its subscriptions.
+func (a *azureServiceBus) DeleteTopic(req pubsub.DeleteTopicRequest) error {
+	if a.topicManager == nil {
+		return fmt.Errorf("%s init() has not been called", errorMessagePrefix)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*time.Duration(a.metadata.TimeoutInSec))
+	defer cancel()
+
+	if err := a.topicManager.Delete(ctx, req.Topic); err != nil {
+		return fmt.Errorf("%s could not delete topic %s, %s", errorMessagePrefix, req.Topic, err)
+	}
+
+	return nil
+}
+
+// ListTopics returns the topics of the namespace.
+func (a *azureServiceBus) ListTopics() ([]string, error) {
+	if a.topicManager == nil {
+		return nil, fmt.Errorf("%s init() has not been called", errorMessagePrefix)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*time.Duration(a.metadata.TimeoutInSec))
+	defer cancel()
+
+	entities, err := a.topicManager.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s could not list topics, %s", errorMessagePrefix, err)
+	}
+
+	topics := make([]string, 0, len(entities))
+	for _, entity := range entities {
+		topics = append(topics, entity.Name)
+	}
+	sort.Strings(topics)
+
+	return topics, nil
+}
+
+// ListSubscriptions returns the subscriptions of a topic.
+func (a *azureServiceBus) ListSubscriptions(topic string) ([]string, error) {
+	if a.namespace == nil {
+		return nil, fmt.Errorf("%s init() has not been called", errorMessagePrefix)
+	}
+
+	subManager, err := a.namespace.NewSubscriptionManager(topic)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*time.Duration(a.metadata.TimeoutInSec))
+	defer cancel()
+
+	entities, err := subManager.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s could not list subscriptions of topic %s, %s", errorMessagePrefix, topic, err)
+	}
+
+	subscriptions := make([]string, 0, len(entities))
+	for _, entity := range entities {
+		subscriptions = append(subscriptions, entity.Name)
+	}
+	sort.Strings(subscriptions)
+
+	return subscriptions, nil
+}
+
+// GetSubscriptionLag returns the number of active messages of a subscription.
+func (a *azureServiceBus) GetSubscriptionLag(req pubsub.SubscriptionLagRequest) (*pubsub.SubscriptionLagResponse, error) {
+	if a.namespace == nil {
+		return nil, fmt.Errorf("%s init() has not been called", errorMessagePrefix)
+	}
+
+	subManager, err := a.namespace.NewSubscriptionManager(req.Topic)
+	if err != nil {
+		return nil, err
+	}
+
+	entity, err := a.getSubscriptionEntity(subManager, req.Topic, req.Subscription)
+	if err != nil {
+		return nil, err
+	}
+	if entity == nil {
+		return nil, fmt.Errorf("%s subscription %s on topic %s not found", errorMessagePrefix, req.Subscription, req.Topic)
+	}
+
+	return &pubsub.SubscriptionLagResponse{
+		Topic:        req.Topic,
+		Subscription: req.Subscription,
+		Lag:          activeMessageCount(entity),
+	}, nil
+}
+
+// topicManagementOptions translates the settings of a create topic request.
+func topicManagementOptions(req pubsub.CreateTopicRequest) []azservicebus.TopicManagementOption {
+	var opts []azservicebus.TopicManagementOption
+	if req.Partitions > 1 {
+		opts = append(opts, azservicebus.TopicWithPartitioning())
+	}
+	if req.RetentionInSeconds > 0 {
+		ttl := time.Duration(req.RetentionInSeconds) * time.Second
+		opts = append(opts, azservicebus.TopicWithMessageTimeToLive(&ttl))
+	}
+
+	return opts
+}
+
+// activeMessageCount returns the number of messages of a subscription waiting
+// to be received, which excludes dead-lettered and scheduled messages.
+func activeMessageCount(entity *azservicebus.SubscriptionEntity) int64 {
+	if entity.SubscriptionDescription == nil || entity.CountDetails == nil || entity.CountDetails.ActiveMessageCount == nil {
+		return 0
+	}
+
+	return int64(*entity.CountDetails.ActiveMessageCount)
+}
@@ -0,0 +1,56 @@
+// ------------------------------------------------------------
+// Copyright (c) Microsoft Corporation and Dapr Contributors.
+// Licensed under the MIT License.
+// ------------------------------------------------------------
+
+package servicebus
+
+import (
+	"testing"
+
+	azservicebus "github.com/Azure/azure-service-bus-go"
+	"github.com/dapr/components-contrib/pubsub"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTopicManagementOptions(t *testing.T) {
+	var _ pubsub.TopicAdmin = &azureServiceBus{}
+
+	t.Run("defaults", func(t *testing.T) {
+		assert.Empty(t, topicManagementOptions(pubsub.CreateTopicRequest{Topic: "orders"}))
+	})
+
+	t.Run("partitions and retention", func(t *testing.T) {
+		description := &azservicebus.TopicDescription{}
+		for _, opt := range topicManagementOptions(pubsub.CreateTopicRequest{Topic: "orders", Partitions: 4, RetentionInSeconds: 3600}) {
+			assert.Nil(t, opt(description))
+		}
+
+		assert.True(t, *description.EnablePartitioning)
+		assert.Equal(t, "PT3600S", *description.DefaultMessageTimeToLive)
+	})
+}
+
+func TestActiveMessageCount(t *testing.T) {
+	assert.Equal(t, int64(0), activeMessageCount(&azservicebus.SubscriptionEntity{}))
+
+	active := int32(42)
+	entity := &azservicebus.SubscriptionEntity{
+		SubscriptionDescription: &azservicebus.SubscriptionDescription{
+			CountDetails: &azservicebus.CountDetails{ActiveMessageCount: &active},
+		},
+	}
+	assert.Equal(t, int64(42), activeMessageCount(entity))
+}
+
+func TestTopicAdminRequiresInit(t *testing.T) {
+	a := NewAzureServiceBus(nil).(*azureServiceBus)
+
+	_, err := a.ListTopics()
+	assert.Error(t, err)
+	assertValidErrorMessage(t, err)
+
+	_, err = a.ListSubscriptions("orders")
+	assert.Error(t, err)
+	assertValidErrorMessage(t, err)
+}
@@ -22,4 +22,7 @@ type metadata struct {
 	AutoDeleteOnIdleInSec          *int   `json:"autoDeleteOnIdleInSec"`
 	MaxConcurrentHandlers          *int   `json:"maxConcurrentHandlers"`
 	PrefetchCount                  *int   `json:"prefetchCount"`
+	SessionIdleTimeoutInSec        int    `json:"sessionIdleTimeoutInSec"`
+	EnableIdempotence              bool   `json:"enableIdempotence"`
+	DuplicateDetectionWindowInSec  int    `json:"duplicateDetectionWindowInSec"`
 }
@@ -8,11 +8,14 @@ package servicebus
 import (
 	"context"
 	"fmt"
+	"sort"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	azservicebus "github.com/Azure/azure-service-bus-go"
+	contrib_servicebus "github.com/dapr/components-contrib/internal/component/azure/servicebus"
 	contrib_metadata "github.com/dapr/components-contrib/metadata"
 	"github.com/dapr/components-contrib/pubsub"
 	"github.com/dapr/dapr/pkg/logger"
@@ -34,8 +37,13 @@ const (
 	prefetchCount                  = "prefetchCount"
 	maxActiveMessages              = "maxActiveMessages"
 	maxActiveMessagesRecoveryInSec = "maxActiveMessagesRecoveryInSec"
+	sessionIdleTimeoutInSec        = "sessionIdleTimeoutInSec"
+	duplicateDetectionWindowInSec  = "duplicateDetectionWindowInSec"
 	errorMessagePrefix             = "azure service bus error:"
 
+	// Subscription metadata
+	requireSessions = "requireSessions"
+
 	// Defaults
 	defaultTimeoutInSec        = 60
 	defaultHandlerTimeoutInSec = 60
@@ -45,9 +53,14 @@ const (
 	defaultMaxActiveMessages              = 10000
 	defaultMaxActiveMessagesRecoveryInSec = 2
 	defaultDisableEntityManagement        = false
+	// The duplicate detection window of topics created with idempotence enabled, which is the Azure default
+	defaultDuplicateDetectionWindowInSec = 600
 
 	maxReconnAttempts       = 10
 	connectionRecoveryInSec = 2
+
+	defaultRuleName = "$Default"
+	filterRuleName  = "daprFilter"
 )
 
 type handler = struct{}
@@ -136,6 +149,18 @@ func parseAzureServiceBusMetadata(meta pubsub.Metadata) (metadata, error) {
 		}
 	}
 
+	m.SessionIdleTimeoutInSec = contrib_servicebus.DefaultSessionIdleTimeoutInSec
+	if val, ok := meta.Properties[sessionIdleTimeoutInSec]; ok && val != "" {
+		var err error
+		m.SessionIdleTimeoutInSec, err = strconv.Atoi(val)
+		if err != nil {
+			return m, fmt.Errorf("%s invalid sessionIdleTimeoutInSec %s, %s", errorMessagePrefix, val, err)
+		}
+		if m.SessionIdleTimeoutInSec <= 0 {
+			return m, fmt.Errorf("%s invalid sessionIdleTimeoutInSec %s, must be greater than 0", errorMessagePrefix, val)
+		}
+	}
+
 	m.MaxActiveMessagesRecoveryInSec = defaultMaxActiveMessagesRecoveryInSec
 	if val, ok := meta.Properties[maxActiveMessagesRecoveryInSec]; ok && val != "" {
 		var err error
@@ -145,6 +170,23 @@ func parseAzureServiceBusMetadata(meta pubsub.Metadata) (metadata, error) {
 		}
 	}
 
+	if val, ok := meta.Properties[pubsub.IdempotenceMetadataKey]; ok && val != "" {
+		var err error
+		m.EnableIdempotence, err = strconv.ParseBool(val)
+		if err != nil {
+			return m, fmt.Errorf("%s invalid enableIdempotence %s, %s", errorMessagePrefix, val, err)
+		}
+	}
+
+	m.DuplicateDetectionWindowInSec = defaultDuplicateDetectionWindowInSec
+	if val, ok := meta.Properties[duplicateDetectionWindowInSec]; ok && val != "" {
+		var err error
+		m.DuplicateDetectionWindowInSec, err = strconv.Atoi(val)
+		if err != nil {
+			return m, fmt.Errorf("%s invalid duplicateDetectionWindowInSec %s, %s", errorMessagePrefix, val, err)
+		}
+	}
+
 	/* Nullable configuration settings - defaults will be set by the server */
 	if val, ok := meta.Properties[maxDeliveryCount]; ok && val != "" {
 		valAsInt, err := strconv.Atoi(val)
@@ -253,6 +295,19 @@ func (a *azureServiceBus) Publish(req *pubsub.PublishRequest) error {
 		msg.TTL = &ttl
 	}
 
+	if val, ok := req.Metadata[contrib_servicebus.SessionIDMetadataKey]; ok && val != "" {
+		msg.SessionID = &val
+	}
+
+	// Duplicate detection compares message IDs
+	if val, ok := req.Metadata[pubsub.MessageIDMetadataKey]; ok && val != "" {
+		msg.ID = val
+	}
+
+	for k, v := range pubsub.FilterProperties(req.Data) {
+		msg.Set(k, v)
+	}
+
 	err = sender.Send(ctx, msg)
 	if err != nil {
 		return err
@@ -262,9 +317,30 @@ func (a *azureServiceBus) Publish(req *pubsub.PublishRequest) error {
 }
 
 func (a *azureServiceBus) Subscribe(req pubsub.SubscribeRequest, appHandler func(msg *pubsub.NewMessage) error) error {
+	appHandler, err := pubsub.FilterHandler(req, appHandler)
+	if err != nil {
+		return err
+	}
+
+	sessions, err := requiresSessions(req)
+	if err != nil {
+		return err
+	}
+
 	subID := a.metadata.ConsumerID
 	if !a.metadata.DisableEntityManagement {
-		err := a.ensureSubscription(subID, req.Topic)
+		err = a.ensureSubscription(subID, req.Topic, sessions)
+		if err != nil {
+			return err
+		}
+
+		filter, err := pubsub.FilterFromMetadata(req.Metadata)
+		if err != nil {
+			return err
+		}
+		// Also called without a filter, to restore the default rule of a
+		// subscription whose filter was removed
+		err = a.ensureSubscriptionFilter(subID, req.Topic, filter)
 		if err != nil {
 			return err
 		}
@@ -330,13 +406,24 @@ func (a *azureServiceBus) Subscribe(req pubsub.SubscribeRequest, appHandler func
 			// to re-establish the subscription connection until
 			// we exhaust the number of reconnect attempts.
 			ctx, cancel := context.WithCancel(context.Background())
-			innerErr := sub.ReceiveAndBlock(ctx,
-				appHandler,
-				a.metadata.LockRenewalInSec,
-				a.metadata.HandlerTimeoutInSec,
-				a.metadata.TimeoutInSec,
-				a.metadata.MaxActiveMessages,
-				a.metadata.MaxActiveMessagesRecoveryInSec)
+			var innerErr error
+			if sessions {
+				innerErr = sub.ReceiveSessionsAndBlock(ctx,
+					appHandler,
+					a.metadata.MaxConcurrentHandlers,
+					a.metadata.LockRenewalInSec,
+					a.metadata.HandlerTimeoutInSec,
+					a.metadata.TimeoutInSec,
+					a.metadata.SessionIdleTimeoutInSec)
+			} else {
+				innerErr = sub.ReceiveAndBlock(ctx,
+					appHandler,
+					a.metadata.LockRenewalInSec,
+					a.metadata.HandlerTimeoutInSec,
+					a.metadata.TimeoutInSec,
+					a.metadata.MaxActiveMessages,
+					a.metadata.MaxActiveMessagesRecoveryInSec)
+			}
 			if innerErr != nil {
 				a.logger.Error(innerErr)
 			}
@@ -358,6 +445,21 @@ func (a *azureServiceBus) Subscribe(req pubsub.SubscribeRequest, appHandler func
 	return nil
 }
 
+// requiresSessions returns whether the subscription receives from a session enabled subscription.
+func requiresSessions(req pubsub.SubscribeRequest) (bool, error) {
+	val, ok := req.Metadata[requireSessions]
+	if !ok || val == "" {
+		return false, nil
+	}
+
+	sessions, err := strconv.ParseBool(val)
+	if err != nil {
+		return false, fmt.Errorf("%s invalid requireSessions %s, %s", errorMessagePrefix, val, err)
+	}
+
+	return sessions, nil
+}
+
 func (a *azureServiceBus) ensureTopic(topic string) error {
 	entity, err := a.getTopicEntity(topic)
 	if err != nil {
@@ -374,7 +476,7 @@ func (a *azureServiceBus) ensureTopic(topic string) error {
 	return nil
 }
 
-func (a *azureServiceBus) ensureSubscription(name string, topic string) error {
+func (a *azureServiceBus) ensureSubscription(name string, topic string, sessions bool) error {
 	err := a.ensureTopic(topic)
 	if err != nil {
 		return err
@@ -391,15 +493,136 @@ func (a *azureServiceBus) ensureSubscription(name string, topic string) error {
 	}
 
 	if entity == nil {
-		err = a.createSubscriptionEntity(subManager, topic, name)
+		err = a.createSubscriptionEntity(subManager, topic, name, sessions)
 		if err != nil {
 			return err
 		}
+	} else if sessions != (entity.RequiresSession != nil && *entity.RequiresSession) {
+		// Sessions can't be enabled or disabled on an existing subscription
+		return fmt.Errorf("%s subscription %s on topic %s exists with requiresSession=%t", errorMessagePrefix, name, topic, !sessions)
+	}
+
+	return nil
+}
+
+// ruleManager manages the rules of subscriptions, it is implemented by *azservicebus.SubscriptionManager.
+type ruleManager interface {
+	ListRules(ctx context.Context, subscriptionName string) ([]*azservicebus.RuleEntity, error)
+	PutRule(ctx context.Context, subscriptionName, ruleName string, filter azservicebus.FilterDescriber) (*azservicebus.RuleEntity, error)
+	DeleteRule(ctx context.Context, subscriptionName, ruleName string) error
+}
+
+// ensureSubscriptionFilter replaces the default rule of the subscription with a SQL
+// filter when the subscription filter can be evaluated by Service Bus. Publishers
+// attach the matched CloudEvent attributes as user properties. Without a filter
+// that can be pushed down, the default rule is restored.
+func (a *azureServiceBus) ensureSubscriptionFilter(name string, topic string, filter *pubsub.Filter) error {
+	expression, ok := "", false
+	if filter != nil {
+		expression, ok = sqlFilterExpression(filter)
+		if !ok {
+			a.logger.Debugf("Filter for subscription %s on topic %s can't be pushed down, evaluating it on receive", name, topic)
+		}
+	}
+
+	subManager, err := a.namespace.NewSubscriptionManager(topic)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*time.Duration(a.metadata.TimeoutInSec))
+	defer cancel()
+
+	return syncSubscriptionRules(ctx, subManager, name, expression, ok)
+}
+
+// syncSubscriptionRules makes the filter rule of the subscription match expression,
+// or removes it and restores the default rule when the filter is not pushed down.
+// The default rule is restored before the filter rule is changed, so that no
+// message is dropped in between; they are filtered on receive meanwhile.
+func syncSubscriptionRules(ctx context.Context, rm ruleManager, name string, expression string, pushdown bool) error {
+	rules, err := rm.ListRules(ctx, name)
+	if err != nil {
+		return fmt.Errorf("%s could not list rules of subscription %s, %s", errorMessagePrefix, name, err)
+	}
+
+	var current *string
+	hasDefault := false
+	for _, rule := range rules {
+		if rule.Entity == nil || rule.RuleDescription == nil {
+			continue
+		}
+		switch rule.Name {
+		case defaultRuleName:
+			hasDefault = true
+		case filterRuleName:
+			current = rule.Filter.SQLExpression
+			if current == nil {
+				current = new(string)
+			}
+		}
+	}
+
+	if pushdown && current != nil && *current == expression && !hasDefault {
+		return nil
+	}
+
+	if !hasDefault {
+		_, err = rm.PutRule(ctx, name, defaultRuleName, azservicebus.TrueFilter{})
+		if err != nil {
+			return fmt.Errorf("%s could not restore default rule for subscription %s, %s", errorMessagePrefix, name, err)
+		}
+	}
+
+	if current != nil && (!pushdown || *current != expression) {
+		err = rm.DeleteRule(ctx, name, filterRuleName)
+		if err != nil && !azservicebus.IsErrNotFound(err) {
+			return fmt.Errorf("%s could not delete filter rule for subscription %s, %s", errorMessagePrefix, name, err)
+		}
+		current = nil
+	}
+
+	if !pushdown {
+		return nil
+	}
+
+	if current == nil {
+		_, err = rm.PutRule(ctx, name, filterRuleName, azservicebus.SQLFilter{Expression: expression})
+		if err != nil {
+			return fmt.Errorf("%s could not put filter rule for subscription %s, %s", errorMessagePrefix, name, err)
+		}
+	}
+
+	err = rm.DeleteRule(ctx, name, defaultRuleName)
+	if err != nil && !azservicebus.IsErrNotFound(err) {
+		return fmt.Errorf("%s could not delete default rule for subscription %s, %s", errorMessagePrefix, name, err)
 	}
 
 	return nil
 }
 
+// sqlFilterExpression translates a filter into a Service Bus SQL filter expression.
+func sqlFilterExpression(filter *pubsub.Filter) (string, bool) {
+	equalities, ok := filter.AttributeEqualities()
+	if !ok {
+		return "", false
+	}
+
+	attributes := make([]string, 0, len(equalities))
+	for attribute := range equalities {
+		attributes = append(attributes, attribute)
+	}
+	sort.Strings(attributes)
+
+	conditions := make([]string, 0, len(attributes))
+	for _, attribute := range attributes {
+		value := strings.ReplaceAll(equalities[attribute], "'", "''")
+		conditions = append(conditions, fmt.Sprintf("%s%s = '%s'", pubsub.FilterAttributePrefix, attribute, value))
+	}
+
+	return strings.Join(conditions, " AND "), true
+}
+
 func (a *azureServiceBus) getTopicEntity(topic string) (*azservicebus.TopicEntity, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second*time.Duration(a.metadata.TimeoutInSec))
 	defer cancel()
@@ -415,10 +638,16 @@ func (a *azureServiceBus) getTopicEntity(topic string) (*azservicebus.TopicEntit
 	return topicEntity, nil
 }
 
-func (a *azureServiceBus) createTopicEntity(topic string) error {
+func (a *azureServiceBus) createTopicEntity(topic string, opts ...azservicebus.TopicManagementOption) error {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second*time.Duration(a.metadata.TimeoutInSec))
 	defer cancel()
-	_, err := a.topicManager.Put(ctx, topic)
+	if a.metadata.EnableIdempotence {
+		// Duplicate detection can only be enabled when the topic is created
+		window := time.Second * time.Duration(a.metadata.DuplicateDetectionWindowInSec)
+		opts = append(opts, azservicebus.TopicWithDuplicateDetection(&window))
+	}
+
+	_, err := a.topicManager.Put(ctx, topic, opts...)
 	if err != nil {
 		return fmt.Errorf("%s could not put topic %s, %s", errorMessagePrefix, topic, err)
 	}
@@ -437,7 +666,7 @@ func (a *azureServiceBus) getSubscriptionEntity(mgr *azservicebus.SubscriptionMa
 	return entity, nil
 }
 
-func (a *azureServiceBus) createSubscriptionEntity(mgr *azservicebus.SubscriptionManager, topic, subscription string) error {
+func (a *azureServiceBus) createSubscriptionEntity(mgr *azservicebus.SubscriptionManager, topic, subscription string, sessions bool) error {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second*time.Duration(a.metadata.TimeoutInSec))
 	defer cancel()
 
@@ -445,6 +674,9 @@ func (a *azureServiceBus) createSubscriptionEntity(mgr *azservicebus.Subscriptio
 	if err != nil {
 		return err
 	}
+	if sessions {
+		opts = append(opts, azservicebus.SubscriptionWithRequiredSessions())
+	}
 
 	_, err = mgr.Put(ctx, subscription, opts...)
 	if err != nil {
@@ -6,10 +6,13 @@
 package servicebus
 
 import (
+	"context"
 	"testing"
 
+	azservicebus "github.com/Azure/azure-service-bus-go"
 	"github.com/dapr/components-contrib/pubsub"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 const (
@@ -32,6 +35,7 @@ func getFakeProperties() map[string]string {
 		prefetchCount:                  "10",
 		maxActiveMessages:              "100",
 		maxActiveMessagesRecoveryInSec: "5",
+		sessionIdleTimeoutInSec:        "30",
 	}
 }
 
@@ -60,6 +64,7 @@ func TestParseServiceBusMetadata(t *testing.T) {
 		assert.Equal(t, 100, m.MaxActiveMessages)
 		assert.NotNil(t, m.MaxActiveMessagesRecoveryInSec)
 		assert.Equal(t, 5, m.MaxActiveMessagesRecoveryInSec)
+		assert.Equal(t, 30, m.SessionIdleTimeoutInSec)
 
 		assert.NotNil(t, m.AutoDeleteOnIdleInSec)
 		assert.Equal(t, 240, *m.AutoDeleteOnIdleInSec)
@@ -461,6 +466,99 @@ func TestParseServiceBusMetadata(t *testing.T) {
 		assertValidErrorMessage(t, err)
 	})
 
+	t.Run("missing optional sessionIdleTimeoutInSec", func(t *testing.T) {
+		fakeProperties := getFakeProperties()
+
+		fakeMetaData := pubsub.Metadata{
+			Properties: fakeProperties,
+		}
+		fakeMetaData.Properties[sessionIdleTimeoutInSec] = ""
+
+		// act
+		m, err := parseAzureServiceBusMetadata(fakeMetaData)
+
+		// assert
+		assert.Equal(t, 60, m.SessionIdleTimeoutInSec)
+		assert.Nil(t, err)
+	})
+
+	t.Run("invalid optional sessionIdleTimeoutInSec", func(t *testing.T) {
+		fakeProperties := getFakeProperties()
+
+		fakeMetaData := pubsub.Metadata{
+			Properties: fakeProperties,
+		}
+		fakeMetaData.Properties[sessionIdleTimeoutInSec] = invalidNumber
+
+		// act
+		_, err := parseAzureServiceBusMetadata(fakeMetaData)
+
+		// assert
+		assert.Error(t, err)
+		assertValidErrorMessage(t, err)
+	})
+
+	t.Run("non positive optional sessionIdleTimeoutInSec", func(t *testing.T) {
+		for _, val := range []string{"0", "-1"} {
+			fakeProperties := getFakeProperties()
+
+			fakeMetaData := pubsub.Metadata{
+				Properties: fakeProperties,
+			}
+			fakeMetaData.Properties[sessionIdleTimeoutInSec] = val
+
+			// act
+			_, err := parseAzureServiceBusMetadata(fakeMetaData)
+
+			// assert
+			assert.Error(t, err, val)
+			assertValidErrorMessage(t, err)
+		}
+	})
+
+	t.Run("idempotence settings", func(t *testing.T) {
+		fakeProperties := getFakeProperties()
+
+		fakeMetaData := pubsub.Metadata{
+			Properties: fakeProperties,
+		}
+
+		// act
+		m, err := parseAzureServiceBusMetadata(fakeMetaData)
+
+		// assert
+		assert.Nil(t, err)
+		assert.False(t, m.EnableIdempotence)
+		assert.Equal(t, 600, m.DuplicateDetectionWindowInSec)
+
+		fakeMetaData.Properties[pubsub.IdempotenceMetadataKey] = "true"
+		fakeMetaData.Properties[duplicateDetectionWindowInSec] = "3600"
+
+		// act
+		m, err = parseAzureServiceBusMetadata(fakeMetaData)
+
+		// assert
+		assert.Nil(t, err)
+		assert.True(t, m.EnableIdempotence)
+		assert.Equal(t, 3600, m.DuplicateDetectionWindowInSec)
+	})
+
+	t.Run("invalid optional duplicateDetectionWindowInSec", func(t *testing.T) {
+		fakeProperties := getFakeProperties()
+
+		fakeMetaData := pubsub.Metadata{
+			Properties: fakeProperties,
+		}
+		fakeMetaData.Properties[duplicateDetectionWindowInSec] = invalidNumber
+
+		// act
+		_, err := parseAzureServiceBusMetadata(fakeMetaData)
+
+		// assert
+		assert.Error(t, err)
+		assertValidErrorMessage(t, err)
+	})
+
 	t.Run("missing nullable prefetchCount", func(t *testing.T) {
 		fakeProperties := getFakeProperties()
 
@@ -497,3 +595,101 @@ func TestParseServiceBusMetadata(t *testing.T) {
 func assertValidErrorMessage(t *testing.T, err error) {
 	assert.Contains(t, err.Error(), errorMessagePrefix)
 }
+
+func TestRequiresSessions(t *testing.T) {
+	sessions, err := requiresSessions(pubsub.SubscribeRequest{})
+	assert.Nil(t, err)
+	assert.False(t, sessions)
+
+	sessions, err = requiresSessions(pubsub.SubscribeRequest{Metadata: map[string]string{requireSessions: "true"}})
+	assert.Nil(t, err)
+	assert.True(t, sessions)
+
+	_, err = requiresSessions(pubsub.SubscribeRequest{Metadata: map[string]string{requireSessions: "maybe"}})
+	assert.Error(t, err)
+	assertValidErrorMessage(t, err)
+}
+
+func TestSQLFilterExpression(t *testing.T) {
+	t.Run("equalities are pushed down", func(t *testing.T) {
+		filter, err := pubsub.ParseFilter("type = 'order.created' AND subject = 'it''s'")
+		assert.Nil(t, err)
+
+		expression, ok := sqlFilterExpression(filter)
+		assert.True(t, ok)
+		assert.Equal(t, "ce_subject = 'it''s' AND ce_type = 'order.created'", expression)
+	})
+
+	t.Run("data filters are not pushed down", func(t *testing.T) {
+		filter, err := pubsub.ParseFilter("type = 'order.created' AND data.amount > 100")
+		assert.Nil(t, err)
+
+		_, ok := sqlFilterExpression(filter)
+		assert.False(t, ok)
+	})
+}
+
+// fakeRuleManager keeps the SQL expressions of the rules of a subscription by name.
+type fakeRuleManager struct {
+	rules map[string]string
+	calls int
+}
+
+func (f *fakeRuleManager) ListRules(ctx context.Context, subscriptionName string) ([]*azservicebus.RuleEntity, error) {
+	rules := make([]*azservicebus.RuleEntity, 0, len(f.rules))
+	for name, expression := range f.rules {
+		expression := expression
+		rules = append(rules, &azservicebus.RuleEntity{
+			RuleDescription: &azservicebus.RuleDescription{Filter: azservicebus.FilterDescription{SQLExpression: &expression}},
+			Entity:          &azservicebus.Entity{Name: name},
+		})
+	}
+
+	return rules, nil
+}
+
+func (f *fakeRuleManager) PutRule(ctx context.Context, subscriptionName, ruleName string, filter azservicebus.FilterDescriber) (*azservicebus.RuleEntity, error) {
+	f.calls++
+	f.rules[ruleName] = *filter.ToFilterDescription().SQLExpression
+
+	return &azservicebus.RuleEntity{}, nil
+}
+
+func (f *fakeRuleManager) DeleteRule(ctx context.Context, subscriptionName, ruleName string) error {
+	f.calls++
+	delete(f.rules, ruleName)
+
+	return nil
+}
+
+func TestSyncSubscriptionRules(t *testing.T) {
+	rm := &fakeRuleManager{rules: map[string]string{defaultRuleName: "1=1"}}
+	ctx := context.Background()
+
+	t.Run("filter replaces the default rule", func(t *testing.T) {
+		require.NoError(t, syncSubscriptionRules(ctx, rm, "sub", "ce_type = 'a'", true))
+		assert.Equal(t, map[string]string{filterRuleName: "ce_type = 'a'"}, rm.rules)
+	})
+
+	t.Run("unchanged filter is kept", func(t *testing.T) {
+		rm.calls = 0
+		require.NoError(t, syncSubscriptionRules(ctx, rm, "sub", "ce_type = 'a'", true))
+		assert.Equal(t, 0, rm.calls)
+	})
+
+	t.Run("changed filter is replaced", func(t *testing.T) {
+		require.NoError(t, syncSubscriptionRules(ctx, rm, "sub", "ce_type = 'b'", true))
+		assert.Equal(t, map[string]string{filterRuleName: "ce_type = 'b'"}, rm.rules)
+	})
+
+	t.Run("removed filter restores the default rule", func(t *testing.T) {
+		require.NoError(t, syncSubscriptionRules(ctx, rm, "sub", "", false))
+		assert.Equal(t, map[string]string{defaultRuleName: "1=1"}, rm.rules)
+	})
+
+	t.Run("default rule is kept without filter", func(t *testing.T) {
+		rm.calls = 0
+		require.NoError(t, syncSubscriptionRules(ctx, rm, "sub", "", false))
+		assert.Equal(t, 0, rm.calls)
+	})
+}
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	azservicebus "github.com/Azure/azure-service-bus-go"
+	contrib_servicebus "github.com/dapr/components-contrib/internal/component/azure/servicebus"
 	"github.com/dapr/components-contrib/pubsub"
 	"github.com/dapr/dapr/pkg/logger"
 )
@@ -97,6 +98,34 @@ func (s *subscription) ReceiveAndBlock(ctx context.Context, appHandler func(msg
 	}
 }
 
+// ReceiveSessionsAndBlock is a blocking call to receive messages on a session enabled Azure Service Bus subscription.
+// Up to maxConcurrentSessions sessions are handled at a time, the messages of a session are handled in order.
+func (s *subscription) ReceiveSessionsAndBlock(ctx context.Context, appHandler func(msg *pubsub.NewMessage) error, maxConcurrentSessions *int, lockRenewalInSec int, handlerTimeoutInSec int, timeoutInSec int, sessionIdleTimeoutInSec int) error {
+	// Close subscription
+	defer func() {
+		closeCtx, closeCancel := context.WithTimeout(context.Background(), time.Second*time.Duration(timeoutInSec))
+		defer closeCancel()
+		s.close(closeCtx)
+	}()
+
+	opts := contrib_servicebus.SessionOptions{
+		MaxConcurrentSessions: contrib_servicebus.DefaultMaxConcurrentSessions,
+		LockRenewalInterval:   time.Second * time.Duration(lockRenewalInSec),
+		IdleTimeout:           time.Second * time.Duration(sessionIdleTimeoutInSec),
+		CloseTimeout:          time.Second * time.Duration(timeoutInSec),
+	}
+	if maxConcurrentSessions != nil {
+		opts.MaxConcurrentSessions = *maxConcurrentSessions
+	}
+	s.logger.Debugf("Receiving up to %d session(s) concurrently for topic %s", opts.MaxConcurrentSessions, s.topic)
+
+	newSession := func() contrib_servicebus.Session {
+		return s.entity.NewSession(nil)
+	}
+
+	return contrib_servicebus.ReceiveSessions(ctx, newSession, s.getHandlerFunc(appHandler, handlerTimeoutInSec, timeoutInSec), opts, s.logger)
+}
+
 func (s *subscription) close(ctx context.Context) {
 	s.logger.Debugf("Closing subscription to topic %s", s.topic)
 
@@ -112,6 +141,9 @@ func (s *subscription) getHandlerFunc(appHandler func(msg *pubsub.NewMessage) er
 			Data:  message.Data,
 			Topic: s.topic,
 		}
+		if message.ID != "" {
+			msg.Metadata = map[string]string{pubsub.MessageIDMetadataKey: message.ID}
+		}
 
 		// TODO(#1721): Context should be propogated to the app handler call for timeout and cancellation.
 		//
@@ -0,0 +1,172 @@
+// ------------------------------------------------------------
+// Copyright (c) Microsoft Corporation and Dapr Contributors.
+// Licensed under the MIT License.
+// ------------------------------------------------------------
+
+package claimcheck
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/google/uuid"
+	jsoniter "github.com/json-iterator/go"
+
+	"github.com/dapr/components-contrib/pubsub"
+	"github.com/dapr/dapr/pkg/logger"
+)
+
+const (
+	// referenceVersion marks a message body as a claim check reference
+	referenceVersion = "v1"
+
+	// KeyMetadataKey is set on a rehydrated message to the key of the offloaded payload
+	KeyMetadataKey = "claimCheckKey"
+)
+
+// reference is published in place of a payload that has been offloaded to the payload store.
+type reference struct {
+	ClaimCheck string `json:"claimCheck"`
+	Key        string `json:"key"`
+	Size       int    `json:"size"`
+}
+
+// claimCheck decorates a pubsub component with the claim check pattern:
+// payloads above a size threshold are uploaded to a PayloadStore and only
+// a small reference is sent through the broker. Subscribers transparently
+// fetch the payload back before the handler is invoked.
+//
+// Payloads are removed once a subscriber handled the message, as most payload
+// stores don't expire them on their own. Topics with several subscribers must
+// set claimCheckDeleteAfterDelivery to false so that every subscriber can fetch
+// the payload, and rely on claimCheckRetentionInSeconds with a store that
+// honors ttlInSeconds, or on a lifecycle rule of the bucket or container.
+type claimCheck struct {
+	pubsub.PubSub
+
+	store    PayloadStore
+	metadata metadata
+	logger   logger.Logger
+}
+
+// New returns a pubsub component that offloads large payloads of the given
+// component to store.
+func New(inner pubsub.PubSub, store PayloadStore, logger logger.Logger) pubsub.PubSub {
+	return &claimCheck{
+		PubSub: inner,
+		store:  store,
+		logger: logger,
+	}
+}
+
+func (c *claimCheck) Init(metadata pubsub.Metadata) error {
+	m, err := parseMetadata(metadata)
+	if err != nil {
+		return err
+	}
+	c.metadata = m
+
+	return c.PubSub.Init(metadata)
+}
+
+func (c *claimCheck) Publish(req *pubsub.PublishRequest) error {
+	if len(req.Data) <= c.metadata.thresholdBytes {
+		return c.PubSub.Publish(req)
+	}
+
+	key := fmt.Sprintf("%s/%s", req.Topic, uuid.New().String())
+	if err := c.store.Put(key, req.Data, c.metadata.retention); err != nil {
+		return fmt.Errorf("claim check error: failed to offload payload for topic %s: %s", req.Topic, err)
+	}
+
+	data, err := jsoniter.Marshal(reference{
+		ClaimCheck: referenceVersion,
+		Key:        key,
+		Size:       len(req.Data),
+	})
+	if err != nil {
+		return err
+	}
+
+	err = c.PubSub.Publish(&pubsub.PublishRequest{
+		Data:       data,
+		PubsubName: req.PubsubName,
+		Topic:      req.Topic,
+		Metadata:   req.Metadata,
+	})
+	if err != nil {
+		if delErr := c.store.Delete(key); delErr != nil {
+			c.logger.Warnf("claim check: failed to remove payload %s of unpublished message: %s", key, delErr)
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+func (c *claimCheck) Subscribe(req pubsub.SubscribeRequest, handler func(msg *pubsub.NewMessage) error) error {
+	// Filters have to see the rehydrated payload, so they are evaluated here
+	// instead of by the decorated component.
+	handler, err := pubsub.FilterHandler(req, handler)
+	if err != nil {
+		return err
+	}
+
+	innerReq := pubsub.SubscribeRequest{
+		Topic:    req.Topic,
+		Metadata: make(map[string]string, len(req.Metadata)),
+	}
+	for k, v := range req.Metadata {
+		if k != pubsub.FilterMetadataKey {
+			innerReq.Metadata[k] = v
+		}
+	}
+
+	return c.PubSub.Subscribe(innerReq, func(msg *pubsub.NewMessage) error {
+		ref, ok := parseReference(msg.Data)
+		if !ok {
+			return handler(msg)
+		}
+
+		data, err := c.store.Get(ref.Key)
+		if err != nil {
+			return fmt.Errorf("claim check error: failed to fetch payload %s: %s", ref.Key, err)
+		}
+
+		metadata := make(map[string]string, len(msg.Metadata)+1)
+		for k, v := range msg.Metadata {
+			metadata[k] = v
+		}
+		metadata[KeyMetadataKey] = ref.Key
+
+		err = handler(&pubsub.NewMessage{
+			Data:     data,
+			Topic:    msg.Topic,
+			Metadata: metadata,
+		})
+		if err != nil {
+			return err
+		}
+
+		if c.metadata.deleteAfterDelivery {
+			if err := c.store.Delete(ref.Key); err != nil {
+				c.logger.Warnf("claim check: failed to remove delivered payload %s: %s", ref.Key, err)
+			}
+		}
+
+		return nil
+	})
+}
+
+func parseReference(data []byte) (reference, bool) {
+	var ref reference
+	if !bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		return ref, false
+	}
+	if err := jsoniter.Unmarshal(data, &ref); err != nil {
+		return ref, false
+	}
+
+	return ref, ref.ClaimCheck == referenceVersion && ref.Key != ""
+}
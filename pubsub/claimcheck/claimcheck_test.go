@@ -0,0 +1,294 @@
+// ------------------------------------------------------------
+// Copyright (c) Microsoft Corporation and Dapr Contributors.
+// Licensed under the MIT License.
+// ------------------------------------------------------------
+
+package claimcheck
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/components-contrib/bindings"
+	"github.com/dapr/components-contrib/pubsub"
+	"github.com/dapr/dapr/pkg/logger"
+)
+
+type fakePubSub struct {
+	handlers  map[string]func(msg *pubsub.NewMessage) error
+	published [][]byte
+	err       error
+}
+
+func newFakePubSub() *fakePubSub {
+	return &fakePubSub{handlers: map[string]func(msg *pubsub.NewMessage) error{}}
+}
+
+func (f *fakePubSub) Init(metadata pubsub.Metadata) error { return nil }
+func (f *fakePubSub) Features() []pubsub.Feature          { return nil }
+func (f *fakePubSub) Close() error                        { return nil }
+
+func (f *fakePubSub) Publish(req *pubsub.PublishRequest) error {
+	if f.err != nil {
+		return f.err
+	}
+	f.published = append(f.published, req.Data)
+
+	return nil
+}
+
+// deliver hands the last published message to the subscriber of topic.
+func (f *fakePubSub) deliver(topic string) error {
+	return f.handlers[topic](&pubsub.NewMessage{Topic: topic, Data: f.published[len(f.published)-1]})
+}
+
+func (f *fakePubSub) Subscribe(req pubsub.SubscribeRequest, handler func(msg *pubsub.NewMessage) error) error {
+	f.handlers[req.Topic] = handler
+
+	return nil
+}
+
+type memoryStore struct {
+	items map[string][]byte
+	ttls  map[string]time.Duration
+	lock  sync.Mutex
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{items: map[string][]byte{}, ttls: map[string]time.Duration{}}
+}
+
+func (m *memoryStore) Put(key string, data []byte, ttl time.Duration) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.items[key] = data
+	m.ttls[key] = ttl
+
+	return nil
+}
+
+func (m *memoryStore) Get(key string) ([]byte, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	data, ok := m.items[key]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+
+	return data, nil
+}
+
+func (m *memoryStore) Delete(key string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	delete(m.items, key)
+
+	return nil
+}
+
+func (m *memoryStore) len() int {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	return len(m.items)
+}
+
+func TestParseMetadata(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		m, err := parseMetadata(pubsub.Metadata{Properties: map[string]string{}})
+		require.NoError(t, err)
+		assert.Equal(t, defaultThresholdBytes, m.thresholdBytes)
+		assert.Equal(t, time.Duration(0), m.retention)
+		assert.True(t, m.deleteAfterDelivery)
+	})
+
+	t.Run("all fields", func(t *testing.T) {
+		m, err := parseMetadata(pubsub.Metadata{Properties: map[string]string{
+			thresholdBytesKey:      "1024",
+			retentionInSecondsKey:  "3600",
+			deleteAfterDeliveryKey: "false",
+		}})
+		require.NoError(t, err)
+		assert.Equal(t, 1024, m.thresholdBytes)
+		assert.Equal(t, time.Hour, m.retention)
+		assert.False(t, m.deleteAfterDelivery)
+	})
+
+	t.Run("invalid threshold", func(t *testing.T) {
+		_, err := parseMetadata(pubsub.Metadata{Properties: map[string]string{thresholdBytesKey: "-1"}})
+		assert.Error(t, err)
+	})
+}
+
+func TestClaimCheck(t *testing.T) {
+	l := logger.NewLogger("claimcheck-test")
+	meta := pubsub.Metadata{Properties: map[string]string{
+		thresholdBytesKey:     "8",
+		retentionInSecondsKey: "60",
+	}}
+
+	t.Run("small payloads are published inline", func(t *testing.T) {
+		inner := newFakePubSub()
+		store := newMemoryStore()
+		c := New(inner, store, l)
+		require.NoError(t, c.Init(meta))
+
+		var received []byte
+		require.NoError(t, c.Subscribe(pubsub.SubscribeRequest{Topic: "t"}, func(msg *pubsub.NewMessage) error {
+			received = msg.Data
+
+			return nil
+		}))
+		require.NoError(t, c.Publish(&pubsub.PublishRequest{Topic: "t", Data: []byte("small")}))
+		require.NoError(t, inner.deliver("t"))
+
+		assert.Equal(t, []byte("small"), inner.published[0])
+		assert.Equal(t, []byte("small"), received)
+		assert.Equal(t, 0, store.len())
+	})
+
+	t.Run("large payloads are offloaded and rehydrated", func(t *testing.T) {
+		inner := newFakePubSub()
+		store := newMemoryStore()
+		c := New(inner, store, l)
+		require.NoError(t, c.Init(meta))
+
+		payload := []byte(strings.Repeat("x", 64))
+		var received *pubsub.NewMessage
+		require.NoError(t, c.Subscribe(pubsub.SubscribeRequest{Topic: "t"}, func(msg *pubsub.NewMessage) error {
+			received = msg
+			assert.Equal(t, 1, store.len())
+
+			return nil
+		}))
+		require.NoError(t, c.Publish(&pubsub.PublishRequest{Topic: "t", Data: payload}))
+		require.NoError(t, inner.deliver("t"))
+
+		ref, ok := parseReference(inner.published[0])
+		require.True(t, ok)
+		assert.Equal(t, len(payload), ref.Size)
+		assert.Equal(t, payload, received.Data)
+		assert.Equal(t, ref.Key, received.Metadata[KeyMetadataKey])
+		assert.Equal(t, time.Minute, store.ttls[ref.Key])
+		assert.Equal(t, 0, store.len())
+	})
+
+	t.Run("payloads are kept for other subscribers", func(t *testing.T) {
+		inner := newFakePubSub()
+		store := newMemoryStore()
+		c := New(inner, store, l)
+		require.NoError(t, c.Init(pubsub.Metadata{Properties: map[string]string{
+			thresholdBytesKey:      "8",
+			retentionInSecondsKey:  "60",
+			deleteAfterDeliveryKey: "false",
+		}}))
+
+		calls := 0
+		require.NoError(t, c.Subscribe(pubsub.SubscribeRequest{Topic: "t"}, func(msg *pubsub.NewMessage) error {
+			calls++
+
+			return nil
+		}))
+		require.NoError(t, c.Publish(&pubsub.PublishRequest{Topic: "t", Data: []byte(strings.Repeat("x", 64))}))
+		require.NoError(t, inner.deliver("t"))
+		require.NoError(t, inner.deliver("t"))
+		assert.Equal(t, 2, calls)
+		assert.Equal(t, 1, store.len())
+	})
+
+	t.Run("handler errors are returned", func(t *testing.T) {
+		inner := newFakePubSub()
+		store := newMemoryStore()
+		c := New(inner, store, l)
+		require.NoError(t, c.Init(meta))
+
+		require.NoError(t, c.Subscribe(pubsub.SubscribeRequest{Topic: "t"}, func(msg *pubsub.NewMessage) error {
+			return errors.New("handler failed")
+		}))
+		require.NoError(t, c.Publish(&pubsub.PublishRequest{Topic: "t", Data: []byte(strings.Repeat("x", 64))}))
+
+		assert.Error(t, inner.deliver("t"))
+		assert.Equal(t, 1, store.len())
+	})
+
+	t.Run("payload is removed when publishing fails", func(t *testing.T) {
+		inner := newFakePubSub()
+		inner.err = errors.New("broker unavailable")
+		store := newMemoryStore()
+		c := New(inner, store, l)
+		require.NoError(t, c.Init(meta))
+
+		err := c.Publish(&pubsub.PublishRequest{Topic: "t", Data: []byte(strings.Repeat("x", 64))})
+		assert.Error(t, err)
+		assert.Equal(t, 0, store.len())
+	})
+}
+
+func TestParseReference(t *testing.T) {
+	_, ok := parseReference([]byte(`{"data":"hello"}`))
+	assert.False(t, ok)
+
+	_, ok = parseReference([]byte(`not json`))
+	assert.False(t, ok)
+
+	ref, ok := parseReference([]byte(`{"claimCheck":"v1","key":"t/1","size":10}`))
+	assert.True(t, ok)
+	assert.Equal(t, "t/1", ref.Key)
+}
+
+type fakeBinding struct {
+	requests []*bindings.InvokeRequest
+}
+
+func (f *fakeBinding) Init(metadata bindings.Metadata) error { return nil }
+func (f *fakeBinding) Operations() []bindings.OperationKind  { return nil }
+
+func (f *fakeBinding) Invoke(req *bindings.InvokeRequest) (*bindings.InvokeResponse, error) {
+	f.requests = append(f.requests, req)
+
+	return &bindings.InvokeResponse{Data: req.Data}, nil
+}
+
+func TestBindingPayloadStore(t *testing.T) {
+	binding := &fakeBinding{}
+	store := NewBindingPayloadStore(binding, "key")
+
+	require.NoError(t, store.Put("t/1", []byte("payload"), time.Hour))
+	require.NoError(t, store.Put("t/2", []byte("payload"), 0))
+
+	require.Len(t, binding.requests, 2)
+	assert.Equal(t, bindings.CreateOperation, binding.requests[0].Operation)
+	assert.Equal(t, map[string]string{"key": "t/1", "ttlInSeconds": "3600"}, binding.requests[0].Metadata)
+	assert.Equal(t, map[string]string{"key": "t/2"}, binding.requests[1].Metadata)
+}
+
+func TestBindingPayloadStoreDeleteAfterDelivery(t *testing.T) {
+	binding := &fakeBinding{}
+	inner := newFakePubSub()
+	c := New(inner, NewBindingPayloadStore(binding, "key"), logger.NewLogger("claimcheck-test"))
+	require.NoError(t, c.Init(pubsub.Metadata{Properties: map[string]string{
+		thresholdBytesKey:     "8",
+		retentionInSecondsKey: "60",
+	}}))
+
+	require.NoError(t, c.Subscribe(pubsub.SubscribeRequest{Topic: "t"}, func(msg *pubsub.NewMessage) error {
+		return nil
+	}))
+	require.NoError(t, c.Publish(&pubsub.PublishRequest{Topic: "t", Data: []byte(strings.Repeat("x", 64))}))
+	require.NoError(t, inner.deliver("t"))
+
+	ref, ok := parseReference(inner.published[0])
+	require.True(t, ok)
+	// The binding ignores ttlInSeconds, so the payload is deleted once it was handled
+	require.Len(t, binding.requests, 3)
+	assert.Equal(t, bindings.CreateOperation, binding.requests[0].Operation)
+	assert.Equal(t, bindings.GetOperation, binding.requests[1].Operation)
+	assert.Equal(t, bindings.DeleteOperation, binding.requests[2].Operation)
+	assert.Equal(t, map[string]string{"key": ref.Key}, binding.requests[2].Metadata)
+}
@@ -0,0 +1,64 @@
+// ------------------------------------------------------------
+// Copyright (c) Microsoft Corporation and Dapr Contributors.
+// Licensed under the MIT License.
+// ------------------------------------------------------------
+
+package claimcheck
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/dapr/components-contrib/pubsub"
+)
+
+const (
+	thresholdBytesKey      = "claimCheckThresholdBytes"
+	retentionInSecondsKey  = "claimCheckRetentionInSeconds"
+	deleteAfterDeliveryKey = "claimCheckDeleteAfterDelivery"
+
+	defaultThresholdBytes = 256 * 1024
+)
+
+type metadata struct {
+	// Payloads larger than this number of bytes are offloaded to the payload store
+	thresholdBytes int
+	// The TTL of offloaded payloads in the payload store (0 keeps them forever)
+	retention time.Duration
+	// Removes the offloaded payload once the subscriber handled it successfully
+	deleteAfterDelivery bool
+}
+
+func parseMetadata(meta pubsub.Metadata) (metadata, error) {
+	m := metadata{
+		thresholdBytes:      defaultThresholdBytes,
+		deleteAfterDelivery: true,
+	}
+
+	if val, ok := meta.Properties[thresholdBytesKey]; ok && val != "" {
+		threshold, err := strconv.Atoi(val)
+		if err != nil || threshold <= 0 {
+			return m, fmt.Errorf("claim check error: %s must be a positive integer: actual is '%s'", thresholdBytesKey, val)
+		}
+		m.thresholdBytes = threshold
+	}
+
+	if val, ok := meta.Properties[retentionInSecondsKey]; ok && val != "" {
+		retention, err := strconv.ParseUint(val, 10, 32)
+		if err != nil {
+			return m, fmt.Errorf("claim check error: can't parse %s field: %s", retentionInSecondsKey, err)
+		}
+		m.retention = time.Duration(retention) * time.Second
+	}
+
+	if val, ok := meta.Properties[deleteAfterDeliveryKey]; ok && val != "" {
+		deleteAfterDelivery, err := strconv.ParseBool(val)
+		if err != nil {
+			return m, fmt.Errorf("claim check error: can't parse %s field: %s", deleteAfterDeliveryKey, err)
+		}
+		m.deleteAfterDelivery = deleteAfterDelivery
+	}
+
+	return m, nil
+}
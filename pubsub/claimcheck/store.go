@@ -0,0 +1,122 @@
+// ------------------------------------------------------------
+// Copyright (c) Microsoft Corporation and Dapr Contributors.
+// Licensed under the MIT License.
+// ------------------------------------------------------------
+
+package claimcheck
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/dapr/components-contrib/bindings"
+	contrib_metadata "github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/components-contrib/state"
+)
+
+// PayloadStore persists offloaded message payloads under a key.
+type PayloadStore interface {
+	// Put stores the payload. A non-zero ttl asks the store to expire the payload on its own.
+	Put(key string, data []byte, ttl time.Duration) error
+	Get(key string) ([]byte, error)
+	Delete(key string) error
+}
+
+type statePayloadStore struct {
+	store state.Store
+}
+
+// NewStatePayloadStore returns a PayloadStore that keeps payloads in a state store.
+// The retention is forwarded as the ttlInSeconds request metadata.
+func NewStatePayloadStore(store state.Store) PayloadStore {
+	return &statePayloadStore{store: store}
+}
+
+func (s *statePayloadStore) Put(key string, data []byte, ttl time.Duration) error {
+	req := &state.SetRequest{
+		Key:   key,
+		Value: data,
+	}
+	if ttl > 0 {
+		req.Metadata = map[string]string{
+			contrib_metadata.TTLMetadataKey: strconv.FormatInt(int64(ttl/time.Second), 10),
+		}
+	}
+
+	return s.store.Set(req)
+}
+
+func (s *statePayloadStore) Get(key string) ([]byte, error) {
+	resp, err := s.store.Get(&state.GetRequest{Key: key})
+	if err != nil {
+		return nil, err
+	}
+	if resp == nil || resp.Data == nil {
+		return nil, fmt.Errorf("claim check error: payload %s not found", key)
+	}
+
+	return resp.Data, nil
+}
+
+func (s *statePayloadStore) Delete(key string) error {
+	return s.store.Delete(&state.DeleteRequest{Key: key})
+}
+
+type bindingPayloadStore struct {
+	binding     bindings.OutputBinding
+	keyMetadata string
+}
+
+// NewBindingPayloadStore returns a PayloadStore backed by a blob-like output binding
+// (aws.s3, azure.blobstorage, gcp.bucket, ...). keyMetadata is the name of the request
+// metadata field the binding reads the object name from, for example "key" or "blobName".
+// The retention is forwarded as the ttlInSeconds request metadata, which the
+// aws.s3, azure.blobstorage and gcp.bucket bindings ignore: their payloads are
+// only removed after delivery (see claimCheckDeleteAfterDelivery) or by a
+// lifecycle rule on the bucket or container.
+func NewBindingPayloadStore(binding bindings.OutputBinding, keyMetadata string) PayloadStore {
+	return &bindingPayloadStore{
+		binding:     binding,
+		keyMetadata: keyMetadata,
+	}
+}
+
+func (b *bindingPayloadStore) Put(key string, data []byte, ttl time.Duration) error {
+	metadata := map[string]string{b.keyMetadata: key}
+	if ttl > 0 {
+		metadata[contrib_metadata.TTLMetadataKey] = strconv.FormatInt(int64(ttl/time.Second), 10)
+	}
+
+	_, err := b.binding.Invoke(&bindings.InvokeRequest{
+		Operation: bindings.CreateOperation,
+		Data:      data,
+		Metadata:  metadata,
+	})
+
+	return err
+}
+
+func (b *bindingPayloadStore) Get(key string) ([]byte, error) {
+	resp, err := b.binding.Invoke(&bindings.InvokeRequest{
+		Operation: bindings.GetOperation,
+		Metadata:  map[string]string{b.keyMetadata: key},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if resp == nil {
+		return nil, fmt.Errorf("claim check error: payload %s not found", key)
+	}
+
+	return resp.Data, nil
+}
+
+func (b *bindingPayloadStore) Delete(key string) error {
+	_, err := b.binding.Invoke(&bindings.InvokeRequest{
+		Operation: bindings.DeleteOperation,
+		Metadata:  map[string]string{b.keyMetadata: key},
+	})
+
+	return err
+}
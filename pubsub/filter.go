@@ -0,0 +1,486 @@
+// ------------------------------------------------------------
+// Copyright (c) Microsoft Corporation and Dapr Contributors.
+// Licensed under the MIT License.
+// ------------------------------------------------------------
+
+package pubsub
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+const (
+	// FilterMetadataKey is the subscription metadata key holding the filter expression
+	FilterMetadataKey = "filter"
+	// FilterAttributePrefix prefixes the CloudEvent attributes that publishers attach
+	// as broker message properties so that filters can be evaluated by the broker
+	FilterAttributePrefix = "ce_"
+)
+
+// filterAttributes are the CloudEvent attributes a filter can push down to a broker.
+var filterAttributes = []string{TypeField, SubjectField, SourceField} // nolint: gochecknoglobals
+
+// Filter is a content-based subscription filter evaluated over the CloudEvent
+// attributes and the JSON data fields of a message.
+//
+// The expression language supports comparisons (=, !=, <, <=, >, >=) between a
+// field path and a literal, combined with AND, OR, NOT and parentheses.
+// Field paths address CloudEvent attributes (type, subject, source, ...) or
+// JSON data fields (data.customer.tier). Literals are single-quoted strings,
+// numbers, true, false or null. For example:
+//
+//	type = 'order.created' AND (data.amount > 100 OR data.priority = 'high')
+type Filter struct {
+	expression string
+	root       filterNode
+}
+
+// ParseFilter parses a filter expression.
+func ParseFilter(expression string) (*Filter, error) {
+	tokens, err := tokenizeFilter(expression)
+	if err != nil {
+		return nil, fmt.Errorf("invalid filter '%s': %s", expression, err)
+	}
+
+	p := &filterParser{tokens: tokens}
+	root, err := p.parseOr()
+	if err == nil && p.pos < len(p.tokens) {
+		err = fmt.Errorf("unexpected '%s'", p.tokens[p.pos].text)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid filter '%s': %s", expression, err)
+	}
+
+	return &Filter{expression: expression, root: root}, nil
+}
+
+// FilterFromMetadata returns the filter configured in the subscription metadata,
+// or nil when the subscription has no filter.
+func FilterFromMetadata(metadata map[string]string) (*Filter, error) {
+	if val, ok := metadata[FilterMetadataKey]; ok && strings.TrimSpace(val) != "" {
+		return ParseFilter(val)
+	}
+
+	return nil, nil
+}
+
+// FilterHandler returns a handler that only calls handler for messages matching
+// the filter configured in the subscription request. Messages that do not match
+// are acknowledged without being handed to the handler.
+func FilterHandler(req SubscribeRequest, handler func(msg *NewMessage) error) (func(msg *NewMessage) error, error) {
+	filter, err := FilterFromMetadata(req.Metadata)
+	if err != nil || filter == nil {
+		return handler, err
+	}
+
+	return func(msg *NewMessage) error {
+		if !filter.Match(msg.Data) {
+			return nil
+		}
+
+		return handler(msg)
+	}, nil
+}
+
+// String returns the filter expression.
+func (f *Filter) String() string {
+	return f.expression
+}
+
+// Match evaluates the filter against a message, which is expected to be a
+// JSON CloudEvent. Fields that are missing never compare as true.
+func (f *Filter) Match(data []byte) bool {
+	var event map[string]interface{}
+	if err := jsoniter.Unmarshal(data, &event); err != nil {
+		event = map[string]interface{}{}
+	}
+
+	return f.root.eval(event)
+}
+
+// AttributeEqualities returns the attribute values the filter requires when it is
+// a conjunction of equalities on type, subject or source only. Such filters can
+// be pushed down to brokers that match on message properties; ok is false otherwise.
+func (f *Filter) AttributeEqualities() (equalities map[string]string, ok bool) {
+	equalities = map[string]string{}
+	if !collectEqualities(f.root, equalities) {
+		return nil, false
+	}
+
+	return equalities, true
+}
+
+func collectEqualities(node filterNode, equalities map[string]string) bool {
+	switch n := node.(type) {
+	case *andNode:
+		return collectEqualities(n.left, equalities) && collectEqualities(n.right, equalities)
+	case *comparisonNode:
+		value, isString := n.value.(string)
+		if n.op != "=" || !isString || len(n.path) != 1 || !isFilterAttribute(n.path[0]) {
+			return false
+		}
+		if existing, ok := equalities[n.path[0]]; ok && existing != value {
+			return false
+		}
+		equalities[n.path[0]] = value
+
+		return true
+	default:
+		return false
+	}
+}
+
+// FilterProperties returns the CloudEvent attributes of data that can be used by
+// pushed down filters, keyed by their property name (see FilterAttributePrefix).
+// Publishers attach them to broker messages as headers or user properties.
+func FilterProperties(data []byte) map[string]string {
+	var event map[string]interface{}
+	if err := jsoniter.Unmarshal(data, &event); err != nil {
+		return nil
+	}
+
+	properties := map[string]string{}
+	for _, attribute := range filterAttributes {
+		if val, ok := event[attribute].(string); ok && val != "" {
+			properties[FilterAttributePrefix+attribute] = val
+		}
+	}
+
+	return properties
+}
+
+func isFilterAttribute(name string) bool {
+	for _, attribute := range filterAttributes {
+		if attribute == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+type filterNode interface {
+	eval(event map[string]interface{}) bool
+}
+
+type andNode struct {
+	left, right filterNode
+}
+
+func (n *andNode) eval(event map[string]interface{}) bool {
+	return n.left.eval(event) && n.right.eval(event)
+}
+
+type orNode struct {
+	left, right filterNode
+}
+
+func (n *orNode) eval(event map[string]interface{}) bool {
+	return n.left.eval(event) || n.right.eval(event)
+}
+
+type notNode struct {
+	inner filterNode
+}
+
+func (n *notNode) eval(event map[string]interface{}) bool {
+	return !n.inner.eval(event)
+}
+
+type comparisonNode struct {
+	path  []string
+	op    string
+	value interface{}
+}
+
+func (n *comparisonNode) eval(event map[string]interface{}) bool {
+	var current interface{} = event
+	for _, segment := range n.path {
+		obj, ok := current.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		if current, ok = obj[segment]; !ok {
+			return false
+		}
+	}
+
+	switch expected := n.value.(type) {
+	case nil:
+		switch n.op {
+		case "=":
+			return current == nil
+		case "!=":
+			return current != nil
+		}
+	case bool:
+		actual, ok := current.(bool)
+		if !ok {
+			return false
+		}
+		switch n.op {
+		case "=":
+			return actual == expected
+		case "!=":
+			return actual != expected
+		}
+	case float64:
+		actual, ok := current.(float64)
+		if !ok {
+			return false
+		}
+
+		return compareOrdered(n.op, compareFloat(actual, expected))
+	case string:
+		actual, ok := current.(string)
+		if !ok {
+			return false
+		}
+
+		return compareOrdered(n.op, strings.Compare(actual, expected))
+	}
+
+	return false
+}
+
+func compareFloat(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func compareOrdered(op string, cmp int) bool {
+	switch op {
+	case "=":
+		return cmp == 0
+	case "!=":
+		return cmp != 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	}
+
+	return false
+}
+
+type filterTokenKind int
+
+const (
+	tokenIdent filterTokenKind = iota
+	tokenString
+	tokenNumber
+	tokenOperator
+	tokenLParen
+	tokenRParen
+)
+
+type filterToken struct {
+	kind filterTokenKind
+	text string
+}
+
+func tokenizeFilter(expression string) ([]filterToken, error) {
+	tokens := []filterToken{}
+	runes := []rune(expression)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			tokens = append(tokens, filterToken{kind: tokenLParen, text: "("})
+			i++
+		case r == ')':
+			tokens = append(tokens, filterToken{kind: tokenRParen, text: ")"})
+			i++
+		case r == '\'':
+			var sb strings.Builder
+			i++
+			for {
+				if i >= len(runes) {
+					return nil, fmt.Errorf("unterminated string")
+				}
+				// A doubled quote escapes a quote inside a string
+				if runes[i] == '\'' {
+					if i+1 < len(runes) && runes[i+1] == '\'' {
+						sb.WriteRune('\'')
+						i += 2
+
+						continue
+					}
+					i++
+
+					break
+				}
+				sb.WriteRune(runes[i])
+				i++
+			}
+			tokens = append(tokens, filterToken{kind: tokenString, text: sb.String()})
+		case strings.ContainsRune("=!<>", r):
+			op := string(r)
+			if i+1 < len(runes) && (runes[i+1] == '=' || (r == '<' && runes[i+1] == '>')) {
+				op += string(runes[i+1])
+			}
+			if op == "!" {
+				return nil, fmt.Errorf("unexpected '!'")
+			}
+			i += len(op)
+			if op == "<>" {
+				op = "!="
+			}
+			tokens = append(tokens, filterToken{kind: tokenOperator, text: op})
+		case r == '-' || unicode.IsDigit(r):
+			start := i
+			i++
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.' || runes[i] == 'e' || runes[i] == 'E' || runes[i] == '-' || runes[i] == '+') {
+				i++
+			}
+			tokens = append(tokens, filterToken{kind: tokenNumber, text: string(runes[start:i])})
+		case unicode.IsLetter(r) || r == '_' || r == '$':
+			start := i
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || strings.ContainsRune("_$.-", runes[i])) {
+				i++
+			}
+			tokens = append(tokens, filterToken{kind: tokenIdent, text: string(runes[start:i])})
+		default:
+			return nil, fmt.Errorf("unexpected character '%c'", r)
+		}
+	}
+
+	return tokens, nil
+}
+
+type filterParser struct {
+	tokens []filterToken
+	pos    int
+}
+
+func (p *filterParser) peekKeyword(keyword string) bool {
+	return p.pos < len(p.tokens) && p.tokens[p.pos].kind == tokenIdent && strings.EqualFold(p.tokens[p.pos].text, keyword)
+}
+
+func (p *filterParser) parseOr() (filterNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peekKeyword("OR") {
+		p.pos++
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &orNode{left: left, right: right}
+	}
+
+	return left, nil
+}
+
+func (p *filterParser) parseAnd() (filterNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peekKeyword("AND") {
+		p.pos++
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &andNode{left: left, right: right}
+	}
+
+	return left, nil
+}
+
+func (p *filterParser) parseUnary() (filterNode, error) {
+	if p.pos >= len(p.tokens) {
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+
+	if p.peekKeyword("NOT") {
+		p.pos++
+		inner, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+
+		return &notNode{inner: inner}, nil
+	}
+
+	if p.tokens[p.pos].kind == tokenLParen {
+		p.pos++
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.pos >= len(p.tokens) || p.tokens[p.pos].kind != tokenRParen {
+			return nil, fmt.Errorf("missing ')'")
+		}
+		p.pos++
+
+		return inner, nil
+	}
+
+	return p.parseComparison()
+}
+
+func (p *filterParser) parseComparison() (filterNode, error) {
+	if p.pos+3 > len(p.tokens) {
+		return nil, fmt.Errorf("incomplete comparison")
+	}
+
+	field, op, literal := p.tokens[p.pos], p.tokens[p.pos+1], p.tokens[p.pos+2]
+	if field.kind != tokenIdent {
+		return nil, fmt.Errorf("expected field name, got '%s'", field.text)
+	}
+	if op.kind != tokenOperator {
+		return nil, fmt.Errorf("expected comparison operator after '%s'", field.text)
+	}
+
+	node := &comparisonNode{path: strings.Split(field.text, "."), op: op.text}
+	switch literal.kind {
+	case tokenString:
+		node.value = literal.text
+	case tokenNumber:
+		number, err := strconv.ParseFloat(literal.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number '%s'", literal.text)
+		}
+		node.value = number
+	case tokenIdent:
+		switch strings.ToLower(literal.text) {
+		case "true":
+			node.value = true
+		case "false":
+			node.value = false
+		case "null":
+			node.value = nil
+		default:
+			return nil, fmt.Errorf("expected literal, got '%s'", literal.text)
+		}
+		if op.text != "=" && op.text != "!=" {
+			return nil, fmt.Errorf("operator '%s' is not supported for '%s'", op.text, literal.text)
+		}
+	default:
+		return nil, fmt.Errorf("expected literal, got '%s'", literal.text)
+	}
+	p.pos += 3
+
+	return node, nil
+}
@@ -0,0 +1,155 @@
+// ------------------------------------------------------------
+// Copyright (c) Microsoft Corporation and Dapr Contributors.
+// Licensed under the MIT License.
+// ------------------------------------------------------------
+
+package pubsub
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const filterTestEvent = `{
+	"id": "1",
+	"specversion": "1.0",
+	"type": "order.created",
+	"source": "checkout",
+	"subject": "orders/42",
+	"datacontenttype": "application/json",
+	"data": {"amount": 150, "priority": "high", "express": true, "coupon": null, "customer": {"tier": "gold"}}
+}`
+
+func TestParseFilter(t *testing.T) {
+	valid := []string{
+		"type = 'order.created'",
+		"type='order.created' and subject != 'orders/1'",
+		"NOT (data.amount < 10 OR data.amount >= 1000)",
+		"data.express = true AND data.coupon = null",
+		"data.amount <> -1.5e2",
+		"subject = 'it''s'",
+	}
+	for _, expr := range valid {
+		_, err := ParseFilter(expr)
+		assert.NoError(t, err, expr)
+	}
+
+	invalid := []string{
+		"",
+		"type",
+		"type =",
+		"type = 'unterminated",
+		"(type = 'a'",
+		"type = 'a' AND",
+		"type = 'a' 'b'",
+		"data.express > true",
+		"type ! 'a'",
+		"'a' = type",
+	}
+	for _, expr := range invalid {
+		_, err := ParseFilter(expr)
+		assert.Error(t, err, expr)
+	}
+}
+
+func TestFilterMatch(t *testing.T) {
+	tests := []struct {
+		expr    string
+		matches bool
+	}{
+		{"type = 'order.created'", true},
+		{"type = 'order.deleted'", false},
+		{"type != 'order.deleted'", true},
+		{"source = 'checkout' AND subject = 'orders/42'", true},
+		{"data.amount > 100", true},
+		{"data.amount <= 100", false},
+		{"data.amount = 150", true},
+		{"data.priority = 'low' OR data.customer.tier = 'gold'", true},
+		{"NOT data.express = true", false},
+		{"data.coupon = null", true},
+		{"data.missing = 'x'", false},
+		{"data.missing != 'x'", false},
+		{"data.amount = '150'", false},
+		{"type = 'order.created' AND (data.amount > 1000 OR data.priority = 'high')", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			filter, err := ParseFilter(tt.expr)
+			require.NoError(t, err)
+			assert.Equal(t, tt.matches, filter.Match([]byte(filterTestEvent)))
+		})
+	}
+
+	t.Run("non JSON data never matches", func(t *testing.T) {
+		filter, err := ParseFilter("type = 'order.created'")
+		require.NoError(t, err)
+		assert.False(t, filter.Match([]byte("plain text")))
+	})
+}
+
+func TestFilterAttributeEqualities(t *testing.T) {
+	filter, err := ParseFilter("type = 'order.created' AND source = 'checkout'")
+	require.NoError(t, err)
+	equalities, ok := filter.AttributeEqualities()
+	assert.True(t, ok)
+	assert.Equal(t, map[string]string{"type": "order.created", "source": "checkout"}, equalities)
+
+	for _, expr := range []string{
+		"type = 'a' OR type = 'b'",
+		"data.amount = 'a'",
+		"type != 'a'",
+		"type = 'a' AND type = 'b'",
+	} {
+		filter, err := ParseFilter(expr)
+		require.NoError(t, err)
+		_, ok := filter.AttributeEqualities()
+		assert.False(t, ok, expr)
+	}
+}
+
+func TestFilterProperties(t *testing.T) {
+	assert.Equal(t, map[string]string{
+		"ce_type":    "order.created",
+		"ce_source":  "checkout",
+		"ce_subject": "orders/42",
+	}, FilterProperties([]byte(filterTestEvent)))
+	assert.Nil(t, FilterProperties([]byte("plain text")))
+}
+
+func TestFilterHandler(t *testing.T) {
+	called := 0
+	handler := func(msg *NewMessage) error {
+		called++
+
+		return errors.New("handled")
+	}
+
+	t.Run("without filter", func(t *testing.T) {
+		called = 0
+		h, err := FilterHandler(SubscribeRequest{Topic: "orders"}, handler)
+		require.NoError(t, err)
+		assert.Error(t, h(&NewMessage{Data: []byte(filterTestEvent)}))
+		assert.Equal(t, 1, called)
+	})
+
+	t.Run("filtered out messages are acked", func(t *testing.T) {
+		called = 0
+		h, err := FilterHandler(SubscribeRequest{Topic: "orders", Metadata: map[string]string{
+			FilterMetadataKey: "type = 'order.deleted'",
+		}}, handler)
+		require.NoError(t, err)
+		assert.NoError(t, h(&NewMessage{Data: []byte(filterTestEvent)}))
+		assert.Equal(t, 0, called)
+	})
+
+	t.Run("invalid filter", func(t *testing.T) {
+		_, err := FilterHandler(SubscribeRequest{Topic: "orders", Metadata: map[string]string{
+			FilterMetadataKey: "type =",
+		}}, handler)
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,150 @@
+package pubsub
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	monitoring "cloud.google.com/go/monitoring/apiv3"
+	"github.com/dapr/components-contrib/pubsub"
+	"github.com/golang/protobuf/ptypes"
+	"google.golang.org/api/iterator"
+	monitoringpb "google.golang.org/genproto/googleapis/monitoring/v3"
+)
+
+const (
+	// undeliveredMessagesMetric is the Cloud Monitoring metric of the backlog of a subscription
+	undeliveredMessagesMetric = "pubsub.googleapis.com/subscription/num_undelivered_messages"
+	// lagSamplesWindow covers the delay with which Cloud Monitoring publishes the samples
+	lagSamplesWindow = 10 * time.Minute
+)
+
+// CreateTopic creates a topic. GCP topics have no partitions, and the
+// retention of their messages is set on subscriptions, so both are ignored.
+func (g *GCPPubSub) CreateTopic(req pubsub.CreateTopicRequest) error {
+	_, err := g.client.CreateTopic(context.Background(), req.Topic)
+	if err != nil {
+		return fmt.Errorf("%s could not create topic %s, %s", errorMessagePrefix, req.Topic, err)
+	}
+
+	return nil
+}
+
+// DeleteTopic deletes a topic. Its subscriptions are kept, detached from the topic.
+func (g *GCPPubSub) DeleteTopic(req pubsub.DeleteTopicRequest) error {
+	g.topicsLock.Lock()
+	if t, ok := g.topics[req.Topic]; ok {
+		t.Stop()
+		delete(g.topics, req.Topic)
+	}
+	g.topicsLock.Unlock()
+
+	if err := g.client.Topic(req.Topic).Delete(context.Background()); err != nil {
+		return fmt.Errorf("%s could not delete topic %s, %s", errorMessagePrefix, req.Topic, err)
+	}
+
+	return nil
+}
+
+// ListTopics returns the topics of the project.
+func (g *GCPPubSub) ListTopics() ([]string, error) {
+	var topics []string
+	it := g.client.Topics(context.Background())
+	for {
+		t, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s could not list topics, %s", errorMessagePrefix, err)
+		}
+		topics = append(topics, t.ID())
+	}
+	sort.Strings(topics)
+
+	return topics, nil
+}
+
+// ListSubscriptions returns the subscriptions of a topic.
+func (g *GCPPubSub) ListSubscriptions(topic string) ([]string, error) {
+	var subscriptions []string
+	it := g.client.Topic(topic).Subscriptions(context.Background())
+	for {
+		s, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s could not list subscriptions of topic %s, %s", errorMessagePrefix, topic, err)
+		}
+		subscriptions = append(subscriptions, s.ID())
+	}
+	sort.Strings(subscriptions)
+
+	return subscriptions, nil
+}
+
+// GetSubscriptionLag returns the num_undelivered_messages metric of a
+// subscription from Cloud Monitoring, as Pub/Sub doesn't expose the backlog
+// itself. The metric is sampled every minute and published with a delay of a
+// few minutes, so the lag is not real time. It is not supported by the emulator.
+func (g *GCPPubSub) GetSubscriptionLag(req pubsub.SubscriptionLagRequest) (*pubsub.SubscriptionLagResponse, error) {
+	if g.metadata.EmulatorHost != "" || os.Getenv(emulatorHostEnvVar) != "" {
+		return nil, fmt.Errorf("%s subscription lag is not available with the emulator: %w", errorMessagePrefix, pubsub.ErrNotSupported)
+	}
+
+	ctx := context.Background()
+	client, err := monitoring.NewMetricClient(ctx, g.clientOptions...)
+	if err != nil {
+		return nil, fmt.Errorf("%s error creating monitoring client: %s", errorMessagePrefix, err)
+	}
+	defer client.Close()
+
+	it := client.ListTimeSeries(ctx, subscriptionLagRequest(g.metadata.ProjectID, req.Subscription, time.Now()))
+	series, err := it.Next()
+	if err == iterator.Done {
+		return nil, fmt.Errorf("%s no %s samples for subscription %s", errorMessagePrefix, undeliveredMessagesMetric, req.Subscription)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s could not get the lag of subscription %s, %s", errorMessagePrefix, req.Subscription, err)
+	}
+
+	lag, err := latestPoint(series)
+	if err != nil {
+		return nil, fmt.Errorf("%s could not get the lag of subscription %s, %s", errorMessagePrefix, req.Subscription, err)
+	}
+
+	return &pubsub.SubscriptionLagResponse{
+		Topic:        req.Topic,
+		Subscription: req.Subscription,
+		Lag:          lag,
+	}, nil
+}
+
+// subscriptionLagRequest requests the samples of the backlog of a subscription
+// over the last lagSamplesWindow.
+func subscriptionLagRequest(projectID, subscription string, now time.Time) *monitoringpb.ListTimeSeriesRequest {
+	// Both times are valid as they are far from the limits of protobuf timestamps
+	start, _ := ptypes.TimestampProto(now.Add(-lagSamplesWindow))
+	end, _ := ptypes.TimestampProto(now)
+
+	return &monitoringpb.ListTimeSeriesRequest{
+		Name:     "projects/" + projectID,
+		Filter:   fmt.Sprintf(`metric.type = "%s" AND resource.labels.subscription_id = "%s"`, undeliveredMessagesMetric, subscription),
+		Interval: &monitoringpb.TimeInterval{StartTime: start, EndTime: end},
+		View:     monitoringpb.ListTimeSeriesRequest_FULL,
+	}
+}
+
+// latestPoint returns the value of the most recent point of a time series,
+// which Cloud Monitoring returns first.
+func latestPoint(series *monitoringpb.TimeSeries) (int64, error) {
+	points := series.GetPoints()
+	if len(points) == 0 {
+		return 0, fmt.Errorf("no samples of %s", undeliveredMessagesMetric)
+	}
+
+	return points[0].GetValue().GetInt64Value(), nil
+}
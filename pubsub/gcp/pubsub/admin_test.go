@@ -0,0 +1,61 @@
+package pubsub
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dapr/components-contrib/pubsub"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	monitoringpb "google.golang.org/genproto/googleapis/monitoring/v3"
+)
+
+func TestTopicAdmin(t *testing.T) {
+	ps, _ := newEmulatedPubSub(t, nil)
+	var admin pubsub.TopicAdmin = ps
+
+	require.NoError(t, admin.CreateTopic(pubsub.CreateTopicRequest{Topic: "payments"}))
+	assert.Error(t, admin.CreateTopic(pubsub.CreateTopicRequest{Topic: "payments"}))
+	require.NoError(t, ps.ensureSubscription("consumer", "orders"))
+
+	topics, err := admin.ListTopics()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"orders", "payments"}, topics)
+
+	subscriptions, err := admin.ListSubscriptions("orders")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"consumer-orders"}, subscriptions)
+
+	// The emulator has no Cloud Monitoring
+	_, err = admin.GetSubscriptionLag(pubsub.SubscriptionLagRequest{Topic: "orders", Subscription: "consumer-orders"})
+	assert.True(t, errors.Is(err, pubsub.ErrNotSupported))
+
+	require.NoError(t, admin.DeleteTopic(pubsub.DeleteTopicRequest{Topic: "orders"}))
+	topics, err = admin.ListTopics()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"payments"}, topics)
+}
+
+func TestSubscriptionLagRequest(t *testing.T) {
+	now := time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC)
+	req := subscriptionLagRequest("project1", "consumer-orders", now)
+
+	assert.Equal(t, "projects/project1", req.Name)
+	assert.Equal(t, `metric.type = "pubsub.googleapis.com/subscription/num_undelivered_messages" AND resource.labels.subscription_id = "consumer-orders"`, req.Filter)
+	assert.Equal(t, now.Unix(), req.Interval.EndTime.Seconds)
+	assert.Equal(t, now.Add(-lagSamplesWindow).Unix(), req.Interval.StartTime.Seconds)
+}
+
+func TestLatestPoint(t *testing.T) {
+	point := func(v int64) *monitoringpb.Point {
+		return &monitoringpb.Point{Value: &monitoringpb.TypedValue{Value: &monitoringpb.TypedValue_Int64Value{Int64Value: v}}}
+	}
+
+	lag, err := latestPoint(&monitoringpb.TimeSeries{Points: []*monitoringpb.Point{point(42), point(7)}})
+	require.NoError(t, err)
+	assert.Equal(t, int64(42), lag)
+
+	_, err = latestPoint(&monitoringpb.TimeSeries{})
+	assert.Error(t, err)
+}
@@ -1,9 +1,11 @@
 package pubsub
 
+import "time"
+
 // GCPPubSubMetaData pubsub metadata
 type metadata struct {
 	ConsumerID              string `json:"consumerID"`
-	DisableEntityManagement bool   `json:"disableEntityManagement"`
+	DisableEntityManagement bool   `json:"-"`
 	Type                    string `json:"type"`
 	ProjectID               string `json:"project_id"`
 	PrivateKeyID            string `json:"private_key_id"`
@@ -14,4 +16,12 @@ type metadata struct {
 	TokenURI                string `json:"token_uri"`
 	AuthProviderCertURL     string `json:"auth_provider_x509_cert_url"`
 	ClientCertURL           string `json:"client_x509_cert_url"`
+
+	EmulatorHost          string        `json:"-"`
+	EnableMessageOrdering bool          `json:"-"`
+	DeadLetterTopic       string        `json:"-"`
+	MaxDeliveryAttempts   int           `json:"-"`
+	AckDeadline           time.Duration `json:"-"`
+	MinRetryBackoff       time.Duration `json:"-"`
+	MaxRetryBackoff       time.Duration `json:"-"`
 }
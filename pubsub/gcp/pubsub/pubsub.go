@@ -4,67 +4,175 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"time"
 
 	gcppubsub "cloud.google.com/go/pubsub"
 	"github.com/dapr/components-contrib/pubsub"
 	"github.com/dapr/dapr/pkg/logger"
 	"google.golang.org/api/option"
+	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
 
 const (
 	errorMessagePrefix = "gcp pubsub error:"
-	consumerID         = "consumerID"
+
+	// Metadata keys.
+	consumerID              = "consumerID"
+	disableEntityManagement = "disableEntityManagement"
+	emulatorHost            = "emulatorHost"
+	enableMessageOrdering   = "enableMessageOrdering"
+	deadLetterTopic         = "deadLetterTopic"
+	maxDeliveryAttempts     = "maxDeliveryAttempts"
+	ackDeadlineInSec        = "ackDeadlineInSec"
+	minRetryBackoffInSec    = "minRetryBackoffInSec"
+	maxRetryBackoffInSec    = "maxRetryBackoffInSec"
+
+	// Publish request and message metadata keys.
+	orderingKey = "orderingKey"
+
+	// emulatorHostEnvVar is honored by the GCP client library itself.
+	emulatorHostEnvVar = "PUBSUB_EMULATOR_HOST"
+
+	// defaultMaxDeliveryAttempts is used when a dead-letter topic is set without a
+	// delivery attempt limit. GCP accepts values between 5 and 100.
+	defaultMaxDeliveryAttempts = 5
 )
 
 // GCPPubSub type
 type GCPPubSub struct {
-	client   *gcppubsub.Client
-	metadata *metadata
-	logger   logger.Logger
+	client        *gcppubsub.Client
+	clientOptions []option.ClientOption
+	metadata      *metadata
+	logger        logger.Logger
+
+	topics     map[string]*gcppubsub.Topic
+	topicsLock sync.Mutex
 }
 
 // NewGCPPubSub returns a new GCPPubSub instance
 func NewGCPPubSub(logger logger.Logger) pubsub.PubSub {
-	return &GCPPubSub{logger: logger}
+	return &GCPPubSub{logger: logger, topics: map[string]*gcppubsub.Topic{}}
 }
 
 // Init parses metadata and creates a new Pub Sub client
 func (g *GCPPubSub) Init(meta pubsub.Metadata) error {
-	b, err := g.parseMetadata(meta)
+	pubsubMeta, err := g.parseMetadata(meta)
 	if err != nil {
 		return err
 	}
 
-	var pubsubMeta metadata
-	err = json.Unmarshal(b, &pubsubMeta)
+	clientOptions, err := g.getClientOptions(meta, pubsubMeta)
 	if err != nil {
 		return err
 	}
-	clientOptions := option.WithCredentialsJSON(b)
+
 	ctx := context.Background()
-	pubsubClient, err := gcppubsub.NewClient(ctx, pubsubMeta.ProjectID, clientOptions)
+	pubsubClient, err := gcppubsub.NewClient(ctx, pubsubMeta.ProjectID, clientOptions...)
 	if err != nil {
 		return fmt.Errorf("%s error creating pubsub client: %s", errorMessagePrefix, err)
 	}
 
+	g.client = pubsubClient
+	g.clientOptions = clientOptions
+	g.metadata = pubsubMeta
+
+	return nil
+}
+
+// getClientOptions returns the options used to connect to GCP. When an emulator
+// is configured no credentials are needed.
+func (g *GCPPubSub) getClientOptions(meta pubsub.Metadata, pubsubMeta *metadata) ([]option.ClientOption, error) {
+	if pubsubMeta.EmulatorHost != "" {
+		return []option.ClientOption{
+			option.WithEndpoint(pubsubMeta.EmulatorHost),
+			option.WithoutAuthentication(),
+			option.WithGRPCDialOption(grpc.WithInsecure()),
+		}, nil
+	}
+
+	// The client library connects to the emulator by itself when the environment variable is set.
+	if os.Getenv(emulatorHostEnvVar) != "" {
+		return nil, nil
+	}
+
+	b, err := json.Marshal(meta.Properties)
+	if err != nil {
+		return nil, err
+	}
+
+	return []option.ClientOption{option.WithCredentialsJSON(b)}, nil
+}
+
+func (g *GCPPubSub) parseMetadata(meta pubsub.Metadata) (*metadata, error) {
+	b, err := json.Marshal(meta.Properties)
+	if err != nil {
+		return nil, err
+	}
+
+	var m metadata
+	err = json.Unmarshal(b, &m)
+	if err != nil {
+		return nil, err
+	}
+
 	if val, ok := meta.Properties[consumerID]; ok && val != "" {
-		pubsubMeta.ConsumerID = val
+		m.ConsumerID = val
 	} else {
-		return fmt.Errorf("%s missing consumerID", errorMessagePrefix)
+		return nil, fmt.Errorf("%s missing consumerID", errorMessagePrefix)
 	}
 
-	g.client = pubsubClient
-	g.metadata = &pubsubMeta
+	if val, ok := meta.Properties[emulatorHost]; ok && val != "" {
+		m.EmulatorHost = val
+	}
 
-	return nil
-}
+	for key, value := range map[string]*bool{
+		disableEntityManagement: &m.DisableEntityManagement,
+		enableMessageOrdering:   &m.EnableMessageOrdering,
+	} {
+		if val, ok := meta.Properties[key]; ok && val != "" {
+			*value, err = strconv.ParseBool(val)
+			if err != nil {
+				return nil, fmt.Errorf("%s invalid %s %s, %s", errorMessagePrefix, key, val, err)
+			}
+		}
+	}
+
+	if val, ok := meta.Properties[deadLetterTopic]; ok && val != "" {
+		m.DeadLetterTopic = val
+		m.MaxDeliveryAttempts = defaultMaxDeliveryAttempts
+	}
+
+	if val, ok := meta.Properties[maxDeliveryAttempts]; ok && val != "" {
+		m.MaxDeliveryAttempts, err = strconv.Atoi(val)
+		if err != nil {
+			return nil, fmt.Errorf("%s invalid %s %s, %s", errorMessagePrefix, maxDeliveryAttempts, val, err)
+		}
+	}
+
+	for key, value := range map[string]*time.Duration{
+		ackDeadlineInSec:     &m.AckDeadline,
+		minRetryBackoffInSec: &m.MinRetryBackoff,
+		maxRetryBackoffInSec: &m.MaxRetryBackoff,
+	} {
+		if val, ok := meta.Properties[key]; ok && val != "" {
+			seconds, err := strconv.Atoi(val)
+			if err != nil {
+				return nil, fmt.Errorf("%s invalid %s %s, %s", errorMessagePrefix, key, val, err)
+			}
+			*value = time.Duration(seconds) * time.Second
+		}
+	}
 
-func (g *GCPPubSub) parseMetadata(metadata pubsub.Metadata) ([]byte, error) {
-	b, err := json.Marshal(metadata.Properties)
+	if m.MinRetryBackoff > 0 && m.MaxRetryBackoff > 0 && m.MinRetryBackoff > m.MaxRetryBackoff {
+		return nil, fmt.Errorf("%s %s must not be greater than %s", errorMessagePrefix, minRetryBackoffInSec, maxRetryBackoffInSec)
+	}
 
-	return b, err
+	return &m, nil
 }
 
 // Publish the topic to GCP Pubsub
@@ -79,15 +187,46 @@ func (g *GCPPubSub) Publish(req *pubsub.PublishRequest) error {
 	ctx := context.Background()
 	topic := g.getTopic(req.Topic)
 
-	_, err := topic.Publish(ctx, &gcppubsub.Message{
-		Data: req.Data,
-	}).Get((ctx))
+	msg := publishMessage(req)
+	_, err := topic.Publish(ctx, msg).Get(ctx)
+	if err != nil && msg.OrderingKey != "" {
+		// Publishing for an ordering key is paused after a failure until it is resumed.
+		topic.ResumePublish(msg.OrderingKey)
+	}
 
 	return err
 }
 
+// publishMessage converts a publish request to a GCP message. The ordering key
+// is taken from the request metadata and all other metadata becomes message attributes.
+func publishMessage(req *pubsub.PublishRequest) *gcppubsub.Message {
+	msg := &gcppubsub.Message{
+		Data: req.Data,
+	}
+
+	for k, v := range req.Metadata {
+		if k == orderingKey {
+			msg.OrderingKey = v
+
+			continue
+		}
+
+		if msg.Attributes == nil {
+			msg.Attributes = map[string]string{}
+		}
+		msg.Attributes[k] = v
+	}
+
+	return msg
+}
+
 // Subscribe to the GCP Pubsub topic
 func (g *GCPPubSub) Subscribe(req pubsub.SubscribeRequest, daprHandler func(msg *pubsub.NewMessage) error) error {
+	daprHandler, err := pubsub.FilterHandler(req, daprHandler)
+	if err != nil {
+		return err
+	}
+
 	if !g.metadata.DisableEntityManagement {
 		topicErr := g.ensureTopic(req.Topic)
 		if topicErr != nil {
@@ -111,20 +250,36 @@ func (g *GCPPubSub) Subscribe(req pubsub.SubscribeRequest, daprHandler func(msg
 func (g *GCPPubSub) handleSubscriptionMessages(topic *gcppubsub.Topic, sub *gcppubsub.Subscription, daprHandler func(msg *pubsub.NewMessage) error) error {
 	err := sub.Receive(context.Background(), func(ctx context.Context, m *gcppubsub.Message) {
 		msg := &pubsub.NewMessage{
-			Data:  m.Data,
-			Topic: topic.ID(),
+			Data:     m.Data,
+			Topic:    topic.ID(),
+			Metadata: messageMetadata(m),
 		}
 
 		err := daprHandler(msg)
 
 		if err == nil {
 			m.Ack()
+		} else {
+			m.Nack()
 		}
 	})
 
 	return err
 }
 
+// messageMetadata returns the message attributes and ordering key as metadata.
+func messageMetadata(m *gcppubsub.Message) map[string]string {
+	metadata := make(map[string]string, len(m.Attributes)+1)
+	for k, v := range m.Attributes {
+		metadata[k] = v
+	}
+	if m.OrderingKey != "" {
+		metadata[orderingKey] = m.OrderingKey
+	}
+
+	return metadata
+}
+
 func (g *GCPPubSub) ensureTopic(topic string) error {
 	entity := g.getTopic(topic)
 	exists, err := entity.Exists(context.Background())
@@ -144,8 +299,21 @@ func (g *GCPPubSub) ensureTopic(topic string) error {
 	return nil
 }
 
+// getTopic returns a cached topic handle, so that the publish goroutines of a
+// topic are shared across requests and stopped on Close.
 func (g *GCPPubSub) getTopic(topic string) *gcppubsub.Topic {
-	return g.client.Topic(topic)
+	g.topicsLock.Lock()
+	defer g.topicsLock.Unlock()
+
+	if t, ok := g.topics[topic]; ok {
+		return t
+	}
+
+	t := g.client.Topic(topic)
+	t.EnableMessageOrdering = g.metadata.EnableMessageOrdering
+	g.topics[topic] = t
+
+	return t
 }
 
 func (g *GCPPubSub) ensureSubscription(subscription string, topic string) error {
@@ -157,19 +325,69 @@ func (g *GCPPubSub) ensureSubscription(subscription string, topic string) error
 	managedSubscription := subscription + "-" + topic
 	entity := g.getSubscription(managedSubscription)
 	exists, subErr := entity.Exists(context.Background())
+	if subErr != nil {
+		return subErr
+	}
+
 	if !exists {
-		_, subErr = g.client.CreateSubscription(context.Background(), managedSubscription,
-			gcppubsub.SubscriptionConfig{Topic: g.getTopic(topic)})
+		cfg, err := g.subscriptionConfig(topic)
+		if err != nil {
+			return err
+		}
+
+		_, subErr = g.client.CreateSubscription(context.Background(), managedSubscription, cfg)
+		if status.Code(subErr) == codes.AlreadyExists {
+			return nil
+		}
 	}
 
 	return subErr
 }
 
+// subscriptionConfig builds the configuration of subscriptions created by the component.
+func (g *GCPPubSub) subscriptionConfig(topic string) (gcppubsub.SubscriptionConfig, error) {
+	cfg := gcppubsub.SubscriptionConfig{
+		Topic:                 g.getTopic(topic),
+		AckDeadline:           g.metadata.AckDeadline,
+		EnableMessageOrdering: g.metadata.EnableMessageOrdering,
+	}
+
+	if g.metadata.DeadLetterTopic != "" {
+		if err := g.ensureTopic(g.metadata.DeadLetterTopic); err != nil {
+			return cfg, err
+		}
+
+		cfg.DeadLetterPolicy = &gcppubsub.DeadLetterPolicy{
+			DeadLetterTopic:     g.getTopic(g.metadata.DeadLetterTopic).String(),
+			MaxDeliveryAttempts: g.metadata.MaxDeliveryAttempts,
+		}
+	}
+
+	if g.metadata.MinRetryBackoff > 0 || g.metadata.MaxRetryBackoff > 0 {
+		cfg.RetryPolicy = &gcppubsub.RetryPolicy{}
+		if g.metadata.MinRetryBackoff > 0 {
+			cfg.RetryPolicy.MinimumBackoff = g.metadata.MinRetryBackoff
+		}
+		if g.metadata.MaxRetryBackoff > 0 {
+			cfg.RetryPolicy.MaximumBackoff = g.metadata.MaxRetryBackoff
+		}
+	}
+
+	return cfg, nil
+}
+
 func (g *GCPPubSub) getSubscription(subscription string) *gcppubsub.Subscription {
 	return g.client.Subscription(subscription)
 }
 
 func (g *GCPPubSub) Close() error {
+	g.topicsLock.Lock()
+	for _, t := range g.topics {
+		t.Stop()
+	}
+	g.topics = map[string]*gcppubsub.Topic{}
+	g.topicsLock.Unlock()
+
 	return g.client.Close()
 }
 
@@ -1,26 +1,25 @@
 package pubsub
 
 import (
-	"encoding/json"
+	"context"
 	"testing"
+	"time"
 
+	"cloud.google.com/go/pubsub/pstest"
 	"github.com/dapr/components-contrib/pubsub"
 	"github.com/dapr/dapr/pkg/logger"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestInit(t *testing.T) {
 	m := pubsub.Metadata{}
 	m.Properties = map[string]string{
 		"auth_provider_x509_cert_url": "https://auth", "auth_uri": "https://auth", "client_x509_cert_url": "https://cert", "client_email": "test@test.com", "client_id": "id", "private_key": "****",
-		"private_key_id": "key_id", "project_id": "project1", "token_uri": "https://token", "type": "serviceaccount",
+		"private_key_id": "key_id", "project_id": "project1", "token_uri": "https://token", "type": "serviceaccount", "consumerID": "consumer",
 	}
 	ps := GCPPubSub{logger: logger.NewLogger("test")}
-	b, err := ps.parseMetadata(m)
-	assert.Nil(t, err)
-
-	var pubsubMeta metadata
-	err = json.Unmarshal(b, &pubsubMeta)
+	pubsubMeta, err := ps.parseMetadata(m)
 	assert.Nil(t, err)
 
 	assert.Equal(t, "https://auth", pubsubMeta.AuthProviderCertURL)
@@ -33,4 +32,150 @@ func TestInit(t *testing.T) {
 	assert.Equal(t, "project1", pubsubMeta.ProjectID)
 	assert.Equal(t, "https://token", pubsubMeta.TokenURI)
 	assert.Equal(t, "serviceaccount", pubsubMeta.Type)
+	assert.Equal(t, "consumer", pubsubMeta.ConsumerID)
+	assert.False(t, pubsubMeta.DisableEntityManagement)
+	assert.False(t, pubsubMeta.EnableMessageOrdering)
+}
+
+func TestParseMetadata(t *testing.T) {
+	ps := GCPPubSub{logger: logger.NewLogger("test")}
+
+	t.Run("missing consumerID", func(t *testing.T) {
+		_, err := ps.parseMetadata(pubsub.Metadata{Properties: map[string]string{"project_id": "p"}})
+		assert.Error(t, err)
+	})
+
+	t.Run("ordering, dead-letter and retry options", func(t *testing.T) {
+		m, err := ps.parseMetadata(pubsub.Metadata{Properties: map[string]string{
+			"project_id":              "p",
+			"consumerID":              "c",
+			"disableEntityManagement": "true",
+			"emulatorHost":            "localhost:8085",
+			"enableMessageOrdering":   "true",
+			"deadLetterTopic":         "dlq",
+			"ackDeadlineInSec":        "30",
+			"minRetryBackoffInSec":    "5",
+			"maxRetryBackoffInSec":    "60",
+		}})
+		require.NoError(t, err)
+		assert.True(t, m.DisableEntityManagement)
+		assert.Equal(t, "localhost:8085", m.EmulatorHost)
+		assert.True(t, m.EnableMessageOrdering)
+		assert.Equal(t, "dlq", m.DeadLetterTopic)
+		assert.Equal(t, defaultMaxDeliveryAttempts, m.MaxDeliveryAttempts)
+		assert.Equal(t, 30*time.Second, m.AckDeadline)
+		assert.Equal(t, 5*time.Second, m.MinRetryBackoff)
+		assert.Equal(t, time.Minute, m.MaxRetryBackoff)
+	})
+
+	t.Run("invalid values", func(t *testing.T) {
+		for key, val := range map[string]string{
+			"enableMessageOrdering": "maybe",
+			"maxDeliveryAttempts":   "many",
+			"ackDeadlineInSec":      "soon",
+		} {
+			_, err := ps.parseMetadata(pubsub.Metadata{Properties: map[string]string{"consumerID": "c", key: val}})
+			assert.Error(t, err, key)
+		}
+	})
+
+	t.Run("min retry backoff greater than max", func(t *testing.T) {
+		_, err := ps.parseMetadata(pubsub.Metadata{Properties: map[string]string{
+			"consumerID":           "c",
+			"minRetryBackoffInSec": "60",
+			"maxRetryBackoffInSec": "5",
+		}})
+		assert.Error(t, err)
+	})
+}
+
+func TestPublishMessage(t *testing.T) {
+	msg := publishMessage(&pubsub.PublishRequest{
+		Data:     []byte("data"),
+		Metadata: map[string]string{"orderingKey": "order-1", "source": "test"},
+	})
+
+	assert.Equal(t, []byte("data"), msg.Data)
+	assert.Equal(t, "order-1", msg.OrderingKey)
+	assert.Equal(t, map[string]string{"source": "test"}, msg.Attributes)
+}
+
+func newEmulatedPubSub(t *testing.T, props map[string]string) (*GCPPubSub, *pstest.Server) {
+	srv := pstest.NewServer()
+	t.Cleanup(func() { srv.Close() })
+
+	properties := map[string]string{
+		"project_id":   "project1",
+		"consumerID":   "consumer",
+		"emulatorHost": srv.Addr,
+	}
+	for k, v := range props {
+		properties[k] = v
+	}
+
+	ps := NewGCPPubSub(logger.NewLogger("test")).(*GCPPubSub)
+	require.NoError(t, ps.Init(pubsub.Metadata{Properties: properties}))
+	t.Cleanup(func() { ps.Close() })
+
+	return ps, srv
+}
+
+func TestEmulator(t *testing.T) {
+	t.Run("publish and subscribe with ordering key and attributes", func(t *testing.T) {
+		ps, _ := newEmulatedPubSub(t, map[string]string{"enableMessageOrdering": "true"})
+
+		received := make(chan *pubsub.NewMessage, 1)
+		err := ps.Subscribe(pubsub.SubscribeRequest{Topic: "orders"}, func(msg *pubsub.NewMessage) error {
+			received <- msg
+
+			return nil
+		})
+		require.NoError(t, err)
+
+		err = ps.Publish(&pubsub.PublishRequest{
+			Topic:    "orders",
+			Data:     []byte("hello"),
+			Metadata: map[string]string{"orderingKey": "customer-1", "source": "test"},
+		})
+		require.NoError(t, err)
+
+		select {
+		case msg := <-received:
+			assert.Equal(t, []byte("hello"), msg.Data)
+			assert.Equal(t, "orders", msg.Topic)
+			assert.Equal(t, "customer-1", msg.Metadata["orderingKey"])
+			assert.Equal(t, "test", msg.Metadata["source"])
+		case <-time.After(10 * time.Second):
+			t.Fatal("timed out waiting for message")
+		}
+	})
+
+	t.Run("subscription created with dead-letter and retry policy", func(t *testing.T) {
+		ps, _ := newEmulatedPubSub(t, map[string]string{
+			"enableMessageOrdering": "true",
+			"deadLetterTopic":       "orders-dlq",
+			"maxDeliveryAttempts":   "10",
+			"ackDeadlineInSec":      "30",
+			"minRetryBackoffInSec":  "5",
+			"maxRetryBackoffInSec":  "60",
+		})
+
+		err := ps.ensureSubscription("consumer", "orders")
+		require.NoError(t, err)
+
+		cfg, err := ps.client.Subscription("consumer-orders").Config(context.Background())
+		require.NoError(t, err)
+		assert.True(t, cfg.EnableMessageOrdering)
+		assert.Equal(t, 30*time.Second, cfg.AckDeadline)
+		require.NotNil(t, cfg.DeadLetterPolicy)
+		assert.Equal(t, "projects/project1/topics/orders-dlq", cfg.DeadLetterPolicy.DeadLetterTopic)
+		assert.Equal(t, 10, cfg.DeadLetterPolicy.MaxDeliveryAttempts)
+		require.NotNil(t, cfg.RetryPolicy)
+		assert.Equal(t, 5*time.Second, cfg.RetryPolicy.MinimumBackoff)
+		assert.Equal(t, time.Minute, cfg.RetryPolicy.MaximumBackoff)
+
+		exists, err := ps.client.Topic("orders-dlq").Exists(context.Background())
+		require.NoError(t, err)
+		assert.True(t, exists)
+	})
 }
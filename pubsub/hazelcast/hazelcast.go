@@ -95,6 +95,11 @@ func (p *Hazelcast) Publish(req *pubsub.PublishRequest) error {
 }
 
 func (p *Hazelcast) Subscribe(req pubsub.SubscribeRequest, handler func(msg *pubsub.NewMessage) error) error {
+	handler, err := pubsub.FilterHandler(req, handler)
+	if err != nil {
+		return err
+	}
+
 	topic, err := p.client.GetTopic(req.Topic)
 	if err != nil {
 		return fmt.Errorf("hazelcast error: failed to get topic for %s", req.Topic)
@@ -0,0 +1,250 @@
+// ------------------------------------------------------------
+// Copyright (c) Microsoft Corporation and Dapr Contributors.
+// Licensed under the MIT License.
+// ------------------------------------------------------------
+
+package idempotency
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+
+	contrib_metadata "github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/components-contrib/pubsub"
+	"github.com/dapr/components-contrib/state"
+	"github.com/dapr/dapr/pkg/logger"
+)
+
+// record is kept in the state store for each message being handled or handled.
+type record struct {
+	// ExpiresAt ends the claim or the dedup window of the message, for stores that do not honor ttlInSeconds
+	ExpiresAt time.Time `json:"expiresAt"`
+	// Handled is false while a subscriber handles the message
+	Handled bool `json:"handled"`
+}
+
+// idempotency decorates a pubsub component so that each message is handled
+// once: published messages get a message id, the duplicate detection of the
+// broker is enabled where available, and subscribers skip the messages whose
+// id was already handled within the dedup window.
+//
+// Subscribers claim a message with a first-write insert of its record before
+// handling it, so the store must support ETags and fail a first-write Set
+// without an ETag when the key exists, like the redis, postgresql and mysql
+// state stores. Claims are written with strong consistency, so Redis Cluster
+// can't be used.
+type idempotency struct {
+	pubsub.PubSub
+
+	store    state.Store
+	metadata metadata
+	logger   logger.Logger
+}
+
+// New returns a pubsub component that deduplicates the messages of the given
+// component with records kept in store.
+func New(inner pubsub.PubSub, store state.Store, logger logger.Logger) pubsub.PubSub {
+	return &idempotency{
+		PubSub: inner,
+		store:  store,
+		logger: logger,
+	}
+}
+
+func (i *idempotency) Init(metadata pubsub.Metadata) error {
+	if !state.FeatureETag.IsPresent(i.store.Features()) {
+		return errors.New("idempotency error: the state store must support ETags")
+	}
+
+	m, err := parseMetadata(metadata)
+	if err != nil {
+		return err
+	}
+	i.metadata = m
+
+	properties := make(map[string]string, len(metadata.Properties)+1)
+	for k, v := range metadata.Properties {
+		properties[k] = v
+	}
+	if _, ok := properties[pubsub.IdempotenceMetadataKey]; !ok && m.brokerNative {
+		properties[pubsub.IdempotenceMetadataKey] = "true"
+	}
+
+	return i.PubSub.Init(pubsub.Metadata{Properties: properties})
+}
+
+func (i *idempotency) Publish(req *pubsub.PublishRequest) error {
+	id, ok := i.messageID(req.Topic, req.Data, req.Metadata)
+	if !ok {
+		// Messages with the same content are distinct unless the publisher says otherwise
+		id = uuid.New().String()
+	}
+
+	metadata := make(map[string]string, len(req.Metadata)+1)
+	for k, v := range req.Metadata {
+		metadata[k] = v
+	}
+	metadata[pubsub.MessageIDMetadataKey] = id
+
+	return i.PubSub.Publish(&pubsub.PublishRequest{
+		Data:       req.Data,
+		PubsubName: req.PubsubName,
+		Topic:      req.Topic,
+		Metadata:   metadata,
+	})
+}
+
+func (i *idempotency) Subscribe(req pubsub.SubscribeRequest, handler func(msg *pubsub.NewMessage) error) error {
+	return i.PubSub.Subscribe(req, func(msg *pubsub.NewMessage) error {
+		id, ok := i.messageID(msg.Topic, msg.Data, msg.Metadata)
+		if !ok {
+			i.logger.Debugf("idempotency: handling message without id on topic %s without deduplication", msg.Topic)
+
+			return handler(msg)
+		}
+		key := i.key(msg.Topic, id)
+
+		claimed, handled, err := i.claim(key, time.Now())
+		if err != nil {
+			return fmt.Errorf("idempotency error: failed to claim message %s: %s", id, err)
+		}
+		if handled {
+			i.logger.Debugf("idempotency: skipping duplicate message %s on topic %s", id, msg.Topic)
+
+			return nil
+		}
+		// A duplicate delivered while the original is being handled is
+		// rejected, so that it is redelivered if the original fails.
+		if !claimed {
+			return fmt.Errorf("idempotency error: message %s is already being handled", id)
+		}
+
+		metadata := make(map[string]string, len(msg.Metadata)+1)
+		for k, v := range msg.Metadata {
+			metadata[k] = v
+		}
+		metadata[pubsub.MessageIDMetadataKey] = id
+
+		err = handler(&pubsub.NewMessage{
+			Data:     msg.Data,
+			Topic:    msg.Topic,
+			Metadata: metadata,
+		})
+		if err != nil {
+			if delErr := i.store.Delete(&state.DeleteRequest{Key: key}); delErr != nil {
+				i.logger.Warnf("idempotency: failed to release message %s: %s", id, delErr)
+			}
+
+			return err
+		}
+
+		// The message was handled, so it is acked even if it can't be remembered
+		if err := i.set(key, record{ExpiresAt: time.Now().Add(i.metadata.window), Handled: true}, i.metadata.window, state.LastWrite, nil); err != nil {
+			i.logger.Warnf("idempotency: failed to record handled message %s: %s", id, err)
+		}
+
+		return nil
+	})
+}
+
+// claim records that the message with key is being handled, unless it was
+// handled within the dedup window or is being handled. The claim is a
+// first-write insert, or a replacement of an expired record with its ETag, so
+// a single subscriber across all replicas claims the message. It expires after
+// the processing timeout, so that a message whose subscriber crashed is
+// handled again when it is redelivered.
+func (i *idempotency) claim(key string, now time.Time) (claimed bool, handled bool, err error) {
+	res, err := i.store.Get(&state.GetRequest{Key: key, Options: state.GetStateOption{Consistency: state.Strong}})
+	if err != nil {
+		return false, false, err
+	}
+
+	var etag *string
+	if res != nil && len(res.Data) > 0 {
+		etag = res.ETag
+
+		var rec record
+		if err := json.Unmarshal(res.Data, &rec); err != nil {
+			i.logger.Warnf("idempotency: replacing invalid record %s: %s", key, err)
+		} else if now.Before(rec.ExpiresAt) {
+			return false, rec.Handled, nil
+		}
+	}
+
+	err = i.set(key, record{ExpiresAt: now.Add(i.metadata.processingTimeout)}, i.metadata.processingTimeout, state.FirstWrite, etag)
+	if err != nil {
+		var etagErr *state.ETagError
+		if errors.As(err, &etagErr) && etagErr.Kind() == state.ETagMismatch {
+			return false, false, nil
+		}
+
+		return false, false, err
+	}
+
+	return true, false, nil
+}
+
+func (i *idempotency) set(key string, rec record, ttl time.Duration, concurrency string, etag *string) error {
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+
+	return i.store.Set(&state.SetRequest{
+		Key:   key,
+		Value: b,
+		ETag:  etag,
+		Metadata: map[string]string{
+			contrib_metadata.TTLMetadataKey: strconv.FormatInt(int64(ttl/time.Second), 10),
+		},
+		Options: state.SetStateOption{
+			Concurrency: concurrency,
+			Consistency: state.Strong,
+		},
+	})
+}
+
+// key scopes the record of a message to the consumer group and the topic.
+func (i *idempotency) key(topic, id string) string {
+	if i.metadata.consumerID == "" {
+		return fmt.Sprintf("%s/%s", topic, id)
+	}
+
+	return fmt.Sprintf("%s/%s/%s", i.metadata.consumerID, topic, id)
+}
+
+// messageID returns the stable id of a message: the CloudEvent id when the
+// message is a CloudEvent, the id in the metadata, and a hash of the topic and
+// the message when content hashing is enabled. The CloudEvent id comes first
+// as it is kept when a message is published again, while brokers such as
+// Service Bus assign a new id to every send. It returns false when the message
+// has no stable id.
+func (i *idempotency) messageID(topic string, data []byte, metadata map[string]string) (string, bool) {
+	var event struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(data, &event); err == nil && event.ID != "" {
+		return event.ID, true
+	}
+
+	if id := metadata[pubsub.MessageIDMetadataKey]; id != "" {
+		return id, true
+	}
+
+	if !i.metadata.contentHash {
+		return "", false
+	}
+
+	h := sha256.New()
+	h.Write([]byte(topic))
+	h.Write([]byte{0})
+	h.Write(data)
+
+	return fmt.Sprintf("%x", h.Sum(nil)), true
+}
@@ -0,0 +1,401 @@
+// ------------------------------------------------------------
+// Copyright (c) Microsoft Corporation and Dapr Contributors.
+// Licensed under the MIT License.
+// ------------------------------------------------------------
+
+package idempotency
+
+import (
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/components-contrib/internal/component/statestore"
+	contrib_metadata "github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/components-contrib/pubsub"
+	"github.com/dapr/components-contrib/state"
+	"github.com/dapr/dapr/pkg/logger"
+)
+
+type fakePubSub struct {
+	metadata  pubsub.Metadata
+	handlers  map[string]func(msg *pubsub.NewMessage) error
+	published []*pubsub.PublishRequest
+}
+
+func newFakePubSub() *fakePubSub {
+	return &fakePubSub{handlers: map[string]func(msg *pubsub.NewMessage) error{}}
+}
+
+func (f *fakePubSub) Init(metadata pubsub.Metadata) error {
+	f.metadata = metadata
+
+	return nil
+}
+
+func (f *fakePubSub) Features() []pubsub.Feature { return nil }
+func (f *fakePubSub) Close() error               { return nil }
+
+func (f *fakePubSub) Publish(req *pubsub.PublishRequest) error {
+	f.published = append(f.published, req)
+
+	return nil
+}
+
+// deliver hands the last published message to the subscriber of topic.
+func (f *fakePubSub) deliver(topic string) error {
+	req := f.published[len(f.published)-1]
+
+	return f.handlers[topic](&pubsub.NewMessage{Topic: topic, Data: req.Data, Metadata: req.Metadata})
+}
+
+func (f *fakePubSub) Subscribe(req pubsub.SubscribeRequest, handler func(msg *pubsub.NewMessage) error) error {
+	f.handlers[req.Topic] = handler
+
+	return nil
+}
+
+// recordingStore records the metadata of the records set in the store.
+type recordingStore struct {
+	state.Store
+	metadata map[string]map[string]string
+	lock     sync.Mutex
+}
+
+func newRecordingStore() *recordingStore {
+	return &recordingStore{Store: statestore.NewMemoryStore(), metadata: map[string]map[string]string{}}
+}
+
+func (r *recordingStore) Set(req *state.SetRequest) error {
+	r.lock.Lock()
+	r.metadata[req.Key] = req.Metadata
+	r.lock.Unlock()
+
+	return r.Store.Set(req)
+}
+
+func TestParseMetadata(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		m, err := parseMetadata(pubsub.Metadata{Properties: map[string]string{}})
+		require.NoError(t, err)
+		assert.Equal(t, defaultWindow, m.window)
+		assert.Equal(t, defaultProcessingTimeout, m.processingTimeout)
+		assert.True(t, m.brokerNative)
+		assert.False(t, m.contentHash)
+	})
+
+	t.Run("all fields", func(t *testing.T) {
+		m, err := parseMetadata(pubsub.Metadata{Properties: map[string]string{
+			windowInSecondsKey:            "60",
+			processingTimeoutInSecondsKey: "30",
+			brokerNativeKey:               "false",
+			consumerIDKey:                 "orders",
+			contentHashKey:                "true",
+		}})
+		require.NoError(t, err)
+		assert.Equal(t, time.Minute, m.window)
+		assert.Equal(t, 30*time.Second, m.processingTimeout)
+		assert.False(t, m.brokerNative)
+		assert.Equal(t, "orders", m.consumerID)
+		assert.True(t, m.contentHash)
+	})
+
+	t.Run("invalid window", func(t *testing.T) {
+		_, err := parseMetadata(pubsub.Metadata{Properties: map[string]string{windowInSecondsKey: "0"}})
+		assert.Error(t, err)
+	})
+
+	t.Run("processing timeout is capped by a short window", func(t *testing.T) {
+		m, err := parseMetadata(pubsub.Metadata{Properties: map[string]string{windowInSecondsKey: "10"}})
+		require.NoError(t, err)
+		assert.Equal(t, 10*time.Second, m.processingTimeout)
+	})
+
+	t.Run("invalid processing timeout", func(t *testing.T) {
+		_, err := parseMetadata(pubsub.Metadata{Properties: map[string]string{processingTimeoutInSecondsKey: "0"}})
+		assert.Error(t, err)
+		_, err = parseMetadata(pubsub.Metadata{Properties: map[string]string{
+			windowInSecondsKey:            "60",
+			processingTimeoutInSecondsKey: "120",
+		}})
+		assert.Error(t, err)
+	})
+}
+
+func TestInit(t *testing.T) {
+	l := logger.NewLogger("idempotency-test")
+
+	t.Run("enables broker-native idempotence", func(t *testing.T) {
+		inner := newFakePubSub()
+		require.NoError(t, New(inner, newRecordingStore(), l).Init(pubsub.Metadata{Properties: map[string]string{}}))
+		assert.Equal(t, "true", inner.metadata.Properties[pubsub.IdempotenceMetadataKey])
+	})
+
+	t.Run("keeps explicit setting", func(t *testing.T) {
+		inner := newFakePubSub()
+		require.NoError(t, New(inner, newRecordingStore(), l).Init(pubsub.Metadata{Properties: map[string]string{
+			pubsub.IdempotenceMetadataKey: "false",
+		}}))
+		assert.Equal(t, "false", inner.metadata.Properties[pubsub.IdempotenceMetadataKey])
+	})
+
+	t.Run("broker-native disabled", func(t *testing.T) {
+		inner := newFakePubSub()
+		require.NoError(t, New(inner, newRecordingStore(), l).Init(pubsub.Metadata{Properties: map[string]string{
+			brokerNativeKey: "false",
+		}}))
+		assert.NotContains(t, inner.metadata.Properties, pubsub.IdempotenceMetadataKey)
+	})
+
+	t.Run("store without etags", func(t *testing.T) {
+		store := noETagStore{statestore.NewMemoryStore()}
+		assert.Error(t, New(newFakePubSub(), store, l).Init(pubsub.Metadata{Properties: map[string]string{}}))
+	})
+}
+
+type noETagStore struct {
+	state.Store
+}
+
+func (s noETagStore) Features() []state.Feature {
+	return nil
+}
+
+func TestIdempotency(t *testing.T) {
+	l := logger.NewLogger("idempotency-test")
+	meta := pubsub.Metadata{Properties: map[string]string{
+		consumerIDKey:      "orders",
+		windowInSecondsKey: "60",
+	}}
+
+	t.Run("publish assigns a message id", func(t *testing.T) {
+		inner := newFakePubSub()
+		p := New(inner, newRecordingStore(), l)
+		require.NoError(t, p.Init(meta))
+
+		req := &pubsub.PublishRequest{Topic: "t", Data: []byte("payload"), Metadata: map[string]string{"k": "v"}}
+		require.NoError(t, p.Publish(req))
+		require.NoError(t, p.Publish(req))
+		require.NoError(t, p.Publish(&pubsub.PublishRequest{Topic: "t", Data: []byte("payload"), Metadata: map[string]string{
+			pubsub.MessageIDMetadataKey: "msg-1",
+		}}))
+
+		require.Len(t, inner.published, 3)
+		id := inner.published[0].Metadata[pubsub.MessageIDMetadataKey]
+		assert.NotEmpty(t, id)
+		// Messages with the same content are distinct
+		assert.NotEqual(t, id, inner.published[1].Metadata[pubsub.MessageIDMetadataKey])
+		assert.Equal(t, "msg-1", inner.published[2].Metadata[pubsub.MessageIDMetadataKey])
+		assert.Equal(t, "v", inner.published[0].Metadata["k"])
+		assert.NotContains(t, req.Metadata, pubsub.MessageIDMetadataKey)
+	})
+
+	t.Run("duplicates are acked without calling the handler", func(t *testing.T) {
+		inner := newFakePubSub()
+		store := newRecordingStore()
+		p := New(inner, store, l)
+		require.NoError(t, p.Init(meta))
+
+		var received []*pubsub.NewMessage
+		require.NoError(t, p.Subscribe(pubsub.SubscribeRequest{Topic: "t"}, func(msg *pubsub.NewMessage) error {
+			received = append(received, msg)
+
+			return nil
+		}))
+		require.NoError(t, p.Publish(&pubsub.PublishRequest{Topic: "t", Data: []byte("payload")}))
+		require.NoError(t, inner.deliver("t"))
+		require.NoError(t, inner.deliver("t"))
+
+		require.Len(t, received, 1)
+		id := received[0].Metadata[pubsub.MessageIDMetadataKey]
+		assert.Equal(t, inner.published[0].Metadata[pubsub.MessageIDMetadataKey], id)
+		assert.Equal(t, "60", store.metadata["orders/t/"+id][contrib_metadata.TTLMetadataKey])
+	})
+
+	t.Run("failed messages are handled again", func(t *testing.T) {
+		inner := newFakePubSub()
+		p := New(inner, newRecordingStore(), l)
+		require.NoError(t, p.Init(meta))
+
+		calls := 0
+		require.NoError(t, p.Subscribe(pubsub.SubscribeRequest{Topic: "t"}, func(msg *pubsub.NewMessage) error {
+			calls++
+			if calls == 1 {
+				return errors.New("handler failed")
+			}
+
+			return nil
+		}))
+		require.NoError(t, p.Publish(&pubsub.PublishRequest{Topic: "t", Data: []byte("payload")}))
+		assert.Error(t, inner.deliver("t"))
+		assert.NoError(t, inner.deliver("t"))
+		assert.NoError(t, inner.deliver("t"))
+		assert.Equal(t, 2, calls)
+	})
+
+	t.Run("expired records are ignored", func(t *testing.T) {
+		inner := newFakePubSub()
+		store := newRecordingStore()
+		p := New(inner, store, l)
+		require.NoError(t, p.Init(meta))
+
+		calls := 0
+		require.NoError(t, p.Subscribe(pubsub.SubscribeRequest{Topic: "t"}, func(msg *pubsub.NewMessage) error {
+			calls++
+
+			return nil
+		}))
+		require.NoError(t, p.Publish(&pubsub.PublishRequest{Topic: "t", Data: []byte("payload")}))
+
+		b, err := json.Marshal(record{ExpiresAt: time.Now().Add(-time.Second), Handled: true})
+		require.NoError(t, err)
+		require.NoError(t, store.Set(&state.SetRequest{
+			Key:   "orders/t/" + inner.published[0].Metadata[pubsub.MessageIDMetadataKey],
+			Value: b,
+		}))
+
+		require.NoError(t, inner.deliver("t"))
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("claims of crashed subscribers expire after the processing timeout", func(t *testing.T) {
+		inner := newFakePubSub()
+		store := newRecordingStore()
+		p := New(inner, store, l)
+		require.NoError(t, p.Init(pubsub.Metadata{Properties: map[string]string{
+			consumerIDKey:                 "orders",
+			windowInSecondsKey:            "60",
+			processingTimeoutInSecondsKey: "5",
+		}}))
+
+		calls := 0
+		require.NoError(t, p.Subscribe(pubsub.SubscribeRequest{Topic: "t"}, func(msg *pubsub.NewMessage) error {
+			calls++
+
+			return nil
+		}))
+		require.NoError(t, p.Publish(&pubsub.PublishRequest{Topic: "t", Data: []byte("payload")}))
+		key := "orders/t/" + inner.published[0].Metadata[pubsub.MessageIDMetadataKey]
+
+		// A subscriber crashed while handling the message
+		claimed, _, err := p.(*idempotency).claim(key, time.Now().Add(-6*time.Second))
+		require.NoError(t, err)
+		require.True(t, claimed)
+		assert.Equal(t, "5", store.metadata[key][contrib_metadata.TTLMetadataKey])
+
+		require.NoError(t, inner.deliver("t"))
+		assert.Equal(t, 1, calls)
+		assert.Equal(t, "60", store.metadata[key][contrib_metadata.TTLMetadataKey])
+	})
+
+	t.Run("concurrent duplicates are rejected", func(t *testing.T) {
+		inner := newFakePubSub()
+		p := New(inner, newRecordingStore(), l)
+		require.NoError(t, p.Init(meta))
+
+		var duplicateErr error
+		require.NoError(t, p.Subscribe(pubsub.SubscribeRequest{Topic: "t"}, func(msg *pubsub.NewMessage) error {
+			if duplicateErr == nil {
+				duplicateErr = inner.deliver("t")
+			}
+
+			return nil
+		}))
+		require.NoError(t, p.Publish(&pubsub.PublishRequest{Topic: "t", Data: []byte("payload")}))
+		require.NoError(t, inner.deliver("t"))
+		assert.Error(t, duplicateErr)
+	})
+}
+
+func TestIdempotencyAcrossReplicas(t *testing.T) {
+	l := logger.NewLogger("idempotency-test")
+	meta := pubsub.Metadata{Properties: map[string]string{consumerIDKey: "orders"}}
+	store := newRecordingStore()
+	inner1, inner2 := newFakePubSub(), newFakePubSub()
+	p1, p2 := New(inner1, store, l), New(inner2, store, l)
+	require.NoError(t, p1.Init(meta))
+	require.NoError(t, p2.Init(meta))
+
+	msg := &pubsub.NewMessage{Topic: "t", Data: []byte("payload"), Metadata: map[string]string{pubsub.MessageIDMetadataKey: "msg-1"}}
+	calls := 0
+	var duplicateErr error
+	require.NoError(t, p1.Subscribe(pubsub.SubscribeRequest{Topic: "t"}, func(*pubsub.NewMessage) error {
+		calls++
+		// The other replica receives the message while this one handles it
+		duplicateErr = inner2.handlers["t"](msg)
+
+		return nil
+	}))
+	require.NoError(t, p2.Subscribe(pubsub.SubscribeRequest{Topic: "t"}, func(*pubsub.NewMessage) error {
+		calls++
+
+		return nil
+	}))
+
+	require.NoError(t, inner1.handlers["t"](msg))
+	assert.Error(t, duplicateErr)
+	require.NoError(t, inner2.handlers["t"](msg))
+	assert.Equal(t, 1, calls)
+}
+
+func TestIdempotencyWithoutMessageID(t *testing.T) {
+	l := logger.NewLogger("idempotency-test")
+	msg := &pubsub.NewMessage{Topic: "t", Data: []byte("payload")}
+
+	t.Run("messages are not deduplicated", func(t *testing.T) {
+		inner := newFakePubSub()
+		p := New(inner, newRecordingStore(), l)
+		require.NoError(t, p.Init(pubsub.Metadata{Properties: map[string]string{}}))
+
+		calls := 0
+		require.NoError(t, p.Subscribe(pubsub.SubscribeRequest{Topic: "t"}, func(*pubsub.NewMessage) error {
+			calls++
+
+			return nil
+		}))
+		require.NoError(t, inner.handlers["t"](msg))
+		require.NoError(t, inner.handlers["t"](msg))
+		assert.Equal(t, 2, calls)
+	})
+
+	t.Run("messages are deduplicated by content hash", func(t *testing.T) {
+		inner := newFakePubSub()
+		p := New(inner, newRecordingStore(), l)
+		require.NoError(t, p.Init(pubsub.Metadata{Properties: map[string]string{contentHashKey: "true"}}))
+
+		calls := 0
+		require.NoError(t, p.Subscribe(pubsub.SubscribeRequest{Topic: "t"}, func(*pubsub.NewMessage) error {
+			calls++
+
+			return nil
+		}))
+		require.NoError(t, inner.handlers["t"](msg))
+		require.NoError(t, inner.handlers["t"](msg))
+		assert.Equal(t, 1, calls)
+	})
+}
+
+func TestMessageID(t *testing.T) {
+	i := &idempotency{}
+	// The CloudEvent id is kept when a message is published again, unlike broker ids
+	id, ok := i.messageID("t", []byte(`{"id":"event-1"}`), map[string]string{pubsub.MessageIDMetadataKey: "msg-1"})
+	assert.True(t, ok)
+	assert.Equal(t, "event-1", id)
+	id, ok = i.messageID("t", []byte("raw"), map[string]string{pubsub.MessageIDMetadataKey: "msg-1"})
+	assert.True(t, ok)
+	assert.Equal(t, "msg-1", id)
+	_, ok = i.messageID("t", []byte("raw"), nil)
+	assert.False(t, ok)
+
+	i.metadata.contentHash = true
+	id, ok = i.messageID("t", []byte("raw"), nil)
+	assert.True(t, ok)
+	assert.Len(t, id, 64)
+	other, _ := i.messageID("u", []byte("raw"), nil)
+	assert.NotEqual(t, id, other)
+}
@@ -0,0 +1,89 @@
+// ------------------------------------------------------------
+// Copyright (c) Microsoft Corporation and Dapr Contributors.
+// Licensed under the MIT License.
+// ------------------------------------------------------------
+
+package idempotency
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/dapr/components-contrib/pubsub"
+)
+
+const (
+	windowInSecondsKey            = "idempotencyWindowInSeconds"
+	processingTimeoutInSecondsKey = "idempotencyProcessingTimeoutInSeconds"
+	brokerNativeKey               = "idempotencyBrokerNative"
+	contentHashKey                = "idempotencyContentHash"
+	consumerIDKey                 = "consumerID"
+
+	defaultWindow            = 10 * time.Minute
+	defaultProcessingTimeout = time.Minute
+)
+
+type metadata struct {
+	// How long the ids of handled messages are remembered to detect duplicates
+	window time.Duration
+	// How long a message is claimed while it is handled, after which it can be
+	// handled again if the subscriber crashed before finishing it
+	processingTimeout time.Duration
+	// Enables the duplicate detection of the decorated component when it supports it
+	brokerNative bool
+	// Scopes the dedup records so that each consumer group handles a message once
+	consumerID string
+	// Identifies the messages without a message id or CloudEvent id by a hash of
+	// their content, so that messages with the same content are duplicates
+	contentHash bool
+}
+
+func parseMetadata(meta pubsub.Metadata) (metadata, error) {
+	m := metadata{
+		window:       defaultWindow,
+		brokerNative: true,
+		consumerID:   meta.Properties[consumerIDKey],
+	}
+
+	if val, ok := meta.Properties[windowInSecondsKey]; ok && val != "" {
+		window, err := strconv.ParseUint(val, 10, 32)
+		if err != nil || window == 0 {
+			return m, fmt.Errorf("idempotency error: %s must be a positive integer: actual is '%s'", windowInSecondsKey, val)
+		}
+		m.window = time.Duration(window) * time.Second
+	}
+
+	m.processingTimeout = defaultProcessingTimeout
+	if m.processingTimeout > m.window {
+		m.processingTimeout = m.window
+	}
+	if val, ok := meta.Properties[processingTimeoutInSecondsKey]; ok && val != "" {
+		timeout, err := strconv.ParseUint(val, 10, 32)
+		if err != nil || timeout == 0 {
+			return m, fmt.Errorf("idempotency error: %s must be a positive integer: actual is '%s'", processingTimeoutInSecondsKey, val)
+		}
+		m.processingTimeout = time.Duration(timeout) * time.Second
+		if m.processingTimeout > m.window {
+			return m, fmt.Errorf("idempotency error: %s can't be longer than %s", processingTimeoutInSecondsKey, windowInSecondsKey)
+		}
+	}
+
+	if val, ok := meta.Properties[brokerNativeKey]; ok && val != "" {
+		brokerNative, err := strconv.ParseBool(val)
+		if err != nil {
+			return m, fmt.Errorf("idempotency error: can't parse %s field: %s", brokerNativeKey, err)
+		}
+		m.brokerNative = brokerNative
+	}
+
+	if val, ok := meta.Properties[contentHashKey]; ok && val != "" {
+		contentHash, err := strconv.ParseBool(val)
+		if err != nil {
+			return m, fmt.Errorf("idempotency error: can't parse %s field: %s", contentHashKey, err)
+		}
+		m.contentHash = contentHash
+	}
+
+	return m, nil
+}
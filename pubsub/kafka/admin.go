@@ -0,0 +1,209 @@
+// ------------------------------------------------------------
+// Copyright (c) Microsoft Corporation and Dapr Contributors.
+// Licensed under the MIT License.
+// ------------------------------------------------------------
+
+package kafka
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Shopify/sarama"
+	"github.com/dapr/components-contrib/pubsub"
+)
+
+const (
+	// replicationFactorKey is the create topic metadata setting the number of replicas of each partition
+	replicationFactorKey = "replicationFactor"
+	// internalTopicPrefix is the prefix of the topics used by Kafka itself, such as __consumer_offsets
+	internalTopicPrefix = "__"
+	// brokerDefault asks the broker to use its default number of partitions or replication factor
+	brokerDefault = -1
+)
+
+// CreateTopic creates a topic with the requested partitions and retention.
+func (k *Kafka) CreateTopic(req pubsub.CreateTopicRequest) error {
+	detail, err := topicDetail(req)
+	if err != nil {
+		return err
+	}
+
+	admin, err := sarama.NewClusterAdmin(k.brokers, k.config)
+	if err != nil {
+		return err
+	}
+	defer admin.Close()
+
+	return admin.CreateTopic(req.Topic, detail, false)
+}
+
+// topicDetail returns the settings of a topic to create. The partitions and the
+// replication factor that aren't requested are left to the broker defaults,
+// num.partitions and default.replication.factor, which requires Kafka 2.4.
+func topicDetail(req pubsub.CreateTopicRequest) (*sarama.TopicDetail, error) {
+	detail := &sarama.TopicDetail{
+		NumPartitions:     brokerDefault,
+		ReplicationFactor: brokerDefault,
+	}
+	if req.Partitions > 0 {
+		detail.NumPartitions = int32(req.Partitions)
+	}
+	if val, ok := req.Metadata[replicationFactorKey]; ok && val != "" {
+		replicationFactor, err := strconv.ParseInt(val, 10, 16)
+		if err != nil || replicationFactor <= 0 {
+			return nil, fmt.Errorf("kafka error: %s must be a positive integer: actual is '%s'", replicationFactorKey, val)
+		}
+		detail.ReplicationFactor = int16(replicationFactor)
+	}
+	if req.RetentionInSeconds > 0 {
+		retention := strconv.FormatInt(req.RetentionInSeconds*1000, 10)
+		detail.ConfigEntries = map[string]*string{"retention.ms": &retention}
+	}
+
+	return detail, nil
+}
+
+// DeleteTopic deletes a topic and its messages.
+func (k *Kafka) DeleteTopic(req pubsub.DeleteTopicRequest) error {
+	admin, err := sarama.NewClusterAdmin(k.brokers, k.config)
+	if err != nil {
+		return err
+	}
+	defer admin.Close()
+
+	return admin.DeleteTopic(req.Topic)
+}
+
+// ListTopics returns the topics of the cluster, except the internal ones.
+func (k *Kafka) ListTopics() ([]string, error) {
+	client, err := sarama.NewClient(k.brokers, k.config)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	all, err := client.Topics()
+	if err != nil {
+		return nil, err
+	}
+
+	topics := make([]string, 0, len(all))
+	for _, topic := range all {
+		if !strings.HasPrefix(topic, internalTopicPrefix) {
+			topics = append(topics, topic)
+		}
+	}
+	sort.Strings(topics)
+
+	return topics, nil
+}
+
+// ListSubscriptions returns the consumer groups that committed offsets for a topic.
+func (k *Kafka) ListSubscriptions(topic string) ([]string, error) {
+	client, admin, err := k.adminClients()
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+	defer admin.Close()
+
+	partitions, err := client.Partitions(topic)
+	if err != nil {
+		return nil, err
+	}
+
+	groups, err := admin.ListConsumerGroups()
+	if err != nil {
+		return nil, err
+	}
+
+	subscriptions := make([]string, 0, len(groups))
+	for group := range groups {
+		offsets, err := admin.ListConsumerGroupOffsets(group, map[string][]int32{topic: partitions})
+		if err != nil {
+			return nil, err
+		}
+		for _, partition := range partitions {
+			if block := offsets.GetBlock(topic, partition); block != nil && block.Offset >= 0 {
+				subscriptions = append(subscriptions, group)
+
+				break
+			}
+		}
+	}
+	sort.Strings(subscriptions)
+
+	return subscriptions, nil
+}
+
+// GetSubscriptionLag returns the number of messages of a topic that a
+// consumer group has not committed yet, summed over all partitions.
+func (k *Kafka) GetSubscriptionLag(req pubsub.SubscriptionLagRequest) (*pubsub.SubscriptionLagResponse, error) {
+	client, admin, err := k.adminClients()
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+	defer admin.Close()
+
+	partitions, err := client.Partitions(req.Topic)
+	if err != nil {
+		return nil, err
+	}
+
+	offsets, err := admin.ListConsumerGroupOffsets(req.Subscription, map[string][]int32{req.Topic: partitions})
+	if err != nil {
+		return nil, err
+	}
+
+	var lag int64
+	for _, partition := range partitions {
+		newest, err := client.GetOffset(req.Topic, partition, sarama.OffsetNewest)
+		if err != nil {
+			return nil, err
+		}
+
+		// A partition without a committed offset is consumed from the initial
+		// offset of the consumer, its newest message by default
+		committed := int64(-1)
+		if block := offsets.GetBlock(req.Topic, partition); block != nil {
+			committed = block.Offset
+		}
+		if committed < 0 {
+			if committed, err = client.GetOffset(req.Topic, partition, k.config.Consumer.Offsets.Initial); err != nil {
+				return nil, err
+			}
+		}
+
+		if newest > committed {
+			lag += newest - committed
+		}
+	}
+
+	return &pubsub.SubscriptionLagResponse{
+		Topic:        req.Topic,
+		Subscription: req.Subscription,
+		Lag:          lag,
+	}, nil
+}
+
+// adminClients returns a client to read the partition offsets and a cluster
+// admin to read the consumer group offsets. Both must be closed by the caller.
+func (k *Kafka) adminClients() (sarama.Client, sarama.ClusterAdmin, error) {
+	client, err := sarama.NewClient(k.brokers, k.config)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	admin, err := sarama.NewClusterAdmin(k.brokers, k.config)
+	if err != nil {
+		client.Close()
+
+		return nil, nil, err
+	}
+
+	return client, admin, nil
+}
@@ -0,0 +1,137 @@
+// ------------------------------------------------------------
+// Copyright (c) Microsoft Corporation and Dapr Contributors.
+// Licensed under the MIT License.
+// ------------------------------------------------------------
+
+package kafka
+
+import (
+	"testing"
+
+	"github.com/Shopify/sarama"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/components-contrib/pubsub"
+)
+
+func newAdminTestKafka(t *testing.T, handlers map[string]sarama.MockResponse) *Kafka {
+	broker := sarama.NewMockBroker(t, 1)
+	t.Cleanup(broker.Close)
+
+	metadata := sarama.NewMockMetadataResponse(t).
+		SetController(broker.BrokerID()).
+		SetBroker(broker.Addr(), broker.BrokerID()).
+		SetLeader("orders", 0, broker.BrokerID()).
+		SetLeader("orders", 1, broker.BrokerID()).
+		SetLeader("__consumer_offsets", 0, broker.BrokerID())
+	all := map[string]sarama.MockResponse{
+		"MetadataRequest": metadata,
+		"FindCoordinatorRequest": sarama.NewMockFindCoordinatorResponse(t).
+			SetCoordinator(sarama.CoordinatorGroup, "billing", broker).
+			SetCoordinator(sarama.CoordinatorGroup, "audit", broker),
+	}
+	for k, v := range handlers {
+		all[k] = v
+	}
+	broker.SetHandlerByMap(all)
+
+	// The mock responses of the admin requests use the versions of Kafka 0.10.2
+	config := sarama.NewConfig()
+	config.Version = sarama.V0_10_2_0
+
+	return &Kafka{brokers: []string{broker.Addr()}, config: config}
+}
+
+func TestTopicAdmin(t *testing.T) {
+	var _ pubsub.TopicAdmin = &Kafka{}
+
+	t.Run("create topic", func(t *testing.T) {
+		k := newAdminTestKafka(t, map[string]sarama.MockResponse{
+			"CreateTopicsRequest": sarama.NewMockCreateTopicsResponse(t),
+		})
+		err := k.CreateTopic(pubsub.CreateTopicRequest{Topic: "payments", Partitions: 3, RetentionInSeconds: 60})
+		assert.NoError(t, err)
+	})
+
+	t.Run("topic settings default to the broker ones", func(t *testing.T) {
+		detail, err := topicDetail(pubsub.CreateTopicRequest{Topic: "payments"})
+		require.NoError(t, err)
+		assert.Equal(t, int32(-1), detail.NumPartitions)
+		assert.Equal(t, int16(-1), detail.ReplicationFactor)
+		assert.Nil(t, detail.ConfigEntries)
+
+		detail, err = topicDetail(pubsub.CreateTopicRequest{Topic: "payments", Partitions: 3, RetentionInSeconds: 60, Metadata: map[string]string{
+			replicationFactorKey: "2",
+		}})
+		require.NoError(t, err)
+		assert.Equal(t, int32(3), detail.NumPartitions)
+		assert.Equal(t, int16(2), detail.ReplicationFactor)
+		assert.Equal(t, "60000", *detail.ConfigEntries["retention.ms"])
+	})
+
+	t.Run("create topic with invalid replication factor", func(t *testing.T) {
+		k := &Kafka{}
+		err := k.CreateTopic(pubsub.CreateTopicRequest{Topic: "payments", Metadata: map[string]string{replicationFactorKey: "0"}})
+		assert.Error(t, err)
+	})
+
+	t.Run("delete topic", func(t *testing.T) {
+		k := newAdminTestKafka(t, map[string]sarama.MockResponse{
+			"DeleteTopicsRequest": sarama.NewMockDeleteTopicsResponse(t),
+		})
+		assert.NoError(t, k.DeleteTopic(pubsub.DeleteTopicRequest{Topic: "orders"}))
+	})
+
+	t.Run("list topics skips internal topics", func(t *testing.T) {
+		k := newAdminTestKafka(t, nil)
+		topics, err := k.ListTopics()
+		require.NoError(t, err)
+		assert.Equal(t, []string{"orders"}, topics)
+	})
+
+	t.Run("list subscriptions", func(t *testing.T) {
+		k := newAdminTestKafka(t, map[string]sarama.MockResponse{
+			"ListGroupsRequest": sarama.NewMockListGroupsResponse(t).
+				AddGroup("billing", "consumer").
+				AddGroup("audit", "consumer"),
+			"OffsetFetchRequest": sarama.NewMockOffsetFetchResponse(t).
+				SetOffset("billing", "orders", 0, 4, "", sarama.ErrNoError).
+				SetOffset("billing", "orders", 1, -1, "", sarama.ErrNoError).
+				SetOffset("audit", "orders", 0, -1, "", sarama.ErrNoError).
+				SetOffset("audit", "orders", 1, -1, "", sarama.ErrNoError),
+		})
+		subscriptions, err := k.ListSubscriptions("orders")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"billing"}, subscriptions)
+	})
+
+	t.Run("subscription lag", func(t *testing.T) {
+		lagHandlers := func() map[string]sarama.MockResponse {
+			return map[string]sarama.MockResponse{
+				"OffsetFetchRequest": sarama.NewMockOffsetFetchResponse(t).
+					SetOffset("billing", "orders", 0, 4, "", sarama.ErrNoError).
+					SetOffset("billing", "orders", 1, -1, "", sarama.ErrNoError),
+				"OffsetRequest": sarama.NewMockOffsetResponse(t).
+					SetVersion(1).
+					SetOffset("orders", 0, sarama.OffsetNewest, 10).
+					SetOffset("orders", 0, sarama.OffsetOldest, 0).
+					SetOffset("orders", 1, sarama.OffsetNewest, 7).
+					SetOffset("orders", 1, sarama.OffsetOldest, 2),
+			}
+		}
+
+		// The partition without a committed offset is consumed from its newest message
+		k := newAdminTestKafka(t, lagHandlers())
+		lag, err := k.GetSubscriptionLag(pubsub.SubscriptionLagRequest{Topic: "orders", Subscription: "billing"})
+		require.NoError(t, err)
+		assert.Equal(t, int64(6), lag.Lag)
+		assert.Equal(t, "billing", lag.Subscription)
+
+		k = newAdminTestKafka(t, lagHandlers())
+		k.config.Consumer.Offsets.Initial = sarama.OffsetOldest
+		lag, err = k.GetSubscriptionLag(pubsub.SubscriptionLagRequest{Topic: "orders", Subscription: "billing"})
+		require.NoError(t, err)
+		assert.Equal(t, int64(6+5), lag.Lag)
+	})
+}
@@ -7,17 +7,16 @@ package kafka
 
 import (
 	"context"
-	"crypto/tls"
 	"encoding/base64"
 	"errors"
 	"fmt"
-	"strconv"
-	"strings"
 	"sync"
 	"time"
 
 	"github.com/Shopify/sarama"
 	"github.com/cenkalti/backoff/v4"
+	contrib_kafka "github.com/dapr/components-contrib/internal/component/kafka"
+	"github.com/dapr/components-contrib/internal/schemaregistry"
 	"github.com/dapr/components-contrib/pubsub"
 	"github.com/dapr/dapr/pkg/logger"
 )
@@ -32,32 +31,27 @@ type Kafka struct {
 	consumerGroup string
 	brokers       []string
 	logger        logger.Logger
-	authRequired  bool
-	saslUsername  string
-	saslPassword  string
 	cg            sarama.ConsumerGroup
 	topics        map[string]bool
 	cancel        context.CancelFunc
 	consumer      consumer
 	backOff       backoff.BackOff
 	config        *sarama.Config
+	serializer    *schemaregistry.Serializer
 }
 
 type kafkaMetadata struct {
-	Brokers         []string `json:"brokers"`
-	ConsumerID      string   `json:"consumerID"`
-	AuthRequired    bool     `json:"authRequired"`
-	SaslUsername    string   `json:"saslUsername"`
-	SaslPassword    string   `json:"saslPassword"`
-	MaxMessageBytes int      `json:"maxMessageBytes"`
+	contrib_kafka.ClientMetadata
+	ConsumerID string `json:"consumerID"`
 }
 
 type consumer struct {
-	logger   logger.Logger
-	backOff  backoff.BackOff
-	ready    chan bool
-	callback func(msg *pubsub.NewMessage) error
-	once     sync.Once
+	logger     logger.Logger
+	backOff    backoff.BackOff
+	ready      chan bool
+	callback   func(msg *pubsub.NewMessage) error
+	serializer *schemaregistry.Serializer
+	once       sync.Once
 }
 
 func (consumer *consumer) ConsumeClaim(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
@@ -68,8 +62,24 @@ func (consumer *consumer) ConsumeClaim(session sarama.ConsumerGroupSession, clai
 	bo := backoff.WithContext(consumer.backOff, session.Context())
 	for message := range claim.Messages() {
 		msg := pubsub.NewMessage{
-			Topic: message.Topic,
-			Data:  message.Value,
+			Topic:    message.Topic,
+			Data:     message.Value,
+			Metadata: contrib_kafka.HeadersToMetadata(message.Headers),
+		}
+		if consumer.serializer != nil {
+			data, err := consumer.deserialize(message, msg.Metadata, bo)
+			if err != nil {
+				if schemaregistry.IsDecodeError(err) && consumer.serializer.SkipUndecodable() {
+					consumer.logger.Errorf("Skipping Kafka message that can't be decoded: %s/%d/%d [key=%s]: %v", message.Topic, message.Partition, message.Offset, asBase64String(message.Key), err)
+					session.MarkMessage(message, "")
+
+					continue
+				}
+
+				// The message isn't marked, so the claim is consumed again from it
+				return err
+			}
+			msg.Data = data
 		}
 		if err := pubsub.RetryNotifyRecover(func() error {
 			consumer.logger.Debugf("Processing Kafka message: %s/%d/%d [key=%s]", message.Topic, message.Partition, message.Offset, asBase64String(message.Key))
@@ -91,6 +101,28 @@ func (consumer *consumer) ConsumeClaim(session sarama.ConsumerGroupSession, clai
 	return nil
 }
 
+// deserialize decodes a message with the schema registry, retrying the errors
+// reaching the registry. Messages that can't be decoded are not retried.
+func (consumer *consumer) deserialize(message *sarama.ConsumerMessage, metadata map[string]string, bo backoff.BackOff) ([]byte, error) {
+	var data []byte
+	err := backoff.RetryNotify(func() error {
+		var err error
+		data, err = deserialize(consumer.serializer, message.Value, metadata)
+		if schemaregistry.IsDecodeError(err) {
+			return backoff.Permanent(err)
+		}
+
+		return err
+	}, bo, func(err error, d time.Duration) {
+		consumer.logger.Errorf("Error decoding Kafka message: %s/%d/%d [key=%s]: %v. Retrying...", message.Topic, message.Partition, message.Offset, asBase64String(message.Key), err)
+	})
+	if err != nil {
+		consumer.logger.Errorf("Error decoding Kafka message: %s/%d/%d [key=%s]: %v", message.Topic, message.Partition, message.Offset, asBase64String(message.Key), err)
+	}
+
+	return data, err
+}
+
 func (consumer *consumer) Cleanup(sarama.ConsumerGroupSession) error {
 	return nil
 }
@@ -115,6 +147,11 @@ func (k *Kafka) Init(metadata pubsub.Metadata) error {
 		return err
 	}
 
+	k.serializer, err = schemaregistry.NewSerializerFromMetadata(metadata.Properties)
+	if err != nil {
+		return err
+	}
+
 	p, err := k.getSyncProducer(meta)
 	if err != nil {
 		return err
@@ -124,16 +161,9 @@ func (k *Kafka) Init(metadata pubsub.Metadata) error {
 	k.producer = p
 	k.consumerGroup = meta.ConsumerID
 
-	if meta.AuthRequired {
-		k.saslUsername = meta.SaslUsername
-		k.saslPassword = meta.SaslPassword
-	}
-
 	config := sarama.NewConfig()
-	config.Version = sarama.V2_0_0_0
-
-	if k.authRequired {
-		updateAuthInfo(config, k.saslUsername, k.saslPassword)
+	if err = meta.UpdateConfig(config); err != nil {
+		return err
 	}
 
 	k.config = config
@@ -161,6 +191,15 @@ func (k *Kafka) Publish(req *pubsub.PublishRequest) error {
 		msg.Key = sarama.StringEncoder(val)
 	}
 
+	if k.serializer != nil {
+		value, headers, err := serialize(k.serializer, req.Topic, req.Data)
+		if err != nil {
+			return err
+		}
+		msg.Value = sarama.ByteEncoder(value)
+		msg.Headers = headers
+	}
+
 	partition, offset, err := k.producer.SendMessage(msg)
 
 	k.logger.Debugf("Partition: %v, offset: %v", partition, offset)
@@ -206,6 +245,11 @@ func (k *Kafka) closeSubscripionResources() {
 // Subscribe to topic in the Kafka cluster
 // This call cannot block like its sibling in bindings/kafka because of where this is invoked in runtime.go
 func (k *Kafka) Subscribe(req pubsub.SubscribeRequest, handler func(msg *pubsub.NewMessage) error) error {
+	handler, err := pubsub.FilterHandler(req, handler)
+	if err != nil {
+		return err
+	}
+
 	if k.consumerGroup == "" {
 		return errors.New("kafka: consumerID must be set to subscribe")
 	}
@@ -227,10 +271,11 @@ func (k *Kafka) Subscribe(req pubsub.SubscribeRequest, handler func(msg *pubsub.
 
 	ready := make(chan bool)
 	k.consumer = consumer{
-		logger:   k.logger,
-		backOff:  k.backOff,
-		ready:    ready,
-		callback: handler,
+		logger:     k.logger,
+		backOff:    k.backOff,
+		ready:      ready,
+		callback:   handler,
+		serializer: k.serializer,
 	}
 
 	go func() {
@@ -274,50 +319,13 @@ func (k *Kafka) getKafkaMetadata(metadata pubsub.Metadata) (*kafkaMetadata, erro
 	meta.ConsumerID = metadata.Properties["consumerID"]
 	k.logger.Debugf("Using %s as ConsumerGroup name", meta.ConsumerID)
 
-	if val, ok := metadata.Properties["brokers"]; ok && val != "" {
-		meta.Brokers = strings.Split(val, ",")
-	} else {
-		return nil, errors.New("kafka error: missing 'brokers' attribute")
-	}
-
-	k.logger.Debugf("Found brokers: %v", meta.Brokers)
-
-	val, ok := metadata.Properties["authRequired"]
-	if !ok {
-		return nil, errors.New("kafka error: missing 'authRequired' attribute")
-	}
-	if val == "" {
-		return nil, errors.New("kafka error: 'authRequired' attribute was empty")
-	}
-	validAuthRequired, err := strconv.ParseBool(val)
+	clientMeta, err := contrib_kafka.ParseClientMetadata(metadata.Properties, sarama.V2_0_0_0)
 	if err != nil {
-		return nil, errors.New("kafka error: invalid value for 'authRequired' attribute")
-	}
-	meta.AuthRequired = validAuthRequired
-
-	// ignore SASL properties if authRequired is false
-	if meta.AuthRequired {
-		if val, ok := metadata.Properties["saslUsername"]; ok && val != "" {
-			meta.SaslUsername = val
-		} else {
-			return nil, errors.New("kafka error: missing SASL Username")
-		}
-
-		if val, ok := metadata.Properties["saslPassword"]; ok && val != "" {
-			meta.SaslPassword = val
-		} else {
-			return nil, errors.New("kafka error: missing SASL Password")
-		}
+		return nil, err
 	}
+	meta.ClientMetadata = *clientMeta
 
-	if val, ok := metadata.Properties["maxMessageBytes"]; ok && val != "" {
-		maxBytes, err := strconv.Atoi(val)
-		if err != nil {
-			return nil, fmt.Errorf("kafka error: cannot parse maxMessageBytes: %s", err)
-		}
-
-		meta.MaxMessageBytes = maxBytes
-	}
+	k.logger.Debugf("Found brokers: %v", meta.Brokers)
 
 	return &meta, nil
 }
@@ -328,12 +336,8 @@ func (k *Kafka) getSyncProducer(meta *kafkaMetadata) (sarama.SyncProducer, error
 	config.Producer.Retry.Max = 5
 	config.Producer.Return.Successes = true
 
-	if k.authRequired {
-		updateAuthInfo(config, k.saslUsername, k.saslPassword)
-	}
-
-	if meta.MaxMessageBytes > 0 {
-		config.Producer.MaxMessageBytes = meta.MaxMessageBytes
+	if err := meta.UpdateConfig(config); err != nil {
+		return nil, err
 	}
 
 	producer, err := sarama.NewSyncProducer(meta.Brokers, config)
@@ -344,20 +348,6 @@ func (k *Kafka) getSyncProducer(meta *kafkaMetadata) (sarama.SyncProducer, error
 	return producer, nil
 }
 
-func updateAuthInfo(config *sarama.Config, saslUsername, saslPassword string) {
-	config.Net.SASL.Enable = true
-	config.Net.SASL.User = saslUsername
-	config.Net.SASL.Password = saslPassword
-	config.Net.SASL.Mechanism = sarama.SASLTypePlaintext
-
-	config.Net.TLS.Enable = true
-	// nolint: gosec
-	config.Net.TLS.Config = &tls.Config{
-		// InsecureSkipVerify: true,
-		ClientAuth: 0,
-	}
-}
-
 func (k *Kafka) Close() error {
 	k.closeSubscripionResources()
 
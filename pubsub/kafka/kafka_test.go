@@ -8,6 +8,7 @@ package kafka
 import (
 	"testing"
 
+	"github.com/Shopify/sarama"
 	"github.com/dapr/components-contrib/pubsub"
 	"github.com/dapr/dapr/pkg/logger"
 	"github.com/stretchr/testify/assert"
@@ -96,3 +97,20 @@ func TestInvalidAuthRequiredFlag(t *testing.T) {
 
 	assert.Equal(t, "kafka error: invalid value for 'authRequired' attribute", err.Error())
 }
+
+func TestClientOptions(t *testing.T) {
+	m := pubsub.Metadata{}
+	m.Properties = map[string]string{
+		"consumerID":      "a",
+		"brokers":         "akfak.com:9092",
+		"authType":        "none",
+		"initialOffset":   "oldest",
+		"balanceStrategy": "roundrobin",
+	}
+	k := getKafkaPubsub()
+	meta, err := k.getKafkaMetadata(m)
+	assert.NoError(t, err)
+	assert.Equal(t, sarama.OffsetOldest, meta.InitialOffset)
+	assert.Equal(t, sarama.V2_0_0_0, meta.Version)
+	assert.Equal(t, sarama.BalanceStrategyRoundRobin, meta.BalanceStrategy)
+}
@@ -0,0 +1,96 @@
+// ------------------------------------------------------------
+// Copyright (c) Microsoft Corporation and Dapr Contributors.
+// Licensed under the MIT License.
+// ------------------------------------------------------------
+
+package kafka
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/Shopify/sarama"
+	"github.com/dapr/components-contrib/internal/schemaregistry"
+	"github.com/dapr/components-contrib/pubsub"
+)
+
+const (
+	// cloudEventHeaderPrefix prefixes CloudEvent attributes in the Kafka binary content mode
+	cloudEventHeaderPrefix = "ce_"
+	contentTypeHeader      = "content-type"
+	jsonContentType        = "application/json"
+)
+
+// serialize encodes a published payload with the value schema of the topic.
+// CloudEvents are sent in binary content mode: only the data is encoded and
+// the other attributes are moved to headers.
+func serialize(serializer *schemaregistry.Serializer, topic string, data []byte) ([]byte, []sarama.RecordHeader, error) {
+	var event map[string]interface{}
+	if err := json.Unmarshal(data, &event); err != nil || event[pubsub.SpecVersionField] == nil || event[pubsub.DataField] == nil {
+		value, err := serializer.Serialize(topic, data)
+
+		return value, nil, err
+	}
+
+	eventData, err := json.Marshal(event[pubsub.DataField])
+	if err != nil {
+		return nil, nil, err
+	}
+	value, err := serializer.Serialize(topic, eventData)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	headers := make([]sarama.RecordHeader, 0, len(event))
+	for name, attribute := range event {
+		switch name {
+		case pubsub.DataField:
+			continue
+		case pubsub.DataContentTypeField:
+			name = contentTypeHeader
+		default:
+			name = cloudEventHeaderPrefix + name
+		}
+
+		s, ok := attribute.(string)
+		if !ok {
+			b, err := json.Marshal(attribute)
+			if err != nil {
+				return nil, nil, err
+			}
+			s = string(b)
+		}
+		headers = append(headers, sarama.RecordHeader{Key: []byte(name), Value: []byte(s)})
+	}
+
+	return value, headers, nil
+}
+
+// deserialize decodes a consumed payload to JSON, rebuilding the CloudEvent
+// when the message was sent in binary content mode.
+func deserialize(serializer *schemaregistry.Serializer, value []byte, metadata map[string]string) ([]byte, error) {
+	if !schemaregistry.IsWireFormat(value) {
+		return value, nil
+	}
+
+	data, err := serializer.Deserialize(value)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := metadata[cloudEventHeaderPrefix+pubsub.SpecVersionField]; !ok {
+		return data, nil
+	}
+
+	event := map[string]interface{}{
+		pubsub.DataField:            json.RawMessage(data),
+		pubsub.DataContentTypeField: jsonContentType,
+	}
+	for header, attribute := range metadata {
+		if strings.HasPrefix(header, cloudEventHeaderPrefix) {
+			event[strings.TrimPrefix(header, cloudEventHeaderPrefix)] = attribute
+		}
+	}
+
+	return json.Marshal(event)
+}
@@ -0,0 +1,143 @@
+// ------------------------------------------------------------
+// Copyright (c) Microsoft Corporation and Dapr Contributors.
+// Licensed under the MIT License.
+// ------------------------------------------------------------
+
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/cenkalti/backoff/v4"
+	contrib_kafka "github.com/dapr/components-contrib/internal/component/kafka"
+	"github.com/dapr/components-contrib/internal/schemaregistry"
+	"github.com/dapr/components-contrib/pubsub"
+	"github.com/dapr/dapr/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchemaRegistrySerialization(t *testing.T) {
+	schema := schemaregistry.Schema{
+		ID:      1,
+		Subject: "orders-value",
+		Version: 1,
+		Schema:  `{"type":"record","name":"Order","fields":[{"name":"id","type":"string"}]}`,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(schema)
+	}))
+	defer server.Close()
+
+	serializer, err := schemaregistry.NewSerializerFromMetadata(map[string]string{"schemaRegistryURL": server.URL})
+	require.NoError(t, err)
+
+	t.Run("raw payload", func(t *testing.T) {
+		value, headers, err := serialize(serializer, "orders", []byte(`{"id":"a1"}`))
+		require.NoError(t, err)
+		assert.Empty(t, headers)
+
+		data, err := deserialize(serializer, value, nil)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"id":"a1"}`, string(data))
+	})
+
+	t.Run("cloud event in binary content mode", func(t *testing.T) {
+		event := `{"specversion":"1.0","id":"e1","type":"order.created","source":"shop","datacontenttype":"application/json","data":{"id":"a1"}}`
+		value, headers, err := serialize(serializer, "orders", []byte(event))
+		require.NoError(t, err)
+
+		recordHeaders := make([]*sarama.RecordHeader, len(headers))
+		for i := range headers {
+			recordHeaders[i] = &headers[i]
+		}
+		metadata := contrib_kafka.HeadersToMetadata(recordHeaders)
+		assert.Equal(t, "order.created", metadata["ce_type"])
+		assert.Equal(t, "application/json", metadata["content-type"])
+
+		data, err := deserialize(serializer, value, metadata)
+		require.NoError(t, err)
+		assert.JSONEq(t, event, string(data))
+	})
+
+	t.Run("payload not matching the schema", func(t *testing.T) {
+		_, _, err := serialize(serializer, "orders", []byte(`{"name":"a1"}`))
+		assert.Error(t, err)
+	})
+
+	t.Run("payload not in wire format", func(t *testing.T) {
+		data, err := deserialize(serializer, []byte(`{"id":"a1"}`), nil)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"id":"a1"}`, string(data))
+	})
+}
+
+type fakeSession struct {
+	sarama.ConsumerGroupSession
+	marked []int64
+}
+
+func (s *fakeSession) Context() context.Context {
+	return context.Background()
+}
+
+func (s *fakeSession) MarkMessage(msg *sarama.ConsumerMessage, metadata string) {
+	s.marked = append(s.marked, msg.Offset)
+}
+
+type fakeClaim struct {
+	sarama.ConsumerGroupClaim
+	messages chan *sarama.ConsumerMessage
+}
+
+func (c *fakeClaim) Messages() <-chan *sarama.ConsumerMessage {
+	return c.messages
+}
+
+func TestConsumeClaimWithSchemaRegistry(t *testing.T) {
+	var unavailable int32 = 1
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.CompareAndSwapInt32(&unavailable, 1, 0) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+
+			return
+		}
+		json.NewEncoder(w).Encode(schemaregistry.Schema{Schema: `{"type":"record","name":"Order","fields":[{"name":"id","type":"string"}]}`})
+	}))
+	defer server.Close()
+
+	serializer, err := schemaregistry.NewSerializerFromMetadata(map[string]string{"schemaRegistryURL": server.URL})
+	require.NoError(t, err)
+
+	var received []string
+	c := &consumer{
+		serializer: serializer,
+		backOff:    backoff.NewConstantBackOff(time.Millisecond),
+		logger:     logger.NewLogger("test"),
+		callback: func(msg *pubsub.NewMessage) error {
+			received = append(received, string(msg.Data))
+
+			return nil
+		},
+	}
+
+	// The registry is unavailable for the first message, which is retried
+	// instead of skipped, and the claim stops at the message that can't be decoded
+	claim := &fakeClaim{messages: make(chan *sarama.ConsumerMessage, 3)}
+	claim.messages <- &sarama.ConsumerMessage{Topic: "orders", Offset: 0, Value: []byte{0, 0, 0, 0, 1, 4, 'a', '1'}}
+	claim.messages <- &sarama.ConsumerMessage{Topic: "orders", Offset: 1, Value: []byte{0, 0, 0, 0, 1, 0xff}}
+	claim.messages <- &sarama.ConsumerMessage{Topic: "orders", Offset: 2, Value: []byte{0, 0, 0, 0, 1, 4, 'a', '2'}}
+	close(claim.messages)
+
+	session := &fakeSession{}
+	assert.Error(t, c.ConsumeClaim(session, claim))
+	assert.Equal(t, []string{`{"id":"a1"}`}, received)
+	assert.Equal(t, []int64{0}, session.marked)
+}
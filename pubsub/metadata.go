@@ -9,3 +9,12 @@ package pubsub
 type Metadata struct {
 	Properties map[string]string `json:"properties"`
 }
+
+const (
+	// MessageIDMetadataKey is the publish and message metadata key of a stable
+	// message identifier, used by brokers and subscribers to detect duplicates.
+	MessageIDMetadataKey = "messageID"
+	// IdempotenceMetadataKey is the component metadata key that enables the
+	// broker-native duplicate detection of components that support it.
+	IdempotenceMetadataKey = "enableIdempotence"
+)
@@ -0,0 +1,158 @@
+// ------------------------------------------------------------
+// Copyright (c) Microsoft Corporation and Dapr Contributors.
+// Licensed under the MIT License.
+// ------------------------------------------------------------
+
+package mqtt
+
+import (
+	"net"
+	"strings"
+	"sync"
+
+	"github.com/eclipse/paho.golang/packets"
+)
+
+// testBroker is a minimal embedded MQTT 5 broker. It supports exact topic
+// matches, shared subscriptions delivered round robin within a group, and
+// rejects publishing or subscribing to forbiddenTopic with "not authorized".
+type testBroker struct {
+	listener net.Listener
+
+	subscriptions []*testSubscription
+	next          map[string]int
+	published     []*packets.Publish
+	lock          sync.Mutex
+}
+
+type testSubscription struct {
+	conn  *testConn
+	group string
+	topic string
+}
+
+type testConn struct {
+	conn net.Conn
+	lock sync.Mutex
+}
+
+const forbiddenTopic = "forbidden"
+
+func newTestBroker() (*testBroker, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+
+	b := &testBroker{listener: l, next: map[string]int{}}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go b.serve(&testConn{conn: conn})
+		}
+	}()
+
+	return b, nil
+}
+
+func (b *testBroker) url() string {
+	return "tcp://" + b.listener.Addr().String()
+}
+
+func (b *testBroker) close() {
+	b.listener.Close()
+}
+
+func (b *testBroker) publishedPackets() []*packets.Publish {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	return append([]*packets.Publish{}, b.published...)
+}
+
+func (c *testConn) write(p packets.Packet) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	p.WriteTo(c.conn)
+}
+
+func (b *testBroker) serve(c *testConn) {
+	defer c.conn.Close()
+	for {
+		cp, err := packets.ReadPacket(c.conn)
+		if err != nil {
+			return
+		}
+
+		switch p := cp.Content.(type) {
+		case *packets.Connect:
+			c.write(&packets.Connack{Properties: &packets.Properties{}})
+		case *packets.Subscribe:
+			reasons := make([]byte, 0, len(p.Subscriptions))
+			for filter, opts := range p.Subscriptions {
+				sub := &testSubscription{conn: c, topic: filter}
+				if strings.HasPrefix(filter, "$share/") {
+					parts := strings.SplitN(filter, "/", 3)
+					sub.group, sub.topic = parts[1], parts[2]
+				}
+				if sub.topic == forbiddenTopic {
+					reasons = append(reasons, packets.SubackNotauthorized)
+
+					continue
+				}
+				b.lock.Lock()
+				b.subscriptions = append(b.subscriptions, sub)
+				b.lock.Unlock()
+				reasons = append(reasons, opts.QoS)
+			}
+			c.write(&packets.Suback{PacketID: p.PacketID, Reasons: reasons, Properties: &packets.Properties{}})
+		case *packets.Publish:
+			if p.QoS > 0 {
+				var reason byte
+				if p.Topic == forbiddenTopic {
+					reason = packets.PubackNotAuthorized
+				}
+				c.write(&packets.Puback{PacketID: p.PacketID, ReasonCode: reason, Properties: &packets.Properties{}})
+				if reason != 0 {
+					continue
+				}
+			}
+			b.route(p)
+		case *packets.Pingreq:
+			c.write(&packets.Pingresp{})
+		case *packets.Disconnect:
+			return
+		}
+	}
+}
+
+func (b *testBroker) route(p *packets.Publish) {
+	b.lock.Lock()
+	b.published = append(b.published, p)
+
+	targets := []*testConn{}
+	groups := map[string][]*testConn{}
+	for _, sub := range b.subscriptions {
+		if sub.topic != p.Topic {
+			continue
+		}
+		if sub.group == "" {
+			targets = append(targets, sub.conn)
+		} else {
+			groups[sub.group] = append(groups[sub.group], sub.conn)
+		}
+	}
+	for group, conns := range groups {
+		key := group + "/" + p.Topic
+		targets = append(targets, conns[b.next[key]%len(conns)])
+		b.next[key]++
+	}
+	b.lock.Unlock()
+
+	for _, c := range targets {
+		c.write(&packets.Publish{Topic: p.Topic, Payload: p.Payload, Properties: p.Properties})
+	}
+}
@@ -13,6 +13,7 @@ type metadata struct {
 	retain            bool
 	cleanSession      bool
 	backOffMaxRetries int
+	protocolVersion   string
 }
 
 type tlsCfg struct {
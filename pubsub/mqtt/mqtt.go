@@ -18,6 +18,7 @@ import (
 	"github.com/cenkalti/backoff/v4"
 	mqtt "github.com/eclipse/paho.mqtt.golang"
 
+	contrib_mqtt "github.com/dapr/components-contrib/internal/component/mqtt"
 	"github.com/dapr/components-contrib/pubsub"
 	"github.com/dapr/dapr/pkg/logger"
 )
@@ -33,6 +34,7 @@ const (
 	mqttClientCert        = "clientCert"
 	mqttClientKey         = "clientKey"
 	mqttBackOffMaxRetries = "backOffMaxRetries"
+	mqttProtocolVersion   = "protocolVersion"
 
 	// errors
 	errorMsgPrefix = "mqtt pub sub error:"
@@ -48,9 +50,12 @@ const (
 type mqttPubSub struct {
 	producer mqtt.Client
 	consumer mqtt.Client
-	metadata *metadata
-	logger   logger.Logger
-	topics   map[string]byte
+	// MQTT 5 clients, used instead of producer and consumer with protocolVersion 5
+	producerV5 *contrib_mqtt.V5Client
+	consumerV5 *contrib_mqtt.V5Client
+	metadata   *metadata
+	logger     logger.Logger
+	topics     map[string]byte
 
 	ctx     context.Context
 	cancel  context.CancelFunc
@@ -140,6 +145,12 @@ func parseMQTTMetaData(md pubsub.Metadata) (*metadata, error) {
 		m.backOffMaxRetries = backOffMaxRetriesInt
 	}
 
+	protocolVersion, err := contrib_mqtt.ParseProtocolVersion(md.Properties[mqttProtocolVersion])
+	if err != nil {
+		return &m, fmt.Errorf("%s %s", errorMsgPrefix, err)
+	}
+	m.protocolVersion = protocolVersion
+
 	return &m, nil
 }
 
@@ -151,19 +162,34 @@ func (m *mqttPubSub) Init(metadata pubsub.Metadata) error {
 	}
 	m.metadata = mqttMeta
 
+	m.ctx, m.cancel = context.WithCancel(context.Background())
+
+	// TODO: Make the backoff configurable for constant or exponential
+	b := backoff.NewConstantBackOff(5 * time.Second)
+	m.backOff = backoff.WithContext(b, m.ctx)
+
+	if m.metadata.protocolVersion == contrib_mqtt.ProtocolV5 {
+		p, err := m.connectV5("producer")
+		if err != nil {
+			m.cancel()
+
+			return err
+		}
+		m.producerV5 = p
+		m.logger.Debug("mqtt 5 message bus initialization complete")
+
+		return nil
+	}
+
 	// mqtt broker allows only one connection at a given time from a clientID.
 	producerClientID := fmt.Sprintf("%s-producer", m.metadata.clientID)
 	p, err := m.connect(producerClientID)
 	if err != nil {
+		m.cancel()
+
 		return err
 	}
 
-	m.ctx, m.cancel = context.WithCancel(context.Background())
-
-	// TODO: Make the backoff configurable for constant or exponential
-	b := backoff.NewConstantBackOff(5 * time.Second)
-	m.backOff = backoff.WithContext(b, m.ctx)
-
 	m.producer = p
 	m.topics = make(map[string]byte)
 
@@ -176,6 +202,10 @@ func (m *mqttPubSub) Init(metadata pubsub.Metadata) error {
 func (m *mqttPubSub) Publish(req *pubsub.PublishRequest) error {
 	m.logger.Debugf("mqtt publishing topic %s with data: %v", req.Topic, req.Data)
 
+	if m.producerV5 != nil {
+		return m.publishV5(req)
+	}
+
 	token := m.producer.Publish(req.Topic, m.metadata.qos, m.metadata.retain, req.Data)
 	if !token.WaitTimeout(defaultWait) || token.Error() != nil {
 		return fmt.Errorf("mqtt error from publish: %v", token.Error())
@@ -186,6 +216,15 @@ func (m *mqttPubSub) Publish(req *pubsub.PublishRequest) error {
 
 // Subscribe to the mqtt pub sub topic.
 func (m *mqttPubSub) Subscribe(req pubsub.SubscribeRequest, handler func(msg *pubsub.NewMessage) error) error {
+	handler, err := pubsub.FilterHandler(req, handler)
+	if err != nil {
+		return err
+	}
+
+	if m.metadata.protocolVersion == contrib_mqtt.ProtocolV5 {
+		return m.subscribeV5(req, handler)
+	}
+
 	m.topics[req.Topic] = m.metadata.qos
 
 	// reset synchronization
@@ -299,6 +338,10 @@ func (m *mqttPubSub) createClientOptions(uri *url.URL, clientID string) *mqtt.Cl
 func (m *mqttPubSub) Close() error {
 	m.cancel()
 
+	if m.producerV5 != nil {
+		return m.closeV5()
+	}
+
 	if m.consumer != nil {
 		m.consumer.Disconnect(0)
 	}
@@ -308,5 +351,10 @@ func (m *mqttPubSub) Close() error {
 }
 
 func (m *mqttPubSub) Features() []pubsub.Feature {
+	if m.metadata != nil && m.metadata.protocolVersion == contrib_mqtt.ProtocolV5 {
+		// MQTT 5 expires messages with the message expiry interval
+		return []pubsub.Feature{pubsub.FeatureMessageTTL}
+	}
+
 	return nil
 }
@@ -0,0 +1,142 @@
+// ------------------------------------------------------------
+// Copyright (c) Microsoft Corporation and Dapr Contributors.
+// Licensed under the MIT License.
+// ------------------------------------------------------------
+
+package mqtt
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+	"github.com/eclipse/paho.golang/paho"
+	"github.com/google/uuid"
+
+	contrib_mqtt "github.com/dapr/components-contrib/internal/component/mqtt"
+	contrib_metadata "github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/components-contrib/pubsub"
+)
+
+// connectV5 connects an MQTT 5 client. Competing consumers are implemented
+// with shared subscriptions instead of a shared session.
+func (m *mqttPubSub) connectV5(role string) (*contrib_mqtt.V5Client, error) {
+	uri, err := url.Parse(m.metadata.url)
+	if err != nil {
+		return nil, err
+	}
+
+	return contrib_mqtt.ConnectV5(m.ctx, contrib_mqtt.V5Options{
+		URL:        uri,
+		ClientID:   m.clientIDV5(role),
+		CleanStart: m.metadata.cleanSession,
+		TLSConfig:  m.newTLSConfig(),
+	}, m.logger)
+}
+
+// clientIDV5 returns the client id of a connection. With a clean session,
+// replicas sharing the consumerID get a random suffix so that they don't take
+// over each other's connection. Otherwise the session is resumed by its client
+// id, which is stable like the one of MQTT 3.1.1 clients.
+func (m *mqttPubSub) clientIDV5(role string) string {
+	if !m.metadata.cleanSession {
+		return fmt.Sprintf("%s-%s", m.metadata.clientID, role)
+	}
+
+	return fmt.Sprintf("%s-%s-%s", m.metadata.clientID, role, uuid.New().String())
+}
+
+func (m *mqttPubSub) publishV5(req *pubsub.PublishRequest) error {
+	ttl, _, err := contrib_metadata.TryGetTTL(req.Metadata)
+	if err != nil {
+		return fmt.Errorf("%s %s", errorMsgPrefix, err)
+	}
+
+	properties := make(map[string]string, len(req.Metadata))
+	for k, v := range req.Metadata {
+		if k != contrib_metadata.TTLMetadataKey {
+			properties[k] = v
+		}
+	}
+
+	err = m.producerV5.Publish(m.ctx, req.Topic, m.metadata.qos, m.metadata.retain, req.Data, properties, ttl)
+	if err != nil {
+		return fmt.Errorf("mqtt error from publish: %w", err)
+	}
+
+	return nil
+}
+
+// subscribeV5 adds a shared subscription of the consumerID group to the topic.
+func (m *mqttPubSub) subscribeV5(req pubsub.SubscribeRequest, handler func(msg *pubsub.NewMessage) error) error {
+	if m.consumerV5 == nil {
+		c, err := m.connectV5("consumer")
+		if err != nil {
+			return err
+		}
+		m.consumerV5 = c
+	}
+
+	filter := contrib_mqtt.SharedSubscription(m.metadata.clientID, req.Topic)
+	messageHandler := func(p *paho.Publish) {
+		m.handleV5Message(p, handler)
+	}
+
+	err := m.consumerV5.Subscribe(m.ctx, filter, req.Topic, m.metadata.qos, messageHandler)
+	var reasonErr *contrib_mqtt.ReasonCodeError
+	if err == nil || (errors.As(err, &reasonErr) && !reasonErr.Retriable()) {
+		return err
+	}
+
+	// The broker may be temporarily unable to accept the subscription
+	m.logger.Warnf("mqtt error subscribing to %s, retrying: %v", filter, err)
+	go func() {
+		if err := backoff.Retry(func() error {
+			return m.consumerV5.Subscribe(m.ctx, filter, req.Topic, m.metadata.qos, messageHandler)
+		}, m.backOff); err != nil {
+			m.logger.Errorf("mqtt error from subscribe: %v", err)
+		}
+	}()
+
+	return nil
+}
+
+func (m *mqttPubSub) handleV5Message(p *paho.Publish, handler func(msg *pubsub.NewMessage) error) {
+	msg := pubsub.NewMessage{
+		Topic: p.Topic,
+		Data:  p.Payload,
+	}
+	if p.Properties != nil {
+		msg.Metadata = contrib_mqtt.MetadataFromUserProperties(p.Properties.User)
+	}
+
+	b := m.backOff
+	if m.metadata.backOffMaxRetries >= 0 {
+		b = backoff.WithMaxRetries(m.backOff, uint64(m.metadata.backOffMaxRetries))
+	}
+	if err := pubsub.RetryNotifyRecover(func() error {
+		m.logger.Debugf("Processing MQTT message %s/%d", p.Topic, p.PacketID)
+
+		return handler(&msg)
+	}, b, func(err error, d time.Duration) {
+		m.logger.Errorf("Error processing MQTT message: %s/%d. Retrying...", p.Topic, p.PacketID)
+	}, func() {
+		m.logger.Infof("Successfully processed MQTT message after it previously failed: %s/%d", p.Topic, p.PacketID)
+	}); err != nil {
+		m.logger.Errorf("Failed processing MQTT message: %s/%d: %v", p.Topic, p.PacketID, err)
+	}
+}
+
+func (m *mqttPubSub) closeV5() error {
+	m.cancel()
+
+	if m.consumerV5 != nil {
+		if err := m.consumerV5.Close(); err != nil {
+			m.logger.Warnf("mqtt error closing consumer: %v", err)
+		}
+	}
+
+	return m.producerV5.Close()
+}
@@ -0,0 +1,145 @@
+// ------------------------------------------------------------
+// Copyright (c) Microsoft Corporation and Dapr Contributors.
+// Licensed under the MIT License.
+// ------------------------------------------------------------
+
+package mqtt
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	contrib_mqtt "github.com/dapr/components-contrib/internal/component/mqtt"
+	"github.com/dapr/components-contrib/pubsub"
+	"github.com/dapr/dapr/pkg/logger"
+)
+
+func newV5PubSub(t *testing.T, broker *testBroker) pubsub.PubSub {
+	ps := NewMQTTPubSub(logger.NewLogger("test"))
+	err := ps.Init(pubsub.Metadata{Properties: map[string]string{
+		mqttURL:             broker.url(),
+		mqttClientID:        "orders-service",
+		mqttQOS:             "1",
+		mqttProtocolVersion: "5",
+	}})
+	require.NoError(t, err)
+	t.Cleanup(func() { ps.Close() })
+
+	return ps
+}
+
+func TestMQTTv5(t *testing.T) {
+	broker, err := newTestBroker()
+	require.NoError(t, err)
+	defer broker.close()
+
+	replica1 := newV5PubSub(t, broker)
+	replica2 := newV5PubSub(t, broker)
+	assert.Equal(t, []pubsub.Feature{pubsub.FeatureMessageTTL}, replica1.Features())
+
+	received := make(chan *pubsub.NewMessage, 10)
+	counts := map[string]int{}
+	lock := sync.Mutex{}
+	subscribe := func(ps pubsub.PubSub, name string) {
+		err := ps.Subscribe(pubsub.SubscribeRequest{Topic: "orders"}, func(msg *pubsub.NewMessage) error {
+			lock.Lock()
+			counts[name]++
+			lock.Unlock()
+			received <- msg
+
+			return nil
+		})
+		require.NoError(t, err)
+	}
+	subscribe(replica1, "replica1")
+	subscribe(replica2, "replica2")
+
+	t.Run("shared subscription delivers each message once", func(t *testing.T) {
+		for i := 0; i < 4; i++ {
+			err := replica1.Publish(&pubsub.PublishRequest{
+				Topic:    "orders",
+				Data:     []byte(fmt.Sprintf("order-%d", i)),
+				Metadata: map[string]string{"tenant": "contoso", "ttlInSeconds": "90"},
+			})
+			require.NoError(t, err)
+		}
+
+		payloads := map[string]bool{}
+		for i := 0; i < 4; i++ {
+			select {
+			case msg := <-received:
+				payloads[string(msg.Data)] = true
+				assert.Equal(t, map[string]string{"tenant": "contoso"}, msg.Metadata)
+			case <-time.After(5 * time.Second):
+				t.Fatal("timeout waiting for messages")
+			}
+		}
+		assert.Len(t, payloads, 4)
+		select {
+		case msg := <-received:
+			t.Fatalf("unexpected duplicate delivery of %s", msg.Data)
+		case <-time.After(100 * time.Millisecond):
+		}
+
+		lock.Lock()
+		assert.Equal(t, map[string]int{"replica1": 2, "replica2": 2}, counts)
+		lock.Unlock()
+	})
+
+	t.Run("ttl sets the message expiry interval", func(t *testing.T) {
+		published := broker.publishedPackets()
+		require.NotEmpty(t, published)
+		require.NotNil(t, published[0].Properties.MessageExpiry)
+		assert.Equal(t, uint32(90), *published[0].Properties.MessageExpiry)
+	})
+
+	t.Run("rejected publish returns the reason code", func(t *testing.T) {
+		err := replica1.Publish(&pubsub.PublishRequest{Topic: forbiddenTopic, Data: []byte("x")})
+		var reasonErr *contrib_mqtt.ReasonCodeError
+		require.True(t, errors.As(err, &reasonErr), err)
+		assert.Equal(t, byte(0x87), reasonErr.Code)
+		assert.False(t, reasonErr.Retriable())
+	})
+
+	t.Run("rejected subscription fails", func(t *testing.T) {
+		err := replica1.Subscribe(pubsub.SubscribeRequest{Topic: forbiddenTopic}, func(msg *pubsub.NewMessage) error {
+			return nil
+		})
+		assert.Error(t, err)
+	})
+}
+
+func TestClientIDV5(t *testing.T) {
+	m := &mqttPubSub{metadata: &metadata{clientID: "orders-service", cleanSession: true}}
+	id := m.clientIDV5("consumer")
+	assert.True(t, strings.HasPrefix(id, "orders-service-consumer-"))
+	assert.NotEqual(t, id, m.clientIDV5("consumer"))
+
+	// The session is resumed by a stable client id
+	m.metadata.cleanSession = false
+	assert.Equal(t, "orders-service-consumer", m.clientIDV5("consumer"))
+	assert.Equal(t, "orders-service-consumer", m.clientIDV5("consumer"))
+}
+
+func TestParseProtocolVersion(t *testing.T) {
+	fakeProperties := getFakeProperties()
+	m, err := parseMQTTMetaData(pubsub.Metadata{Properties: fakeProperties})
+	assert.NoError(t, err)
+	assert.Equal(t, contrib_mqtt.ProtocolV3, m.protocolVersion)
+
+	fakeProperties[mqttProtocolVersion] = "5"
+	m, err = parseMQTTMetaData(pubsub.Metadata{Properties: fakeProperties})
+	assert.NoError(t, err)
+	assert.Equal(t, contrib_mqtt.ProtocolV5, m.protocolVersion)
+
+	fakeProperties[mqttProtocolVersion] = "6"
+	_, err = parseMQTTMetaData(pubsub.Metadata{Properties: fakeProperties})
+	assert.Error(t, err)
+}
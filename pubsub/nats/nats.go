@@ -76,6 +76,11 @@ func (n *natsPubSub) Publish(req *pubsub.PublishRequest) error {
 }
 
 func (n *natsPubSub) Subscribe(req pubsub.SubscribeRequest, handler func(msg *pubsub.NewMessage) error) error {
+	handler, err := pubsub.FilterHandler(req, handler)
+	if err != nil {
+		return err
+	}
+
 	sub, err := n.natsConn.QueueSubscribe(req.Topic, n.metadata.natsQueueGroupName, func(natsMsg *nats.Msg) {
 		handler(&pubsub.NewMessage{Topic: req.Topic, Data: natsMsg.Data})
 	})
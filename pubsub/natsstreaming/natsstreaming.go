@@ -216,6 +216,11 @@ func (n *natsStreamingPubSub) Publish(req *pubsub.PublishRequest) error {
 }
 
 func (n *natsStreamingPubSub) Subscribe(req pubsub.SubscribeRequest, handler func(msg *pubsub.NewMessage) error) error {
+	handler, err := pubsub.FilterHandler(req, handler)
+	if err != nil {
+		return err
+	}
+
 	natStreamingsubscriptionOptions, err := n.subscriptionOptions()
 	if err != nil {
 		return fmt.Errorf("nats-streaming: error getting subscription options %s", err)
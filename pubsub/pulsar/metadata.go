@@ -1,7 +1,24 @@
 package pulsar
 
+import "time"
+
 type pulsarMetadata struct {
 	Host       string `json:"host"`
 	ConsumerID string `json:"consumerID"`
 	EnableTLS  bool   `json:"enableTLS"`
+
+	// Authentication, at most one of token, OAuth2 or TLS client certificate
+	Token                 string   `json:"token"`
+	OAuth2TokenURL        string   `json:"oauth2TokenURL"`
+	OAuth2ClientID        string   `json:"oauth2ClientID"`
+	OAuth2ClientSecret    string   `json:"oauth2ClientSecret"`
+	OAuth2Scopes          []string `json:"oauth2Scopes"`
+	OAuth2Audience        string   `json:"oauth2Audience"`
+	TLSCertFilePath       string   `json:"tlsCertFilePath"`
+	TLSKeyFilePath        string   `json:"tlsKeyFilePath"`
+	TLSTrustCertsFilePath string   `json:"tlsTrustCertsFilePath"`
+	TLSSkipVerify         bool     `json:"tlsSkipVerify"`
+
+	SubscriptionType    string        `json:"subscriptionType"`
+	NackRedeliveryDelay time.Duration `json:"nackRedeliveryDelay"`
 }
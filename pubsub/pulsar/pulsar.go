@@ -4,29 +4,60 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net/url"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/apache/pulsar-client-go/pulsar"
-	"github.com/cenkalti/backoff/v4"
+	"golang.org/x/oauth2/clientcredentials"
 
 	"github.com/dapr/components-contrib/pubsub"
 	"github.com/dapr/dapr/pkg/logger"
 )
 
 const (
-	host      = "host"
-	enableTLS = "enableTLS"
+	host                  = "host"
+	enableTLS             = "enableTLS"
+	token                 = "token"
+	oauth2TokenURL        = "oauth2TokenURL"
+	oauth2ClientID        = "oauth2ClientID"
+	oauth2ClientSecret    = "oauth2ClientSecret"
+	oauth2Scopes          = "oauth2Scopes"
+	oauth2Audience        = "oauth2Audience"
+	tlsCertFilePath       = "tlsCertFilePath"
+	tlsKeyFilePath        = "tlsKeyFilePath"
+	tlsTrustCertsFilePath = "tlsTrustCertsFilePath"
+	tlsSkipVerify         = "tlsSkipVerify"
+	subscriptionType      = "subscriptionType"
+	nackRedeliveryDelay   = "nackRedeliveryDelay"
+
+	// Publish metadata
+	keyMetadataKey          = "key"
+	deliverAfterMetadataKey = "deliverAfter"
+	deliverAtMetadataKey    = "deliverAt"
+
+	defaultSubscriptionType = "failover"
 )
 
+var subscriptionTypes = map[string]pulsar.SubscriptionType{
+	"exclusive":  pulsar.Exclusive,
+	"shared":     pulsar.Shared,
+	"failover":   pulsar.Failover,
+	"key_shared": pulsar.KeyShared,
+}
+
 type Pulsar struct {
 	logger   logger.Logger
 	client   pulsar.Client
 	metadata pulsarMetadata
 
-	ctx     context.Context
-	cancel  context.CancelFunc
-	backOff backoff.BackOff
+	producers    map[string]pulsar.Producer
+	producersMux sync.Mutex
+
+	ctx    context.Context
+	cancel context.CancelFunc
 }
 
 func NewPulsar(l logger.Logger) pubsub.PubSub {
@@ -34,7 +65,9 @@ func NewPulsar(l logger.Logger) pubsub.PubSub {
 }
 
 func parsePulsarMetadata(meta pubsub.Metadata) (*pulsarMetadata, error) {
-	m := pulsarMetadata{}
+	m := pulsarMetadata{
+		SubscriptionType: defaultSubscriptionType,
+	}
 	m.ConsumerID = meta.Properties["consumerID"]
 
 	if val, ok := meta.Properties[host]; ok && val != "" {
@@ -49,66 +82,248 @@ func parsePulsarMetadata(meta pubsub.Metadata) (*pulsarMetadata, error) {
 		}
 		m.EnableTLS = tls
 	}
+	if val, ok := meta.Properties[tlsSkipVerify]; ok && val != "" {
+		skip, err := strconv.ParseBool(val)
+		if err != nil {
+			return nil, errors.New("pulsar error: invalid value for tlsSkipVerify")
+		}
+		m.TLSSkipVerify = skip
+	}
+
+	m.Token = meta.Properties[token]
+	m.OAuth2TokenURL = meta.Properties[oauth2TokenURL]
+	m.OAuth2ClientID = meta.Properties[oauth2ClientID]
+	m.OAuth2ClientSecret = meta.Properties[oauth2ClientSecret]
+	m.OAuth2Audience = meta.Properties[oauth2Audience]
+	if val, ok := meta.Properties[oauth2Scopes]; ok && val != "" {
+		for _, scope := range strings.Split(val, ",") {
+			if scope = strings.TrimSpace(scope); scope != "" {
+				m.OAuth2Scopes = append(m.OAuth2Scopes, scope)
+			}
+		}
+	}
+	m.TLSCertFilePath = meta.Properties[tlsCertFilePath]
+	m.TLSKeyFilePath = meta.Properties[tlsKeyFilePath]
+	m.TLSTrustCertsFilePath = meta.Properties[tlsTrustCertsFilePath]
+
+	if err := validateAuth(&m); err != nil {
+		return nil, err
+	}
+
+	if val, ok := meta.Properties[subscriptionType]; ok && val != "" {
+		val = strings.ToLower(val)
+		if _, ok := subscriptionTypes[val]; !ok {
+			return nil, fmt.Errorf("pulsar error: invalid subscriptionType %s, must be one of exclusive, shared, failover or key_shared", val)
+		}
+		m.SubscriptionType = val
+	}
+	if val, ok := meta.Properties[nackRedeliveryDelay]; ok && val != "" {
+		d, err := time.ParseDuration(val)
+		if err != nil || d < 0 {
+			return nil, errors.New("pulsar error: invalid value for nackRedeliveryDelay")
+		}
+		m.NackRedeliveryDelay = d
+	}
 
 	return &m, nil
 }
 
+func validateAuth(m *pulsarMetadata) error {
+	methods := 0
+	if m.Token != "" {
+		methods++
+	}
+	if m.OAuth2TokenURL != "" || m.OAuth2ClientID != "" || m.OAuth2ClientSecret != "" {
+		if m.OAuth2TokenURL == "" || m.OAuth2ClientID == "" || m.OAuth2ClientSecret == "" {
+			return errors.New("pulsar error: oauth2 authentication requires oauth2TokenURL, oauth2ClientID and oauth2ClientSecret")
+		}
+		methods++
+	}
+	if m.TLSCertFilePath != "" || m.TLSKeyFilePath != "" {
+		if m.TLSCertFilePath == "" || m.TLSKeyFilePath == "" {
+			return errors.New("pulsar error: tls authentication requires both tlsCertFilePath and tlsKeyFilePath")
+		}
+		methods++
+	}
+	if methods > 1 {
+		return errors.New("pulsar error: only one of token, oauth2 or tls authentication can be configured")
+	}
+
+	return nil
+}
+
+// authentication returns the authentication provider configured in the
+// metadata, or nil when the broker does not require authentication.
+func (m *pulsarMetadata) authentication() pulsar.Authentication {
+	switch {
+	case m.Token != "":
+		return pulsar.NewAuthenticationToken(m.Token)
+	case m.OAuth2TokenURL != "":
+		config := clientcredentials.Config{
+			ClientID:     m.OAuth2ClientID,
+			ClientSecret: m.OAuth2ClientSecret,
+			TokenURL:     m.OAuth2TokenURL,
+			Scopes:       m.OAuth2Scopes,
+		}
+		if m.OAuth2Audience != "" {
+			config.EndpointParams = url.Values{"audience": []string{m.OAuth2Audience}}
+		}
+		// The token source caches the token until it expires
+		tokens := config.TokenSource(context.Background())
+
+		return pulsar.NewAuthenticationTokenFromSupplier(func() (string, error) {
+			t, err := tokens.Token()
+			if err != nil {
+				return "", fmt.Errorf("pulsar error: failed to get oauth2 token: %s", err)
+			}
+
+			return t.AccessToken, nil
+		})
+	case m.TLSCertFilePath != "":
+		return pulsar.NewAuthenticationTLS(m.TLSCertFilePath, m.TLSKeyFilePath)
+	}
+
+	return nil
+}
+
+// serviceURL returns the broker URL. A host without scheme gets pulsar:// or
+// pulsar+ssl:// depending on enableTLS.
+func (m *pulsarMetadata) serviceURL() string {
+	if strings.Contains(m.Host, "://") {
+		return m.Host
+	}
+	if m.EnableTLS {
+		return fmt.Sprintf("pulsar+ssl://%s", m.Host)
+	}
+
+	return fmt.Sprintf("pulsar://%s", m.Host)
+}
+
 func (p *Pulsar) Init(metadata pubsub.Metadata) error {
 	m, err := parsePulsarMetadata(metadata)
 	if err != nil {
 		return err
 	}
-	client, err := pulsar.NewClient(pulsar.ClientOptions{
-		URL:                        fmt.Sprintf("pulsar://%s", m.Host),
+	options := pulsar.ClientOptions{
+		URL:                        m.serviceURL(),
 		OperationTimeout:           30 * time.Second,
 		ConnectionTimeout:          30 * time.Second,
-		TLSAllowInsecureConnection: !m.EnableTLS,
-	})
+		TLSTrustCertsFilePath:      m.TLSTrustCertsFilePath,
+		TLSAllowInsecureConnection: m.TLSSkipVerify,
+	}
+	if auth := m.authentication(); auth != nil {
+		options.Authentication = auth
+	}
+	client, err := pulsar.NewClient(options)
 	if err != nil {
 		return fmt.Errorf("could not instantiate pulsar client: %v", err)
 	}
-	defer client.Close()
 
 	p.ctx, p.cancel = context.WithCancel(context.Background())
 
 	p.client = client
 	p.metadata = *m
-
-	// TODO: Make the backoff configurable for constant or exponential
-	b := backoff.NewConstantBackOff(5 * time.Second)
-	p.backOff = backoff.WithContext(b, p.ctx)
+	p.producers = map[string]pulsar.Producer{}
 
 	return nil
 }
 
-func (p *Pulsar) Publish(req *pubsub.PublishRequest) error {
+// producer returns the producer for topic, creating it on first use.
+func (p *Pulsar) producer(topic string) (pulsar.Producer, error) {
+	p.producersMux.Lock()
+	defer p.producersMux.Unlock()
+
+	if producer, ok := p.producers[topic]; ok {
+		return producer, nil
+	}
 	producer, err := p.client.CreateProducer(pulsar.ProducerOptions{
-		Topic: req.Topic,
+		Topic: topic,
 	})
 	if err != nil {
-		return err
+		return nil, err
 	}
+	p.producers[topic] = producer
+
+	return producer, nil
+}
 
-	_, err = producer.Send(context.Background(), &pulsar.ProducerMessage{
+// producerMessage builds the message for a publish request. The key,
+// deliverAfter and deliverAt metadata control delivery, all other metadata
+// is attached as message properties.
+func producerMessage(req *pubsub.PublishRequest) (*pulsar.ProducerMessage, error) {
+	msg := &pulsar.ProducerMessage{
 		Payload: req.Data,
-	})
+	}
+
+	for k, v := range req.Metadata {
+		switch k {
+		case keyMetadataKey:
+			msg.Key = v
+		case deliverAfterMetadataKey:
+			if v == "" {
+				continue
+			}
+			d, err := time.ParseDuration(v)
+			if err != nil || d < 0 {
+				return nil, fmt.Errorf("pulsar error: invalid value for deliverAfter: %s", v)
+			}
+			msg.DeliverAfter = d
+		case deliverAtMetadataKey:
+		default:
+			if msg.Properties == nil {
+				msg.Properties = map[string]string{}
+			}
+			msg.Properties[k] = v
+		}
+	}
+
+	if v := req.Metadata[deliverAtMetadataKey]; v != "" {
+		if msg.DeliverAfter != 0 {
+			return nil, errors.New("pulsar error: deliverAfter and deliverAt cannot be set together")
+		}
+		at, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return nil, fmt.Errorf("pulsar error: invalid value for deliverAt, must be RFC3339: %s", v)
+		}
+		// A time in the past delivers immediately
+		if d := time.Until(at); d > 0 {
+			msg.DeliverAfter = d
+		}
+	}
+
+	return msg, nil
+}
+
+func (p *Pulsar) Publish(req *pubsub.PublishRequest) error {
+	msg, err := producerMessage(req)
 	if err != nil {
 		return err
 	}
 
-	defer producer.Close()
+	producer, err := p.producer(req.Topic)
+	if err != nil {
+		return err
+	}
 
-	return nil
+	_, err = producer.Send(context.Background(), msg)
+
+	return err
 }
 
 func (p *Pulsar) Subscribe(req pubsub.SubscribeRequest, handler func(msg *pubsub.NewMessage) error) error {
+	handler, err := pubsub.FilterHandler(req, handler)
+	if err != nil {
+		return err
+	}
+
 	channel := make(chan pulsar.ConsumerMessage, 100)
 
 	options := pulsar.ConsumerOptions{
-		Topic:            req.Topic,
-		SubscriptionName: p.metadata.ConsumerID,
-		Type:             pulsar.Failover,
-		MessageChannel:   channel,
+		Topic:               req.Topic,
+		SubscriptionName:    p.metadata.ConsumerID,
+		Type:                subscriptionTypes[p.metadata.SubscriptionType],
+		MessageChannel:      channel,
+		NackRedeliveryDelay: p.metadata.NackRedeliveryDelay,
 	}
 
 	consumer, err := p.client.Subscribe(options)
@@ -129,11 +344,7 @@ func (p *Pulsar) listenMessage(consumer pulsar.Consumer, handler func(msg *pubsu
 	for {
 		select {
 		case msg := <-consumer.Chan():
-			if err := p.handleMessage(msg, handler); err != nil && !errors.Is(err, context.Canceled) {
-				p.logger.Errorf("Error processing message and retries are exhausted: %s/%#v [key=%s]. Closing consumer.", msg.Topic(), msg.ID(), msg.Key())
-
-				return
-			}
+			p.handleMessage(msg, handler)
 
 		case <-p.ctx.Done():
 			// Handle the component being closed
@@ -142,30 +353,50 @@ func (p *Pulsar) listenMessage(consumer pulsar.Consumer, handler func(msg *pubsu
 	}
 }
 
-func (p *Pulsar) handleMessage(msg pulsar.ConsumerMessage, handler func(msg *pubsub.NewMessage) error) error {
-	pubsubMsg := pubsub.NewMessage{
-		Data:     msg.Payload(),
-		Topic:    msg.Topic(),
-		Metadata: msg.Properties(),
+// handleMessage acknowledges messages that were processed successfully.
+// Failed messages are negatively acknowledged so that the broker redelivers
+// them after nackRedeliveryDelay without blocking the consumer.
+func (p *Pulsar) handleMessage(msg pulsar.ConsumerMessage, handler func(msg *pubsub.NewMessage) error) {
+	p.logger.Debugf("Processing Pulsar message %s/%#v", msg.Topic(), msg.ID())
+
+	if err := handler(consumerMessage(msg.Message)); err != nil {
+		p.logger.Errorf("Error processing Pulsar message: %s/%#v [key=%s]. Redelivering: %s", msg.Topic(), msg.ID(), msg.Key(), err)
+		msg.Nack(msg.Message)
+
+		return
 	}
 
-	return pubsub.RetryNotifyRecover(func() error {
-		p.logger.Debugf("Processing Pulsar message %s/%#v", msg.Topic(), msg.ID())
-		err := handler(&pubsubMsg)
-		if err == nil {
-			msg.Ack(msg.Message)
-		}
+	msg.Ack(msg.Message)
+}
 
-		return err
-	}, p.backOff, func(err error, d time.Duration) {
-		p.logger.Errorf("Error processing Pulsar message: %s/%#v [key=%s]. Retrying...", msg.Topic(), msg.ID(), msg.Key())
-	}, func() {
-		p.logger.Infof("Successfully processed Pulsar message after it previously failed: %s/%#v [key=%s]", msg.Topic(), msg.ID(), msg.Key())
-	})
+// consumerMessage maps a Pulsar message to a pubsub message. Message
+// properties become metadata and the message key is exposed as key.
+func consumerMessage(msg pulsar.Message) *pubsub.NewMessage {
+	metadata := make(map[string]string, len(msg.Properties())+1)
+	for k, v := range msg.Properties() {
+		metadata[k] = v
+	}
+	if key := msg.Key(); key != "" {
+		metadata[keyMetadataKey] = key
+	}
+
+	return &pubsub.NewMessage{
+		Data:     msg.Payload(),
+		Topic:    msg.Topic(),
+		Metadata: metadata,
+	}
 }
 
 func (p *Pulsar) Close() error {
 	p.cancel()
+
+	p.producersMux.Lock()
+	for _, producer := range p.producers {
+		producer.Close()
+	}
+	p.producers = map[string]pulsar.Producer{}
+	p.producersMux.Unlock()
+
 	p.client.Close()
 
 	return nil
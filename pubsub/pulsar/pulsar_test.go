@@ -2,9 +2,12 @@ package pulsar
 
 import (
 	"testing"
+	"time"
 
+	"github.com/apache/pulsar-client-go/pulsar"
 	"github.com/dapr/components-contrib/pubsub"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestParsePulsarMetadata(t *testing.T) {
@@ -36,3 +39,183 @@ func TestInvalidTLSInput(t *testing.T) {
 	assert.Nil(t, meta)
 	assert.Equal(t, "pulsar error: invalid value for enableTLS", err.Error())
 }
+
+func TestParseAuthMetadata(t *testing.T) {
+	t.Run("token", func(t *testing.T) {
+		m := pubsub.Metadata{}
+		m.Properties = map[string]string{"host": "a", "token": "secret"}
+		meta, err := parsePulsarMetadata(m)
+
+		require.NoError(t, err)
+		assert.Equal(t, "secret", meta.Token)
+		assert.NotNil(t, meta.authentication())
+	})
+
+	t.Run("oauth2", func(t *testing.T) {
+		m := pubsub.Metadata{}
+		m.Properties = map[string]string{
+			"host":               "a",
+			"oauth2TokenURL":     "https://auth/token",
+			"oauth2ClientID":     "id",
+			"oauth2ClientSecret": "secret",
+			"oauth2Scopes":       "a, b",
+			"oauth2Audience":     "urn:pulsar",
+		}
+		meta, err := parsePulsarMetadata(m)
+
+		require.NoError(t, err)
+		assert.Equal(t, []string{"a", "b"}, meta.OAuth2Scopes)
+		assert.Equal(t, "urn:pulsar", meta.OAuth2Audience)
+		assert.NotNil(t, meta.authentication())
+	})
+
+	t.Run("oauth2 incomplete", func(t *testing.T) {
+		m := pubsub.Metadata{}
+		m.Properties = map[string]string{"host": "a", "oauth2ClientID": "id"}
+		_, err := parsePulsarMetadata(m)
+
+		assert.Error(t, err)
+	})
+
+	t.Run("tls", func(t *testing.T) {
+		m := pubsub.Metadata{}
+		m.Properties = map[string]string{"host": "a", "tlsCertFilePath": "cert.pem", "tlsKeyFilePath": "key.pem"}
+		meta, err := parsePulsarMetadata(m)
+
+		require.NoError(t, err)
+		assert.NotNil(t, meta.authentication())
+	})
+
+	t.Run("tls missing key", func(t *testing.T) {
+		m := pubsub.Metadata{}
+		m.Properties = map[string]string{"host": "a", "tlsCertFilePath": "cert.pem"}
+		_, err := parsePulsarMetadata(m)
+
+		assert.Error(t, err)
+	})
+
+	t.Run("multiple methods", func(t *testing.T) {
+		m := pubsub.Metadata{}
+		m.Properties = map[string]string{"host": "a", "token": "secret", "tlsCertFilePath": "cert.pem", "tlsKeyFilePath": "key.pem"}
+		_, err := parsePulsarMetadata(m)
+
+		assert.Error(t, err)
+	})
+
+	t.Run("none", func(t *testing.T) {
+		m := pubsub.Metadata{}
+		m.Properties = map[string]string{"host": "a"}
+		meta, err := parsePulsarMetadata(m)
+
+		require.NoError(t, err)
+		assert.Nil(t, meta.authentication())
+	})
+}
+
+func TestServiceURL(t *testing.T) {
+	assert.Equal(t, "pulsar://a:6650", (&pulsarMetadata{Host: "a:6650"}).serviceURL())
+	assert.Equal(t, "pulsar+ssl://a:6651", (&pulsarMetadata{Host: "a:6651", EnableTLS: true}).serviceURL())
+	assert.Equal(t, "pulsar+ssl://b:6651", (&pulsarMetadata{Host: "pulsar+ssl://b:6651"}).serviceURL())
+}
+
+func TestParseSubscriptionType(t *testing.T) {
+	m := pubsub.Metadata{}
+	m.Properties = map[string]string{"host": "a"}
+	meta, err := parsePulsarMetadata(m)
+	require.NoError(t, err)
+	assert.Equal(t, "failover", meta.SubscriptionType)
+
+	m.Properties["subscriptionType"] = "Key_Shared"
+	m.Properties["nackRedeliveryDelay"] = "30s"
+	meta, err = parsePulsarMetadata(m)
+	require.NoError(t, err)
+	assert.Equal(t, pulsar.KeyShared, subscriptionTypes[meta.SubscriptionType])
+	assert.Equal(t, 30*time.Second, meta.NackRedeliveryDelay)
+
+	m.Properties["subscriptionType"] = "round_robin"
+	_, err = parsePulsarMetadata(m)
+	assert.Error(t, err)
+
+	m.Properties["subscriptionType"] = "shared"
+	m.Properties["nackRedeliveryDelay"] = "soon"
+	_, err = parsePulsarMetadata(m)
+	assert.Error(t, err)
+}
+
+func TestProducerMessage(t *testing.T) {
+	t.Run("key and properties", func(t *testing.T) {
+		msg, err := producerMessage(&pubsub.PublishRequest{
+			Data:     []byte("data"),
+			Metadata: map[string]string{"key": "order-1", "tenant": "contoso"},
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, []byte("data"), msg.Payload)
+		assert.Equal(t, "order-1", msg.Key)
+		assert.Equal(t, map[string]string{"tenant": "contoso"}, msg.Properties)
+		assert.Zero(t, msg.DeliverAfter)
+	})
+
+	t.Run("deliverAfter", func(t *testing.T) {
+		msg, err := producerMessage(&pubsub.PublishRequest{
+			Metadata: map[string]string{"deliverAfter": "1m"},
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, time.Minute, msg.DeliverAfter)
+		assert.Nil(t, msg.Properties)
+	})
+
+	t.Run("deliverAt", func(t *testing.T) {
+		at := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+		msg, err := producerMessage(&pubsub.PublishRequest{
+			Metadata: map[string]string{"deliverAt": at},
+		})
+
+		require.NoError(t, err)
+		assert.InDelta(t, float64(time.Hour), float64(msg.DeliverAfter), float64(2*time.Second))
+	})
+
+	t.Run("deliverAt in the past", func(t *testing.T) {
+		msg, err := producerMessage(&pubsub.PublishRequest{
+			Metadata: map[string]string{"deliverAt": "2000-01-01T00:00:00Z"},
+		})
+
+		require.NoError(t, err)
+		assert.Zero(t, msg.DeliverAfter)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		for _, md := range []map[string]string{
+			{"deliverAfter": "later"},
+			{"deliverAfter": "-1s"},
+			{"deliverAt": "tomorrow"},
+			{"deliverAfter": "1m", "deliverAt": "2000-01-01T00:00:00Z"},
+		} {
+			_, err := producerMessage(&pubsub.PublishRequest{Metadata: md})
+			assert.Error(t, err, md)
+		}
+	})
+}
+
+type fakeMessage struct {
+	pulsar.Message
+	key        string
+	properties map[string]string
+}
+
+func (m *fakeMessage) Topic() string                 { return "orders" }
+func (m *fakeMessage) Payload() []byte               { return []byte("data") }
+func (m *fakeMessage) Key() string                   { return m.key }
+func (m *fakeMessage) Properties() map[string]string { return m.properties }
+
+func TestConsumerMessage(t *testing.T) {
+	msg := consumerMessage(&fakeMessage{key: "order-1", properties: map[string]string{"tenant": "contoso"}})
+
+	assert.Equal(t, "orders", msg.Topic)
+	assert.Equal(t, []byte("data"), msg.Data)
+	assert.Equal(t, map[string]string{"tenant": "contoso", "key": "order-1"}, msg.Metadata)
+
+	msg = consumerMessage(&fakeMessage{})
+	assert.Empty(t, msg.Metadata)
+}
@@ -0,0 +1,181 @@
+package rabbitmq
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dapr/components-contrib/pubsub"
+	"github.com/streadway/amqp"
+)
+
+const (
+	managementRequestTimeout = 10 * time.Second
+	systemExchangePrefix     = "amq."
+)
+
+// managementExchange is an exchange as returned by the management API
+type managementExchange struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Internal bool   `json:"internal"`
+}
+
+// managementBinding is a binding as returned by the management API
+type managementBinding struct {
+	Destination     string `json:"destination"`
+	DestinationType string `json:"destination_type"`
+}
+
+// managementQueue is a queue as returned by the management API
+type managementQueue struct {
+	// Messages counts the ready and unacknowledged messages of the queue
+	Messages int64 `json:"messages"`
+}
+
+// CreateTopic declares the exchange of a topic. Exchanges don't keep
+// messages, so partitions and retention are ignored.
+func (r *rabbitMQ) CreateTopic(req pubsub.CreateTopicRequest) error {
+	channel, _, err := r.getChannelOrReconnect()
+	if err != nil {
+		return err
+	}
+
+	return r.ensureExchangeDeclared(channel, req.Topic)
+}
+
+// DeleteTopic deletes the exchange of a topic. The subscription queues are
+// kept, and are deleted by RabbitMQ when unused if deleteWhenUnused is set.
+func (r *rabbitMQ) DeleteTopic(req pubsub.DeleteTopicRequest) error {
+	channel, connectionCount, err := r.getChannelOrReconnect()
+	if err != nil {
+		return err
+	}
+
+	if err = channel.ExchangeDelete(req.Topic, false, false); err != nil {
+		if mustReconnect(channel, err) {
+			r.reconnect(connectionCount)
+		}
+
+		return err
+	}
+
+	r.channelMutex.Lock()
+	delete(r.declaredExchanges, req.Topic)
+	r.channelMutex.Unlock()
+
+	return nil
+}
+
+// ListTopics returns the exchanges of the virtual host, except the system
+// exchanges and the filter exchanges of subscriptions. It requires the
+// management API.
+func (r *rabbitMQ) ListTopics() ([]string, error) {
+	var exchanges []managementExchange
+	if err := r.managementGet(&exchanges, "exchanges"); err != nil {
+		return nil, err
+	}
+
+	topics := make([]string, 0, len(exchanges))
+	for _, exchange := range exchanges {
+		if exchange.Name == "" || exchange.Internal || strings.HasPrefix(exchange.Name, systemExchangePrefix) {
+			continue
+		}
+		if exchange.Type == headersExchangeKind && strings.HasSuffix(exchange.Name, filterExchangeSuffix) {
+			continue
+		}
+		topics = append(topics, exchange.Name)
+	}
+	sort.Strings(topics)
+
+	return topics, nil
+}
+
+// ListSubscriptions returns the queues bound to the exchange of a topic,
+// directly or through the filter exchange of a subscription. It requires the
+// management API.
+func (r *rabbitMQ) ListSubscriptions(topic string) ([]string, error) {
+	var bindings []managementBinding
+	if err := r.managementGet(&bindings, "exchanges", topic, "bindings", "source"); err != nil {
+		return nil, err
+	}
+
+	queues := map[string]struct{}{}
+	for _, binding := range bindings {
+		switch binding.DestinationType {
+		case "queue":
+			queues[binding.Destination] = struct{}{}
+		case "exchange":
+			if strings.HasSuffix(binding.Destination, filterExchangeSuffix) {
+				queues[strings.TrimSuffix(binding.Destination, filterExchangeSuffix)] = struct{}{}
+			}
+		}
+	}
+
+	subscriptions := make([]string, 0, len(queues))
+	for queue := range queues {
+		subscriptions = append(subscriptions, queue)
+	}
+	sort.Strings(subscriptions)
+
+	return subscriptions, nil
+}
+
+// GetSubscriptionLag returns the number of ready and unacknowledged messages
+// of a subscription queue. It requires the management API.
+func (r *rabbitMQ) GetSubscriptionLag(req pubsub.SubscriptionLagRequest) (*pubsub.SubscriptionLagResponse, error) {
+	var queue managementQueue
+	if err := r.managementGet(&queue, "queues", req.Subscription); err != nil {
+		return nil, err
+	}
+
+	return &pubsub.SubscriptionLagResponse{
+		Topic:        req.Topic,
+		Subscription: req.Subscription,
+		Lag:          queue.Messages,
+	}, nil
+}
+
+// managementGet decodes the response of the management API resource of the
+// virtual host of the component, for example `exchanges/<vhost>/<name>`.
+// The credentials of the AMQP URI are used unless the management URL has its own.
+func (r *rabbitMQ) managementGet(v interface{}, resource string, path ...string) error {
+	if r.metadata.managementURL == "" {
+		return fmt.Errorf("%s %s is required to list topics and subscriptions: %w", errorMessagePrefix, metadataManagementURLKey, pubsub.ErrNotSupported)
+	}
+
+	uri, err := amqp.ParseURI(r.metadata.host)
+	if err != nil {
+		return fmt.Errorf("%s invalid RabbitMQ host: %s", errorMessagePrefix, err)
+	}
+
+	segments := []string{r.metadata.managementURL, "api", resource, url.PathEscape(uri.Vhost)}
+	for _, p := range path {
+		segments = append(segments, url.PathEscape(p))
+	}
+
+	req, err := http.NewRequest(http.MethodGet, strings.Join(segments, "/"), nil)
+	if err != nil {
+		return err
+	}
+	if req.URL.User == nil {
+		req.SetBasicAuth(uri.Username, uri.Password)
+	}
+
+	client := http.Client{Timeout: managementRequestTimeout}
+	res, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s management API request failed: %s", errorMessagePrefix, err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s management API request %s returned %s", errorMessagePrefix, req.URL.Path, res.Status)
+	}
+
+	return json.NewDecoder(res.Body).Decode(v)
+}
@@ -2,10 +2,14 @@ package rabbitmq
 
 import (
 	"fmt"
+	"net/url"
 	"strconv"
+	"strings"
 	"time"
 
+	contrib_metadata "github.com/dapr/components-contrib/metadata"
 	"github.com/dapr/components-contrib/pubsub"
+	"github.com/streadway/amqp"
 )
 
 type metadata struct {
@@ -18,6 +22,48 @@ type metadata struct {
 	prefetchCount    uint8 // Prefetch deactivated if 0
 	reconnectWait    time.Duration
 	concurrency      pubsub.ConcurrencyMode
+
+	exchangeKind            string
+	publisherConfirm        bool
+	publisherConfirmTimeout time.Duration
+
+	queueType            string
+	maxLen               int64
+	maxLenBytes          int64
+	messageTTL           time.Duration // Queue message TTL, 0 if not set
+	deadLetterExchange   string
+	deadLetterRoutingKey string
+
+	managementURL string // Base URL of the management HTTP API, used to list topics and subscriptions
+}
+
+// queueArgs returns the arguments used to declare subscription queues.
+func (m *metadata) queueArgs() amqp.Table {
+	args := amqp.Table{}
+	if m.queueType == quorumQueueType {
+		args[argQueueType] = quorumQueueType
+	}
+	if m.maxLen > 0 {
+		args[argMaxLength] = m.maxLen
+	}
+	if m.maxLenBytes > 0 {
+		args[argMaxLengthBytes] = m.maxLenBytes
+	}
+	if m.messageTTL > 0 {
+		args[argMessageTTL] = m.messageTTL.Milliseconds()
+	}
+	if m.deadLetterExchange != "" {
+		args[argDeadLetterExchange] = m.deadLetterExchange
+		if m.deadLetterRoutingKey != "" {
+			args[argDeadLetterRoutingKey] = m.deadLetterRoutingKey
+		}
+	}
+
+	if len(args) == 0 {
+		return nil
+	}
+
+	return args
 }
 
 // createMetadata creates a new instance from the pubsub metadata
@@ -26,6 +72,10 @@ func createMetadata(pubSubMetadata pubsub.Metadata) (*metadata, error) {
 		deleteWhenUnused: true,
 		autoAck:          false,
 		reconnectWait:    time.Duration(defaultReconnectWaitSeconds) * time.Second,
+
+		exchangeKind:            fanoutExchangeKind,
+		publisherConfirmTimeout: time.Duration(defaultPublisherConfirmTimeoutSeconds) * time.Second,
+		queueType:               classicQueueType,
 	}
 
 	if val, found := pubSubMetadata.Properties[metadataHostKey]; found && val != "" {
@@ -77,6 +127,85 @@ func createMetadata(pubSubMetadata pubsub.Metadata) (*metadata, error) {
 		}
 	}
 
+	if val, found := pubSubMetadata.Properties[metadataExchangeKindKey]; found && val != "" {
+		switch val {
+		case fanoutExchangeKind, topicExchangeKind, directExchangeKind, headersExchangeKind:
+			result.exchangeKind = val
+		default:
+			return &result, fmt.Errorf("%s invalid RabbitMQ exchange kind %s, accepted values are fanout, topic, direct and headers", errorMessagePrefix, val)
+		}
+	}
+
+	if val, found := pubSubMetadata.Properties[metadataPublisherConfirmKey]; found && val != "" {
+		if boolVal, err := strconv.ParseBool(val); err == nil {
+			result.publisherConfirm = boolVal
+		}
+	}
+
+	if val, found := pubSubMetadata.Properties[metadataPublisherConfirmTimeoutSeconds]; found && val != "" {
+		intVal, err := strconv.Atoi(val)
+		if err != nil || intVal <= 0 {
+			return &result, fmt.Errorf("%s invalid RabbitMQ publisher confirm timeout %s", errorMessagePrefix, val)
+		}
+		result.publisherConfirmTimeout = time.Duration(intVal) * time.Second
+	}
+
+	if val, found := pubSubMetadata.Properties[metadataQueueTypeKey]; found && val != "" {
+		switch val {
+		case classicQueueType:
+		case quorumQueueType:
+			// Quorum queues are always durable and cannot be auto deleted
+			if deleteWhenUnused, found := pubSubMetadata.Properties[metadataDeleteWhenUnusedKey]; found && deleteWhenUnused != "" {
+				if boolVal, err := strconv.ParseBool(deleteWhenUnused); err == nil && boolVal {
+					return &result, fmt.Errorf("%s quorum queues cannot be deleted when unused", errorMessagePrefix)
+				}
+			}
+			result.deleteWhenUnused = false
+		default:
+			return &result, fmt.Errorf("%s invalid RabbitMQ queue type %s, accepted values are classic and quorum", errorMessagePrefix, val)
+		}
+		result.queueType = val
+	}
+
+	if val, found := pubSubMetadata.Properties[metadataMaxLenKey]; found && val != "" {
+		intVal, err := strconv.ParseInt(val, 10, 64)
+		if err != nil || intVal <= 0 {
+			return &result, fmt.Errorf("%s invalid RabbitMQ queue max length %s", errorMessagePrefix, val)
+		}
+		result.maxLen = intVal
+	}
+
+	if val, found := pubSubMetadata.Properties[metadataMaxLenBytesKey]; found && val != "" {
+		intVal, err := strconv.ParseInt(val, 10, 64)
+		if err != nil || intVal <= 0 {
+			return &result, fmt.Errorf("%s invalid RabbitMQ queue max length bytes %s", errorMessagePrefix, val)
+		}
+		result.maxLenBytes = intVal
+	}
+
+	ttl, ok, err := contrib_metadata.TryGetTTL(pubSubMetadata.Properties)
+	if err != nil {
+		return &result, fmt.Errorf("%s invalid RabbitMQ queue message TTL: %s", errorMessagePrefix, err)
+	}
+	if ok {
+		result.messageTTL = ttl
+	}
+
+	if val, found := pubSubMetadata.Properties[metadataDeadLetterExchangeKey]; found && val != "" {
+		result.deadLetterExchange = val
+	}
+
+	if val, found := pubSubMetadata.Properties[metadataDeadLetterRoutingKeyKey]; found && val != "" {
+		result.deadLetterRoutingKey = val
+	}
+
+	if val, found := pubSubMetadata.Properties[metadataManagementURLKey]; found && val != "" {
+		if _, err := url.Parse(val); err != nil {
+			return &result, fmt.Errorf("%s invalid RabbitMQ management URL %s: %s", errorMessagePrefix, val, err)
+		}
+		result.managementURL = strings.TrimSuffix(val, "/")
+	}
+
 	c, err := pubsub.Concurrency(pubSubMetadata.Properties)
 	if err != nil {
 		return &result, err
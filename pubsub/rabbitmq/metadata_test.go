@@ -3,8 +3,11 @@ package rabbitmq
 import (
 	"fmt"
 	"testing"
+	"time"
 
+	contrib_metadata "github.com/dapr/components-contrib/metadata"
 	"github.com/dapr/components-contrib/pubsub"
+	"github.com/streadway/amqp"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -138,6 +141,95 @@ func TestCreateMetadata(t *testing.T) {
 		assert.Equal(t, uint8(1), m.prefetchCount)
 	})
 
+	t.Run("exchange and queue options are set", func(t *testing.T) {
+		fakeProperties := getFakeProperties()
+
+		fakeMetaData := pubsub.Metadata{
+			Properties: fakeProperties,
+		}
+		fakeMetaData.Properties[metadataExchangeKindKey] = "topic"
+		fakeMetaData.Properties[metadataPublisherConfirmKey] = "true"
+		fakeMetaData.Properties[metadataPublisherConfirmTimeoutSeconds] = "2"
+		fakeMetaData.Properties[metadataQueueTypeKey] = "quorum"
+		fakeMetaData.Properties[metadataMaxLenBytesKey] = "1024"
+		fakeMetaData.Properties[metadataDeadLetterExchangeKey] = "dlx"
+		fakeMetaData.Properties[metadataDeadLetterRoutingKeyKey] = "failed"
+
+		// act
+		m, err := createMetadata(fakeMetaData)
+
+		// assert
+		assert.NoError(t, err)
+		assert.Equal(t, "topic", m.exchangeKind)
+		assert.Equal(t, true, m.publisherConfirm)
+		assert.Equal(t, 2*time.Second, m.publisherConfirmTimeout)
+		assert.Equal(t, "quorum", m.queueType)
+		assert.Equal(t, false, m.deleteWhenUnused)
+		assert.Equal(t, amqp.Table{
+			"x-queue-type":              "quorum",
+			"x-max-length-bytes":        int64(1024),
+			"x-dead-letter-exchange":    "dlx",
+			"x-dead-letter-routing-key": "failed",
+		}, m.queueArgs())
+	})
+
+	t.Run("default exchange and queue options", func(t *testing.T) {
+		fakeMetaData := pubsub.Metadata{
+			Properties: getFakeProperties(),
+		}
+
+		// act
+		m, err := createMetadata(fakeMetaData)
+
+		// assert
+		assert.NoError(t, err)
+		assert.Equal(t, "fanout", m.exchangeKind)
+		assert.Equal(t, false, m.publisherConfirm)
+		assert.Equal(t, 5*time.Second, m.publisherConfirmTimeout)
+		assert.Nil(t, m.queueArgs())
+	})
+
+	invalidOptions := []struct {
+		key   string
+		value string
+	}{
+		{metadataExchangeKindKey, "x-delayed"},
+		{metadataQueueTypeKey, "stream"},
+		{metadataPublisherConfirmTimeoutSeconds, "0"},
+		{metadataMaxLenKey, "many"},
+		{metadataMaxLenBytesKey, "-1"},
+		{contrib_metadata.TTLMetadataKey, "soon"},
+	}
+
+	for _, tt := range invalidOptions {
+		t.Run(fmt.Sprintf("%s value=%s", tt.key, tt.value), func(t *testing.T) {
+			fakeMetaData := pubsub.Metadata{
+				Properties: getFakeProperties(),
+			}
+			fakeMetaData.Properties[tt.key] = tt.value
+
+			// act
+			_, err := createMetadata(fakeMetaData)
+
+			// assert
+			assert.Error(t, err)
+		})
+	}
+
+	t.Run("quorum queue cannot be deleted when unused", func(t *testing.T) {
+		fakeMetaData := pubsub.Metadata{
+			Properties: getFakeProperties(),
+		}
+		fakeMetaData.Properties[metadataQueueTypeKey] = "quorum"
+		fakeMetaData.Properties[metadataDeleteWhenUnusedKey] = "true"
+
+		// act
+		_, err := createMetadata(fakeMetaData)
+
+		// assert
+		assert.EqualError(t, err, "rabbitmq pub/sub error: quorum queues cannot be deleted when unused")
+	})
+
 	for _, tt := range booleanFlagTests {
 		t.Run(fmt.Sprintf("autoAck value=%s", tt.in), func(t *testing.T) {
 			fakeProperties := getFakeProperties()
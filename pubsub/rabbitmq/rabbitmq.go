@@ -3,11 +3,13 @@ package rabbitmq
 import (
 	"errors"
 	"fmt"
+	"reflect"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
 
+	contrib_metadata "github.com/dapr/components-contrib/metadata"
 	"github.com/dapr/components-contrib/pubsub"
 	"github.com/dapr/dapr/pkg/logger"
 	"github.com/streadway/amqp"
@@ -15,6 +17,11 @@ import (
 
 const (
 	fanoutExchangeKind     = "fanout"
+	topicExchangeKind      = "topic"
+	directExchangeKind     = "direct"
+	headersExchangeKind    = "headers"
+	classicQueueType       = "classic"
+	quorumQueueType        = "quorum"
 	logMessagePrefix       = "rabbitmq pub/sub:"
 	errorMessagePrefix     = "rabbitmq pub/sub error:"
 	errorChannelConnection = "channel/connection is not open"
@@ -29,6 +36,34 @@ const (
 
 	defaultReconnectWaitSeconds = 10
 	metadataprefetchCount       = "prefetchCount"
+
+	metadataExchangeKindKey                = "exchangeKind"
+	metadataPublisherConfirmKey            = "publisherConfirm"
+	metadataPublisherConfirmTimeoutSeconds = "publisherConfirmTimeoutSeconds"
+	metadataQueueTypeKey                   = "queueType"
+	metadataMaxLenKey                      = "maxLen"
+	metadataMaxLenBytesKey                 = "maxLenBytes"
+	metadataDeadLetterExchangeKey          = "deadLetterExchange"
+	metadataDeadLetterRoutingKeyKey        = "deadLetterRoutingKey"
+	metadataManagementURLKey               = "managementURL"
+
+	defaultPublisherConfirmTimeoutSeconds = 5
+
+	// Publish and subscribe request metadata
+	metadataRoutingKey       = "routingKey"
+	metadataContentTypeKey   = "contentType"
+	metadataHeadersMatchKey  = "headersMatch"
+	metadataHeaderPrefix     = "header."
+	defaultContentType       = "text/plain"
+	topicExchangeMatchAllKey = "#"
+	filterExchangeSuffix     = "-filter"
+
+	argQueueType            = "x-queue-type"
+	argMaxLength            = "x-max-length"
+	argMaxLengthBytes       = "x-max-length-bytes"
+	argMessageTTL           = "x-message-ttl"
+	argDeadLetterExchange   = "x-dead-letter-exchange"
+	argDeadLetterRoutingKey = "x-dead-letter-routing-key"
 )
 
 // RabbitMQ allows sending/receiving messages in pub/sub format
@@ -41,6 +76,15 @@ type rabbitMQ struct {
 	metadata          *metadata
 	declaredExchanges map[string]bool
 
+	// filterBindings keeps the header match that each queue was bound to its
+	// filter exchange with by this instance, nil when it has no filter
+	filterBindings map[string]amqp.Table
+
+	// Publishes are serialized when publisher confirms are enabled so that
+	// confirmations can be matched to messages by delivery tag.
+	confirms     *publisherConfirms
+	publishMutex sync.Mutex
+
 	connectionDial func(host string) (rabbitMQConnectionBroker, rabbitMQChannelBroker, error)
 
 	logger logger.Logger
@@ -51,11 +95,16 @@ type rabbitMQChannelBroker interface {
 	Publish(exchange string, key string, mandatory bool, immediate bool, msg amqp.Publishing) error
 	QueueDeclare(name string, durable bool, autoDelete bool, exclusive bool, noWait bool, args amqp.Table) (amqp.Queue, error)
 	QueueBind(name string, key string, exchange string, noWait bool, args amqp.Table) error
+	QueueUnbind(name string, key string, exchange string, args amqp.Table) error
 	Consume(queue string, consumer string, autoAck bool, exclusive bool, noLocal bool, noWait bool, args amqp.Table) (<-chan amqp.Delivery, error)
 	Nack(tag uint64, multiple bool, requeue bool) error
 	Ack(tag uint64, multiple bool) error
 	ExchangeDeclare(name string, kind string, durable bool, autoDelete bool, internal bool, noWait bool, args amqp.Table) error
+	ExchangeBind(destination string, key string, source string, noWait bool, args amqp.Table) error
+	ExchangeDelete(name string, ifUnused bool, noWait bool) error
 	Qos(prefetchCount, prefetchSize int, global bool) error
+	Confirm(noWait bool) error
+	NotifyPublish(confirm chan amqp.Confirmation) chan amqp.Confirmation
 }
 
 // interface used to allow unit testing
@@ -67,6 +116,7 @@ type rabbitMQConnectionBroker interface {
 func NewRabbitMQ(logger logger.Logger) pubsub.PubSub {
 	return &rabbitMQ{
 		declaredExchanges: make(map[string]bool),
+		filterBindings:    make(map[string]amqp.Table),
 		stopped:           false,
 		logger:            logger,
 		connectionDial:    dial,
@@ -135,6 +185,17 @@ func (r *rabbitMQ) reconnect(connectionCount int) error {
 		return err
 	}
 
+	if r.metadata.publisherConfirm {
+		if err = ch.Confirm(false); err != nil {
+			r.reset()
+
+			return err
+		}
+		r.confirms = &publisherConfirms{
+			confirms: ch.NotifyPublish(make(chan amqp.Confirmation, 16)),
+		}
+	}
+
 	r.connection = conn
 	r.channel = ch
 	r.connectionCount++
@@ -172,13 +233,28 @@ func (r *rabbitMQ) Publish(req *pubsub.PublishRequest) error {
 		return err
 	}
 
-	r.logger.Debugf("%s publishing message to topic '%s'", logMessagePrefix, req.Topic)
+	routingKey, msg, err := r.publishing(req)
+	if err != nil {
+		return err
+	}
+
+	var confirms *publisherConfirms
+	if r.metadata.publisherConfirm {
+		r.publishMutex.Lock()
+		defer r.publishMutex.Unlock()
 
-	err = channel.Publish(req.Topic, "", false, false, amqp.Publishing{
-		ContentType:  "text/plain",
-		Body:         req.Data,
-		DeliveryMode: r.metadata.deliveryMode,
-	})
+		confirms = r.getPublisherConfirms(connectionCount)
+		if confirms == nil {
+			return fmt.Errorf("%s %s", errorMessagePrefix, errorChannelConnection)
+		}
+	}
+
+	r.logger.Debugf("%s publishing message to topic '%s' with routing key '%s'", logMessagePrefix, req.Topic, routingKey)
+
+	err = channel.Publish(req.Topic, routingKey, false, false, msg)
+	if err == nil && confirms != nil {
+		err = confirms.wait(r.metadata.publisherConfirmTimeout)
+	}
 
 	if err != nil {
 		if mustReconnect(channel, err) {
@@ -192,7 +268,97 @@ func (r *rabbitMQ) Publish(req *pubsub.PublishRequest) error {
 	return nil
 }
 
+// publishing builds the AMQP message for a publish request. The routingKey,
+// contentType and ttlInSeconds metadata are applied to the message, any other
+// metadata is sent as message headers.
+func (r *rabbitMQ) publishing(req *pubsub.PublishRequest) (string, amqp.Publishing, error) {
+	msg := amqp.Publishing{
+		ContentType:  defaultContentType,
+		Headers:      filterHeaders(req.Data),
+		Body:         req.Data,
+		DeliveryMode: r.metadata.deliveryMode,
+	}
+
+	ttl, ok, err := contrib_metadata.TryGetTTL(req.Metadata)
+	if err != nil {
+		return "", msg, err
+	}
+	if ok {
+		msg.Expiration = strconv.FormatInt(ttl.Milliseconds(), 10)
+	}
+
+	var routingKey string
+	for k, v := range req.Metadata {
+		switch k {
+		case metadataRoutingKey:
+			routingKey = v
+		case metadataContentTypeKey:
+			if v != "" {
+				msg.ContentType = v
+			}
+		case contrib_metadata.TTLMetadataKey:
+		default:
+			if msg.Headers == nil {
+				msg.Headers = amqp.Table{}
+			}
+			msg.Headers[k] = v
+		}
+	}
+
+	return routingKey, msg, nil
+}
+
+func (r *rabbitMQ) getPublisherConfirms(connectionCount int) *publisherConfirms {
+	r.channelMutex.RLock()
+	defer r.channelMutex.RUnlock()
+
+	if connectionCount != r.connectionCount {
+		return nil
+	}
+
+	return r.confirms
+}
+
+// publisherConfirms tracks the confirmations of a channel in confirm mode.
+type publisherConfirms struct {
+	confirms    chan amqp.Confirmation
+	deliveryTag uint64
+}
+
+// wait waits for the confirmation of the last published message.
+// Confirmations of earlier messages that timed out are skipped.
+func (c *publisherConfirms) wait(timeout time.Duration) error {
+	c.deliveryTag++
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for {
+		select {
+		case confirm, ok := <-c.confirms:
+			if !ok {
+				return errors.New(errorChannelConnection)
+			}
+			if confirm.DeliveryTag < c.deliveryTag {
+				continue
+			}
+			if !confirm.Ack {
+				return fmt.Errorf("%s message was rejected by the broker", errorMessagePrefix)
+			}
+
+			return nil
+		case <-timer.C:
+			return fmt.Errorf("%s timed out waiting for publisher confirm", errorMessagePrefix)
+		}
+	}
+}
+
 func (r *rabbitMQ) Subscribe(req pubsub.SubscribeRequest, handler func(msg *pubsub.NewMessage) error) error {
+	handler, err := pubsub.FilterHandler(req, handler)
+	if err != nil {
+		return err
+	}
+
 	if r.metadata.consumerID == "" {
 		return errors.New("consumerID is required for subscriptions")
 	}
@@ -211,7 +377,7 @@ func (r *rabbitMQ) prepareSubscription(channel rabbitMQChannelBroker, req pubsub
 	}
 
 	r.logger.Debugf("%s declaring queue '%s'", logMessagePrefix, queueName)
-	q, err := channel.QueueDeclare(queueName, true, r.metadata.deleteWhenUnused, false, false, nil)
+	q, err := channel.QueueDeclare(queueName, true, r.metadata.deleteWhenUnused, false, false, r.metadata.queueArgs())
 	if err != nil {
 		return nil, err
 	}
@@ -224,15 +390,167 @@ func (r *rabbitMQ) prepareSubscription(channel rabbitMQChannelBroker, req pubsub
 		}
 	}
 
-	r.logger.Debugf("%s binding queue '%s' to exchange '%s'", logMessagePrefix, q.Name, req.Topic)
-	err = channel.QueueBind(q.Name, "", req.Topic, false, nil)
+	keys, args := r.bindings(req)
+
+	filter, err := pubsub.FilterFromMetadata(req.Metadata)
 	if err != nil {
 		return nil, err
 	}
+	if filter != nil {
+		if equalities, ok := filter.AttributeEqualities(); ok {
+			err = r.bindFilteredQueue(channel, q.Name, req.Topic, keys, args, equalities)
+			if err != nil {
+				return nil, err
+			}
+
+			return &q, nil
+		}
+	}
+
+	for _, key := range keys {
+		r.logger.Debugf("%s binding queue '%s' to exchange '%s' with routing key '%s'", logMessagePrefix, q.Name, req.Topic, key)
+		err = channel.QueueBind(q.Name, key, req.Topic, false, args)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// The filter of a previous subscription would keep routing its matches to the queue
+	if previous, known := r.filterBinding(q.Name); !known || previous != nil {
+		err = r.deleteFilterExchange(channel, q.Name)
+		if err != nil {
+			return nil, err
+		}
+	}
+	r.putFilterBinding(q.Name, nil)
 
 	return &q, nil
 }
 
+// bindings returns the routing keys and arguments used to bind a subscription
+// to the topic exchange. Routing keys are taken from the comma separated
+// routingKey subscription metadata. Headers exchanges match on the metadata
+// prefixed with header., using the headersMatch metadata as x-match.
+func (r *rabbitMQ) bindings(req pubsub.SubscribeRequest) ([]string, amqp.Table) {
+	if r.metadata.exchangeKind == headersExchangeKind {
+		args := amqp.Table{"x-match": "all"}
+		for k, v := range req.Metadata {
+			if strings.HasPrefix(k, metadataHeaderPrefix) {
+				args[strings.TrimPrefix(k, metadataHeaderPrefix)] = v
+			}
+		}
+		if val, ok := req.Metadata[metadataHeadersMatchKey]; ok && val != "" {
+			args["x-match"] = val
+		}
+
+		return []string{""}, args
+	}
+
+	var keys []string
+	for _, key := range strings.Split(req.Metadata[metadataRoutingKey], ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) == 0 {
+		if r.metadata.exchangeKind == topicExchangeKind {
+			keys = []string{topicExchangeMatchAllKey}
+		} else {
+			keys = []string{""}
+		}
+	}
+
+	return keys, nil
+}
+
+// bindFilteredQueue pushes a subscription filter down to the broker: the queue is
+// bound to a headers exchange matching the CloudEvent attributes that publishers
+// attach as message headers, and that exchange is bound to the topic exchange.
+// The queue is unbound from the topic exchange once the filter is in place, so
+// that it stops receiving the unfiltered messages.
+//
+// When the filter changed, the new header match is bound before the previous one
+// is unbound, so that no message is dropped on resubscribe. The header match of a
+// filter set before a restart is unknown, so the first subscription of the queue
+// recreates the filter exchange instead, while the queue is still bound to the
+// topic exchange. Extra messages received meanwhile are filtered by the subscriber.
+func (r *rabbitMQ) bindFilteredQueue(channel rabbitMQChannelBroker, queueName string, topic string, keys []string, bindingArgs amqp.Table, equalities map[string]string) error {
+	filterExchange := queueName + filterExchangeSuffix
+	args := amqp.Table{"x-match": "all"}
+	for attribute, value := range equalities {
+		args[pubsub.FilterAttributePrefix+attribute] = value
+	}
+
+	previous, known := r.filterBinding(queueName)
+	if !known {
+		for _, key := range keys {
+			r.logger.Debugf("%s binding queue '%s' to exchange '%s' with routing key '%s'", logMessagePrefix, queueName, topic, key)
+			err := channel.QueueBind(queueName, key, topic, false, bindingArgs)
+			if err != nil {
+				return err
+			}
+		}
+
+		err := r.deleteFilterExchange(channel, queueName)
+		if err != nil {
+			return err
+		}
+	}
+
+	r.logger.Debugf("%s declaring exchange '%s' of kind '%s'", logMessagePrefix, filterExchange, headersExchangeKind)
+	err := channel.ExchangeDeclare(filterExchange, headersExchangeKind, true, r.metadata.deleteWhenUnused, false, false, nil)
+	if err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		r.logger.Debugf("%s binding exchange '%s' to exchange '%s' with routing key '%s'", logMessagePrefix, filterExchange, topic, key)
+		err = channel.ExchangeBind(filterExchange, key, topic, false, bindingArgs)
+		if err != nil {
+			return err
+		}
+	}
+
+	r.logger.Debugf("%s binding queue '%s' to exchange '%s' with filter %v", logMessagePrefix, queueName, filterExchange, args)
+	err = channel.QueueBind(queueName, "", filterExchange, false, args)
+	if err != nil {
+		return err
+	}
+
+	if previous != nil && !reflect.DeepEqual(previous, args) {
+		r.logger.Debugf("%s unbinding queue '%s' from exchange '%s' with filter %v", logMessagePrefix, queueName, filterExchange, previous)
+		err = channel.QueueUnbind(queueName, "", filterExchange, previous)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, key := range keys {
+		r.logger.Debugf("%s unbinding queue '%s' from exchange '%s' with routing key '%s'", logMessagePrefix, queueName, topic, key)
+		err = channel.QueueUnbind(queueName, key, topic, bindingArgs)
+		if err != nil {
+			return err
+		}
+	}
+	r.putFilterBinding(queueName, args)
+
+	return nil
+}
+
+// deleteFilterExchange deletes the filter exchange of a queue, with all its
+// bindings. It is declared first, as deleting a missing exchange closes the channel.
+func (r *rabbitMQ) deleteFilterExchange(channel rabbitMQChannelBroker, queueName string) error {
+	filterExchange := queueName + filterExchangeSuffix
+	err := channel.ExchangeDeclare(filterExchange, headersExchangeKind, true, r.metadata.deleteWhenUnused, false, false, nil)
+	if err != nil {
+		return err
+	}
+
+	r.logger.Debugf("%s deleting exchange '%s'", logMessagePrefix, filterExchange)
+
+	return channel.ExchangeDelete(filterExchange, false, false)
+}
+
 func (r *rabbitMQ) subscribeForever(
 	req pubsub.SubscribeRequest,
 	queueName string,
@@ -312,6 +630,9 @@ func (r *rabbitMQ) handleMessage(channel rabbitMQChannelBroker, d amqp.Delivery,
 		Data:  d.Body,
 		Topic: topic,
 	}
+	if d.RoutingKey != "" {
+		pubsubMsg.Metadata = map[string]string{metadataRoutingKey: d.RoutingKey}
+	}
 
 	err := handler(pubsubMsg)
 	if err != nil {
@@ -341,8 +662,8 @@ func (r *rabbitMQ) handleMessage(channel rabbitMQChannelBroker, d amqp.Delivery,
 
 func (r *rabbitMQ) ensureExchangeDeclared(channel rabbitMQChannelBroker, exchange string) error {
 	if !r.containsExchange(exchange) {
-		r.logger.Debugf("%s declaring exchange '%s' of kind '%s'", logMessagePrefix, exchange, fanoutExchangeKind)
-		err := channel.ExchangeDeclare(exchange, fanoutExchangeKind, true, false, false, false, nil)
+		r.logger.Debugf("%s declaring exchange '%s' of kind '%s'", logMessagePrefix, exchange, r.metadata.exchangeKind)
+		err := channel.ExchangeDeclare(exchange, r.metadata.exchangeKind, true, false, false, false, nil)
 		if err != nil {
 			return err
 		}
@@ -369,10 +690,29 @@ func (r *rabbitMQ) putExchange(exchange string) {
 	r.declaredExchanges[exchange] = true
 }
 
+// filterBinding returns the header match that queue was bound to its filter
+// exchange with, and false when it wasn't subscribed by this instance yet.
+func (r *rabbitMQ) filterBinding(queueName string) (amqp.Table, bool) {
+	r.channelMutex.RLock()
+	defer r.channelMutex.RUnlock()
+
+	args, known := r.filterBindings[queueName]
+
+	return args, known
+}
+
+func (r *rabbitMQ) putFilterBinding(queueName string, args amqp.Table) {
+	r.channelMutex.Lock()
+	defer r.channelMutex.Unlock()
+
+	r.filterBindings[queueName] = args
+}
+
 func (r *rabbitMQ) reset() error {
 	conn := r.connection
 	r.connection = nil
 	r.channel = nil
+	r.confirms = nil
 	if len(r.declaredExchanges) > 0 {
 		r.declaredExchanges = make(map[string]bool)
 	}
@@ -395,7 +735,22 @@ func (r *rabbitMQ) Close() error {
 }
 
 func (r *rabbitMQ) Features() []pubsub.Feature {
-	return nil
+	return []pubsub.Feature{pubsub.FeatureMessageTTL}
+}
+
+// filterHeaders returns the message headers used by subscriptions with pushed down filters.
+func filterHeaders(data []byte) amqp.Table {
+	properties := pubsub.FilterProperties(data)
+	if len(properties) == 0 {
+		return nil
+	}
+
+	headers := make(amqp.Table, len(properties))
+	for k, v := range properties {
+		headers[k] = v
+	}
+
+	return headers
 }
 
 func mustReconnect(channel rabbitMQChannelBroker, err error) bool {
@@ -2,12 +2,17 @@ package rabbitmq
 
 import (
 	"errors"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 
+	contrib_metadata "github.com/dapr/components-contrib/metadata"
 	"github.com/dapr/components-contrib/pubsub"
 	"github.com/dapr/dapr/pkg/logger"
 	"github.com/streadway/amqp"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func newBroker() *rabbitMQInMemoryBroker {
@@ -19,6 +24,7 @@ func newBroker() *rabbitMQInMemoryBroker {
 func newRabbitMQTest(broker *rabbitMQInMemoryBroker) pubsub.PubSub {
 	return &rabbitMQ{
 		declaredExchanges: make(map[string]bool),
+		filterBindings:    make(map[string]amqp.Table),
 		stopped:           false,
 		logger:            logger.NewLogger("test"),
 		connectionDial: func(host string) (rabbitMQConnectionBroker, rabbitMQChannelBroker, error) {
@@ -291,13 +297,249 @@ func TestSubscribeReconnect(t *testing.T) {
 	assert.Equal(t, 1, broker.closeCount)
 }
 
-func createAMQPMessage(body []byte) amqp.Delivery {
-	return amqp.Delivery{Body: body}
+func TestSubscriptionFilterPushdown(t *testing.T) {
+	broker := newBroker()
+	pubsubRabbitMQ := newRabbitMQTest(broker)
+	metadata := pubsub.Metadata{
+		Properties: map[string]string{
+			metadataHostKey:       "anyhost",
+			metadataConsumerIDKey: "consumer",
+		},
+	}
+	err := pubsubRabbitMQ.Init(metadata)
+	assert.Nil(t, err)
+
+	r := pubsubRabbitMQ.(*rabbitMQ)
+	t.Run("attribute equalities bind through a headers exchange", func(t *testing.T) {
+		req := pubsub.SubscribeRequest{Topic: "orders", Metadata: map[string]string{
+			pubsub.FilterMetadataKey: "type = 'order.created'",
+		}}
+		_, err := r.prepareSubscription(broker, req, "consumer-orders")
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"consumer-orders-filter->orders"}, broker.exchangeBindings)
+		assert.Equal(t, amqp.Table{"x-match": "all", "ce_type": "order.created"}, broker.queueBindings["consumer-orders->consumer-orders-filter"])
+	})
+
+	t.Run("data filters bind to the topic exchange", func(t *testing.T) {
+		req := pubsub.SubscribeRequest{Topic: "payments", Metadata: map[string]string{
+			pubsub.FilterMetadataKey: "data.amount > 10",
+		}}
+		_, err := r.prepareSubscription(broker, req, "consumer-payments")
+		assert.Nil(t, err)
+		assert.Contains(t, broker.queueBindings, "consumer-payments->payments")
+	})
+
+	t.Run("changed filters replace the previous bindings", func(t *testing.T) {
+		req := pubsub.SubscribeRequest{Topic: "invoices"}
+		_, err := r.prepareSubscription(broker, req, "consumer-invoices")
+		require.NoError(t, err)
+		assert.Contains(t, broker.queueBindings, "consumer-invoices->invoices")
+
+		req.Metadata = map[string]string{pubsub.FilterMetadataKey: "type = 'invoice.paid'"}
+		_, err = r.prepareSubscription(broker, req, "consumer-invoices")
+		require.NoError(t, err)
+		assert.NotContains(t, broker.queueBindings, "consumer-invoices->invoices")
+		assert.Equal(t, amqp.Table{"x-match": "all", "ce_type": "invoice.paid"}, broker.queueBindings["consumer-invoices->consumer-invoices-filter"])
+
+		// The header match is replaced without deleting the filter exchange
+		deleted := len(broker.deletedExchanges)
+		req.Metadata = map[string]string{pubsub.FilterMetadataKey: "type = 'invoice.voided'"}
+		_, err = r.prepareSubscription(broker, req, "consumer-invoices")
+		require.NoError(t, err)
+		assert.Equal(t, amqp.Table{"x-match": "all", "ce_type": "invoice.voided"}, broker.queueBindings["consumer-invoices->consumer-invoices-filter"])
+		assert.Contains(t, broker.exchangeBindings, "consumer-invoices-filter->invoices")
+		assert.Len(t, broker.deletedExchanges, deleted)
+
+		req.Metadata = nil
+		_, err = r.prepareSubscription(broker, req, "consumer-invoices")
+		require.NoError(t, err)
+		assert.Contains(t, broker.queueBindings, "consumer-invoices->invoices")
+		assert.NotContains(t, broker.queueBindings, "consumer-invoices->consumer-invoices-filter")
+		assert.NotContains(t, broker.exchangeBindings, "consumer-invoices-filter->invoices")
+	})
+
+	t.Run("resubscribing keeps the filter exchange", func(t *testing.T) {
+		req := pubsub.SubscribeRequest{Topic: "refunds", Metadata: map[string]string{
+			pubsub.FilterMetadataKey: "type = 'refund.issued'",
+		}}
+		_, err := r.prepareSubscription(broker, req, "consumer-refunds")
+		require.NoError(t, err)
+		deleted := len(broker.deletedExchanges)
+
+		// Reconnects subscribe again with the same filter
+		_, err = r.prepareSubscription(broker, req, "consumer-refunds")
+		require.NoError(t, err)
+		assert.Len(t, broker.deletedExchanges, deleted)
+		assert.Equal(t, amqp.Table{"x-match": "all", "ce_type": "refund.issued"}, broker.queueBindings["consumer-refunds->consumer-refunds-filter"])
+		assert.NotContains(t, broker.queueBindings, "consumer-refunds->refunds")
+	})
+
+	t.Run("the queue stays bound while the filter exchange is recreated", func(t *testing.T) {
+		// A restarted instance doesn't know the filter the queue was bound with
+		restarted := newRabbitMQTest(broker).(*rabbitMQ)
+		require.NoError(t, restarted.Init(metadata))
+		req := pubsub.SubscribeRequest{Topic: "refunds", Metadata: map[string]string{
+			pubsub.FilterMetadataKey: "type = 'refund.issued'",
+		}}
+		broker.bindingKeys = nil
+		_, err := restarted.prepareSubscription(broker, req, "consumer-refunds")
+		require.NoError(t, err)
+		assert.Equal(t, "consumer-refunds->refunds:", broker.bindingKeys[0])
+		assert.Equal(t, "consumer-refunds-filter", broker.deletedExchanges[len(broker.deletedExchanges)-1])
+		assert.Equal(t, amqp.Table{"x-match": "all", "ce_type": "refund.issued"}, broker.queueBindings["consumer-refunds->consumer-refunds-filter"])
+		assert.NotContains(t, broker.queueBindings, "consumer-refunds->refunds")
+	})
+}
+
+func TestFilterHeaders(t *testing.T) {
+	assert.Equal(t, amqp.Table{"ce_type": "order.created"}, filterHeaders([]byte(`{"type":"order.created"}`)))
+	assert.Nil(t, filterHeaders([]byte("raw")))
+}
+
+func TestExchangeKindRouting(t *testing.T) {
+	t.Run("topic exchange binds routing keys", func(t *testing.T) {
+		broker := newBroker()
+		pubsubRabbitMQ := newRabbitMQTest(broker)
+		err := pubsubRabbitMQ.Init(pubsub.Metadata{Properties: map[string]string{
+			metadataHostKey:         "anyhost",
+			metadataConsumerIDKey:   "consumer",
+			metadataExchangeKindKey: "topic",
+		}})
+		require.NoError(t, err)
+
+		r := pubsubRabbitMQ.(*rabbitMQ)
+		req := pubsub.SubscribeRequest{Topic: "orders", Metadata: map[string]string{metadataRoutingKey: "eu.*, us.#"}}
+		_, err = r.prepareSubscription(broker, req, "consumer-orders")
+		require.NoError(t, err)
+		assert.Equal(t, "topic", broker.declaredKinds["orders"])
+		assert.Equal(t, []string{"consumer-orders->orders:eu.*", "consumer-orders->orders:us.#"}, broker.bindingKeys)
+
+		broker.bindingKeys = nil
+		_, err = r.prepareSubscription(broker, pubsub.SubscribeRequest{Topic: "orders"}, "consumer-orders")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"consumer-orders->orders:#"}, broker.bindingKeys)
+	})
+
+	t.Run("headers exchange binds header arguments", func(t *testing.T) {
+		broker := newBroker()
+		pubsubRabbitMQ := newRabbitMQTest(broker)
+		err := pubsubRabbitMQ.Init(pubsub.Metadata{Properties: map[string]string{
+			metadataHostKey:         "anyhost",
+			metadataConsumerIDKey:   "consumer",
+			metadataExchangeKindKey: "headers",
+		}})
+		require.NoError(t, err)
+
+		r := pubsubRabbitMQ.(*rabbitMQ)
+		req := pubsub.SubscribeRequest{Topic: "orders", Metadata: map[string]string{
+			"header.region":         "eu",
+			metadataHeadersMatchKey: "any",
+		}}
+		_, err = r.prepareSubscription(broker, req, "consumer-orders")
+		require.NoError(t, err)
+		assert.Equal(t, amqp.Table{"x-match": "any", "region": "eu"}, broker.queueBindings["consumer-orders->orders"])
+	})
+
+	t.Run("publish passes routing key, content type, ttl and headers", func(t *testing.T) {
+		broker := &rabbitMQInMemoryBroker{}
+		pubsubRabbitMQ := newRabbitMQTest(broker)
+		err := pubsubRabbitMQ.Init(pubsub.Metadata{Properties: map[string]string{
+			metadataHostKey:         "anyhost",
+			metadataExchangeKindKey: "direct",
+		}})
+		require.NoError(t, err)
+
+		err = pubsubRabbitMQ.Publish(&pubsub.PublishRequest{Topic: "orders", Data: []byte("{}"), Metadata: map[string]string{
+			metadataRoutingKey:              "eu",
+			metadataContentTypeKey:          "application/json",
+			contrib_metadata.TTLMetadataKey: "30",
+			"tenant":                        "contoso",
+		}})
+		require.NoError(t, err)
+		assert.Equal(t, "direct", broker.declaredKinds["orders"])
+		assert.Equal(t, []string{"eu"}, broker.publishedKeys)
+		assert.Equal(t, "application/json", broker.published[0].ContentType)
+		assert.Equal(t, "30000", broker.published[0].Expiration)
+		assert.Equal(t, amqp.Table{"tenant": "contoso"}, broker.published[0].Headers)
+
+		err = pubsubRabbitMQ.Publish(&pubsub.PublishRequest{Topic: "orders", Data: []byte("raw")})
+		require.NoError(t, err)
+		assert.Equal(t, "text/plain", broker.published[1].ContentType)
+		assert.Empty(t, broker.published[1].Expiration)
+	})
+}
+
+func TestPublisherConfirms(t *testing.T) {
+	broker := &rabbitMQInMemoryBroker{}
+	pubsubRabbitMQ := newRabbitMQTest(broker)
+	err := pubsubRabbitMQ.Init(pubsub.Metadata{Properties: map[string]string{
+		metadataHostKey:                        "anyhost",
+		metadataPublisherConfirmKey:            "true",
+		metadataPublisherConfirmTimeoutSeconds: "1",
+	}})
+	require.NoError(t, err)
+
+	err = pubsubRabbitMQ.Publish(&pubsub.PublishRequest{Topic: "orders", Data: []byte("a")})
+	assert.NoError(t, err)
+	err = pubsubRabbitMQ.Publish(&pubsub.PublishRequest{Topic: "orders", Data: []byte("b")})
+	assert.NoError(t, err)
+
+	broker.nackAll = true
+	err = pubsubRabbitMQ.Publish(&pubsub.PublishRequest{Topic: "orders", Data: []byte("c")})
+	assert.EqualError(t, err, "rabbitmq pub/sub error: message was rejected by the broker")
+
+	t.Run("late confirmations are skipped", func(t *testing.T) {
+		confirms := &publisherConfirms{confirms: make(chan amqp.Confirmation, 2)}
+		err := confirms.wait(10 * time.Millisecond)
+		assert.EqualError(t, err, "rabbitmq pub/sub error: timed out waiting for publisher confirm")
+
+		confirms.confirms <- amqp.Confirmation{DeliveryTag: 1, Ack: false}
+		confirms.confirms <- amqp.Confirmation{DeliveryTag: 2, Ack: true}
+		assert.NoError(t, confirms.wait(time.Second))
+	})
+}
+
+func TestQueueArguments(t *testing.T) {
+	broker := newBroker()
+	pubsubRabbitMQ := newRabbitMQTest(broker)
+	err := pubsubRabbitMQ.Init(pubsub.Metadata{Properties: map[string]string{
+		metadataHostKey:                 "anyhost",
+		metadataConsumerIDKey:           "consumer",
+		metadataQueueTypeKey:            "quorum",
+		metadataMaxLenKey:               "1000",
+		contrib_metadata.TTLMetadataKey: "60",
+		metadataDeadLetterExchangeKey:   "dlx",
+	}})
+	require.NoError(t, err)
+
+	r := pubsubRabbitMQ.(*rabbitMQ)
+	_, err = r.prepareSubscription(broker, pubsub.SubscribeRequest{Topic: "orders"}, "consumer-orders")
+	require.NoError(t, err)
+	assert.Equal(t, amqp.Table{
+		"x-queue-type":           "quorum",
+		"x-max-length":           int64(1000),
+		"x-message-ttl":          int64(60000),
+		"x-dead-letter-exchange": "dlx",
+	}, broker.declaredQueues["consumer-orders"])
+	assert.Equal(t, []pubsub.Feature{pubsub.FeatureMessageTTL}, r.Features())
 }
 
 type rabbitMQInMemoryBroker struct {
 	buffer chan amqp.Delivery
 
+	exchangeBindings []string
+	queueBindings    map[string]amqp.Table
+	bindingKeys      []string
+	declaredKinds    map[string]string
+	deletedExchanges []string
+	declaredQueues   map[string]amqp.Table
+	published        []amqp.Publishing
+	publishedKeys    []string
+
+	confirms    chan amqp.Confirmation
+	deliveryTag uint64
+	nackAll     bool
+
 	connectCount int
 	closeCount   int
 }
@@ -311,16 +553,55 @@ func (r *rabbitMQInMemoryBroker) Publish(exchange string, key string, mandatory
 		return errors.New(errorChannelConnection)
 	}
 
-	r.buffer <- createAMQPMessage(msg.Body)
+	r.published = append(r.published, msg)
+	r.publishedKeys = append(r.publishedKeys, key)
+	if r.buffer != nil {
+		r.buffer <- amqp.Delivery{Body: msg.Body, RoutingKey: key}
+	}
+	if r.confirms != nil {
+		r.deliveryTag++
+		r.confirms <- amqp.Confirmation{DeliveryTag: r.deliveryTag, Ack: !r.nackAll}
+	}
 
 	return nil
 }
 
+func (r *rabbitMQInMemoryBroker) Confirm(noWait bool) error {
+	return nil
+}
+
+func (r *rabbitMQInMemoryBroker) NotifyPublish(confirm chan amqp.Confirmation) chan amqp.Confirmation {
+	r.confirms = confirm
+	r.deliveryTag = 0
+
+	return confirm
+}
+
 func (r *rabbitMQInMemoryBroker) QueueDeclare(name string, durable bool, autoDelete bool, exclusive bool, noWait bool, args amqp.Table) (amqp.Queue, error) {
+	if r.declaredQueues == nil {
+		r.declaredQueues = map[string]amqp.Table{}
+	}
+	r.declaredQueues[name] = args
+
 	return amqp.Queue{Name: name}, nil
 }
 
 func (r *rabbitMQInMemoryBroker) QueueBind(name string, key string, exchange string, noWait bool, args amqp.Table) error {
+	if r.queueBindings == nil {
+		r.queueBindings = map[string]amqp.Table{}
+	}
+	r.queueBindings[name+"->"+exchange] = args
+	r.bindingKeys = append(r.bindingKeys, name+"->"+exchange+":"+key)
+
+	return nil
+}
+
+func (r *rabbitMQInMemoryBroker) QueueUnbind(name string, key string, exchange string, args amqp.Table) error {
+	// Bindings are identified by their arguments too
+	if bound, ok := r.queueBindings[name+"->"+exchange]; ok && reflect.DeepEqual(bound, args) {
+		delete(r.queueBindings, name+"->"+exchange)
+	}
+
 	return nil
 }
 
@@ -337,6 +618,37 @@ func (r *rabbitMQInMemoryBroker) Ack(tag uint64, multiple bool) error {
 }
 
 func (r *rabbitMQInMemoryBroker) ExchangeDeclare(name string, kind string, durable bool, autoDelete bool, internal bool, noWait bool, args amqp.Table) error {
+	if r.declaredKinds == nil {
+		r.declaredKinds = map[string]string{}
+	}
+	r.declaredKinds[name] = kind
+
+	return nil
+}
+
+func (r *rabbitMQInMemoryBroker) ExchangeBind(destination string, key string, source string, noWait bool, args amqp.Table) error {
+	r.exchangeBindings = append(r.exchangeBindings, destination+"->"+source)
+
+	return nil
+}
+
+func (r *rabbitMQInMemoryBroker) ExchangeDelete(name string, ifUnused bool, noWait bool) error {
+	r.deletedExchanges = append(r.deletedExchanges, name)
+
+	// The bindings of the exchange are deleted with it
+	for binding := range r.queueBindings {
+		if strings.HasSuffix(binding, "->"+name) {
+			delete(r.queueBindings, binding)
+		}
+	}
+	bindings := r.exchangeBindings[:0]
+	for _, binding := range r.exchangeBindings {
+		if !strings.HasPrefix(binding, name+"->") && !strings.HasSuffix(binding, "->"+name) {
+			bindings = append(bindings, binding)
+		}
+	}
+	r.exchangeBindings = bindings
+
 	return nil
 }
 
@@ -0,0 +1,237 @@
+// ------------------------------------------------------------
+// Copyright (c) Microsoft Corporation and Dapr Contributors.
+// Licensed under the MIT License.
+// ------------------------------------------------------------
+
+package redis
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/go-redis/redis/v7"
+
+	"github.com/dapr/components-contrib/pubsub"
+)
+
+const (
+	// scanCount is the number of keys inspected by each `SCAN` when listing streams.
+	scanCount = 100
+	// rangeCount is the number of entries read by each `XRANGE` when counting undelivered entries.
+	rangeCount = 100
+)
+
+// streamGroup is a consumer group of a stream as reported by `XINFO GROUPS`.
+type streamGroup struct {
+	name            string
+	pending         int64
+	lastDeliveredID string
+	// lag is the number of entries not yet delivered to the group, only reported by Redis 7 and later
+	lag    int64
+	hasLag bool
+}
+
+// CreateTopic creates the stream of a topic with the consumer group of the
+// component, as Redis only keeps streams that have entries or groups.
+// Partitions and retention are ignored, entries are trimmed based on the
+// `maxLenApprox` and `maxAge` settings.
+func (r *redisStreams) CreateTopic(req pubsub.CreateTopicRequest) error {
+	err := r.client.XGroupCreateMkStream(req.Topic, r.metadata.consumerID, "0").Err()
+	if err != nil && !isBusyGroup(err) {
+		return fmt.Errorf("redis streams: error creating stream %s: %s", req.Topic, err)
+	}
+
+	return nil
+}
+
+// DeleteTopic deletes the stream of a topic with its entries and consumer groups.
+func (r *redisStreams) DeleteTopic(req pubsub.DeleteTopicRequest) error {
+	if err := r.client.Del(req.Topic).Err(); err != nil {
+		return fmt.Errorf("redis streams: error deleting stream %s: %s", req.Topic, err)
+	}
+
+	return nil
+}
+
+// ListTopics returns the keys holding streams. With Redis Cluster the keys of
+// every master node are scanned.
+func (r *redisStreams) ListTopics() ([]string, error) {
+	var (
+		topics []string
+		lock   sync.Mutex
+	)
+	add := func(streams []string) {
+		lock.Lock()
+		topics = append(topics, streams...)
+		lock.Unlock()
+	}
+
+	var err error
+	if cluster, ok := r.client.(*redis.ClusterClient); ok {
+		err = cluster.ForEachMaster(func(client *redis.Client) error {
+			return scanStreams(client, add)
+		})
+	} else {
+		err = scanStreams(r.client, add)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("redis streams: error listing streams: %s", err)
+	}
+	sort.Strings(topics)
+
+	return topics, nil
+}
+
+// ListSubscriptions returns the consumer groups of the stream of a topic.
+func (r *redisStreams) ListSubscriptions(topic string) ([]string, error) {
+	groups, err := r.streamGroups(topic)
+	if err != nil {
+		return nil, err
+	}
+
+	subscriptions := make([]string, 0, len(groups))
+	for _, g := range groups {
+		subscriptions = append(subscriptions, g.name)
+	}
+	sort.Strings(subscriptions)
+
+	return subscriptions, nil
+}
+
+// GetSubscriptionLag returns the number of entries of a stream that a consumer
+// group has not acknowledged, which are the pending entries and the entries
+// not delivered to the group yet.
+func (r *redisStreams) GetSubscriptionLag(req pubsub.SubscriptionLagRequest) (*pubsub.SubscriptionLagResponse, error) {
+	groups, err := r.streamGroups(req.Topic)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, g := range groups {
+		if g.name != req.Subscription {
+			continue
+		}
+
+		lag := g.lag
+		if !g.hasLag {
+			if lag, err = r.undelivered(req.Topic, g.lastDeliveredID); err != nil {
+				return nil, err
+			}
+		}
+
+		return &pubsub.SubscriptionLagResponse{
+			Topic:        req.Topic,
+			Subscription: req.Subscription,
+			Lag:          lag + g.pending,
+		}, nil
+	}
+
+	return nil, fmt.Errorf("redis streams: consumer group %s of stream %s not found", req.Subscription, req.Topic)
+}
+
+func (r *redisStreams) streamGroups(stream string) ([]streamGroup, error) {
+	res, err := r.client.Do("XINFO", "GROUPS", stream).Result()
+	if err != nil {
+		return nil, fmt.Errorf("redis streams: error retrieving consumer groups of stream %s: %s", stream, err)
+	}
+
+	return parseStreamGroups(res)
+}
+
+// undelivered counts the entries of a stream added after lastDeliveredID, for
+// servers that don't report the lag of consumer groups. The entries are read by
+// pages, as the group may be far behind.
+func (r *redisStreams) undelivered(stream, lastDeliveredID string) (int64, error) {
+	var count int64
+	start := lastDeliveredID
+	for {
+		// The range includes its start, which was counted or delivered already
+		entries, err := r.client.XRangeN(stream, start, "+", rangeCount+1).Result()
+		if err != nil {
+			return 0, fmt.Errorf("redis streams: error reading stream %s: %s", stream, err)
+		}
+
+		for _, entry := range entries {
+			if entry.ID != start {
+				count++
+			}
+		}
+		if len(entries) <= rangeCount {
+			return count, nil
+		}
+		start = entries[len(entries)-1].ID
+	}
+}
+
+// scanStreams calls add with the stream keys of a server, checking the type of
+// each batch of keys with a pipeline as `SCAN TYPE` requires Redis 6.
+func scanStreams(client redis.Cmdable, add func(streams []string)) error {
+	var cursor uint64
+	for {
+		keys, next, err := client.Scan(cursor, "", scanCount).Result()
+		if err != nil {
+			return err
+		}
+
+		if len(keys) > 0 {
+			pipe := client.Pipeline()
+			types := make([]*redis.StatusCmd, len(keys))
+			for i, key := range keys {
+				types[i] = pipe.Type(key)
+			}
+			if _, err = pipe.Exec(); err != nil {
+				return err
+			}
+
+			streams := make([]string, 0, len(keys))
+			for i, key := range keys {
+				if types[i].Val() == "stream" {
+					streams = append(streams, key)
+				}
+			}
+			add(streams)
+		}
+
+		if next == 0 {
+			return nil
+		}
+		cursor = next
+	}
+}
+
+// parseStreamGroups parses the reply of `XINFO GROUPS`, which is a list of
+// field-value lists for each consumer group.
+func parseStreamGroups(res interface{}) ([]streamGroup, error) {
+	items, ok := res.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("unexpected XINFO GROUPS reply %v", res)
+	}
+
+	groups := make([]streamGroup, 0, len(items))
+	for _, item := range items {
+		fields, ok := item.([]interface{})
+		if !ok || len(fields)%2 != 0 {
+			return nil, fmt.Errorf("unexpected XINFO GROUPS entry %v", item)
+		}
+
+		var g streamGroup
+		for i := 0; i < len(fields); i += 2 {
+			key, _ := fields[i].(string)
+			switch key {
+			case "name":
+				g.name, _ = fields[i+1].(string)
+			case "pending":
+				g.pending, _ = fields[i+1].(int64)
+			case "last-delivered-id":
+				g.lastDeliveredID, _ = fields[i+1].(string)
+			case "lag":
+				// The lag is nil when Redis can't compute it, for example after entries were deleted
+				g.lag, g.hasLag = fields[i+1].(int64)
+			}
+		}
+		groups = append(groups, g)
+	}
+
+	return groups, nil
+}
@@ -0,0 +1,105 @@
+// ------------------------------------------------------------
+// Copyright (c) Microsoft Corporation and Dapr Contributors.
+// Licensed under the MIT License.
+// ------------------------------------------------------------
+
+package redis
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v7"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/components-contrib/pubsub"
+	"github.com/dapr/dapr/pkg/logger"
+)
+
+func TestTopicAdmin(t *testing.T) {
+	s, err := miniredis.Run()
+	require.NoError(t, err)
+	defer s.Close()
+
+	testRedisStream := &redisStreams{
+		logger:   logger.NewLogger("test"),
+		client:   redis.NewClient(&redis.Options{Addr: s.Addr()}),
+		metadata: metadata{consumerID: "billing"},
+	}
+	defer testRedisStream.client.Close()
+	var admin pubsub.TopicAdmin = testRedisStream
+
+	require.NoError(t, admin.CreateTopic(pubsub.CreateTopicRequest{Topic: "orders"}))
+	require.NoError(t, admin.CreateTopic(pubsub.CreateTopicRequest{Topic: "orders"}))
+	require.NoError(t, testRedisStream.Publish(&pubsub.PublishRequest{Topic: "payments", Data: []byte("1")}))
+	s.Set("not-a-stream", "value")
+
+	topics, err := admin.ListTopics()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"orders", "payments"}, topics)
+
+	require.NoError(t, admin.DeleteTopic(pubsub.DeleteTopicRequest{Topic: "payments"}))
+	topics, err = admin.ListTopics()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"orders"}, topics)
+}
+
+func TestUndelivered(t *testing.T) {
+	s, err := miniredis.Run()
+	require.NoError(t, err)
+	defer s.Close()
+
+	testRedisStream := &redisStreams{client: redis.NewClient(&redis.Options{Addr: s.Addr()})}
+	defer testRedisStream.client.Close()
+
+	for _, id := range []string{"1-0", "2-0", "3-0"} {
+		require.NoError(t, testRedisStream.client.XAdd(&redis.XAddArgs{Stream: "orders", ID: id, Values: map[string]interface{}{"data": id}}).Err())
+	}
+
+	count, err := testRedisStream.undelivered("orders", "0-0")
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), count)
+
+	count, err = testRedisStream.undelivered("orders", "2-0")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+
+	// Long streams are read by pages
+	for i := 1; i <= 2*rangeCount+50; i++ {
+		id := fmt.Sprintf("10-%d", i)
+		require.NoError(t, testRedisStream.client.XAdd(&redis.XAddArgs{Stream: "orders", ID: id, Values: map[string]interface{}{"data": id}}).Err())
+	}
+	count, err = testRedisStream.undelivered("orders", "3-0")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2*rangeCount+50), count)
+	count, err = testRedisStream.undelivered("orders", "0-0")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2*rangeCount+53), count)
+}
+
+func TestParseStreamGroups(t *testing.T) {
+	t.Run("valid reply", func(t *testing.T) {
+		groups, err := parseStreamGroups([]interface{}{
+			[]interface{}{"name", "billing", "consumers", int64(2), "pending", int64(3), "last-delivered-id", "5-0"},
+			[]interface{}{"name", "audit", "consumers", int64(1), "pending", int64(0), "last-delivered-id", "7-0", "entries-read", int64(7), "lag", int64(4)},
+			[]interface{}{"name", "archive", "consumers", int64(0), "pending", int64(0), "last-delivered-id", "0-0", "entries-read", nil, "lag", nil},
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, []streamGroup{
+			{name: "billing", pending: 3, lastDeliveredID: "5-0"},
+			{name: "audit", lastDeliveredID: "7-0", lag: 4, hasLag: true},
+			{name: "archive", lastDeliveredID: "0-0"},
+		}, groups)
+	})
+
+	t.Run("invalid reply", func(t *testing.T) {
+		_, err := parseStreamGroups("OK")
+		assert.Error(t, err)
+
+		_, err = parseStreamGroups([]interface{}{[]interface{}{"name"}})
+		assert.Error(t, err)
+	})
+}
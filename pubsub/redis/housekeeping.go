@@ -0,0 +1,140 @@
+// ------------------------------------------------------------
+// Copyright (c) Microsoft Corporation and Dapr Contributors.
+// Licensed under the MIT License.
+// ------------------------------------------------------------
+
+package redis
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v7"
+)
+
+// streamConsumer is a member of a consumer group as reported by `XINFO CONSUMERS`.
+type streamConsumer struct {
+	name    string
+	pending int64
+	idle    time.Duration
+}
+
+// housekeepingLoop periodically trims old entries of a stream, reports the
+// number of pending messages and removes idle consumers from the consumer group
+// based on the `housekeepingInterval` setting.
+func (r *redisStreams) housekeepingLoop(stream string) {
+	if r.metadata.housekeepingInterval == 0 {
+		return
+	}
+
+	housekeepingTicker := time.NewTicker(r.metadata.housekeepingInterval)
+	defer housekeepingTicker.Stop()
+
+	for {
+		select {
+		case <-r.ctx.Done():
+			return
+
+		case <-housekeepingTicker.C:
+			r.housekeeping(stream)
+		}
+	}
+}
+
+func (r *redisStreams) housekeeping(stream string) {
+	if r.metadata.maxAge > 0 {
+		if err := r.trimByAge(stream, time.Now()); err != nil {
+			r.logger.Errorf("redis streams: error trimming stream %s: %s", stream, err)
+		}
+	}
+
+	pending, err := r.client.XPending(stream, r.metadata.consumerID).Result()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		r.logger.Errorf("redis streams: error retrieving pending messages of stream %s: %s", stream, err)
+	} else if pending != nil && pending.Count > 0 {
+		r.logger.Warnf("redis streams: %d pending messages in stream %s for consumer group %s", pending.Count, stream, r.metadata.consumerID)
+	} else if pending != nil {
+		r.logger.Debugf("redis streams: %d pending messages in stream %s for consumer group %s", pending.Count, stream, r.metadata.consumerID)
+	}
+
+	if r.metadata.consumerIdleTimeout > 0 {
+		if err := r.removeIdleConsumers(stream); err != nil {
+			r.logger.Errorf("redis streams: error removing idle consumers of stream %s: %s", stream, err)
+		}
+	}
+}
+
+// trimByAge removes the entries of a stream older than `maxAge` with `XTRIM MINID`,
+// which requires Redis 6.2 or later.
+func (r *redisStreams) trimByAge(stream string, now time.Time) error {
+	return r.client.Do("XTRIM", stream, "MINID", "~", minID(now, r.metadata.maxAge)).Err()
+}
+
+// minID returns the smallest stream entry ID that is younger than maxAge.
+func minID(now time.Time, maxAge time.Duration) string {
+	return fmt.Sprintf("%d-0", now.Add(-maxAge).UnixNano()/int64(time.Millisecond))
+}
+
+// removeIdleConsumers deletes the consumers of the group that have been idle
+// for longer than `consumerIdleTimeout`. Deleting a consumer drops its pending
+// messages, so consumers with pending messages are kept until those messages
+// have been reclaimed by `reclaimPendingMessagesLoop`.
+func (r *redisStreams) removeIdleConsumers(stream string) error {
+	res, err := r.client.Do("XINFO", "CONSUMERS", stream, r.metadata.consumerID).Result()
+	if err != nil {
+		return err
+	}
+
+	consumers, err := parseStreamConsumers(res)
+	if err != nil {
+		return err
+	}
+
+	for _, c := range consumers {
+		if c.name == r.metadata.consumerName || c.pending > 0 || c.idle < r.metadata.consumerIdleTimeout {
+			continue
+		}
+
+		if err := r.client.XGroupDelConsumer(stream, r.metadata.consumerID, c.name).Err(); err != nil {
+			return err
+		}
+		r.logger.Infof("redis streams: removed consumer %s idle for %s from stream %s", c.name, c.idle, stream)
+	}
+
+	return nil
+}
+
+// parseStreamConsumers parses the reply of `XINFO CONSUMERS`, which is a list of
+// field-value lists for each consumer.
+func parseStreamConsumers(res interface{}) ([]streamConsumer, error) {
+	items, ok := res.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("unexpected XINFO CONSUMERS reply %v", res)
+	}
+
+	consumers := make([]streamConsumer, 0, len(items))
+	for _, item := range items {
+		fields, ok := item.([]interface{})
+		if !ok || len(fields)%2 != 0 {
+			return nil, fmt.Errorf("unexpected XINFO CONSUMERS entry %v", item)
+		}
+
+		var c streamConsumer
+		for i := 0; i < len(fields); i += 2 {
+			key, _ := fields[i].(string)
+			switch key {
+			case "name":
+				c.name, _ = fields[i+1].(string)
+			case "pending":
+				c.pending, _ = fields[i+1].(int64)
+			case "idle":
+				idle, _ := fields[i+1].(int64)
+				c.idle = time.Duration(idle) * time.Millisecond
+			}
+		}
+		consumers = append(consumers, c)
+	}
+
+	return consumers, nil
+}
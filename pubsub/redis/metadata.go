@@ -7,17 +7,17 @@ package redis
 
 import (
 	"time"
+
+	contrib_redis "github.com/dapr/components-contrib/internal/component/redis"
 )
 
 type metadata struct {
-	// The Redis host
-	host string
-	// The Redis password
-	password string
+	// The connection settings shared with the other Redis components
+	contrib_redis.Settings
 	// The consumer identifier
 	consumerID string
-	// A flag to enables TLS by setting InsecureSkipVerify to true
-	enableTLS bool
+	// The name of this consumer within the consumer group (defaults to the consumer identifier)
+	consumerName string
 	// The interval between checking for pending messages to redelivery (0 disables redelivery)
 	redeliverInterval time.Duration
 	// The amount time a message must be pending before attempting to redeliver it (0 disables redelivery)
@@ -26,4 +26,12 @@ type metadata struct {
 	queueDepth uint
 	// The number of concurrent workers that are processing messages
 	concurrency uint
+	// The approximate maximum number of entries kept in a stream when publishing (0 disables trimming)
+	maxLenApprox int64
+	// The maximum age of stream entries, older entries are trimmed by housekeeping (0 disables trimming)
+	maxAge time.Duration
+	// The interval between housekeeping runs for each subscribed stream (0 disables housekeeping)
+	housekeepingInterval time.Duration
+	// The amount of time a consumer without pending messages must be idle before it is removed from the group (0 disables removal)
+	consumerIdleTimeout time.Duration
 }
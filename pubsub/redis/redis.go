@@ -7,27 +7,34 @@ package redis
 
 import (
 	"context"
-	"crypto/tls"
 	"errors"
 	"fmt"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/go-redis/redis/v7"
 
+	contrib_redis "github.com/dapr/components-contrib/internal/component/redis"
 	"github.com/dapr/components-contrib/pubsub"
 	"github.com/dapr/dapr/pkg/logger"
 )
 
 const (
-	host              = "redisHost"
-	password          = "redisPassword"
-	consumerID        = "consumerID"
-	enableTLS         = "enableTLS"
-	processingTimeout = "processingTimeout"
-	redeliverInterval = "redeliverInterval"
-	queueDepth        = "queueDepth"
-	concurrency       = "concurrency"
+	consumerID           = "consumerID"
+	consumerName         = "consumerName"
+	processingTimeout    = "processingTimeout"
+	redeliverInterval    = "redeliverInterval"
+	queueDepth           = "queueDepth"
+	concurrency          = "concurrency"
+	maxLenApprox         = "maxLenApprox"
+	maxAge               = "maxAge"
+	housekeepingInterval = "housekeepingInterval"
+	consumerIdleTimeout  = "consumerIdleTimeout"
+
+	// deliveryCountMetadataKey is the message metadata key holding the number of
+	// times a message has been delivered, as reported by `XPENDING`.
+	deliveryCountMetadataKey = "deliveryCount"
 )
 
 // redisStreams handles consuming from a Redis stream using
@@ -36,9 +43,13 @@ const (
 //
 // See https://redis.io/topics/streams-intro for more information
 // on the mechanics of Redis Streams.
+//
+// Each topic is a single stream key, so with Redis Cluster the consumer
+// group of a topic lives on the node owning its slot and every read,
+// ack and claim of that topic is routed there.
 type redisStreams struct {
 	metadata metadata
-	client   *redis.Client
+	client   redis.UniversalClient
 
 	logger logger.Logger
 
@@ -64,28 +75,18 @@ func NewRedisStreams(logger logger.Logger) pubsub.PubSub {
 func parseRedisMetadata(meta pubsub.Metadata) (metadata, error) {
 	// Default values
 	m := metadata{
-		processingTimeout: 60 * time.Second,
-		redeliverInterval: 15 * time.Second,
-		queueDepth:        100,
-		concurrency:       10,
-	}
-	if val, ok := meta.Properties[host]; ok && val != "" {
-		m.host = val
-	} else {
-		return m, errors.New("redis streams error: missing host address")
-	}
-
-	if val, ok := meta.Properties[password]; ok && val != "" {
-		m.password = val
+		processingTimeout:    60 * time.Second,
+		redeliverInterval:    15 * time.Second,
+		queueDepth:           100,
+		concurrency:          10,
+		housekeepingInterval: 60 * time.Second,
 	}
 
-	if val, ok := meta.Properties[enableTLS]; ok && val != "" {
-		tls, err := strconv.ParseBool(val)
-		if err != nil {
-			return m, fmt.Errorf("redis streams error: can't parse enableTLS field: %s", err)
-		}
-		m.enableTLS = tls
+	settings, err := contrib_redis.ParseSettings(meta.Properties, "redis streams error")
+	if err != nil {
+		return m, err
 	}
+	m.Settings = *settings
 
 	if val, ok := meta.Properties[consumerID]; ok && val != "" {
 		m.consumerID = val
@@ -93,6 +94,11 @@ func parseRedisMetadata(meta pubsub.Metadata) (metadata, error) {
 		return m, errors.New("redis streams error: missing consumerID")
 	}
 
+	m.consumerName = m.consumerID
+	if val, ok := meta.Properties[consumerName]; ok && val != "" {
+		m.consumerName = val
+	}
+
 	if val, ok := meta.Properties[processingTimeout]; ok && val != "" {
 		if processingTimeoutMs, err := strconv.ParseUint(val, 10, 64); err == nil {
 			m.processingTimeout = time.Duration(processingTimeoutMs) * time.Millisecond
@@ -113,6 +119,22 @@ func parseRedisMetadata(meta pubsub.Metadata) (metadata, error) {
 		}
 	}
 
+	for key, value := range map[string]*time.Duration{
+		maxAge:               &m.maxAge,
+		housekeepingInterval: &m.housekeepingInterval,
+		consumerIdleTimeout:  &m.consumerIdleTimeout,
+	} {
+		if val, ok := meta.Properties[key]; ok && val != "" {
+			if ms, err := strconv.ParseUint(val, 10, 64); err == nil {
+				*value = time.Duration(ms) * time.Millisecond
+			} else if d, err := time.ParseDuration(val); err == nil {
+				*value = d
+			} else {
+				return m, fmt.Errorf("redis streams error: can't parse %s field: %s", key, err)
+			}
+		}
+	}
+
 	if val, ok := meta.Properties[queueDepth]; ok && val != "" {
 		queueDepth, err := strconv.ParseUint(val, 10, 64)
 		if err != nil {
@@ -129,6 +151,14 @@ func parseRedisMetadata(meta pubsub.Metadata) (metadata, error) {
 		m.concurrency = uint(concurrency)
 	}
 
+	if val, ok := meta.Properties[maxLenApprox]; ok && val != "" {
+		maxLen, err := strconv.ParseInt(val, 10, 64)
+		if err != nil || maxLen < 0 {
+			return m, fmt.Errorf("redis streams error: can't parse maxLenApprox field: %s", val)
+		}
+		m.maxLenApprox = maxLen
+	}
+
 	return m, nil
 }
 
@@ -139,24 +169,9 @@ func (r *redisStreams) Init(metadata pubsub.Metadata) error {
 	}
 	r.metadata = m
 
-	options := &redis.Options{
-		Addr:            m.host,
-		Password:        m.password,
-		DB:              0,
-		MaxRetries:      3,
-		MaxRetryBackoff: time.Second * 2,
-	}
-
-	/* #nosec */
-	if r.metadata.enableTLS {
-		options.TLSConfig = &tls.Config{
-			InsecureSkipVerify: r.metadata.enableTLS,
-		}
-	}
-
-	client := redis.NewClient(options)
+	client := contrib_redis.NewClient(&m.Settings)
 	if _, err = client.Ping().Result(); err != nil {
-		return fmt.Errorf("redis streams: error connecting to redis at %s: %s", m.host, err)
+		return fmt.Errorf("redis streams: error connecting to redis at %s: %s", m.Host, err)
 	}
 
 	r.ctx, r.cancel = context.WithCancel(context.Background())
@@ -173,8 +188,9 @@ func (r *redisStreams) Init(metadata pubsub.Metadata) error {
 
 func (r *redisStreams) Publish(req *pubsub.PublishRequest) error {
 	_, err := r.client.XAdd(&redis.XAddArgs{
-		Stream: req.Topic,
-		Values: map[string]interface{}{"data": req.Data},
+		Stream:       req.Topic,
+		MaxLenApprox: r.metadata.maxLenApprox,
+		Values:       map[string]interface{}{"data": req.Data},
 	}).Result()
 	if err != nil {
 		return fmt.Errorf("redis streams: error from publish: %s", err)
@@ -184,9 +200,14 @@ func (r *redisStreams) Publish(req *pubsub.PublishRequest) error {
 }
 
 func (r *redisStreams) Subscribe(req pubsub.SubscribeRequest, handler func(msg *pubsub.NewMessage) error) error {
-	err := r.client.XGroupCreateMkStream(req.Topic, r.metadata.consumerID, "0").Err()
+	handler, err := pubsub.FilterHandler(req, handler)
+	if err != nil {
+		return err
+	}
+
+	err = r.client.XGroupCreateMkStream(req.Topic, r.metadata.consumerID, "0").Err()
 	// Ignore BUSYGROUP errors
-	if err != nil && err.Error() != "BUSYGROUP Consumer Group name already exists" {
+	if err != nil && !isBusyGroup(err) {
 		r.logger.Errorf("redis streams: %s", err)
 
 		return err
@@ -194,16 +215,27 @@ func (r *redisStreams) Subscribe(req pubsub.SubscribeRequest, handler func(msg *
 
 	go r.pollNewMessagesLoop(req.Topic, handler)
 	go r.reclaimPendingMessagesLoop(req.Topic, handler)
+	go r.housekeepingLoop(req.Topic)
 
 	return nil
 }
 
+// isBusyGroup returns whether err reports that the consumer group already exists.
+func isBusyGroup(err error) bool {
+	return strings.Contains(err.Error(), "BUSYGROUP")
+}
+
 // enqueueMessages is a shared function that funnels new messages (via polling)
 // and redelivered messages (via reclaiming) to a channel where workers can
-// pick them up for processing.
-func (r *redisStreams) enqueueMessages(stream string, handler func(msg *pubsub.NewMessage) error, msgs []redis.XMessage) {
+// pick them up for processing. Delivery counts default to 1 for messages that
+// are not in deliveryCounts.
+func (r *redisStreams) enqueueMessages(stream string, handler func(msg *pubsub.NewMessage) error, msgs []redis.XMessage, deliveryCounts map[string]int64) {
 	for _, msg := range msgs {
-		rmsg := createRedisMessageWrapper(stream, handler, msg)
+		deliveryCount, ok := deliveryCounts[msg.ID]
+		if !ok {
+			deliveryCount = 1
+		}
+		rmsg := createRedisMessageWrapper(stream, handler, msg, deliveryCount)
 
 		select {
 		// Might block if the queue is full so we need the r.ctx.Done below.
@@ -218,7 +250,7 @@ func (r *redisStreams) enqueueMessages(stream string, handler func(msg *pubsub.N
 
 // createRedisMessageWrapper encapsulates the Redis message, message identifier, and handler
 // in `redisMessage` for processing.
-func createRedisMessageWrapper(stream string, handler func(msg *pubsub.NewMessage) error, msg redis.XMessage) redisMessageWrapper {
+func createRedisMessageWrapper(stream string, handler func(msg *pubsub.NewMessage) error, msg redis.XMessage, deliveryCount int64) redisMessageWrapper {
 	var data []byte
 	if dataValue, exists := msg.Values["data"]; exists && dataValue != nil {
 		switch v := dataValue.(type) {
@@ -233,6 +265,9 @@ func createRedisMessageWrapper(stream string, handler func(msg *pubsub.NewMessag
 		message: pubsub.NewMessage{
 			Topic: stream,
 			Data:  data,
+			Metadata: map[string]string{
+				deliveryCountMetadataKey: strconv.FormatInt(deliveryCount, 10),
+			},
 		},
 		messageID: msg.ID,
 		handler:   handler,
@@ -282,7 +317,7 @@ func (r *redisStreams) pollNewMessagesLoop(stream string, handler func(msg *pubs
 		// Read messages
 		streams, err := r.client.XReadGroup(&redis.XReadGroupArgs{
 			Group:    r.metadata.consumerID,
-			Consumer: r.metadata.consumerID,
+			Consumer: r.metadata.consumerName,
 			Streams:  []string{stream, ">"},
 			Count:    int64(r.metadata.queueDepth),
 			Block:    0,
@@ -295,7 +330,7 @@ func (r *redisStreams) pollNewMessagesLoop(stream string, handler func(msg *pubs
 
 		// Enqueue messages for the returned streams
 		for _, s := range streams {
-			r.enqueueMessages(s.Stream, handler, s.Messages)
+			r.enqueueMessages(s.Stream, handler, s.Messages, nil)
 		}
 
 		// Return on cancelation
@@ -348,11 +383,14 @@ func (r *redisStreams) reclaimPendingMessages(stream string, handler func(msg *p
 			break
 		}
 
-		// Filter out messages that have not timed out yet
+		// Filter out messages that have not timed out yet. Claiming
+		// a message increments its delivery count.
 		msgIDs := make([]string, 0, len(pendingResult))
+		deliveryCounts := make(map[string]int64, len(pendingResult))
 		for _, msg := range pendingResult {
 			if msg.Idle >= r.metadata.processingTimeout {
 				msgIDs = append(msgIDs, msg.ID)
+				deliveryCounts[msg.ID] = msg.RetryCount + 1
 			}
 		}
 
@@ -365,7 +403,7 @@ func (r *redisStreams) reclaimPendingMessages(stream string, handler func(msg *p
 		claimResult, err := r.client.XClaim(&redis.XClaimArgs{
 			Stream:   stream,
 			Group:    r.metadata.consumerID,
-			Consumer: r.metadata.consumerID,
+			Consumer: r.metadata.consumerName,
 			MinIdle:  r.metadata.processingTimeout,
 			Messages: msgIDs,
 		}).Result()
@@ -376,7 +414,7 @@ func (r *redisStreams) reclaimPendingMessages(stream string, handler func(msg *p
 		}
 
 		// Enqueue claimed messages
-		r.enqueueMessages(stream, handler, claimResult)
+		r.enqueueMessages(stream, handler, claimResult, deliveryCounts)
 
 		// If the Redis nil error is returned, it means somes message in the pending
 		// state no longer exist. We need to acknowledge these messages to
@@ -392,20 +430,20 @@ func (r *redisStreams) reclaimPendingMessages(stream string, handler func(msg *p
 				delete(expectedMsgIDs, claimed.ID)
 			}
 
-			r.removeMessagesThatNoLongerExistFromPending(stream, expectedMsgIDs, handler)
+			r.removeMessagesThatNoLongerExistFromPending(stream, expectedMsgIDs, deliveryCounts, handler)
 		}
 	}
 }
 
 // removeMessagesThatNoLongerExistFromPending attempts to claim messages individually so that messages in the pending list
 // that no longer exist can be removed from the pending list. This is done by calling `XACK`.
-func (r *redisStreams) removeMessagesThatNoLongerExistFromPending(stream string, messageIDs map[string]struct{}, handler func(msg *pubsub.NewMessage) error) {
+func (r *redisStreams) removeMessagesThatNoLongerExistFromPending(stream string, messageIDs map[string]struct{}, deliveryCounts map[string]int64, handler func(msg *pubsub.NewMessage) error) {
 	// Check each message ID individually.
 	for pendingID := range messageIDs {
 		claimResultSingleMsg, err := r.client.XClaim(&redis.XClaimArgs{
 			Stream:   stream,
 			Group:    r.metadata.consumerID,
-			Consumer: r.metadata.consumerID,
+			Consumer: r.metadata.consumerName,
 			MinIdle:  r.metadata.processingTimeout,
 			Messages: []string{pendingID},
 		}).Result()
@@ -422,7 +460,7 @@ func (r *redisStreams) removeMessagesThatNoLongerExistFromPending(stream string,
 			}
 		} else {
 			// This should not happen but if it does the message should be processed.
-			r.enqueueMessages(stream, handler, claimResultSingleMsg)
+			r.enqueueMessages(stream, handler, claimResultSingleMsg, deliveryCounts)
 		}
 	}
 }
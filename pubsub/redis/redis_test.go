@@ -11,9 +11,12 @@ import (
 	"fmt"
 	"sync"
 	"testing"
+	"time"
 
+	"github.com/alicebob/miniredis/v2"
 	"github.com/go-redis/redis/v7"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/dapr/components-contrib/pubsub"
 	"github.com/dapr/dapr/pkg/logger"
@@ -21,10 +24,10 @@ import (
 
 func getFakeProperties() map[string]string {
 	return map[string]string{
-		consumerID: "fakeConsumer",
-		host:       "fake.redis.com",
-		password:   "fakePassword",
-		enableTLS:  "true",
+		consumerID:      "fakeConsumer",
+		"redisHost":     "fake.redis.com",
+		"redisPassword": "fakePassword",
+		"enableTLS":     "true",
 	}
 }
 
@@ -41,10 +44,10 @@ func TestParseRedisMetadata(t *testing.T) {
 
 		// assert
 		assert.NoError(t, err)
-		assert.Equal(t, fakeProperties[host], m.host)
-		assert.Equal(t, fakeProperties[password], m.password)
+		assert.Equal(t, fakeProperties["redisHost"], m.Host)
+		assert.Equal(t, fakeProperties["redisPassword"], m.Password)
 		assert.Equal(t, fakeProperties[consumerID], m.consumerID)
-		assert.Equal(t, true, m.enableTLS)
+		assert.Equal(t, true, m.EnableTLS)
 	})
 
 	t.Run("host is not given", func(t *testing.T) {
@@ -53,15 +56,15 @@ func TestParseRedisMetadata(t *testing.T) {
 		fakeMetaData := pubsub.Metadata{
 			Properties: fakeProperties,
 		}
-		fakeMetaData.Properties[host] = ""
+		fakeMetaData.Properties["redisHost"] = ""
 
 		// act
 		m, err := parseRedisMetadata(fakeMetaData)
 
 		// assert
 		assert.Error(t, errors.New("redis streams error: missing host address"), err)
-		assert.Empty(t, m.host)
-		assert.Empty(t, m.password)
+		assert.Empty(t, m.Host)
+		assert.Empty(t, m.Password)
 		assert.Empty(t, m.consumerID)
 	})
 
@@ -77,8 +80,8 @@ func TestParseRedisMetadata(t *testing.T) {
 		m, err := parseRedisMetadata(fakeMetaData)
 		// assert
 		assert.Error(t, errors.New("redis streams error: missing consumerID"), err)
-		assert.Equal(t, fakeProperties[host], m.host)
-		assert.Equal(t, fakeProperties[password], m.password)
+		assert.Equal(t, fakeProperties["redisHost"], m.Host)
+		assert.Equal(t, fakeProperties["redisPassword"], m.Password)
 		assert.Empty(t, m.consumerID)
 	})
 }
@@ -112,7 +115,7 @@ func TestProcessStreams(t *testing.T) {
 	testRedisStream.ctx, testRedisStream.cancel = context.WithCancel(context.Background())
 	testRedisStream.queue = make(chan redisMessageWrapper, 10)
 	go testRedisStream.worker()
-	testRedisStream.enqueueMessages(fakeConsumerID, fakeHandler, generateRedisStreamTestData(2, 3, expectedData))
+	testRedisStream.enqueueMessages(fakeConsumerID, fakeHandler, generateRedisStreamTestData(2, 3, expectedData), nil)
 
 	// Wait for the handler to finish processing
 	wg.Wait()
@@ -139,3 +142,114 @@ func generateRedisStreamTestData(topicCount, messageCount int, data string) []re
 
 	return xmessageArray
 }
+
+func TestParseRedisMetadataRetention(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		m, err := parseRedisMetadata(pubsub.Metadata{Properties: getFakeProperties()})
+
+		assert.NoError(t, err)
+		assert.Equal(t, "fakeConsumer", m.consumerName)
+		assert.Equal(t, int64(0), m.maxLenApprox)
+		assert.Equal(t, time.Duration(0), m.maxAge)
+		assert.Equal(t, 60*time.Second, m.housekeepingInterval)
+		assert.Equal(t, time.Duration(0), m.consumerIdleTimeout)
+	})
+
+	t.Run("retention and consumer settings", func(t *testing.T) {
+		fakeProperties := getFakeProperties()
+		fakeProperties[consumerName] = "replica-1"
+		fakeProperties[maxLenApprox] = "10000"
+		fakeProperties[maxAge] = "24h"
+		fakeProperties[housekeepingInterval] = "30000"
+		fakeProperties[consumerIdleTimeout] = "1h"
+
+		m, err := parseRedisMetadata(pubsub.Metadata{Properties: fakeProperties})
+
+		assert.NoError(t, err)
+		assert.Equal(t, "replica-1", m.consumerName)
+		assert.Equal(t, int64(10000), m.maxLenApprox)
+		assert.Equal(t, 24*time.Hour, m.maxAge)
+		assert.Equal(t, 30*time.Second, m.housekeepingInterval)
+		assert.Equal(t, time.Hour, m.consumerIdleTimeout)
+	})
+
+	t.Run("invalid values", func(t *testing.T) {
+		for key, val := range map[string]string{
+			maxLenApprox:        "-1",
+			maxAge:              "old",
+			consumerIdleTimeout: "later",
+		} {
+			fakeProperties := getFakeProperties()
+			fakeProperties[key] = val
+
+			_, err := parseRedisMetadata(pubsub.Metadata{Properties: fakeProperties})
+			assert.Error(t, err, key)
+		}
+	})
+}
+
+func TestPublishTrimsStream(t *testing.T) {
+	s, err := miniredis.Run()
+	require.NoError(t, err)
+	defer s.Close()
+
+	testRedisStream := &redisStreams{
+		logger:   logger.NewLogger("test"),
+		client:   redis.NewClient(&redis.Options{Addr: s.Addr()}),
+		metadata: metadata{maxLenApprox: 2},
+	}
+	defer testRedisStream.client.Close()
+
+	for i := 0; i < 5; i++ {
+		err = testRedisStream.Publish(&pubsub.PublishRequest{Topic: "topic", Data: []byte(fmt.Sprintf("%d", i))})
+		require.NoError(t, err)
+	}
+
+	n, err := testRedisStream.client.XLen("topic").Result()
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), n)
+}
+
+func TestMinID(t *testing.T) {
+	now := time.Unix(1000, 0)
+
+	assert.Equal(t, "940000-0", minID(now, time.Minute))
+}
+
+func TestParseStreamConsumers(t *testing.T) {
+	t.Run("valid reply", func(t *testing.T) {
+		consumers, err := parseStreamConsumers([]interface{}{
+			[]interface{}{"name", "replica-1", "pending", int64(2), "idle", int64(1500)},
+			[]interface{}{"name", "replica-2", "pending", int64(0), "idle", int64(60000), "inactive", int64(60000)},
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, []streamConsumer{
+			{name: "replica-1", pending: 2, idle: 1500 * time.Millisecond},
+			{name: "replica-2", pending: 0, idle: time.Minute},
+		}, consumers)
+	})
+
+	t.Run("invalid reply", func(t *testing.T) {
+		_, err := parseStreamConsumers("OK")
+		assert.Error(t, err)
+
+		_, err = parseStreamConsumers([]interface{}{[]interface{}{"name"}})
+		assert.Error(t, err)
+	})
+}
+
+func TestDeliveryCountMetadata(t *testing.T) {
+	testRedisStream := &redisStreams{logger: logger.NewLogger("test")}
+	testRedisStream.ctx, testRedisStream.cancel = context.WithCancel(context.Background())
+	defer testRedisStream.cancel()
+	testRedisStream.queue = make(chan redisMessageWrapper, 10)
+
+	msgs := generateRedisStreamTestData(1, 2, "testData")
+	testRedisStream.enqueueMessages("topic", nil, msgs, map[string]int64{"1": 4})
+
+	first := <-testRedisStream.queue
+	second := <-testRedisStream.queue
+	assert.Equal(t, "1", first.message.Metadata[deliveryCountMetadataKey])
+	assert.Equal(t, "4", second.message.Metadata[deliveryCountMetadataKey])
+}
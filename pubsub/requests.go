@@ -25,3 +25,27 @@ type NewMessage struct {
 	Topic    string            `json:"topic"`
 	Metadata map[string]string `json:"metadata"`
 }
+
+// CreateTopicRequest is the request to create a topic. Settings that the
+// broker does not support are ignored.
+type CreateTopicRequest struct {
+	Topic string `json:"topic"`
+	// Number of partitions of the topic, 0 for the broker default
+	Partitions int `json:"partitions"`
+	// How long messages are kept in the topic, 0 for the broker default
+	RetentionInSeconds int64             `json:"retentionInSeconds"`
+	Metadata           map[string]string `json:"metadata"`
+}
+
+// DeleteTopicRequest is the request to delete a topic
+type DeleteTopicRequest struct {
+	Topic    string            `json:"topic"`
+	Metadata map[string]string `json:"metadata"`
+}
+
+// SubscriptionLagRequest is the request to get the backlog of a subscription
+// as returned by ListSubscriptions
+type SubscriptionLagRequest struct {
+	Topic        string `json:"topic"`
+	Subscription string `json:"subscription"`
+}
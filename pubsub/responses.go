@@ -21,3 +21,11 @@ const (
 type AppResponse struct {
 	Status AppResponseStatus `json:"status"`
 }
+
+// SubscriptionLagResponse is the backlog of a subscription
+type SubscriptionLagResponse struct {
+	Topic        string `json:"topic"`
+	Subscription string `json:"subscription"`
+	// Number of messages of the topic that the subscription has not acknowledged yet
+	Lag int64 `json:"lag"`
+}
@@ -13,6 +13,7 @@ import (
 	"strings"
 
 	"github.com/agrea/ptr"
+	"github.com/go-sql-driver/mysql"
 	"github.com/google/uuid"
 
 	"github.com/dapr/components-contrib/state"
@@ -38,6 +39,9 @@ const (
 	// than the defaultSchemaName
 	schemaNameKey = "schemaName"
 
+	// The error number of MySQL when an insert conflicts with an existing key
+	duplicateEntryErrorNumber = 1062
+
 	// The key for the mandatory connection string of the metadata
 	connectionStringKey = "connectionString"
 
@@ -379,7 +383,17 @@ func (m *MySQL) setValue(req *state.SetRequest) error {
 	// Sprintf is required for table name because sql.DB does not substitute
 	// parameters for table names.
 	// Other parameters use sql.DB parameter substitution.
-	if req.ETag == nil || *req.ETag == "" {
+	if (req.ETag == nil || *req.ETag == "") && req.Options.Concurrency == state.FirstWrite {
+		// A first-write without an eTag only inserts
+		result, err = m.db.Exec(fmt.Sprintf(
+			`INSERT INTO %s (value, id, eTag) VALUES (?, ?, ?);`,
+			m.tableName), value, req.Key, eTag)
+
+		var mysqlErr *mysql.MySQLError
+		if errors.As(err, &mysqlErr) && mysqlErr.Number == duplicateEntryErrorNumber {
+			return state.NewETagError(state.ETagMismatch, err)
+		}
+	} else if req.ETag == nil || *req.ETag == "" {
 		// If this is a duplicate MySQL returns that two rows affected
 		result, err = m.db.Exec(fmt.Sprintf(
 			`INSERT INTO %s (value, id, eTag)
@@ -391,6 +405,12 @@ func (m *MySQL) setValue(req *state.SetRequest) error {
 			`UPDATE %s SET value = ?, eTag = ?
 			 WHERE id = ? AND eTag = ?;`,
 			m.tableName), value, eTag, req.Key, *req.ETag)
+
+		if err == nil {
+			if rows, rowsErr := result.RowsAffected(); rowsErr == nil && rows == 0 {
+				return state.NewETagError(state.ETagMismatch, fmt.Errorf("no row matches key %s and eTag %s", req.Key, *req.ETag))
+			}
+		}
 	}
 
 	// Have to pass 2 because if the insert has a conflict MySQL returns that
@@ -6,12 +6,14 @@ package mysql
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	"testing"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/dapr/components-contrib/state"
 	"github.com/dapr/dapr/pkg/logger"
+	"github.com/go-sql-driver/mysql"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -203,6 +205,47 @@ func TestMySQLBulkSetRollbackSets(t *testing.T) {
 	assert.Equal(t, "setError", err.Error(), "wrong error returned")
 }
 
+func TestFirstWriteSetWithoutETagOnlyInserts(t *testing.T) {
+	// Arrange
+	m, _ := mockDatabase(t)
+	defer m.mySQL.Close()
+
+	m.mock1.ExpectExec(`INSERT INTO state \(value, id, eTag\) VALUES \(\?, \?, \?\);`).
+		WillReturnError(&mysql.MySQLError{Number: duplicateEntryErrorNumber, Message: "Duplicate entry"})
+
+	request := createSetRequest()
+	request.Options.Concurrency = state.FirstWrite
+
+	// Act
+	err := m.mySQL.Set(&request)
+
+	// Assert
+	var etagErr *state.ETagError
+	assert.True(t, errors.As(err, &etagErr), "expected an etag error")
+	assert.Equal(t, state.ETagMismatch, etagErr.Kind())
+	assert.NoError(t, m.mock1.ExpectationsWereMet())
+}
+
+func TestSetWithStaleETagFails(t *testing.T) {
+	// Arrange
+	m, _ := mockDatabase(t)
+	defer m.mySQL.Close()
+
+	m.mock1.ExpectExec("UPDATE state").WillReturnResult(sqlmock.NewResult(0, 0))
+
+	request := createSetRequest()
+	etag := "stale"
+	request.ETag = &etag
+
+	// Act
+	err := m.mySQL.Set(&request)
+
+	// Assert
+	var etagErr *state.ETagError
+	assert.True(t, errors.As(err, &etagErr), "expected an etag error")
+	assert.Equal(t, state.ETagMismatch, etagErr.Kind())
+}
+
 func TestExecuteMultiCommitSetsAndDeletes(t *testing.T) {
 	// Arrange
 	m, _ := mockDatabase(t)
@@ -105,7 +105,13 @@ func (p *postgresDBAccess) setValue(req *state.SetRequest) error {
 
 	// Sprintf is required for table name because sql.DB does not substitute parameters for table names.
 	// Other parameters use sql.DB parameter substitution.
-	if req.ETag == nil {
+	if req.ETag == nil && req.Options.Concurrency == state.FirstWrite {
+		// A first-write without an etag only inserts, no row is affected if the key exists
+		result, err = p.db.Exec(fmt.Sprintf(
+			`INSERT INTO %s (key, value) VALUES ($1, $2)
+			ON CONFLICT (key) DO NOTHING;`,
+			tableName), req.Key, value)
+	} else if req.ETag == nil {
 		result, err = p.db.Exec(fmt.Sprintf(
 			`INSERT INTO %s (key, value) VALUES ($1, $2)
 			ON CONFLICT (key) DO UPDATE SET value = $2, updatedate = NOW();`,
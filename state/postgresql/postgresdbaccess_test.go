@@ -0,0 +1,52 @@
+// ------------------------------------------------------------
+// Copyright (c) Microsoft Corporation and Dapr Contributors.
+// Licensed under the MIT License.
+// ------------------------------------------------------------
+
+package postgresql
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/components-contrib/state"
+	"github.com/dapr/dapr/pkg/logger"
+)
+
+func TestSetFirstWriteWithoutETag(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	dba := &postgresDBAccess{logger: logger.NewLogger("test"), db: db}
+
+	t.Run("inserts a new key", func(t *testing.T) {
+		mock.ExpectExec(`INSERT INTO state \(key, value\) VALUES \(\$1, \$2\)\s+ON CONFLICT \(key\) DO NOTHING;`).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		err := dba.Set(&state.SetRequest{Key: "key", Value: "value", Options: state.SetStateOption{Concurrency: state.FirstWrite}})
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("fails with an etag mismatch for an existing key", func(t *testing.T) {
+		mock.ExpectExec(`ON CONFLICT \(key\) DO NOTHING;`).WillReturnResult(sqlmock.NewResult(0, 0))
+
+		err := dba.Set(&state.SetRequest{Key: "key", Value: "value", Options: state.SetStateOption{Concurrency: state.FirstWrite}})
+		var etagErr *state.ETagError
+		require.True(t, errors.As(err, &etagErr))
+		assert.Equal(t, state.ETagMismatch, etagErr.Kind())
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("last-write still upserts", func(t *testing.T) {
+		mock.ExpectExec(`ON CONFLICT \(key\) DO UPDATE`).WillReturnResult(sqlmock.NewResult(0, 1))
+
+		err := dba.Set(&state.SetRequest{Key: "key", Value: "value"})
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
@@ -100,6 +100,11 @@ func TestPostgreSQLIntegration(t *testing.T) {
 		newItemWithEtagFails(t, pgs)
 	})
 
+	t.Run("First-write insert of an existing item fails", func(t *testing.T) {
+		t.Parallel()
+		firstWriteInsertOfExistingItemFails(t, pgs)
+	})
+
 	t.Run("Delete with invalid etag fails", func(t *testing.T) {
 		t.Parallel()
 		deleteWithInvalidEtagFails(t, pgs)
@@ -327,6 +332,24 @@ func newItemWithEtagFails(t *testing.T, pgs *PostgreSQL) {
 	assert.NotNil(t, err)
 }
 
+func firstWriteInsertOfExistingItemFails(t *testing.T, pgs *PostgreSQL) {
+	setReq := &state.SetRequest{
+		Key:     randomKey(),
+		Value:   &fakeItem{Color: "amber"},
+		Options: state.SetStateOption{Concurrency: state.FirstWrite},
+	}
+
+	err := pgs.Set(setReq)
+	assert.Nil(t, err)
+
+	// Without an etag a first-write only inserts
+	setReq.Value = &fakeItem{Color: "olive"}
+	err = pgs.Set(setReq)
+	assert.NotNil(t, err)
+	_, item := getItem(t, pgs, setReq.Key)
+	assert.Equal(t, &fakeItem{Color: "amber"}, item)
+}
+
 func updateWithOldEtagFails(t *testing.T, pgs *PostgreSQL) {
 	// Create and retrieve new item
 	key := randomKey()
@@ -7,17 +7,16 @@ package redis
 
 import (
 	"context"
-	"crypto/tls"
 	"errors"
 	"fmt"
 	"strconv"
 	"strings"
-	"time"
 
 	"github.com/agrea/ptr"
 	redis "github.com/go-redis/redis/v7"
 	jsoniter "github.com/json-iterator/go"
 
+	contrib_redis "github.com/dapr/components-contrib/internal/component/redis"
 	"github.com/dapr/components-contrib/state"
 	"github.com/dapr/components-contrib/state/utils"
 	"github.com/dapr/dapr/pkg/logger"
@@ -28,27 +27,17 @@ const (
 	delQuery                 = "local var1 = redis.pcall(\"HGET\", KEYS[1], \"version\"); if not var1 or type(var1)==\"table\" or var1 == ARGV[1] or var1 == \"\" or ARGV[1] == \"0\" then return redis.call(\"DEL\", KEYS[1]) else return error(\"failed to delete \" .. KEYS[1]) end"
 	connectedSlavesReplicas  = "connected_slaves:"
 	infoReplicationDelimiter = "\r\n"
-	host                     = "redisHost"
-	password                 = "redisPassword"
-	enableTLS                = "enableTLS"
-	maxRetries               = "maxRetries"
-	maxRetryBackoff          = "maxRetryBackoff"
-	failover                 = "failover"
-	sentinelMasterName       = "sentinelMasterName"
-	defaultBase              = 10
-	defaultBitSize           = 0
-	defaultDB                = 0
-	defaultMaxRetries        = 3
-	defaultMaxRetryBackoff   = time.Second * 2
-	defaultEnableTLS         = false
 )
 
+// insertVersion is passed to setQuery instead of an ETag to only create a key, as no version matches it.
+const insertVersion = -1
+
 // StateStore is a Redis state store
 type StateStore struct {
 	state.DefaultBulkStore
-	client   *redis.Client
+	client   redis.UniversalClient
 	json     jsoniter.API
-	metadata metadata
+	metadata *contrib_redis.Settings
 	replicas int
 
 	features []state.Feature
@@ -67,64 +56,8 @@ func NewRedisStateStore(logger logger.Logger) *StateStore {
 	return s
 }
 
-func parseRedisMetadata(meta state.Metadata) (metadata, error) {
-	m := metadata{}
-
-	if val, ok := meta.Properties[host]; ok && val != "" {
-		m.host = val
-	} else {
-		return m, errors.New("redis store error: missing host address")
-	}
-
-	if val, ok := meta.Properties[password]; ok && val != "" {
-		m.password = val
-	}
-
-	m.enableTLS = defaultEnableTLS
-	if val, ok := meta.Properties[enableTLS]; ok && val != "" {
-		tls, err := strconv.ParseBool(val)
-		if err != nil {
-			return m, fmt.Errorf("redis store error: can't parse enableTLS field: %s", err)
-		}
-		m.enableTLS = tls
-	}
-
-	m.maxRetries = defaultMaxRetries
-	if val, ok := meta.Properties[maxRetries]; ok && val != "" {
-		parsedVal, err := strconv.ParseInt(val, defaultBase, defaultBitSize)
-		if err != nil {
-			return m, fmt.Errorf("redis store error: can't parse maxRetries field: %s", err)
-		}
-		m.maxRetries = int(parsedVal)
-	}
-
-	m.maxRetryBackoff = defaultMaxRetryBackoff
-	if val, ok := meta.Properties[maxRetryBackoff]; ok && val != "" {
-		parsedVal, err := strconv.ParseInt(val, defaultBase, defaultBitSize)
-		if err != nil {
-			return m, fmt.Errorf("redis store error: can't parse maxRetries field: %s", err)
-		}
-		m.maxRetryBackoff = time.Duration(parsedVal)
-	}
-
-	if val, ok := meta.Properties[failover]; ok && val != "" {
-		failover, err := strconv.ParseBool(val)
-		if err != nil {
-			return m, fmt.Errorf("redis store error: can't parse failover field: %s", err)
-		}
-		m.failover = failover
-	}
-
-	// set the sentinelMasterName only with failover == true.
-	if m.failover {
-		if val, ok := meta.Properties[sentinelMasterName]; ok && val != "" {
-			m.sentinelMasterName = val
-		} else {
-			return m, errors.New("redis store error: missing sentinelMasterName")
-		}
-	}
-
-	return m, nil
+func parseRedisMetadata(meta state.Metadata) (*contrib_redis.Settings, error) {
+	return contrib_redis.ParseSettings(meta.Properties, "redis store error")
 }
 
 // Init does metadata and connection parsing
@@ -134,15 +67,18 @@ func (r *StateStore) Init(metadata state.Metadata) error {
 		return err
 	}
 	r.metadata = m
+	r.client = contrib_redis.NewClient(m)
 
-	if r.metadata.failover {
-		r.client = r.newFailoverClient(m)
-	} else {
-		r.client = r.newClient(m)
+	if _, err = r.client.Ping().Result(); err != nil {
+		return fmt.Errorf("redis store: error connecting to redis at %s: %s", m.Host, err)
 	}
 
-	if _, err = r.client.Ping().Result(); err != nil {
-		return fmt.Errorf("redis store: error connecting to redis at %s: %s", m.host, err)
+	if m.IsCluster() {
+		// WAIT only applies to the writes of its own connection, which the
+		// cluster client doesn't expose, so strong consistency writes are rejected.
+		r.logger.Warn("redis store: strong consistency is not supported with redisType cluster, strongly consistent writes will fail")
+
+		return nil
 	}
 
 	r.replicas, err = r.getConnectedSlaves()
@@ -155,45 +91,6 @@ func (r *StateStore) Features() []state.Feature {
 	return r.features
 }
 
-func (r *StateStore) newClient(m metadata) *redis.Client {
-	opts := &redis.Options{
-		Addr:            m.host,
-		Password:        m.password,
-		DB:              defaultDB,
-		MaxRetries:      m.maxRetries,
-		MaxRetryBackoff: m.maxRetryBackoff,
-	}
-
-	// tell the linter to skip a check here.
-	/* #nosec */
-	if m.enableTLS {
-		opts.TLSConfig = &tls.Config{
-			InsecureSkipVerify: m.enableTLS,
-		}
-	}
-
-	return redis.NewClient(opts)
-}
-
-func (r *StateStore) newFailoverClient(m metadata) *redis.Client {
-	opts := &redis.FailoverOptions{
-		MasterName:      r.metadata.sentinelMasterName,
-		SentinelAddrs:   []string{r.metadata.host},
-		DB:              defaultDB,
-		MaxRetries:      m.maxRetries,
-		MaxRetryBackoff: m.maxRetryBackoff,
-	}
-
-	/* #nosec */
-	if m.enableTLS {
-		opts.TLSConfig = &tls.Config{
-			InsecureSkipVerify: m.enableTLS,
-		}
-	}
-
-	return redis.NewFailoverClient(opts)
-}
-
 func (r *StateStore) getConnectedSlaves() (int, error) {
 	res, err := r.client.DoContext(context.Background(), "INFO", "replication").Result()
 	if err != nil {
@@ -293,6 +190,9 @@ func (r *StateStore) setValue(req *state.SetRequest) error {
 	if err != nil {
 		return err
 	}
+	if req.Options.Consistency == state.Strong && r.metadata != nil && r.metadata.IsCluster() {
+		return fmt.Errorf("failed to set key %s: strong consistency is not supported with redisType cluster", req.Key)
+	}
 	ver, err := r.parseETag(req)
 	if err != nil {
 		return err
@@ -302,7 +202,7 @@ func (r *StateStore) setValue(req *state.SetRequest) error {
 
 	_, err = r.client.DoContext(context.Background(), "EVAL", setQuery, 1, req.Key, ver, bt).Result()
 	if err != nil {
-		if req.ETag != nil {
+		if req.ETag != nil || ver == insertVersion {
 			return state.NewETagError(state.ETagMismatch, err)
 		}
 
@@ -326,6 +226,22 @@ func (r *StateStore) Set(req *state.SetRequest) error {
 
 // Multi performs a transactional operation. succeeds only if all operations succeed, and fails if one or more operations fail
 func (r *StateStore) Multi(request *state.TransactionalStateRequest) error {
+	if r.metadata != nil && r.metadata.IsCluster() {
+		// The cluster client splits transactions per slot, they are only atomic within one slot
+		keys := make([]string, 0, len(request.Operations))
+		for _, o := range request.Operations {
+			switch req := o.Request.(type) {
+			case state.SetRequest:
+				keys = append(keys, req.Key)
+			case state.DeleteRequest:
+				keys = append(keys, req.Key)
+			}
+		}
+		if !contrib_redis.SameSlot(keys...) {
+			return fmt.Errorf("redis store error: %s", contrib_redis.ErrCrossSlot)
+		}
+	}
+
 	pipe := r.client.TxPipeline()
 	for _, o := range request.Operations {
 		if o.Operation == state.Upsert {
@@ -373,7 +289,12 @@ func (r *StateStore) getKeyVersion(vals []interface{}) (data string, version *st
 	return data, version, nil
 }
 
+// parseETag returns the version to pass to setQuery. A first-write Set without
+// an ETag only creates the key.
 func (r *StateStore) parseETag(req *state.SetRequest) (int, error) {
+	if req.Options.Concurrency == state.FirstWrite && (req.ETag == nil || *req.ETag == "") {
+		return insertVersion, nil
+	}
 	if req.Options.Concurrency == state.LastWrite || req.ETag == nil || (req.ETag != nil && *req.ETag == "") {
 		return 0, nil
 	}
@@ -7,6 +7,7 @@ package redis
 
 import (
 	"context"
+	"errors"
 	"testing"
 
 	"github.com/agrea/ptr"
@@ -15,6 +16,7 @@ import (
 	jsoniter "github.com/json-iterator/go"
 	"github.com/stretchr/testify/assert"
 
+	contrib_redis "github.com/dapr/components-contrib/internal/component/redis"
 	"github.com/dapr/components-contrib/state"
 	"github.com/dapr/dapr/pkg/logger"
 )
@@ -87,6 +89,15 @@ func TestParseEtag(t *testing.T) {
 		assert.Equal(t, nil, err, "failed to parse ETag")
 		assert.Equal(t, 0, ver, "version should be 0")
 	})
+	t.Run("Concurrency=FirstWrite without ETag", func(t *testing.T) {
+		ver, err := store.parseETag(&state.SetRequest{
+			Options: state.SetStateOption{
+				Concurrency: state.FirstWrite,
+			},
+		})
+		assert.Equal(t, nil, err, "failed to parse ETag")
+		assert.Equal(t, insertVersion, ver, "version should only match a missing key")
+	})
 }
 
 func TestParseConnectedSlavs(t *testing.T) {
@@ -212,6 +223,93 @@ func TestTransactionalDeleteNoEtag(t *testing.T) {
 	assert.Equal(t, 0, len(vals))
 }
 
+func TestTransactionalCluster(t *testing.T) {
+	s, c := setupMiniredis()
+	defer s.Close()
+
+	ss := &StateStore{
+		client:   c,
+		json:     jsoniter.ConfigFastest,
+		logger:   logger.NewLogger("test"),
+		metadata: &contrib_redis.Settings{RedisType: contrib_redis.ClusterType},
+	}
+
+	err := ss.Multi(&state.TransactionalStateRequest{
+		Operations: []state.TransactionalStateOperation{{
+			Operation: state.Upsert,
+			Request:   state.SetRequest{Key: "weapon", Value: "deathstar"},
+		}, {
+			Operation: state.Delete,
+			Request:   state.DeleteRequest{Key: "planet"},
+		}},
+	})
+	assert.Error(t, err)
+	assert.False(t, s.Exists("weapon"))
+
+	err = ss.Multi(&state.TransactionalStateRequest{
+		Operations: []state.TransactionalStateOperation{{
+			Operation: state.Upsert,
+			Request:   state.SetRequest{Key: "{empire}weapon", Value: "deathstar"},
+		}, {
+			Operation: state.Delete,
+			Request:   state.DeleteRequest{Key: "{empire}planet"},
+		}},
+	})
+	assert.NoError(t, err)
+	assert.True(t, s.Exists("{empire}weapon"))
+}
+
+func TestStrongConsistencyCluster(t *testing.T) {
+	s, c := setupMiniredis()
+	defer s.Close()
+
+	ss := &StateStore{
+		client:   c,
+		json:     jsoniter.ConfigFastest,
+		logger:   logger.NewLogger("test"),
+		metadata: &contrib_redis.Settings{RedisType: contrib_redis.ClusterType},
+	}
+
+	err := ss.Set(&state.SetRequest{Key: "weapon", Value: "deathstar", Options: state.SetStateOption{Consistency: state.Strong}})
+	assert.Error(t, err)
+	assert.False(t, s.Exists("weapon"))
+
+	err = ss.Set(&state.SetRequest{Key: "weapon", Value: "deathstar", Options: state.SetStateOption{Consistency: state.Eventual}})
+	assert.NoError(t, err)
+	assert.True(t, s.Exists("weapon"))
+}
+
+func TestFirstWriteInsert(t *testing.T) {
+	s, c := setupMiniredis()
+	defer s.Close()
+
+	ss := &StateStore{
+		client: c,
+		json:   jsoniter.ConfigFastest,
+		logger: logger.NewLogger("test"),
+	}
+
+	insert := &state.SetRequest{Key: "weapon", Value: "deathstar", Options: state.SetStateOption{Concurrency: state.FirstWrite}}
+	err := ss.Set(insert)
+	assert.NoError(t, err)
+
+	var etagErr *state.ETagError
+	err = ss.Set(insert)
+	assert.True(t, errors.As(err, &etagErr))
+	assert.Equal(t, state.ETagMismatch, etagErr.Kind())
+
+	err = ss.Set(&state.SetRequest{Key: "weapon", Value: "tie fighter"})
+	assert.NoError(t, err)
+
+	res, err := c.DoContext(context.Background(), "HGETALL", "weapon").Result()
+	assert.NoError(t, err)
+
+	data, version, err := ss.getKeyVersion(res.([]interface{}))
+	assert.NoError(t, err)
+	assert.Equal(t, ptr.String("2"), version)
+	assert.Equal(t, `"tie fighter"`, data)
+}
+
 func setupMiniredis() (*miniredis.Miniredis, *redis.Client) {
 	s, err := miniredis.Run()
 	if err != nil {
@@ -219,7 +317,6 @@ func setupMiniredis() (*miniredis.Miniredis, *redis.Client) {
 	}
 	opts := &redis.Options{
 		Addr: s.Addr(),
-		DB:   defaultDB,
 	}
 
 	return s, redis.NewClient(opts)
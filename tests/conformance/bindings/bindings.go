@@ -6,6 +6,7 @@
 package bindings
 
 import (
+	"encoding/json"
 	"strconv"
 	"strings"
 	"testing"
@@ -38,6 +39,9 @@ type TestConfig struct {
 	InputMetadata      map[string]string `mapstructure:"input"`
 	OutputMetadata     map[string]string `mapstructure:"output"`
 	ReadBindingTimeout time.Duration     `mapstructure:"readBindingTimeout"`
+	// ExecMetadata and QueryMetadata are the request metadata of the exec and query operations of SQL bindings
+	ExecMetadata  map[string]string `mapstructure:"exec"`
+	QueryMetadata map[string]string `mapstructure:"query"`
 }
 
 func NewTestConfig(name string, allOperations bool, operations []string, configMap map[string]interface{}) (TestConfig, error) {
@@ -52,6 +56,8 @@ func NewTestConfig(name string, allOperations bool, operations []string, configM
 		InputMetadata:      make(map[string]string),
 		OutputMetadata:     make(map[string]string),
 		ReadBindingTimeout: defaultTimeoutDuration,
+		ExecMetadata:       make(map[string]string),
+		QueryMetadata:      make(map[string]string),
 	}
 
 	err := config.Decode(configMap, &testConfig)
@@ -185,6 +191,37 @@ func ConformanceTests(t *testing.T, props map[string]string, inputBinding bindin
 		})
 	}
 
+	// ExecOperation, SQL bindings only
+	if config.HasOperation("exec") {
+		t.Run("exec", func(t *testing.T) {
+			req := bindings.InvokeRequest{
+				Operation: "exec",
+				Metadata:  config.CopyMap(config.ExecMetadata),
+			}
+			resp, err := outputBinding.Invoke(&req)
+			assert.Nil(t, err, "expected no error invoking output binding")
+			if assert.NotNil(t, resp) {
+				assert.NotEmpty(t, resp.Metadata["rows-affected"])
+			}
+		})
+	}
+
+	// QueryOperation, SQL bindings only
+	if config.HasOperation("query") {
+		t.Run("query", func(t *testing.T) {
+			req := bindings.InvokeRequest{
+				Operation: "query",
+				Metadata:  config.CopyMap(config.QueryMetadata),
+			}
+			resp, err := outputBinding.Invoke(&req)
+			assert.Nil(t, err, "expected no error invoking output binding")
+			if assert.NotNil(t, resp) {
+				var rows []map[string]interface{}
+				assert.NoError(t, json.Unmarshal(resp.Data, &rows), "expected a JSON array of rows")
+			}
+		})
+	}
+
 	if config.CommonConfig.HasOperation("read") {
 		t.Run("verify read", func(t *testing.T) {
 			// To stop the test from hanging if there's no response, we can setup a simple timeout.
@@ -214,6 +251,15 @@ func ConformanceTests(t *testing.T, props map[string]string, inputBinding bindin
 			}
 		})
 	}
+
+	// CloseOperation
+	if config.HasOperation("close") {
+		t.Run("close", func(t *testing.T) {
+			req := bindings.InvokeRequest{Operation: "close"}
+			_, err := outputBinding.Invoke(&req)
+			assert.Nil(t, err, "expected no error invoking output binding")
+		})
+	}
 }
 
 func readFromInputBinding(binding bindings.InputBinding, reads *int, readChan chan int) {
@@ -23,7 +23,9 @@ import (
 	b_azure_storagequeues "github.com/dapr/components-contrib/bindings/azure/storagequeues"
 	b_http "github.com/dapr/components-contrib/bindings/http"
 	b_kafka "github.com/dapr/components-contrib/bindings/kafka"
+	b_localstorage "github.com/dapr/components-contrib/bindings/localstorage"
 	b_redis "github.com/dapr/components-contrib/bindings/redis"
+	b_sqlite "github.com/dapr/components-contrib/bindings/sqlite"
 	"github.com/dapr/components-contrib/pubsub"
 	p_servicebus "github.com/dapr/components-contrib/pubsub/azure/servicebus"
 	p_hazelcast "github.com/dapr/components-contrib/pubsub/hazelcast"
@@ -385,6 +387,10 @@ func loadOutputBindings(tc TestComponent) bindings.OutputBinding {
 		binding = b_kafka.NewKafka(testLogger)
 	case "http":
 		binding = b_http.NewHTTP(testLogger)
+	case "sqlite":
+		binding = b_sqlite.NewSQLite(testLogger)
+	case "localstorage":
+		binding = b_localstorage.NewLocalStorage(testLogger)
 	default:
 		return nil
 	}